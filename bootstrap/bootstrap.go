@@ -17,6 +17,9 @@ import (
 //   - InitConfig: initializes the configuration
 //   - InitLogger: initializes the LoggerService with a production-ready logger
 //   - InitCommon: initializes the common resources
+//   - InitTracing: initializes the OpenTelemetry tracer provider
+//   - InitMetrics: registers the HTTP metrics with the configured buckets
+//   - InitSchemaRegistry: creates the serde of the Kafka and NSQ messages
 //   - InitClickHouse: initializes the ClickHouse database
 //   - InitCron: initializes the cron scheduler
 //   - InitEnforcer: initializes the Casbin enforcer
@@ -30,6 +33,13 @@ import (
 //   - InitPostgresql: initializes the Postgresql database
 //   - InitRedis: initializes the Redis database
 //   - InitTDengine: initializes the TDengine database
+//   - InitKafkaProducers: creates the async Kafka producer and the outbox relay
+//   - StartKafkaConsumers: starts the consumers of the registered Kafka handlers
+//   - StartKafkaLagMonitor: starts the monitor of the lag of the Kafka consumer groups
+//   - InitializeCircuitBreaker: creates the circuit breakers declared in the configuration
+//   - InitResilience: creates the resilience policies of the dependencies
+//   - InitAudit: initializes the audit trail
+//   - InitLogSinks: starts shipping logs to the remote sinks
 //   - TaskStart: starts the one-off task
 //
 // If any of the initialization functions return an error, this function will panic with the error.
@@ -46,9 +56,16 @@ func MustInit(ctx context.Context) {
 	// Initialize the common resources
 	service.InitCommon(ctx)
 
-	//// Initialize circuit breaker manager
-	//service.InitializeCircuitBreaker()
-	//
+	// Initialize the tracer provider
+	service.InitTracing(ctx)
+
+	// Register the HTTP metrics, before the servers are created
+	service.InitMetrics(ctx)
+
+	// Initialize the schema registry serde, before the Kafka and NSQ clients
+	// producing and consuming the messages
+	service.InitSchemaRegistry(ctx)
+
 	//// Initialize the ClickHouse
 	//service.InitClickHouse(ctx)
 	//
@@ -90,6 +107,30 @@ func MustInit(ctx context.Context) {
 	//TDengine
 	//database
 	//service.InitTDengine(ctx) // Commented out - TDengine driver not available
+	//
+	//// Create the async Kafka producer and start the outbox relay, after the
+	//// database of the outbox
+	//InitKafkaProducers(ctx)
+	//
+	//// Start the consumers of the registered Kafka handlers
+	//StartKafkaConsumers(ctx)
+	//
+	//// Start the monitor of the lag of the Kafka consumer groups
+	//StartKafkaLagMonitor(ctx)
+
+	// Initialize the circuit breaker manager, after the Redis client sharing
+	// its state and before the servers are created
+	service.InitializeCircuitBreaker()
+
+	// Initialize the resilience policies of the dependencies, after the
+	// circuit breakers they use
+	service.InitResilience()
+
+	// Initialize the audit trail, after the database it is stored in
+	service.InitAudit(ctx)
+
+	// Ship logs to the remote sinks, after the clients they reuse
+	service.InitLogSinks(ctx)
 
 	//TaskStart(ctx)
 }
@@ -123,13 +164,15 @@ func HookStd(_ context.Context) {
 //   - Redis client
 //   - ElasticSearch client
 //   - ClickHouse connection
-//   - Kafka connections
+//   - Kafka consumers, producers and connections
 //   - NSQ connections
 //   - Manticore client
 //   - etcd client
 //   - NSQ connections
 //   - TDengine client
 //   - Cron jobs scheduler
+//   - Tracer provider
+//   - Audit trail
 //
 // Parameters:
 //   - ctx: Context for the operation, used for timeouts and cancellation
@@ -140,6 +183,9 @@ func HookStd(_ context.Context) {
 func Close(ctx context.Context) error {
 	var errs []error
 
+	// Stop recording audit events before their database is closed.
+	service.CloseAudit()
+
 	// Close the ClickHouse connection.
 	err := service.CloseClickHouse()
 	if err != nil {
@@ -158,6 +204,19 @@ func Close(ctx context.Context) error {
 		errs = append(errs, err)
 	}
 
+	// Stop the Kafka consumers, waiting for the messages being processed,
+	// before their connections are closed.
+	err = StopKafkaConsumers()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// Stop the outbox relay and flush the async producer.
+	CloseKafkaProducers()
+
+	// Stop the lag monitor sharing the Kafka client.
+	StopKafkaLagMonitor()
+
 	// Close the Kafka connections.
 	err = service.CloseKafka()
 	if err != nil {
@@ -225,6 +284,12 @@ func Close(ctx context.Context) error {
 		}
 	}
 
+	// Flush the pending spans and shut down the tracer provider.
+	err = service.CloseTracing(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
 	// Close the Logger service (should be last to capture all shutdown logs).
 	err = service.CloseLogger(ctx)
 	if err != nil {
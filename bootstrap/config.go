@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/pkg/buildinfo"
 
 	"github.com/BurntSushi/toml"
 )
@@ -13,7 +14,7 @@ import (
 //
 // This function loads configuration files for different services including
 // logging, server, Elasticsearch, Etcd, Kafka, MongoDB, MySQL, NSQ, Redis,
-// ClickHouse and PostgresSQL.
+// ClickHouse, PostgresSQL, tracing, audit and circuit breakers.
 //
 // It decodes the configurations and assigns them to their
 // respective global configuration variables in the config package.
@@ -36,6 +37,14 @@ func InitConfig(_ context.Context) {
 		panic("Failed to load server configuration file: " + err.Error())
 	}
 
+	// The version injected at build time takes precedence over the configured
+	// one, which is reported by binaries built without it
+	if buildinfo.Version != "" {
+		config.ServerConfig.Version.Version = buildinfo.Version
+	} else {
+		buildinfo.SetFallbackVersion(config.ServerConfig.Version.Version)
+	}
+
 	// Load ClickHouse configuration
 	if _, err := toml.DecodeFile("./conf/service/clickhouse.toml", &config.ClickHouseConfig); err != nil {
 		// The ClickHouse configuration file could not be decoded. Panic with the error message.
@@ -106,4 +115,34 @@ func InitConfig(_ context.Context) {
 		// The Cron configuration file could not be decoded. Panic with the error message.
 		panic("Failed to load Cron configuration file: " + err.Error())
 	}
+
+	// Load Tracing configuration
+	if _, err := toml.DecodeFile("./conf/service/tracing.toml", &config.TracingConfig); err != nil {
+		// The Tracing configuration file could not be decoded. Panic with the error message.
+		panic("Failed to load Tracing configuration file: " + err.Error())
+	}
+
+	// Load Audit configuration
+	if _, err := toml.DecodeFile("./conf/service/audit.toml", &config.AuditConfig); err != nil {
+		// The Audit configuration file could not be decoded. Panic with the error message.
+		panic("Failed to load Audit configuration file: " + err.Error())
+	}
+
+	// Load Circuit breaker configuration
+	if _, err := toml.DecodeFile("./conf/service/circuitbreaker.toml", &config.CircuitBreakerConfig); err != nil {
+		// The Circuit breaker configuration file could not be decoded. Panic with the error message.
+		panic("Failed to load Circuit breaker configuration file: " + err.Error())
+	}
+
+	// Load Resilience configuration
+	if _, err := toml.DecodeFile("./conf/service/resilience.toml", &config.ResilienceConfig); err != nil {
+		// The Resilience configuration file could not be decoded. Panic with the error message.
+		panic("Failed to load Resilience configuration file: " + err.Error())
+	}
+
+	// Load Schema registry configuration
+	if _, err := toml.DecodeFile("./conf/service/schemaregistry.toml", &config.SchemaRegistryConfig); err != nil {
+		// The Schema registry configuration file could not be decoded. Panic with the error message.
+		panic("Failed to load Schema registry configuration file: " + err.Error())
+	}
 }
@@ -0,0 +1,205 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/bootstrap/service"
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/kafka"
+
+	"github.com/IBM/sarama"
+)
+
+// StartKafkaConsumers starts the consumer groups of the handlers registered
+// with kafka.Register, after the Kafka clients are initialized. The failed
+// messages are sent to the retry and the dead letter topics with the shared
+// producer. The partitions without committed offset are consumed from the
+// configured start position.
+//
+// The package service cannot import the package kafka, whose tests import
+// it, so the consumers are started and stopped here.
+//
+// Parameters:
+//   - ctx: context.Context used for managing request-scoped values
+//     and cancellation signals.
+//
+// Panics:
+//   - If a consumer group cannot be created.
+func StartKafkaConsumers(_ context.Context) {
+	groups := kafka.DefaultRegistry.Groups()
+	if len(groups) == 0 {
+		return
+	}
+
+	cfg := config.KafkaConfig
+	version, err := sarama.ParseKafkaVersion(cfg.Kafka.Version)
+	if err != nil {
+		panic(fmt.Sprintf("invalid kafka version: %v", err))
+	}
+	position, err := kafka.ParseStartPosition(cfg.Advanced.ConsumerStartPosition)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	err = kafka.DefaultRegistry.Start(func(group string) (sarama.ConsumerGroup, error) {
+		return sarama.NewConsumerGroup(cfg.Kafka.Brokers, group, service.ConfigureKafkaConsumer(cfg, version))
+	}, kafka.RunnerConfig{
+		Concurrency:         cfg.Advanced.ConsumerConcurrency,
+		MinReconnectBackoff: time.Duration(cfg.Advanced.MinReconnectBackoff) * time.Millisecond,
+		MaxReconnectBackoff: time.Duration(cfg.Advanced.MaxReconnectBackoff) * time.Millisecond,
+		Producer:            resource.KafkaProducer,
+		StartPosition:       position,
+		Client:              resource.KafkaClient,
+		ManualCommit:        cfg.Advanced.ConsumerManualCommit,
+	})
+	if err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("failed to start kafka consumers: %v", err))
+		panic(err.Error())
+	}
+
+	resource.LoggerService.Info(fmt.Sprintf("✅ successfully started kafka consumers | groups: %s",
+		strings.Join(groups, ", ")))
+}
+
+// StopKafkaConsumers stops the registered consumers, waiting for the
+// messages being processed, before the Kafka clients are closed.
+//
+// Returns:
+//   - an error if a consumer group cannot be closed
+func StopKafkaConsumers() error {
+	if err := kafka.DefaultRegistry.Stop(); err != nil {
+		return fmt.Errorf("failed to stop kafka consumers: %w", err)
+	}
+	return nil
+}
+
+// outboxRelay is the relay of the Kafka outbox table, nil if the outbox is
+// disabled.
+var outboxRelay *kafka.OutboxRelay
+
+// InitKafkaProducers creates the async producer of kafka.SendAsync and
+// starts the relay of the outbox table if it is enabled, after the Kafka
+// clients and the database of the outbox are initialized.
+//
+// Parameters:
+//   - ctx: context.Context used for managing request-scoped values
+//     and cancellation signals.
+//
+// Panics:
+//   - If the async producer cannot be created or the outbox table cannot be
+//     migrated.
+func InitKafkaProducers(ctx context.Context) {
+	cfg := config.KafkaConfig
+	version, err := sarama.ParseKafkaVersion(cfg.Kafka.Version)
+	if err != nil {
+		panic(fmt.Sprintf("invalid kafka version: %v", err))
+	}
+
+	producer, err := sarama.NewAsyncProducer(cfg.Kafka.Brokers, service.ConfigureKafkaProducer(cfg, version))
+	if err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("failed to create kafka async producer: %v", err))
+		panic(err.Error())
+	}
+	kafka.DefaultAsyncProducer = kafka.NewAsyncProducer(producer)
+
+	if !cfg.Outbox.Enable {
+		return
+	}
+
+	db := resource.MySQLClient
+	if cfg.Outbox.Database == "postgresql" {
+		db = resource.PostgresqlClient
+	}
+	if db == nil || resource.KafkaProducer == nil {
+		resource.LoggerService.Warn(fmt.Sprintf("kafka outbox is enabled but %s or kafka is not initialized, skipping",
+			cfg.Outbox.Database))
+		return
+	}
+
+	if cfg.Outbox.AutoMigrate {
+		if err := kafka.MigrateOutbox(ctx, db); err != nil {
+			resource.LoggerService.Error(fmt.Sprintf("failed to migrate kafka outbox table: %v", err))
+			panic(err.Error())
+		}
+	}
+
+	outboxRelay = kafka.NewOutboxRelay(db, resource.KafkaProducer, kafka.OutboxRelayConfig{
+		BatchSize:   cfg.Outbox.BatchSize,
+		Interval:    time.Duration(cfg.Outbox.Interval) * time.Millisecond,
+		Retention:   time.Duration(cfg.Outbox.Retention) * time.Hour,
+		MaxAttempts: cfg.Outbox.MaxAttempts,
+	})
+	outboxRelay.Start()
+
+	resource.LoggerService.Info(fmt.Sprintf("✅ successfully started kafka outbox relay | database: %s",
+		cfg.Outbox.Database))
+}
+
+// lagMonitor is the monitor of the lag of the consumer groups, nil if it is
+// disabled.
+var lagMonitor *kafka.LagMonitor
+
+// StartKafkaLagMonitor starts the monitor of the lag of the consumer groups
+// if it is enabled, after the Kafka clients are initialized. The monitor
+// shares the client of the admin operations.
+//
+// Parameters:
+//   - ctx: context.Context used for managing request-scoped values
+//     and cancellation signals.
+//
+// Panics:
+//   - If the controller of the cluster cannot be found.
+func StartKafkaLagMonitor(_ context.Context) {
+	cfg := config.KafkaConfig
+	if !cfg.LagMonitor.Enable {
+		return
+	}
+	if resource.KafkaClient == nil {
+		resource.LoggerService.Warn("kafka lag monitor is enabled but kafka is not initialized, skipping")
+		return
+	}
+
+	monitor, err := kafka.NewLagMonitor(resource.KafkaClient, kafka.LagMonitorConfig{
+		Interval:          time.Duration(cfg.LagMonitor.Interval) * time.Millisecond,
+		Groups:            cfg.LagMonitor.Groups,
+		WarnThreshold:     cfg.LagMonitor.WarnThreshold,
+		CriticalThreshold: cfg.LagMonitor.CriticalThreshold,
+	})
+	if err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("failed to create kafka lag monitor: %v", err))
+		panic(err.Error())
+	}
+	lagMonitor = monitor
+	lagMonitor.Start()
+
+	groups := "all"
+	if len(cfg.LagMonitor.Groups) > 0 {
+		groups = strings.Join(cfg.LagMonitor.Groups, ", ")
+	}
+	resource.LoggerService.Info(fmt.Sprintf("✅ successfully started kafka lag monitor | groups: %s", groups))
+}
+
+// StopKafkaLagMonitor stops the lag monitor, before the Kafka clients are
+// closed.
+func StopKafkaLagMonitor() {
+	if lagMonitor != nil {
+		lagMonitor.Stop()
+		lagMonitor = nil
+	}
+}
+
+// CloseKafkaProducers stops the outbox relay and closes the async producer,
+// flushing its queued messages, before the Kafka clients are closed.
+func CloseKafkaProducers() {
+	if outboxRelay != nil {
+		outboxRelay.Stop()
+		outboxRelay = nil
+	}
+	if kafka.DefaultAsyncProducer != nil {
+		kafka.DefaultAsyncProducer.Close()
+	}
+}
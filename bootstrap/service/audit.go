@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/audit"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// InitAudit initializes the audit trail.
+//
+// This function reads the audit configuration from the global AuditConfig,
+// creates the audit store on the configured database and assigns it to the
+// global AuditStore resource. It must be called after the database is
+// initialized. Nothing is recorded when auditing is disabled or the
+// database is not initialized.
+//
+// Parameters:
+//   - ctx: Context for the operation, used for timeouts and cancellation
+func InitAudit(ctx context.Context) {
+	if err := InitAuditStore(ctx); err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("Failed to initialize audit trail: %v", err))
+		panic(fmt.Sprintf("Audit initialization failed: %v", err))
+	}
+}
+
+// InitAuditStore creates the audit store using the global AuditConfig.
+//
+// Parameters:
+//   - ctx: Context for the operation, used for timeouts and cancellation
+//
+// Returns:
+//   - error: An error if the configuration is invalid or the migration
+//     fails, nil otherwise
+func InitAuditStore(ctx context.Context) error {
+	if config.AuditConfig == nil {
+		return fmt.Errorf("audit configuration is not initialized")
+	}
+	cfg := &config.AuditConfig.Audit
+	if !cfg.Enable {
+		resource.LoggerService.Info("audit trail is disabled")
+		return nil
+	}
+
+	var db *gorm.DB
+	switch cfg.Database {
+	case "mysql":
+		db = resource.MySQLClient
+	case "postgresql":
+		db = resource.PostgresqlClient
+	default:
+		return fmt.Errorf("unsupported audit database: %s", cfg.Database)
+	}
+	if db == nil {
+		resource.LoggerService.Warn("⚠️ audit trail skipped, database is not initialized",
+			zap.String("database", cfg.Database))
+		return nil
+	}
+
+	store := audit.NewStore(db)
+	if cfg.AutoMigrate {
+		migrateCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		if err := store.Migrate(migrateCtx); err != nil {
+			return err
+		}
+	}
+
+	resource.AuditStore = store
+	resource.LoggerService.Info("✅ audit trail initialized successfully",
+		zap.String("database", cfg.Database))
+	return nil
+}
+
+// CloseAudit releases the audit store. The database is closed by its own
+// service.
+func CloseAudit() {
+	resource.AuditStore = nil
+}
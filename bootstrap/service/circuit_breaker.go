@@ -1,78 +1,304 @@
 package service
 
 import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/middleware"
 	"github.com/xiebingnote/go-gin-project/library/resource"
 	"github.com/xiebingnote/go-gin-project/pkg/circuitbreaker"
+
+	"github.com/BurntSushi/toml"
+)
+
+// circuitBreakerConfigFile is the configuration file of the circuit breakers,
+// decoded again on reload.
+const circuitBreakerConfigFile = "./conf/service/circuitbreaker.toml"
+
+var (
+	// circuitBreakerConfig is the circuit breaker configuration in force,
+	// replaced on reload while the routes may read it.
+	circuitBreakerConfig atomic.Pointer[config.CircuitBreakerConfigEntry]
+
+	// reloadMu serializes the reloads, so that the configuration in force is
+	// the one applied last.
+	reloadMu sync.Mutex
 )
 
-// 全局熔断器管理器
-var circuitBreakerManager *middleware.CircuitBreakerManager
+// CircuitBreakerConfig returns the circuit breaker configuration in force,
+// the one loaded at boot until the configuration is reloaded.
+//
+// The global config.CircuitBreakerConfig keeps the configuration loaded at
+// boot and is never written after it, so that it can be read without
+// synchronization.
+//
+// Returns:
+//   - *config.CircuitBreakerConfigEntry: The configuration, nil if it is
+//     not loaded.
+func CircuitBreakerConfig() *config.CircuitBreakerConfigEntry {
+	if cfg := circuitBreakerConfig.Load(); cfg != nil {
+		return cfg
+	}
+	return config.CircuitBreakerConfig
+}
 
-// InitializeCircuitBreaker initializes the circuit breaker manager and creates
-// some predefined circuit breakers for critical services
+// InitializeCircuitBreaker initializes the circuit breaker manager with the
+// breakers declared in the global CircuitBreakerConfig and stores it in
+// resource.CircuitBreakerManager.
+//
+// The dependency breakers are available with
+// resource.CircuitBreakerManager.GetBreaker(name) and the route rules are
+// applied by middleware.CircuitBreakerMiddleware. The configuration file is
+// reloaded when the process receives SIGHUP.
+//
+// If the shared state is enabled, the breakers of all the instances trip
+// together through Redis, which must be initialized first.
+//
+// Nothing is initialized if the circuit breakers are disabled. The function
+// panics if the configuration is invalid.
 func InitializeCircuitBreaker() {
+	if config.CircuitBreakerConfig == nil {
+		panic("Circuit breaker configuration is not initialized")
+	}
+	if !config.CircuitBreakerConfig.CircuitBreaker.Enable {
+		resource.LoggerService.Info("Circuit breaker is disabled, skipping initialization")
+		return
+	}
+
 	// Create circuit breaker manager
-	circuitBreakerManager = middleware.NewCircuitBreakerManager(resource.LoggerService)
+	manager := middleware.NewCircuitBreakerManager(resource.LoggerService)
+
+	// Share the open state with the other instances, before the breakers are created
+	shared := &config.CircuitBreakerConfig.CircuitBreaker.SharedState
+	if shared.Enable {
+		if resource.RedisClient == nil {
+			resource.LoggerService.Warn("⚠️ circuit breaker shared state skipped, Redis is not initialized")
+		} else {
+			manager.SetSharedState(circuitbreaker.NewRedisState(resource.RedisClient, shared.KeyPrefix),
+				time.Duration(shared.SyncInterval)*time.Millisecond)
+		}
+	}
+
+	// Create the declared circuit breakers
+	if err := ApplyCircuitBreakerConfig(manager, config.CircuitBreakerConfig); err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("Failed to initialize circuit breaker: %v", err))
+		panic(fmt.Sprintf("Circuit breaker initialization failed: %v", err))
+	}
+	circuitBreakerConfig.Store(config.CircuitBreakerConfig)
+	resource.CircuitBreakerManager = manager
+
+	watchCircuitBreakerReload()
 
-	// Create circuit breakers for critical services
-	createServiceCircuitBreakers()
+	resource.LoggerService.Info(fmt.Sprintf("✅ successfully initialized circuit breaker manager, %d breakers declared",
+		len(manager.ListBreakers())))
+}
+
+// ReloadCircuitBreaker decodes the circuit breaker configuration file again
+// and applies it to the manager created at boot. The applied configuration
+// is returned by CircuitBreakerConfig.
+//
+// Breakers whose policy is unchanged keep their state. If the new
+// configuration is invalid, the current one is kept. Disabling the circuit
+// breakers removes the declared breakers, while enabling them requires a
+// restart, as the middleware is only mounted at boot.
+//
+// Returns:
+//   - error: An error if the manager is not initialized or the configuration
+//     cannot be loaded, nil otherwise
+func ReloadCircuitBreaker() error {
+	manager := resource.CircuitBreakerManager
+	if manager == nil {
+		return fmt.Errorf("circuit breaker manager is not initialized, enabling it requires a restart")
+	}
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	var cfg *config.CircuitBreakerConfigEntry
+	if _, err := toml.DecodeFile(circuitBreakerConfigFile, &cfg); err != nil {
+		return fmt.Errorf("failed to load circuit breaker configuration file: %w", err)
+	}
+	if err := ApplyCircuitBreakerConfig(manager, cfg); err != nil {
+		return err
+	}
+	circuitBreakerConfig.Store(cfg)
+
+	return nil
+}
+
+// ApplyCircuitBreakerConfig validates the circuit breaker configuration and
+// configures the declared breakers of the manager. A disabled configuration
+// removes them.
+//
+// Parameters:
+//   - manager: The circuit breaker manager
+//   - cfg: The circuit breaker configuration
+//
+// Returns:
+//   - error: An error describing the first invalid value, nil otherwise
+func ApplyCircuitBreakerConfig(manager *circuitbreaker.Manager, cfg *config.CircuitBreakerConfigEntry) error {
+	if !cfg.CircuitBreaker.Enable {
+		manager.Configure(nil, nil, nil)
+		return nil
+	}
+
+	dependencies, routes, routeDefault, err := circuitBreakerPolicies(cfg)
+	if err != nil {
+		return err
+	}
+	manager.Configure(dependencies, routes, routeDefault)
+	return nil
+}
 
-	resource.LoggerService.Info("Circuit breaker manager initialized successfully")
+// ValidateCircuitBreakerConfig checks the default policy, the dependencies
+// and the route rules of the circuit breaker configuration.
+//
+// Parameters:
+//   - cfg: The circuit breaker configuration
+//
+// Returns:
+//   - error: An error describing the first invalid value, nil otherwise
+func ValidateCircuitBreakerConfig(cfg *config.CircuitBreakerConfigEntry) error {
+	_, _, _, err := circuitBreakerPolicies(cfg)
+	return err
 }
 
-// createServiceCircuitBreakers creates circuit breakers for critical services
-func createServiceCircuitBreakers() {
-	// Database circuit breaker
-	if resource.MySQLClient != nil {
-		circuitBreakerManager.GetOrCreateBreaker("mysql", circuitbreaker.Config{
-			MaxRequests: 5,
-			Interval:    30 * time.Second,
-			Timeout:     60 * time.Second,
-			ReadyToTrip: func(counts circuitbreaker.Counts) bool {
-				return counts.Requests >= 10 &&
-					float64(counts.TotalFailures)/float64(counts.Requests) >= 0.5
-			},
-			IsSuccessful: func(err error) bool {
-				return err == nil
-			},
-		})
-	}
-
-	// Redis circuit breaker
-	if resource.RedisClient != nil {
-		circuitBreakerManager.GetOrCreateBreaker("redis", circuitbreaker.Config{
-			MaxRequests: 10,
-			Interval:    30 * time.Second,
-			Timeout:     30 * time.Second,
-			ReadyToTrip: func(counts circuitbreaker.Counts) bool {
-				return counts.Requests >= 15 &&
-					float64(counts.TotalFailures)/float64(counts.Requests) >= 0.6
-			},
-			IsSuccessful: func(err error) bool {
-				return err == nil
-			},
-		})
-	}
-
-	// External API circuit breaker
-	circuitBreakerManager.GetOrCreateBreaker("external-api", circuitbreaker.Config{
-		MaxRequests: 3,
-		Interval:    60 * time.Second,
-		Timeout:     120 * time.Second,
-		ReadyToTrip: func(counts circuitbreaker.Counts) bool {
-			return counts.Requests >= 20 &&
-				float64(counts.TotalFailures)/float64(counts.Requests) >= 0.7
-		},
-		IsSuccessful: func(err error) bool {
-			return err == nil
-		},
-	})
+// circuitBreakerPolicies converts the configuration into the policies of
+// the dependencies, the route rules and the default route policy, nil
+// unless GuardAllRoutes is set.
+func circuitBreakerPolicies(cfg *config.CircuitBreakerConfigEntry) (map[string]circuitbreaker.Policy,
+	[]circuitbreaker.RouteRule, *circuitbreaker.Policy, error) {
+	cb := &cfg.CircuitBreaker
+
+	def := circuitBreakerPolicy(cb.Default, config.CircuitBreakerPolicy{})
+	if err := def.Validate(); err != nil {
+		return nil, nil, nil, fmt.Errorf("circuit breaker default policy: %w", err)
+	}
+
+	names := make(map[string]bool, len(cb.Dependencies)+len(cb.Routes))
+	dependencies := make(map[string]circuitbreaker.Policy, len(cb.Dependencies))
+	for _, d := range cb.Dependencies {
+		if d.Name == "" {
+			return nil, nil, nil, fmt.Errorf("circuit breaker dependency name must not be empty")
+		}
+		if names[d.Name] {
+			return nil, nil, nil, fmt.Errorf("duplicate circuit breaker %q", d.Name)
+		}
+		names[d.Name] = true
+
+		p := circuitBreakerPolicy(d.CircuitBreakerPolicy, cb.Default)
+		if err := p.Validate(); err != nil {
+			return nil, nil, nil, fmt.Errorf("circuit breaker %s: %w", d.Name, err)
+		}
+		dependencies[d.Name] = p
+	}
+
+	routes := make([]circuitbreaker.RouteRule, 0, len(cb.Routes))
+	for _, r := range cb.Routes {
+		rule := circuitbreaker.RouteRule{
+			Name:    r.Name,
+			Method:  r.Method,
+			Pattern: r.Pattern,
+			Policy:  circuitBreakerPolicy(r.CircuitBreakerPolicy, cb.Default),
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, nil, nil, fmt.Errorf("circuit breaker: %w", err)
+		}
+		if names[rule.BreakerName()] {
+			return nil, nil, nil, fmt.Errorf("duplicate circuit breaker %q", rule.BreakerName())
+		}
+		names[rule.BreakerName()] = true
+		routes = append(routes, rule)
+	}
+
+	if !cb.GuardAllRoutes {
+		return dependencies, routes, nil, nil
+	}
+	return dependencies, routes, &def, nil
+}
+
+// circuitBreakerPolicy converts a configured policy, the zero values being
+// replaced by the values of the default policy.
+func circuitBreakerPolicy(p, def config.CircuitBreakerPolicy) circuitbreaker.Policy {
+	if p.MaxRequests == 0 {
+		p.MaxRequests = def.MaxRequests
+	}
+	if p.Interval == 0 {
+		p.Interval = def.Interval
+	}
+	if p.BucketCount == 0 {
+		p.BucketCount = def.BucketCount
+	}
+	if p.Timeout == 0 {
+		p.Timeout = def.Timeout
+	}
+	if p.MaxTimeout == 0 {
+		p.MaxTimeout = def.MaxTimeout
+	}
+	if p.BackoffMultiplier == 0 {
+		p.BackoffMultiplier = def.BackoffMultiplier
+	}
+	if p.MinRequests == 0 {
+		p.MinRequests = def.MinRequests
+	}
+	if p.FailureRate == 0 {
+		p.FailureRate = def.FailureRate
+	}
+	if p.ConsecutiveFailures == 0 {
+		p.ConsecutiveFailures = def.ConsecutiveFailures
+	}
+	if p.SlowCallDuration == 0 {
+		p.SlowCallDuration = def.SlowCallDuration
+	}
+	if p.SlowCallRate == 0 {
+		p.SlowCallRate = def.SlowCallRate
+	}
+	if len(p.FailureStatusCodes) == 0 {
+		p.FailureStatusCodes = def.FailureStatusCodes
+	}
+
+	return circuitbreaker.Policy{
+		MaxRequests:         p.MaxRequests,
+		Interval:            time.Duration(p.Interval) * time.Second,
+		BucketCount:         p.BucketCount,
+		Timeout:             time.Duration(p.Timeout) * time.Second,
+		MaxTimeout:          time.Duration(p.MaxTimeout) * time.Second,
+		BackoffMultiplier:   p.BackoffMultiplier,
+		MinRequests:         p.MinRequests,
+		FailureRate:         p.FailureRate,
+		ConsecutiveFailures: p.ConsecutiveFailures,
+		SlowCallDuration:    time.Duration(p.SlowCallDuration) * time.Millisecond,
+		SlowCallRate:        p.SlowCallRate,
+		FailureStatusCodes:  p.FailureStatusCodes,
+	}
+}
+
+// watchCircuitBreakerReload reloads the circuit breaker configuration when
+// SIGHUP is received. A failed reload is logged and the current
+// configuration is kept.
+func watchCircuitBreakerReload() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		for range sigChan {
+			if err := ReloadCircuitBreaker(); err != nil {
+				resource.LoggerService.Error(fmt.Sprintf("Failed to reload circuit breaker configuration: %v", err))
+				continue
+			}
+			resource.LoggerService.Info("🔄 Circuit breaker configuration reloaded")
+		}
+	}()
 }
 
-// GetCircuitBreakerManager returns the global circuit breaker manager
+// GetCircuitBreakerManager returns the global circuit breaker manager, nil
+// if the circuit breakers are disabled.
 func GetCircuitBreakerManager() *middleware.CircuitBreakerManager {
-	return circuitBreakerManager
+	return resource.CircuitBreakerManager
 }
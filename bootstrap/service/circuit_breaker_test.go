@@ -0,0 +1,80 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+)
+
+// TestValidateCircuitBreakerConfig tests the validation of the default
+// policy, the dependencies and the route rules.
+func TestValidateCircuitBreakerConfig(t *testing.T) {
+	validDefault := config.CircuitBreakerPolicy{
+		MaxRequests: 10, Interval: 60, Timeout: 30, MinRequests: 20, FailureRate: 0.6,
+		FailureStatusCodes: []string{"5xx"},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.CircuitBreakerConfigEntry)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(cfg *config.CircuitBreakerConfigEntry) {}},
+		{name: "default without threshold", modify: func(cfg *config.CircuitBreakerConfigEntry) {
+			cfg.CircuitBreaker.Default = config.CircuitBreakerPolicy{}
+		}, wantErr: true},
+		{name: "dependency without name", modify: func(cfg *config.CircuitBreakerConfigEntry) {
+			cfg.CircuitBreaker.Dependencies = append(cfg.CircuitBreaker.Dependencies, config.CircuitBreakerDependency{})
+		}, wantErr: true},
+		{name: "duplicate dependency", modify: func(cfg *config.CircuitBreakerConfigEntry) {
+			cfg.CircuitBreaker.Dependencies = append(cfg.CircuitBreaker.Dependencies, cfg.CircuitBreaker.Dependencies[0])
+		}, wantErr: true},
+		{name: "invalid failure rate", modify: func(cfg *config.CircuitBreakerConfigEntry) {
+			cfg.CircuitBreaker.Dependencies[0].FailureRate = 2
+		}, wantErr: true},
+		{name: "invalid status code", modify: func(cfg *config.CircuitBreakerConfigEntry) {
+			cfg.CircuitBreaker.Routes[0].FailureStatusCodes = []string{"abc"}
+		}, wantErr: true},
+		{name: "relative pattern", modify: func(cfg *config.CircuitBreakerConfigEntry) {
+			cfg.CircuitBreaker.Routes[0].Pattern = "api/v1/users"
+		}, wantErr: true},
+		{name: "route named as dependency", modify: func(cfg *config.CircuitBreakerConfigEntry) {
+			cfg.CircuitBreaker.Routes[0].Name = "mysql"
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.CircuitBreakerConfigEntry{}
+			cfg.CircuitBreaker.Enable = true
+			cfg.CircuitBreaker.Default = validDefault
+			cfg.CircuitBreaker.Dependencies = []config.CircuitBreakerDependency{{Name: "mysql"}}
+			cfg.CircuitBreaker.Routes = []config.CircuitBreakerRoute{{Method: "GET", Pattern: "/api/v1/users/:id"}}
+			tt.modify(cfg)
+
+			err := ValidateCircuitBreakerConfig(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCircuitBreakerConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestCircuitBreakerPolicy tests that the unset fields of a policy are
+// taken from the default policy.
+func TestCircuitBreakerPolicy(t *testing.T) {
+	def := config.CircuitBreakerPolicy{
+		MaxRequests: 10, Interval: 60, Timeout: 30, MinRequests: 20, FailureRate: 0.6,
+		FailureStatusCodes: []string{"5xx"},
+	}
+
+	p := circuitBreakerPolicy(config.CircuitBreakerPolicy{MaxRequests: 3, Timeout: 120}, def)
+	if p.MaxRequests != 3 || p.Timeout != 120*time.Second {
+		t.Errorf("configured values not kept: %+v", p)
+	}
+	if p.Interval != 60*time.Second || p.MinRequests != 20 || p.FailureRate != 0.6 ||
+		len(p.FailureStatusCodes) != 1 {
+		t.Errorf("default values not applied: %+v", p)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
 
 	_ "github.com/ClickHouse/clickhouse-go/v2"
 )
@@ -73,6 +74,7 @@ func InitClickHouseClient() error {
 
 	// Store the initialized client in the global resource
 	resource.ClickHouseClient = db
+	registerClientMetrics(clientmetrics.ClientClickHouse, clientmetrics.NewDBStatsCollector(clientmetrics.ClientClickHouse, db))
 	resource.LoggerService.Info("✅ successfully connected to clickhouse")
 
 	return nil
@@ -182,6 +184,7 @@ func CloseClickHouse() error {
 
 	// Reset the global ClickHouse client to nil
 	resource.ClickHouseClient = nil
+	clientmetrics.Unregister(clientmetrics.ClientClickHouse)
 
 	if resource.LoggerService != nil {
 		resource.LoggerService.Info("🛑 successfully closed clickhouse connection")
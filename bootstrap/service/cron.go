@@ -9,6 +9,7 @@ import (
 	"github.com/xiebingnote/go-gin-project/library/resource"
 
 	"github.com/go-co-op/gocron/v2"
+	"go.uber.org/zap"
 )
 
 // InitCron initializes the Cron scheduler with comprehensive configuration.
@@ -155,6 +156,12 @@ func createCronScheduler(ctx context.Context) (gocron.Scheduler, error) {
 	var options []gocron.SchedulerOption
 	options = append(options, gocron.WithLocation(location))
 
+	// Route the scheduler logs to the cron module logger, whose level can be
+	// adjusted at runtime
+	if resource.CronLogger != nil {
+		options = append(options, gocron.WithLogger(cronLogger{l: resource.CronLogger.Sugar()}))
+	}
+
 	// Add max concurrent jobs limit if configured
 	if cfg.MaxConcurrentJobs > 0 {
 		options = append(options, gocron.WithLimitConcurrentJobs(uint(cfg.MaxConcurrentJobs), gocron.LimitModeWait))
@@ -345,3 +352,20 @@ func CloseCron(ctx context.Context) error {
 
 	return nil
 }
+
+// cronLogger adapts a zap logger to the gocron.Logger interface.
+type cronLogger struct {
+	l *zap.SugaredLogger
+}
+
+// Debug implements gocron.Logger.
+func (c cronLogger) Debug(msg string, args ...any) { c.l.Debugw(msg, args...) }
+
+// Error implements gocron.Logger.
+func (c cronLogger) Error(msg string, args ...any) { c.l.Errorw(msg, args...) }
+
+// Info implements gocron.Logger.
+func (c cronLogger) Info(msg string, args ...any) { c.l.Infow(msg, args...) }
+
+// Warn implements gocron.Logger.
+func (c cronLogger) Warn(msg string, args ...any) { c.l.Warnw(msg, args...) }
@@ -10,6 +10,10 @@ import (
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/httpclient"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
 
 	"github.com/olivere/elastic/v7"
 )
@@ -47,9 +51,14 @@ func InitElasticSearchClient() error {
 	// Configure the HTTP transport
 	httpTransport := ConfigureElasticSearchTransport(cfg)
 
-	// Create an HTTP client with the configured transport
+	// Record the metrics of each call
+	metricsTransport := clientmetrics.NewTransport(clientmetrics.ClientElasticsearch, httpTransport)
+
+	// Create an HTTP client with the configured transport, creating a span
+	// for each call and propagating the request ID of the request context
+	// to Elasticsearch
 	httpClient := &http.Client{
-		Transport: httpTransport,
+		Transport: httpclient.NewTransport(tracing.NewTransport(metricsTransport)),
 		Timeout:   30 * time.Second, // Set default timeout
 	}
 
@@ -212,6 +221,9 @@ func CloseElasticSearch() error {
 		return nil
 	}
 
+	// Ship the buffered logs before the shared client is stopped
+	closeLogSink(logger.SinkElasticsearch)
+
 	// Attempt to close the Elasticsearch connection
 	resource.ElasticSearchClient.Stop()
 
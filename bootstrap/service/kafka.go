@@ -3,11 +3,15 @@ package service
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
 
 	"github.com/IBM/sarama"
 )
@@ -97,12 +101,36 @@ func InitKafkaClient(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize kafka consumer group: %w", err)
 	}
 
+	// Initialize the client of the admin operations
+	resource.KafkaClient, err = sarama.NewClient(cfg.Kafka.Brokers, consumerConfig)
+	if err != nil {
+		cleanupKafkaClients()
+		return fmt.Errorf("failed to initialize kafka client: %w", err)
+	}
+
+	// Initialize the transactional producer if a transaction ID is configured
+	if cfg.Advanced.ProducerTransactionalID != "" {
+		resource.KafkaTransactionalProducer, err = sarama.NewSyncProducer(cfg.Kafka.Brokers,
+			ConfigureKafkaTransactionalProducer(cfg, version))
+		if err != nil {
+			cleanupKafkaClients()
+			return fmt.Errorf("failed to initialize kafka transactional producer: %w", err)
+		}
+	}
+
 	// Test the connection (using a lighter-weight approach)
 	if err := TestKafkaConnection(cfg); err != nil {
 		cleanupKafkaClients()
 		return fmt.Errorf("kafka connection test failed: %w", err)
 	}
 
+	// Export the metrics sarama records, the consumer and the consumer group
+	// share the registry of their configuration
+	registerClientMetrics(clientmetrics.ClientKafkaProducer,
+		clientmetrics.NewSaramaCollector(clientmetrics.ClientKafkaProducer, producerConfig.MetricRegistry))
+	registerClientMetrics(clientmetrics.ClientKafkaConsumer,
+		clientmetrics.NewSaramaCollector(clientmetrics.ClientKafkaConsumer, consumerConfig.MetricRegistry))
+
 	// Start the background health check
 	startKafkaHealthCheck(ctx)
 
@@ -140,6 +168,22 @@ func cleanupKafkaClients() {
 		}
 		resource.KafkaConsumerGroup = nil
 	}
+
+	// Close the client if it has been initialized
+	if resource.KafkaClient != nil {
+		if err := resource.KafkaClient.Close(); err != nil {
+			resource.LoggerService.Error(fmt.Sprintf("failed to close kafka client during cleanup: %v", err))
+		}
+		resource.KafkaClient = nil
+	}
+
+	// Close the transactional producer if it has been initialized
+	if resource.KafkaTransactionalProducer != nil {
+		if err := resource.KafkaTransactionalProducer.Close(); err != nil {
+			resource.LoggerService.Error(fmt.Sprintf("failed to close kafka transactional producer during cleanup: %v", err))
+		}
+		resource.KafkaTransactionalProducer = nil
+	}
 }
 
 // ValidateKafkaConfig validates the Kafka configuration.
@@ -152,10 +196,16 @@ func cleanupKafkaClients() {
 //     - Version (Kafka version)
 //     - GroupID (Kafka consumer group ID)
 //  3. Advanced settings are valid:
-//     - ProducerMaxRetry (maximum retry count for the producer)
+//     - ProducerMaxRetry (maximum retry count for the idempotent producer)
+//     - ProducerFlushFrequency and ProducerFlushMessages (batching)
 //     - ConsumerSessionTimeout (session timeout for the consumer)
 //     - HeartbeatInterval (heartbeat interval for the consumer)
 //     - MaxProcessingTime (maximum processing time for the consumer)
+//     - ConsumerConcurrency (messages processed at once per topic)
+//     - MinReconnectBackoff and MaxReconnectBackoff (consumer reconnect delays)
+//     - ConsumerStartPosition (earliest, latest or an RFC 3339 time)
+//  4. The outbox settings are valid when the outbox is enabled
+//  5. The lag monitor settings are valid when the lag monitor is enabled
 func ValidateKafkaConfig(cfg *config.KafkaConfigEntry) error {
 	if cfg == nil {
 		return fmt.Errorf("kafka configuration is nil")
@@ -184,6 +234,13 @@ func ValidateKafkaConfig(cfg *config.KafkaConfigEntry) error {
 	if cfg.Advanced.ProducerMaxRetry < 0 {
 		return fmt.Errorf("invalid producer max retry count: %d, must be non-negative", cfg.Advanced.ProducerMaxRetry)
 	}
+	if cfg.Advanced.ProducerMaxRetry == 0 {
+		return fmt.Errorf("invalid producer max retry count: 0, the idempotent producer requires at least 1")
+	}
+	if cfg.Advanced.ProducerFlushFrequency < 0 || cfg.Advanced.ProducerFlushMessages < 0 {
+		return fmt.Errorf("invalid producer flush settings: %d ms, %d messages, must be non-negative",
+			cfg.Advanced.ProducerFlushFrequency, cfg.Advanced.ProducerFlushMessages)
+	}
 	if cfg.Advanced.ConsumerSessionTimeout <= 0 {
 		return fmt.Errorf("invalid consumer session timeout: %d ms, must be greater than 0", cfg.Advanced.ConsumerSessionTimeout)
 	}
@@ -193,6 +250,41 @@ func ValidateKafkaConfig(cfg *config.KafkaConfigEntry) error {
 	if cfg.Advanced.MaxProcessingTime <= 0 {
 		return fmt.Errorf("invalid max processing time: %d ms, must be greater than 0", cfg.Advanced.MaxProcessingTime)
 	}
+	if cfg.Advanced.ConsumerConcurrency < 0 {
+		return fmt.Errorf("invalid consumer concurrency: %d, must be non-negative", cfg.Advanced.ConsumerConcurrency)
+	}
+	if cfg.Advanced.MinReconnectBackoff < 0 || cfg.Advanced.MaxReconnectBackoff < 0 {
+		return fmt.Errorf("invalid reconnect backoff: min %d ms, max %d ms, must be non-negative",
+			cfg.Advanced.MinReconnectBackoff, cfg.Advanced.MaxReconnectBackoff)
+	}
+	switch position := strings.ToLower(cfg.Advanced.ConsumerStartPosition); position {
+	case "", "earliest", "latest":
+	default:
+		if _, err := time.Parse(time.RFC3339, cfg.Advanced.ConsumerStartPosition); err != nil {
+			return fmt.Errorf("invalid consumer start position: %q, must be earliest, latest or an RFC 3339 time",
+				cfg.Advanced.ConsumerStartPosition)
+		}
+	}
+	if cfg.Outbox.Enable {
+		if cfg.Outbox.Database != "mysql" && cfg.Outbox.Database != "postgresql" {
+			return fmt.Errorf("unsupported outbox database: %s", cfg.Outbox.Database)
+		}
+		if cfg.Outbox.BatchSize < 0 || cfg.Outbox.Interval < 0 || cfg.Outbox.Retention < 0 || cfg.Outbox.MaxAttempts < 0 {
+			return fmt.Errorf("invalid outbox settings: batch size %d, interval %d ms, retention %d h, max attempts %d, must be non-negative",
+				cfg.Outbox.BatchSize, cfg.Outbox.Interval, cfg.Outbox.Retention, cfg.Outbox.MaxAttempts)
+		}
+	}
+	if cfg.LagMonitor.Enable {
+		if cfg.LagMonitor.Interval < 0 || cfg.LagMonitor.WarnThreshold < 0 || cfg.LagMonitor.CriticalThreshold < 0 {
+			return fmt.Errorf("invalid lag monitor settings: interval %d ms, thresholds %d and %d, must be non-negative",
+				cfg.LagMonitor.Interval, cfg.LagMonitor.WarnThreshold, cfg.LagMonitor.CriticalThreshold)
+		}
+		if cfg.LagMonitor.WarnThreshold > 0 && cfg.LagMonitor.CriticalThreshold > 0 &&
+			cfg.LagMonitor.CriticalThreshold < cfg.LagMonitor.WarnThreshold {
+			return fmt.Errorf("lag monitor critical threshold (%d) must not be less than warn threshold (%d)",
+				cfg.LagMonitor.CriticalThreshold, cfg.LagMonitor.WarnThreshold)
+		}
+	}
 
 	// Check logical consistency
 	if cfg.Advanced.HeartbeatInterval >= cfg.Advanced.ConsumerSessionTimeout {
@@ -250,7 +342,14 @@ func ConfigureKafkaProducer(cfg *config.KafkaConfigEntry, version sarama.KafkaVe
 
 	// Set flush frequency and limits
 	configSarama.Producer.Flush.Frequency = 500 * time.Millisecond
-	configSarama.Producer.Flush.MaxMessages = 100
+	if cfg.Advanced.ProducerFlushFrequency > 0 {
+		configSarama.Producer.Flush.Frequency = time.Duration(cfg.Advanced.ProducerFlushFrequency) * time.Millisecond
+	}
+	configSarama.Producer.Flush.Messages = 100
+	if cfg.Advanced.ProducerFlushMessages > 0 {
+		configSarama.Producer.Flush.Messages = cfg.Advanced.ProducerFlushMessages
+	}
+	configSarama.Producer.Flush.MaxMessages = max(100, configSarama.Producer.Flush.Messages)
 	configSarama.Producer.Flush.Bytes = 1024 * 1024 // 1MB
 
 	// Apply common network settings first
@@ -265,6 +364,39 @@ func ConfigureKafkaProducer(cfg *config.KafkaConfigEntry, version sarama.KafkaVe
 	return configSarama
 }
 
+// ConfigureKafkaTransactionalProducer configures the options of the
+// transactional producer, the idempotent producer options with a
+// transaction ID.
+//
+// The transaction ID is the configured prefix followed by the host name, so
+// that the instances of the service do not fence each other.
+//
+// Parameters:
+//   - cfg: A pointer to the Kafka configuration containing connection settings
+//   - version: The parsed Kafka version
+//
+// Returns:
+//   - A configured *sarama.Config transactional producer instance
+func ConfigureKafkaTransactionalProducer(cfg *config.KafkaConfigEntry, version sarama.KafkaVersion) *sarama.Config {
+	configSarama := ConfigureKafkaProducer(cfg, version)
+
+	transactionalID := cfg.Advanced.ProducerTransactionalID
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		transactionalID += "-" + hostname
+	}
+	configSarama.Producer.Transaction.ID = transactionalID
+
+	// The sync producer waits for each message, so every flush trigger is
+	// cleared for it to be sent right away instead of lingering until the
+	// producer timeout
+	configSarama.Producer.Flush.Frequency = 0
+	configSarama.Producer.Flush.Messages = 0
+	configSarama.Producer.Flush.MaxMessages = 0
+	configSarama.Producer.Flush.Bytes = 0
+
+	return configSarama
+}
+
 // ConfigureKafkaConsumer configures Kafka consumer options
 //
 // Parameters:
@@ -279,16 +411,24 @@ func ConfigureKafkaConsumer(cfg *config.KafkaConfigEntry, version sarama.KafkaVe
 
 	// Consumer settings
 	//
-	//  1. Set the initial offset to the oldest available message
-	//  2. Enable auto-commit with an interval of 1 second
+	//  1. Set the initial offset to the oldest available message, or the
+	//     newest one if the start position is latest, a start position time
+	//     being resolved by the consumers
+	//  2. Enable auto-commit with an interval of 1 second, unless the
+	//     consumers commit each processed message
 	//  3. Set the group rebalance strategy to round-robin
 	//  4. Set the session timeout to the value in the configuration file
 	//  5. Set the heartbeat interval to the value in the configuration file
 	//  6. Set the maximum processing time to the value in the configuration file
 	//  7. Set the maximum wait time to 250 milliseconds
 	//  8. Set the minimum and maximum fetch sizes to 1MB and 10MB respectively
+	//  9. Read the committed messages only when the transactional producer is
+	//     used, so that the messages of aborted transactions are never consumed
 	configSarama.Consumer.Offsets.Initial = sarama.OffsetOldest
-	configSarama.Consumer.Offsets.AutoCommit.Enable = true
+	if strings.EqualFold(cfg.Advanced.ConsumerStartPosition, "latest") {
+		configSarama.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
+	configSarama.Consumer.Offsets.AutoCommit.Enable = !cfg.Advanced.ConsumerManualCommit
 	configSarama.Consumer.Offsets.AutoCommit.Interval = 1 * time.Second
 	configSarama.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{
 		sarama.NewBalanceStrategyRange(),
@@ -304,6 +444,10 @@ func ConfigureKafkaConsumer(cfg *config.KafkaConfigEntry, version sarama.KafkaVe
 	configSarama.Consumer.Fetch.Default = 1024 * 1024  // 1MB
 	configSarama.Consumer.Fetch.Max = 1024 * 1024 * 10 // 10MB
 
+	if cfg.Advanced.ProducerTransactionalID != "" {
+		configSarama.Consumer.IsolationLevel = sarama.ReadCommitted
+	}
+
 	// Apply common network settings
 	configureNetworkSettings(configSarama)
 
@@ -369,6 +513,12 @@ func CloseKafka() error {
 	// Stop the health check goroutine
 	stopKafkaHealthCheck()
 
+	// Ship the buffered logs before the shared producer is closed
+	closeLogSink(logger.SinkKafka)
+
+	clientmetrics.Unregister(clientmetrics.ClientKafkaProducer)
+	clientmetrics.Unregister(clientmetrics.ClientKafkaConsumer)
+
 	// Close the producer
 	if resource.KafkaProducer != nil {
 		if err := resource.KafkaProducer.Close(); err != nil {
@@ -393,6 +543,22 @@ func CloseKafka() error {
 		resource.KafkaConsumerGroup = nil
 	}
 
+	// Close the transactional producer
+	if resource.KafkaTransactionalProducer != nil {
+		if err := resource.KafkaTransactionalProducer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close kafka transactional producer: %w", err))
+		}
+		resource.KafkaTransactionalProducer = nil
+	}
+
+	// Close the client
+	if resource.KafkaClient != nil {
+		if err := resource.KafkaClient.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close kafka client: %w", err))
+		}
+		resource.KafkaClient = nil
+	}
+
 	// If any of the close operations fail, return a combined error
 	if len(errs) > 0 {
 		return fmt.Errorf("failed to close all kafka connections: %v", errs)
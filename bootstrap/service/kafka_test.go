@@ -37,15 +37,28 @@ func setupTestKafkaConfig() *config.KafkaConfigEntry {
 			Version:            "2.8.0",
 		},
 		Advanced: struct {
-			ProducerMaxRetry       int   `toml:"ProducerMaxRetry"`
-			ConsumerSessionTimeout int64 `toml:"ConsumerSessionTimeout"`
-			HeartbeatInterval      int64 `toml:"HeartbeatInterval"`
-			MaxProcessingTime      int64 `toml:"MaxProcessingTime"`
+			ProducerMaxRetry        int    `toml:"ProducerMaxRetry"`
+			ProducerFlushFrequency  int64  `toml:"ProducerFlushFrequency"`
+			ProducerFlushMessages   int    `toml:"ProducerFlushMessages"`
+			ProducerTransactionalID string `toml:"ProducerTransactionalID"`
+			ConsumerSessionTimeout  int64  `toml:"ConsumerSessionTimeout"`
+			HeartbeatInterval       int64  `toml:"HeartbeatInterval"`
+			MaxProcessingTime       int64  `toml:"MaxProcessingTime"`
+			ConsumerConcurrency     int    `toml:"ConsumerConcurrency"`
+			MinReconnectBackoff     int64  `toml:"MinReconnectBackoff"`
+			MaxReconnectBackoff     int64  `toml:"MaxReconnectBackoff"`
+			ConsumerStartPosition   string `toml:"ConsumerStartPosition"`
+			ConsumerManualCommit    bool   `toml:"ConsumerManualCommit"`
 		}{
 			ProducerMaxRetry:       3,
+			ProducerFlushFrequency: 500, // 500 milliseconds
+			ProducerFlushMessages:  100,
 			ConsumerSessionTimeout: 30000, // 30 seconds
 			HeartbeatInterval:      3000,  // 3 seconds
 			MaxProcessingTime:      60000, // 60 seconds
+			MinReconnectBackoff:    1000,  // 1 second
+			MaxReconnectBackoff:    30000, // 30 seconds
+			ConsumerStartPosition:  "earliest",
 		},
 	}
 }
@@ -128,6 +141,26 @@ func TestValidateKafkaConfig(t *testing.T) {
 			expectError: true,
 			errorMsg:    "invalid producer max retry count: -1, must be non-negative",
 		},
+		{
+			name: "zero producer max retry",
+			config: func() *config.KafkaConfigEntry {
+				cfg := setupTestKafkaConfig()
+				cfg.Advanced.ProducerMaxRetry = 0
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "invalid producer max retry count: 0, the idempotent producer requires at least 1",
+		},
+		{
+			name: "invalid producer flush settings",
+			config: func() *config.KafkaConfigEntry {
+				cfg := setupTestKafkaConfig()
+				cfg.Advanced.ProducerFlushMessages = -1
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "invalid producer flush settings: 500 ms, -1 messages, must be non-negative",
+		},
 		{
 			name: "invalid consumer session timeout",
 			config: func() *config.KafkaConfigEntry {
@@ -169,6 +202,59 @@ func TestValidateKafkaConfig(t *testing.T) {
 			expectError: true,
 			errorMsg:    "heartbeat interval (30000 ms) must be less than session timeout (30000 ms)",
 		},
+		{
+			name: "invalid consumer concurrency",
+			config: func() *config.KafkaConfigEntry {
+				cfg := setupTestKafkaConfig()
+				cfg.Advanced.ConsumerConcurrency = -1
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "invalid consumer concurrency: -1, must be non-negative",
+		},
+		{
+			name: "invalid reconnect backoff",
+			config: func() *config.KafkaConfigEntry {
+				cfg := setupTestKafkaConfig()
+				cfg.Advanced.MinReconnectBackoff = -1
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "invalid reconnect backoff: min -1 ms, max 30000 ms, must be non-negative",
+		},
+		{
+			name: "invalid consumer start position",
+			config: func() *config.KafkaConfigEntry {
+				cfg := setupTestKafkaConfig()
+				cfg.Advanced.ConsumerStartPosition = "yesterday"
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    `invalid consumer start position: "yesterday", must be earliest, latest or an RFC 3339 time`,
+		},
+		{
+			name: "unsupported outbox database",
+			config: func() *config.KafkaConfigEntry {
+				cfg := setupTestKafkaConfig()
+				cfg.Outbox.Enable = true
+				cfg.Outbox.Database = "tdengine"
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "unsupported outbox database: tdengine",
+		},
+		{
+			name: "lag monitor critical threshold below warn threshold",
+			config: func() *config.KafkaConfigEntry {
+				cfg := setupTestKafkaConfig()
+				cfg.LagMonitor.Enable = true
+				cfg.LagMonitor.WarnThreshold = 1000
+				cfg.LagMonitor.CriticalThreshold = 100
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "lag monitor critical threshold (100) must not be less than warn threshold (1000)",
+		},
 		{
 			name:        "valid config",
 			config:      setupTestKafkaConfig(),
@@ -260,6 +346,29 @@ func TestConfigureKafkaProducer(t *testing.T) {
 	}
 }
 
+// TestConfigureKafkaTransactionalProducer tests that the transactional
+// producer has a transaction ID and no flush trigger, so that a message is
+// sent as soon as it is produced rather than when a batch is full.
+func TestConfigureKafkaTransactionalProducer(t *testing.T) {
+	cfg := setupTestKafkaConfig()
+	version, err := sarama.ParseKafkaVersion(cfg.Kafka.Version)
+	if err != nil {
+		t.Fatalf("Failed to parse Kafka version: %v", err)
+	}
+
+	producerConfig := ConfigureKafkaTransactionalProducer(cfg, version)
+
+	if producerConfig.Producer.Transaction.ID == "" {
+		t.Errorf("Expected Producer.Transaction.ID to be set")
+	}
+
+	flush := producerConfig.Producer.Flush
+	if flush.Frequency != 0 || flush.Messages != 0 || flush.MaxMessages != 0 || flush.Bytes != 0 {
+		t.Errorf("Expected every flush trigger to be 0, got Frequency=%v Messages=%d MaxMessages=%d Bytes=%d",
+			flush.Frequency, flush.Messages, flush.MaxMessages, flush.Bytes)
+	}
+}
+
 // TestConfigureKafkaConsumer tests the ConfigureKafkaConsumer function by verifying
 // the basic Kafka consumer settings, advanced settings, and network settings are
 // properly configured.
@@ -315,6 +424,20 @@ func TestConfigureKafkaConsumer(t *testing.T) {
 	if consumerConfig.Net.DialTimeout != 30*time.Second {
 		t.Errorf("Expected Net.DialTimeout to be 30s, got %v", consumerConfig.Net.DialTimeout)
 	}
+
+	if consumerConfig.Consumer.IsolationLevel != sarama.ReadUncommitted {
+		t.Errorf("Expected Consumer.IsolationLevel to be ReadUncommitted without transactions")
+	}
+
+	// The messages of the aborted transactions are not consumed
+	cfg.Advanced.ProducerTransactionalID = "test-tx"
+	consumerConfig = ConfigureKafkaConsumer(cfg, version)
+	if consumerConfig.Consumer.IsolationLevel != sarama.ReadCommitted {
+		t.Errorf("Expected Consumer.IsolationLevel to be ReadCommitted with transactions")
+	}
+	if err := consumerConfig.Validate(); err != nil {
+		t.Errorf("Expected a valid consumer configuration, got %v", err)
+	}
 }
 
 // TestConfigureNetworkSettings verifies that the configureNetworkSettings function
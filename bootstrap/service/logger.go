@@ -72,6 +72,33 @@ func InitLoggerService(ctx context.Context) error {
 	// Store the logger in the resource package
 	resource.LoggerService = loggerInstance
 
+	// Apply the configured module levels
+	for module, level := range config.LogConfig.Modules {
+		lvl, err := logger.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("invalid level for log module %s: %w", module, err)
+		}
+		if err := logger.DefaultLevels.SetLevel(module, lvl); err != nil {
+			return err
+		}
+	}
+
+	// Create the module loggers, each with its own adjustable level
+	resource.KafkaLogger = logger.DefaultLevels.Named(loggerInstance, logger.ModuleKafka)
+	resource.NsqLogger = logger.DefaultLevels.Named(loggerInstance, logger.ModuleNSQ)
+	resource.HTTPLogger = logger.DefaultLevels.Named(loggerInstance, logger.ModuleHTTP)
+	resource.CronLogger = logger.DefaultLevels.Named(loggerInstance, logger.ModuleCron)
+	resource.AuthLogger = logger.DefaultLevels.Named(loggerInstance, logger.ModuleAuth)
+
+	// Create the access logger writing to its own file
+	if config.LogConfig.Access.Enable {
+		accessLogger, err := createAccessLogger()
+		if err != nil {
+			return fmt.Errorf("failed to create access logger: %w", err)
+		}
+		resource.AccessLogger = accessLogger
+	}
+
 	// Log successful initialization
 	resource.LoggerService.Info("✅ logger service initialized successfully",
 		zap.String("log_dir", config.LogConfig.Log.LogDir),
@@ -257,10 +284,29 @@ func createLoggerInstance(ctx context.Context) (*zap.Logger, error) {
 		options = append(options, logger.WithDisableConsole())
 	}
 
+	// Drop repeated entries of hot paths
+	logCfg := config.LogConfig
+	if logCfg.Sampling.Enable {
+		options = append(options, logger.WithSampling(
+			time.Duration(logCfg.Sampling.Tick)*time.Second, logCfg.Sampling.First, logCfg.Sampling.Thereafter))
+	}
+	if logCfg.RateLimit.Enable {
+		options = append(options, logger.WithRateLimit(
+			time.Duration(logCfg.RateLimit.Window)*time.Second, logCfg.RateLimit.Burst))
+	}
+
+	// Mask sensitive fields before they are written
+	redactor, err := logger.RedactorFromConfig(logCfg)
+	if err != nil {
+		return nil, err
+	}
+	if redactor != nil {
+		options = append(options, logger.WithRedactor(redactor))
+	}
+
 	// Create logger with timeout
 	done := make(chan struct{})
 	var loggerInstance *zap.Logger
-	var err error
 
 	go func() {
 		defer close(done)
@@ -280,6 +326,39 @@ func createLoggerInstance(ctx context.Context) (*zap.Logger, error) {
 	return loggerInstance, nil
 }
 
+// createAccessLogger creates the HTTP access logger configured in the
+// [Access] section of the log configuration.
+//
+// Returns:
+//   - *zap.Logger: The created access logger
+//   - error: An error if the access log file cannot be created, nil otherwise
+func createAccessLogger() (*zap.Logger, error) {
+	cfg := config.LogConfig
+
+	var options []logger.Option
+	options = append(options, logger.WithField("version", config.ServerConfig.Version.Version))
+	if hostname, err := os.Hostname(); err == nil {
+		options = append(options, logger.WithField("hostname", hostname))
+	}
+
+	redactor, err := logger.RedactorFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if redactor != nil {
+		options = append(options, logger.WithRedactor(redactor))
+	}
+
+	return logger.NewAccessLogger(logger.AccessLogOptions{
+		Filename:   filepath.Join(cfg.Log.LogDir, cfg.Access.File),
+		MaxSize:    cfg.Access.MaxSize,
+		MaxAge:     cfg.Access.MaxAge,
+		MaxBackups: cfg.Access.MaxBackups,
+		LocalTime:  cfg.Log.LocalTime,
+		Compress:   cfg.Access.Compress,
+	}, options...)
+}
+
 // CloseLogger closes the logger service and flushes any pending log entries.
 //
 // Parameters:
@@ -290,7 +369,7 @@ func createLoggerInstance(ctx context.Context) (*zap.Logger, error) {
 //
 // The function performs the following operations:
 // 1. Checks if the logger is initialized
-// 2. Flushes any pending log entries
+// 2. Flushes the remote log sinks and any pending log entries
 // 3. Clears the global resource reference
 func CloseLogger(ctx context.Context) error {
 	if resource.LoggerService == nil {
@@ -308,6 +387,11 @@ func CloseLogger(ctx context.Context) error {
 	go func() {
 		defer close(done)
 
+		// Ship the entries buffered by the remote sinks
+		if err := logger.DefaultSinks.Close(closeCtx); err != nil {
+			log.Printf("Warning: log sinks flush failed during close: %v", err)
+		}
+
 		// Sync the logger to flush any pending entries
 		if err := resource.LoggerService.Sync(); err != nil {
 			// Check if this is a common sync error that can be safely ignored
@@ -316,6 +400,13 @@ func CloseLogger(ctx context.Context) error {
 			}
 		}
 
+		// Sync the access log
+		if resource.AccessLogger != nil {
+			if err := resource.AccessLogger.Sync(); err != nil && !isSyncErrorIgnorable(err) {
+				log.Printf("Warning: access logger sync failed during close: %v", err)
+			}
+		}
+
 		done <- nil
 	}()
 
@@ -329,8 +420,9 @@ func CloseLogger(ctx context.Context) error {
 		fmt.Println("Warning: logger close timeout, proceeding anyway")
 	}
 
-	// Clear the global reference
+	// Clear the global references
 	resource.LoggerService = nil
+	resource.AccessLogger = nil
 
 	return nil
 }
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// InitLogSinks starts shipping logs to the remote sinks enabled in the
+// [Sinks] section of the log configuration.
+//
+// It must be called after the clients the sinks reuse are initialized, a
+// sink whose client is not initialized is skipped with a warning. It panics
+// if a sink is misconfigured.
+//
+// Parameters:
+//   - ctx: Context for the initialization.
+func InitLogSinks(ctx context.Context) {
+	if err := InitLogSinksService(ctx); err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("failed to initialize log sinks: %v", err))
+		panic(err.Error())
+	}
+}
+
+// InitLogSinksService adds the enabled remote sinks to logger.DefaultSinks.
+//
+// Parameters:
+//   - _: Context for the initialization.
+//
+// Returns:
+//   - error: An error if a sink is misconfigured, nil otherwise.
+func InitLogSinksService(_ context.Context) error {
+	if config.LogConfig == nil {
+		return fmt.Errorf("log configuration is not initialized")
+	}
+	cfg := &config.LogConfig.Sinks
+
+	if cfg.Kafka.Enable {
+		if resource.KafkaProducer == nil {
+			resource.LoggerService.Warn("⚠️ kafka log sink skipped, kafka producer is not initialized")
+		} else if err := addLogSink(logger.NewKafkaSink(resource.KafkaProducer, cfg.Kafka.Topic), cfg.Kafka.Level); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Elasticsearch.Enable {
+		if resource.ElasticSearchClient == nil {
+			resource.LoggerService.Warn("⚠️ elasticsearch log sink skipped, elasticsearch client is not initialized")
+		} else {
+			sink := logger.NewElasticsearchSink(resource.ElasticSearchClient, cfg.Elasticsearch.IndexPrefix, cfg.Elasticsearch.DateLayout)
+			if err := addLogSink(sink, cfg.Elasticsearch.Level); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.Syslog.Enable {
+		sink, err := logger.NewSyslogSink(cfg.Syslog.Network, cfg.Syslog.Address, cfg.Syslog.Tag, cfg.Syslog.Facility)
+		if err != nil {
+			return err
+		}
+		if err := addLogSink(sink, cfg.Syslog.Level); err != nil {
+			return err
+		}
+	}
+
+	if names := logger.DefaultSinks.Names(); len(names) > 0 {
+		resource.LoggerService.Info("✅ log sinks initialized successfully", zap.Strings("sinks", names))
+	}
+	return nil
+}
+
+// addLogSink adds sink to logger.DefaultSinks with the buffering options of
+// the log configuration.
+//
+// Parameters:
+//   - sink: The sink to add.
+//   - level: The minimum level shipped to the sink.
+//
+// Returns:
+//   - error: An error if the level is invalid or the sink is already added.
+func addLogSink(sink logger.Sink, level string) error {
+	cfg := &config.LogConfig.Sinks
+
+	lvl, err := logger.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid level for log sink %s: %w", sink.Name(), err)
+	}
+
+	return logger.DefaultSinks.Add(sink, logger.SinkOptions{
+		Level:         lvl,
+		BufferSize:    cfg.BufferSize,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: time.Duration(cfg.FlushInterval) * time.Second,
+	})
+}
+
+// closeLogSink flushes and removes the named sink. Clients shared with a
+// sink call it before they are closed so that no buffered entry is lost.
+//
+// Parameters:
+//   - name: The name of the sink.
+func closeLogSink(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := logger.DefaultSinks.Remove(ctx, name); err != nil && resource.LoggerService != nil {
+		resource.LoggerService.Warn(fmt.Sprintf("failed to flush %s log sink: %v", name, err))
+	}
+}
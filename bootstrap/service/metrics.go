@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/middleware"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// InitMetrics registers the HTTP metrics with the buckets of the server
+// configuration.
+//
+// It must run before the HTTP servers are created, whose Prometheus
+// middleware otherwise registers the metrics with the default buckets.
+//
+// Parameters:
+//   - ctx: Context for the operation
+func InitMetrics(_ context.Context) {
+	if err := InitHTTPMetrics(); err != nil {
+		// Log an error message if the metrics cannot be registered.
+		resource.LoggerService.Error(fmt.Sprintf("Failed to initialize metrics: %v", err))
+		panic(fmt.Sprintf("Metrics initialization failed: %v", err))
+	}
+}
+
+// InitHTTPMetrics validates the metrics configuration of the global
+// ServerConfig and registers the HTTP metrics.
+//
+// Returns:
+//   - error: An error if the configuration is invalid or the metrics are
+//     already registered, nil otherwise
+func InitHTTPMetrics() error {
+	if config.ServerConfig == nil {
+		return fmt.Errorf("server configuration is not initialized")
+	}
+
+	cfg := &config.ServerConfig.Metrics
+	if err := ValidateMetricsConfig(cfg); err != nil {
+		return err
+	}
+
+	if err := middleware.InitHTTPMetrics(middleware.HTTPMetricsOptions{
+		DurationBuckets: cfg.DurationBuckets,
+		SizeBuckets:     cfg.SizeBuckets,
+	}); err != nil {
+		return err
+	}
+
+	resource.LoggerService.Info(fmt.Sprintf("✅ successfully initialized HTTP metrics, SLO enabled: %t", cfg.SLO.Enable))
+	return nil
+}
+
+// ValidateMetricsConfig checks the histogram buckets and the service level
+// objectives of the metrics configuration.
+//
+// Parameters:
+//   - cfg: The metrics configuration
+//
+// Returns:
+//   - error: An error describing the first invalid value, nil otherwise
+func ValidateMetricsConfig(cfg *config.MetricsConfig) error {
+	for name, buckets := range map[string][]float64{
+		"DurationBuckets": cfg.DurationBuckets,
+		"SizeBuckets":     cfg.SizeBuckets,
+	} {
+		// Prometheus requires strictly increasing buckets
+		if !sort.Float64sAreSorted(buckets) {
+			return fmt.Errorf("metrics %s must be sorted in increasing order", name)
+		}
+		for i := 1; i < len(buckets); i++ {
+			if buckets[i] == buckets[i-1] {
+				return fmt.Errorf("metrics %s contains the duplicate bucket %v", name, buckets[i])
+			}
+		}
+	}
+
+	if !cfg.SLO.Enable {
+		return nil
+	}
+	if cfg.SLO.AvailabilityObjective <= 0 || cfg.SLO.AvailabilityObjective >= 1 {
+		return fmt.Errorf("SLO AvailabilityObjective must be between 0 and 1, got %v", cfg.SLO.AvailabilityObjective)
+	}
+	if cfg.SLO.LatencyThreshold < 0 {
+		return fmt.Errorf("SLO LatencyThreshold must not be negative, got %d", cfg.SLO.LatencyThreshold)
+	}
+	if cfg.SLO.LatencyThreshold > 0 && (cfg.SLO.LatencyObjective <= 0 || cfg.SLO.LatencyObjective >= 1) {
+		return fmt.Errorf("SLO LatencyObjective must be between 0 and 1, got %v", cfg.SLO.LatencyObjective)
+	}
+	return nil
+}
+
+// registerClientMetrics registers the pool metrics of a client created at
+// boot. A failure is only logged, the client works without its metrics.
+//
+// Parameters:
+//   - client: The name of the client instance, e.g. clientmetrics.ClientMySQL
+//   - c: The collector of the client
+func registerClientMetrics(client string, c prometheus.Collector) {
+	if err := clientmetrics.Register(client, c); err != nil && resource.LoggerService != nil {
+		resource.LoggerService.Warn(fmt.Sprintf("Failed to register %s metrics: %v", client, err))
+	}
+}
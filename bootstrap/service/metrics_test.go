@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+)
+
+// TestValidateMetricsConfig tests the validation of the histogram buckets
+// and of the service level objectives.
+func TestValidateMetricsConfig(t *testing.T) {
+	validSLO := config.SLOConfig{
+		Enable:                true,
+		AvailabilityObjective: 0.999,
+		LatencyObjective:      0.99,
+		LatencyThreshold:      500,
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.MetricsConfig
+		wantErr bool
+	}{
+		{name: "empty", cfg: config.MetricsConfig{}},
+		{name: "valid", cfg: config.MetricsConfig{
+			DurationBuckets: []float64{0.01, 0.1, 1},
+			SizeBuckets:     []float64{100, 1000},
+			SLO:             validSLO,
+		}},
+		{name: "unsorted buckets", cfg: config.MetricsConfig{DurationBuckets: []float64{1, 0.1}}, wantErr: true},
+		{name: "duplicate buckets", cfg: config.MetricsConfig{SizeBuckets: []float64{100, 100}}, wantErr: true},
+		{name: "SLO disabled", cfg: config.MetricsConfig{SLO: config.SLOConfig{AvailabilityObjective: 2}}},
+		{name: "availability objective too high", cfg: config.MetricsConfig{SLO: config.SLOConfig{
+			Enable: true, AvailabilityObjective: 1,
+		}}, wantErr: true},
+		{name: "latency objective missing", cfg: config.MetricsConfig{SLO: config.SLOConfig{
+			Enable: true, AvailabilityObjective: 0.999, LatencyThreshold: 500,
+		}}, wantErr: true},
+		{name: "latency objective disabled", cfg: config.MetricsConfig{SLO: config.SLOConfig{
+			Enable: true, AvailabilityObjective: 0.999,
+		}}},
+		{name: "negative latency threshold", cfg: config.MetricsConfig{SLO: config.SLOConfig{
+			Enable: true, AvailabilityObjective: 0.999, LatencyObjective: 0.99, LatencyThreshold: -1,
+		}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMetricsConfig(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMetricsConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
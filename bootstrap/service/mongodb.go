@@ -7,6 +7,8 @@ import (
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -97,6 +99,13 @@ func InitMongoDBClient(ctx context.Context) error {
 		clientOptions.SetServerSelectionTimeout(cfg.ServerTimeout * time.Millisecond)
 	}
 
+	// Create a span for each command
+	clientOptions.SetMonitor(tracing.NewMongoMonitor())
+
+	// Export the pool statistics, which the driver only exposes as events
+	poolCollector := clientmetrics.NewMongoPoolCollector(clientmetrics.ClientMongoDB)
+	clientOptions.SetPoolMonitor(poolCollector.PoolMonitor())
+
 	// Connect to MongoDB using the clientOptions with provided context
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
@@ -134,6 +143,7 @@ func InitMongoDBClient(ctx context.Context) error {
 
 	// Store the initialized MongoDB client in the resource package
 	resource.MongoDBClient = database
+	registerClientMetrics(clientmetrics.ClientMongoDB, poolCollector)
 
 	// Log successful connection with connection details
 	resource.LoggerService.Info(fmt.Sprintf("✅ successfully connected to MongoDB database '%s' at %s:%v",
@@ -173,6 +183,7 @@ func CloseMongoDB(ctx context.Context) error {
 
 	// Clear the global reference
 	resource.MongoDBClient = nil
+	clientmetrics.Unregister(clientmetrics.ClientMongoDB)
 
 	// Log successful disconnection (check if logger is still available)
 	if resource.LoggerService != nil {
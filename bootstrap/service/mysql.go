@@ -10,7 +10,10 @@ import (
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
 
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -73,6 +76,12 @@ func InitMySQLClient() error {
 		return fmt.Errorf("failed to open database connection: %w", err)
 	}
 
+	// Register the tracing plugin, creating a span for each statement
+	// executed with db.WithContext(ctx).
+	if err := db.Use(tracing.NewGormPlugin(semconv.DBSystemMySQL, cfg.MySQL.DBName)); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
 	// Attempt to get the underlying *sql.DB object from the GORM database connection.
 	// If the underlying *sql.DB object cannot be obtained, an error is returned.
 	sqlDB, err := db.DB()
@@ -93,6 +102,7 @@ func InitMySQLClient() error {
 	// Store the initialized GORM database connection in the resource package.
 	// The GORM database connection is a pointer to a GORM database connection.
 	resource.MySQLClient = db
+	registerClientMetrics(clientmetrics.ClientMySQL, clientmetrics.NewDBStatsCollector(clientmetrics.ClientMySQL, sqlDB))
 
 	if resource.LoggerService != nil {
 		resource.LoggerService.Info("✅ successfully connected to mysql")
@@ -260,6 +270,7 @@ func CloseMySQL() error {
 
 	// Reset the global MySQL client to nil.
 	resource.MySQLClient = nil
+	clientmetrics.Unregister(clientmetrics.ClientMySQL)
 
 	if resource.LoggerService != nil {
 		resource.LoggerService.Info("🛑 successfully closed MySQL connection")
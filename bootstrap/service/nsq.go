@@ -8,6 +8,7 @@ import (
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
 
 	"github.com/nsqio/go-nsq"
 )
@@ -131,6 +132,7 @@ func cleanupNSQResources() {
 	if resource.NsqConsumer != nil {
 		resource.NsqConsumer.Stop()
 		resource.NsqConsumer = nil
+		clientmetrics.Unregister(clientmetrics.ClientNSQConsumer)
 	}
 
 	resource.LoggerService.Info("Cleaned up NSQ resources")
@@ -306,6 +308,7 @@ func InitConsumer(ctx context.Context) error {
 
 	// Store the consumer in global resource
 	resource.NsqConsumer = consumer
+	registerClientMetrics(clientmetrics.ClientNSQConsumer, clientmetrics.NewNSQConsumerCollector(clientmetrics.ClientNSQConsumer, consumer))
 
 	resource.LoggerService.Info(fmt.Sprintf("Successfully initialized NSQ consumer for topic: %s, channel: %s",
 		cfg.Topic, cfg.Channel))
@@ -398,6 +401,7 @@ func CloseNsq(ctx context.Context) error {
 
 		// Clear the consumer reference
 		resource.NsqConsumer = nil
+		clientmetrics.Unregister(clientmetrics.ClientNSQConsumer)
 	}
 
 	// Return combined errors if any
@@ -8,7 +8,10 @@ import (
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
 
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -64,6 +67,12 @@ func InitPostgresqlClient() error {
 		return fmt.Errorf("failed to open database connection: %w", err)
 	}
 
+	// Register the tracing plugin, creating a span for each statement
+	// executed with db.WithContext(ctx).
+	if err := db.Use(tracing.NewGormPlugin(semconv.DBSystemPostgreSQL, cfg.Postgresql.DBName)); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
 	// Get the underlying sql.DB object
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -84,6 +93,7 @@ func InitPostgresqlClient() error {
 
 	// Store the initialized GORM DB in the global resource
 	resource.PostgresqlClient = db
+	registerClientMetrics(clientmetrics.ClientPostgresql, clientmetrics.NewDBStatsCollector(clientmetrics.ClientPostgresql, sqlDB))
 	resource.LoggerService.Info("✅ successfully connected to postgresql")
 	return nil
 }
@@ -242,6 +252,7 @@ func ClosePostgresql() error {
 
 	// Reset the global PostgreSQL client to nil.
 	resource.PostgresqlClient = nil
+	clientmetrics.Unregister(clientmetrics.ClientPostgresql)
 
 	if resource.LoggerService != nil {
 		resource.LoggerService.Info("🛑 successfully closed postgresql connection")
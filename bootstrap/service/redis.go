@@ -7,6 +7,8 @@ import (
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -73,6 +75,9 @@ func InitRedisClient(ctx context.Context) error {
 		ConnMaxLifetime: cfg.MaxConnAge,
 	})
 
+	// Create a span for each command and pipeline
+	redisClient.AddHook(tracing.NewRedisHook(cfg.Addr))
+
 	// Perform comprehensive connection testing
 	if err := testRedisConnection(ctx, redisClient); err != nil {
 		// Clean up the client if connection test fails
@@ -84,6 +89,8 @@ func InitRedisClient(ctx context.Context) error {
 
 	// Store the Redis client instance in the global resource
 	resource.RedisClient = redisClient
+	registerClientMetrics(clientmetrics.ClientRedis,
+		clientmetrics.NewRedisPoolCollector(clientmetrics.ClientRedis, redisClient, redisClient.Options().PoolSize))
 
 	// Start health check if configured
 	if cfg.HealthCheckFreq > 0 {
@@ -323,6 +330,7 @@ func CloseRedis(ctx context.Context) error {
 
 	// Clear the global Redis client reference
 	resource.RedisClient = nil
+	clientmetrics.Unregister(clientmetrics.ClientRedis)
 
 	if resource.LoggerService != nil {
 		resource.LoggerService.Info("🛑 successfully closed redis client connection")
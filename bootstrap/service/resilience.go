@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/circuitbreaker"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
+)
+
+// InitResilience creates the resilience policies of the dependencies
+// declared in the global ResilienceConfig and stores them in
+// resource.Resilience.
+//
+// The calls to a dependency are protected with
+// resilience.Do(ctx, resource.Resilience.Get("mysql"), fn). The policies use
+// the circuit breakers of resource.CircuitBreakerManager, so this function
+// must be called after InitializeCircuitBreaker.
+//
+// When the policies are disabled, resource.Resilience is left empty and the
+// calls are run as they are. The function panics if the configuration is
+// invalid.
+func InitResilience() {
+	if config.ResilienceConfig == nil {
+		panic("Resilience configuration is not initialized")
+	}
+
+	registry := resilience.NewRegistry()
+	resource.Resilience = registry
+	if !config.ResilienceConfig.Resilience.Enable {
+		resource.LoggerService.Info("Resilience policies are disabled, skipping initialization")
+		return
+	}
+
+	if err := ApplyResilienceConfig(registry, resource.CircuitBreakerManager, config.ResilienceConfig); err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("Failed to initialize resilience policies: %v", err))
+		panic(fmt.Sprintf("Resilience initialization failed: %v", err))
+	}
+
+	resource.LoggerService.Info(fmt.Sprintf("✅ successfully initialized resilience policies of %v", registry.Names()))
+}
+
+// ApplyResilienceConfig validates the resilience configuration and sets the
+// policy of every declared dependency in the registry.
+//
+// Parameters:
+//   - registry: The registry of the policies
+//   - manager: The circuit breaker manager, nil if the circuit breakers are
+//     disabled
+//   - cfg: The resilience configuration
+//
+// Returns:
+//   - error: An error describing the first invalid value, nil otherwise
+func ApplyResilienceConfig(registry *resilience.Registry, manager *circuitbreaker.Manager, cfg *config.ResilienceConfigEntry) error {
+	names := make(map[string]bool, len(cfg.Resilience.Dependencies))
+	for _, d := range cfg.Resilience.Dependencies {
+		if d.Name == "" {
+			return fmt.Errorf("resilience dependency name must not be empty")
+		}
+		if names[d.Name] {
+			return fmt.Errorf("duplicate resilience dependency %q", d.Name)
+		}
+		names[d.Name] = true
+
+		if err := validateResilienceDependency(d); err != nil {
+			return fmt.Errorf("resilience dependency %s: %w", d.Name, err)
+		}
+		if d.CircuitBreaker && (manager == nil || manager.GetBreaker(d.Name) == nil) {
+			resource.LoggerService.Warn(fmt.Sprintf("⚠️ resilience dependency %s has no circuit breaker, calls are not broken", d.Name))
+		}
+
+		registry.Set(d.Name, resiliencePolicy(d, manager))
+	}
+	return nil
+}
+
+// validateResilienceDependency checks the values of a dependency.
+func validateResilienceDependency(d config.ResilienceDependency) error {
+	if d.MaxConcurrent < 0 || d.MaxQueue < 0 || d.MaxAttempts < 0 || d.MaxHedges < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if d.QueueTimeout < 0 || d.InitialBackoff < 0 || d.MaxBackoff < 0 || d.HedgeDelay < 0 {
+		return fmt.Errorf("durations must not be negative")
+	}
+	if d.Multiplier != 0 && d.Multiplier < 1 {
+		return fmt.Errorf("backoff multiplier %v must not be less than 1", d.Multiplier)
+	}
+	if d.Jitter < 0 || d.Jitter > 1 {
+		return fmt.Errorf("jitter %v must be between 0 and 1", d.Jitter)
+	}
+	return nil
+}
+
+// resiliencePolicy combines the policies of a dependency: retry, circuit
+// breaker, hedge and bulkhead, the disabled ones being skipped.
+func resiliencePolicy(d config.ResilienceDependency, manager *circuitbreaker.Manager) resilience.Policy {
+	var policies []resilience.Policy
+	if d.MaxAttempts > 1 {
+		policies = append(policies, resilience.NewRetry(d.Name, resilience.RetryConfig{
+			MaxAttempts:    d.MaxAttempts,
+			InitialBackoff: time.Duration(d.InitialBackoff) * time.Millisecond,
+			MaxBackoff:     time.Duration(d.MaxBackoff) * time.Millisecond,
+			Multiplier:     d.Multiplier,
+			Jitter:         d.Jitter,
+			RetryWrites:    d.RetryWrites,
+		}))
+	}
+	// The circuit breaker is outside the hedge, so that it counts a hedged
+	// call once rather than the attempts cancelled by the hedge as failures
+	if d.CircuitBreaker {
+		policies = append(policies, resilience.NewCircuitBreaker(manager, d.Name))
+	}
+	if d.HedgeDelay > 0 {
+		policies = append(policies, resilience.NewHedge(d.Name, resilience.HedgeConfig{
+			Delay:     time.Duration(d.HedgeDelay) * time.Millisecond,
+			MaxHedges: d.MaxHedges,
+		}))
+	}
+	if d.MaxConcurrent > 0 {
+		policies = append(policies, resilience.NewBulkhead(d.Name, resilience.BulkheadConfig{
+			MaxConcurrent: d.MaxConcurrent,
+			MaxQueue:      d.MaxQueue,
+			QueueTimeout:  time.Duration(d.QueueTimeout) * time.Millisecond,
+		}))
+	}
+	return resilience.Wrap(policies...)
+}
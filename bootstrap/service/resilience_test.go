@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/pkg/circuitbreaker"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
+
+	"go.uber.org/zap"
+)
+
+// TestApplyResilienceConfig tests the validation of the dependencies.
+func TestApplyResilienceConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.ResilienceConfigEntry)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(cfg *config.ResilienceConfigEntry) {}},
+		{name: "dependency without name", modify: func(cfg *config.ResilienceConfigEntry) {
+			cfg.Resilience.Dependencies[0].Name = ""
+		}, wantErr: true},
+		{name: "duplicate dependency", modify: func(cfg *config.ResilienceConfigEntry) {
+			cfg.Resilience.Dependencies = append(cfg.Resilience.Dependencies, cfg.Resilience.Dependencies[0])
+		}, wantErr: true},
+		{name: "negative duration", modify: func(cfg *config.ResilienceConfigEntry) {
+			cfg.Resilience.Dependencies[0].QueueTimeout = -1
+		}, wantErr: true},
+		{name: "invalid jitter", modify: func(cfg *config.ResilienceConfigEntry) {
+			cfg.Resilience.Dependencies[0].Jitter = 2
+		}, wantErr: true},
+		{name: "invalid multiplier", modify: func(cfg *config.ResilienceConfigEntry) {
+			cfg.Resilience.Dependencies[0].Multiplier = 0.5
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ResilienceConfigEntry{}
+			cfg.Resilience.Enable = true
+			cfg.Resilience.Dependencies = []config.ResilienceDependency{{
+				Name: "mysql", MaxConcurrent: 10, QueueTimeout: 100, MaxAttempts: 3, InitialBackoff: 10, Jitter: 0.2,
+			}}
+			tt.modify(cfg)
+
+			registry := resilience.NewRegistry()
+			err := ApplyResilienceConfig(registry, nil, cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ApplyResilienceConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if names := registry.Names(); err == nil && (len(names) != 1 || names[0] != "mysql") {
+				t.Errorf("Names() = %v, want [mysql]", names)
+			}
+		})
+	}
+}
+
+// TestResiliencePolicyHedgeBreaker tests that the attempts cancelled by the
+// hedge are not counted as failures by the circuit breaker.
+func TestResiliencePolicyHedgeBreaker(t *testing.T) {
+	manager := circuitbreaker.NewManager(zap.NewNop())
+	cb := manager.GetOrCreateBreaker("elasticsearch", circuitbreaker.Config{Interval: time.Minute, Timeout: time.Minute})
+
+	policy := resiliencePolicy(config.ResilienceDependency{
+		Name: "elasticsearch", HedgeDelay: 10, MaxHedges: 1, CircuitBreaker: true,
+	}, manager)
+
+	const calls = 5
+	for i := 0; i < calls; i++ {
+		var attempts sync.WaitGroup
+		var mu sync.Mutex
+		started := 0
+
+		err := policy.Execute(context.Background(), func(ctx context.Context) error {
+			attempts.Add(1)
+			defer attempts.Done()
+
+			mu.Lock()
+			started++
+			first := started == 1
+			mu.Unlock()
+
+			if first {
+				// The first attempt is slow and loses the race
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		attempts.Wait()
+	}
+
+	counts := cb.Counts()
+	if counts.TotalFailures != 0 || counts.TotalSuccesses != calls {
+		t.Errorf("Counts() = %+v, want %d successes and no failure", counts, calls)
+	}
+}
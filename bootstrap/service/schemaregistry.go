@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/httpclient"
+	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/schema"
+)
+
+// InitSchemaRegistry creates the serde of the schema registry declared in
+// the global SchemaRegistryConfig and stores it in resource.SchemaSerde.
+//
+// The Kafka and NSQ messages are then produced in the wire format of the
+// registry and decoded into the type named by their schema. The message
+// types are registered with schema.RegisterType, the TestMessage type
+// being registered here. When the registry is disabled,
+// resource.SchemaSerde is left nil and the messages are bare protobuf.
+//
+// Parameters:
+//   - ctx: Context for the operation
+func InitSchemaRegistry(_ context.Context) {
+	if config.SchemaRegistryConfig == nil {
+		panic("Schema registry configuration is not initialized")
+	}
+	if !config.SchemaRegistryConfig.SchemaRegistry.Enable {
+		resource.LoggerService.Info("Schema registry is disabled, skipping initialization")
+		return
+	}
+
+	serde, err := NewSchemaSerde(config.SchemaRegistryConfig)
+	if err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("Failed to initialize schema registry: %v", err))
+		panic(fmt.Sprintf("Schema registry initialization failed: %v", err))
+	}
+	schema.RegisterType(&pkgproto.TestMessage{})
+	resource.SchemaSerde = serde
+
+	resource.LoggerService.Info(fmt.Sprintf("✅ successfully initialized schema registry %s", config.SchemaRegistryConfig.SchemaRegistry.URL))
+}
+
+// NewSchemaSerde validates the schema registry configuration and creates
+// the serde of its registry.
+//
+// Parameters:
+//   - cfg: The schema registry configuration
+//
+// Returns:
+//   - *schema.Serde: The serde
+//   - error: An error describing the first invalid value, nil otherwise
+func NewSchemaSerde(cfg *config.SchemaRegistryConfigEntry) (*schema.Serde, error) {
+	c := cfg.SchemaRegistry
+	if c.Timeout < 0 {
+		return nil, fmt.Errorf("schema registry timeout %d ms must not be negative", c.Timeout)
+	}
+	format, err := schema.ParseFormat(c.Format)
+	if err != nil {
+		return nil, err
+	}
+	strategy, err := schema.ParseSubjectStrategy(c.SubjectStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := make(map[string]schema.Format, len(c.Topics))
+	for _, t := range c.Topics {
+		if t.Name == "" {
+			return nil, fmt.Errorf("schema registry topic name must not be empty")
+		}
+		if _, ok := topics[t.Name]; ok {
+			return nil, fmt.Errorf("duplicate schema registry topic %q", t.Name)
+		}
+		if topics[t.Name], err = schema.ParseFormat(t.Format); err != nil {
+			return nil, fmt.Errorf("schema registry topic %s: %w", t.Name, err)
+		}
+	}
+
+	timeout := time.Duration(c.Timeout) * time.Millisecond
+	client, err := schema.NewClient(schema.ClientConfig{
+		URL:        c.URL,
+		Username:   c.Username,
+		Password:   c.Password,
+		HTTPClient: httpclient.NewClient(timeout),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.NewSerde(client, schema.SerdeConfig{
+		Format:          format,
+		Topics:          topics,
+		SubjectStrategy: strategy,
+		AutoRegister:    c.AutoRegister,
+	}), nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/pkg/schema"
+)
+
+// TestNewSchemaSerde tests the validation of the schema registry
+// configuration.
+func TestNewSchemaSerde(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.SchemaRegistryConfigEntry)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(cfg *config.SchemaRegistryConfigEntry) {}},
+		{name: "invalid url", modify: func(cfg *config.SchemaRegistryConfigEntry) {
+			cfg.SchemaRegistry.URL = "localhost"
+		}, wantErr: true},
+		{name: "negative timeout", modify: func(cfg *config.SchemaRegistryConfigEntry) {
+			cfg.SchemaRegistry.Timeout = -1
+		}, wantErr: true},
+		{name: "invalid format", modify: func(cfg *config.SchemaRegistryConfigEntry) {
+			cfg.SchemaRegistry.Format = "xml"
+		}, wantErr: true},
+		{name: "invalid subject strategy", modify: func(cfg *config.SchemaRegistryConfigEntry) {
+			cfg.SchemaRegistry.SubjectStrategy = "record_topic"
+		}, wantErr: true},
+		{name: "duplicate topic", modify: func(cfg *config.SchemaRegistryConfigEntry) {
+			cfg.SchemaRegistry.Topics = append(cfg.SchemaRegistry.Topics, cfg.SchemaRegistry.Topics[0])
+		}, wantErr: true},
+		{name: "invalid topic format", modify: func(cfg *config.SchemaRegistryConfigEntry) {
+			cfg.SchemaRegistry.Topics[0].Format = "xml"
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.SchemaRegistryConfigEntry{}
+			cfg.SchemaRegistry.Enable = true
+			cfg.SchemaRegistry.URL = "http://localhost:8081"
+			cfg.SchemaRegistry.Timeout = 5000
+			cfg.SchemaRegistry.SubjectStrategy = "topic_record"
+			cfg.SchemaRegistry.Topics = []config.SchemaRegistryTopic{{Name: "events", Format: "json"}}
+			tt.modify(cfg)
+
+			serde, err := NewSchemaSerde(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSchemaSerde() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (serde.Format("events") != schema.FormatJSON || serde.Format("orders") != schema.FormatProtobuf) {
+				t.Errorf("Format() = %s, %s, want the topic and the default formats", serde.Format("events"), serde.Format("orders"))
+			}
+		})
+	}
+}
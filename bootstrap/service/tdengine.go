@@ -8,6 +8,8 @@ import (
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
 	// TDengine driver - uncomment when TDengine is available
 	//_ "github.com/taosdata/driver-go/v3/taosSql"
 )
@@ -69,6 +71,7 @@ func InitTDengineClient(ctx context.Context) error {
 
 	// Store the client in the resource package
 	resource.TDengineClient = db
+	registerClientMetrics(clientmetrics.ClientTDengine, clientmetrics.NewDBStatsCollector(clientmetrics.ClientTDengine, db))
 
 	resource.LoggerService.Info("✅ successfully initialized tdengine client")
 	return nil
@@ -161,7 +164,7 @@ func createTDengineClient(ctx context.Context) (*sql.DB, error) {
 
 	// Create the DSN (Data Source Name) for the TDengine client
 	dsn := buildTDengineDSN(config.TDengineConfig)
-	resource.LoggerService.Info(fmt.Sprintf("tdengine dsn: %s", maskPassword(dsn)))
+	resource.LoggerService.Info(fmt.Sprintf("tdengine dsn: %s", logger.MaskDSN(dsn)))
 
 	// Open a connection to the TDengine database using the DSN
 	done := make(chan struct{})
@@ -249,24 +252,6 @@ func joinParams(params []string) string {
 	return result
 }
 
-// maskPassword masks the password in DSN for logging.
-func maskPassword(dsn string) string {
-	// Simple password masking for logging
-	// Find the pattern username:password@
-	for i := 0; i < len(dsn); i++ {
-		if dsn[i] == ':' {
-			// Found username:, now find the @ after password
-			for j := i + 1; j < len(dsn); j++ {
-				if dsn[j] == '@' {
-					// Replace password with ***
-					return dsn[:i+1] + "***" + dsn[j:]
-				}
-			}
-		}
-	}
-	return dsn
-}
-
 // configureTDenginePool configures the connection pool settings for TDengine.
 //
 // Parameters:
@@ -441,6 +426,7 @@ func CloseTDengine(ctx context.Context) error {
 
 	// Clear the global reference
 	resource.TDengineClient = nil
+	clientmetrics.Unregister(clientmetrics.ClientTDengine)
 
 	if resource.LoggerService != nil {
 		resource.LoggerService.Info("🛑 successfully closed tdengine client")
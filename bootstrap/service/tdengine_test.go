@@ -252,50 +252,6 @@ func TestBuildTDengineDSN(t *testing.T) {
 	}
 }
 
-// TestMaskPassword tests the `maskPassword` function.
-//
-// The function `maskPassword` takes a Data Source Name (DSN) as input and
-// returns a masked version where the password is replaced with asterisks.
-//
-// The test cases cover the following scenarios:
-//
-//  1. A basic DSN with a password, where the password should be masked.
-//  2. A DSN with additional parameters, ensuring the password is masked while
-//     keeping other parameters intact.
-//  3. A DSN without a password, which should remain unchanged.
-func TestMaskPassword(t *testing.T) {
-	tests := []struct {
-		name     string
-		dsn      string
-		expected string
-	}{
-		{
-			name:     "basic dsn",
-			dsn:      "root:taosdata@tcp(localhost:6030)/test",
-			expected: "root:***@tcp(localhost:6030)/test",
-		},
-		{
-			name:     "dsn with params",
-			dsn:      "user:pass123@tcp(host:6030)/db?timeout=10s",
-			expected: "user:***@tcp(host:6030)/db?timeout=10s",
-		},
-		{
-			name:     "no password",
-			dsn:      "tcp(localhost:6030)/test",
-			expected: "tcp(localhost:6030)/test",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := maskPassword(tt.dsn)
-			if result != tt.expected {
-				t.Errorf("Expected masked DSN '%s', got '%s'", tt.expected, result)
-			}
-		})
-	}
-}
-
 // TestJoinParams tests the `joinParams` function.
 //
 // The function takes a slice of strings representing individual parameters
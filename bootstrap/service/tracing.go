@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/buildinfo"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
+)
+
+// InitTracing initializes the OpenTelemetry tracer provider.
+//
+// This function reads the tracing configuration from the global TracingConfig,
+// installs the tracer provider and propagators globally, and assigns the
+// provider to the global TracerProvider resource. Nothing is installed when
+// tracing is disabled, in which case all spans are no-ops.
+//
+// Parameters:
+//   - ctx: Context for the operation, used for timeouts and cancellation
+func InitTracing(ctx context.Context) {
+	if err := InitTracingProvider(ctx); err != nil {
+		// Log an error message if the tracer provider cannot be created.
+		resource.LoggerService.Error(fmt.Sprintf("Failed to initialize tracing: %v", err))
+		panic(fmt.Sprintf("Tracing initialization failed: %v", err))
+	}
+}
+
+// InitTracingProvider creates the tracer provider using the global
+// TracingConfig.
+//
+// Parameters:
+//   - ctx: Context for the operation, used for timeouts and cancellation
+//
+// Returns:
+//   - error: An error if the configuration is invalid or the exporter cannot
+//     be created, nil otherwise
+func InitTracingProvider(ctx context.Context) error {
+	if config.TracingConfig == nil {
+		return fmt.Errorf("tracing configuration is not initialized")
+	}
+	if !config.TracingConfig.Tracing.Enable {
+		resource.LoggerService.Info("tracing is disabled")
+		return nil
+	}
+
+	provider, err := tracing.Init(ctx, TracingOptions(config.TracingConfig))
+	if err != nil {
+		return fmt.Errorf("failed to create tracer provider: %w", err)
+	}
+
+	resource.TracerProvider = provider.TracerProvider()
+
+	resource.LoggerService.Info(fmt.Sprintf("✅ successfully initialized tracing with %s exporter",
+		config.TracingConfig.Exporter.Type))
+
+	return nil
+}
+
+// TracingOptions converts the tracing configuration into tracing.Options.
+//
+// The service version is the version injected at build time, see
+// buildinfo, so that the spans tell the deployed releases apart. When none
+// was injected, the ServiceVersion of the configuration is used, or else the
+// version of the server configuration.
+//
+// Parameters:
+//   - cfg: The tracing configuration
+//
+// Returns:
+//   - tracing.Options: The tracer provider options
+func TracingOptions(cfg *config.TracingConfigEntry) tracing.Options {
+	return tracing.Options{
+		ServiceName:        cfg.Tracing.ServiceName,
+		ServiceVersion:     tracingServiceVersion(cfg),
+		Environment:        cfg.Tracing.Environment,
+		Exporter:           cfg.Exporter.Type,
+		Endpoint:           cfg.Exporter.Endpoint,
+		URLPath:            cfg.Exporter.URLPath,
+		Insecure:           cfg.Exporter.Insecure,
+		Headers:            cfg.Exporter.Headers,
+		Timeout:            time.Duration(cfg.Exporter.Timeout) * time.Second,
+		PrettyPrint:        cfg.Exporter.PrettyPrint,
+		Sampler:            cfg.Sampler.Type,
+		SampleRatio:        cfg.Sampler.Ratio,
+		ParentBased:        cfg.Sampler.ParentBased,
+		MaxQueueSize:       cfg.Batch.MaxQueueSize,
+		MaxExportBatchSize: cfg.Batch.MaxExportBatchSize,
+		BatchTimeout:       time.Duration(cfg.Batch.BatchTimeout) * time.Second,
+	}
+}
+
+// tracingServiceVersion returns the service version of the spans, see
+// TracingOptions.
+func tracingServiceVersion(cfg *config.TracingConfigEntry) string {
+	if buildinfo.Version == "" && cfg.Tracing.ServiceVersion != "" {
+		return cfg.Tracing.ServiceVersion
+	}
+	return buildinfo.Get().Version
+}
+
+// CloseTracing flushes the pending spans and shuts down the tracer provider.
+//
+// Parameters:
+//   - ctx: Context for the operation, used for timeouts and cancellation
+//
+// Returns:
+//   - error: An error if the provider fails to shut down, nil otherwise
+func CloseTracing(ctx context.Context) error {
+	if resource.TracerProvider == nil {
+		return nil
+	}
+
+	// Create timeout context for the flush
+	closeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := resource.TracerProvider.Shutdown(closeCtx); err != nil {
+		return fmt.Errorf("failed to shut down tracer provider: %w", err)
+	}
+	resource.TracerProvider = nil
+
+	if resource.LoggerService != nil {
+		resource.LoggerService.Info("🛑 successfully shut down tracing")
+	}
+
+	return nil
+}
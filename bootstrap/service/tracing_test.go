@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/pkg/buildinfo"
+)
+
+// TestTracingServiceVersion tests that the version injected at build time
+// takes precedence over the configured service version.
+func TestTracingServiceVersion(t *testing.T) {
+	previous := buildinfo.Version
+	t.Cleanup(func() {
+		buildinfo.Version = previous
+		buildinfo.SetFallbackVersion("")
+	})
+
+	cfg := &config.TracingConfigEntry{}
+	cfg.Tracing.ServiceVersion = "1.0.0"
+
+	buildinfo.Version = ""
+	if v := TracingOptions(cfg).ServiceVersion; v != "1.0.0" {
+		t.Errorf("ServiceVersion = %s, want the configured version", v)
+	}
+
+	buildinfo.Version = "1.2.0"
+	if v := TracingOptions(cfg).ServiceVersion; v != "1.2.0" {
+		t.Errorf("ServiceVersion = %s, want the injected version", v)
+	}
+
+	buildinfo.Version = ""
+	buildinfo.SetFallbackVersion("1.1.0")
+	cfg.Tracing.ServiceVersion = ""
+	if v := TracingOptions(cfg).ServiceVersion; v != "1.1.0" {
+		t.Errorf("ServiceVersion = %s, want the server version", v)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -27,15 +28,15 @@ func main() {
 	// 创建 Gin 引擎
 	r := gin.Default()
 
-	// 添加熔断器中间件
-	r.Use(middleware.CustomCircuitBreakerMiddleware(cbManager, middleware.CircuitBreakerConfig{
-		Enabled:     true,
+	// 添加熔断器中间件，每个路由使用默认策略的熔断器
+	cbManager.Configure(nil, nil, &circuitbreaker.Policy{
 		MaxRequests: 5,
 		Interval:    30 * time.Second,
 		Timeout:     60 * time.Second,
 		FailureRate: 0.6,
 		MinRequests: 10,
-	}))
+	})
+	r.Use(middleware.CircuitBreakerMiddleware(cbManager))
 
 	// 添加熔断器状态查看端点
 	r.GET("/circuit-breakers", middleware.CircuitBreakerStatusHandler(cbManager))
@@ -123,7 +124,7 @@ func main() {
 		})
 
 		if err != nil {
-			if err.Error() == "circuit breaker is open" {
+			if errors.Is(err, circuitbreaker.ErrOpenState) {
 				c.JSON(http.StatusServiceUnavailable, gin.H{
 					"error": "Database service temporarily unavailable",
 					"code":  "DATABASE_CIRCUIT_OPEN",
@@ -160,7 +161,7 @@ func main() {
 		})
 
 		if err != nil {
-			if err.Error() == "circuit breaker is open" {
+			if errors.Is(err, circuitbreaker.ErrOpenState) {
 				c.JSON(http.StatusServiceUnavailable, gin.H{
 					"error": "External API temporarily unavailable",
 					"code":  "EXTERNAL_API_CIRCUIT_OPEN",
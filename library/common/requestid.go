@@ -0,0 +1,63 @@
+package common
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader is the header carrying the request ID in HTTP requests,
+	// HTTP responses and message headers.
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDKey is the key of the request ID in the gin context and the
+	// field name used in logs.
+	RequestIDKey = "request_id"
+)
+
+// requestIDCtxKey is the context key for the request ID.
+type requestIDCtxKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+//
+// Parameters:
+//   - ctx: The parent context.
+//   - requestID: The request ID to store.
+//
+// Returns:
+//   - context.Context: The context carrying the request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, requestIDCtxKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or an empty
+// string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return requestID
+}
+
+// NewRequestID generates a new request ID.
+func NewRequestID() string {
+	return uuid.NewString()
+}
+
+// EnsureRequestID returns ctx and its request ID, generating and storing a
+// new one if ctx does not carry any.
+//
+// It is used by message consumers and background jobs which do not run
+// behind the HTTP request ID middleware.
+func EnsureRequestID(ctx context.Context) (context.Context, string) {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return ctx, requestID
+	}
+	requestID := NewRequestID()
+	return WithRequestID(ctx, requestID), requestID
+}
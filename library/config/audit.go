@@ -0,0 +1,13 @@
+package config
+
+// AuditConfigEntry 审计日志配置
+type AuditConfigEntry struct {
+	Audit struct {
+		Enable        bool   `toml:"Enable"`        // 是否启用审计日志
+		Database      string `toml:"Database"`      // 存储数据库：mysql, postgresql
+		AutoMigrate   bool   `toml:"AutoMigrate"`   // 启动时是否创建审计表和触发器
+		Timeout       int    `toml:"Timeout"`       // 写入超时（秒）
+		QueryMaxLimit int    `toml:"QueryMaxLimit"` // 查询接口每页最大条数
+		ExportMaxRows int    `toml:"ExportMaxRows"` // CSV 导出最大条数
+	} `toml:"Audit"`
+}
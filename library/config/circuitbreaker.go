@@ -0,0 +1,51 @@
+package config
+
+// CircuitBreakerConfigEntry 熔断器配置
+type CircuitBreakerConfigEntry struct {
+	CircuitBreaker struct {
+		Enable           bool                       `toml:"Enable"`           // 是否启用熔断器
+		EnableMiddleware bool                       `toml:"EnableMiddleware"` // 是否在主服务上挂载路由熔断中间件
+		GuardAllRoutes   bool                       `toml:"GuardAllRoutes"`   // 未匹配路由规则的路由是否按默认策略各自熔断
+		SharedState      CircuitBreakerSharedState  `toml:"SharedState"`      // 多实例共享熔断状态
+		Default          CircuitBreakerPolicy       `toml:"Default"`          // 默认策略，依赖和路由未设置的字段使用默认值
+		Dependencies     []CircuitBreakerDependency `toml:"Dependencies"`     // 依赖熔断器
+		Routes           []CircuitBreakerRoute      `toml:"Routes"`           // 路由熔断规则，按顺序匹配
+	} `toml:"CircuitBreaker"`
+}
+
+// CircuitBreakerSharedState 熔断器共享状态配置
+type CircuitBreakerSharedState struct {
+	Enable       bool   `toml:"Enable"`       // 是否启用共享状态，需要先初始化 Redis
+	KeyPrefix    string `toml:"KeyPrefix"`    // Redis 键前缀
+	SyncInterval int    `toml:"SyncInterval"` // 同步共享状态的最小间隔（毫秒）
+}
+
+// CircuitBreakerPolicy 熔断策略，0 或空表示使用默认策略的值
+type CircuitBreakerPolicy struct {
+	MaxRequests         uint32   `toml:"MaxRequests"`         // 半开状态下允许通过的请求数
+	Interval            int      `toml:"Interval"`            // 关闭状态下的统计窗口（秒）
+	BucketCount         int      `toml:"BucketCount"`         // 滑动窗口的桶数，0 表示固定窗口
+	Timeout             int      `toml:"Timeout"`             // 熔断开启后转为半开状态的时间（秒）
+	MaxTimeout          int      `toml:"MaxTimeout"`          // 连续熔断时超时时间指数退避的上限（秒）
+	BackoffMultiplier   float64  `toml:"BackoffMultiplier"`   // 超时时间的退避倍数
+	MinRequests         uint32   `toml:"MinRequests"`         // 统计窗口内触发熔断的最小请求数
+	FailureRate         float64  `toml:"FailureRate"`         // 触发熔断的失败率，取值 (0, 1]
+	ConsecutiveFailures uint32   `toml:"ConsecutiveFailures"` // 触发熔断的连续失败数
+	SlowCallDuration    int      `toml:"SlowCallDuration"`    // 慢调用阈值（毫秒）
+	SlowCallRate        float64  `toml:"SlowCallRate"`        // 触发熔断的慢调用率，取值 (0, 1]
+	FailureStatusCodes  []string `toml:"FailureStatusCodes"`  // 计为失败的 HTTP 状态码，如 "5xx"、"429"，仅用于路由
+}
+
+// CircuitBreakerDependency 依赖熔断器，如 mysql、redis、外部 API
+type CircuitBreakerDependency struct {
+	Name string `toml:"Name"` // 熔断器名称
+	CircuitBreakerPolicy
+}
+
+// CircuitBreakerRoute 路由熔断规则，匹配的路由共用一个熔断器
+type CircuitBreakerRoute struct {
+	Name    string `toml:"Name"`    // 熔断器名称，为空时使用 "Method:Pattern"
+	Method  string `toml:"Method"`  // HTTP 方法，为空或 "*" 匹配所有方法
+	Pattern string `toml:"Pattern"` // 路由模板，支持 * 通配一段路径，以 /** 结尾时匹配该前缀下的所有路由
+	CircuitBreakerPolicy
+}
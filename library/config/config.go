@@ -42,4 +42,19 @@ var (
 
 	// CronConfig cron config entry
 	CronConfig *CronConfigEntry
+
+	// TracingConfig tracing config entry
+	TracingConfig *TracingConfigEntry
+
+	// AuditConfig audit config entry
+	AuditConfig *AuditConfigEntry
+
+	// CircuitBreakerConfig circuit breaker config entry
+	CircuitBreakerConfig *CircuitBreakerConfigEntry
+
+	// ResilienceConfig resilience config entry
+	ResilienceConfig *ResilienceConfigEntry
+
+	// SchemaRegistryConfig schema registry config entry
+	SchemaRegistryConfig *SchemaRegistryConfigEntry
 )
@@ -12,9 +12,35 @@ type KafkaConfigEntry struct {
 	} `toml:"Kafka"`
 
 	Advanced struct {
-		ProducerMaxRetry       int   `toml:"ProducerMaxRetry"`       // 生产者最大重试次数，整数
-		ConsumerSessionTimeout int64 `toml:"ConsumerSessionTimeout"` // 消费者会话超时（ms），整数
-		HeartbeatInterval      int64 `toml:"HeartbeatInterval"`      // 心跳间隔（ms），整数
-		MaxProcessingTime      int64 `toml:"MaxProcessingTime"`      // 最大处理时间（ms），整数
+		ProducerMaxRetry        int    `toml:"ProducerMaxRetry"`        // 生产者最大重试次数，幂等生产者要求至少为 1
+		ProducerFlushFrequency  int64  `toml:"ProducerFlushFrequency"`  // 生产者批量发送间隔（ms），0 使用默认值 500
+		ProducerFlushMessages   int    `toml:"ProducerFlushMessages"`   // 生产者批量发送的消息数，0 使用默认值 100
+		ProducerTransactionalID string `toml:"ProducerTransactionalID"` // 事务生产者 ID 前缀，为空则不创建事务生产者
+		ConsumerSessionTimeout  int64  `toml:"ConsumerSessionTimeout"`  // 消费者会话超时（ms），整数
+		HeartbeatInterval       int64  `toml:"HeartbeatInterval"`       // 心跳间隔（ms），整数
+		MaxProcessingTime       int64  `toml:"MaxProcessingTime"`       // 最大处理时间（ms），整数
+		ConsumerConcurrency     int    `toml:"ConsumerConcurrency"`     // 每个主题同时处理的消息数，0 表示每个分区一条
+		MinReconnectBackoff     int64  `toml:"MinReconnectBackoff"`     // 消费者组重连的初始退避时间（ms），0 使用默认值
+		MaxReconnectBackoff     int64  `toml:"MaxReconnectBackoff"`     // 消费者组重连的最大退避时间（ms），0 使用默认值
+		ConsumerStartPosition   string `toml:"ConsumerStartPosition"`   // 无已提交偏移量时的起始位置：earliest, latest 或 RFC 3339 时间
+		ConsumerManualCommit    bool   `toml:"ConsumerManualCommit"`    // 是否在消息处理成功后手动提交偏移量
 	} `toml:"Advanced"`
+
+	Outbox struct {
+		Enable      bool   `toml:"Enable"`      // 是否启用 outbox 转发
+		Database    string `toml:"Database"`    // outbox 表所在数据库：mysql, postgresql
+		AutoMigrate bool   `toml:"AutoMigrate"` // 启动时是否创建 outbox 表
+		BatchSize   int    `toml:"BatchSize"`   // 每批转发的消息数
+		Interval    int64  `toml:"Interval"`    // 无待转发消息时的轮询间隔（ms）
+		Retention   int64  `toml:"Retention"`   // 已转发消息的保留时间（小时）
+		MaxAttempts int    `toml:"MaxAttempts"` // 转发失败次数上限，达到后搁置该消息，不再阻塞后续消息
+	} `toml:"Outbox"`
+
+	LagMonitor struct {
+		Enable            bool     `toml:"Enable"`            // 是否启用消费延迟监控
+		Interval          int64    `toml:"Interval"`          // 采集间隔（ms），0 使用默认值 30000
+		Groups            []string `toml:"Groups"`            // 监控的消费者组，为空则监控集群中的所有消费者组
+		WarnThreshold     int64    `toml:"WarnThreshold"`     // 主题延迟消息数的告警阈值，0 表示不告警
+		CriticalThreshold int64    `toml:"CriticalThreshold"` // 主题延迟消息数的严重告警阈值，0 表示不告警
+	} `toml:"LagMonitor"`
 }
@@ -15,4 +15,83 @@ type LogConfigEntry struct {
 		LocalTime    bool   `toml:"LocalTime"`    // 是否使用本地时间
 		Compress     bool   `toml:"Compress"`     // 是否压缩
 	} `toml:"Log"`
+
+	// Modules 各模块日志级别，eg: kafka = "debug"，未配置的模块跟随 DefaultLevel
+	Modules map[string]string `toml:"Modules"`
+
+	// Sampling zap 采样，每个 Tick 内相同级别和消息的日志先记录 First 条，之后每 Thereafter 条记录一条
+	Sampling struct {
+		Enable     bool `toml:"Enable"`     // 是否启用
+		Tick       int  `toml:"Tick"`       // 采样周期，单位：秒
+		First      int  `toml:"First"`      // 每个周期先记录的条数
+		Thereafter int  `toml:"Thereafter"` // 之后每多少条记录一条
+	} `toml:"Sampling"`
+
+	// RateLimit 相同消息限流，每个 Window 内最多记录 Burst 条，丢弃条数记录在下一条日志的 suppressed 字段
+	RateLimit struct {
+		Enable bool `toml:"Enable"` // 是否启用
+		Window int  `toml:"Window"` // 限流窗口，单位：秒
+		Burst  int  `toml:"Burst"`  // 每个窗口最多记录的条数
+	} `toml:"RateLimit"`
+
+	// Redact 敏感字段脱敏
+	Redact struct {
+		Enable   bool            `toml:"Enable"`   // 是否启用
+		Fields   []string        `toml:"Fields"`   // 需要脱敏的字段名，不区分大小写
+		Mask     string          `toml:"Mask"`     // 脱敏后的值，默认 ***
+		Patterns []RedactPattern `toml:"Patterns"` // 对消息和字符串字段做正则替换
+	} `toml:"Redact"`
+
+	// Sinks 远程日志投递，异步批量发送，缓冲区满时丢弃并计入 log_sink_dropped_total
+	Sinks struct {
+		BufferSize    int `toml:"BufferSize"`    // 每个投递目标的缓冲区大小（条）
+		BatchSize     int `toml:"BatchSize"`     // 每批最多发送的条数
+		FlushInterval int `toml:"FlushInterval"` // 最长发送间隔，单位：秒
+
+		Kafka struct {
+			Enable bool   `toml:"Enable"` // 是否启用，复用 Kafka 生产者
+			Level  string `toml:"Level"`  // 最低投递级别
+			Topic  string `toml:"Topic"`  // 投递的 Topic
+		} `toml:"Kafka"`
+
+		Elasticsearch struct {
+			Enable      bool   `toml:"Enable"`      // 是否启用，复用 Elasticsearch 客户端
+			Level       string `toml:"Level"`       // 最低投递级别
+			IndexPrefix string `toml:"IndexPrefix"` // 索引前缀，按天建索引，eg: app-logs-2025.01.02
+			DateLayout  string `toml:"DateLayout"`  // 索引日期格式，默认 2006.01.02
+		} `toml:"Elasticsearch"`
+
+		Syslog struct {
+			Enable   bool   `toml:"Enable"`   // 是否启用
+			Level    string `toml:"Level"`    // 最低投递级别
+			Network  string `toml:"Network"`  // udp 或 tcp
+			Address  string `toml:"Address"`  // syslog 地址，eg: 127.0.0.1:514
+			Tag      string `toml:"Tag"`      // APP-NAME
+			Facility int    `toml:"Facility"` // facility，默认 16(local0)
+		} `toml:"Syslog"`
+	} `toml:"Sinks"`
+
+	// Access HTTP 访问日志，写入单独的文件，独立滚动
+	Access struct {
+		Enable        bool     `toml:"Enable"`        // 是否启用
+		File          string   `toml:"File"`          // 访问日志文件，位于 LogDir 下
+		MaxSize       int      `toml:"MaxSize"`       // 日志文件最大大小，单位：MB
+		MaxAge        int      `toml:"MaxAge"`        // 日志文件最大保存天数
+		MaxBackups    int      `toml:"MaxBackups"`    // 日志文件最大备份数量
+		Compress      bool     `toml:"Compress"`      // 是否压缩
+		SlowThreshold int      `toml:"SlowThreshold"` // 慢请求阈值，超过时以 warn 级别记录，单位：毫秒，0 表示不启用
+		Fields        []string `toml:"Fields"`        // 记录的字段，为空时记录默认字段
+		SkipPaths     []string `toml:"SkipPaths"`     // 不记录的路径，eg: /health
+
+		Body struct {
+			Enable  bool `toml:"Enable"`  // 是否记录请求和响应体
+			MaxSize int  `toml:"MaxSize"` // 记录的最大长度，单位：字节，超出部分截断
+		} `toml:"Body"`
+	} `toml:"Access"`
+}
+
+// RedactPattern 脱敏正则
+type RedactPattern struct {
+	Pattern string `toml:"Pattern"` // 正则表达式
+	Replace string `toml:"Replace"` // 替换内容，可引用分组，eg: ${1}***
 }
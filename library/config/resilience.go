@@ -0,0 +1,26 @@
+package config
+
+// ResilienceConfigEntry 依赖调用弹性策略配置
+type ResilienceConfigEntry struct {
+	Resilience struct {
+		Enable       bool                   `toml:"Enable"`       // 是否启用弹性策略
+		Dependencies []ResilienceDependency `toml:"Dependencies"` // 依赖的弹性策略
+	} `toml:"Resilience"`
+}
+
+// ResilienceDependency 依赖的弹性策略，按 重试 -> 对冲 -> 熔断 -> 舱壁 的顺序执行
+type ResilienceDependency struct {
+	Name           string  `toml:"Name"`           // 依赖名称，如 mysql、redis、elasticsearch
+	CircuitBreaker bool    `toml:"CircuitBreaker"` // 是否使用 circuitbreaker.toml 中同名的依赖熔断器
+	MaxConcurrent  int     `toml:"MaxConcurrent"`  // 舱壁的最大并发调用数，0 表示不限制
+	MaxQueue       int     `toml:"MaxQueue"`       // 舱壁的最大排队调用数，0 表示只受排队超时限制
+	QueueTimeout   int     `toml:"QueueTimeout"`   // 舱壁的排队超时（毫秒），0 表示不排队，没有空闲槽位时直接拒绝
+	MaxAttempts    int     `toml:"MaxAttempts"`    // 最大调用次数，包括第一次调用，不大于 1 时不重试
+	InitialBackoff int     `toml:"InitialBackoff"` // 第一次重试前的等待时间（毫秒）
+	MaxBackoff     int     `toml:"MaxBackoff"`     // 重试等待时间的上限（毫秒）
+	Multiplier     float64 `toml:"Multiplier"`     // 重试等待时间的增长倍数
+	Jitter         float64 `toml:"Jitter"`         // 重试等待时间的随机比例，取值 [0, 1]
+	RetryWrites    bool    `toml:"RetryWrites"`    // 是否重试写操作（resilience.DoWrite），仅在写操作幂等时启用
+	HedgeDelay     int     `toml:"HedgeDelay"`     // 发送对冲请求前的等待时间（毫秒），0 表示不发送对冲请求
+	MaxHedges      int     `toml:"MaxHedges"`      // 每次调用的最大对冲请求数
+}
@@ -0,0 +1,22 @@
+package config
+
+// SchemaRegistryConfigEntry 消息 Schema Registry 配置
+type SchemaRegistryConfigEntry struct {
+	SchemaRegistry struct {
+		Enable          bool                  `toml:"Enable"`          // 是否启用 Schema Registry，未启用时消息按裸 protobuf 序列化
+		URL             string                `toml:"URL"`             // Schema Registry 地址，如 http://localhost:8081
+		Username        string                `toml:"Username"`        // Basic 认证用户名，为空时不认证
+		Password        string                `toml:"Password"`        // Basic 认证密码
+		Timeout         int                   `toml:"Timeout"`         // 请求超时（毫秒）
+		Format          string                `toml:"Format"`          // 默认消息格式：protobuf、json、avro
+		SubjectStrategy string                `toml:"SubjectStrategy"` // Subject 命名策略：topic、record、topic_record
+		AutoRegister    bool                  `toml:"AutoRegister"`    // 生产消息时是否自动注册消息类型的 Schema
+		Topics          []SchemaRegistryTopic `toml:"Topics"`          // 按 Topic 覆盖消息格式
+	} `toml:"SchemaRegistry"`
+}
+
+// SchemaRegistryTopic Topic 的消息格式
+type SchemaRegistryTopic struct {
+	Name   string `toml:"Name"`   // Topic 名称
+	Format string `toml:"Format"` // 消息格式：protobuf、json、avro
+}
@@ -14,15 +14,68 @@ type ServerConfigEntry struct {
 	} `toml:"HTTPServer"`
 
 	AdminServer struct {
-		Listen string `toml:"Listen"` // 监听地址
+		Listen string          `toml:"Listen"` // 监听地址
+		Auth   AdminAuthConfig `toml:"Auth"`   // /admin 接口认证配置
 	} `toml:"AdminServer"`
 
 	Version struct {
 		Version string `toml:"Version"` // 版本号
 	} `toml:"Version"`
 
+	// 监听器配置，Listen 地址支持 unix:///path/to/app.sock
+	Listener ListenerConfig `toml:"Listener"`
+
 	// 新增的服务器选项配置
 	Options ServerOptions `toml:"Options"`
+
+	// HTTP 指标配置
+	Metrics MetricsConfig `toml:"Metrics"`
+
+	// 自适应过载保护配置
+	LoadShedding LoadSheddingConfig `toml:"LoadShedding"`
+}
+
+// AdminAuthConfig 管理接口认证配置，请求需携带 Authorization: Bearer <token>
+type AdminAuthConfig struct {
+	Enable bool              `toml:"Enable"` // 是否启用认证，关闭后任何能访问管理端口的人都可以调用 /admin 接口
+	Tokens []AdminTokenEntry `toml:"Tokens"` // 允许访问的令牌
+}
+
+// AdminTokenEntry 管理接口令牌，Name 作为操作人记录到审计日志
+type AdminTokenEntry struct {
+	Name     string `toml:"Name"`     // 令牌持有人，如 "ops"
+	Token    string `toml:"Token"`    // 令牌，建议使用 TokenEnv 避免写入配置文件
+	TokenEnv string `toml:"TokenEnv"` // 保存令牌的环境变量，设置后优先于 Token
+}
+
+// LoadSheddingConfig 自适应过载保护配置，按请求延迟和 CPU 使用率调整并发限制
+type LoadSheddingConfig struct {
+	Enable           bool     `toml:"Enable"`           // 是否启用过载保护
+	InitialLimit     int      `toml:"InitialLimit"`     // 初始并发限制
+	MinLimit         int      `toml:"MinLimit"`         // 最小并发限制
+	MaxLimit         int      `toml:"MaxLimit"`         // 最大并发限制
+	Window           int      `toml:"Window"`           // 调整并发限制的统计窗口，单位：毫秒
+	Tolerance        float64  `toml:"Tolerance"`        // 容忍的近期延迟与长期延迟之比，超过后降低并发限制
+	CPUThreshold     float64  `toml:"CPUThreshold"`     // 降低并发限制的进程 CPU 使用率，取值 (0, 1]，0 表示不按 CPU 调整
+	RetryAfter       int      `toml:"RetryAfter"`       // 被拒绝请求的 Retry-After 响应头，单位：秒
+	CriticalPaths    []string `toml:"CriticalPaths"`    // 最后被拒绝的路由，如登录
+	LowPriorityPaths []string `toml:"LowPriorityPaths"` // 最先被拒绝的路由，如导出和报表
+}
+
+// MetricsConfig HTTP 指标配置
+type MetricsConfig struct {
+	DurationBuckets []float64 `toml:"DurationBuckets"` // 请求耗时直方图的桶，单位：秒，为空使用默认值
+	SizeBuckets     []float64 `toml:"SizeBuckets"`     // 请求/响应大小直方图的桶，单位：字节，为空使用默认值
+	SLO             SLOConfig `toml:"SLO"`             // 服务级别目标配置
+}
+
+// SLOConfig 服务级别目标（SLO）配置
+type SLOConfig struct {
+	Enable                bool     `toml:"Enable"`                // 是否启用 SLO 指标
+	AvailabilityObjective float64  `toml:"AvailabilityObjective"` // 可用性目标，非 5xx 请求的比例，如 0.999
+	LatencyObjective      float64  `toml:"LatencyObjective"`      // 延迟目标，耗时不超过阈值的请求比例，如 0.99
+	LatencyThreshold      int      `toml:"LatencyThreshold"`      // 延迟阈值，单位：毫秒
+	SkipPaths             []string `toml:"SkipPaths"`             // 不计入 SLO 的路由，如健康检查
 }
 
 // ServerOptions 服务器配置选项
@@ -51,6 +104,15 @@ type ServerOptions struct {
 	WriteTimeout    time.Duration `toml:"WriteTimeout"`    // 写入超时
 	IdleTimeout     time.Duration `toml:"IdleTimeout"`     // 空闲超时
 	ShutdownTimeout time.Duration `toml:"ShutdownTimeout"` // 关闭超时
+	RequestTimeout  time.Duration `toml:"RequestTimeout"`  // 请求处理超时，0 表示不启用超时中间件
+}
+
+// ListenerConfig 监听器配置
+type ListenerConfig struct {
+	SocketMode             string        `toml:"SocketMode"`             // unix socket 文件权限，八进制，如 "0660"
+	EnableSocketActivation bool          `toml:"EnableSocketActivation"` // 是否启用 systemd socket 激活
+	EnableGracefulRestart  bool          `toml:"EnableGracefulRestart"`  // 是否启用 SIGUSR2 平滑重启
+	GracefulRestartTimeout time.Duration `toml:"GracefulRestartTimeout"` // 平滑重启时等待新进程就绪的超时时间（秒），0 表示 60 秒
 }
 
 // ServerRateLimitConfig 服务器限流配置
@@ -0,0 +1,33 @@
+package config
+
+// TracingConfigEntry 链路追踪配置
+type TracingConfigEntry struct {
+	Tracing struct {
+		Enable         bool   `toml:"Enable"`         // 是否启用链路追踪
+		ServiceName    string `toml:"ServiceName"`    // 服务名称，对应 service.name
+		ServiceVersion string `toml:"ServiceVersion"` // 服务版本，对应 service.version，构建时注入了版本号（buildinfo）则忽略
+		Environment    string `toml:"Environment"`    // 部署环境，eg: "dev", "prod"
+	} `toml:"Tracing"`
+
+	Exporter struct {
+		Type        string            `toml:"Type"`        // 导出器类型：otlp, stdout, memory, none
+		Endpoint    string            `toml:"Endpoint"`    // OTLP HTTP 地址，eg: "127.0.0.1:4318"
+		URLPath     string            `toml:"URLPath"`     // OTLP URL 路径，默认 "/v1/traces"
+		Insecure    bool              `toml:"Insecure"`    // 是否使用 HTTP 而非 HTTPS
+		Headers     map[string]string `toml:"Headers"`     // OTLP 请求头，eg: 鉴权信息
+		Timeout     int               `toml:"Timeout"`     // 导出超时（秒）
+		PrettyPrint bool              `toml:"PrettyPrint"` // stdout 导出器是否格式化输出
+	} `toml:"Exporter"`
+
+	Sampler struct {
+		Type        string  `toml:"Type"`        // 采样器类型：always_on, always_off, ratio
+		Ratio       float64 `toml:"Ratio"`       // 采样比例，0-1，Type 为 ratio 时有效
+		ParentBased bool    `toml:"ParentBased"` // 是否遵循上游的采样决策
+	} `toml:"Sampler"`
+
+	Batch struct {
+		MaxQueueSize       int `toml:"MaxQueueSize"`       // 待导出 span 队列大小
+		MaxExportBatchSize int `toml:"MaxExportBatchSize"` // 单次导出的最大 span 数
+		BatchTimeout       int `toml:"BatchTimeout"`       // 批量导出间隔（秒）
+	} `toml:"Batch"`
+}
@@ -0,0 +1,300 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Access log fields.
+const (
+	AccessFieldMethod    = "method"
+	AccessFieldRoute     = "route"
+	AccessFieldPath      = "path"
+	AccessFieldQuery     = "query"
+	AccessFieldStatus    = "status"
+	AccessFieldLatency   = "latency"
+	AccessFieldBytes     = "bytes"
+	AccessFieldClientIP  = "client_ip"
+	AccessFieldUserID    = "user_id"
+	AccessFieldRequestID = "request_id"
+	AccessFieldTraceID   = "trace_id"
+	AccessFieldUserAgent = "user_agent"
+	AccessFieldReferer   = "referer"
+)
+
+// DefaultAccessFields are the fields logged when AccessLogOptions.Fields is
+// empty.
+var DefaultAccessFields = []string{
+	AccessFieldMethod,
+	AccessFieldRoute,
+	AccessFieldPath,
+	AccessFieldStatus,
+	AccessFieldLatency,
+	AccessFieldBytes,
+	AccessFieldClientIP,
+	AccessFieldUserID,
+	AccessFieldRequestID,
+	AccessFieldUserAgent,
+}
+
+// DefaultMaxBodySize is the default maximum number of body bytes logged.
+const DefaultMaxBodySize = 2048
+
+// AccessLogOptions configures AccessLogMiddleware.
+type AccessLogOptions struct {
+	Logger        *zap.Logger      // the access logger, e.g. resource.AccessLogger
+	Fields        []string         // the logged fields, DefaultAccessFields if empty
+	SlowThreshold time.Duration    // requests slower than it are logged at warn level, 0 disables it
+	SkipPaths     []string         // the paths of the requests not logged
+	CaptureBody   bool             // whether the request and response bodies are logged
+	MaxBodySize   int              // the maximum number of body bytes logged, DefaultMaxBodySize if 0
+	Redactor      *logger.Redactor // masks sensitive fields of the bodies, may be nil
+}
+
+// AccessLogMiddleware returns a middleware writing one structured entry per
+// request to the access log.
+//
+// The entry is written once the request is handled, with the route template
+// (e.g. "/users/:id") so that entries can be grouped by endpoint. Server
+// errors are logged at error level, requests slower than SlowThreshold at
+// warn level with "slow": true, other requests at info level.
+//
+// When CaptureBody is set, the request and response bodies with a text
+// content type (JSON, forms, XML, text) are logged, truncated to
+// MaxBodySize bytes and redacted with Redactor. The request body is copied
+// as the handlers read it, so a body left unread is not logged.
+//
+// Parameters:
+//   - opts: The access log options.
+//
+// Returns:
+//   - gin.HandlerFunc: The Gin middleware function for the access log.
+func AccessLogMiddleware(opts AccessLogOptions) gin.HandlerFunc {
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = DefaultAccessFields
+	}
+	for _, field := range fields {
+		if !isAccessField(field) {
+			panic(fmt.Sprintf("unknown access log field %q", field))
+		}
+	}
+
+	maxBodySize := opts.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	skip := make(map[string]struct{}, len(opts.SkipPaths))
+	for _, path := range opts.SkipPaths {
+		skip[path] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		if _, ok := skip[c.Request.URL.Path]; ok || opts.Logger == nil {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		// Capture the bodies before the handlers consume them
+		var reqBody, respBody *bodyCapture
+		if opts.CaptureBody {
+			if isTextContent(c.ContentType()) {
+				reqBody = captureRequestBody(c, maxBodySize)
+			}
+			respBody = &bodyCapture{limit: maxBodySize}
+			c.Writer = &bodyCaptureWriter{ResponseWriter: c.Writer, body: respBody}
+		}
+
+		c.Next()
+
+		latency := time.Since(start)
+		entry := make([]zap.Field, 0, len(fields)+4)
+		for _, field := range fields {
+			if f, ok := accessField(c, field, latency); ok {
+				entry = append(entry, f)
+			}
+		}
+
+		if reqBody != nil {
+			entry = append(entry, reqBody.fields("request", opts.Redactor)...)
+		}
+		if respBody != nil && isTextContent(c.Writer.Header().Get("Content-Type")) {
+			entry = append(entry, respBody.fields("response", opts.Redactor)...)
+		}
+
+		level := zapcore.InfoLevel
+		switch {
+		case c.Writer.Status() >= 500:
+			level = zapcore.ErrorLevel
+		case opts.SlowThreshold > 0 && latency >= opts.SlowThreshold:
+			level = zapcore.WarnLevel
+			entry = append(entry, zap.Bool("slow", true))
+		}
+
+		if ce := opts.Logger.Check(level, "access"); ce != nil {
+			ce.Write(entry...)
+		}
+	}
+}
+
+// isAccessField reports whether field is a known access log field.
+func isAccessField(field string) bool {
+	switch field {
+	case AccessFieldMethod, AccessFieldRoute, AccessFieldPath, AccessFieldQuery,
+		AccessFieldStatus, AccessFieldLatency, AccessFieldBytes, AccessFieldClientIP,
+		AccessFieldUserID, AccessFieldRequestID, AccessFieldTraceID,
+		AccessFieldUserAgent, AccessFieldReferer:
+		return true
+	}
+	return false
+}
+
+// accessField returns the zap field of a handled request.
+//
+// Parameters:
+//   - c: The Gin context of the request.
+//   - field: The name of the field.
+//   - latency: The time spent handling the request.
+//
+// Returns:
+//   - zap.Field: The field.
+//   - bool: False if the field is optional and empty.
+func accessField(c *gin.Context, field string, latency time.Duration) (zap.Field, bool) {
+	switch field {
+	case AccessFieldMethod:
+		return zap.String(field, c.Request.Method), true
+	case AccessFieldRoute:
+		return zap.String(field, c.FullPath()), true
+	case AccessFieldPath:
+		return zap.String(field, c.Request.URL.Path), true
+	case AccessFieldQuery:
+		return zap.String(field, c.Request.URL.RawQuery), c.Request.URL.RawQuery != ""
+	case AccessFieldStatus:
+		return zap.Int(field, c.Writer.Status()), true
+	case AccessFieldLatency:
+		return zap.Duration(field, latency), true
+	case AccessFieldBytes:
+		return zap.Int(field, max(c.Writer.Size(), 0)), true
+	case AccessFieldClientIP:
+		return zap.String(field, c.ClientIP()), true
+	case AccessFieldUserID:
+		var userID string
+		if v, ok := c.Get("userID"); ok {
+			userID = fmt.Sprint(v)
+		}
+		return zap.String(field, userID), true
+	case AccessFieldRequestID:
+		return zap.String(field, resp.RequestID(c)), true
+	case AccessFieldTraceID:
+		sc := trace.SpanContextFromContext(c.Request.Context())
+		return zap.String(field, sc.TraceID().String()), sc.HasTraceID()
+	case AccessFieldUserAgent:
+		return zap.String(field, c.Request.UserAgent()), true
+	case AccessFieldReferer:
+		return zap.String(field, c.Request.Referer()), c.Request.Referer() != ""
+	}
+	return zap.Skip(), false
+}
+
+// isTextContent reports whether a content type is logged with the bodies.
+func isTextContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
+// bodyCapture keeps the first bytes of a body.
+type bodyCapture struct {
+	buf   bytes.Buffer
+	limit int
+	size  int
+}
+
+// Write implements io.Writer, bytes beyond the limit are only counted.
+func (b *bodyCapture) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	b.size += len(p)
+	return len(p), nil
+}
+
+// fields returns the access log fields of the captured body.
+func (b *bodyCapture) fields(prefix string, r *logger.Redactor) []zap.Field {
+	if b.size == 0 {
+		return nil
+	}
+
+	body := b.buf.String()
+	if r != nil {
+		body = r.Body(b.buf.Bytes())
+	}
+
+	fields := []zap.Field{zap.String(prefix+"_body", body)}
+	if b.size > b.buf.Len() {
+		fields = append(fields, zap.Bool(prefix+"_body_truncated", true))
+	}
+	return fields
+}
+
+// captureRequestBody captures the first limit bytes of the request body
+// while leaving the whole body readable by the handlers.
+func captureRequestBody(c *gin.Context, limit int) *bodyCapture {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil
+	}
+
+	capture := &bodyCapture{limit: limit}
+	c.Request.Body = &teeReadCloser{
+		Reader: io.TeeReader(c.Request.Body, capture),
+		Closer: c.Request.Body,
+	}
+	return capture
+}
+
+// teeReadCloser is the request body copying what the handlers read.
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyCaptureWriter copies the response body written by the handlers.
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body *bodyCapture
+}
+
+// Write implements http.ResponseWriter.
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	_, _ = w.body.Write(b[:n])
+	return n, err
+}
+
+// WriteString implements io.StringWriter.
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	_, _ = w.body.Write([]byte(s[:n]))
+	return n, err
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAdminAuthMiddleware tests that only the configured bearer tokens are
+// accepted and that the name of the token is stored as the actor.
+func TestAdminAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TEST_ADMIN_TOKEN", "from-env")
+
+	cfg := config.AdminAuthConfig{
+		Enable: true,
+		Tokens: []config.AdminTokenEntry{
+			{Name: "ops", Token: "secret"},
+			{Name: "deploy", Token: "ignored", TokenEnv: "TEST_ADMIN_TOKEN"},
+			{Name: "unset", TokenEnv: "TEST_ADMIN_TOKEN_UNSET"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantActor  string
+	}{
+		{"missing header", "", http.StatusUnauthorized, ""},
+		{"not bearer", "Basic secret", http.StatusUnauthorized, ""},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized, ""},
+		{"empty token", "Bearer ", http.StatusUnauthorized, ""},
+		{"config token", "Bearer secret", http.StatusOK, "ops"},
+		{"env token", "Bearer from-env", http.StatusOK, "deploy"},
+		{"env overrides config", "Bearer ignored", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actor any
+			router := gin.New()
+			router.Use(AdminAuthMiddleware(cfg))
+			router.POST("/admin/x", func(c *gin.Context) {
+				actor, _ = c.Get(ActorKey)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/x", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantActor != "" && actor != tt.wantActor {
+				t.Errorf("actor = %v, want %q", actor, tt.wantActor)
+			}
+		})
+	}
+}
+
+// TestAdminAuthMiddlewareNoToken tests that every request is rejected when
+// authentication is enabled without any usable token.
+func TestAdminAuthMiddlewareNoToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(AdminAuthMiddleware(config.AdminAuthConfig{
+		Enable: true,
+		Tokens: []config.AdminTokenEntry{{Name: "admin", TokenEnv: "TEST_ADMIN_TOKEN_UNSET"}},
+	}))
+	router.GET("/admin/x", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/x", nil)
+	req.Header.Set("Authorization", BearerPrefix)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/audit"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// defaultAuditTimeout bounds the time spent storing an audit event when no
+// timeout is configured.
+const defaultAuditTimeout = 3 * time.Second
+
+// RecordAudit records a security relevant event of the current request in
+// the audit trail.
+//
+// The client IP, the user agent, the request ID, the authenticated
+// principal and the ID of the authenticated user are filled in from c when
+// not set. See RecordAuditContext.
+//
+// Parameters:
+//   - c: The Gin context of the request.
+//   - e: The event, at least its action and outcome.
+func RecordAudit(c *gin.Context, e audit.Event) {
+	if e.ClientIP == "" {
+		e.ClientIP = c.ClientIP()
+	}
+	if e.UserAgent == "" {
+		e.UserAgent = c.Request.UserAgent()
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.RequestID(c)
+	}
+	if e.Actor == "" {
+		if actor, ok := c.Get(ActorKey); ok {
+			e.Actor = fmt.Sprint(actor)
+		}
+	}
+	if e.ActorID == "" {
+		if userID, ok := c.Get("userID"); ok {
+			e.ActorID = fmt.Sprint(userID)
+		}
+	}
+
+	RecordAuditContext(c.Request.Context(), e)
+}
+
+// RecordAuditContext records a security relevant event in the audit trail,
+// e.g. a change made by a job rather than by a request.
+//
+// The event is stored synchronously, but a failure does not fail the
+// caller: it is logged with the event so that it is not lost. Nothing is
+// recorded if the audit trail is not initialized.
+//
+// Parameters:
+//   - ctx: The context of the operation, carrying the request ID if any.
+//   - e: The event, at least its action and outcome.
+func RecordAuditContext(ctx context.Context, e audit.Event) {
+	store := resource.AuditStore
+	if store == nil {
+		return
+	}
+	if e.RequestID == "" {
+		e.RequestID = common.RequestIDFromContext(ctx)
+	}
+
+	timeout := defaultAuditTimeout
+	if config.AuditConfig != nil && config.AuditConfig.Audit.Timeout > 0 {
+		timeout = time.Duration(config.AuditConfig.Audit.Timeout) * time.Second
+	}
+
+	// The event is stored even if the request is cancelled meanwhile
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	if err := store.Append(storeCtx, &e); err != nil {
+		logger.WithContext(ctx, resource.AuthLogger).Error("Failed to record audit event",
+			zap.String("action", e.Action),
+			zap.String("actor", e.Actor),
+			zap.String("target", e.Target),
+			zap.String("outcome", e.Outcome),
+			zap.Error(err),
+		)
+	}
+}
+
+// AuditMiddleware returns a middleware recording every call of the routes
+// it is mounted on in the audit trail, e.g. the admin endpoints.
+//
+// The event is recorded once the request is handled, with the route as
+// target and a failure outcome for 4xx and 5xx responses.
+//
+// Returns:
+//   - gin.HandlerFunc: The Gin middleware function for auditing.
+func AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		e := audit.Event{
+			Action:  audit.ActionAdminCall,
+			Target:  c.Request.Method + " " + route,
+			Outcome: audit.OutcomeSuccess,
+		}
+		if status := c.Writer.Status(); status >= http.StatusBadRequest {
+			e.Outcome = audit.OutcomeFailure
+			e.Reason = http.StatusText(status)
+		}
+		if c.Request.URL.RawQuery != "" {
+			_ = e.SetDetails(map[string]string{"query": c.Request.URL.RawQuery})
+		}
+
+		RecordAudit(c, e)
+	}
+}
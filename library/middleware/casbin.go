@@ -1,10 +1,11 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
 	"time"
 
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+
 	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -34,7 +35,7 @@ func CasbinMiddleware(enforcer *casbin.Enforcer) gin.HandlerFunc {
 
 		if !exists {
 			// Abort with 403 Forbidden if the role is not found
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			resp.AbortWithAppError(c, resp.ErrForbidden, resp.RequestID(c))
 			return
 		}
 
@@ -47,13 +48,13 @@ func CasbinMiddleware(enforcer *casbin.Enforcer) gin.HandlerFunc {
 
 		if err != nil {
 			// Abort with 500 Internal Server Error if the enforcer encounters an error
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			resp.AbortWithAppError(c, resp.ErrAuthorizationFailed.Wrap(err), resp.RequestID(c))
 			return
 		}
 
 		if !ok {
 			// Abort with 403 Forbidden if access is denied
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			resp.AbortWithAppError(c, resp.ErrForbidden, resp.RequestID(c))
 			return
 		}
 
@@ -113,7 +114,7 @@ func AuthMiddlewareCasbin() gin.HandlerFunc {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			// Abort with 401 Unauthorized if the token is missing
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			resp.AbortWithAppError(c, resp.ErrTokenMissing, resp.RequestID(c))
 			return
 		}
 
@@ -128,7 +129,7 @@ func AuthMiddlewareCasbin() gin.HandlerFunc {
 
 		if err != nil || !token.Valid {
 			// Abort with 401 Unauthorized if the token is invalid
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			resp.AbortWithAppError(c, resp.ErrTokenInvalid, resp.RequestID(c))
 			return
 		}
 
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ErrorMiddleware renders errors attached to the context with c.Error into
+// the standard Response envelope.
+//
+// Handlers can return an error by calling c.Error(err) and c.Abort() without
+// writing a response themselves. After the chain completes, the last error is
+// rendered with response.NewAppErrResp: AppErrors keep their code, status and
+// localised message, any other error is rendered as an internal error without
+// leaking its message. Server errors are logged with their cause.
+//
+// Nothing is rendered if a response has already been written.
+//
+// Returns:
+//   - gin.HandlerFunc: The Gin middleware function for error rendering.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := resp.AsAppError(c.Errors.Last().Err)
+		if appErr.HTTPStatus >= 500 {
+			logger.WithContext(c.Request.Context(), resource.HTTPLogger).Error("Request failed",
+				zap.String("code", appErr.Code),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.Error(appErr),
+			)
+		}
+
+		resp.NewAppErrResp(c, appErr, resp.RequestID(c))
+	}
+}
+
+// NotFoundHandler renders unknown routes with the standard Response envelope.
+func NotFoundHandler(c *gin.Context) {
+	resp.AbortWithAppError(c, resp.ErrNotFound, resp.RequestID(c))
+}
+
+// MethodNotAllowedHandler renders unsupported methods with the standard
+// Response envelope.
+func MethodNotAllowedHandler(c *gin.Context) {
+	resp.AbortWithAppError(c, resp.ErrMethodNotAllowed, resp.RequestID(c))
+}
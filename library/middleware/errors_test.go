@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestErrorMiddleware tests the status and the body written by the error
+// middleware for the errors attached to the context.
+func TestErrorMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		lang         string
+		handler      gin.HandlerFunc
+		wantStatus   int
+		wantErrCode  string
+		wantMsg      string
+		wantLanguage string
+	}{
+		{
+			name: "app error",
+			lang: "en-US",
+			handler: func(c *gin.Context) {
+				_ = c.Error(resp.ErrUserExists.WithDetails(resp.FieldError{Field: "username"}))
+			},
+			wantStatus:   http.StatusConflict,
+			wantErrCode:  resp.CodeUserExists,
+			wantMsg:      "Username already exists",
+			wantLanguage: resp.LangEnUS,
+		},
+		{
+			name: "localized app error",
+			lang: "zh-CN,en;q=0.5",
+			handler: func(c *gin.Context) {
+				_ = c.Error(resp.ErrNotFound)
+			},
+			wantStatus:   http.StatusNotFound,
+			wantErrCode:  resp.CodeNotFound,
+			wantMsg:      "资源不存在",
+			wantLanguage: resp.LangZhCN,
+		},
+		{
+			name: "wrapped app error",
+			lang: "en",
+			handler: func(c *gin.Context) {
+				_ = c.Error(fmt.Errorf("get user: %w", resp.ErrNotFound.Wrap(errors.New("record not found"))))
+			},
+			wantStatus:   http.StatusNotFound,
+			wantErrCode:  resp.CodeNotFound,
+			wantMsg:      "Not found",
+			wantLanguage: resp.LangEnUS,
+		},
+		{
+			name: "plain error",
+			lang: "en-US",
+			handler: func(c *gin.Context) {
+				_ = c.Error(errors.New("dial tcp 10.0.0.1:3306: secret"))
+			},
+			wantStatus:   http.StatusInternalServerError,
+			wantErrCode:  resp.CodeInternal,
+			wantMsg:      "Internal server error",
+			wantLanguage: resp.LangEnUS,
+		},
+		{
+			name: "last error",
+			lang: "en-US",
+			handler: func(c *gin.Context) {
+				_ = c.Error(resp.ErrNotFound)
+				_ = c.Error(resp.ErrTooManyRequests)
+			},
+			wantStatus:   http.StatusTooManyRequests,
+			wantErrCode:  resp.CodeTooManyRequests,
+			wantMsg:      "Too many requests",
+			wantLanguage: resp.LangEnUS,
+		},
+		{
+			name: "response written",
+			lang: "en-US",
+			handler: func(c *gin.Context) {
+				_ = c.Error(resp.ErrInternal)
+				c.String(http.StatusAccepted, "accepted")
+			},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name: "no error",
+			lang: "en-US",
+			handler: func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorMiddleware())
+			router.GET("/errors", tt.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/errors", nil)
+			req.Header.Set("Accept-Language", tt.lang)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantErrCode == "" {
+				if strings.Contains(w.Body.String(), "errorCode") {
+					t.Errorf("body = %s, want no error response", w.Body.String())
+				}
+				return
+			}
+
+			var body resp.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode the body %s: %v", w.Body.String(), err)
+			}
+			if body.Code != tt.wantStatus || body.Success || body.ErrCode != tt.wantErrCode || body.ErrMsg != tt.wantMsg {
+				t.Errorf("body = %+v, want %d %s %q", body, tt.wantStatus, tt.wantErrCode, tt.wantMsg)
+			}
+			if strings.Contains(w.Body.String(), "secret") || strings.Contains(w.Body.String(), "record not found") {
+				t.Errorf("body = %s, want no cause", w.Body.String())
+			}
+			if got := w.Header().Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantLanguage)
+			}
+		})
+	}
+}
+
+// TestNotFoundHandlers tests the responses to the unknown routes and the
+// unsupported methods.
+func TestNotFoundHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(NotFoundHandler)
+	router.NoMethod(MethodNotAllowedHandler)
+	router.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		wantErrCode string
+	}{
+		{"unknown route", http.MethodGet, "/unknown", http.StatusNotFound, resp.CodeNotFound},
+		{"unsupported method", http.MethodDelete, "/users", http.StatusMethodNotAllowed, resp.CodeMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			var body resp.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode the body %s: %v", w.Body.String(), err)
+			}
+			if w.Code != tt.wantStatus || body.ErrCode != tt.wantErrCode {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.ErrCode, tt.wantStatus, tt.wantErrCode)
+			}
+		})
+	}
+}
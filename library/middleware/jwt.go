@@ -2,10 +2,11 @@ package middleware
 
 import (
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -31,13 +32,13 @@ func AuthMiddlewareJWT(c *gin.Context) {
 	// Get the token from the request header
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+		resp.AbortWithAppError(c, resp.ErrTokenMissing, resp.RequestID(c))
 		return
 	}
 
 	// Check if the token has the correct prefix
 	if !strings.HasPrefix(authHeader, BearerPrefix) {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
+		resp.AbortWithAppError(c, resp.ErrTokenInvalid.WithDetails(gin.H{"reason": "invalid token format"}), resp.RequestID(c))
 		return
 	}
 
@@ -47,7 +48,7 @@ func AuthMiddlewareJWT(c *gin.Context) {
 	// Verify the token and extract the userID
 	userID, err := verifyToken(token)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("Invalid token: %v", err)})
+		resp.AbortWithAppError(c, resp.ErrTokenInvalid.Wrap(err), resp.RequestID(c))
 		return
 	}
 
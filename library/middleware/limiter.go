@@ -1,17 +1,15 @@
 package middleware
 
 import (
-	"context"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
 	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/ulule/limiter/v3"
 	limitergin "github.com/ulule/limiter/v3/drivers/middleware/gin"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
@@ -71,10 +69,7 @@ func RedisLimiter(rate limiter.Rate) gin.HandlerFunc {
 		}),
 		limitergin.WithErrorHandler(func(c *gin.Context, err error) {
 			// Custom error handler for rate limiting errors.
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"error":   "Internal Server Error",
-				"message": err.Error(),
-			})
+			resp.AbortWithAppError(c, resp.ErrDependencyFailure.Wrap(err), resp.RequestID(c))
 		}),
 	)
 }
@@ -100,7 +95,7 @@ func UserIDLimiter(rate limiter.Rate) gin.HandlerFunc {
 		userID, exists := c.Get("userID")
 		if !exists {
 			// Abort the request if the user ID does not exist
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			resp.AbortWithAppError(c, resp.ErrUnauthorized, resp.RequestID(c))
 			return
 		}
 
@@ -117,18 +112,17 @@ func UserIDLimiter(rate limiter.Rate) gin.HandlerFunc {
 		ctx, err := instance.Get(c, limiterKey)
 		if err != nil {
 			// Abort the request if there is an error getting the rate limit context
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			resp.AbortWithAppError(c, resp.ErrInternal.Wrap(err), resp.RequestID(c))
 			return
 		}
 
 		// Check if the rate limit has been exceeded
 		if ctx.Reached {
 			// Abort the request if the rate limit has been exceeded
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error":  "Rate limit exceeded",
+			resp.AbortWithAppError(c, resp.ErrTooManyRequests.WithDetails(gin.H{
 				"limit":  rate.Limit,
 				"period": rate.Period.String(),
-			})
+			}), resp.RequestID(c))
 			return
 		}
 
@@ -163,7 +157,7 @@ func IPWhitelist(whitelist []string) gin.HandlerFunc {
 		}
 
 		// Abort the request with a 403 Forbidden status if the IP is not allowed
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "IP not allowed"})
+		resp.AbortWithAppError(c, resp.ErrIPNotAllowed, resp.RequestID(c))
 	}
 }
 
@@ -185,32 +179,30 @@ func IPWhitelist(whitelist []string) gin.HandlerFunc {
 //   - gin.HandlerFunc: The Gin middleware function for login rate limiting.
 func LoginRateLimiter() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Generate a unique request ID
-		reqID := uuid.NewString()
+		// Get the request ID of the current request
+		reqID := resp.RequestID(c)
 
 		// Construct the Redis key for the login attempt counter
 		key := fmt.Sprintf("rate_limit:login:%s", c.ClientIP())
 
 		// Execute the Redis script to increment the counter and check if the limit has been exceeded
-		allowed, err := resource.RedisClient.Eval(context.Background(), config.LuaScript, []string{key}, 1, 10).Int()
+		allowed, err := resource.RedisClient.Eval(c.Request.Context(), config.LuaScript, []string{key}, 1, 10).Int()
 		if err != nil {
 			// Log an error if the Redis eval fails
-			resource.LoggerService.Error(fmt.Sprintf("Redis eval failed: %v", err))
+			logger.WithContext(c.Request.Context(), resource.HTTPLogger).Error(fmt.Sprintf("Redis eval failed: %v", err))
 
 			// Abort the request with a 500 Internal Server Error status
-			resp.NewErrResp(c, http.StatusInternalServerError, fmt.Sprintf("Redis eval failed: %v", err), reqID)
-			c.Abort()
+			resp.AbortWithAppError(c, resp.ErrDependencyFailure.Wrap(err), reqID)
 			return
 		}
 
 		// Check if the rate limit has been exceeded
 		if allowed == 0 {
 			// Log an error if the rate limit has been exceeded
-			resource.LoggerService.Error(fmt.Sprintf("Login rate limit exceeded"))
+			logger.WithContext(c.Request.Context(), resource.HTTPLogger).Error("Login rate limit exceeded")
 
 			// Abort the request with 429 Too Much Requests status
-			resp.NewErrResp(c, http.StatusTooManyRequests, fmt.Sprintf("Too much login requests, rate limit exceeded"), reqID)
-			c.Abort()
+			resp.AbortWithAppError(c, resp.ErrLoginRateLimited, reqID)
 			return
 		}
 
@@ -237,32 +229,30 @@ func LoginRateLimiter() gin.HandlerFunc {
 //   - gin.HandlerFunc: The Gin middleware function for API rate limiting.
 func APIRateLimiter() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Generate a unique request ID
-		reqID := uuid.NewString()
+		// Get the request ID of the current request
+		reqID := resp.RequestID(c)
 
 		// Construct the Redis key for the api attempt counter
 		key := fmt.Sprintf("rate_limit:api:%s", c.ClientIP())
 
 		// Execute the Redis script to increment the counter and check if the limit has been exceeded
-		allowed, err := resource.RedisClient.Eval(context.Background(), config.LuaScript, []string{key}, 5, 1).Int()
+		allowed, err := resource.RedisClient.Eval(c.Request.Context(), config.LuaScript, []string{key}, 5, 1).Int()
 		if err != nil {
 			// Log an error if the Redis eval fails
-			resource.LoggerService.Error(fmt.Sprintf("Redis eval failed: %v", err))
+			logger.WithContext(c.Request.Context(), resource.HTTPLogger).Error(fmt.Sprintf("Redis eval failed: %v", err))
 
 			// Abort the request with a 500 Internal Server Error status
-			resp.NewErrResp(c, http.StatusInternalServerError, "Redis eval failed", reqID)
-			c.Abort()
+			resp.AbortWithAppError(c, resp.ErrDependencyFailure.Wrap(err), reqID)
 			return
 		}
 
 		// Check if the rate limit has been exceeded
 		if allowed == 0 {
 			// Log an error if the rate limit has been exceeded
-			resource.LoggerService.Error(fmt.Sprintf("API request rate limit exceeded"))
+			logger.WithContext(c.Request.Context(), resource.HTTPLogger).Error("API request rate limit exceeded")
 
 			// Abort the request with 429 Too Much Requests status
-			resp.NewErrResp(c, http.StatusTooManyRequests, "Too much API requests, rate limit exceeded", reqID)
-			c.Abort()
+			resp.AbortWithAppError(c, resp.ErrAPIRateLimited, reqID)
 			return
 		}
 
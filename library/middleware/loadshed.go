@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/loadshed"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoadSheddingOptions configures LoadSheddingMiddleware.
+type LoadSheddingOptions struct {
+	Limiter          *loadshed.Limiter // the adaptive concurrency limiter
+	RetryAfter       time.Duration     // the delay advertised in the Retry-After header of the shed requests
+	CriticalPaths    []string          // the paths shed last, e.g. the login
+	LowPriorityPaths []string          // the paths shed first, e.g. exports and reports
+}
+
+// LoadSheddingMiddleware returns a middleware protecting the server from
+// overload with an adaptive concurrency limiter.
+//
+// Requests beyond the limit of their priority are rejected with 503 Service
+// Unavailable and a Retry-After header, before any handler runs. The
+// priority of a request is critical if its route or path is one of
+// CriticalPaths, low if it is one of LowPriorityPaths, and normal
+// otherwise. The latency of the admitted requests adjusts the limit, see
+// package loadshed.
+//
+// Parameters:
+//   - opts: The load shedding options.
+//
+// Returns:
+//   - gin.HandlerFunc: The Gin middleware function for load shedding.
+func LoadSheddingMiddleware(opts LoadSheddingOptions) gin.HandlerFunc {
+	priorities := make(map[string]loadshed.Priority, len(opts.CriticalPaths)+len(opts.LowPriorityPaths))
+	for _, p := range opts.LowPriorityPaths {
+		priorities[p] = loadshed.PriorityLow
+	}
+	for _, p := range opts.CriticalPaths {
+		priorities[p] = loadshed.PriorityCritical
+	}
+
+	retryAfter := strconv.Itoa(max(int(math.Ceil(opts.RetryAfter.Seconds())), 1))
+
+	return func(c *gin.Context) {
+		priority := loadshed.PriorityNormal
+		if p, ok := priorities[c.FullPath()]; ok {
+			priority = p
+		} else if p, ok := priorities[c.Request.URL.Path]; ok {
+			priority = p
+		}
+
+		if !opts.Limiter.Acquire(priority) {
+			c.Header("Retry-After", retryAfter)
+			resp.AbortWithAppError(c, resp.ErrOverloaded.WithDetails(gin.H{"priority": priority.String()}), resp.RequestID(c))
+			return
+		}
+
+		start := time.Now()
+		defer func() {
+			opts.Limiter.Release(time.Since(start))
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/loadshed"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestLoadSheddingMiddleware tests that a request beyond the limit is
+// rejected with 503 and a Retry-After header, and that the metrics and SLO
+// middleware mounted before it count the rejection.
+func TestLoadSheddingMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := loadshed.NewLimiter(loadshed.Config{InitialLimit: 1, MinLimit: 1, MaxLimit: 1})
+
+	called := false
+	router := gin.New()
+	router.Use(
+		PrometheusMiddleware(),
+		SLOMiddleware(SLOOptions{AvailabilityObjective: 0.999}),
+		LoadSheddingMiddleware(LoadSheddingOptions{
+			Limiter:       limiter,
+			RetryAfter:    2 * time.Second,
+			CriticalPaths: []string{"/shed/critical"},
+		}),
+	)
+	router.GET("/shed/normal", func(c *gin.Context) {
+		called = true
+		c.Status(http.StatusOK)
+	})
+	router.GET("/shed/critical", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// The only slot of the limit is taken
+	if !limiter.Acquire(loadshed.PriorityNormal) {
+		t.Fatal("Acquire() of the first request failed")
+	}
+	defer limiter.Release(time.Millisecond)
+
+	badBefore := testutil.ToFloat64(sloRequestsTotal.WithLabelValues(SLOAvailability, sloBad))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shed/normal", nil))
+
+	if w.Code != http.StatusServiceUnavailable || called {
+		t.Fatalf("status = %d, handler called = %v, want 503 without calling the handler", w.Code, called)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+	var body resp.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.ErrCode != resp.CodeOverloaded {
+		t.Errorf("body = %s, want the %s error code", w.Body.String(), resp.CodeOverloaded)
+	}
+
+	if got := testutil.ToFloat64(httpMetrics.requests.WithLabelValues(http.MethodGet, "/shed/normal", "503")); got != 1 {
+		t.Errorf("http_requests_total{status=503} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(sloRequestsTotal.WithLabelValues(SLOAvailability, sloBad)) - badBefore; got != 1 {
+		t.Errorf("bad availability requests = %v, want 1", got)
+	}
+
+	// A critical request is admitted up to 1.5 times the limit
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shed/critical", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status of the critical request = %d, want 200", w.Code)
+	}
+}
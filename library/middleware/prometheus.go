@@ -1,8 +1,14 @@
 package middleware
 
 import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/xiebingnote/go-gin-project/pkg/buildinfo"
+
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -13,24 +19,6 @@ var (
 	// Timer 启动时间
 	Timer = prometheus.NewTimer(ServerStartupDuration)
 
-	// AppStartTime 启动时间
-	AppStartTime = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "app_start_timestamp_seconds",
-			Help: "Application start timestamp",
-		},
-		[]string{"version"},
-	)
-
-	// AppUptime 运行时间
-	AppUptime = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "app_uptime_seconds",
-			Help: "Application uptime in seconds",
-		},
-		[]string{"version"},
-	)
-
 	// ServerStartupDuration 启动时间
 	ServerStartupDuration = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
@@ -38,55 +26,205 @@ var (
 			Help: "Time taken to start servers",
 		},
 	)
-
-	// HTTP 请求计数
-	httpRequestsTotal = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Total number of HTTP requests",
-		},
-		[]string{"method", "path"},
-	)
-
-	// HTTP 请求持续时间
-	httpRequestDuration = promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "Duration of HTTP requests",
-			Buckets: prometheus.DefBuckets, // use default buckets
-		},
-		[]string{"method", "path"},
-	)
 )
 
 // init registers the prometheus metrics with the default prometheus registry.
 //
 // This init function is called automatically when the package is initialized.
+// The build information, start time and uptime of the application are
+// exported by buildinfo.Collector, computed on each scrape.
 func init() {
-	prometheus.MustRegister(AppStartTime, AppUptime, ServerStartupDuration)
+	prometheus.MustRegister(buildinfo.NewCollector(), ServerStartupDuration)
 }
 
-// PrometheusMiddleware returns a gin.HandlerFunc that records
-// request metrics using Prometheus. It records the total count
-// of requests and the duration of each request.
+// UnmatchedRoute is the route label of the requests matching no route, e.g.
+// 404 responses, so that unknown paths do not create new series.
+const UnmatchedRoute = "unmatched"
+
+// otherMethod is the method label of non-standard HTTP methods.
+const otherMethod = "OTHER"
+
+// DefaultSizeBuckets are the buckets of the request and response size
+// histograms, from 100 B to 10 MB.
+var DefaultSizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
+
+// ErrHTTPMetricsInitialized is returned by InitHTTPMetrics when the HTTP
+// metrics are already registered.
+var ErrHTTPMetricsInitialized = errors.New("http metrics are already initialized")
+
+// HTTPMetricsOptions configures the HTTP metrics recorded by
+// PrometheusMiddleware.
+type HTTPMetricsOptions struct {
+	DurationBuckets []float64 // the request duration buckets in seconds, prometheus.DefBuckets if empty
+	SizeBuckets     []float64 // the request and response size buckets in bytes, DefaultSizeBuckets if empty
+}
+
+// httpMetricSet holds the HTTP metrics, registered once for all servers.
+type httpMetricSet struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+}
+
+var (
+	httpMetricsOnce sync.Once
+	httpMetrics     *httpMetricSet
+)
+
+// InitHTTPMetrics registers the HTTP metrics with the given buckets.
 //
-// The request metrics are recorded with the following labels:
+// It must be called before the first PrometheusMiddleware is created, which
+// otherwise registers the metrics with the default buckets.
 //
-// - method: the HTTP method (e.g. "GET", "POST", etc.)
-// - path: the path of the request (e.g. "/api/v1/users", etc.)
+// Parameters:
+//   - opts: The HTTP metrics options.
 //
-// The duration of the request is recorded in seconds.
+// Returns:
+//   - error: ErrHTTPMetricsInitialized if the metrics are already registered.
+func InitHTTPMetrics(opts HTTPMetricsOptions) error {
+	initialized := false
+	httpMetricsOnce.Do(func() {
+		httpMetrics = newHTTPMetricSet(opts)
+		initialized = true
+	})
+	if !initialized {
+		return ErrHTTPMetricsInitialized
+	}
+	return nil
+}
+
+// newHTTPMetricSet creates and registers the HTTP metrics.
+func newHTTPMetricSet(opts HTTPMetricsOptions) *httpMetricSet {
+	durationBuckets := opts.DurationBuckets
+	if len(durationBuckets) == 0 {
+		durationBuckets = prometheus.DefBuckets
+	}
+	sizeBuckets := opts.SizeBuckets
+	if len(sizeBuckets) == 0 {
+		sizeBuckets = DefaultSizeBuckets
+	}
+
+	return &httpMetricSet{
+		// HTTP 请求计数
+		requests: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_total",
+				Help: "Total number of HTTP requests",
+			},
+			[]string{"method", "route", "code"},
+		),
+
+		// HTTP 请求持续时间
+		duration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_duration_seconds",
+				Help:    "Duration of HTTP requests",
+				Buckets: durationBuckets,
+			},
+			[]string{"method", "route", "status_class"},
+		),
+
+		// HTTP 请求大小
+		requestSize: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_size_bytes",
+				Help:    "Size of HTTP request bodies",
+				Buckets: sizeBuckets,
+			},
+			[]string{"method", "route", "status_class"},
+		),
+
+		// HTTP 响应大小
+		responseSize: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_response_size_bytes",
+				Help:    "Size of HTTP response bodies",
+				Buckets: sizeBuckets,
+			},
+			[]string{"method", "route", "status_class"},
+		),
+
+		// 正在处理的 HTTP 请求数
+		inFlight: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "http_requests_in_flight",
+				Help: "Number of HTTP requests being handled",
+			},
+		),
+	}
+}
+
+// PrometheusMiddleware returns a gin.HandlerFunc that records the RED
+// (rate, errors, duration) metrics of the requests using Prometheus.
+//
+// The following metrics are recorded:
+//
+// - http_requests_total: the requests by method, route and status code
+// - http_request_duration_seconds: the duration of the requests in seconds
+// - http_request_size_bytes, http_response_size_bytes: the body sizes
+// - http_requests_in_flight: the requests being handled
+//
+// The histograms are labeled by method, route and status class (e.g. "2xx")
+// rather than status code to bound their number of series. The route is the
+// route template (e.g. "/api/v1/users/:id"), or UnmatchedRoute for requests
+// matching no route; non-standard methods are labeled "OTHER".
+//
+// The metrics are registered with the buckets given to InitHTTPMetrics, or
+// with the default buckets if it was not called.
 func PrometheusMiddleware() gin.HandlerFunc {
+	_ = InitHTTPMetrics(HTTPMetricsOptions{})
+	m := httpMetrics
+
 	return func(c *gin.Context) {
 		// Record the start time of the request
 		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
 
 		// Handle the request
 		c.Next()
 
 		// Record the request metrics
 		duration := time.Since(start).Seconds()
-		httpRequestsTotal.WithLabelValues(c.Request.Method, c.FullPath()).Inc()
-		httpRequestDuration.WithLabelValues(c.Request.Method, c.FullPath()).Observe(duration)
+		method := metricMethod(c.Request.Method)
+		route := metricRoute(c)
+		status := c.Writer.Status()
+		class := statusClass(status)
+
+		m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+		m.duration.WithLabelValues(method, route, class).Observe(duration)
+		if c.Request.ContentLength >= 0 {
+			m.requestSize.WithLabelValues(method, route, class).Observe(float64(c.Request.ContentLength))
+		}
+		m.responseSize.WithLabelValues(method, route, class).Observe(float64(max(c.Writer.Size(), 0)))
+	}
+}
+
+// metricRoute returns the route label of a handled request.
+func metricRoute(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return UnmatchedRoute
+}
+
+// metricMethod returns the method label of a request, so that arbitrary
+// methods sent by clients do not create new series.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return otherMethod
+}
+
+// statusClass returns the class of a status code, e.g. "2xx".
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
 	}
+	return strconv.Itoa(status/100) + "xx"
 }
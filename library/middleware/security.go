@@ -3,8 +3,10 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/xiebingnote/go-gin-project/library/common"
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // CORSMiddleware adds CORS headers to responses.
@@ -116,35 +118,57 @@ func SecurityHeadersMiddleware() gin.HandlerFunc {
 // RequestIDMiddleware  requests a unique request ID for each request.
 //
 // The request ID is used for logging and debugging purposes. If the request
-// header contains an X-Request-ID, the value of the header is used.
+// header contains a valid X-Request-ID, the value of the header is used.
 // Otherwise, a new UUID is generated.
 //
+// The request ID is stored in the gin context, in the request context (so that
+// context-aware loggers, message producers and HTTP clients can pick it up)
+// and returned in the X-Request-ID response header. Handlers should read it
+// with response.RequestID instead of generating their own.
+//
 // Returns:
 //   - gin.HandlerFunc: The request ID middleware function.
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check if the request ID is already set in the request header.
-		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
-			// Generate a new request ID if it is not set.
-			requestID = generateRequestID()
+		requestID := c.GetHeader(common.RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			// Generate a new request ID if it is not set or not acceptable.
+			requestID = common.NewRequestID()
 		}
 
 		// Set the request ID to the context and the response header.
-		c.Set("request_id", requestID)
-		c.Header("X-Request-ID", requestID)
+		c.Set(common.RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(common.WithRequestID(c.Request.Context(), requestID))
+		c.Header(common.RequestIDHeader, requestID)
 
 		c.Next()
 	}
 }
 
-// generateRequestID generates a unique request ID.
+// maxRequestIDLength is the maximum length of a request ID accepted from
+// the client.
+const maxRequestIDLength = 128
+
+// isValidRequestID reports whether a request ID received from the client can
+// be reused. Only short, printable ASCII values are accepted so that the ID
+// cannot be used to inject content into logs or headers.
+//
+// Parameters:
+//   - requestID: The request ID from the X-Request-ID header.
 //
 // Returns:
-//   - string: The generated request ID.
-func generateRequestID() string {
-	// Generate a new UUID for the request ID.
-	return uuid.NewString()
+//   - bool: True if the request ID can be used.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] <= ' ' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
 }
 
 // IPWhitelistMiddleware creates a middleware function for IP whitelisting.
@@ -171,10 +195,7 @@ func IPWhitelistMiddleware(allowedIPs []string) gin.HandlerFunc {
 		// Check if the client's IP is in the whitelist.
 		if !ipMap[clientIP] {
 			// If the client's IP is not allowed, abort the request with a 403 Forbidden status.
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-				"error": "IP not allowed",
-				"code":  "IP_NOT_ALLOWED",
-			})
+			resp.AbortWithAppError(c, resp.ErrIPNotAllowed, resp.RequestID(c))
 			return
 		}
 
@@ -206,10 +227,7 @@ func UserAgentFilterMiddleware(blockedAgents []string) gin.HandlerFunc {
 		// Check if the User-Agent is in the blacklist.
 		if agentMap[userAgent] {
 			// If the User-Agent is blacklisted, respond with a 403 Forbidden status.
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-				"error": "Access denied",
-				"code":  "USER_AGENT_BLOCKED",
-			})
+			resp.AbortWithAppError(c, resp.ErrUserAgentBlocked, resp.RequestID(c))
 			return
 		}
 
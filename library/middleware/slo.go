@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// SLO objectives.
+const (
+	SLOAvailability = "availability"
+	SLOLatency      = "latency"
+)
+
+// SLO results of a request.
+const (
+	sloGood = "good"
+	sloBad  = "bad"
+)
+
+var (
+	// SLO 请求计数，按目标和结果（good/bad）区分
+	sloRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_slo_requests_total",
+			Help: "Total number of HTTP requests counted by the service level objectives, by result",
+		},
+		[]string{"objective", "result"},
+	)
+
+	// SLO 目标值，如 0.999
+	sloObjectiveRatio = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_slo_objective_ratio",
+			Help: "Target ratio of good requests of the service level objectives",
+		},
+		[]string{"objective"},
+	)
+
+	// 延迟 SLO 阈值
+	sloLatencyThreshold = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_slo_latency_threshold_seconds",
+			Help: "Duration under which a request is good for the latency objective",
+		},
+	)
+)
+
+// SLOOptions configures SLOMiddleware.
+type SLOOptions struct {
+	AvailabilityObjective float64       // the target ratio of requests without server error, e.g. 0.999
+	LatencyObjective      float64       // the target ratio of requests faster than LatencyThreshold, e.g. 0.99
+	LatencyThreshold      time.Duration // the duration under which a request is good for the latency objective
+	SkipPaths             []string      // the routes not counted, e.g. the health checks
+}
+
+// SLOMiddleware returns a middleware counting the good and bad requests of
+// the service level objectives in http_slo_requests_total.
+//
+// A request is good for the availability objective unless it fails with a
+// server error (5xx), and good for the latency objective if it is handled
+// within LatencyThreshold; requests failing with a server error are only
+// counted by the availability objective. Requests matching no route are
+// not counted. The objectives are exported in http_slo_objective_ratio, so
+// that the error budget burn rate can be computed by the alerting rules,
+// e.g. for the availability objective:
+//
+//	sum(rate(http_slo_requests_total{objective="availability",result="bad"}[1h]))
+//	  / sum(rate(http_slo_requests_total{objective="availability"}[1h]))
+//	  / (1 - on() http_slo_objective_ratio{objective="availability"})
+//
+// Parameters:
+//   - opts: The SLO options.
+//
+// Returns:
+//   - gin.HandlerFunc: The Gin middleware function for the SLO metrics.
+func SLOMiddleware(opts SLOOptions) gin.HandlerFunc {
+	sloObjectiveRatio.WithLabelValues(SLOAvailability).Set(opts.AvailabilityObjective)
+	sloObjectiveRatio.WithLabelValues(SLOLatency).Set(opts.LatencyObjective)
+	sloLatencyThreshold.Set(opts.LatencyThreshold.Seconds())
+
+	skip := make(map[string]struct{}, len(opts.SkipPaths))
+	for _, path := range opts.SkipPaths {
+		skip[path] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if _, ok := skip[route]; ok || route == "" {
+			return
+		}
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			sloRequestsTotal.WithLabelValues(SLOAvailability, sloBad).Inc()
+			return
+		}
+		sloRequestsTotal.WithLabelValues(SLOAvailability, sloGood).Inc()
+
+		if opts.LatencyThreshold > 0 {
+			result := sloGood
+			if time.Since(start) > opts.LatencyThreshold {
+				result = sloBad
+			}
+			sloRequestsTotal.WithLabelValues(SLOLatency, result).Inc()
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// TimeoutMiddleware returns a middleware that enforces a deadline on the
+// remaining handlers of the chain.
+//
+// The deadline context is derived from c.Request.Context() and replaces the
+// request context, so handlers and the clients they call (Redis, etcd,
+// Elasticsearch, ...) are canceled when the deadline expires. The handlers run
+// in a separate goroutine and write into a buffer; if they do not finish in
+// time, a 504 Gateway Timeout response is sent and anything they write
+// afterward is discarded.
+//
+// The middleware can be used globally or per route. When nested, the shorter
+// deadline wins because each deadline is derived from the parent context.
+// The middleware recording the status of the response, e.g. the metrics and
+// the circuit breakers, must be mounted before it to record the 504.
+//
+// Parameters:
+//   - timeout: The maximum duration allowed for the remaining handlers.
+//     A non-positive value disables the middleware.
+//
+// Returns:
+//   - gin.HandlerFunc: The Gin middleware function for request timeouts.
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		// Resolve the request ID before the handlers run concurrently
+		reqID := resp.RequestID(c)
+
+		// Derive the deadline from the request context so that client
+		// cancellation is still honoured.
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		// Buffer the response until the handlers finish.
+		tw := newTimeoutWriter(c.Writer)
+		c.Writer = tw
+
+		done := make(chan struct{})
+		panicChan := make(chan any, 1)
+
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+				close(done)
+			}()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+			select {
+			case p := <-panicChan:
+				// Restore the writer so that the recovery middleware can respond
+				c.Writer = tw.ResponseWriter
+				panic(p)
+			default:
+			}
+
+			// Copy the buffered response to the client
+			if err := tw.flushBuffered(); err != nil && resource.LoggerService != nil {
+				resource.LoggerService.Error("Failed to write response", zap.Error(err))
+			}
+			c.Writer = tw.ResponseWriter
+
+		case <-ctx.Done():
+			// The request was canceled by the client, or the deadline expired
+			timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+			// Discard anything the handlers write from now on
+			if tw.timeout() && timedOut {
+				if err := resp.WriteErrResp(tw.ResponseWriter, http.StatusGatewayTimeout, resp.LocalizeContext(c, resp.ErrGatewayTimeout.MessageKey), reqID); err != nil && resource.LoggerService != nil {
+					resource.LoggerService.Error("Failed to write timeout response", zap.Error(err))
+				}
+				// Send the response now instead of after the handlers return
+				tw.ResponseWriter.Flush()
+			}
+
+			if timedOut {
+				logger.WithContext(ctx, resource.HTTPLogger).Warn("Request timeout",
+					zap.String("method", c.Request.Method),
+					zap.String("path", c.Request.URL.Path),
+					zap.Duration("timeout", timeout),
+				)
+			}
+
+			// Wait for the handlers to return before releasing the context,
+			// gin reuses it for other requests once this middleware returns.
+			<-done
+			select {
+			case p := <-panicChan:
+				logger.WithContext(ctx, resource.HTTPLogger).Error("Panic recovered after request timeout",
+					zap.Any("error", p),
+					zap.String("path", c.Request.URL.Path),
+				)
+			default:
+			}
+			c.Writer = tw.ResponseWriter
+			c.Abort()
+		}
+	}
+}
+
+// timeoutWriter buffers the response of the handlers running under
+// TimeoutMiddleware.
+//
+// It is safe for concurrent use by the handler goroutine and the middleware.
+type timeoutWriter struct {
+	gin.ResponseWriter
+
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	written  bool
+	timedOut bool
+}
+
+// newTimeoutWriter creates a timeoutWriter wrapping w.
+//
+// The headers already set on w (e.g. X-Request-ID, CORS, security headers)
+// are copied so that handlers can still read and modify them.
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{
+		ResponseWriter: w,
+		header:         w.Header().Clone(),
+		status:         http.StatusOK,
+	}
+}
+
+// Header returns the buffered response headers.
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader records the status code of the response.
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut || w.written {
+		return
+	}
+	if code > 0 {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow marks the header as written.
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.written = true
+}
+
+// Write appends data to the buffered response body.
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.written = true
+	return w.body.Write(data)
+}
+
+// WriteString appends a string to the buffered response body.
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Status returns the buffered status code.
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.status
+}
+
+// Size returns the size of the buffered body, or -1 if nothing was written.
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+// Written reports whether the response has been written.
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.written
+}
+
+// Flush is a no-op, the response is sent once the handlers finish.
+func (w *timeoutWriter) Flush() {}
+
+// Hijack is not supported while the response is buffered.
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, fmt.Errorf("hijack is not supported by the timeout middleware")
+}
+
+// timeout marks the writer as timed out.
+//
+// It reports whether the timeout response may be written, which is the case
+// unless the handlers already completed and flushed their response.
+func (w *timeoutWriter) timeout() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return false
+	}
+	w.timedOut = true
+	return true
+}
+
+// flushBuffered writes the buffered headers, status and body to the
+// underlying writer.
+func (w *timeoutWriter) flushBuffered() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return nil
+	}
+	w.timedOut = true
+
+	dst := w.ResponseWriter.Header()
+	for k := range dst {
+		if _, ok := w.header[k]; !ok {
+			dst.Del(k)
+		}
+	}
+	for k, v := range w.header {
+		dst[k] = v
+	}
+
+	if !w.written {
+		// Nothing was written by the handlers, leave the header unsent so
+		// that middlewares higher in the chain can still respond.
+		if w.status != http.StatusOK {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+		return nil
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	return err
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestTimeoutMiddleware tests that the middleware mounted before the
+// timeout middleware, e.g. the metrics, record the 504 it writes rather than
+// the status the handler writes after the deadline.
+func TestTimeoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		route      string
+		delay      time.Duration
+		wantStatus int
+	}{
+		{"in time", "/timeout/fast", 0, http.StatusOK},
+		{"timed out", "/timeout/slow", 200 * time.Millisecond, http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recorded int
+			router := gin.New()
+			router.Use(
+				func(c *gin.Context) {
+					c.Header("X-Outer", "1")
+					c.Next()
+					recorded = c.Writer.Status()
+				},
+				PrometheusMiddleware(),
+				TimeoutMiddleware(50*time.Millisecond),
+			)
+			router.GET(tt.route, func(c *gin.Context) {
+				time.Sleep(tt.delay)
+				c.String(http.StatusOK, "done")
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.route, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("response status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if recorded != tt.wantStatus {
+				t.Errorf("recorded status = %d, want %d", recorded, tt.wantStatus)
+			}
+			if w.Header().Get("X-Outer") != "1" {
+				t.Error("response misses the header set by the outer middleware")
+			}
+
+			if got := testutil.ToFloat64(httpMetrics.requests.WithLabelValues(http.MethodGet, tt.route, strconv.Itoa(tt.wantStatus))); got != 1 {
+				t.Errorf("http_requests_total{status=%d} = %v, want 1", tt.wantStatus, got)
+			}
+		})
+	}
+}
@@ -13,11 +13,23 @@ import (
 	"github.com/redis/go-redis/v9"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.mongodb.org/mongo-driver/mongo"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+
+	"github.com/xiebingnote/go-gin-project/pkg/audit"
+	"github.com/xiebingnote/go-gin-project/pkg/circuitbreaker"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
+	"github.com/xiebingnote/go-gin-project/pkg/schema"
 )
 
 var (
+	// AuditStore is the audit trail
+	AuditStore *audit.Store
+
+	// CircuitBreakerManager is the circuit breaker manager
+	CircuitBreakerManager *circuitbreaker.Manager
+
 	// ClickHouseClient is the ClickHouse client
 	ClickHouseClient *sql.DB
 
@@ -33,6 +45,24 @@ var (
 	// LoggerService is the logger
 	LoggerService *zap.Logger
 
+	// AccessLogger is the logger of the HTTP access log
+	AccessLogger *zap.Logger
+
+	// KafkaLogger is the logger of the Kafka subsystem
+	KafkaLogger *zap.Logger
+
+	// NsqLogger is the logger of the NSQ subsystem
+	NsqLogger *zap.Logger
+
+	// HTTPLogger is the logger of the HTTP server
+	HTTPLogger *zap.Logger
+
+	// CronLogger is the logger of the cron scheduler
+	CronLogger *zap.Logger
+
+	// AuthLogger is the logger of the authentication subsystem
+	AuthLogger *zap.Logger
+
 	// ManticoreClient is the Manticore client
 	ManticoreClient *manticore.APIClient
 
@@ -53,18 +83,36 @@ var (
 	// RedisClient is the Redis client
 	RedisClient *redis.Client
 
+	// Resilience holds the resilience policies of the dependencies
+	Resilience *resilience.Registry
+
+	// SchemaSerde is the serde of the Kafka and NSQ messages in the schema
+	// registry wire format, nil if the schema registry is disabled
+	SchemaSerde *schema.Serde
+
 	// KafkaProducer is the Kafka producer
 	KafkaProducer sarama.SyncProducer
 
+	// KafkaTransactionalProducer is the Kafka producer of the transactions,
+	// nil if no transaction ID is configured
+	KafkaTransactionalProducer sarama.SyncProducer
+
 	// KafkaConsumer is the Kafka consumer
 	KafkaConsumer sarama.Consumer
 
 	// KafkaConsumerGroup is the Kafka consumer
 	KafkaConsumerGroup sarama.ConsumerGroup
 
+	// KafkaClient is the Kafka client of the admin operations, e.g. the
+	// replay of the dead letters
+	KafkaClient sarama.Client
+
 	// Enforcer is the Casbin enforcer
 	Enforcer *casbin.Enforcer
 
+	// TracerProvider is the OpenTelemetry tracer provider
+	TracerProvider *sdktrace.TracerProvider
+
 	// TDengineClient is the TDengine client
 	TDengineClient *sql.DB
 
@@ -0,0 +1,265 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stable error codes returned in the ErrCode field of the response.
+//
+// Clients should rely on these codes rather than on the message, which is
+// localised and may change.
+const (
+	CodeBadRequest          = "BAD_REQUEST"
+	CodeInvalidParams       = "INVALID_PARAMS"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeTokenMissing        = "TOKEN_MISSING"
+	CodeTokenInvalid        = "TOKEN_INVALID"
+	CodeForbidden           = "FORBIDDEN"
+	CodeIPNotAllowed        = "IP_NOT_ALLOWED"
+	CodeUserAgentBlocked    = "USER_AGENT_BLOCKED"
+	CodeNotFound            = "NOT_FOUND"
+	CodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	CodeConflict            = "CONFLICT"
+	CodeTooManyRequests     = "TOO_MANY_REQUESTS"
+	CodeInternal            = "INTERNAL_ERROR"
+	CodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
+	CodeGatewayTimeout      = "GATEWAY_TIMEOUT"
+	CodeCircuitOpen         = "CIRCUIT_BREAKER_OPEN"
+	CodeCircuitHalfOpen     = "CIRCUIT_BREAKER_HALF_OPEN_LIMIT"
+	CodeOverloaded          = "SERVER_OVERLOADED"
+	CodeUsernameRequired    = "USERNAME_REQUIRED"
+	CodePasswordRequired    = "PASSWORD_REQUIRED"
+	CodeUsernameInvalid     = "USERNAME_INVALID"
+	CodePasswordWeak        = "PASSWORD_WEAK"
+	CodeInvalidCredentials  = "INVALID_CREDENTIALS"
+	CodeUserExists          = "USER_EXISTS"
+	CodeLoginRateLimited    = "LOGIN_RATE_LIMITED"
+	CodeAPIRateLimited      = "API_RATE_LIMITED"
+	CodeDependencyFailure   = "DEPENDENCY_FAILURE"
+	CodeAuthorizationFailed = "AUTHORIZATION_FAILED"
+)
+
+// AppError is an error with a stable code, an HTTP status and a message key
+// resolved against the i18n bundles when the error is rendered.
+//
+// Catalogue entries are shared values, use WithDetails, WithArgs and Wrap to
+// derive a request specific copy instead of modifying them.
+type AppError struct {
+	Code       string // stable error code, e.g. "USERNAME_REQUIRED"
+	HTTPStatus int    // HTTP status code of the response
+	MessageKey string // key of the localised message
+	Args       []any  // arguments for the message template
+	Details    any    // optional details returned to the client
+	Err        error  // underlying cause, logged but never returned to the client
+}
+
+// NewAppError creates a new AppError.
+//
+// Parameters:
+//   - code: The stable error code.
+//   - status: The HTTP status code of the response.
+//   - messageKey: The key of the localised message.
+//
+// Returns:
+//   - *AppError: The new error.
+func NewAppError(code string, status int, messageKey string) *AppError {
+	return &AppError{
+		Code:       code,
+		HTTPStatus: status,
+		MessageKey: messageKey,
+	}
+}
+
+// Error implements the error interface.
+//
+// The message is rendered in the default language and includes the
+// underlying cause, so it is suitable for logs.
+func (e *AppError) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Code, Localize(DefaultLanguage, e.MessageKey, e.Args...))
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying cause.
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the same catalogue entry, so that
+// errors.Is(err, response.ErrUserExists) matches derived copies.
+func (e *AppError) Is(target error) bool {
+	var t *AppError
+	if !errors.As(target, &t) {
+		return false
+	}
+	return t.Code == e.Code && t.MessageKey == e.MessageKey
+}
+
+// WithDetails returns a copy of the error with the given details.
+func (e *AppError) WithDetails(details any) *AppError {
+	cp := *e
+	cp.Details = details
+	return &cp
+}
+
+// WithArgs returns a copy of the error with the given message arguments.
+func (e *AppError) WithArgs(args ...any) *AppError {
+	cp := *e
+	cp.Args = args
+	return &cp
+}
+
+// Wrap returns a copy of the error with the given underlying cause.
+func (e *AppError) Wrap(err error) *AppError {
+	cp := *e
+	cp.Err = err
+	return &cp
+}
+
+// FieldError describes which request field failed validation.
+type FieldError struct {
+	Field string `json:"field"`
+}
+
+// Error catalogue
+var (
+	// Generic errors
+	ErrBadRequest         = NewAppError(CodeBadRequest, http.StatusBadRequest, "error.bad_request")
+	ErrInvalidParams      = NewAppError(CodeInvalidParams, http.StatusBadRequest, "error.invalid_params")
+	ErrUnauthorized       = NewAppError(CodeUnauthorized, http.StatusUnauthorized, "error.unauthorized")
+	ErrForbidden          = NewAppError(CodeForbidden, http.StatusForbidden, "error.forbidden")
+	ErrNotFound           = NewAppError(CodeNotFound, http.StatusNotFound, "error.not_found")
+	ErrMethodNotAllowed   = NewAppError(CodeMethodNotAllowed, http.StatusMethodNotAllowed, "error.method_not_allowed")
+	ErrConflict           = NewAppError(CodeConflict, http.StatusConflict, "error.conflict")
+	ErrTooManyRequests    = NewAppError(CodeTooManyRequests, http.StatusTooManyRequests, "error.too_many_requests")
+	ErrInternal           = NewAppError(CodeInternal, http.StatusInternalServerError, "error.internal")
+	ErrServiceUnavailable = NewAppError(CodeServiceUnavailable, http.StatusServiceUnavailable, "error.service_unavailable")
+	ErrGatewayTimeout     = NewAppError(CodeGatewayTimeout, http.StatusGatewayTimeout, "error.gateway_timeout")
+	ErrDependencyFailure  = NewAppError(CodeDependencyFailure, http.StatusInternalServerError, "error.dependency_failure")
+
+	// Security errors
+	ErrTokenMissing        = NewAppError(CodeTokenMissing, http.StatusUnauthorized, "auth.token_missing")
+	ErrTokenInvalid        = NewAppError(CodeTokenInvalid, http.StatusUnauthorized, "auth.token_invalid")
+	ErrAuthorizationFailed = NewAppError(CodeAuthorizationFailed, http.StatusInternalServerError, "auth.authorization_failed")
+	ErrIPNotAllowed        = NewAppError(CodeIPNotAllowed, http.StatusForbidden, "security.ip_not_allowed")
+	ErrUserAgentBlocked    = NewAppError(CodeUserAgentBlocked, http.StatusForbidden, "security.user_agent_blocked")
+	ErrLoginRateLimited    = NewAppError(CodeLoginRateLimited, http.StatusTooManyRequests, "limit.login")
+	ErrAPIRateLimited      = NewAppError(CodeAPIRateLimited, http.StatusTooManyRequests, "limit.api")
+	ErrCircuitOpen         = NewAppError(CodeCircuitOpen, http.StatusServiceUnavailable, "breaker.open")
+	ErrCircuitHalfOpen     = NewAppError(CodeCircuitHalfOpen, http.StatusTooManyRequests, "breaker.half_open")
+	ErrOverloaded          = NewAppError(CodeOverloaded, http.StatusServiceUnavailable, "limit.overloaded")
+
+	// Account errors
+	ErrUsernameRequired   = NewAppError(CodeUsernameRequired, http.StatusBadRequest, "auth.username_required")
+	ErrPasswordRequired   = NewAppError(CodePasswordRequired, http.StatusBadRequest, "auth.password_required")
+	ErrUsernameLength     = NewAppError(CodeUsernameInvalid, http.StatusBadRequest, "auth.username_length")
+	ErrUsernameFormat     = NewAppError(CodeUsernameInvalid, http.StatusBadRequest, "auth.username_format")
+	ErrPasswordLength     = NewAppError(CodePasswordWeak, http.StatusBadRequest, "auth.password_length")
+	ErrPasswordTooLong    = NewAppError(CodePasswordWeak, http.StatusBadRequest, "auth.password_too_long")
+	ErrPasswordUppercase  = NewAppError(CodePasswordWeak, http.StatusBadRequest, "auth.password_uppercase")
+	ErrPasswordLowercase  = NewAppError(CodePasswordWeak, http.StatusBadRequest, "auth.password_lowercase")
+	ErrPasswordDigit      = NewAppError(CodePasswordWeak, http.StatusBadRequest, "auth.password_digit")
+	ErrPasswordSpecial    = NewAppError(CodePasswordWeak, http.StatusBadRequest, "auth.password_special")
+	ErrInvalidCredentials = NewAppError(CodeInvalidCredentials, http.StatusUnauthorized, "auth.invalid_credentials")
+	ErrUserExists         = NewAppError(CodeUserExists, http.StatusConflict, "auth.user_exists")
+)
+
+// statusErrors maps HTTP status codes to the generic catalogue entry used by
+// NewErrResp.
+var statusErrors = map[int]*AppError{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusMethodNotAllowed:    ErrMethodNotAllowed,
+	http.StatusConflict:            ErrConflict,
+	http.StatusTooManyRequests:     ErrTooManyRequests,
+	http.StatusInternalServerError: ErrInternal,
+	http.StatusServiceUnavailable:  ErrServiceUnavailable,
+	http.StatusGatewayTimeout:      ErrGatewayTimeout,
+}
+
+// CodeForStatus returns the generic error code for an HTTP status code.
+func CodeForStatus(status int) string {
+	if appErr, ok := statusErrors[status]; ok {
+		return appErr.Code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// AsAppError converts any error into an AppError.
+//
+// Errors that are not AppErrors are wrapped into ErrInternal, so their
+// message never leaks to the client.
+func AsAppError(err error) *AppError {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// AppErrorRestResp returns a *Response for the given error, with the message
+// localised in lang.
+//
+// Parameters:
+//   - err: error, the error to render, converted with AsAppError.
+//   - lang: string, the language of the message.
+//   - reqID: string, the unique identifier for the request.
+//
+// Returns: *Response, a pointer to a Response struct
+func AppErrorRestResp(err error, lang string, reqID string) *Response {
+	appErr := AsAppError(err)
+	return &Response{
+		Code:      appErr.HTTPStatus,
+		Success:   false,
+		ErrCode:   appErr.Code,
+		ErrMsg:    Localize(lang, appErr.MessageKey, appErr.Args...),
+		Details:   appErr.Details,
+		RequestID: reqID,
+		Data:      nil,
+	}
+}
+
+// NewAppErrResp sends an error response rendered from an AppError.
+//
+// The message is localised according to the Accept-Language header of the
+// request and the Content-Language header is set accordingly. Errors that
+// are not AppErrors are rendered as ErrInternal.
+//
+// Parameters:
+//   - c: *gin.Context, the Gin context that carries request-scoped values.
+//   - err: error, the error to render.
+//   - reqID: string, the unique identifier for the request.
+func NewAppErrResp(c *gin.Context, err error, reqID string) {
+	lang := LanguageFromContext(c)
+	body := AppErrorRestResp(err, lang, reqID)
+
+	c.Header("Content-Language", lang)
+	c.JSON(body.Code, body)
+}
+
+// AbortWithAppError sends an error response rendered from an AppError and
+// aborts the handler chain.
+//
+// Parameters:
+//   - c: *gin.Context, the Gin context that carries request-scoped values.
+//   - err: error, the error to render.
+//   - reqID: string, the unique identifier for the request.
+func AbortWithAppError(c *gin.Context, err error, reqID string) {
+	NewAppErrResp(c, err, reqID)
+	c.Abort()
+}
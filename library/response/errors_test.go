@@ -0,0 +1,129 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestAsAppError tests that AsAppError finds an AppError in the chain of an
+// error, and wraps any other error into ErrInternal keeping it as the cause.
+func TestAsAppError(t *testing.T) {
+	cause := errors.New("connection refused")
+	notFound := ErrNotFound.WithDetails(FieldError{Field: "id"}).Wrap(cause)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   string
+		wantStatus int
+		wantCause  error
+	}{
+		{"app error", ErrUserExists, CodeUserExists, http.StatusConflict, nil},
+		{"derived app error", notFound, CodeNotFound, http.StatusNotFound, cause},
+		{"wrapped app error", fmt.Errorf("get user: %w", notFound), CodeNotFound, http.StatusNotFound, cause},
+		{"plain error", cause, CodeInternal, http.StatusInternalServerError, cause},
+		{"wrapped plain error", fmt.Errorf("query: %w", cause), CodeInternal, http.StatusInternalServerError, cause},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := AsAppError(tt.err)
+			if appErr == nil {
+				t.Fatal("AsAppError() = nil")
+			}
+			if appErr.Code != tt.wantCode || appErr.HTTPStatus != tt.wantStatus {
+				t.Errorf("AsAppError() = %s/%d, want %s/%d", appErr.Code, appErr.HTTPStatus, tt.wantCode, tt.wantStatus)
+			}
+			if tt.wantCause != nil && !errors.Is(appErr, tt.wantCause) {
+				t.Errorf("errors.Is(AsAppError(), cause) = false, want true")
+			}
+			if tt.wantCause == nil && errors.Unwrap(appErr) != nil {
+				t.Errorf("errors.Unwrap(AsAppError()) = %v, want nil", errors.Unwrap(appErr))
+			}
+		})
+	}
+
+	if AsAppError(nil) != nil {
+		t.Errorf("AsAppError(nil) = %v, want nil", AsAppError(nil))
+	}
+}
+
+// TestAppErrorIs tests that the copies derived from a catalogue entry match
+// it with errors.Is and leave it untouched.
+func TestAppErrorIs(t *testing.T) {
+	cause := errors.New("duplicate key")
+	derived := ErrUserExists.WithDetails(FieldError{Field: "username"}).WithArgs("bob").Wrap(cause)
+
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{"same entry", ErrUserExists, ErrUserExists, true},
+		{"derived copy", derived, ErrUserExists, true},
+		{"wrapped derived copy", fmt.Errorf("register: %w", derived), ErrUserExists, true},
+		{"cause", derived, cause, true},
+		{"other entry", derived, ErrConflict, false},
+		{"same code other key", ErrUsernameLength, ErrUsernameFormat, false},
+		{"plain target", derived, errors.New("duplicate key"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if ErrUserExists.Err != nil || ErrUserExists.Details != nil || ErrUserExists.Args != nil {
+		t.Errorf("catalogue entry modified: %+v", ErrUserExists)
+	}
+}
+
+// TestAppErrorError tests that the message of an AppError, used in the logs,
+// contains the code, the message in the default language and the cause.
+func TestAppErrorError(t *testing.T) {
+	err := ErrInternal.Wrap(errors.New("connection refused"))
+	want := CodeInternal + ": " + Localize(DefaultLanguage, "error.internal") + ": connection refused"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+// TestAppErrorRestResp tests the response rendered from an error, which
+// never contains the message of the cause.
+func TestAppErrorRestResp(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		lang        string
+		wantCode    int
+		wantErrCode string
+		wantMsg     string
+		wantDetails any
+	}{
+		{"app error", ErrNotFound.WithDetails(FieldError{Field: "id"}), LangEnUS, http.StatusNotFound, CodeNotFound, "Not found", FieldError{Field: "id"}},
+		{"localized", ErrNotFound, LangZhCN, http.StatusNotFound, CodeNotFound, "资源不存在", nil},
+		{"arguments", ErrUsernameLength.WithArgs(3, 20), LangEnUS, http.StatusBadRequest, CodeUsernameInvalid, "Username must be between 3 and 20 characters", nil},
+		{"plain error", errors.New("secret dsn"), LangEnUS, http.StatusInternalServerError, CodeInternal, "Internal server error", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := AppErrorRestResp(tt.err, tt.lang, "req-1")
+			if body.Code != tt.wantCode || body.ErrCode != tt.wantErrCode || body.Success || body.RequestID != "req-1" {
+				t.Errorf("AppErrorRestResp() = %+v, want %d %s", body, tt.wantCode, tt.wantErrCode)
+			}
+			if body.ErrMsg != tt.wantMsg {
+				t.Errorf("message = %q, want %q", body.ErrMsg, tt.wantMsg)
+			}
+			if body.Details != tt.wantDetails {
+				t.Errorf("details = %v, want %v", body.Details, tt.wantDetails)
+			}
+		})
+	}
+}
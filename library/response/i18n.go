@@ -0,0 +1,237 @@
+package response
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Supported languages
+const (
+	LangZhCN = "zh-CN"
+	LangEnUS = "en-US"
+
+	// DefaultLanguage is used when the client does not send Accept-Language
+	// or none of the requested languages is supported.
+	DefaultLanguage = LangZhCN
+)
+
+var (
+	// bundlesMu protects bundles.
+	bundlesMu sync.RWMutex
+
+	// bundles holds the message templates keyed by language and message key.
+	bundles = map[string]map[string]string{
+		LangZhCN: {
+			"error.bad_request":           "请求格式错误",
+			"error.invalid_params":        "请求参数错误",
+			"error.unauthorized":          "未授权",
+			"error.forbidden":             "禁止访问",
+			"error.not_found":             "资源不存在",
+			"error.method_not_allowed":    "请求方法不允许",
+			"error.conflict":              "资源冲突",
+			"error.too_many_requests":     "请求过于频繁",
+			"error.internal":              "服务器内部错误",
+			"error.service_unavailable":   "服务暂时不可用",
+			"error.gateway_timeout":       "请求超时",
+			"error.dependency_failure":    "依赖服务异常",
+			"auth.token_missing":          "缺少 Authorization 请求头",
+			"auth.token_invalid":          "无效的令牌",
+			"auth.authorization_failed":   "权限校验失败",
+			"auth.username_required":      "用户名不能为空",
+			"auth.password_required":      "密码不能为空",
+			"auth.username_length":        "用户名长度必须在%d-%d个字符之间",
+			"auth.username_format":        "用户名只能包含字母、数字和下划线",
+			"auth.password_length":        "密码长度至少%d个字符",
+			"auth.password_too_long":      "密码长度不能超过%d个字符",
+			"auth.password_uppercase":     "密码必须包含至少一个大写字母",
+			"auth.password_lowercase":     "密码必须包含至少一个小写字母",
+			"auth.password_digit":         "密码必须包含至少一个数字",
+			"auth.password_special":       "密码必须包含至少一个特殊字符",
+			"auth.invalid_credentials":    "用户名或密码错误",
+			"auth.user_exists":            "用户名已存在",
+			"auth.register_success":       "用户创建成功",
+			"auth.login_success":          "登录成功",
+			"security.ip_not_allowed":     "IP 不在白名单中",
+			"security.user_agent_blocked": "拒绝访问",
+			"limit.login":                 "登录请求过于频繁，请稍后再试",
+			"limit.api":                   "API 请求过于频繁，请稍后再试",
+			"breaker.open":                "服务暂时不可用，熔断器已打开",
+			"breaker.half_open":           "服务恢复中，请求过多",
+			"limit.overloaded":            "服务繁忙，请稍后再试",
+		},
+		LangEnUS: {
+			"error.bad_request":           "Bad request",
+			"error.invalid_params":        "Invalid parameters",
+			"error.unauthorized":          "Unauthorized",
+			"error.forbidden":             "Forbidden",
+			"error.not_found":             "Not found",
+			"error.method_not_allowed":    "Method not allowed",
+			"error.conflict":              "Conflict",
+			"error.too_many_requests":     "Too many requests",
+			"error.internal":              "Internal server error",
+			"error.service_unavailable":   "Service temporarily unavailable",
+			"error.gateway_timeout":       "Request timeout",
+			"error.dependency_failure":    "Dependency failure",
+			"auth.token_missing":          "Authorization header is required",
+			"auth.token_invalid":          "Invalid token",
+			"auth.authorization_failed":   "Authorization check failed",
+			"auth.username_required":      "Username is required",
+			"auth.password_required":      "Password is required",
+			"auth.username_length":        "Username must be between %d and %d characters",
+			"auth.username_format":        "Username may only contain letters, digits and underscores",
+			"auth.password_length":        "Password must be at least %d characters",
+			"auth.password_too_long":      "Password must not exceed %d characters",
+			"auth.password_uppercase":     "Password must contain at least one uppercase letter",
+			"auth.password_lowercase":     "Password must contain at least one lowercase letter",
+			"auth.password_digit":         "Password must contain at least one digit",
+			"auth.password_special":       "Password must contain at least one special character",
+			"auth.invalid_credentials":    "Invalid username or password",
+			"auth.user_exists":            "Username already exists",
+			"auth.register_success":       "User created",
+			"auth.login_success":          "Login successful",
+			"security.ip_not_allowed":     "IP not allowed",
+			"security.user_agent_blocked": "Access denied",
+			"limit.login":                 "Too many login requests, rate limit exceeded",
+			"limit.api":                   "Too many API requests, rate limit exceeded",
+			"breaker.open":                "Service temporarily unavailable, circuit breaker is open",
+			"breaker.half_open":           "Circuit breaker is in half-open state with too many requests",
+			"limit.overloaded":            "Server is overloaded, please retry later",
+		},
+	}
+)
+
+// RegisterMessages adds or overrides message templates for a language.
+//
+// It allows other packages to extend the bundles with their own keys.
+//
+// Parameters:
+//   - lang: The language tag, e.g. "zh-CN".
+//   - messages: The message templates keyed by message key.
+func RegisterMessages(lang string, messages map[string]string) {
+	bundlesMu.Lock()
+	defer bundlesMu.Unlock()
+
+	bundle, ok := bundles[lang]
+	if !ok {
+		bundle = make(map[string]string, len(messages))
+		bundles[lang] = bundle
+	}
+	for key, msg := range messages {
+		bundle[key] = msg
+	}
+}
+
+// Localize returns the message for key in the given language.
+//
+// It falls back to the default language, and to the key itself when no
+// template exists. The arguments are applied with fmt.Sprintf.
+//
+// Parameters:
+//   - lang: The language tag.
+//   - key: The message key.
+//   - args: Optional template arguments.
+//
+// Returns:
+//   - string: The localised message.
+func Localize(lang, key string, args ...any) string {
+	bundlesMu.RLock()
+	msg, ok := bundles[lang][key]
+	if !ok {
+		msg, ok = bundles[DefaultLanguage][key]
+	}
+	bundlesMu.RUnlock()
+
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// LocalizeContext returns the message for key in the language negotiated
+// from the request.
+func LocalizeContext(c *gin.Context, key string, args ...any) string {
+	return Localize(LanguageFromContext(c), key, args...)
+}
+
+// LanguageFromContext negotiates the response language from the
+// Accept-Language header of the request.
+func LanguageFromContext(c *gin.Context) string {
+	if c == nil || c.Request == nil {
+		return DefaultLanguage
+	}
+	return NegotiateLanguage(c.GetHeader("Accept-Language"))
+}
+
+// NegotiateLanguage returns the supported language that best matches an
+// Accept-Language header value, e.g. "en-US,en;q=0.9,zh;q=0.8".
+//
+// Languages are matched exactly first, then by primary subtag ("en" matches
+// "en-US"). The default language is returned when nothing matches.
+func NegotiateLanguage(header string) string {
+	if header == "" {
+		return DefaultLanguage
+	}
+
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if v, ok := strings.CutPrefix(param, "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+
+	// Highest quality first, keeping the header order for equal values
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	bundlesMu.RLock()
+	defer bundlesMu.RUnlock()
+
+	for _, cand := range candidates {
+		if cand.tag == "*" {
+			return DefaultLanguage
+		}
+		for lang := range bundles {
+			if strings.EqualFold(lang, cand.tag) {
+				return lang
+			}
+		}
+		primary, _, _ := strings.Cut(cand.tag, "-")
+		for _, lang := range []string{LangZhCN, LangEnUS} {
+			langPrimary, _, _ := strings.Cut(lang, "-")
+			if strings.EqualFold(langPrimary, primary) {
+				return lang
+			}
+		}
+	}
+
+	return DefaultLanguage
+}
@@ -0,0 +1,95 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestNegotiateLanguage tests the negotiation of the response language from
+// the Accept-Language header, with quality values and fallbacks.
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"empty", "", DefaultLanguage},
+		{"exact", "en-US", LangEnUS},
+		{"case insensitive", "EN-us", LangEnUS},
+		{"primary subtag", "en", LangEnUS},
+		{"other region", "en-GB", LangEnUS},
+		{"chinese region", "zh-TW", LangZhCN},
+		{"header order", "en-US,zh-CN", LangEnUS},
+		{"highest quality", "en;q=0.5,zh;q=0.9", LangZhCN},
+		{"default quality", "zh;q=0.8,en", LangEnUS},
+		{"equal quality keeps order", "zh;q=0.7,en;q=0.7", LangZhCN},
+		{"unsupported skipped", "fr-FR,de;q=0.9,en;q=0.1", LangEnUS},
+		{"zero quality excluded", "en;q=0,zh;q=0.1", LangZhCN},
+		{"only zero quality", "en;q=0", DefaultLanguage},
+		{"invalid quality", "en;q=abc", LangEnUS},
+		{"spaces", " en-US ; q=0.9 , zh ; q=0.1", LangEnUS},
+		{"wildcard", "*", DefaultLanguage},
+		{"wildcard before match", "*;q=0.9,en;q=0.5", DefaultLanguage},
+		{"unsupported", "fr-FR,de", DefaultLanguage},
+		{"empty parts", ",,en", LangEnUS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NegotiateLanguage(tt.header); got != tt.want {
+				t.Errorf("NegotiateLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLocalize tests the fallback of the messages to the default language
+// and to the key, and the application of the template arguments.
+func TestLocalize(t *testing.T) {
+	RegisterMessages(LangZhCN, map[string]string{"test.zh_only": "仅中文"})
+
+	tests := []struct {
+		name string
+		lang string
+		key  string
+		args []any
+		want string
+	}{
+		{"english", LangEnUS, "error.not_found", nil, "Not found"},
+		{"chinese", LangZhCN, "error.not_found", nil, "资源不存在"},
+		{"arguments", LangEnUS, "auth.username_length", []any{3, 20}, "Username must be between 3 and 20 characters"},
+		{"unknown language", "fr-FR", "error.not_found", nil, "资源不存在"},
+		{"default language fallback", LangEnUS, "test.zh_only", nil, "仅中文"},
+		{"unknown key", LangEnUS, "test.unknown", nil, "test.unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Localize(tt.lang, tt.key, tt.args...); got != tt.want {
+				t.Errorf("Localize(%q, %q) = %q, want %q", tt.lang, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLanguageFromContext tests that the language is negotiated from the
+// request of the context, and that the default language is used without one.
+func TestLanguageFromContext(t *testing.T) {
+	if got := LanguageFromContext(nil); got != DefaultLanguage {
+		t.Errorf("LanguageFromContext(nil) = %q, want %q", got, DefaultLanguage)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if got := LanguageFromContext(c); got != DefaultLanguage {
+		t.Errorf("LanguageFromContext() without request = %q, want %q", got, DefaultLanguage)
+	}
+
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("Accept-Language", "en;q=0.8,fr")
+	if got := LanguageFromContext(c); got != LangEnUS {
+		t.Errorf("LanguageFromContext() = %q, want %q", got, LangEnUS)
+	}
+}
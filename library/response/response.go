@@ -1,7 +1,12 @@
 package response
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"strconv"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,11 +25,44 @@ const (
 type Response struct {
 	Code      int    `json:"code" binding:"required"`
 	Success   bool   `json:"success" binding:"required"`
+	ErrCode   string `json:"errorCode,omitempty"`
 	ErrMsg    string `json:"message" binding:"required"`
+	Details   any    `json:"details,omitempty"`
 	RequestID string `json:"requestId" binding:"required"`
 	Data      any    `json:"result" binding:"required"`
 }
 
+// RequestID returns the request ID of the current request.
+//
+// It is the ID set by the request ID middleware, which is also returned in
+// the X-Request-ID header and attached to logs and downstream calls. If the
+// middleware did not run (e.g. on the admin server), the ID is taken from the
+// request context, or a new one is generated and stored so that subsequent
+// calls for the same request return the same value.
+//
+// Parameters:
+//   - c: *gin.Context, the Gin context that carries request-scoped values.
+//
+// Returns:
+//   - string: The request ID.
+func RequestID(c *gin.Context) string {
+	if requestID := c.GetString(common.RequestIDKey); requestID != "" {
+		return requestID
+	}
+
+	var ctx context.Context = context.Background()
+	if c.Request != nil {
+		ctx = c.Request.Context()
+	}
+	ctx, requestID := common.EnsureRequestID(ctx)
+
+	c.Set(common.RequestIDKey, requestID)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(ctx)
+	}
+	return requestID
+}
+
 // NewOKResp sends a successful HTTP 200 response to the client.
 //
 // It sets the Content-Type header of the response to "application/json; charset=UTF-8"
@@ -65,7 +103,8 @@ func OKRestResp(data any, reqID string) *Response {
 // It sets the Content-Type header of the response to "application/json; charset=UTF-8"
 // and sets the HTTP status code of the response to the provided statusCode.
 // The response body is a JSON object with the ErrMsg field set to errMsg, and the
-// RequestID set to reqID.
+// RequestID set to reqID. The ErrCode field is the generic code for statusCode;
+// prefer NewAppErrResp to return a specific, localised error.
 //
 // Parameters:
 //   - c: *gin.Context, the Gin context that carries request-scoped values.
@@ -78,7 +117,8 @@ func NewErrResp(c *gin.Context, statusCode int, errMsg string, reqID string) {
 
 // ErrorRestResp returns a *Response with code and success false.
 //
-// The returned *Response has ErrMsg set to errMsg, and RequestID set to reqID.
+// The returned *Response has ErrMsg set to errMsg, ErrCode set to the generic
+// code for the status, and RequestID set to reqID.
 // The Data field is set to nil.
 //
 // Useful for returning an error response to a request.
@@ -95,8 +135,37 @@ func ErrorRestResp(code int, errMsg string, reqID string) *Response {
 	return &Response{
 		Code:      code,
 		Success:   false,
+		ErrCode:   CodeForStatus(code),
 		ErrMsg:    errMsg,
 		RequestID: reqID,
 		Data:      nil,
 	}
 }
+
+// WriteErrResp writes an error response directly to an http.ResponseWriter.
+//
+// It produces the same JSON body as NewErrResp and is meant for code that
+// must answer a request while the gin.Context is still owned by another
+// goroutine, such as the timeout middleware responding while the handler
+// is still running.
+//
+// Parameters:
+//   - w: http.ResponseWriter, the writer to send the response to.
+//   - statusCode: int, the HTTP status code to set on the response.
+//   - errMsg: string, the error message to include in the response body.
+//   - reqID: string, the unique identifier for the request.
+//
+// Returns:
+//   - error: An error if the response could not be encoded or written.
+func WriteErrResp(w http.ResponseWriter, statusCode int, errMsg string, reqID string) error {
+	body, err := json.Marshal(ErrorRestResp(statusCode, errMsg, reqID))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(statusCode)
+	_, err = w.Write(body)
+	return err
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"runtime"
 	"runtime/debug"
 	"time"
@@ -12,6 +14,7 @@ import (
 	"github.com/xiebingnote/go-gin-project/bootstrap"
 	"github.com/xiebingnote/go-gin-project/library/middleware"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/buildinfo"
 	"github.com/xiebingnote/go-gin-project/pkg/shutdown"
 	"github.com/xiebingnote/go-gin-project/servers"
 
@@ -66,17 +69,25 @@ func (e *StartupError) Unwrap() error {
 // components, starting the servers, starting background tasks, logging startup
 // metrics, and setting up graceful shutdown to handle termination signals.
 //
-// The main function performs the following tasks:
+// With the --version flag, it prints the build information and exits.
+// Otherwise the main function performs the following tasks:
 //  1. Initializes all components using the bootstrap package.
 //  2. Starts the main and admin servers and records startup metrics.
-//  3. Starts background tasks such as memory monitoring and uptime updates.
+//  3. Starts background tasks such as memory monitoring.
 //  4. Logs the time taken to complete startup and the addresses of the main and
 //     admin servers.
 //  5. Sets up graceful shutdown to handle termination signals.
 func main() {
-	// Record the application start time for metrics
+	// Print the build information if requested
+	showVersion := flag.Bool("version", false, "print the build information and exit")
+	flag.Parse()
+	if *showVersion {
+		fmt.Println(buildinfo.Get())
+		os.Exit(0)
+	}
+
+	// Record the application start time for the startup duration
 	startTime := time.Now()
-	middleware.AppStartTime.WithLabelValues("1.0.0").Set(float64(startTime.Unix()))
 
 	// Handle panics gracefully and log them
 	defer func() {
@@ -95,13 +106,16 @@ func main() {
 		resource.LoggerService.Fatal("Failed to start serverPair", zap.Error(err))
 	}
 
-	// 3. Start background tasks such as memory monitoring and uptime updates
+	// 3. Start background tasks such as memory monitoring
 	startBackgroundTasks()
 
 	// 4. Log the time taken to complete startup
 	startupDuration := time.Since(startTime)
 	middleware.ServerStartupDuration.Observe(startupDuration.Seconds())
+	info := buildinfo.Get()
 	resource.LoggerService.Info("✅ Application started successfully",
+		zap.String("version", info.Version),
+		zap.String("git_commit", info.GitCommit),
 		zap.Duration("startup_duration", startupDuration),
 		zap.String("main_server", serverPair.Main.Addr),
 		zap.String("admin_server", serverPair.Admin.Addr),
@@ -141,8 +155,8 @@ func startServersWithMetrics() (*ServerPair, error) {
 
 // startBackgroundTasks starts the background tasks.
 //
-// This function starts the background tasks such as memory monitoring
-// and uptime updating.
+// This function starts the background tasks such as memory monitoring.
+// The uptime is computed on each scrape by buildinfo.Collector.
 func startBackgroundTasks() {
 	// Start memory monitoring
 	//
@@ -150,11 +164,6 @@ func startBackgroundTasks() {
 	// minutes and triggers a manual GC if the memory usage exceeds
 	// 500MB.
 	startMemoryMonitor()
-
-	// Start updating the uptime
-	//
-	//  updater logs the current uptime every 30 seconds.
-	startUptimeUpdater()
 }
 
 // startMemoryMonitor starts monitoring memory usage and logs memory statistics
@@ -191,23 +200,6 @@ func startMemoryMonitor() {
 	}()
 }
 
-// startUptimeUpdater starts the uptime updater goroutine.
-//
-// The uptime updater logs the current uptime every 30 seconds.
-func startUptimeUpdater() {
-	startTime := time.Now()
-	ticker := time.NewTicker(30 * time.Second)
-	go func() {
-		defer ticker.Stop() // Ensure ticker is stopped when the goroutine exits
-
-		// Continuously update the uptime every 30 seconds
-		for range ticker.C {
-			uptime := time.Since(startTime).Seconds()
-			middleware.AppUptime.WithLabelValues("1.0.0").Set(uptime)
-		}
-	}()
-}
-
 // setupGracefulShutdown sets up the shutdown hook to handle termination signals.
 //
 // The shutdown hook is used to perform the following tasks in order:
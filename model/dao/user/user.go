@@ -1,8 +1,11 @@
 package user
 
 import (
+	"context"
+
 	"github.com/xiebingnote/go-gin-project/library/resource"
 	"github.com/xiebingnote/go-gin-project/model/types"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
 
 	"gorm.io/gorm"
 )
@@ -37,12 +40,13 @@ type User interface {
 	// and returns the username if found.
 	//
 	// Parameters:
+	//   - ctx: The context of the request, canceling the query.
 	//   - id: The ID of the user whose username is to be retrieved.
 	//
 	// Returns:
 	//   - A string containing the username associated with the given ID.
 	//   - An error if the retrieval fails or the user is not found.
-	GetUserNameByID(id string) (string, error)
+	GetUserNameByID(ctx context.Context, id string) (string, error)
 }
 
 // CreateTb creates the table in the database.
@@ -66,24 +70,30 @@ func (c *ClientUser) CreateTb() error {
 // and returns the username if found.
 //
 // Parameters:
+//   - ctx: The context of the request, canceling the query.
 //   - id: The ID of the user whose username is to be retrieved.
 //
 // Returns:
 //   - A string containing the username associated with the given ID.
 //   - An error if the retrieval fails or the user is not found.
-func (c *ClientUser) GetUserNameByID(id string) (string, error) {
+func (c *ClientUser) GetUserNameByID(ctx context.Context, id string) (string, error) {
 	// Retrieve the user information from the database
 	// The `Find` method is used to query the "tb_user" table and retrieve the
 	// user information associated with the given ID.
 	// The `Select` method is used to specify that only the "username" column
 	// should be retrieved.
 	// The `Find` method returns an error if the query fails or the user is not found.
-	var info types.TbUser
-	err := c.db.Table("tb_user").
-		Where("id = ?", id).
-		Select("username").
-		Find(&info).
-		Error
+	// The query is run under the MySQL policy of resource.Resilience, with
+	// the context of the caller so that its deadline and cancellation apply.
+	info, err := resilience.Do(ctx, resource.Resilience.Get("mysql"), func(ctx context.Context) (types.TbUser, error) {
+		var info types.TbUser
+		err := c.db.WithContext(ctx).Table("tb_user").
+			Where("id = ?", id).
+			Select("username").
+			Find(&info).
+			Error
+		return info, err
+	})
 
 	// Return the username if the user is found.
 	// If the user is not found, return an empty string.
@@ -63,7 +63,7 @@ func TestCreateDb(t *testing.T) {
 // associated with the given ID. If the retrieval fails, the test will log an error.
 func TestGetUserNameByID(t *testing.T) {
 	id := "1"
-	name, err := userClient.GetUserNameByID(id)
+	name, err := userClient.GetUserNameByID(context.Background(), id)
 	if err != nil {
 		// Log an error if the retrieval fails
 		t.Error(err)
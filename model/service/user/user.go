@@ -1,6 +1,8 @@
 package user
 
 import (
+	"context"
+
 	"github.com/xiebingnote/go-gin-project/model/dao/user"
 )
 
@@ -21,6 +23,6 @@ func CreateTb() error {
 // It calls the GetUserNameByID method on the userClient to retrieve the username
 // associated with the given ID. If the retrieval fails or the user is not found,
 // it returns an empty string and the error.
-func GetUserNameByID(id string) (string, error) {
-	return clientUser.GetUserNameByID(id)
+func GetUserNameByID(ctx context.Context, id string) (string, error) {
+	return clientUser.GetUserNameByID(ctx, id)
 }
@@ -0,0 +1,193 @@
+// Package audit records security relevant events in an append-only audit
+// trail.
+//
+// Every event carries the hash of the previous one, so that a modified,
+// inserted or deleted row breaks the chain and is reported by Verify.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Actions of the audited events.
+const (
+	ActionLogin        = "auth.login"
+	ActionRegister     = "auth.register"
+	ActionTokenRevoke  = "auth.token_revoke"
+	ActionPolicyAdd    = "casbin.policy_add"
+	ActionPolicyRemove = "casbin.policy_remove"
+	ActionRoleChange   = "user.role_change"
+	ActionAdminCall    = "admin.call"
+)
+
+// Outcomes of the audited events.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// GenesisHash is the previous hash of the first event of the chain.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Event is a row of the audit trail: who did what, when, from where and
+// with which outcome.
+type Event struct {
+	ID        uint64    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                // 主键ID
+	Time      time.Time `gorm:"column:time;precision:6;not null;index" json:"time"`          // 发生时间
+	Actor     string    `gorm:"column:actor;type:varchar(64);index" json:"actor"`            // 操作人，eg: 用户名
+	ActorID   string    `gorm:"column:actor_id;type:varchar(64)" json:"actor_id"`            // 操作人ID
+	Action    string    `gorm:"column:action;type:varchar(64);not null;index" json:"action"` // 操作，eg: auth.login
+	Target    string    `gorm:"column:target;type:varchar(255)" json:"target"`               // 操作对象
+	Outcome   string    `gorm:"column:outcome;type:varchar(16);not null" json:"outcome"`     // 结果：success, failure
+	Reason    string    `gorm:"column:reason;type:varchar(255)" json:"reason"`               // 失败原因
+	ClientIP  string    `gorm:"column:client_ip;type:varchar(64)" json:"client_ip"`          // 来源IP
+	UserAgent string    `gorm:"column:user_agent;type:varchar(255)" json:"user_agent"`       // User-Agent
+	RequestID string    `gorm:"column:request_id;type:varchar(64);index" json:"request_id"`  // 请求ID
+	Details   string    `gorm:"column:details;type:text" json:"details,omitempty"`           // 详细信息，JSON
+	PrevHash  string    `gorm:"column:prev_hash;type:char(64);not null" json:"prev_hash"`    // 上一条记录的哈希
+	Hash      string    `gorm:"column:hash;type:char(64);not null;uniqueIndex" json:"hash"`  // 本条记录的哈希
+}
+
+// TableName returns the table of the audit trail.
+func (Event) TableName() string {
+	return "tb_audit_log"
+}
+
+// maxLengths are the sizes of the varchar columns. Values are truncated
+// before hashing so that the stored row hashes to the same value.
+var maxLengths = map[string]int{
+	"actor":      64,
+	"actor_id":   64,
+	"action":     64,
+	"target":     255,
+	"outcome":    16,
+	"reason":     255,
+	"client_ip":  64,
+	"user_agent": 255,
+	"request_id": 64,
+}
+
+// Normalize prepares e to be stored: the time is set if missing and
+// truncated to the microsecond precision of the databases, and the values
+// are truncated to the size of their column.
+func (e *Event) Normalize() {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC().Truncate(time.Microsecond)
+
+	e.Actor = truncate(e.Actor, maxLengths["actor"])
+	e.ActorID = truncate(e.ActorID, maxLengths["actor_id"])
+	e.Action = truncate(e.Action, maxLengths["action"])
+	e.Target = truncate(e.Target, maxLengths["target"])
+	e.Outcome = truncate(e.Outcome, maxLengths["outcome"])
+	e.Reason = truncate(e.Reason, maxLengths["reason"])
+	e.ClientIP = truncate(e.ClientIP, maxLengths["client_ip"])
+	e.UserAgent = truncate(e.UserAgent, maxLengths["user_agent"])
+	e.RequestID = truncate(e.RequestID, maxLengths["request_id"])
+}
+
+// ComputeHash returns the hash of e chained to e.PrevHash.
+//
+// The ID is part of the hash, so that a row moved to another position or
+// deleted breaks the chain.
+//
+// Returns:
+//   - string: The hex encoded SHA-256 hash.
+func (e *Event) ComputeHash() string {
+	h := sha256.New()
+	for _, v := range []string{
+		e.PrevHash,
+		strconv.FormatUint(e.ID, 10),
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.Actor,
+		e.ActorID,
+		e.Action,
+		e.Target,
+		e.Outcome,
+		e.Reason,
+		e.ClientIP,
+		e.UserAgent,
+		e.RequestID,
+		e.Details,
+	} {
+		// Prefix each value with its length so that values cannot be
+		// shifted from one field to the next
+		h.Write([]byte(strconv.Itoa(len(v))))
+		h.Write([]byte{':'})
+		h.Write([]byte(v))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetDetails stores v as the JSON details of e.
+//
+// Parameters:
+//   - v: The details, e.g. a map or a struct.
+//
+// Returns:
+//   - error: An error if v cannot be encoded.
+func (e *Event) SetDetails(v interface{}) error {
+	if v == nil {
+		e.Details = ""
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.Details = string(data)
+	return nil
+}
+
+// Outcome returns OutcomeFailure if err is not nil, OutcomeSuccess otherwise.
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+// BrokenLink describes where a hash chain is broken.
+type BrokenLink struct {
+	ID     uint64 `json:"id"`     // the ID of the first invalid event
+	Reason string `json:"reason"` // why the event is invalid
+}
+
+// VerifyChain checks that events, ordered by ID, are chained to prevHash
+// and that their hashes match their content.
+//
+// Parameters:
+//   - prevHash: The hash of the event preceding events, GenesisHash for the first one.
+//   - events: The events ordered by ID.
+//
+// Returns:
+//   - string: The hash of the last event, to verify the next batch.
+//   - *BrokenLink: The first invalid event, nil if the chain is valid.
+func VerifyChain(prevHash string, events []Event) (string, *BrokenLink) {
+	for i := range events {
+		e := &events[i]
+		if e.PrevHash != prevHash {
+			return prevHash, &BrokenLink{ID: e.ID, Reason: "previous hash mismatch, an event was modified, inserted or deleted before it"}
+		}
+		if e.ComputeHash() != e.Hash {
+			return prevHash, &BrokenLink{ID: e.ID, Reason: "hash mismatch, the event was modified"}
+		}
+		prevHash = e.Hash
+	}
+	return prevHash, nil
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newChain returns n events chained from GenesisHash, as Store.Append does.
+func newChain(t *testing.T, n int) []Event {
+	t.Helper()
+
+	events := make([]Event, n)
+	prevHash := GenesisHash
+	for i := range events {
+		e := &events[i]
+		e.ID = uint64(i + 1)
+		e.Time = time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC)
+		e.Actor = "alice"
+		e.Action = ActionLogin
+		e.Outcome = OutcomeSuccess
+		e.ClientIP = "10.0.0.1"
+		e.Normalize()
+		e.PrevHash = prevHash
+		e.Hash = e.ComputeHash()
+		prevHash = e.Hash
+	}
+	return events
+}
+
+// TestVerifyChain tests that a modified, deleted or inserted event breaks
+// the chain at the right event.
+func TestVerifyChain(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		events := newChain(t, 5)
+		last, broken := VerifyChain(GenesisHash, events)
+		if broken != nil {
+			t.Fatalf("VerifyChain() broken = %+v, want nil", broken)
+		}
+		if last != events[4].Hash {
+			t.Errorf("VerifyChain() last = %q, want %q", last, events[4].Hash)
+		}
+	})
+
+	t.Run("batches", func(t *testing.T) {
+		events := newChain(t, 5)
+		prevHash, broken := VerifyChain(GenesisHash, events[:2])
+		if broken != nil {
+			t.Fatalf("VerifyChain() first batch broken = %+v", broken)
+		}
+		if _, broken = VerifyChain(prevHash, events[2:]); broken != nil {
+			t.Fatalf("VerifyChain() second batch broken = %+v", broken)
+		}
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		events := newChain(t, 5)
+		events[2].Outcome = OutcomeFailure
+		_, broken := VerifyChain(GenesisHash, events)
+		if broken == nil || broken.ID != 3 {
+			t.Fatalf("VerifyChain() broken = %+v, want ID 3", broken)
+		}
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		events := newChain(t, 5)
+		events = append(events[:1], events[2:]...)
+		_, broken := VerifyChain(GenesisHash, events)
+		if broken == nil || broken.ID != 3 {
+			t.Fatalf("VerifyChain() broken = %+v, want ID 3", broken)
+		}
+	})
+
+	t.Run("rehashed", func(t *testing.T) {
+		// Recomputing the hash of a modified event breaks the next link
+		events := newChain(t, 5)
+		events[1].Actor = "mallory"
+		events[1].Hash = events[1].ComputeHash()
+		_, broken := VerifyChain(GenesisHash, events)
+		if broken == nil || broken.ID != 3 {
+			t.Fatalf("VerifyChain() broken = %+v, want ID 3", broken)
+		}
+	})
+}
+
+// TestComputeHashFieldBoundaries tests that moving characters from a field
+// to the next one changes the hash.
+func TestComputeHashFieldBoundaries(t *testing.T) {
+	a := Event{PrevHash: GenesisHash, Actor: "ab", ActorID: "c"}
+	b := Event{PrevHash: GenesisHash, Actor: "a", ActorID: "bc"}
+	if a.ComputeHash() == b.ComputeHash() {
+		t.Error("ComputeHash() is the same for shifted fields")
+	}
+}
+
+// TestNormalize tests that the time is truncated to microseconds in UTC and
+// that values are truncated to their column size.
+func TestNormalize(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	e := Event{
+		Time:   time.Date(2024, 1, 1, 8, 0, 0, 123456789, loc),
+		Actor:  strings.Repeat("用", 70),
+		Reason: strings.Repeat("x", 300),
+	}
+	e.Normalize()
+
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 123456000, time.UTC); !e.Time.Equal(want) || e.Time.Location() != time.UTC {
+		t.Errorf("Time = %v, want %v", e.Time, want)
+	}
+	if n := len([]rune(e.Actor)); n != 64 {
+		t.Errorf("len(Actor) = %d, want 64", n)
+	}
+	if n := len(e.Reason); n != 255 {
+		t.Errorf("len(Reason) = %d, want 255", n)
+	}
+
+	var empty Event
+	empty.Normalize()
+	if empty.Time.IsZero() {
+		t.Error("Normalize() did not set the time")
+	}
+}
+
+// TestWriteCSV tests the header row and the escaping of formulas.
+func TestWriteCSV(t *testing.T) {
+	events := newChain(t, 2)
+	events[1].Actor = "=HYPERLINK(\"http://evil\")"
+	events[1].Details = `{"role":"admin"}`
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, events); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("len(records) = %d, want 3", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		t.Errorf("header = %v, want %v", records[0], csvHeader)
+	}
+	if records[1][0] != "1" || records[1][4] != ActionLogin {
+		t.Errorf("record = %v", records[1])
+	}
+	if got := records[2][2]; got != "'=HYPERLINK(\"http://evil\")" {
+		t.Errorf("actor = %q, want escaped formula", got)
+	}
+	if got := records[2][11]; got != `{"role":"admin"}` {
+		t.Errorf("details = %q", got)
+	}
+}
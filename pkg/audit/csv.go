@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader is the header row of WriteCSV.
+var csvHeader = []string{
+	"id", "time", "actor", "actor_id", "action", "target", "outcome", "reason",
+	"client_ip", "user_agent", "request_id", "details", "prev_hash", "hash",
+}
+
+// WriteCSV writes events as CSV with a header row.
+//
+// Values starting with =, +, - or @ are prefixed with a quote so that
+// spreadsheets do not evaluate them as formulas.
+//
+// Parameters:
+//   - w: The writer.
+//   - events: The events to write.
+//
+// Returns:
+//   - error: An error if writing fails.
+func WriteCSV(w io.Writer, events []Event) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		record := []string{
+			strconv.FormatUint(e.ID, 10),
+			e.Time.UTC().Format(time.RFC3339Nano),
+			e.Actor,
+			e.ActorID,
+			e.Action,
+			e.Target,
+			e.Outcome,
+			e.Reason,
+			e.ClientIP,
+			e.UserAgent,
+			e.RequestID,
+			e.Details,
+			e.PrevHash,
+			e.Hash,
+		}
+		for i, v := range record {
+			record[i] = escapeFormula(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// escapeFormula prevents a CSV value from being evaluated as a formula.
+func escapeFormula(v string) string {
+	if v == "" {
+		return v
+	}
+	switch v[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + v
+	}
+	return v
+}
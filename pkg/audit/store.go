@@ -0,0 +1,285 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultVerifyBatchSize is the number of events read at once by Verify.
+const DefaultVerifyBatchSize = 1000
+
+// ErrNotMigrated is returned when the chain head is missing.
+var ErrNotMigrated = errors.New("audit tables are not migrated")
+
+// chainHead is the single row holding the end of the hash chain.
+//
+// Appends lock it, so that events are chained in order even when several
+// instances share the database.
+type chainHead struct {
+	ID     uint   `gorm:"column:id;primaryKey"`               // 固定为 1
+	LastID uint64 `gorm:"column:last_id;not null"`            // 最后一条记录的ID
+	Hash   string `gorm:"column:hash;type:char(64);not null"` // 最后一条记录的哈希
+}
+
+// TableName returns the table of the chain head.
+func (chainHead) TableName() string {
+	return "tb_audit_chain"
+}
+
+// Filter selects events.
+type Filter struct {
+	Actor     string    // exact actor
+	Action    string    // exact action
+	Outcome   string    // exact outcome
+	Target    string    // exact target
+	RequestID string    // exact request ID
+	From      time.Time // events at or after, ignored if zero
+	To        time.Time // events before, ignored if zero
+	Offset    int       // number of events skipped
+	Limit     int       // maximum number of events, no limit if 0
+}
+
+// Store is the audit trail stored in MySQL or PostgreSQL.
+//
+// It only appends events: there is no method to update or delete them, and
+// Migrate installs triggers rejecting updates and deletes.
+type Store struct {
+	db *gorm.DB
+}
+
+// NewStore creates a new Store.
+//
+// Parameters:
+//   - db: The MySQL or PostgreSQL database.
+//
+// Returns:
+//   - *Store: The store.
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Migrate creates the audit tables, the chain head and the triggers making
+// the audit table append-only.
+//
+// Parameters:
+//   - ctx: The context of the migration.
+//
+// Returns:
+//   - error: An error if the migration fails.
+func (s *Store) Migrate(ctx context.Context) error {
+	db := s.db.WithContext(ctx)
+
+	if err := db.AutoMigrate(&Event{}, &chainHead{}); err != nil {
+		return fmt.Errorf("failed to migrate audit tables: %w", err)
+	}
+
+	head := chainHead{ID: 1, Hash: GenesisHash}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&head).Error; err != nil {
+		return fmt.Errorf("failed to create audit chain head: %w", err)
+	}
+
+	return s.createTriggers(db)
+}
+
+// createTriggers makes tb_audit_log append-only at the database level.
+func (s *Store) createTriggers(db *gorm.DB) error {
+	table := Event{}.TableName()
+
+	var statements []string
+	switch db.Dialector.Name() {
+	case "mysql":
+		for _, op := range []string{"UPDATE", "DELETE"} {
+			name := fmt.Sprintf("%s_no_%s", table, op)
+
+			var count int64
+			if err := db.Raw("SELECT COUNT(*) FROM information_schema.triggers WHERE trigger_schema = DATABASE() AND trigger_name = ?", name).
+				Scan(&count).Error; err != nil {
+				return fmt.Errorf("failed to check audit triggers: %w", err)
+			}
+			if count > 0 {
+				continue
+			}
+			statements = append(statements, fmt.Sprintf(
+				"CREATE TRIGGER %s BEFORE %s ON %s FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = '%s is append-only'",
+				name, op, table, table))
+		}
+	case "postgres":
+		statements = []string{
+			fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION '%s is append-only';
+END;
+$$ LANGUAGE plpgsql`, table, table),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_append_only ON %s", table, table),
+			fmt.Sprintf("CREATE TRIGGER %s_append_only BEFORE UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION %s_append_only()",
+				table, table, table),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_no_truncate ON %s", table, table),
+			fmt.Sprintf("CREATE TRIGGER %s_no_truncate BEFORE TRUNCATE ON %s FOR EACH STATEMENT EXECUTE FUNCTION %s_append_only()",
+				table, table, table),
+		}
+	default:
+		// Other databases rely on the hash chain only
+		return nil
+	}
+
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create audit triggers: %w", err)
+		}
+	}
+	return nil
+}
+
+// Append stores e at the end of the chain.
+//
+// The ID, the previous hash and the hash of e are set, and e is normalized,
+// see Event.Normalize.
+//
+// Parameters:
+//   - ctx: The context of the operation.
+//   - e: The event to store.
+//
+// Returns:
+//   - error: An error if the event cannot be stored.
+func (s *Store) Append(ctx context.Context, e *Event) error {
+	e.Normalize()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the end of the chain until the event is stored
+		var head chainHead
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, 1).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotMigrated
+		}
+		if err != nil {
+			return err
+		}
+
+		e.ID = head.LastID + 1
+		e.PrevHash = head.Hash
+		e.Hash = e.ComputeHash()
+
+		if err := tx.Create(e).Error; err != nil {
+			return err
+		}
+		return tx.Model(&head).Updates(map[string]interface{}{"last_id": e.ID, "hash": e.Hash}).Error
+	})
+}
+
+// Query returns the events matching f, the most recent first, and the
+// number of matching events.
+//
+// Parameters:
+//   - ctx: The context of the operation.
+//   - f: The filter.
+//
+// Returns:
+//   - []Event: The events of the requested page.
+//   - int64: The number of events matching f.
+//   - error: An error if the query fails.
+func (s *Store) Query(ctx context.Context, f Filter) ([]Event, int64, error) {
+	query := s.db.WithContext(ctx).Model(&Event{})
+	for column, value := range map[string]string{
+		"actor":      f.Actor,
+		"action":     f.Action,
+		"outcome":    f.Outcome,
+		"target":     f.Target,
+		"request_id": f.RequestID,
+	} {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if !f.From.IsZero() {
+		query = query.Where("time >= ?", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		query = query.Where("time < ?", f.To.UTC())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("id DESC").Offset(f.Offset)
+	if f.Limit > 0 {
+		query = query.Limit(f.Limit)
+	}
+
+	var events []Event
+	if err := query.Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// VerifyResult is the result of Verify.
+type VerifyResult struct {
+	Valid   bool        `json:"valid"`            // whether the whole chain is valid
+	Checked int64       `json:"checked"`          // the number of events checked
+	LastID  uint64      `json:"last_id"`          // the ID of the last event of the chain
+	Broken  *BrokenLink `json:"broken,omitempty"` // the first invalid event
+}
+
+// Verify checks the whole hash chain, from the first event to the chain
+// head.
+//
+// Parameters:
+//   - ctx: The context of the operation.
+//   - batchSize: The number of events read at once, DefaultVerifyBatchSize if 0.
+//
+// Returns:
+//   - *VerifyResult: Whether the chain is valid and where it is broken.
+//   - error: An error if the events cannot be read.
+func (s *Store) Verify(ctx context.Context, batchSize int) (*VerifyResult, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultVerifyBatchSize
+	}
+	db := s.db.WithContext(ctx)
+
+	// Read the head first, events appended meanwhile are checked next time
+	var head chainHead
+	if err := db.First(&head, 1).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotMigrated
+		}
+		return nil, err
+	}
+
+	result := &VerifyResult{LastID: head.LastID}
+	prevHash := GenesisHash
+	var lastID uint64
+	for {
+		var events []Event
+		if err := db.Where("id > ? AND id <= ?", lastID, head.LastID).Order("id").Limit(batchSize).Find(&events).Error; err != nil {
+			return nil, err
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		var broken *BrokenLink
+		prevHash, broken = VerifyChain(prevHash, events)
+		if broken != nil {
+			result.Broken = broken
+			return result, nil
+		}
+		result.Checked += int64(len(events))
+		lastID = events[len(events)-1].ID
+	}
+
+	// Events deleted at the end of the chain are detected by the head
+	if lastID != head.LastID || prevHash != head.Hash {
+		result.Broken = &BrokenLink{ID: head.LastID, Reason: "chain head mismatch, events were deleted at the end of the chain"}
+		return result, nil
+	}
+
+	result.Valid = true
+	return result, nil
+}
@@ -0,0 +1,109 @@
+// Package buildinfo holds the version of the running binary, injected at
+// build time with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/xiebingnote/go-gin-project/pkg/buildinfo.Version=1.2.0 \
+//	  -X github.com/xiebingnote/go-gin-project/pkg/buildinfo.GitCommit=$(git rev-parse HEAD) \
+//	  -X github.com/xiebingnote/go-gin-project/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// The Makefile sets them. When they are not injected, the commit and the
+// commit time recorded by the Go toolchain are used instead.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Set with -ldflags "-X github.com/xiebingnote/go-gin-project/pkg/buildinfo.<Name>=<value>".
+var (
+	// Version is the version of the binary, e.g. "1.2.0".
+	Version string
+	// GitCommit is the git commit the binary was built from.
+	GitCommit string
+	// BuildTime is the time the binary was built, in RFC 3339 format.
+	BuildTime string
+)
+
+// unknown is the value of the build information that is not available.
+const unknown = "unknown"
+
+// startTime is the time the process started, approximately.
+var startTime = time.Now()
+
+var (
+	mu              sync.RWMutex
+	fallbackVersion string
+)
+
+// Info is the build information of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// SetFallbackVersion sets the version reported when none was injected at
+// build time, e.g. the version of the server configuration.
+//
+// Parameters:
+//   - version: The fallback version.
+func SetFallbackVersion(version string) {
+	mu.Lock()
+	defer mu.Unlock()
+	fallbackVersion = version
+}
+
+// Get returns the build information of the running binary.
+//
+// Returns:
+//   - Info: The build information, "unknown" for the values not available.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if info.Version == "" {
+		mu.RLock()
+		info.Version = fallbackVersion
+		mu.RUnlock()
+	}
+
+	// Fall back to the VCS information stamped by the Go toolchain
+	if bi, ok := debug.ReadBuildInfo(); ok && (info.GitCommit == "" || info.BuildTime == "") {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.GitCommit == "":
+				info.GitCommit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+
+	for _, v := range []*string{&info.Version, &info.GitCommit, &info.BuildTime} {
+		if *v == "" {
+			*v = unknown
+		}
+	}
+	return info
+}
+
+// StartTime returns the time the process started.
+func StartTime() time.Time {
+	return startTime
+}
+
+// String returns the build information as printed by the --version flag.
+func (i Info) String() string {
+	return fmt.Sprintf("version: %s\ngit commit: %s\nbuild time: %s\ngo version: %s\nplatform: %s",
+		i.Version, i.GitCommit, i.BuildTime, i.GoVersion, i.Platform)
+}
@@ -0,0 +1,75 @@
+package buildinfo
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// setVars sets the ldflags variables for the duration of the test.
+func setVars(t *testing.T, version, commit, buildTime string) {
+	t.Helper()
+	oldVersion, oldCommit, oldBuildTime := Version, GitCommit, BuildTime
+	Version, GitCommit, BuildTime = version, commit, buildTime
+	t.Cleanup(func() {
+		Version, GitCommit, BuildTime = oldVersion, oldCommit, oldBuildTime
+		SetFallbackVersion("")
+	})
+}
+
+// TestGetInjected tests that the injected values are reported as is.
+func TestGetInjected(t *testing.T) {
+	setVars(t, "1.2.0", "abc123", "2024-01-02T03:04:05Z")
+	SetFallbackVersion("0.9.0")
+
+	info := Get()
+	if info.Version != "1.2.0" || info.GitCommit != "abc123" || info.BuildTime != "2024-01-02T03:04:05Z" {
+		t.Errorf("Get() = %+v, want the injected values", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("GoVersion = %q, want %q", info.GoVersion, runtime.Version())
+	}
+	if info.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("Platform = %q", info.Platform)
+	}
+}
+
+// TestGetFallback tests the fallback version and that no value is left
+// empty.
+func TestGetFallback(t *testing.T) {
+	setVars(t, "", "", "")
+
+	info := Get()
+	if info.Version != unknown {
+		t.Errorf("Version = %q, want %q", info.Version, unknown)
+	}
+	if info.GitCommit == "" || info.BuildTime == "" {
+		t.Errorf("Get() = %+v, want no empty value", info)
+	}
+
+	SetFallbackVersion("0.9.0")
+	if got := Get().Version; got != "0.9.0" {
+		t.Errorf("Version = %q, want the fallback version", got)
+	}
+}
+
+// TestCollector tests the build_info metric and that the uptime is
+// exported.
+func TestCollector(t *testing.T) {
+	setVars(t, "1.2.0", "abc123", "2024-01-02T03:04:05Z")
+
+	expected := `
+# HELP build_info Build information of the binary, always 1
+# TYPE build_info gauge
+build_info{build_time="2024-01-02T03:04:05Z",git_commit="abc123",go_version="` + runtime.Version() + `",version="1.2.0"} 1
+`
+	c := NewCollector()
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "build_info"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(c); n != 3 {
+		t.Errorf("CollectAndCount() = %d, want 3", n)
+	}
+}
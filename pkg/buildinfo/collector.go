@@ -0,0 +1,58 @@
+package buildinfo
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector exports the build information and the uptime of the process.
+//
+// The values are computed on each scrape, so that the uptime is always
+// current and the version reflects SetFallbackVersion.
+type Collector struct {
+	buildInfo *prometheus.Desc
+	startTime *prometheus.Desc
+	uptime    *prometheus.Desc
+}
+
+// NewCollector creates a collector exporting the following metrics:
+//
+//   - build_info: always 1, labeled by version, git_commit, build_time and go_version
+//   - app_start_timestamp_seconds: the start time of the process, labeled by version
+//   - app_uptime_seconds: the time since the process started, labeled by version
+//
+// Returns:
+//   - *Collector: The collector.
+func NewCollector() *Collector {
+	return &Collector{
+		buildInfo: prometheus.NewDesc("build_info",
+			"Build information of the binary, always 1",
+			[]string{"version", "git_commit", "build_time", "go_version"}, nil),
+		startTime: prometheus.NewDesc("app_start_timestamp_seconds",
+			"Application start timestamp",
+			[]string{"version"}, nil),
+		uptime: prometheus.NewDesc("app_uptime_seconds",
+			"Application uptime in seconds",
+			[]string{"version"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.buildInfo
+	ch <- c.startTime
+	ch <- c.uptime
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	info := Get()
+
+	ch <- prometheus.MustNewConstMetric(c.buildInfo, prometheus.GaugeValue, 1,
+		info.Version, info.GitCommit, info.BuildTime, info.GoVersion)
+	ch <- prometheus.MustNewConstMetric(c.startTime, prometheus.GaugeValue,
+		float64(startTime.UnixNano())/float64(time.Second), info.Version)
+	ch <- prometheus.MustNewConstMetric(c.uptime, prometheus.GaugeValue,
+		time.Since(startTime).Seconds(), info.Version)
+}
@@ -2,12 +2,17 @@ package circuitbreaker
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/olivere/elastic/v7"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // State 熔断器状态
@@ -36,42 +41,74 @@ func (s State) String() string {
 	}
 }
 
+var (
+	// ErrOpenState 熔断器开启，请求被拒绝
+	ErrOpenState = errors.New("circuit breaker is open")
+	// ErrTooManyRequests 熔断器半开，探测请求数已达上限，请求被拒绝
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
 // Config 熔断器配置
 type Config struct {
-	Name          string                                  // 熔断器名称
-	MaxRequests   uint32                                  // 半开状态下允许的最大请求数
-	Interval      time.Duration                           // 统计窗口时间
-	Timeout       time.Duration                           // 熔断器开启后的超时时间
-	ReadyToTrip   func(counts Counts) bool                // 判断是否应该熔断的函数
-	OnStateChange func(name string, from State, to State) // 状态变化回调
-	IsSuccessful  func(err error) bool                    // 判断请求是否成功的函数
+	Name              string                                  // 熔断器名称
+	MaxRequests       uint32                                  // 半开状态下允许的最大请求数
+	Interval          time.Duration                           // 统计窗口时间
+	BucketCount       int                                     // 滑动窗口的桶数，0 表示固定窗口，每个 Interval 结束时清空计数
+	Timeout           time.Duration                           // 熔断器开启后的超时时间
+	MaxTimeout        time.Duration                           // 连续熔断时超时时间指数退避的上限，不大于 Timeout 时不退避
+	BackoffMultiplier float64                                 // 超时时间的退避倍数，默认为 2
+	MinRequests       uint32                                  // 统计窗口内的最小请求数，达到之前不判断是否熔断
+	SlowCallDuration  time.Duration                           // 慢调用阈值，耗时超过该值的调用计为慢调用，0 表示不统计
+	ReadyToTrip       func(counts Counts) bool                // 判断是否应该熔断的函数
+	OnStateChange     func(name string, from State, to State) // 状态变化回调
+	IsSuccessful      func(err error) bool                    // 判断请求是否成功的函数
+	SharedState       SharedState                             // 共享状态，为 nil 时仅使用本实例的状态
+	SyncInterval      time.Duration                           // 同步共享状态的最小间隔，默认为 1 秒
 }
 
 // Counts 统计信息
+//
+// 滑动窗口模式下，总数为窗口内所有桶的合计，连续数不受窗口影响。
 type Counts struct {
 	Requests             uint32 // 总请求数
 	TotalSuccesses       uint32 // 总成功数
 	TotalFailures        uint32 // 总失败数
+	TotalSlowCalls       uint32 // 总慢调用数，包括成功和失败的调用
 	ConsecutiveSuccesses uint32 // 连续成功数
 	ConsecutiveFailures  uint32 // 连续失败数
 }
 
 // CircuitBreaker 熔断器
 type CircuitBreaker struct {
-	name          string
-	maxRequests   uint32
-	interval      time.Duration
-	timeout       time.Duration
-	readyToTrip   func(counts Counts) bool
-	isSuccessful  func(err error) bool
-	onStateChange func(name string, from State, to State)
+	name              string
+	maxRequests       uint32
+	interval          time.Duration
+	timeout           time.Duration
+	maxTimeout        time.Duration
+	backoffMultiplier float64
+	minRequests       uint32
+	slowCallDuration  time.Duration
+	readyToTrip       func(counts Counts) bool
+	isSuccessful      func(err error) bool
+	onStateChange     func(name string, from State, to State)
 
 	mutex      sync.Mutex
 	state      State
 	generation uint64
 	counts     Counts
+	window     *rollingWindow // 滑动窗口，固定窗口模式下为 nil
+	trips      int            // 半开状态下探测失败导致的连续熔断次数
 	expiry     time.Time
 
+	shared       SharedState
+	syncInterval time.Duration
+	lastSync     time.Time // 上次同步共享状态的时间
+	syncing      bool      // 是否正在同步共享状态
+	silent       bool      // 是否不将状态变化发布到共享状态，如状态来自共享状态或被强制设置
+
+	forced      bool      // 状态是否被强制设置，此时不再自动变化
+	forcedUntil time.Time // 强制状态的截止时间，零值表示直到 Reset
+
 	logger *zap.Logger
 }
 
@@ -100,24 +137,59 @@ var (
 		},
 		[]string{"name"},
 	)
+
+	circuitBreakerSlowCallRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_slow_call_rate",
+			Help: "Current rate of calls slower than the slow call duration of circuit breaker",
+		},
+		[]string{"name"},
+	)
+
+	circuitBreakerOpenTimeout = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_open_timeout_seconds",
+			Help: "Time the circuit breaker stays open before half-opening, including the backoff",
+		},
+		[]string{"name"},
+	)
 )
 
 func init() {
-	prometheus.MustRegister(circuitBreakerRequests, circuitBreakerState, circuitBreakerFailureRate)
+	prometheus.MustRegister(circuitBreakerRequests, circuitBreakerState, circuitBreakerFailureRate,
+		circuitBreakerSlowCallRate, circuitBreakerOpenTimeout)
+}
+
+// deleteMetrics 删除已移除熔断器的指标
+func deleteMetrics(name string) {
+	circuitBreakerState.DeleteLabelValues(name)
+	circuitBreakerFailureRate.DeleteLabelValues(name)
+	circuitBreakerSlowCallRate.DeleteLabelValues(name)
+	circuitBreakerOpenTimeout.DeleteLabelValues(name)
+	for _, state := range []State{StateClosed, StateOpen, StateHalfOpen} {
+		for _, result := range []string{"success", "failure", "rejected"} {
+			circuitBreakerRequests.DeleteLabelValues(name, state.String(), result)
+		}
+	}
 }
 
 // NewCircuitBreaker 创建新的熔断器
 func NewCircuitBreaker(cfg Config) *CircuitBreaker {
 	cb := &CircuitBreaker{
-		name:          cfg.Name,
-		maxRequests:   cfg.MaxRequests,
-		interval:      cfg.Interval,
-		timeout:       cfg.Timeout,
-		readyToTrip:   cfg.ReadyToTrip,
-		isSuccessful:  cfg.IsSuccessful,
-		onStateChange: cfg.OnStateChange,
-		state:         StateClosed,
-		expiry:        time.Now().Add(cfg.Interval),
+		name:              cfg.Name,
+		maxRequests:       cfg.MaxRequests,
+		interval:          cfg.Interval,
+		timeout:           cfg.Timeout,
+		maxTimeout:        cfg.MaxTimeout,
+		backoffMultiplier: cfg.BackoffMultiplier,
+		minRequests:       cfg.MinRequests,
+		slowCallDuration:  cfg.SlowCallDuration,
+		readyToTrip:       cfg.ReadyToTrip,
+		isSuccessful:      cfg.IsSuccessful,
+		onStateChange:     cfg.OnStateChange,
+		shared:            cfg.SharedState,
+		syncInterval:      cfg.SyncInterval,
+		state:             StateClosed,
 	}
 
 	// 设置默认值
@@ -130,6 +202,12 @@ func NewCircuitBreaker(cfg Config) *CircuitBreaker {
 	if cb.timeout == 0 {
 		cb.timeout = 60 * time.Second
 	}
+	if cb.backoffMultiplier < 1 {
+		cb.backoffMultiplier = 2
+	}
+	if cb.syncInterval <= 0 {
+		cb.syncInterval = time.Second
+	}
 	if cb.readyToTrip == nil {
 		cb.readyToTrip = defaultReadyToTrip
 	}
@@ -137,8 +215,17 @@ func NewCircuitBreaker(cfg Config) *CircuitBreaker {
 		cb.isSuccessful = defaultIsSuccessful
 	}
 
+	// 滑动窗口模式下统计窗口按桶滚动，不再整体清空
+	now := time.Now()
+	if cfg.BucketCount > 0 {
+		cb.window = newRollingWindow(cb.interval, cfg.BucketCount, now)
+	} else {
+		cb.expiry = now.Add(cb.interval)
+	}
+
 	// 初始化指标
 	circuitBreakerState.WithLabelValues(cb.name).Set(float64(cb.state))
+	circuitBreakerOpenTimeout.WithLabelValues(cb.name).Set(cb.timeout.Seconds())
 
 	return cb
 }
@@ -148,6 +235,27 @@ func (cb *CircuitBreaker) SetLogger(logger *zap.Logger) {
 	cb.logger = logger
 }
 
+// MarshalText 将状态编码为字符串，如 "OPEN"
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Status 熔断器状态快照
+type Status struct {
+	Name        string     `json:"name"`
+	State       State      `json:"state"`
+	Counts      Counts     `json:"counts"`
+	FailureRate float64    `json:"failure_rate"`
+	OpenUntil   *time.Time `json:"open_until,omitempty"`   // 开启状态转为半开状态的时间
+	Forced      bool       `json:"forced"`                 // 状态是否被强制设置
+	ForcedUntil *time.Time `json:"forced_until,omitempty"` // 强制状态的截止时间，为空表示直到 Reset
+}
+
+// Name 返回熔断器名称
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
 // Execute 执行函数，如果熔断器开启则返回错误
 func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
 	generation, err := cb.beforeRequest()
@@ -159,13 +267,14 @@ func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{},
 	defer func() {
 		e := recover()
 		if e != nil {
-			cb.afterRequest(generation, false)
+			cb.afterRequest(generation, false, 0)
 			panic(e)
 		}
 	}()
 
+	start := time.Now()
 	result, err := req()
-	cb.afterRequest(generation, cb.isSuccessful(err))
+	cb.afterRequest(generation, cb.isSuccessful(err), time.Since(start))
 
 	// 记录指标
 	if err != nil {
@@ -177,6 +286,28 @@ func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{},
 	return result, err
 }
 
+// Execute 执行返回类型为 T 的函数，如果熔断器开启则返回 ErrOpenState 或 ErrTooManyRequests
+func Execute[T any](cb *CircuitBreaker, req func() (T, error)) (T, error) {
+	var result T
+	_, err := cb.Execute(func() (interface{}, error) {
+		var err error
+		result, err = req()
+		return nil, err
+	})
+	return result, err
+}
+
+// ExecuteWithContext 带上下文执行返回类型为 T 的函数
+func ExecuteWithContext[T any](ctx context.Context, cb *CircuitBreaker, req func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	_, err := cb.ExecuteWithContext(ctx, func(ctx context.Context) (interface{}, error) {
+		var err error
+		result, err = req(ctx)
+		return nil, err
+	})
+	return result, err
+}
+
 // ExecuteWithContext 带上下文的执行函数
 func (cb *CircuitBreaker) ExecuteWithContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
 	generation, err := cb.beforeRequest()
@@ -188,13 +319,14 @@ func (cb *CircuitBreaker) ExecuteWithContext(ctx context.Context, req func(ctx c
 	defer func() {
 		e := recover()
 		if e != nil {
-			cb.afterRequest(generation, false)
+			cb.afterRequest(generation, false, 0)
 			panic(e)
 		}
 	}()
 
+	start := time.Now()
 	result, err := req(ctx)
-	cb.afterRequest(generation, cb.isSuccessful(err))
+	cb.afterRequest(generation, cb.isSuccessful(err), time.Since(start))
 
 	// 记录指标
 	if err != nil {
@@ -221,9 +353,112 @@ func (cb *CircuitBreaker) Counts() Counts {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
+	cb.currentState(time.Now())
 	return cb.counts
 }
 
+// Status 返回熔断器的状态快照
+func (cb *CircuitBreaker) Status() Status {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	state, _ := cb.currentState(time.Now())
+	status := Status{
+		Name:   cb.name,
+		State:  state,
+		Counts: cb.counts,
+		Forced: cb.forced,
+	}
+	if cb.forced && !cb.forcedUntil.IsZero() {
+		until := cb.forcedUntil
+		status.ForcedUntil = &until
+	}
+	if cb.counts.Requests > 0 {
+		status.FailureRate = float64(cb.counts.TotalFailures) / float64(cb.counts.Requests)
+	}
+	if state == StateOpen && !cb.expiry.IsZero() {
+		expiry := cb.expiry
+		status.OpenUntil = &expiry
+	}
+	return status
+}
+
+// Force forces the breaker open or closed, e.g. during the planned
+// maintenance of a dependency.
+//
+// A breaker forced open rejects every request, a breaker forced closed lets
+// every request through and does not trip. The forced state lasts until
+// the given time, or until Reset if it is zero. A breaker forced open then
+// half-opens to probe the dependency.
+//
+// With a shared state, a breaker forced open until a given time opens the
+// breakers of the other instances until that time as well.
+//
+// Parameters:
+//   - state: StateOpen or StateClosed.
+//   - until: The end of the forced state, zero for no end.
+//
+// Returns:
+//   - error: An error if the state cannot be forced.
+func (cb *CircuitBreaker) Force(state State, until time.Time) error {
+	if state != StateOpen && state != StateClosed {
+		return fmt.Errorf("cannot force the %s state", state)
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := time.Now()
+	cb.forced = false
+	cb.silent = true
+	if cb.state == state {
+		cb.toNewGeneration(now)
+	} else {
+		cb.setState(state, now)
+	}
+	cb.silent = false
+	cb.forced, cb.forcedUntil = true, until
+	if state == StateOpen {
+		cb.expiry = until
+	}
+
+	if cb.shared != nil {
+		switch {
+		case state == StateOpen && !until.IsZero():
+			go cb.publishShared(func(ctx context.Context) error { return cb.shared.Open(ctx, cb.name, until) })
+		case state == StateClosed:
+			go cb.publishShared(func(ctx context.Context) error { return cb.shared.Close(ctx, cb.name) })
+		}
+	}
+	return nil
+}
+
+// Reset releases a forced state and closes the breaker with cleared counts
+// and backoff.
+func (cb *CircuitBreaker) Reset() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := time.Now()
+	cb.forced, cb.forcedUntil = false, time.Time{}
+	cb.trips = 0
+	cb.silent = true
+	if cb.state == StateClosed {
+		cb.toNewGeneration(now)
+	} else {
+		cb.setState(StateClosed, now)
+	}
+	cb.silent = false
+
+	circuitBreakerFailureRate.WithLabelValues(cb.name).Set(0)
+	circuitBreakerSlowCallRate.WithLabelValues(cb.name).Set(0)
+	circuitBreakerOpenTimeout.WithLabelValues(cb.name).Set(cb.timeout.Seconds())
+
+	if cb.shared != nil {
+		go cb.publishShared(func(ctx context.Context) error { return cb.shared.Close(ctx, cb.name) })
+	}
+}
+
 // beforeRequest 请求前检查
 func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
 	cb.mutex.Lock()
@@ -232,18 +467,25 @@ func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
 	now := time.Now()
 	state, generation := cb.currentState(now)
 
+	// 异步同步共享状态，其他实例的熔断在之后的请求生效
+	if cb.shared != nil && !cb.syncing && now.Sub(cb.lastSync) >= cb.syncInterval {
+		cb.syncing = true
+		cb.lastSync = now
+		go cb.syncShared()
+	}
+
 	if state == StateOpen {
-		return generation, errors.New("circuit breaker is open")
+		return generation, ErrOpenState
 	} else if state == StateHalfOpen && cb.counts.Requests >= cb.maxRequests {
-		return generation, errors.New("too many requests")
+		return generation, ErrTooManyRequests
 	}
 
-	cb.counts.onRequest()
+	cb.record(now, (*Counts).onRequest)
 	return generation, nil
 }
 
 // afterRequest 请求后处理
-func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
+func (cb *CircuitBreaker) afterRequest(before uint64, success bool, duration time.Duration) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
@@ -253,24 +495,52 @@ func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
 		return
 	}
 
+	slow := cb.slowCallDuration > 0 && duration > cb.slowCallDuration
 	if success {
-		cb.onSuccess(state, now)
+		cb.onSuccess(state, now, slow)
 	} else {
-		cb.onFailure(state, now)
+		cb.onFailure(state, now, slow)
 	}
 
-	// 更新失败率指标
+	// 更新失败率和慢调用率指标
 	if cb.counts.Requests > 0 {
 		failureRate := float64(cb.counts.TotalFailures) / float64(cb.counts.Requests)
 		circuitBreakerFailureRate.WithLabelValues(cb.name).Set(failureRate)
+		if cb.slowCallDuration > 0 {
+			slowCallRate := float64(cb.counts.TotalSlowCalls) / float64(cb.counts.Requests)
+			circuitBreakerSlowCallRate.WithLabelValues(cb.name).Set(slowCallRate)
+		}
 	}
 }
 
 // currentState 获取当前状态
 func (cb *CircuitBreaker) currentState(now time.Time) (State, uint64) {
+	if cb.forced {
+		if cb.forcedUntil.IsZero() || now.Before(cb.forcedUntil) {
+			if cb.window != nil {
+				cb.window.rotate(now)
+				cb.window.sum(&cb.counts)
+			}
+			return cb.state, cb.generation
+		}
+
+		// 强制状态到期，强制开启的熔断器转为半开状态探测依赖是否恢复
+		cb.forced, cb.forcedUntil = false, time.Time{}
+		if cb.state == StateOpen {
+			cb.setState(StateHalfOpen, now)
+		} else {
+			cb.toNewGeneration(now)
+		}
+		return cb.state, cb.generation
+	}
+
 	switch cb.state {
 	case StateClosed:
-		if !cb.expiry.IsZero() && cb.expiry.Before(now) {
+		if cb.window != nil {
+			// 滚动窗口，丢弃过期桶的计数
+			cb.window.rotate(now)
+			cb.window.sum(&cb.counts)
+		} else if !cb.expiry.IsZero() && cb.expiry.Before(now) {
 			cb.toNewGeneration(now)
 		}
 	case StateOpen:
@@ -281,22 +551,49 @@ func (cb *CircuitBreaker) currentState(now time.Time) (State, uint64) {
 	return cb.state, cb.generation
 }
 
+// record 更新计数，滑动窗口模式下同时更新当前桶
+func (cb *CircuitBreaker) record(now time.Time, update func(c *Counts)) {
+	update(&cb.counts)
+	if cb.window != nil {
+		cb.window.rotate(now)
+		update(cb.window.current())
+		cb.window.sum(&cb.counts)
+	}
+}
+
 // onSuccess 成功处理
-func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
-	cb.counts.onSuccess()
+func (cb *CircuitBreaker) onSuccess(state State, now time.Time, slow bool) {
+	cb.record(now, func(c *Counts) { c.onSuccess(slow) })
+	if cb.forced {
+		return
+	}
 
-	if state == StateHalfOpen && cb.counts.ConsecutiveSuccesses >= cb.maxRequests {
-		cb.setState(StateClosed, now)
+	switch state {
+	case StateClosed:
+		// 慢调用率可能达到熔断阈值
+		if slow && cb.shouldTrip() {
+			cb.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		// 半开状态下的慢调用视为探测失败
+		if slow {
+			cb.setState(StateOpen, now)
+		} else if cb.counts.ConsecutiveSuccesses >= cb.maxRequests {
+			cb.setState(StateClosed, now)
+		}
 	}
 }
 
 // onFailure 失败处理
-func (cb *CircuitBreaker) onFailure(state State, now time.Time) {
-	cb.counts.onFailure()
+func (cb *CircuitBreaker) onFailure(state State, now time.Time, slow bool) {
+	cb.record(now, func(c *Counts) { c.onFailure(slow) })
+	if cb.forced {
+		return
+	}
 
 	switch state {
 	case StateClosed:
-		if cb.readyToTrip(cb.counts) {
+		if cb.shouldTrip() {
 			cb.setState(StateOpen, now)
 		}
 	case StateHalfOpen:
@@ -304,6 +601,11 @@ func (cb *CircuitBreaker) onFailure(state State, now time.Time) {
 	}
 }
 
+// shouldTrip 判断是否应该熔断，请求数未达到最小请求数时不熔断
+func (cb *CircuitBreaker) shouldTrip() bool {
+	return cb.counts.Requests >= cb.minRequests && cb.readyToTrip(cb.counts)
+}
+
 // setState 设置状态
 func (cb *CircuitBreaker) setState(state State, now time.Time) {
 	if cb.state == state {
@@ -313,8 +615,27 @@ func (cb *CircuitBreaker) setState(state State, now time.Time) {
 	prev := cb.state
 	cb.state = state
 
+	// 半开状态下探测失败时增加退避次数，恢复关闭时清零
+	switch {
+	case state == StateOpen && prev == StateHalfOpen:
+		cb.trips++
+	case state == StateOpen, state == StateClosed:
+		cb.trips = 0
+	}
+
 	cb.toNewGeneration(now)
 
+	// 将本实例的熔断和恢复发布到共享状态
+	if cb.shared != nil && !cb.silent {
+		switch {
+		case state == StateOpen:
+			until := cb.expiry
+			go cb.publishShared(func(ctx context.Context) error { return cb.shared.Open(ctx, cb.name, until) })
+		case state == StateClosed && prev == StateHalfOpen:
+			go cb.publishShared(func(ctx context.Context) error { return cb.shared.Close(ctx, cb.name) })
+		}
+	}
+
 	if cb.onStateChange != nil {
 		cb.onStateChange(cb.name, prev, state)
 	}
@@ -331,21 +652,40 @@ func (cb *CircuitBreaker) setState(state State, now time.Time) {
 	circuitBreakerState.WithLabelValues(cb.name).Set(float64(state))
 }
 
+// openTimeout 返回开启状态的超时时间
+//
+// 半开状态下每次探测失败，超时时间乘以退避倍数，直到 maxTimeout。
+func (cb *CircuitBreaker) openTimeout() time.Duration {
+	timeout := cb.timeout
+	for i := 0; i < cb.trips && timeout < cb.maxTimeout; i++ {
+		timeout = time.Duration(float64(timeout) * cb.backoffMultiplier)
+	}
+	if cb.maxTimeout > cb.timeout && timeout > cb.maxTimeout {
+		timeout = cb.maxTimeout
+	}
+	return timeout
+}
+
 // toNewGeneration 开始新的统计周期
 func (cb *CircuitBreaker) toNewGeneration(now time.Time) {
 	cb.generation++
 	cb.counts.clear()
+	if cb.window != nil {
+		cb.window.reset(now)
+	}
 
 	var zero time.Time
 	switch cb.state {
 	case StateClosed:
-		if cb.interval == 0 {
+		if cb.interval == 0 || cb.window != nil {
 			cb.expiry = zero
 		} else {
 			cb.expiry = now.Add(cb.interval)
 		}
 	case StateOpen:
-		cb.expiry = now.Add(cb.timeout)
+		timeout := cb.openTimeout()
+		cb.expiry = now.Add(timeout)
+		circuitBreakerOpenTimeout.WithLabelValues(cb.name).Set(timeout.Seconds())
 	default: // StateHalfOpen
 		cb.expiry = zero
 	}
@@ -357,17 +697,23 @@ func (c *Counts) onRequest() {
 }
 
 // onSuccess 成功计数
-func (c *Counts) onSuccess() {
+func (c *Counts) onSuccess(slow bool) {
 	c.TotalSuccesses++
 	c.ConsecutiveSuccesses++
 	c.ConsecutiveFailures = 0
+	if slow {
+		c.TotalSlowCalls++
+	}
 }
 
 // onFailure 失败计数
-func (c *Counts) onFailure() {
+func (c *Counts) onFailure(slow bool) {
 	c.TotalFailures++
 	c.ConsecutiveFailures++
 	c.ConsecutiveSuccesses = 0
+	if slow {
+		c.TotalSlowCalls++
+	}
 }
 
 // clear 清空计数
@@ -375,6 +721,7 @@ func (c *Counts) clear() {
 	c.Requests = 0
 	c.TotalSuccesses = 0
 	c.TotalFailures = 0
+	c.TotalSlowCalls = 0
 	c.ConsecutiveSuccesses = 0
 	c.ConsecutiveFailures = 0
 }
@@ -384,7 +731,16 @@ func defaultReadyToTrip(counts Counts) bool {
 	return counts.Requests >= 20 && counts.TotalFailures > counts.TotalSuccesses
 }
 
-// 默认的成功判断函数
+// 默认的成功判断函数，查询无结果说明依赖正常响应，计为成功
 func defaultIsSuccessful(err error) bool {
-	return err == nil
+	return err == nil || IsNotFound(err)
+}
+
+// IsNotFound 判断错误是否为依赖正常响应的查询无结果：redis.Nil、gorm.ErrRecordNotFound、
+// sql.ErrNoRows 或 Elasticsearch 的 404，这类错误不计为熔断失败，也不应重试
+func IsNotFound(err error) bool {
+	return err != nil && (errors.Is(err, redis.Nil) ||
+		errors.Is(err, gorm.ErrRecordNotFound) ||
+		errors.Is(err, sql.ErrNoRows) ||
+		elastic.IsNotFound(err))
 }
@@ -0,0 +1,249 @@
+package circuitbreaker
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// TestRollingWindow tests that the buckets expire one by one as the window
+// rolls.
+func TestRollingWindow(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	w := newRollingWindow(10*time.Second, 5, t0)
+
+	var counts Counts
+	for i := 0; i < 5; i++ {
+		now := t0.Add(time.Duration(i) * 2 * time.Second)
+		w.rotate(now)
+		w.current().onRequest()
+		w.current().onFailure(false)
+	}
+	w.sum(&counts)
+	if counts.Requests != 5 || counts.TotalFailures != 5 {
+		t.Fatalf("sum() = %+v, want 5 requests and failures", counts)
+	}
+
+	// The first two buckets expire
+	w.rotate(t0.Add(12 * time.Second))
+	w.sum(&counts)
+	if counts.Requests != 3 {
+		t.Errorf("Requests = %d after 12s, want 3", counts.Requests)
+	}
+
+	// The whole window expires
+	w.rotate(t0.Add(time.Minute))
+	w.sum(&counts)
+	if counts.Requests != 0 || counts.TotalFailures != 0 {
+		t.Errorf("sum() = %+v after a minute, want no requests", counts)
+	}
+}
+
+// TestRollingWindowBreaker tests that the counts of a rolling window breaker
+// decrease gradually instead of being cleared at the interval boundary.
+func TestRollingWindowBreaker(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:        "test-rolling",
+		Interval:    10 * time.Second,
+		BucketCount: 5,
+		ReadyToTrip: func(Counts) bool { return false },
+	})
+
+	// Align on the 2s buckets so that the expiry does not depend on the clock
+	t0 := time.Now().Truncate(2 * time.Second)
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	for i := 0; i < 4; i++ {
+		cb.record(t0, (*Counts).onRequest)
+		cb.onFailure(StateClosed, t0, false)
+	}
+	cb.record(t0.Add(4*time.Second), (*Counts).onRequest)
+	cb.onSuccess(StateClosed, t0.Add(4*time.Second), false)
+
+	cb.currentState(t0.Add(9 * time.Second))
+	if cb.counts.Requests != 5 {
+		t.Errorf("Requests = %d within the window, want 5", cb.counts.Requests)
+	}
+
+	// The failures expire while the later success is kept
+	cb.currentState(t0.Add(12 * time.Second))
+	if cb.counts.Requests != 1 || cb.counts.TotalFailures != 0 || cb.counts.TotalSuccesses != 1 {
+		t.Errorf("counts = %+v after 12s, want the success only", cb.counts)
+	}
+}
+
+// TestSlowCallRate tests that slow successful calls trip the breaker and
+// that a slow probe reopens a half-open breaker.
+func TestSlowCallRate(t *testing.T) {
+	cb := NewCircuitBreaker(Policy{
+		MinRequests:      2,
+		SlowCallDuration: time.Millisecond,
+		SlowCallRate:     0.5,
+		Timeout:          time.Minute,
+	}.Config("test-slow"))
+
+	slow := func() (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return nil, nil
+	}
+
+	_, _ = cb.Execute(slow)
+	if cb.State() != StateClosed {
+		t.Fatal("breaker opened below the minimum requests")
+	}
+	_, _ = cb.Execute(slow)
+	if cb.State() != StateOpen {
+		t.Fatalf("State() = %v, want OPEN", cb.State())
+	}
+	if counts := cb.Counts(); counts.TotalFailures != 0 {
+		t.Errorf("TotalFailures = %d after the trip, want 0", counts.TotalFailures)
+	}
+
+	cb.mutex.Lock()
+	cb.setState(StateHalfOpen, time.Now())
+	cb.mutex.Unlock()
+	_, _ = cb.Execute(slow)
+	if cb.State() != StateOpen {
+		t.Errorf("State() = %v after a slow probe, want OPEN", cb.State())
+	}
+}
+
+// TestMinRequests tests that the breaker does not trip before the minimum
+// number of requests of the window.
+func TestMinRequests(t *testing.T) {
+	cb := NewCircuitBreaker(Policy{ConsecutiveFailures: 1, MinRequests: 3, Timeout: time.Minute}.Config("test-min"))
+
+	failure := errors.New("failure")
+	for i := 1; i <= 3; i++ {
+		_, _ = cb.Execute(func() (interface{}, error) { return nil, failure })
+		want := StateClosed
+		if i == 3 {
+			want = StateOpen
+		}
+		if cb.State() != want {
+			t.Fatalf("State() = %v after %d failures, want %v", cb.State(), i, want)
+		}
+	}
+}
+
+// TestNotFoundIsSuccessful tests that the not found results of the
+// dependencies do not trip the breaker.
+func TestNotFoundIsSuccessful(t *testing.T) {
+	cb := NewCircuitBreaker(Policy{ConsecutiveFailures: 1, Timeout: time.Minute}.Config("test-not-found"))
+
+	for _, err := range []error{redis.Nil, fmt.Errorf("find user: %w", gorm.ErrRecordNotFound), sql.ErrNoRows} {
+		if _, got := cb.Execute(func() (interface{}, error) { return nil, err }); !errors.Is(got, err) {
+			t.Errorf("Execute() error = %v, want %v", got, err)
+		}
+	}
+	if cb.State() != StateClosed || cb.Counts().TotalSuccesses != 3 {
+		t.Errorf("State() = %v with %+v, want closed after 3 successes", cb.State(), cb.Counts())
+	}
+}
+
+// TestOpenTimeoutBackoff tests that the open timeout grows exponentially
+// after failed probes, up to the maximum, and is reset once closed.
+func TestOpenTimeoutBackoff(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:       "test-backoff",
+		Timeout:    time.Second,
+		MaxTimeout: 5 * time.Second,
+	})
+
+	now := time.Now()
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.setState(StateOpen, now)
+	for _, want := range []time.Duration{2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		cb.setState(StateHalfOpen, now)
+		cb.setState(StateOpen, now)
+		if got := cb.expiry.Sub(now); got != want {
+			t.Errorf("open timeout = %v, want %v", got, want)
+		}
+	}
+
+	cb.setState(StateHalfOpen, now)
+	cb.setState(StateClosed, now)
+	cb.setState(StateOpen, now)
+	if got := cb.expiry.Sub(now); got != time.Second {
+		t.Errorf("open timeout after closing = %v, want 1s", got)
+	}
+}
+
+// TestForce tests that a forced state does not change with the requests and
+// that a breaker forced open half-opens once the forced state expires.
+func TestForce(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:        "test-force",
+		Timeout:     time.Minute,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+
+	if err := cb.Force(StateHalfOpen, time.Time{}); err == nil {
+		t.Error("Force(HALF_OPEN) succeeded, want an error")
+	}
+
+	// A breaker forced closed does not trip
+	if err := cb.Force(StateClosed, time.Time{}); err != nil {
+		t.Fatalf("Force(CLOSED) error = %v", err)
+	}
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, errors.New("failure") })
+	if cb.State() != StateClosed {
+		t.Errorf("State() = %v after a failure, want CLOSED", cb.State())
+	}
+
+	// A breaker forced open rejects the requests until the forced state expires
+	until := time.Now().Add(time.Hour)
+	if err := cb.Force(StateOpen, until); err != nil {
+		t.Fatalf("Force(OPEN) error = %v", err)
+	}
+	if _, err := cb.Execute(func() (interface{}, error) { return nil, nil }); !errors.Is(err, ErrOpenState) {
+		t.Errorf("Execute() error = %v, want ErrOpenState", err)
+	}
+	status := cb.Status()
+	if !status.Forced || status.ForcedUntil == nil || !status.ForcedUntil.Equal(until) ||
+		status.OpenUntil == nil || !status.OpenUntil.Equal(until) {
+		t.Errorf("Status() = %+v, want forced open until %v", status, until)
+	}
+
+	cb.mutex.Lock()
+	state, _ := cb.currentState(until.Add(time.Second))
+	forced := cb.forced
+	cb.mutex.Unlock()
+	if state != StateHalfOpen || forced {
+		t.Errorf("state = %v, forced = %v after the forced state, want HALF_OPEN and not forced", state, forced)
+	}
+}
+
+// TestReset tests that Reset releases the forced state and clears the counts
+// and the backoff.
+func TestReset(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "test-reset", Timeout: time.Second, MaxTimeout: time.Minute})
+
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, errors.New("failure") })
+	cb.mutex.Lock()
+	cb.setState(StateOpen, time.Now())
+	cb.setState(StateHalfOpen, time.Now())
+	cb.setState(StateOpen, time.Now())
+	cb.mutex.Unlock()
+	if err := cb.Force(StateOpen, time.Time{}); err != nil {
+		t.Fatalf("Force(OPEN) error = %v", err)
+	}
+
+	cb.Reset()
+	if status := cb.Status(); status.State != StateClosed || status.Forced || status.Counts != (Counts{}) {
+		t.Errorf("Status() = %+v after Reset, want closed with no counts", status)
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if cb.trips != 0 {
+		t.Errorf("trips = %d after Reset, want 0", cb.trips)
+	}
+}
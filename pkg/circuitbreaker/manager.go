@@ -0,0 +1,297 @@
+package circuitbreaker
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Manager 熔断器管理器，按名称管理依赖和路由的熔断器
+//
+// 熔断器可以通过 GetOrCreateBreaker 直接创建，也可以通过 Configure 由熔断
+// 策略声明，后者在配置重新加载时会被更新。
+type Manager struct {
+	mu       sync.RWMutex
+	breakers map[string]*CircuitBreaker
+	// policies 由策略声明的熔断器的策略
+	policies map[string]Policy
+	// defaultRoutes 按默认策略创建的路由熔断器
+	defaultRoutes map[string]bool
+	routes        []RouteRule
+	routeDefault  *Policy
+	shared        SharedState
+	syncInterval  time.Duration
+	logger        *zap.Logger
+
+	subMu       sync.Mutex
+	subscribers map[chan StateChangeEvent]struct{}
+}
+
+// StateChangeEvent 熔断器状态变化事件
+type StateChangeEvent struct {
+	Name string    `json:"name"`
+	From State     `json:"from"`
+	To   State     `json:"to"`
+	Time time.Time `json:"time"`
+}
+
+// subscriberBuffer 订阅者的事件缓冲大小，缓冲已满时丢弃事件
+const subscriberBuffer = 64
+
+// NewManager 创建熔断器管理器
+func NewManager(logger *zap.Logger) *Manager {
+	return &Manager{
+		breakers:      make(map[string]*CircuitBreaker),
+		policies:      make(map[string]Policy),
+		defaultRoutes: make(map[string]bool),
+		logger:        logger,
+		subscribers:   make(map[chan StateChangeEvent]struct{}),
+	}
+}
+
+// SetSharedState 设置之后创建的熔断器使用的共享状态，使多个实例同时熔断
+//
+// 已创建的熔断器不受影响，因此应在 Configure 之前调用。
+func (m *Manager) SetSharedState(shared SharedState, syncInterval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.shared = shared
+	m.syncInterval = syncInterval
+}
+
+// GetOrCreateBreaker 获取或创建熔断器
+func (m *Manager) GetOrCreateBreaker(name string, config Config) *CircuitBreaker {
+	m.mu.RLock()
+	cb, exists := m.breakers[name]
+	m.mu.RUnlock()
+	if exists {
+		return cb
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cb, exists := m.breakers[name]; exists {
+		return cb
+	}
+	return m.createLocked(name, config)
+}
+
+// createLocked creates a circuit breaker, replacing the one of the same
+// name. The caller must hold the write lock.
+func (m *Manager) createLocked(name string, config Config) *CircuitBreaker {
+	config.Name = name
+	if custom := config.OnStateChange; custom != nil {
+		// 自定义回调之外仍然通知订阅者
+		config.OnStateChange = func(name string, from State, to State) {
+			custom(name, from, to)
+			m.publish(name, from, to)
+		}
+	} else {
+		config.OnStateChange = m.onStateChange
+	}
+	if config.SharedState == nil {
+		config.SharedState = m.shared
+		config.SyncInterval = m.syncInterval
+	}
+
+	cb := NewCircuitBreaker(config)
+	cb.SetLogger(m.logger)
+	m.breakers[name] = cb
+
+	if m.logger != nil {
+		m.logger.Info("Circuit breaker created",
+			zap.String("name", name),
+			zap.Duration("interval", cb.interval),
+			zap.Duration("timeout", cb.timeout),
+			zap.Uint32("max_requests", cb.maxRequests),
+		)
+	}
+
+	return cb
+}
+
+// removeLocked removes a circuit breaker and its metrics. The caller must
+// hold the write lock.
+func (m *Manager) removeLocked(name string) {
+	delete(m.breakers, name)
+	delete(m.policies, name)
+	delete(m.defaultRoutes, name)
+	deleteMetrics(name)
+}
+
+// onStateChange 状态变化回调
+func (m *Manager) onStateChange(name string, from State, to State) {
+	if m.logger != nil {
+		m.logger.Warn("Circuit breaker state changed",
+			zap.String("name", name),
+			zap.String("from", from.String()),
+			zap.String("to", to.String()),
+		)
+	}
+	m.publish(name, from, to)
+}
+
+// publish 将状态变化发送给所有订阅者
+//
+// 回调在熔断器加锁时调用，因此不会阻塞，缓冲已满的订阅者会丢失事件。
+func (m *Manager) publish(name string, from State, to State) {
+	event := StateChangeEvent{Name: name, From: from, To: to, Time: time.Now()}
+
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe subscribes to the state changes of all the circuit breakers of
+// the manager, e.g. to stream them to on-call.
+//
+// Events are dropped while the buffer of the subscriber is full, so that a
+// slow subscriber never blocks the breakers.
+//
+// Returns:
+//   - <-chan StateChangeEvent: The state changes, closed on unsubscribe.
+//   - func(): The function to unsubscribe, safe to call more than once.
+func (m *Manager) Subscribe() (<-chan StateChangeEvent, func()) {
+	ch := make(chan StateChangeEvent, subscriberBuffer)
+
+	m.subMu.Lock()
+	m.subscribers[ch] = struct{}{}
+	m.subMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			m.subMu.Lock()
+			delete(m.subscribers, ch)
+			m.subMu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// GetBreaker 获取熔断器，不存在时返回 nil
+func (m *Manager) GetBreaker(name string) *CircuitBreaker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.breakers[name]
+}
+
+// ListBreakers 列出所有熔断器
+func (m *Manager) ListBreakers() map[string]*CircuitBreaker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string]*CircuitBreaker, len(m.breakers))
+	for name, cb := range m.breakers {
+		result[name] = cb
+	}
+	return result
+}
+
+// Configure applies the declared circuit breakers, at boot and on every
+// configuration reload.
+//
+// A breaker whose policy is unchanged keeps its state and counts. A breaker
+// whose policy changed is replaced by a new, closed one, and a breaker no
+// longer declared is removed. Breakers created with GetOrCreateBreaker are
+// left untouched unless a declared breaker has the same name.
+//
+// Parameters:
+//   - dependencies: The policies of the dependency breakers, by name.
+//   - routes: The route rules, checked in order by RouteBreaker.
+//   - routeDefault: The policy of the routes matching no rule, each route
+//     getting its own breaker, or nil to leave these routes unprotected.
+func (m *Manager) Configure(dependencies map[string]Policy, routes []RouteRule, routeDefault *Policy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	declared := make(map[string]Policy, len(dependencies)+len(routes))
+	for name, p := range dependencies {
+		declared[name] = p
+	}
+	for _, r := range routes {
+		declared[r.BreakerName()] = r.Policy
+	}
+
+	defaultChanged := !reflect.DeepEqual(m.routeDefault, routeDefault)
+	for name, current := range m.policies {
+		p, ok := declared[name]
+		switch {
+		case ok && !m.defaultRoutes[name] && reflect.DeepEqual(current, p):
+			// Unchanged, keep the state of the breaker
+			delete(declared, name)
+		case !ok && m.defaultRoutes[name] && !defaultChanged:
+			// Route breaker of the unchanged default policy
+		default:
+			m.removeLocked(name)
+		}
+	}
+
+	for name, p := range declared {
+		m.createLocked(name, p.Config(name))
+		m.policies[name] = p
+	}
+
+	m.routes = append([]RouteRule(nil), routes...)
+	m.routeDefault = routeDefault
+}
+
+// RouteBreaker returns the circuit breaker protecting a route and its
+// policy.
+//
+// The first matching route rule applies. Routes matching no rule get their
+// own breaker named "Method:Route" with the default route policy, if any.
+//
+// Parameters:
+//   - method: The HTTP method of the request.
+//   - route: The route template of the request, e.g. "/api/v1/users/:id".
+//
+// Returns:
+//   - *CircuitBreaker: The circuit breaker of the route.
+//   - Policy: The policy of the breaker, e.g. to tell the failure statuses.
+//   - bool: False if the route is not protected.
+func (m *Manager) RouteBreaker(method, route string) (*CircuitBreaker, Policy, bool) {
+	m.mu.RLock()
+	for _, r := range m.routes {
+		if r.Matches(method, route) {
+			cb, ok := m.breakers[r.BreakerName()]
+			m.mu.RUnlock()
+			return cb, r.Policy, ok
+		}
+	}
+	if m.routeDefault == nil {
+		m.mu.RUnlock()
+		return nil, Policy{}, false
+	}
+	name := method + ":" + route
+	cb, ok := m.breakers[name]
+	policy := *m.routeDefault
+	m.mu.RUnlock()
+	if ok {
+		return cb, policy, true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// The configuration may have been reloaded in between
+	if m.routeDefault == nil {
+		return nil, Policy{}, false
+	}
+	policy = *m.routeDefault
+	if cb, ok := m.breakers[name]; ok {
+		return cb, policy, true
+	}
+	cb = m.createLocked(name, policy.Config(name))
+	m.policies[name] = policy
+	m.defaultRoutes[name] = true
+	return cb, policy, true
+}
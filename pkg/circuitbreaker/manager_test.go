@@ -0,0 +1,214 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestPolicyIsFailureStatus tests the status codes and status classes
+// counted as failures.
+func TestPolicyIsFailureStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		codes  []string
+		status int
+		want   bool
+	}{
+		{name: "default 5xx", status: 503, want: true},
+		{name: "default 4xx", status: 429, want: false},
+		{name: "class", codes: []string{"5xx"}, status: 500, want: true},
+		{name: "class mismatch", codes: []string{"5xx"}, status: 404, want: false},
+		{name: "code", codes: []string{"5xx", "429"}, status: 429, want: true},
+		{name: "upper case class", codes: []string{"4XX"}, status: 401, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{FailureStatusCodes: tt.codes}
+			if got := p.IsFailureStatus(tt.status); got != tt.want {
+				t.Errorf("IsFailureStatus(%d) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPolicyValidate tests the validation of the thresholds and status
+// codes.
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{name: "failure rate", policy: Policy{FailureRate: 0.5}},
+		{name: "consecutive failures", policy: Policy{ConsecutiveFailures: 5}},
+		{name: "no threshold", policy: Policy{}, wantErr: true},
+		{name: "failure rate too high", policy: Policy{FailureRate: 1.5}, wantErr: true},
+		{name: "negative timeout", policy: Policy{FailureRate: 0.5, Timeout: -time.Second}, wantErr: true},
+		{name: "invalid code", policy: Policy{FailureRate: 0.5, FailureStatusCodes: []string{"6xx"}}, wantErr: true},
+		{name: "code out of range", policy: Policy{FailureRate: 0.5, FailureStatusCodes: []string{"99"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestPolicyConfigTrips tests that a breaker created from a policy opens
+// after the configured consecutive failures.
+func TestPolicyConfigTrips(t *testing.T) {
+	cb := NewCircuitBreaker(Policy{ConsecutiveFailures: 3, Timeout: time.Minute}.Config("test-trip"))
+
+	failure := errors.New("failure")
+	for i := 0; i < 3; i++ {
+		_, _ = cb.Execute(func() (interface{}, error) { return nil, failure })
+	}
+	if cb.State() != StateOpen {
+		t.Fatalf("State() = %v, want OPEN", cb.State())
+	}
+	if _, err := cb.Execute(func() (interface{}, error) { return nil, nil }); err == nil {
+		t.Error("Execute() on an open breaker succeeded")
+	}
+}
+
+// TestRouteRuleMatches tests the method and pattern matching of the route
+// rules.
+func TestRouteRuleMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   RouteRule
+		method string
+		route  string
+		want   bool
+	}{
+		{name: "exact", rule: RouteRule{Pattern: "/api/v1/users/:id"}, method: "GET", route: "/api/v1/users/:id", want: true},
+		{name: "method", rule: RouteRule{Method: "post", Pattern: "/api/v1/users"}, method: "POST", route: "/api/v1/users", want: true},
+		{name: "method mismatch", rule: RouteRule{Method: "POST", Pattern: "/api/v1/users"}, method: "GET", route: "/api/v1/users", want: false},
+		{name: "segment wildcard", rule: RouteRule{Pattern: "/api/v1/*/export"}, method: "GET", route: "/api/v1/orders/export", want: true},
+		{name: "segment wildcard depth", rule: RouteRule{Pattern: "/api/v1/*"}, method: "GET", route: "/api/v1/orders/export", want: false},
+		{name: "prefix", rule: RouteRule{Pattern: "/api/v1/**"}, method: "GET", route: "/api/v1/orders/export", want: true},
+		{name: "prefix itself", rule: RouteRule{Pattern: "/api/v1/**"}, method: "GET", route: "/api/v1", want: true},
+		{name: "prefix boundary", rule: RouteRule{Pattern: "/api/v1/**"}, method: "GET", route: "/api/v10", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(tt.method, tt.route); got != tt.want {
+				t.Errorf("Matches(%s, %s) = %v, want %v", tt.method, tt.route, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestManagerConfigure tests that unchanged breakers keep their state on
+// reconfiguration while changed and removed ones are replaced.
+func TestManagerConfigure(t *testing.T) {
+	m := NewManager(nil)
+	mysql := Policy{ConsecutiveFailures: 1, Timeout: time.Minute}
+	redis := Policy{FailureRate: 0.5, MinRequests: 10}
+	m.Configure(map[string]Policy{"test-mysql": mysql, "test-redis": redis}, nil, nil)
+
+	_, _ = m.GetBreaker("test-mysql").Execute(func() (interface{}, error) { return nil, errors.New("failure") })
+	if m.GetBreaker("test-mysql").State() != StateOpen {
+		t.Fatal("test-mysql breaker did not open")
+	}
+	redisBreaker := m.GetBreaker("test-redis")
+	custom := m.GetOrCreateBreaker("test-custom", Config{})
+
+	// Unchanged policy keeps the open breaker, changed policy replaces it
+	redis.MinRequests = 20
+	m.Configure(map[string]Policy{"test-mysql": mysql, "test-redis": redis}, nil, nil)
+	if m.GetBreaker("test-mysql").State() != StateOpen {
+		t.Error("unchanged test-mysql breaker was reset")
+	}
+	if m.GetBreaker("test-redis") == redisBreaker {
+		t.Error("changed test-redis breaker was not replaced")
+	}
+
+	// Removed declarations remove their breakers only
+	m.Configure(nil, nil, nil)
+	if m.GetBreaker("test-mysql") != nil || m.GetBreaker("test-redis") != nil {
+		t.Error("removed breakers still exist")
+	}
+	if m.GetBreaker("test-custom") != custom {
+		t.Error("breaker created with GetOrCreateBreaker was removed")
+	}
+}
+
+// TestManagerRouteBreaker tests that the first matching rule applies and
+// that unmatched routes use the default policy only when it is set.
+func TestManagerRouteBreaker(t *testing.T) {
+	m := NewManager(nil)
+	export := RouteRule{Name: "test-export", Pattern: "/api/*/export", Policy: Policy{FailureRate: 0.5}}
+	all := RouteRule{Pattern: "/api/**", Policy: Policy{FailureRate: 0.9}}
+	m.Configure(nil, []RouteRule{export, all}, nil)
+
+	cb, policy, ok := m.RouteBreaker("GET", "/api/orders/export")
+	if !ok || cb.Name() != "test-export" || policy.FailureRate != 0.5 {
+		t.Errorf("RouteBreaker() = %v, %+v, %v, want the test-export breaker", cb, policy, ok)
+	}
+	if cb, _, ok := m.RouteBreaker("GET", "/api/orders"); !ok || cb.Name() != "*:/api/**" {
+		t.Errorf("RouteBreaker() = %v, %v, want the *:/api/** breaker", cb, ok)
+	}
+	if _, _, ok := m.RouteBreaker("GET", "/health"); ok {
+		t.Error("RouteBreaker() protects a route without rule and default policy")
+	}
+
+	m.Configure(nil, nil, &Policy{FailureRate: 0.6})
+	cb, policy, ok = m.RouteBreaker("GET", "/health")
+	if !ok || cb.Name() != "GET:/health" || policy.FailureRate != 0.6 {
+		t.Errorf("RouteBreaker() = %v, %+v, %v, want the default route breaker", cb, policy, ok)
+	}
+	if again, _, _ := m.RouteBreaker("GET", "/health"); again != cb {
+		t.Error("RouteBreaker() created a second breaker for the same route")
+	}
+
+	// An unchanged default policy keeps the route breakers
+	m.Configure(nil, nil, &Policy{FailureRate: 0.6})
+	if m.GetBreaker("GET:/health") != cb {
+		t.Error("route breaker of the unchanged default policy was replaced")
+	}
+	m.Configure(nil, nil, nil)
+	if m.GetBreaker("GET:/health") != nil {
+		t.Error("route breaker of the removed default policy still exists")
+	}
+}
+
+// TestManagerSubscribe tests that the subscribers receive the state changes
+// of the breakers, including those with a custom callback.
+func TestManagerSubscribe(t *testing.T) {
+	m := NewManager(nil)
+	events, unsubscribe := m.Subscribe()
+
+	var custom int
+	cb := m.GetOrCreateBreaker("test-subscribe", Config{
+		OnStateChange: func(string, State, State) { custom++ },
+	})
+	if err := cb.Force(StateOpen, time.Time{}); err != nil {
+		t.Fatalf("Force(OPEN) error = %v", err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Name != "test-subscribe" || e.From != StateClosed || e.To != StateOpen {
+			t.Errorf("event = %+v, want test-subscribe CLOSED to OPEN", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the state change")
+	}
+	if custom != 1 {
+		t.Errorf("custom callback called %d times, want 1", custom)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Error("events not closed after unsubscribe")
+	}
+	cb.Reset()
+}
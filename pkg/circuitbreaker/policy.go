@@ -0,0 +1,196 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy 熔断策略，由配置文件声明，用于创建熔断器
+type Policy struct {
+	MaxRequests         uint32        // 半开状态下允许通过的请求数
+	Interval            time.Duration // 关闭状态下的统计窗口时间
+	BucketCount         int           // 滑动窗口的桶数，0 表示固定窗口
+	Timeout             time.Duration // 熔断器开启后转为半开状态的时间
+	MaxTimeout          time.Duration // 连续熔断时超时时间指数退避的上限，不大于 Timeout 时不退避
+	BackoffMultiplier   float64       // 超时时间的退避倍数，0 表示默认值 2
+	MinRequests         uint32        // 统计窗口内触发熔断的最小请求数
+	FailureRate         float64       // 触发熔断的失败率，0 表示不按失败率熔断
+	ConsecutiveFailures uint32        // 触发熔断的连续失败数，0 表示不按连续失败熔断
+	SlowCallDuration    time.Duration // 慢调用阈值，0 表示不统计慢调用
+	SlowCallRate        float64       // 触发熔断的慢调用率，0 表示不按慢调用率熔断
+	FailureStatusCodes  []string      // 计为失败的 HTTP 状态码，如 "5xx"、"429"，为空时 5xx 计为失败
+}
+
+// Validate checks the thresholds and the failure status codes of the policy.
+//
+// Returns:
+//   - error: An error describing the first invalid value, nil otherwise.
+func (p Policy) Validate() error {
+	if p.FailureRate < 0 || p.FailureRate > 1 {
+		return fmt.Errorf("failure rate %v must be between 0 and 1", p.FailureRate)
+	}
+	if p.SlowCallRate < 0 || p.SlowCallRate > 1 {
+		return fmt.Errorf("slow call rate %v must be between 0 and 1", p.SlowCallRate)
+	}
+	if p.FailureRate == 0 && p.ConsecutiveFailures == 0 && p.SlowCallRate == 0 {
+		return fmt.Errorf("either the failure rate, the consecutive failures or the slow call rate must be set")
+	}
+	if p.SlowCallRate > 0 && p.SlowCallDuration <= 0 {
+		return fmt.Errorf("slow call rate requires a slow call duration")
+	}
+	if p.Interval < 0 || p.Timeout < 0 || p.MaxTimeout < 0 || p.SlowCallDuration < 0 {
+		return fmt.Errorf("durations must not be negative")
+	}
+	if p.BucketCount < 0 {
+		return fmt.Errorf("bucket count %d must not be negative", p.BucketCount)
+	}
+	if p.BucketCount > 0 && p.Interval > 0 && p.Interval/time.Duration(p.BucketCount) < time.Millisecond {
+		return fmt.Errorf("interval %v is too short for %d buckets", p.Interval, p.BucketCount)
+	}
+	if p.BackoffMultiplier != 0 && p.BackoffMultiplier < 1 {
+		return fmt.Errorf("backoff multiplier %v must not be less than 1", p.BackoffMultiplier)
+	}
+	for _, code := range p.FailureStatusCodes {
+		if _, _, err := parseStatusCode(code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Config returns the configuration of a circuit breaker applying the policy.
+//
+// Once at least MinRequests requests were made within the statistics
+// window, the breaker trips when the failure rate reaches FailureRate, when
+// the rate of calls slower than SlowCallDuration reaches SlowCallRate, or
+// when ConsecutiveFailures requests failed in a row.
+//
+// Parameters:
+//   - name: The name of the circuit breaker.
+//
+// Returns:
+//   - Config: The circuit breaker configuration.
+func (p Policy) Config(name string) Config {
+	return Config{
+		Name:              name,
+		MaxRequests:       p.MaxRequests,
+		Interval:          p.Interval,
+		BucketCount:       p.BucketCount,
+		Timeout:           p.Timeout,
+		MaxTimeout:        p.MaxTimeout,
+		BackoffMultiplier: p.BackoffMultiplier,
+		MinRequests:       p.MinRequests,
+		SlowCallDuration:  p.SlowCallDuration,
+		ReadyToTrip: func(counts Counts) bool {
+			if p.ConsecutiveFailures > 0 && counts.ConsecutiveFailures >= p.ConsecutiveFailures {
+				return true
+			}
+			if counts.Requests == 0 {
+				return false
+			}
+			if p.FailureRate > 0 && float64(counts.TotalFailures)/float64(counts.Requests) >= p.FailureRate {
+				return true
+			}
+			return p.SlowCallRate > 0 && float64(counts.TotalSlowCalls)/float64(counts.Requests) >= p.SlowCallRate
+		},
+	}
+}
+
+// IsFailureStatus reports whether an HTTP response status counts as a
+// failure of the policy.
+//
+// Parameters:
+//   - status: The HTTP status code of the response.
+//
+// Returns:
+//   - bool: True if the status is a failure.
+func (p Policy) IsFailureStatus(status int) bool {
+	if len(p.FailureStatusCodes) == 0 {
+		return status >= 500
+	}
+	for _, code := range p.FailureStatusCodes {
+		// The codes are checked by Validate
+		if n, class, err := parseStatusCode(code); err == nil {
+			if (class && status/100 == n) || (!class && status == n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseStatusCode parses an HTTP status code, e.g. "503", or a status class,
+// e.g. "5xx".
+func parseStatusCode(code string) (n int, class bool, err error) {
+	s := strings.ToLower(strings.TrimSpace(code))
+	if len(s) == 3 && strings.HasSuffix(s, "xx") && s[0] >= '1' && s[0] <= '5' {
+		return int(s[0] - '0'), true, nil
+	}
+	n, err = strconv.Atoi(s)
+	if err != nil || n < 100 || n > 599 {
+		return 0, false, fmt.Errorf("invalid failure status code %q", code)
+	}
+	return n, false, nil
+}
+
+// RouteRule 路由熔断规则，匹配的路由共用一个熔断器
+type RouteRule struct {
+	Name    string // 熔断器名称，为空时使用 "Method:Pattern"
+	Method  string // HTTP 方法，为空或 "*" 匹配所有方法
+	Pattern string // 路由模板，如 "/api/v1/users/:id"、"/api/v1/*/export"，以 "/**" 结尾时匹配该前缀下的所有路由
+	Policy  Policy // 熔断策略
+}
+
+// BreakerName returns the name of the circuit breaker of the rule.
+//
+// Returns:
+//   - string: The configured name, or "Method:Pattern" if it is empty.
+func (r RouteRule) BreakerName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = "*"
+	}
+	return method + ":" + r.Pattern
+}
+
+// Validate checks the pattern and the policy of the rule.
+//
+// Returns:
+//   - error: An error describing the first invalid value, nil otherwise.
+func (r RouteRule) Validate() error {
+	if !strings.HasPrefix(r.Pattern, "/") {
+		return fmt.Errorf("route pattern %q must start with /", r.Pattern)
+	}
+	if _, err := path.Match(strings.TrimSuffix(r.Pattern, "/**"), "/"); err != nil {
+		return fmt.Errorf("invalid route pattern %q: %w", r.Pattern, err)
+	}
+	if err := r.Policy.Validate(); err != nil {
+		return fmt.Errorf("route %s: %w", r.BreakerName(), err)
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a route.
+//
+// Parameters:
+//   - method: The HTTP method of the request.
+//   - route: The route template of the request, e.g. "/api/v1/users/:id".
+//
+// Returns:
+//   - bool: True if the method and the route match the rule.
+func (r RouteRule) Matches(method, route string) bool {
+	if r.Method != "" && r.Method != "*" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(r.Pattern, "/**"); ok {
+		return route == prefix || strings.HasPrefix(route, prefix+"/")
+	}
+	matched, _ := path.Match(r.Pattern, route)
+	return matched
+}
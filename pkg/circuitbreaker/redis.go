@@ -0,0 +1,61 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisKeyPrefix Redis 共享状态的默认键前缀
+const DefaultRedisKeyPrefix = "circuitbreaker:"
+
+// RedisState 基于 Redis 的共享状态
+//
+// 每个开启的熔断器对应一个键，值为开启截止时间的毫秒时间戳，键在截止时间
+// 过期。
+type RedisState struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisState 创建基于 Redis 的共享状态，prefix 为空时使用 DefaultRedisKeyPrefix
+func NewRedisState(client redis.UniversalClient, prefix string) *RedisState {
+	if prefix == "" {
+		prefix = DefaultRedisKeyPrefix
+	}
+	return &RedisState{client: client, prefix: prefix}
+}
+
+// Load implements SharedState.
+func (s *RedisState) Load(ctx context.Context, name string) (time.Time, error) {
+	v, err := s.client.Get(ctx, s.prefix+name).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// Open implements SharedState.
+func (s *RedisState) Open(ctx context.Context, name string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(ctx, s.prefix+name, strconv.FormatInt(until.UnixMilli(), 10), ttl).Err()
+}
+
+// Close implements SharedState.
+func (s *RedisState) Close(ctx context.Context, name string) error {
+	return s.client.Del(ctx, s.prefix+name).Err()
+}
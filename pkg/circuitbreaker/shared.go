@@ -0,0 +1,74 @@
+package circuitbreaker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sharedStateTimeout 访问共享状态的超时时间
+const sharedStateTimeout = time.Second
+
+// SharedState 熔断器共享状态
+//
+// 多个实例的同名熔断器通过共享状态同时熔断：一个实例熔断时记录开启的截止
+// 时间，其他实例同步后在截止时间之前同样拒绝请求，而不是各自等到失败率达到
+// 阈值。截止时间之后各实例分别进入半开状态探测下游是否恢复。
+type SharedState interface {
+	// Load 返回熔断器开启的截止时间，未开启时返回零值
+	Load(ctx context.Context, name string) (time.Time, error)
+	// Open 记录熔断器开启直到 until
+	Open(ctx context.Context, name string, until time.Time) error
+	// Close 清除熔断器的开启状态
+	Close(ctx context.Context, name string) error
+}
+
+// syncShared loads the shared state of the breaker and opens the closed
+// breaker until the shared deadline if another instance tripped.
+//
+// A half-open breaker ignores the shared state and probes the downstream on
+// its own, a failed probe of another instance must not extend its backoff.
+// A forced state is not changed by the shared state either.
+func (cb *CircuitBreaker) syncShared() {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+	until, err := cb.shared.Load(ctx, cb.name)
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.syncing = false
+
+	if err != nil {
+		if cb.logger != nil {
+			cb.logger.Warn("Failed to load circuit breaker shared state",
+				zap.String("name", cb.name), zap.Error(err))
+		}
+		return
+	}
+
+	now := time.Now()
+	if state, _ := cb.currentState(now); cb.forced || state != StateClosed || !until.After(now) {
+		return
+	}
+
+	cb.silent = true
+	cb.setState(StateOpen, now)
+	cb.silent = false
+
+	// 使用共享的截止时间，使各实例同时进入半开状态
+	cb.expiry = until
+	circuitBreakerOpenTimeout.WithLabelValues(cb.name).Set(until.Sub(now).Seconds())
+}
+
+// publishShared writes a state change of the breaker to the shared state.
+// A failure is only logged, the other instances trip on their own.
+func (cb *CircuitBreaker) publishShared(publish func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+
+	if err := publish(ctx); err != nil && cb.logger != nil {
+		cb.logger.Warn("Failed to publish circuit breaker shared state",
+			zap.String("name", cb.name), zap.Error(err))
+	}
+}
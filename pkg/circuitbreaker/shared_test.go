@@ -0,0 +1,143 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryState is an in-memory SharedState shared by the breakers of a test.
+type memoryState struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func newMemoryState() *memoryState {
+	return &memoryState{until: make(map[string]time.Time)}
+}
+
+// Load implements SharedState.
+func (s *memoryState) Load(_ context.Context, name string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.until[name], nil
+}
+
+// Open implements SharedState.
+func (s *memoryState) Open(_ context.Context, name string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.until[name] = until
+	return nil
+}
+
+// Close implements SharedState.
+func (s *memoryState) Close(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.until, name)
+	return nil
+}
+
+// waitFor polls cond until it holds or a second elapsed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// TestSentinelErrors tests that rejected requests return the sentinel
+// errors.
+func TestSentinelErrors(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "test-sentinel", Timeout: time.Minute})
+
+	cb.mutex.Lock()
+	cb.setState(StateOpen, time.Now())
+	cb.mutex.Unlock()
+	if _, err := cb.Execute(func() (interface{}, error) { return nil, nil }); !errors.Is(err, ErrOpenState) {
+		t.Errorf("Execute() error = %v, want ErrOpenState", err)
+	}
+
+	cb.mutex.Lock()
+	cb.setState(StateHalfOpen, time.Now())
+	cb.counts.Requests = cb.maxRequests
+	cb.mutex.Unlock()
+	if _, err := cb.Execute(func() (interface{}, error) { return nil, nil }); !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("Execute() error = %v, want ErrTooManyRequests", err)
+	}
+}
+
+// TestExecuteGeneric tests the typed result of Execute and
+// ExecuteWithContext.
+func TestExecuteGeneric(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "test-generic"})
+
+	n, err := Execute(cb, func() (int, error) { return 42, nil })
+	if err != nil || n != 42 {
+		t.Errorf("Execute() = %d, %v, want 42, nil", n, err)
+	}
+
+	failure := errors.New("failure")
+	s, err := ExecuteWithContext(context.Background(), cb, func(context.Context) (string, error) {
+		return "partial", failure
+	})
+	if !errors.Is(err, failure) || s != "partial" {
+		t.Errorf("ExecuteWithContext() = %q, %v, want the result and error of the function", s, err)
+	}
+}
+
+// TestSharedState tests that a breaker tripping opens the breaker of the
+// same name of another instance, and that recovering clears the shared
+// state.
+func TestSharedState(t *testing.T) {
+	shared := newMemoryState()
+	newBreaker := func() *CircuitBreaker {
+		return NewCircuitBreaker(Config{
+			Name:         "test-shared",
+			Timeout:      time.Minute,
+			ReadyToTrip:  func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+			SharedState:  shared,
+			SyncInterval: time.Millisecond,
+		})
+	}
+	a, b := newBreaker(), newBreaker()
+
+	_, _ = a.Execute(func() (interface{}, error) { return nil, errors.New("failure") })
+	if a.State() != StateOpen {
+		t.Fatalf("State() = %v, want OPEN", a.State())
+	}
+	waitFor(t, "the shared open state", func() bool {
+		until, _ := shared.Load(context.Background(), "test-shared")
+		return !until.IsZero()
+	})
+
+	// The other instance opens once it synchronized
+	waitFor(t, "the other instance to open", func() bool {
+		_, _ = b.Execute(func() (interface{}, error) { return nil, nil })
+		return b.State() == StateOpen
+	})
+	a.mutex.Lock()
+	wantExpiry := a.expiry
+	a.mutex.Unlock()
+	b.mutex.Lock()
+	if !b.expiry.Equal(wantExpiry) {
+		t.Errorf("expiry = %v, want the shared deadline %v", b.expiry, wantExpiry)
+	}
+	b.mutex.Unlock()
+
+	// Recovering clears the shared state
+	a.mutex.Lock()
+	a.setState(StateHalfOpen, time.Now())
+	a.setState(StateClosed, time.Now())
+	a.mutex.Unlock()
+	waitFor(t, "the shared state to be cleared", func() bool {
+		until, _ := shared.Load(context.Background(), "test-shared")
+		return until.IsZero()
+	})
+}
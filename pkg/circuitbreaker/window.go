@@ -0,0 +1,76 @@
+package circuitbreaker
+
+import "time"
+
+// rollingWindow 滑动窗口，将统计窗口划分为若干个时间桶
+//
+// 每个桶统计一段时间内的请求数、成功数、失败数和慢调用数，过期的桶在
+// 窗口滚动时被清空，因此失败率随时间平滑变化，而不是在窗口边界突变。
+type rollingWindow struct {
+	buckets []Counts
+	width   time.Duration // 每个桶的时间长度
+	head    int64         // 最新的桶的序号，即时间除以桶的长度
+}
+
+// newRollingWindow 创建滑动窗口，窗口长度 size 被均分为 n 个桶
+func newRollingWindow(size time.Duration, n int, now time.Time) *rollingWindow {
+	width := size / time.Duration(n)
+	if width <= 0 {
+		width = 1
+	}
+	w := &rollingWindow{
+		buckets: make([]Counts, n),
+		width:   width,
+	}
+	w.head = w.epoch(now)
+	return w
+}
+
+// epoch 返回时间所在的桶的序号
+func (w *rollingWindow) epoch(now time.Time) int64 {
+	return now.UnixNano() / int64(w.width)
+}
+
+// rotate 滚动窗口，清空当前时间之前已过期的桶
+func (w *rollingWindow) rotate(now time.Time) {
+	epoch := w.epoch(now)
+	if epoch <= w.head {
+		return
+	}
+
+	n := int64(len(w.buckets))
+	if epoch-w.head >= n {
+		for i := range w.buckets {
+			w.buckets[i] = Counts{}
+		}
+	} else {
+		for e := w.head + 1; e <= epoch; e++ {
+			w.buckets[e%n] = Counts{}
+		}
+	}
+	w.head = epoch
+}
+
+// current 返回最新的桶
+func (w *rollingWindow) current() *Counts {
+	return &w.buckets[w.head%int64(len(w.buckets))]
+}
+
+// reset 清空所有桶
+func (w *rollingWindow) reset(now time.Time) {
+	for i := range w.buckets {
+		w.buckets[i] = Counts{}
+	}
+	w.head = w.epoch(now)
+}
+
+// sum 将窗口内所有桶的合计写入 c 的总数，连续数保持不变
+func (w *rollingWindow) sum(c *Counts) {
+	c.Requests, c.TotalSuccesses, c.TotalFailures, c.TotalSlowCalls = 0, 0, 0, 0
+	for _, b := range w.buckets {
+		c.Requests += b.Requests
+		c.TotalSuccesses += b.TotalSuccesses
+		c.TotalFailures += b.TotalFailures
+		c.TotalSlowCalls += b.TotalSlowCalls
+	}
+}
@@ -0,0 +1,89 @@
+// Package clientmetrics exports Prometheus metrics of the client pools
+// created at boot: database/sql pools, go-redis pools, MongoDB pool events,
+// sarama producers and consumers, NSQ producers and consumers and HTTP
+// clients such as the Elasticsearch one.
+//
+// Every metric carries a "client" label holding the name of the client
+// instance (e.g. "mysql", "kafka-producer"), so that the dashboards can tell
+// the pools apart and detect their exhaustion. The label is not named
+// "instance" so that it does not collide with the label of the scraped
+// target.
+package clientmetrics
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ClientLabel is the label holding the name of the client instance.
+const ClientLabel = "client"
+
+// Names of the client instances created at boot.
+const (
+	ClientMySQL         = "mysql"
+	ClientPostgresql    = "postgresql"
+	ClientClickHouse    = "clickhouse"
+	ClientTDengine      = "tdengine"
+	ClientRedis         = "redis"
+	ClientMongoDB       = "mongodb"
+	ClientKafkaProducer = "kafka-producer"
+	ClientKafkaConsumer = "kafka-consumer"
+	ClientNSQProducer   = "nsq-producer"
+	ClientNSQConsumer   = "nsq-consumer"
+	ClientElasticsearch = "elasticsearch"
+)
+
+var (
+	mu         sync.Mutex
+	registered = make(map[string]prometheus.Collector)
+)
+
+// Register registers the collector of a client instance with the default
+// Prometheus registry.
+//
+// A collector already registered under the same name, e.g. by a previous
+// initialization of the client, is unregistered first.
+//
+// Parameters:
+//   - name: The name of the client instance.
+//   - c: The collector of the client.
+//
+// Returns:
+//   - error: An error if the collector cannot be registered.
+func Register(name string, c prometheus.Collector) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if old, ok := registered[name]; ok {
+		prometheus.Unregister(old)
+		delete(registered, name)
+	}
+
+	if err := prometheus.Register(c); err != nil {
+		return fmt.Errorf("failed to register %s client metrics: %w", name, err)
+	}
+	registered[name] = c
+	return nil
+}
+
+// Unregister unregisters the collector of a client instance, e.g. when the
+// client is closed. Nothing happens if no collector is registered under name.
+//
+// Parameters:
+//   - name: The name of the client instance.
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if c, ok := registered[name]; ok {
+		prometheus.Unregister(c)
+		delete(registered, name)
+	}
+}
+
+// newDesc returns the description of a metric of a client instance.
+func newDesc(client, name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(name, help, labels, prometheus.Labels{ClientLabel: client})
+}
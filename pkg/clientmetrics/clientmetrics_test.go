@@ -0,0 +1,227 @@
+package clientmetrics
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// fakeDriver is a database/sql driver that cannot connect, the pool
+// statistics are available without a connection.
+type fakeDriver struct{}
+
+// Open implements driver.Driver.
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("not supported")
+}
+
+func init() {
+	sql.Register("clientmetrics-fake", fakeDriver{})
+}
+
+// TestDBStatsCollector tests that the pool statistics are exported with the
+// client label.
+func TestDBStatsCollector(t *testing.T) {
+	db, err := sql.Open("clientmetrics-fake", "")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(7)
+
+	expected := `
+# HELP db_pool_max_open_connections Maximum number of open connections to the database
+# TYPE db_pool_max_open_connections gauge
+db_pool_max_open_connections{client="mysql"} 7
+# HELP db_pool_in_use_connections Number of connections currently in use
+# TYPE db_pool_in_use_connections gauge
+db_pool_in_use_connections{client="mysql"} 0
+`
+	c := NewDBStatsCollector(ClientMySQL, db)
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"db_pool_max_open_connections", "db_pool_in_use_connections"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(c); n != 9 {
+		t.Errorf("CollectAndCount() = %d, want 9", n)
+	}
+}
+
+// TestRedisPoolCollector tests that the pool size and statistics are
+// exported.
+func TestRedisPoolCollector(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0", PoolSize: 5})
+	defer client.Close()
+
+	expected := `
+# HELP redis_pool_max_connections Maximum number of connections of the pool
+# TYPE redis_pool_max_connections gauge
+redis_pool_max_connections{client="redis"} 5
+# HELP redis_pool_timeouts_total Total number of times waiting for a connection timed out
+# TYPE redis_pool_timeouts_total counter
+redis_pool_timeouts_total{client="redis"} 0
+`
+	c := NewRedisPoolCollector(ClientRedis, client, client.Options().PoolSize)
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"redis_pool_max_connections", "redis_pool_timeouts_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestMongoPoolCollector tests that the pool events are aggregated by
+// server address.
+func TestMongoPoolCollector(t *testing.T) {
+	c := NewMongoPoolCollector(ClientMongoDB)
+	monitor := c.PoolMonitor()
+
+	const addr = "mongo:27017"
+	for _, e := range []*event.PoolEvent{
+		{Type: event.PoolCreated, Address: addr, PoolOptions: &event.MonitorPoolOptions{MaxPoolSize: 10}},
+		{Type: event.ConnectionCreated, Address: addr},
+		{Type: event.ConnectionCreated, Address: addr},
+		{Type: event.GetSucceeded, Address: addr},
+		{Type: event.GetSucceeded, Address: addr},
+		{Type: event.ConnectionReturned, Address: addr},
+		{Type: event.GetFailed, Address: addr, Reason: event.ReasonTimedOut},
+		{Type: event.ConnectionClosed, Address: addr},
+	} {
+		monitor.Event(e)
+	}
+
+	expected := `
+# HELP mongo_pool_max_connections Maximum number of connections of the pool
+# TYPE mongo_pool_max_connections gauge
+mongo_pool_max_connections{address="mongo:27017",client="mongodb"} 10
+# HELP mongo_pool_connections Number of connections in the pool, both in use and idle
+# TYPE mongo_pool_connections gauge
+mongo_pool_connections{address="mongo:27017",client="mongodb"} 1
+# HELP mongo_pool_in_use_connections Number of connections checked out of the pool
+# TYPE mongo_pool_in_use_connections gauge
+mongo_pool_in_use_connections{address="mongo:27017",client="mongodb"} 1
+# HELP mongo_pool_checkouts_total Total number of connections checked out of the pool
+# TYPE mongo_pool_checkouts_total counter
+mongo_pool_checkouts_total{address="mongo:27017",client="mongodb"} 2
+# HELP mongo_pool_checkout_failures_total Total number of failed connection checkouts, by reason
+# TYPE mongo_pool_checkout_failures_total counter
+mongo_pool_checkout_failures_total{address="mongo:27017",client="mongodb",reason="timeout"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"mongo_pool_max_connections", "mongo_pool_connections", "mongo_pool_in_use_connections",
+		"mongo_pool_checkouts_total", "mongo_pool_checkout_failures_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestSaramaCollector tests that meters, counters and histograms of the
+// sarama registry are exported and that missing metrics are skipped.
+func TestSaramaCollector(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.GetOrRegisterMeter("record-send-rate", registry).Mark(42)
+	gometrics.GetOrRegisterCounter("requests-in-flight", registry).Inc(3)
+	latency := gometrics.GetOrRegisterHistogram("request-latency-in-ms", registry, gometrics.NewUniformSample(10))
+	latency.Update(10)
+	latency.Update(30)
+
+	expected := `
+# HELP kafka_producer_records_sent_total Total number of records sent
+# TYPE kafka_producer_records_sent_total counter
+kafka_producer_records_sent_total{client="kafka-producer"} 42
+# HELP kafka_client_requests_in_flight Number of requests waiting for a response
+# TYPE kafka_client_requests_in_flight gauge
+kafka_client_requests_in_flight{client="kafka-producer"} 3
+`
+	c := NewSaramaCollector(ClientKafkaProducer, registry)
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"kafka_producer_records_sent_total", "kafka_client_requests_in_flight"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(c); n != 3 {
+		t.Errorf("CollectAndCount() = %d, want 3", n)
+	}
+	if n := testutil.CollectAndCount(c, "kafka_client_request_latency_milliseconds"); n != 1 {
+		t.Errorf("latency summary count = %d, want 1", n)
+	}
+}
+
+// TestObserveKafkaConsumerLag tests the lag computed from the high water
+// mark.
+func TestObserveKafkaConsumerLag(t *testing.T) {
+	ObserveKafkaConsumerLag(ClientKafkaConsumer, "group", "orders", 2, 100, 89)
+	if got := testutil.ToFloat64(kafkaConsumerLag.WithLabelValues(ClientKafkaConsumer, "group", "orders", "2")); got != 10 {
+		t.Errorf("lag = %v, want 10", got)
+	}
+
+	// The last message of the partition leaves no lag
+	ObserveKafkaConsumerLag(ClientKafkaConsumer, "group", "orders", 2, 100, 99)
+	if got := testutil.ToFloat64(kafkaConsumerLag.WithLabelValues(ClientKafkaConsumer, "group", "orders", "2")); got != 0 {
+		t.Errorf("lag = %v, want 0", got)
+	}
+}
+
+// TestTransport tests that requests are counted by status code and that
+// transport errors are counted as "error".
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	const client = "test-transport"
+	httpClient := &http.Client{Transport: NewTransport(client, nil)}
+
+	resp, err := httpClient.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if got := testutil.ToFloat64(httpClientRequests.WithLabelValues(client, http.MethodGet, "404")); got != 1 {
+		t.Errorf("404 requests = %v, want 1", got)
+	}
+
+	srv.Close()
+	if _, err := httpClient.Get(srv.URL); err == nil {
+		t.Fatal("Get() on a closed server succeeded")
+	}
+	if got := testutil.ToFloat64(httpClientRequests.WithLabelValues(client, http.MethodGet, "error")); got != 1 {
+		t.Errorf("error requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(httpClientInFlight.WithLabelValues(client)); got != 0 {
+		t.Errorf("in flight = %v, want 0", got)
+	}
+}
+
+// TestRegister tests that registering a client twice replaces its
+// collector and that Unregister removes it.
+func TestRegister(t *testing.T) {
+	db, err := sql.Open("clientmetrics-fake", "")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	const client = "test-register"
+	if err := Register(client, NewDBStatsCollector(client, db)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := Register(client, NewDBStatsCollector(client, db)); err != nil {
+		t.Fatalf("Register() again error = %v", err)
+	}
+
+	Unregister(client)
+	c := NewDBStatsCollector(client, db)
+	if err := prometheus.Register(c); err != nil {
+		t.Fatalf("collector still registered after Unregister(): %v", err)
+	}
+	prometheus.Unregister(c)
+}
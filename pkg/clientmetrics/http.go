@@ -0,0 +1,85 @@
+package clientmetrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTP 客户端请求计数
+	httpClientRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_requests_total",
+			Help: "Total number of requests sent by the HTTP clients, by status code or \"error\"",
+		},
+		[]string{ClientLabel, "method", "code"},
+	)
+
+	// HTTP 客户端请求耗时
+	httpClientDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_client_request_duration_seconds",
+			Help:    "Duration of the requests sent by the HTTP clients, until the response headers are received",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{ClientLabel, "method"},
+	)
+
+	// HTTP 客户端正在处理的请求数
+	httpClientInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_client_requests_in_flight",
+			Help: "Number of requests of the HTTP clients waiting for their response headers",
+		},
+		[]string{ClientLabel},
+	)
+)
+
+// Transport is an http.RoundTripper recording the requests of an HTTP
+// client, e.g. the Elasticsearch client.
+type Transport struct {
+	// Client is the name of the client instance, e.g. "elasticsearch".
+	Client string
+	// Base is the underlying RoundTripper, http.DefaultTransport if nil.
+	Base http.RoundTripper
+}
+
+// NewTransport wraps base with request metrics.
+//
+// Parameters:
+//   - client: The name of the client instance, e.g. "elasticsearch".
+//   - base: The underlying RoundTripper, http.DefaultTransport if nil.
+//
+// Returns:
+//   - *Transport: The wrapping transport.
+func NewTransport(client string, base http.RoundTripper) *Transport {
+	return &Transport{Client: client, Base: base}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	inFlight := httpClientInFlight.WithLabelValues(t.Client)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	httpClientDuration.WithLabelValues(t.Client, req.Method).Observe(time.Since(start).Seconds())
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	httpClientRequests.WithLabelValues(t.Client, req.Method, code).Inc()
+
+	return resp, err
+}
@@ -0,0 +1,122 @@
+package clientmetrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// kafkaQuantiles are the quantiles exported for the sarama histograms.
+var kafkaQuantiles = []float64{0.5, 0.95, 0.99}
+
+// kafkaMetric maps a metric of the sarama registry to a Prometheus metric.
+type kafkaMetric struct {
+	sarama string // the sarama metric name
+	name   string // the Prometheus metric name
+	help   string
+}
+
+// kafkaMetrics are the sarama metrics exported by SaramaCollector, see
+// https://pkg.go.dev/github.com/IBM/sarama#hdr-Metrics. Meters are exported
+// as counters, histograms as summaries and counters as gauges. Only the
+// metrics aggregated over all brokers and topics are exported, to bound the
+// number of series.
+var kafkaMetrics = []kafkaMetric{
+	{"incoming-byte-rate", "kafka_client_incoming_bytes_total", "Total number of bytes read from the brokers"},
+	{"outgoing-byte-rate", "kafka_client_outgoing_bytes_total", "Total number of bytes written to the brokers"},
+	{"request-rate", "kafka_client_requests_total", "Total number of requests sent to the brokers"},
+	{"response-rate", "kafka_client_responses_total", "Total number of responses received from the brokers"},
+	{"requests-in-flight", "kafka_client_requests_in_flight", "Number of requests waiting for a response"},
+	{"request-latency-in-ms", "kafka_client_request_latency_milliseconds", "Latency of the requests in milliseconds"},
+	{"request-size", "kafka_client_request_size_bytes", "Size of the requests in bytes"},
+	{"response-size", "kafka_client_response_size_bytes", "Size of the responses in bytes"},
+	{"record-send-rate", "kafka_producer_records_sent_total", "Total number of records sent"},
+	{"batch-size", "kafka_producer_batch_size_bytes", "Size of the produce batches in bytes"},
+	{"records-per-request", "kafka_producer_records_per_request", "Number of records per produce request"},
+	{"compression-ratio", "kafka_producer_compression_ratio", "Compression ratio of the batches, times 100"},
+	{"consumer-fetch-rate", "kafka_consumer_fetches_total", "Total number of fetch requests"},
+	{"consumer-batch-size", "kafka_consumer_batch_size", "Number of messages per fetch response"},
+	{"consumer-fetch-response-size", "kafka_consumer_fetch_response_size_bytes", "Size of the fetch responses in bytes"},
+}
+
+// kafkaConsumerLag is the lag of the consumers, updated as they process
+// messages.
+var kafkaConsumerLag = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Number of messages of the partition not processed yet by the consumer",
+	},
+	[]string{ClientLabel, "group", "topic", "partition"},
+)
+
+// ObserveKafkaConsumerLag records the lag of a consumer after it processed
+// the message at offset.
+//
+// Parameters:
+//   - client: The name of the client instance, e.g. "kafka-consumer".
+//   - group: The consumer group, empty for a partition consumer.
+//   - topic: The topic of the message.
+//   - partition: The partition of the message.
+//   - highWaterMark: The offset of the next message produced to the partition.
+//   - offset: The offset of the processed message.
+func ObserveKafkaConsumerLag(client, group, topic string, partition int32, highWaterMark, offset int64) {
+	kafkaConsumerLag.WithLabelValues(client, group, topic, strconv.Itoa(int(partition))).
+		Set(float64(max(highWaterMark-offset-1, 0)))
+}
+
+// SaramaCollector exports the metrics of a sarama producer or consumer.
+type SaramaCollector struct {
+	registry gometrics.Registry
+	descs    map[string]*prometheus.Desc // by sarama metric name
+}
+
+// NewSaramaCollector creates a collector of the metrics sarama records in
+// registry, the MetricRegistry of the sarama.Config of the client.
+//
+// Parameters:
+//   - client: The name of the client instance, e.g. "kafka-producer".
+//   - registry: The metric registry of the client.
+//
+// Returns:
+//   - *SaramaCollector: The collector.
+func NewSaramaCollector(client string, registry gometrics.Registry) *SaramaCollector {
+	descs := make(map[string]*prometheus.Desc, len(kafkaMetrics))
+	for _, m := range kafkaMetrics {
+		descs[m.sarama] = newDesc(client, m.name, m.help)
+	}
+	return &SaramaCollector{registry: registry, descs: descs}
+}
+
+// Describe implements prometheus.Collector.
+func (c *SaramaCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+//
+// Metrics not recorded yet, e.g. the producer metrics of a consumer, are
+// skipped. The sum of a summary is estimated from the mean of the sample
+// kept by sarama.
+func (c *SaramaCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, desc := range c.descs {
+		switch m := c.registry.Get(name).(type) {
+		case gometrics.Meter:
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(m.Count()))
+		case gometrics.Counter:
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(m.Count()))
+		case gometrics.Histogram:
+			snapshot := m.Snapshot()
+			percentiles := snapshot.Percentiles(kafkaQuantiles)
+			quantiles := make(map[float64]float64, len(kafkaQuantiles))
+			for i, q := range kafkaQuantiles {
+				quantiles[q] = percentiles[i]
+			}
+			count := snapshot.Count()
+			ch <- prometheus.MustNewConstSummary(desc, uint64(count), snapshot.Mean()*float64(count), quantiles)
+		}
+	}
+}
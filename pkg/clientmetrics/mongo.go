@@ -0,0 +1,133 @@
+package clientmetrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// mongoPoolStats are the statistics of the pool of one MongoDB server.
+type mongoPoolStats struct {
+	maxConns         uint64
+	conns            int64
+	inUse            int64
+	checkouts        uint64
+	checkoutFailures map[string]uint64 // by reason
+	cleared          uint64
+}
+
+// MongoPoolCollector exports the connection pool statistics of a MongoDB
+// client, computed from the pool events.
+//
+// The MongoDB driver exposes no pool statistics, so the collector must be
+// installed as the pool monitor of the client, see PoolMonitor.
+type MongoPoolCollector struct {
+	mu    sync.Mutex
+	pools map[string]*mongoPoolStats // by server address
+
+	maxConns         *prometheus.Desc
+	conns            *prometheus.Desc
+	inUse            *prometheus.Desc
+	checkouts        *prometheus.Desc
+	checkoutFailures *prometheus.Desc
+	cleared          *prometheus.Desc
+}
+
+// NewMongoPoolCollector creates a collector of the pool events of a MongoDB
+// client.
+//
+// A pool is exhausted when mongo_pool_in_use_connections reaches
+// mongo_pool_max_connections, which makes checkouts fail with the
+// "timeout" reason.
+//
+// Parameters:
+//   - client: The name of the client instance, e.g. "mongodb".
+//
+// Returns:
+//   - *MongoPoolCollector: The collector.
+func NewMongoPoolCollector(client string) *MongoPoolCollector {
+	return &MongoPoolCollector{
+		pools: make(map[string]*mongoPoolStats),
+		maxConns: newDesc(client, "mongo_pool_max_connections",
+			"Maximum number of connections of the pool", "address"),
+		conns: newDesc(client, "mongo_pool_connections",
+			"Number of connections in the pool, both in use and idle", "address"),
+		inUse: newDesc(client, "mongo_pool_in_use_connections",
+			"Number of connections checked out of the pool", "address"),
+		checkouts: newDesc(client, "mongo_pool_checkouts_total",
+			"Total number of connections checked out of the pool", "address"),
+		checkoutFailures: newDesc(client, "mongo_pool_checkout_failures_total",
+			"Total number of failed connection checkouts, by reason", "address", "reason"),
+		cleared: newDesc(client, "mongo_pool_cleared_total",
+			"Total number of times the pool was cleared", "address"),
+	}
+}
+
+// PoolMonitor returns the pool monitor to install with
+// options.Client().SetPoolMonitor.
+//
+// Returns:
+//   - *event.PoolMonitor: The pool monitor feeding the collector.
+func (c *MongoPoolCollector) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: c.handle}
+}
+
+// handle updates the statistics of the pool of the event.
+func (c *MongoPoolCollector) handle(e *event.PoolEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, ok := c.pools[e.Address]
+	if !ok {
+		pool = &mongoPoolStats{checkoutFailures: make(map[string]uint64)}
+		c.pools[e.Address] = pool
+	}
+
+	switch e.Type {
+	case event.PoolCreated:
+		if e.PoolOptions != nil {
+			pool.maxConns = e.PoolOptions.MaxPoolSize
+		}
+	case event.ConnectionCreated:
+		pool.conns++
+	case event.ConnectionClosed:
+		pool.conns--
+	case event.GetSucceeded:
+		pool.inUse++
+		pool.checkouts++
+	case event.GetFailed:
+		pool.checkoutFailures[e.Reason]++
+	case event.ConnectionReturned:
+		pool.inUse--
+	case event.PoolCleared:
+		pool.cleared++
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *MongoPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxConns
+	ch <- c.conns
+	ch <- c.inUse
+	ch <- c.checkouts
+	ch <- c.checkoutFailures
+	ch <- c.cleared
+}
+
+// Collect implements prometheus.Collector.
+func (c *MongoPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for address, pool := range c.pools {
+		ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(pool.maxConns), address)
+		ch <- prometheus.MustNewConstMetric(c.conns, prometheus.GaugeValue, float64(pool.conns), address)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(pool.inUse), address)
+		ch <- prometheus.MustNewConstMetric(c.checkouts, prometheus.CounterValue, float64(pool.checkouts), address)
+		ch <- prometheus.MustNewConstMetric(c.cleared, prometheus.CounterValue, float64(pool.cleared), address)
+		for reason, n := range pool.checkoutFailures {
+			ch <- prometheus.MustNewConstMetric(c.checkoutFailures, prometheus.CounterValue, float64(n), address, reason)
+		}
+	}
+}
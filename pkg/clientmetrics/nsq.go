@@ -0,0 +1,98 @@
+package clientmetrics
+
+import (
+	"github.com/nsqio/go-nsq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// nsqPublished counts the messages published by the NSQ producers, which
+// expose no statistics.
+var nsqPublished = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "nsq_producer_messages_total",
+		Help: "Total number of messages published to NSQ, by result",
+	},
+	[]string{ClientLabel, "address", "topic", "result"},
+)
+
+// ObserveNSQPublish records a message published by an NSQ producer.
+//
+// Parameters:
+//   - client: The name of the client instance, e.g. "nsq-producer".
+//   - address: The address of the nsqd the producer is connected to.
+//   - topic: The topic of the message.
+//   - err: The error of the publication, nil if it succeeded.
+func ObserveNSQPublish(client, address, topic string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	nsqPublished.WithLabelValues(client, address, topic, result).Inc()
+}
+
+// NSQConsumerCollector exports the statistics of an NSQ consumer.
+type NSQConsumerCollector struct {
+	consumer *nsq.Consumer
+
+	received    *prometheus.Desc
+	finished    *prometheus.Desc
+	requeued    *prometheus.Desc
+	connections *prometheus.Desc
+	inFlight    *prometheus.Desc
+}
+
+// NewNSQConsumerCollector creates a collector of the statistics of an NSQ
+// consumer.
+//
+// A consumer falls behind when nsq_consumer_messages_received_total grows
+// faster than nsq_consumer_messages_finished_total, or when
+// nsq_consumer_messages_requeued_total grows.
+//
+// Parameters:
+//   - client: The name of the client instance, e.g. "nsq-consumer".
+//   - consumer: The NSQ consumer.
+//
+// Returns:
+//   - *NSQConsumerCollector: The collector.
+func NewNSQConsumerCollector(client string, consumer *nsq.Consumer) *NSQConsumerCollector {
+	return &NSQConsumerCollector{
+		consumer: consumer,
+		received: newDesc(client, "nsq_consumer_messages_received_total",
+			"Total number of messages received"),
+		finished: newDesc(client, "nsq_consumer_messages_finished_total",
+			"Total number of messages finished"),
+		requeued: newDesc(client, "nsq_consumer_messages_requeued_total",
+			"Total number of messages requeued"),
+		connections: newDesc(client, "nsq_consumer_connections",
+			"Number of connections to nsqd"),
+		inFlight: newDesc(client, "nsq_consumer_messages_in_flight",
+			"Number of messages received and not finished or requeued yet"),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *NSQConsumerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.received
+	ch <- c.finished
+	ch <- c.requeued
+	ch <- c.connections
+	ch <- c.inFlight
+}
+
+// Collect implements prometheus.Collector.
+func (c *NSQConsumerCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.consumer.Stats()
+
+	done := stats.MessagesFinished + stats.MessagesRequeued
+	var inFlight uint64
+	if stats.MessagesReceived > done {
+		inFlight = stats.MessagesReceived - done
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.received, prometheus.CounterValue, float64(stats.MessagesReceived))
+	ch <- prometheus.MustNewConstMetric(c.finished, prometheus.CounterValue, float64(stats.MessagesFinished))
+	ch <- prometheus.MustNewConstMetric(c.requeued, prometheus.CounterValue, float64(stats.MessagesRequeued))
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stats.Connections))
+	ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(inFlight))
+}
@@ -0,0 +1,85 @@
+package clientmetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisPool is a go-redis client exposing its pool statistics, e.g. a
+// *redis.Client or a *redis.ClusterClient.
+type RedisPool interface {
+	PoolStats() *redis.PoolStats
+}
+
+// RedisPoolCollector exports the pool statistics of a go-redis client.
+type RedisPoolCollector struct {
+	pool     RedisPool
+	poolSize int
+
+	maxConns   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+}
+
+// NewRedisPoolCollector creates a collector of the pool statistics of a
+// go-redis client.
+//
+// The statistics are read on each scrape. A pool is exhausted when
+// redis_pool_timeouts_total grows: no connection was available within the
+// pool timeout.
+//
+// Parameters:
+//   - client: The name of the client instance, e.g. "redis".
+//   - pool: The go-redis client.
+//   - poolSize: The maximum number of connections of the pool, exported as redis_pool_max_connections.
+//
+// Returns:
+//   - *RedisPoolCollector: The collector.
+func NewRedisPoolCollector(client string, pool RedisPool, poolSize int) *RedisPoolCollector {
+	return &RedisPoolCollector{
+		pool:     pool,
+		poolSize: poolSize,
+		maxConns: newDesc(client, "redis_pool_max_connections",
+			"Maximum number of connections of the pool"),
+		totalConns: newDesc(client, "redis_pool_connections",
+			"Number of connections in the pool, both in use and idle"),
+		idleConns: newDesc(client, "redis_pool_idle_connections",
+			"Number of idle connections in the pool"),
+		staleConns: newDesc(client, "redis_pool_stale_connections_total",
+			"Total number of stale connections removed from the pool"),
+		hits: newDesc(client, "redis_pool_hits_total",
+			"Total number of times a free connection was found in the pool"),
+		misses: newDesc(client, "redis_pool_misses_total",
+			"Total number of times a free connection was not found in the pool"),
+		timeouts: newDesc(client, "redis_pool_timeouts_total",
+			"Total number of times waiting for a connection timed out"),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *RedisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxConns
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+}
+
+// Collect implements prometheus.Collector.
+func (c *RedisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.pool.PoolStats()
+
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(c.poolSize))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns))
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+}
@@ -0,0 +1,87 @@
+package clientmetrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DBStatsCollector exports the sql.DBStats of a database/sql pool, e.g. the
+// MySQL, PostgreSQL, ClickHouse or TDengine one.
+type DBStatsCollector struct {
+	db *sql.DB
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// NewDBStatsCollector creates a collector of the statistics of db.
+//
+// The statistics are read on each scrape. A pool is exhausted when
+// db_pool_in_use_connections reaches db_pool_max_open_connections, which
+// makes db_pool_wait_count_total grow.
+//
+// Parameters:
+//   - client: The name of the client instance, e.g. "mysql".
+//   - db: The database pool.
+//
+// Returns:
+//   - *DBStatsCollector: The collector.
+func NewDBStatsCollector(client string, db *sql.DB) *DBStatsCollector {
+	return &DBStatsCollector{
+		db: db,
+		maxOpen: newDesc(client, "db_pool_max_open_connections",
+			"Maximum number of open connections to the database"),
+		open: newDesc(client, "db_pool_open_connections",
+			"Number of established connections, both in use and idle"),
+		inUse: newDesc(client, "db_pool_in_use_connections",
+			"Number of connections currently in use"),
+		idle: newDesc(client, "db_pool_idle_connections",
+			"Number of idle connections"),
+		waitCount: newDesc(client, "db_pool_wait_count_total",
+			"Total number of connections waited for"),
+		waitDuration: newDesc(client, "db_pool_wait_duration_seconds_total",
+			"Total time blocked waiting for a new connection"),
+		maxIdleClosed: newDesc(client, "db_pool_max_idle_closed_total",
+			"Total number of connections closed due to SetMaxIdleConns"),
+		maxIdleTimeClosed: newDesc(client, "db_pool_max_idle_time_closed_total",
+			"Total number of connections closed due to SetConnMaxIdleTime"),
+		maxLifetimeClosed: newDesc(client, "db_pool_max_lifetime_closed_total",
+			"Total number of connections closed due to SetConnMaxLifetime"),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *DBStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector.
+func (c *DBStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
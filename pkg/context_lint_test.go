@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestNoPackageLevelContext ensures that no client package under pkg/ keeps a
+// package-level context such as `var ctx = context.Background()`.
+//
+// Client functions must accept the caller's context (usually the request
+// context) so that deadlines set by the timeout middleware and client
+// cancellations reach Redis, etcd, Elasticsearch and the other backends.
+func TestNoPackageLevelContext(t *testing.T) {
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				for _, value := range spec.(*ast.ValueSpec).Values {
+					if isRootContextCall(value) {
+						t.Errorf("%s: package-level context.Background()/context.TODO() is not allowed, accept a ctx parameter instead",
+							fset.Position(value.Pos()))
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk pkg directory: %v", err)
+	}
+}
+
+// isRootContextCall reports whether expr is a call to context.Background or
+// context.TODO.
+func isRootContextCall(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || pkg.Name != "context" {
+		return false
+	}
+	return sel.Sel.Name == "Background" || sel.Sel.Name == "TODO"
+}
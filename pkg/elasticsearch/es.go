@@ -7,12 +7,11 @@ import (
 	"time"
 
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
 
 	"github.com/olivere/elastic/v7"
 )
 
-var ctx = context.Background()
-
 type Test struct {
 	Title     string `json:"title"`
 	CreatedAt string `json:"created_at"`
@@ -27,7 +26,7 @@ type Test struct {
 // Finally, it executes the request and checks for errors.
 //
 // Returns an error if the insertion operation fails, otherwise nil.
-func Insert() error {
+func Insert(ctx context.Context) error {
 	// Construct the index name using the current date
 	indexName := "products" + time.Now().Format("20060102")
 
@@ -43,8 +42,9 @@ func Insert() error {
 	// Add the document to the bulk request
 	req.Add(doc)
 
-	// Execute the insert request and retrieve the response
-	_, err := req.Do(ctx)
+	// Execute the insert request under the Elasticsearch policy, once: the
+	// bulk create is not idempotent
+	_, err := resilience.DoWrite(ctx, resource.Resilience.Get("elasticsearch"), req.Do)
 	if err != nil {
 		return err
 	}
@@ -62,7 +62,7 @@ func Insert() error {
 // If the search operation fails, it returns an error, otherwise nil.
 //
 // The method returns the first document found in the index, if any.
-func Search() error {
+func Search(ctx context.Context) error {
 	// The name of the index to search
 	indexName := "products*"
 	// Create a search request with the specified index name and sorts
@@ -72,8 +72,9 @@ func Search() error {
 		Sort("created_at", false).
 		Size(1)
 
-	// Execute the search request and retrieve the response
-	res, err := req.Do(ctx)
+	// Execute the search request under the Elasticsearch policy and retrieve
+	// the response
+	res, err := resilience.Do(ctx, resource.Resilience.Get("elasticsearch"), req.Do)
 	if err != nil {
 		return err
 	}
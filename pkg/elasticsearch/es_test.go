@@ -72,7 +72,7 @@ func init() {
 // occurs, it logs an error message with the test name and the error.
 // Otherwise, it logs a success message with the test name.
 func TestInsert(t *testing.T) {
-	err := Insert()
+	err := Insert(context.Background())
 	if err != nil {
 		// Log an error if there is an error
 		t.Errorf("%s: %v", "TestInsert", err)
@@ -89,7 +89,7 @@ func TestInsert(t *testing.T) {
 // Otherwise, it logs a success message with the test name.
 func TestSearch(t *testing.T) {
 	// Call the Search function to execute the search operation
-	err := Search()
+	err := Search(context.Background())
 	if err != nil {
 		// Log an error if there is an error
 		t.Errorf("%s: %v", "TestSearch", err)
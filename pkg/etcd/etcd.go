@@ -9,14 +9,18 @@ import (
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
-var ctx = context.Background()
-
 // PutValue puts a value into etcd.
 //
-// It creates a background context and puts the value into etcd with the key
-// "test". If the put operation is successful, it returns nil. Otherwise, it
-// returns an error.
-func PutValue() error {
+// It puts the value into etcd with the key "test" within the given context.
+// If the put operation is successful, it returns nil. Otherwise, it returns
+// an error.
+//
+// Parameters:
+//   - ctx: The context of the request, canceling it once done.
+//
+// Returns:
+//   - error: An error if the value cannot be put.
+func PutValue(ctx context.Context) error {
 	// Put the value into etcd
 	_, err := resource.EtcdClient.Put(ctx, "test", "test")
 	if err != nil {
@@ -28,10 +32,16 @@ func PutValue() error {
 
 // GetValue gets a value from etcd.
 //
-// It creates a background context and retrieves the value specified by the
-// key from etcd. If the retrieval is successful, it prints out the value.
+// It retrieves the value specified by the key from etcd within the given
+// context. If the retrieval is successful, it prints out the value.
 // Otherwise, it returns an error.
-func GetValue() error {
+//
+// Parameters:
+//   - ctx: The context of the request, canceling it once done.
+//
+// Returns:
+//   - error: An error if the value cannot be retrieved.
+func GetValue(ctx context.Context) error {
 	// Get the value from etcd
 	resp, err := resource.EtcdClient.Get(ctx, "test")
 	if err != nil {
@@ -48,10 +58,16 @@ func GetValue() error {
 
 // DeleteValue deletes a value from etcd.
 //
-// It creates a background context and deletes the value specified by the key
-// from etcd. If the deletion is successful, it returns nil. Otherwise, it
+// It deletes the value specified by the key from etcd within the given
+// context. If the deletion is successful, it returns nil. Otherwise, it
 // returns an error.
-func DeleteValue() error {
+//
+// Parameters:
+//   - ctx: The context of the request, canceling it once done.
+//
+// Returns:
+//   - error: An error if the value cannot be deleted.
+func DeleteValue(ctx context.Context) error {
 
 	// Delete the value from etcd
 	_, err := resource.EtcdClient.Delete(ctx, "test")
@@ -67,7 +83,13 @@ func DeleteValue() error {
 // It grants a lease with a TTL of 10 seconds and puts a key-value pair into etcd
 // with the lease attached. It logs an error if any operation fails and returns the error.
 // On success, it prints a message indicating the lease will expire in 10 seconds.
-func Lease() error {
+//
+// Parameters:
+//   - ctx: The context of the requests, canceling them once done.
+//
+// Returns:
+//   - error: An error if the lease cannot be granted or the key cannot be put.
+func Lease(ctx context.Context) error {
 	// Grant a lease with a TTL of 10 seconds
 	leaseResp, err := resource.EtcdClient.Grant(ctx, 10)
 	if err != nil {
@@ -95,7 +117,13 @@ func Lease() error {
 // This function creates a watcher on the key "test" and continuously monitors for events.
 // The events can include PUT, DELETE, and other types of changes to the key. Each detected
 // event is printed with its type and associated key-value information.
-func Watch() error {
+//
+// Parameters:
+//   - ctx: The context of the watch, stopping it once done.
+//
+// Returns:
+//   - error: Always nil, the watch ends when the context is done.
+func Watch(ctx context.Context) error {
 	// Start watching the key "test" for changes
 	watchChan := resource.EtcdClient.Watch(ctx, "test")
 
@@ -119,7 +147,13 @@ func Watch() error {
 // If the key does not exist, the transaction will succeed and the key will be
 // created. If the key already exists, the transaction will fail and the key
 // will not be modified.
-func Txn() error {
+//
+// Parameters:
+//   - ctx: The context of the transaction, canceling it once done.
+//
+// Returns:
+//   - error: An error if the transaction fails or the key already exists.
+func Txn(ctx context.Context) error {
 	// The key to lock
 	muteKey := "test-Lock"
 
@@ -76,7 +76,7 @@ func init() {
 // occurs, it logs an error message with the test name and the error.
 // Otherwise, it logs a success message with the test name.
 func TestPutValue(t *testing.T) {
-	err := PutValue()
+	err := PutValue(context.Background())
 	if err != nil {
 		t.Errorf("%s: %v", "TestPutValue", err)
 	} else {
@@ -90,7 +90,7 @@ func TestPutValue(t *testing.T) {
 // occurs, it logs an error message with the test name and the error.
 // Otherwise, it logs a success message with the test name.
 func TestGetValue(t *testing.T) {
-	err := GetValue()
+	err := GetValue(context.Background())
 	if err != nil {
 		t.Errorf("%s: %v", "TestGetValue", err)
 	} else {
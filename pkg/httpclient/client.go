@@ -0,0 +1,66 @@
+package httpclient
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
+)
+
+// Transport is an http.RoundTripper that propagates the request ID found in
+// the request context as the X-Request-ID header of outgoing requests.
+type Transport struct {
+	// Base is the underlying RoundTripper, http.DefaultTransport if nil.
+	Base http.RoundTripper
+}
+
+// NewTransport wraps base with request ID propagation.
+//
+// Parameters:
+//   - base: The underlying RoundTripper, http.DefaultTransport if nil.
+//
+// Returns:
+//   - *Transport: The wrapping transport.
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper.
+//
+// The header is only set when the request does not carry one already, and the
+// request is cloned as required by the RoundTripper contract.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	requestID := common.RequestIDFromContext(req.Context())
+	if requestID == "" || req.Header.Get(common.RequestIDHeader) != "" {
+		return base.RoundTrip(req)
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Header.Set(common.RequestIDHeader, requestID)
+	return base.RoundTrip(clone)
+}
+
+// NewClient returns an http.Client propagating the request ID and the trace
+// context of the request context to downstream services, with a client span
+// for each call.
+//
+// Use http.NewRequestWithContext with the context of the incoming request so
+// that the request ID and the trace can be found.
+//
+// Parameters:
+//   - timeout: The client timeout, zero means no timeout.
+//
+// Returns:
+//   - *http.Client: The HTTP client.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: NewTransport(tracing.NewTransport(nil)),
+		Timeout:   timeout,
+	}
+}
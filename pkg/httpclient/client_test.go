@@ -0,0 +1,40 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+)
+
+// TestClientPropagatesRequestID tests that the request ID of the context is
+// sent to the downstream service.
+func TestClientPropagatesRequestID(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(common.RequestIDHeader)
+	}))
+	defer srv.Close()
+
+	ctx := common.WithRequestID(context.Background(), "req-1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequestWithContext() error = %v", err)
+	}
+
+	res, err := NewClient(5 * time.Second).Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	res.Body.Close()
+
+	if got != "req-1" {
+		t.Errorf("X-Request-ID = %q, want %q", got, "req-1")
+	}
+	if req.Header.Get(common.RequestIDHeader) != "" {
+		t.Error("original request must not be modified")
+	}
+}
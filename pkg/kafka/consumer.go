@@ -1,19 +1,31 @@
 package kafka
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 
-	"github.com/xiebingnote/go-gin-project/library/common"
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
 	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
 )
 
-// Consumer consumes messages from the specified topic and partition.
+// Consumer consumes the messages of all the partitions of the consumer
+// topic.
 //
-// It will block until the end of the topic/partition is reached.
+// The offsets are tracked for the consumer group ID: a partition is
+// consumed from its committed offset, or from the configured start position
+// if it has none, and the offset of a message is committed once it is
+// processed successfully.
 //
-// It will automatically commit the messages it has processed.
+// It blocks until the partition consumers are closed.
 //
 // Parameters:
 //   - None
@@ -21,8 +33,67 @@ import (
 // Returns:
 //   - An error if consumer creation fails.
 func Consumer() error {
-	// Get a partition consumer for the specified topic and partition
-	partitionConsumer, err := resource.KafkaConsumer.ConsumePartition(config.KafkaConfig.Kafka.ConsumerTopic, 0, 0)
+	cfg := config.KafkaConfig
+	if resource.KafkaClient == nil || resource.KafkaConsumer == nil {
+		return errors.New("kafka is not initialized")
+	}
+
+	position, err := ParseStartPosition(cfg.Advanced.ConsumerStartPosition)
+	if err != nil {
+		return err
+	}
+
+	topic := cfg.Kafka.ConsumerTopic
+	partitions, err := resource.KafkaClient.Partitions(topic)
+	if err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("Kafka consumer error: %v", err))
+		return err
+	}
+
+	offsets, err := sarama.NewOffsetManagerFromClient(cfg.Kafka.GroupID, resource.KafkaClient)
+	if err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("Kafka consumer error: %v", err))
+		return err
+	}
+	defer offsets.Close()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(partitions))
+	for i, partition := range partitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = consumePartition(offsets, position, topic, partition)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// consumePartition consumes the messages of a partition from its committed
+// offset, or from the start position, committing the offset of each message
+// processed successfully.
+func consumePartition(offsets sarama.OffsetManager, position StartPosition, topic string, partition int32) error {
+	pom, err := offsets.ManagePartition(topic, partition)
+	if err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("Kafka consumer error: %v", err))
+		return err
+	}
+	defer pom.Close()
+
+	// A negative offset is the initial offset of a partition without
+	// committed offset
+	offset, _ := pom.NextOffset()
+	if offset < 0 {
+		if offset, err = position.Offset(resource.KafkaClient, topic, partition); err != nil {
+			resource.LoggerService.Error(fmt.Sprintf("Kafka consumer error: %v", err))
+			return err
+		}
+	}
+
+	// Get a partition consumer for the topic and partition
+	partitionConsumer, err := resource.KafkaConsumer.ConsumePartition(topic, partition, offset)
 	if err != nil {
 		// Log an error if consumer creation fails
 		resource.LoggerService.Error(fmt.Sprintf("Kafka consumer error: %v", err))
@@ -32,18 +103,35 @@ func Consumer() error {
 
 	// Iterate over the messages in the partition
 	for msg := range partitionConsumer.Messages() {
+		// Restore the request ID and trace propagated by the producer
+		ctx, span := StartConsumerSpan(context.Background(), msg)
+		log := logger.WithContext(ctx, resource.KafkaLogger)
+
 		// Deserialize the message
-		data, err := common.DeSerializeData(msg.Value, &pkgproto.TestMessage{})
+		_, err := decodeValue(ctx, msg.Value, &pkgproto.TestMessage{})
 		if err != nil {
 			// Log an error if deserialization fails
-			resource.LoggerService.Error(fmt.Sprintf("Kafka consumer error: %v", err))
+			log.Error(fmt.Sprintf("Kafka consumer error: %v", err))
+			tracing.EndSpan(span, err)
 			continue
 		}
-		// Print the deserialized message
-		fmt.Println("data:", data)
-		// Log the message
-		resource.LoggerService.Info(fmt.Sprintf("Message received: Topic=%s Partition=%d Offset=%d Key=%v Value=%v",
-			msg.Topic, msg.Partition, msg.Offset, string(msg.Key), string(msg.Value)))
+		// Log the message metadata, the value may hold sensitive data and is
+		// only logged by its size
+		log.Info("Message received",
+			zap.String("topic", msg.Topic),
+			zap.Int32("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.ByteString("key", msg.Key),
+			zap.Int("value_size", len(msg.Value)),
+		)
+
+		// Commit the offset of the processed message
+		pom.MarkOffset(msg.Offset+1, "")
+		offsets.Commit()
+
+		clientmetrics.ObserveKafkaConsumerLag(clientmetrics.ClientKafkaConsumer, "", msg.Topic, msg.Partition,
+			partitionConsumer.HighWaterMarkOffset(), msg.Offset)
+		tracing.EndSpan(span, nil)
 	}
 
 	return nil
@@ -2,91 +2,713 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"time"
 
-	"github.com/xiebingnote/go-gin-project/library/common"
 	"github.com/xiebingnote/go-gin-project/library/resource"
-	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
 
 	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
-// ExampleConsumerGroupHandler is a consumer group handler that implements
-type ExampleConsumerGroupHandler struct {
-	Ready chan bool
+// Default runner configuration.
+const (
+	DefaultMinReconnectBackoff = time.Second
+	DefaultMaxReconnectBackoff = 30 * time.Second
+)
+
+// Results of the processing of a message.
+const (
+	ResultSuccess     = "success"
+	ResultError       = "error"
+	ResultDecodeError = "decode_error"
+)
+
+// Actions taken on a failed message.
+const (
+	ActionRetried      = "retried"
+	ActionDeadLettered = "dead_lettered"
+	ActionDropped      = "dropped"
+)
+
+var (
+	// 消费的消息数
+	consumedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consumer_messages_total",
+			Help: "Total number of messages processed by the registered handlers, by result",
+		},
+		[]string{"group", "topic", "result"},
+	)
+
+	// 消息处理耗时
+	handleDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kafka_consumer_handle_duration_seconds",
+			Help:    "Time taken by the registered handlers to process a message",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"group", "topic"},
+	)
+
+	// 消费者组重连次数
+	reconnectTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consumer_reconnects_total",
+			Help: "Total number of consumer group sessions restarted after an error",
+		},
+		[]string{"group"},
+	)
+
+	// 处理失败的消息数
+	failuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consumer_failures_total",
+			Help: "Total number of messages whose handler failed, by action: retried, dead_lettered or dropped",
+		},
+		[]string{"group", "topic", "action"},
+	)
+)
+
+// RunnerConfig is the configuration of the consumer groups run by a
+// Registry.
+type RunnerConfig struct {
+	// Concurrency is the number of messages of a topic processed
+	// concurrently, for the handlers registered without WithConcurrency.
+	// Zero processes one message per assigned partition at a time.
+	Concurrency int
+	// MinReconnectBackoff is the delay before a consumer group session is
+	// restarted after an error, doubled after each consecutive error,
+	// DefaultMinReconnectBackoff if zero.
+	MinReconnectBackoff time.Duration
+	// MaxReconnectBackoff is the longest delay between the restarts,
+	// DefaultMaxReconnectBackoff if zero.
+	MaxReconnectBackoff time.Duration
+	// Producer sends the failed messages to the retry and the dead letter
+	// topics, required by the handlers having a retry policy. A message is
+	// sent again with the reconnect backoff until it succeeds.
+	Producer sarama.SyncProducer
+	// StartPosition is where the partitions without committed offset are
+	// consumed from when it is a time. The earliest and latest positions are
+	// set by the Consumer.Offsets.Initial option of the consumer groups.
+	StartPosition StartPosition
+	// Client resolves the offsets of a time start position, required if
+	// StartPosition is a time.
+	Client sarama.Client
+	// ManualCommit commits the offset of each message once processed,
+	// instead of periodically, for consumer groups whose auto commit is
+	// disabled.
+	ManualCommit bool
+	// ProcessedRetention is the time the idempotency keys of the exactly
+	// once handlers are kept for, DefaultProcessedRetention if zero.
+	ProcessedRetention time.Duration
 }
 
-// Setup is run at the beginning of a new session, before ConsumeClaim.
-// It closes the Ready channel to signal that the consumer is ready.
-func (h *ExampleConsumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
-	// Close the Ready channel to indicate readiness
-	close(h.Ready)
+// GroupFactory creates the consumer group of a group ID.
+type GroupFactory func(group string) (sarama.ConsumerGroup, error)
+
+// Start starts a consumer group per group ID having a handler, consuming
+// all the topics of its handlers until Stop is called.
+//
+// The messages of a partition are processed one by one, in order, and the
+// number of messages of a topic processed concurrently across partitions is
+// bounded by its concurrency. A session ended by an error, e.g. a broker
+// disconnection, is restarted with an exponential backoff. The retry topics
+// of the handlers having a retry policy are consumed as well.
+//
+// The tables of the exactly once handlers are created in their databases,
+// and the partitions of these handlers are consumed from the offsets
+// stored in the databases.
+//
+// Parameters:
+//   - newGroup: The factory of the consumer groups.
+//   - cfg: The runner configuration.
+//
+// Returns:
+//   - error: An error if the registry is started, a retry policy has no
+//     producer, a time start position has no client, the database of an
+//     exactly once handler cannot be migrated or a consumer group cannot be
+//     created, in which case no consumer is started.
+func (r *Registry) Start(newGroup GroupFactory, cfg RunnerConfig) error {
+	if cfg.MinReconnectBackoff <= 0 {
+		cfg.MinReconnectBackoff = DefaultMinReconnectBackoff
+	}
+	if cfg.MaxReconnectBackoff <= 0 {
+		cfg.MaxReconnectBackoff = DefaultMaxReconnectBackoff
+	}
+	cfg.MaxReconnectBackoff = max(cfg.MaxReconnectBackoff, cfg.MinReconnectBackoff)
+	if cfg.ProcessedRetention <= 0 {
+		cfg.ProcessedRetention = DefaultProcessedRetention
+	}
+	if cfg.StartPosition.IsTime() && cfg.Client == nil {
+		return fmt.Errorf("kafka start position %s requires a client", cfg.StartPosition)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return ErrRegistryStarted
+	}
+	if cfg.Producer == nil {
+		for _, topics := range r.handlers {
+			for topic, h := range topics {
+				if h.retry != nil {
+					return fmt.Errorf("kafka handler of topic %s, group %s has a retry policy but no producer", topic, h.group)
+				}
+			}
+		}
+	}
+
+	dbs, err := r.migrate()
+	if err != nil {
+		return err
+	}
+
+	runners := make([]*groupRunner, 0, len(r.handlers))
+	for group, topics := range r.handlers {
+		cg, err := newGroup(group)
+		if err != nil {
+			for _, runner := range runners {
+				_ = runner.consumerGroup.Close()
+			}
+			return fmt.Errorf("failed to create kafka consumer group %s: %w", group, err)
+		}
+		runner := newGroupRunner(group, cg, topics, cfg)
+		for _, route := range runner.routes {
+			route.db = dbs[route.handler]
+		}
+		runners = append(runners, runner)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.groups = r.groups[:0]
+	for _, runner := range runners {
+		r.groups = append(r.groups, runner.consumerGroup)
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			runner.run(ctx)
+		}()
+		if runner.exactlyOnce() {
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				runner.purge(ctx)
+			}()
+		}
+	}
 	return nil
 }
 
-// Cleanup is called once all ConsumeClaim goroutines have exited.
-// It is a last chance to clean up any resources, but it is not
-// a guarantee that it will be called in all cases (e.g., if the
-// process is killed).
-func (h *ExampleConsumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
-	return nil
+// migrate resolves the databases of the exactly once handlers and creates
+// their tables, the caller holding r.mu.
+//
+// Returns:
+//   - map[*handler]*gorm.DB: The databases of the exactly once handlers.
+//   - error: An error if a database is not initialized or cannot be
+//     migrated.
+func (r *Registry) migrate() (map[*handler]*gorm.DB, error) {
+	dbs := make(map[*handler]*gorm.DB)
+	migrated := make(map[*gorm.DB]bool)
+	for _, topics := range r.handlers {
+		for topic, h := range topics {
+			if h.txDB == nil {
+				continue
+			}
+			db := h.txDB()
+			if db == nil {
+				return nil, fmt.Errorf("database of the exactly once kafka handler of topic %s, group %s is not initialized", topic, h.group)
+			}
+			if !migrated[db] {
+				if err := MigrateExactlyOnce(context.Background(), db); err != nil {
+					return nil, err
+				}
+				migrated[db] = true
+			}
+			dbs[h] = db
+		}
+	}
+	return dbs, nil
 }
 
-// ConsumeClaim is called once for each consumer claim being consumed.
-// A claim is a unique partition of a topic that the consumer is responsible
-// for consuming. The claim is closed when the consumer is done consuming.
+// Stop stops the consumer groups, waiting for the messages being processed,
+// and closes them. It does nothing if the registry is not started.
 //
-// This function will be called for each message in the partition until the
-// message queue is empty, at which point the claim will be closed.
-func (h *ExampleConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	// Iterate over the messages in the partition
-	for message := range claim.Messages() {
-		data, err := common.DeSerializeData(message.Value, &pkgproto.TestMessage{})
-		if err != nil {
-			resource.LoggerService.Error(fmt.Sprintf("Kafka consumer error: %v", err))
+// Returns:
+//   - error: The errors closing the consumer groups.
+func (r *Registry) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel == nil {
+		return nil
+	}
+
+	r.cancel()
+	r.wg.Wait()
+
+	var errs []error
+	for _, cg := range r.groups {
+		if err := cg.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	r.cancel, r.groups = nil, nil
+	return errors.Join(errs...)
+}
+
+// groupRunner consumes the topics of a consumer group, it implements
+// sarama.ConsumerGroupHandler.
+type groupRunner struct {
+	group         string
+	consumerGroup sarama.ConsumerGroup
+	topics        []string
+	routes        map[string]*route // by topic
+	cfg           RunnerConfig
+}
+
+// topicHandler is a handler with the slots bounding its concurrency.
+type topicHandler struct {
+	*handler
+	slots chan struct{} // nil if the concurrency is not limited
+	db    *gorm.DB      // nil if the handler is not exactly once
+}
+
+// route is the handler of a topic consumed by a group, the topic of the
+// handler or one of its retry topics.
+type route struct {
+	*topicHandler
+	attempt int // the number of failed attempts of the messages of the topic
+}
+
+// newGroupRunner creates the runner of a consumer group.
+func newGroupRunner(group string, cg sarama.ConsumerGroup, handlers map[string]*handler, cfg RunnerConfig) *groupRunner {
+	runner := &groupRunner{
+		group:         group,
+		consumerGroup: cg,
+		routes:        make(map[string]*route, len(handlers)),
+		cfg:           cfg,
+	}
+	for topic, h := range handlers {
+		th := &topicHandler{handler: h}
+		concurrency := h.concurrency
+		if concurrency <= 0 {
+			concurrency = cfg.Concurrency
+		}
+		if concurrency > 0 {
+			th.slots = make(chan struct{}, concurrency)
+		}
+		runner.topics = append(runner.topics, topic)
+		runner.routes[topic] = &route{topicHandler: th}
+		if h.retry != nil {
+			for i, delay := range h.retry.Delays {
+				retryTopic := RetryTopic(topic, delay)
+				runner.topics = append(runner.topics, retryTopic)
+				runner.routes[retryTopic] = &route{topicHandler: th, attempt: i + 1}
+			}
+		}
+	}
+	return runner
+}
+
+// run consumes the topics until ctx is canceled, restarting the sessions
+// ended by an error with an exponential backoff.
+func (g *groupRunner) run(ctx context.Context) {
+	log := resource.KafkaLogger
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	backoff := g.cfg.MinReconnectBackoff
+	for {
+		// Consume returns at the end of each session, e.g. on a rebalance
+		err := g.consumerGroup.Consume(ctx, g.topics, g)
+		if ctx.Err() != nil || errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			return
+		}
+		if err == nil {
+			backoff = g.cfg.MinReconnectBackoff
 			continue
 		}
-		fmt.Println(data)
 
-		// Log the message
-		resource.LoggerService.Info(fmt.Sprintf("Message received: Topic=%s Partition=%d Offset=%d Key=%s Value=%s",
-			message.Topic, message.Partition, message.Offset,
-			string(message.Key), string(message.Value)))
+		reconnectTotal.WithLabelValues(g.group).Inc()
+		log.Error("Kafka consumer group session failed, reconnecting",
+			zap.String("group", g.group),
+			zap.Strings("topics", g.topics),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		if !sleep(ctx, backoff) {
+			return
+		}
+		backoff = min(backoff*2, g.cfg.MaxReconnectBackoff)
+	}
+}
+
+// exactlyOnce reports whether the group has an exactly once handler.
+func (g *groupRunner) exactlyOnce() bool {
+	for _, route := range g.routes {
+		if route.db != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// purge removes the expired idempotency keys of the group until ctx is
+// canceled.
+func (g *groupRunner) purge(ctx context.Context) {
+	log := resource.KafkaLogger
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	for {
+		purged := make(map[*gorm.DB]bool)
+		for _, route := range g.routes {
+			if route.db == nil || purged[route.db] {
+				continue
+			}
+			purged[route.db] = true
+			if err := purgeProcessed(ctx, route.db, g.group, g.cfg.ProcessedRetention); err != nil && ctx.Err() == nil {
+				log.Error("Failed to purge kafka idempotency keys", zap.String("group", g.group), zap.Error(err))
+			}
+		}
+		if !sleep(ctx, processedPurgeInterval) {
+			return
+		}
+	}
+}
+
+// Setup implements sarama.ConsumerGroupHandler, seeking the claimed
+// partitions of the exactly once handlers to their stored offsets, and the
+// partitions without committed offset to the time start position.
+func (g *groupRunner) Setup(session sarama.ConsumerGroupSession) error {
+	pending := make(map[string][]int32)
+	for topic, partitions := range session.Claims() {
+		h, ok := g.routes[topic]
+		if !ok {
+			continue
+		}
+		stored := map[int32]int64{}
+		if h.db != nil {
+			var err error
+			if stored, err = loadOffsets(session.Context(), h.db, g.group, topic, partitions); err != nil {
+				return err
+			}
+		}
+		for _, partition := range partitions {
+			if offset, ok := stored[partition]; ok {
+				seek(session, topic, partition, offset)
+			} else if g.cfg.StartPosition.IsTime() {
+				pending[topic] = append(pending[topic], partition)
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
 
-		// Mark the message as processed
-		session.MarkMessage(message, "")
+	committed, err := committedOffsets(g.cfg.Client, g.group, pending)
+	if err != nil {
+		return err
+	}
+	for topic, partitions := range pending {
+		for _, partition := range partitions {
+			if _, ok := committed[topic][partition]; ok {
+				continue
+			}
+			offset, err := g.cfg.StartPosition.Offset(g.cfg.Client, topic, partition)
+			if err != nil {
+				return err
+			}
+			seek(session, topic, partition, offset)
+		}
 	}
 	return nil
 }
 
-// StartKafkaConsumer starts a Kafka consumer in a separate goroutine,
-// consuming messages from the given topics with the given handler.
+// Cleanup implements sarama.ConsumerGroupHandler.
+func (g *groupRunner) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler, processing the
+// messages of a partition one by one, in order. The messages of a retry
+// topic are processed once their delay elapsed. It returns once the claim
+// is closed or the session ends.
+func (g *groupRunner) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	h, ok := g.routes[claim.Topic()]
+	if !ok {
+		return fmt.Errorf("no kafka handler for topic %s, group %s", claim.Topic(), g.group)
+	}
+
+	for {
+		select {
+		case <-session.Context().Done():
+			return nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if h.attempt > 0 {
+				// The retry topics are shared by the groups of the topic
+				if string(header(msg.Headers, HeaderConsumerGroup)) != g.group {
+					session.MarkMessage(msg, "")
+					continue
+				}
+				// The messages of a retry topic have the same delay, so that
+				// they are due in order
+				if !sleep(session.Context(), time.Until(retryAt(msg))) {
+					return nil
+				}
+			}
+			if !h.acquire(session.Context()) {
+				return nil
+			}
+			done := g.process(session, h, msg)
+			h.release()
+			if !done {
+				// The session ended while the message was processed, it is
+				// redelivered to the next owner of the partition
+				return nil
+			}
+			clientmetrics.ObserveKafkaConsumerLag(clientmetrics.ClientKafkaConsumer, g.group,
+				msg.Topic, msg.Partition, claim.HighWaterMarkOffset(), msg.Offset)
+		}
+	}
+}
+
+// process processes a message and marks it as consumed, once retried or
+// sent to the dead letter topic if it failed. The offset of a failed
+// message of an exactly once handler is then stored as well.
 //
-// It waits until the consumer is ready, and returns an error if the consumer fails
-// to start.
+// Returns:
+//   - bool: False if the session ended before the message was processed, in
+//     which case it is not marked.
+func (g *groupRunner) process(session sarama.ConsumerGroupSession, h *route, msg *sarama.ConsumerMessage) bool {
+	// Restore the request ID and trace propagated by the producer
+	ctx, span := StartConsumerSpan(session.Context(), msg)
+	log := logger.WithContext(ctx, resource.KafkaLogger)
+
+	meta := Meta{
+		Group:     g.group,
+		Topic:     h.topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Timestamp: msg.Timestamp,
+		Headers:   msg.Headers,
+		Attempt:   h.attempt,
+	}
+
+	start := time.Now()
+	var result string
+	var err error
+	if h.db != nil {
+		result, err = h.runTx(ctx, g.group, msg, meta)
+	} else {
+		result, err = h.run(ctx, msg.Value, meta)
+	}
+	handleDuration.WithLabelValues(g.group, msg.Topic).Observe(time.Since(start).Seconds())
+	consumedTotal.WithLabelValues(g.group, msg.Topic, result).Inc()
+	tracing.EndSpan(span, err)
+
+	if err != nil {
+		if session.Context().Err() != nil {
+			return false
+		}
+		if !g.fail(session.Context(), log, h, msg, result, err) {
+			return false
+		}
+		// The offset of the failed message was rolled back with its
+		// transaction, Setup would seek back to it once it is marked
+		if h.db != nil && !g.skip(session.Context(), log, h, msg) {
+			return false
+		}
+	}
+
+	session.MarkMessage(msg, "")
+	if g.cfg.ManualCommit {
+		session.Commit()
+	}
+	return true
+}
+
+// fail retries a failed message or sends it to the dead letter topic
+// according to the retry policy of its handler, or drops it.
 //
-// Parameters:
+// Returns:
+//   - bool: False if the session ended before the message was sent.
+func (g *groupRunner) fail(ctx context.Context, log *zap.Logger, h *route, msg *sarama.ConsumerMessage, result string, err error) bool {
+	attempt := h.attempt + 1
+	action := ActionDropped
+	var pm *sarama.ProducerMessage
+	switch policy := h.retry; {
+	case policy == nil:
+	case result != ResultDecodeError && !resilience.IsPermanent(err) && h.attempt < len(policy.Delays):
+		delay := policy.Delays[h.attempt]
+		pm = failureMessage(RetryTopic(h.topic, delay), g.group, msg, attempt, err, time.Now().Add(delay))
+		action = ActionRetried
+	case policy.DeadLetter:
+		pm = failureMessage(DeadLetterTopic(h.topic), g.group, msg, attempt, err, time.Time{})
+		action = ActionDeadLettered
+	}
+
+	if pm != nil && !g.send(ctx, log, pm) {
+		return false
+	}
+	failuresTotal.WithLabelValues(g.group, h.topic, action).Inc()
+
+	// The value may hold sensitive data and is only logged by its size
+	fields := []zap.Field{
+		zap.String("group", g.group),
+		zap.String("topic", msg.Topic),
+		zap.Int32("partition", msg.Partition),
+		zap.Int64("offset", msg.Offset),
+		zap.String("result", result),
+		zap.String("action", action),
+		zap.Int("attempt", attempt),
+		zap.Int("value_size", len(msg.Value)),
+		zap.Error(err),
+	}
+	if action == ActionRetried {
+		log.Warn("Kafka handler failed, message retried", append(fields, zap.String("retry_topic", pm.Topic))...)
+	} else {
+		log.Error("Kafka handler failed", fields...)
+	}
+	return true
+}
+
+// send sends a message to a retry or the dead letter topic, again with the
+// reconnect backoff until it succeeds.
 //
-//	topics: The topics to consume from.
-//	handler: The handler that will process the messages.
+// Returns:
+//   - bool: False if ctx is done first.
+func (g *groupRunner) send(ctx context.Context, log *zap.Logger, pm *sarama.ProducerMessage) bool {
+	backoff := g.cfg.MinReconnectBackoff
+	for {
+		_, _, err := g.cfg.Producer.SendMessage(pm)
+		if err == nil {
+			return true
+		}
+
+		log.Error("Failed to send the failed kafka message, retrying",
+			zap.String("topic", pm.Topic),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		if !sleep(ctx, backoff) {
+			return false
+		}
+		backoff = min(backoff*2, g.cfg.MaxReconnectBackoff)
+	}
+}
+
+// skip stores the next offset of a failed message of an exactly once
+// handler in its own transaction, once it is retried, dead lettered or
+// dropped, again with the reconnect backoff until it succeeds.
 //
 // Returns:
+//   - bool: False if ctx is done first.
+func (g *groupRunner) skip(ctx context.Context, log *zap.Logger, h *route, msg *sarama.ConsumerMessage) bool {
+	backoff := g.cfg.MinReconnectBackoff
+	for {
+		err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return saveOffset(tx, g.group, msg.Topic, msg.Partition, msg.Offset+1)
+		})
+		if err == nil {
+			return true
+		}
+
+		log.Error("Failed to store the offset of the failed kafka message, retrying",
+			zap.String("topic", msg.Topic),
+			zap.Int32("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		if !sleep(ctx, backoff) {
+			return false
+		}
+		backoff = min(backoff*2, g.cfg.MaxReconnectBackoff)
+	}
+}
+
+// run decodes a message and calls the handler, recovering from its panics.
 //
-//	An error if the consumer fails to start.
-func StartKafkaConsumer(topics []string, handler *ExampleConsumerGroupHandler) error {
-	// Start the consumer in a separate goroutine
-	go func() {
-		for {
-			// Consume messages from the specified topics
-			if err := resource.KafkaConsumerGroup.Consume(context.Background(), topics, handler); err != nil {
-				resource.LoggerService.Error(fmt.Sprintf("Kafka consumer error: %v", err))
-				return
-			}
+// Returns:
+//   - string: The result of the processing, e.g. ResultDecodeError.
+//   - error: The decoding or the handler error.
+func (h *topicHandler) run(ctx context.Context, value []byte, meta Meta) (result string, err error) {
+	msg, err := h.decode(ctx, value)
+	if err != nil {
+		return ResultDecodeError, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = ResultError, fmt.Errorf("kafka handler panic: %v\n%s", p, debug.Stack())
 		}
 	}()
 
-	//Wait until the consumer is ready
-	<-handler.Ready
+	if err := h.handle(ctx, msg, meta); err != nil {
+		return ResultError, err
+	}
+	return ResultSuccess, nil
+}
 
-	return nil
+// acquire waits for a processing slot of the handler.
+//
+// Returns:
+//   - bool: False if ctx is done first.
+func (h *topicHandler) acquire(ctx context.Context) bool {
+	if h.slots == nil {
+		return true
+	}
+	select {
+	case h.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release releases a processing slot of the handler.
+func (h *topicHandler) release() {
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+// sleep waits for d.
+//
+// Returns:
+//   - bool: False if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
 }
@@ -3,59 +3,44 @@ package kafka
 import (
 	"context"
 	"fmt"
-	"os"
-	"strings"
 	"testing"
 
 	"github.com/xiebingnote/go-gin-project/bootstrap/service"
 	"github.com/xiebingnote/go-gin-project/library/config"
+	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
 
-	"github.com/BurntSushi/toml"
+	"github.com/IBM/sarama"
 )
 
-// Init initializes the Kafka service by loading the configuration from
-// a TOML file and decoding it into the KafkaConfig struct.
-//
-// It then initializes the Kafka service with a background context.
-//
-// If there is an error getting the current working directory, or the
-// Kafka configuration file cannot be decoded, the function will panic
-// with an error message.
-func init() {
-	// Retrieve the current working directory
-	rootDir, err := os.Getwd()
-	if err != nil {
-		// Panic if there is an error getting the working directory
-		panic(err)
-	}
-
-	// Extract the root directory path by splitting on "/pkg"
-	dir := strings.Split(rootDir, "/pkg")
-	rootDir = dir[0]
-
-	// Load Kafka configuration from the specified TOML file
-	if _, err := toml.DecodeFile(rootDir+"/conf/service/kafka.toml", &config.KafkaConfig); err != nil {
-		// Panic if the MySQL configuration file cannot be decoded
-		panic("Failed to load Kafka configuration file: " + err.Error())
-	}
-
-	// Initialize the Kafka service with a background context
-	service.InitKafka(context.Background())
-}
-
 // TestConsumerGroup_Success tests the successful operation of a Kafka consumer group.
 //
-// It initializes a consumer group handler and starts the Kafka consumer with the
-// specified topic and handler. It then checks for errors, logging an error if the
+// It registers a handler of the test messages of the consumer group topics
+// and starts the consumer group, checking for errors, logging an error if the
 // consumer fails to start, otherwise logging a success message.
 func TestConsumerGroup_Success(t *testing.T) {
-	// Initialize the consumer group handler with a ready channel
-	handler := &ExampleConsumerGroupHandler{
-		Ready: make(chan bool),
+	requireKafka(t)
+
+	cfg := config.KafkaConfig
+	version, err := sarama.ParseKafkaVersion(cfg.Kafka.Version)
+	if err != nil {
+		t.Fatalf("Invalid Kafka version: %v", err)
 	}
 
-	// Start the Kafka consumer with the specified topic and handler
-	err := StartKafkaConsumer(config.KafkaConfig.Kafka.ConsumerGroupTopic, handler)
+	registry := NewRegistry()
+	for _, topic := range cfg.Kafka.ConsumerGroupTopic {
+		err := RegisterTo(registry, topic, cfg.Kafka.GroupID, func(ctx context.Context, msg *pkgproto.TestMessage, meta Meta) error {
+			fmt.Printf("Message received: %s/%d/%d id=%d\n", meta.Topic, meta.Partition, meta.Offset, msg.GetId())
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to register handler: %v", err)
+		}
+	}
+
+	// Start the Kafka consumer with the registered handlers
+	err = registry.Start(func(group string) (sarama.ConsumerGroup, error) {
+		return sarama.NewConsumerGroup(cfg.Kafka.Brokers, group, service.ConfigureKafkaConsumer(cfg, version))
+	}, RunnerConfig{})
 	if err != nil {
 		// Log an error if the consumer fails to start
 		t.Errorf("Failed to consumer message: %v", err)
@@ -63,4 +48,8 @@ func TestConsumerGroup_Success(t *testing.T) {
 		// Log a success message if the consumer starts successfully
 		fmt.Println("Message consumer successfully.")
 	}
+
+	if err := registry.Stop(); err != nil {
+		t.Errorf("Failed to stop consumer: %v", err)
+	}
 }
@@ -0,0 +1,286 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// ReplayGroup is the prefix of the consumer groups whose offsets track the
+// dead letters already replayed, e.g. "kafka-dlq-replay.billing" for the
+// billing group, so that a dead letter is replayed once.
+const ReplayGroup = "kafka-dlq-replay"
+
+// ErrHandlerNotFound is returned when no handler is registered for a topic
+// and a consumer group.
+var ErrHandlerNotFound = errors.New("kafka handler not registered")
+
+// ErrNoRetryTopic is returned when the dead letters of a handler are
+// replayed but its retry policy has no retry topic to replay them to.
+var ErrNoRetryTopic = errors.New("kafka handler has no retry topic")
+
+// replayIdleTimeout is the time waited for the next dead letter of a
+// partition before its replay ends, e.g. at a compacted offset.
+const replayIdleTimeout = 5 * time.Second
+
+// replayBatchSize is the number of dead letters sent at once by a replay.
+const replayBatchSize = 100
+
+// ReplayResult is the result of the replay of a dead letter topic.
+type ReplayResult struct {
+	Group           string `json:"group"`
+	Topic           string `json:"topic"`
+	DeadLetterTopic string `json:"dead_letter_topic"`
+	RetryTopic      string `json:"retry_topic"`
+	Replayed        int    `json:"replayed"`
+	Skipped         int    `json:"skipped"` // the dead letters of the other groups
+}
+
+// ReplayDeadLetters sends the dead letters of a consumer group on a topic
+// not replayed yet to the first retry topic of its handler, e.g. once the
+// bug failing them is fixed.
+//
+// The dead letter topic is shared by the groups of the topic, and so are
+// the retry topics, but each group only processes its own retries: the
+// dead letters are thus processed again by the failing group only, and not
+// by the groups which processed them successfully, as a replay to the topic
+// itself would. The dead letters keep their key and their original
+// position headers, so that their idempotency key, see IdempotencyKey, is
+// the key of the original message, and are due right away. The dead
+// letters of the other groups are skipped. The progress is committed as the
+// offsets of the group ReplayGroup + "." + group.
+//
+// Parameters:
+//   - ctx: The context of the replay, stopping it once done.
+//   - client: The Kafka client.
+//   - producer: The producer sending the dead letters to the retry topic.
+//   - group: The consumer group whose dead letters are replayed.
+//   - topic: The topic of the handler whose dead letters are replayed.
+//   - limit: The maximum number of dead letters replayed, zero for all.
+//
+// Returns:
+//   - ReplayResult: The number of dead letters replayed, also on error.
+//   - error: ErrHandlerNotFound if no handler is registered for the topic
+//     and the group, ErrNoRetryTopic if its retry policy has no retry topic,
+//     sarama.ErrUnknownTopicOrPartition if the topic has no dead letter
+//     topic, or an error if the dead letter topic cannot be read or a dead
+//     letter cannot be sent.
+func (r *Registry) ReplayDeadLetters(ctx context.Context, client sarama.Client, producer sarama.SyncProducer,
+	group, topic string, limit int) (ReplayResult, error) {
+	result := ReplayResult{Group: group, Topic: topic, DeadLetterTopic: DeadLetterTopic(topic)}
+
+	r.mu.Lock()
+	h := r.handlers[group][topic]
+	r.mu.Unlock()
+	if h == nil {
+		return result, fmt.Errorf("%w: topic %s, group %s", ErrHandlerNotFound, topic, group)
+	}
+	if h.retry == nil || len(h.retry.Delays) == 0 {
+		return result, fmt.Errorf("%w: topic %s, group %s", ErrNoRetryTopic, topic, group)
+	}
+	result.RetryTopic = RetryTopic(topic, h.retry.Delays[0])
+
+	partitions, err := client.Partitions(result.DeadLetterTopic)
+	if err != nil {
+		return result, err
+	}
+
+	offsets, err := sarama.NewOffsetManagerFromClient(ReplayGroup+"."+group, client)
+	if err != nil {
+		return result, fmt.Errorf("failed to create the offset manager: %w", err)
+	}
+	defer func() {
+		_ = offsets.Close()
+	}()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return result, fmt.Errorf("failed to create the consumer: %w", err)
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+
+	for _, partition := range partitions {
+		if limit > 0 && result.Replayed >= limit {
+			break
+		}
+		err := replayPartition(ctx, client, consumer, offsets, producer, &result, partition, limit-result.Replayed)
+		if err != nil {
+			offsets.Commit()
+			return result, err
+		}
+	}
+	offsets.Commit()
+	return result, nil
+}
+
+// replayPartition replays the dead letters of a partition of the dead letter
+// topic, up to its high water mark when the replay starts, adding them to
+// the counts of result.
+//
+// The dead letters are sent in batches of replayBatchSize with a single
+// SendMessages call, and the offset of the partition is marked once a batch
+// is sent.
+//
+// Returns:
+//   - error: An error if the partition cannot be read or a dead letter cannot
+//     be sent.
+func replayPartition(ctx context.Context, client sarama.Client, consumer sarama.Consumer, offsets sarama.OffsetManager,
+	producer sarama.SyncProducer, result *ReplayResult, partition int32, limit int) error {
+	dlq := result.DeadLetterTopic
+	pom, err := offsets.ManagePartition(dlq, partition)
+	if err != nil {
+		return fmt.Errorf("failed to manage the offset of partition %d: %w", partition, err)
+	}
+	defer func() {
+		_ = pom.Close()
+	}()
+
+	oldest, err := client.GetOffset(dlq, partition, sarama.OffsetOldest)
+	if err != nil {
+		return err
+	}
+	newest, err := client.GetOffset(dlq, partition, sarama.OffsetNewest)
+	if err != nil {
+		return err
+	}
+
+	// The dead letters removed by the retention are skipped
+	next, _ := pom.NextOffset()
+	next = max(next, oldest)
+	if next >= newest {
+		return nil
+	}
+
+	pc, err := consumer.ConsumePartition(dlq, partition, next)
+	if err != nil {
+		return fmt.Errorf("failed to consume partition %d: %w", partition, err)
+	}
+	defer func() {
+		_ = pc.Close()
+	}()
+
+	batch := &replayBatch{producer: producer, pom: pom, result: result, partition: partition}
+	replayed := 0
+	idle := time.NewTimer(replayIdleTimeout)
+	defer idle.Stop()
+
+	for limit <= 0 || replayed < limit {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-idle.C:
+			return batch.flush()
+		case msg, ok := <-pc.Messages():
+			if !ok {
+				return errors.Join(errors.New("dead letter consumer closed"), batch.flush())
+			}
+			if msg.Offset >= newest {
+				return batch.flush()
+			}
+
+			if string(header(msg.Headers, HeaderConsumerGroup)) == result.Group {
+				batch.add(replayMessage(result.RetryTopic, msg, time.Now()), msg.Offset)
+				replayed++
+			} else {
+				result.Skipped++
+			}
+			batch.next = msg.Offset + 1
+
+			if msg.Offset >= newest-1 {
+				return batch.flush()
+			}
+			if len(batch.msgs) >= replayBatchSize {
+				if err := batch.flush(); err != nil {
+					return err
+				}
+			}
+			idle.Reset(replayIdleTimeout)
+		}
+	}
+	return batch.flush()
+}
+
+// replayBatch is a batch of dead letters of a partition being replayed.
+type replayBatch struct {
+	producer  sarama.SyncProducer
+	pom       sarama.PartitionOffsetManager
+	result    *ReplayResult
+	partition int32
+
+	msgs []*sarama.ProducerMessage // the metadata of a message is the offset of its dead letter
+	next int64                     // the offset marked once the batch is sent
+}
+
+// add adds the message replaying the dead letter at offset to the batch.
+func (b *replayBatch) add(msg *sarama.ProducerMessage, offset int64) {
+	msg.Metadata = offset
+	b.msgs = append(b.msgs, msg)
+}
+
+// flush sends the messages of the batch and marks the offset of the
+// partition. If some messages are not sent, the offset of the first of them
+// is marked so that the next replay resumes from it.
+func (b *replayBatch) flush() error {
+	if len(b.msgs) == 0 {
+		if b.next > 0 {
+			b.pom.MarkOffset(b.next, "")
+		}
+		return nil
+	}
+
+	err := b.producer.SendMessages(b.msgs)
+	sent, next := len(b.msgs), b.next
+	if err != nil {
+		var producerErrs sarama.ProducerErrors
+		if !errors.As(err, &producerErrs) {
+			next = b.msgs[0].Metadata.(int64)
+			sent = 0
+		} else {
+			// Report the error of the first dead letter not sent
+			for _, pe := range producerErrs {
+				if offset := pe.Msg.Metadata.(int64); offset < next {
+					next, err = offset, pe
+				}
+			}
+			sent -= len(producerErrs)
+		}
+		err = fmt.Errorf("failed to replay the dead letter at offset %d of partition %d: %w", next, b.partition, err)
+	}
+
+	b.result.Replayed += sent
+	if next > 0 {
+		b.pom.MarkOffset(next, "")
+	}
+	b.msgs = b.msgs[:0]
+	return err
+}
+
+// replayMessage returns the message replaying a dead letter to a retry
+// topic, due at now. It keeps the original position and the consumer group
+// of the dead letter, without the metadata of its last failure.
+func replayMessage(retryTopic string, msg *sarama.ConsumerMessage, now time.Time) *sarama.ProducerMessage {
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers))
+	for _, h := range msg.Headers {
+		if h != nil {
+			headers = append(headers, *h)
+		}
+	}
+	headers = removeHeaders(headers, HeaderAttempt, HeaderError, HeaderFailedAt)
+	headers = setHeader(headers, HeaderRetryAt, strconv.FormatInt(now.UnixMilli(), 10))
+
+	pm := &sarama.ProducerMessage{
+		Topic:   retryTopic,
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}
+	if msg.Key != nil {
+		pm.Key = sarama.ByteEncoder(msg.Key)
+	}
+	return pm
+}
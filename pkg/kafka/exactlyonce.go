@@ -0,0 +1,261 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HeaderIdempotencyKey is the header carrying the idempotency key of a
+// message, deduplicating the messages of the exactly once handlers.
+const HeaderIdempotencyKey = "x-idempotency-key"
+
+// ResultDuplicate is the result of a message already processed by an
+// exactly once handler, which is skipped.
+const ResultDuplicate = "duplicate"
+
+// DefaultProcessedRetention is the default time the idempotency keys of the
+// processed messages are kept for.
+const DefaultProcessedRetention = 7 * 24 * time.Hour
+
+// processedPurgeInterval is the interval of the removal of the idempotency
+// keys older than the retention.
+const processedPurgeInterval = time.Hour
+
+// processedDeleteBatch is the number of idempotency keys removed per
+// statement by resetStoredOffsets.
+const processedDeleteBatch = 1000
+
+// ConsumerOffset is the offset of a partition consumed by an exactly once
+// handler, stored in the transaction of the processing of its messages, or
+// on its own once a failed message is retried, dead lettered or dropped.
+type ConsumerOffset struct {
+	Group     string    `gorm:"column:consumer_group;type:varchar(255);primaryKey"` // 消费者组
+	Topic     string    `gorm:"column:topic;type:varchar(255);primaryKey"`          // 主题
+	Partition int32     `gorm:"column:partition_id;primaryKey;autoIncrement:false"` // 分区
+	Offset    int64     `gorm:"column:next_offset;not null"`                        // 下一条待消费消息的偏移量
+	UpdatedAt time.Time `gorm:"column:updated_at;not null"`                         // 更新时间
+}
+
+// TableName returns the consumer offset table.
+func (ConsumerOffset) TableName() string {
+	return "tb_kafka_consumer_offset"
+}
+
+// ProcessedMessage is the idempotency key of a message processed by an
+// exactly once handler.
+type ProcessedMessage struct {
+	Group       string    `gorm:"column:consumer_group;type:varchar(255);primaryKey"`                // 消费者组
+	Key         string    `gorm:"column:idempotency_key;type:varchar(255);primaryKey"`               // 幂等键
+	ProcessedAt time.Time `gorm:"column:processed_at;not null;index:idx_kafka_processed_message_at"` // 处理时间
+}
+
+// TableName returns the processed message table.
+func (ProcessedMessage) TableName() string {
+	return "tb_kafka_processed_message"
+}
+
+// txContextKey is the context key of the transaction of an exactly once
+// handler.
+type txContextKey struct{}
+
+// WithExactlyOnce processes each message of the handler in a transaction
+// of db, available to the handler with Tx, in which the offset of the
+// message and its idempotency key are stored as well. The results of the
+// handler are thus committed if and only if the message is consumed, and
+// the messages sent twice, e.g. by a producer retry or an outbox relay, are
+// processed once.
+//
+// The idempotency key of a message is its HeaderIdempotencyKey header, or
+// its HeaderOutboxID header, or its original topic, partition and offset.
+// The offset and idempotency key tables are created by Registry.Start.
+//
+// Parameters:
+//   - db: The provider of the MySQL or PostgreSQL database, called by
+//     Registry.Start once the database is initialized, e.g.
+//     func() *gorm.DB { return resource.MySQLClient }.
+//
+// Returns:
+//   - HandlerOption: The option.
+func WithExactlyOnce(db func() *gorm.DB) HandlerOption {
+	return func(o *handlerOptions) {
+		o.txDB = db
+	}
+}
+
+// Tx returns the transaction of the message processed by an exactly once
+// handler, see WithExactlyOnce.
+//
+// Parameters:
+//   - ctx: The context of the handler.
+//
+// Returns:
+//   - *gorm.DB: The transaction, nil if the handler is not exactly once.
+func Tx(ctx context.Context) *gorm.DB {
+	tx, _ := ctx.Value(txContextKey{}).(*gorm.DB)
+	return tx
+}
+
+// MigrateExactlyOnce creates the offset and idempotency key tables of the
+// exactly once handlers.
+//
+// Parameters:
+//   - ctx: The context of the migration.
+//   - db: The MySQL or PostgreSQL database.
+//
+// Returns:
+//   - error: An error if the migration fails.
+func MigrateExactlyOnce(ctx context.Context, db *gorm.DB) error {
+	if err := db.WithContext(ctx).AutoMigrate(&ConsumerOffset{}, &ProcessedMessage{}); err != nil {
+		return fmt.Errorf("failed to migrate kafka exactly once tables: %w", err)
+	}
+	return nil
+}
+
+// IdempotencyKey returns the idempotency key of a consumed message, see
+// WithExactlyOnce. The key of a retried message is the key of the original
+// one.
+//
+// Parameters:
+//   - msg: The consumed message.
+//
+// Returns:
+//   - string: The idempotency key.
+func IdempotencyKey(msg *sarama.ConsumerMessage) string {
+	if key := header(msg.Headers, HeaderIdempotencyKey); len(key) > 0 {
+		return string(key)
+	}
+	if id := header(msg.Headers, HeaderOutboxID); len(id) > 0 {
+		return "outbox:" + string(id)
+	}
+
+	topic, partition, offset := msg.Topic, strconv.Itoa(int(msg.Partition)), strconv.FormatInt(msg.Offset, 10)
+	if original := header(msg.Headers, HeaderOriginalTopic); len(original) > 0 {
+		topic = string(original)
+		partition = string(header(msg.Headers, HeaderOriginalPartition))
+		offset = string(header(msg.Headers, HeaderOriginalOffset))
+	}
+	return topic + "/" + partition + "/" + offset
+}
+
+// runTx processes a message in a transaction storing its idempotency key and
+// its offset, skipping it if its key is already stored.
+//
+// Returns:
+//   - string: The result of the processing, e.g. ResultDuplicate.
+//   - error: The decoding, the handler or the database error.
+func (h *topicHandler) runTx(ctx context.Context, group string, msg *sarama.ConsumerMessage, meta Meta) (string, error) {
+	result := ResultError
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		processed := ProcessedMessage{Group: group, Key: IdempotencyKey(msg), ProcessedAt: time.Now()}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&processed)
+		if created.Error != nil {
+			return fmt.Errorf("failed to store the idempotency key of the message: %w", created.Error)
+		}
+
+		if created.RowsAffected == 0 {
+			result = ResultDuplicate
+		} else {
+			var err error
+			if result, err = h.run(context.WithValue(ctx, txContextKey{}, tx), msg.Value, meta); err != nil {
+				return err
+			}
+		}
+		return saveOffset(tx, group, msg.Topic, msg.Partition, msg.Offset+1)
+	})
+	if err != nil && result == ResultSuccess {
+		// The transaction failed to commit
+		result = ResultError
+	}
+	return result, err
+}
+
+// saveOffset stores the next offset of a partition in a transaction.
+func saveOffset(tx *gorm.DB, group, topic string, partition int32, offset int64) error {
+	row := ConsumerOffset{Group: group, Topic: topic, Partition: partition, Offset: offset, UpdatedAt: time.Now()}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "consumer_group"}, {Name: "topic"}, {Name: "partition_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"next_offset", "updated_at"}),
+	}).Create(&row).Error
+	if err != nil {
+		return fmt.Errorf("failed to store the offset of %s/%d: %w", topic, partition, err)
+	}
+	return nil
+}
+
+// resetStoredOffsets stores the next offsets of the partitions of a topic
+// reset by Registry.ResetOffsets, and removes the idempotency keys of the
+// messages between a reset offset and the stored one, so that the messages
+// consumed again are processed again.
+func resetStoredOffsets(ctx context.Context, db *gorm.DB, group, topic string, offsets map[int32]int64) error {
+	partitions := sortedPartitions(offsets)
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stored, err := loadOffsets(ctx, tx, group, topic, partitions)
+		if err != nil {
+			return err
+		}
+
+		for _, partition := range partitions {
+			if next, ok := stored[partition]; ok && offsets[partition] < next {
+				if err := deleteProcessed(tx, group, topic, partition, offsets[partition], next); err != nil {
+					return err
+				}
+			}
+			if err := saveOffset(tx, group, topic, partition, offsets[partition]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deleteProcessed removes the idempotency keys of the messages of a
+// partition from offset from to offset to, excluded, in a transaction.
+func deleteProcessed(tx *gorm.DB, group, topic string, partition int32, from, to int64) error {
+	prefix := topic + "/" + strconv.Itoa(int(partition)) + "/"
+	keys := make([]string, 0, min(to-from, processedDeleteBatch))
+	for offset := from; offset < to; offset++ {
+		keys = append(keys, prefix+strconv.FormatInt(offset, 10))
+		if len(keys) < processedDeleteBatch && offset+1 < to {
+			continue
+		}
+
+		err := tx.Where("consumer_group = ? AND idempotency_key IN ?", group, keys).Delete(&ProcessedMessage{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to remove the idempotency keys of %s/%d: %w", topic, partition, err)
+		}
+		keys = keys[:0]
+	}
+	return nil
+}
+
+// loadOffsets returns the stored next offsets of the partitions of a topic,
+// without the partitions having no stored offset.
+func loadOffsets(ctx context.Context, db *gorm.DB, group, topic string, partitions []int32) (map[int32]int64, error) {
+	var rows []ConsumerOffset
+	err := db.WithContext(ctx).
+		Where("consumer_group = ? AND topic = ? AND partition_id IN ?", group, topic, partitions).
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the offsets of topic %s: %w", topic, err)
+	}
+
+	offsets := make(map[int32]int64, len(rows))
+	for _, row := range rows {
+		offsets[row.Partition] = row.Offset
+	}
+	return offsets, nil
+}
+
+// purgeProcessed removes the idempotency keys of a consumer group older than
+// the retention.
+func purgeProcessed(ctx context.Context, db *gorm.DB, group string, retention time.Duration) error {
+	return db.WithContext(ctx).
+		Where("consumer_group = ? AND processed_at < ?", group, time.Now().Add(-retention)).
+		Delete(&ProcessedMessage{}).Error
+}
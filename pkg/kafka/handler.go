@@ -0,0 +1,209 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+// Meta is the metadata of a consumed message.
+type Meta struct {
+	Group     string
+	Topic     string // the topic of the handler, also for a message of a retry topic
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Timestamp time.Time
+	Headers   []*sarama.RecordHeader
+	Attempt   int // the number of failed attempts to process the message
+}
+
+// Header returns the value of a header of the message, nil if it is absent.
+//
+// Parameters:
+//   - key: The header key.
+//
+// Returns:
+//   - []byte: The header value.
+func (m Meta) Header(key string) []byte {
+	for _, h := range m.Headers {
+		if h != nil && string(h.Key) == key {
+			return h.Value
+		}
+	}
+	return nil
+}
+
+// HandlerFunc processes a decoded message. The message is committed once the
+// function returns. A message whose handler fails is retried or sent to the
+// dead letter topic according to the retry policy of the handler, see
+// WithRetryPolicy, or dropped otherwise.
+type HandlerFunc[T proto.Message] func(ctx context.Context, msg T, meta Meta) error
+
+// HandlerOption configures a registered handler.
+type HandlerOption func(*handlerOptions)
+
+// handlerOptions are the options of a registered handler.
+type handlerOptions struct {
+	concurrency int
+	retry       *RetryPolicy
+	txDB        func() *gorm.DB
+}
+
+// WithConcurrency limits the number of messages of the topic processed
+// concurrently across the partitions assigned to the instance. The
+// messages of a partition are always processed one by one, in order.
+//
+// Parameters:
+//   - n: The maximum number of messages processed concurrently, zero for
+//     the concurrency of the runner, see RunnerConfig.
+//
+// Returns:
+//   - HandlerOption: The option.
+func WithConcurrency(n int) HandlerOption {
+	return func(o *handlerOptions) {
+		o.concurrency = n
+	}
+}
+
+// handler is a registered handler, whose message type is erased so that
+// the handlers of several types are consumed by the same consumer group.
+type handler struct {
+	group  string
+	topic  string
+	decode func(ctx context.Context, value []byte) (proto.Message, error)
+	handle func(ctx context.Context, msg proto.Message, meta Meta) error
+
+	concurrency int             // zero for the concurrency of the runner
+	retry       *RetryPolicy    // nil if the failed messages are dropped
+	txDB        func() *gorm.DB // nil if the handler is not exactly once
+}
+
+// Registry holds the message handlers by consumer group and topic, and
+// runs their consumer groups.
+type Registry struct {
+	mu       sync.Mutex
+	handlers map[string]map[string]*handler // group -> topic -> handler
+	cancel   context.CancelFunc
+	groups   []sarama.ConsumerGroup
+	wg       sync.WaitGroup
+}
+
+// NewRegistry creates an empty handler registry.
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]map[string]*handler)}
+}
+
+// DefaultRegistry is the registry of Register, started with the Kafka
+// clients at boot.
+var DefaultRegistry = NewRegistry()
+
+// ErrHandlerExists is returned when a handler is already registered for the
+// topic and the consumer group.
+var ErrHandlerExists = errors.New("kafka handler already registered")
+
+// ErrRegistryStarted is returned when a handler is registered once the
+// consumer groups are started.
+var ErrRegistryStarted = errors.New("kafka consumers already started")
+
+// Register registers the handler of the messages of a topic consumed by a
+// consumer group in DefaultRegistry.
+//
+// The messages are decoded into a new T, e.g. *pkgproto.TestMessage, before
+// the handler is called, with the schema registry serde if they are in its
+// wire format. Handlers must be registered before the consumers are
+// started, e.g. in an init function.
+//
+// Parameters:
+//   - topic: The topic to consume.
+//   - group: The consumer group ID.
+//   - fn: The handler of the decoded messages.
+//   - opts: The handler options, e.g. WithConcurrency.
+//
+// Returns:
+//   - error: An error if a handler is already registered for the topic and
+//     the group, or if the consumers are started.
+func Register[T proto.Message](topic, group string, fn HandlerFunc[T], opts ...HandlerOption) error {
+	return RegisterTo(DefaultRegistry, topic, group, fn, opts...)
+}
+
+// RegisterTo registers a handler in the given registry, see Register.
+func RegisterTo[T proto.Message](r *Registry, topic, group string, fn HandlerFunc[T], opts ...HandlerOption) error {
+	if topic == "" || group == "" {
+		return fmt.Errorf("kafka handler topic and group must not be empty")
+	}
+
+	var o handlerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.retry != nil {
+		if err := o.retry.validate(topic); err != nil {
+			return err
+		}
+	}
+
+	h := &handler{
+		group:       group,
+		topic:       topic,
+		concurrency: o.concurrency,
+		retry:       o.retry,
+		txDB:        o.txDB,
+		decode: func(ctx context.Context, value []byte) (proto.Message, error) {
+			var zero T
+			return decodeValue(ctx, value, zero.ProtoReflect().New().Interface())
+		},
+		handle: func(ctx context.Context, msg proto.Message, meta Meta) error {
+			return fn(ctx, msg.(T), meta)
+		},
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return ErrRegistryStarted
+	}
+	topics, ok := r.handlers[group]
+	if !ok {
+		topics = make(map[string]*handler)
+		r.handlers[group] = topics
+	}
+	if _, ok := topics[topic]; ok {
+		return fmt.Errorf("%w: topic %s, group %s", ErrHandlerExists, topic, group)
+	}
+	topics[topic] = h
+	return nil
+}
+
+// Groups returns the sorted consumer groups having a handler.
+func (r *Registry) Groups() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	groups := make([]string, 0, len(r.handlers))
+	for group := range r.handlers {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// Topics returns the sorted topics consumed by a consumer group.
+func (r *Registry) Topics(group string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	topics := make([]string, 0, len(r.handlers[group]))
+	for topic := range r.handlers[group] {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
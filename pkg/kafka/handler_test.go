@@ -0,0 +1,400 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
+
+	"github.com/IBM/sarama"
+)
+
+// testSession is a consumer group session recording the marked messages.
+type testSession struct {
+	ctx context.Context
+
+	mu     sync.Mutex
+	marked []int64
+}
+
+func (s *testSession) Claims() map[string][]int32               { return nil }
+func (s *testSession) MemberID() string                         { return "member" }
+func (s *testSession) GenerationID() int32                      { return 1 }
+func (s *testSession) MarkOffset(string, int32, int64, string)  {}
+func (s *testSession) Commit()                                  {}
+func (s *testSession) ResetOffset(string, int32, int64, string) {}
+func (s *testSession) Context() context.Context                 { return s.ctx }
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked = append(s.marked, msg.Offset)
+}
+
+// testClaim is a claim of the messages of a channel.
+type testClaim struct {
+	topic    string
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *testClaim) Topic() string                            { return c.topic }
+func (c *testClaim) Partition() int32                         { return 0 }
+func (c *testClaim) InitialOffset() int64                     { return 0 }
+func (c *testClaim) HighWaterMarkOffset() int64               { return 0 }
+func (c *testClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+// newTestClaim returns a closed claim of the given messages of topic.
+func newTestClaim(topic string, values ...[]byte) *testClaim {
+	claim := &testClaim{topic: topic, messages: make(chan *sarama.ConsumerMessage, len(values))}
+	for i, value := range values {
+		claim.messages <- &sarama.ConsumerMessage{Topic: topic, Offset: int64(i), Value: value}
+	}
+	close(claim.messages)
+	return claim
+}
+
+// testMessage serializes a test message.
+func testMessage(t *testing.T, id int32) []byte {
+	t.Helper()
+
+	data, err := common.SerializeData(&pkgproto.TestMessage{Id: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestRegister tests the registration of the handlers.
+func TestRegister(t *testing.T) {
+	registry := NewRegistry()
+	fn := func(ctx context.Context, msg *pkgproto.TestMessage, meta Meta) error { return nil }
+
+	if err := RegisterTo(registry, "orders", "billing", fn); err != nil {
+		t.Fatalf("RegisterTo() error = %v", err)
+	}
+	if err := RegisterTo(registry, "refunds", "billing", fn); err != nil {
+		t.Fatalf("RegisterTo() error = %v", err)
+	}
+	if err := RegisterTo(registry, "orders", "billing", fn); !errors.Is(err, ErrHandlerExists) {
+		t.Errorf("RegisterTo() of a registered topic error = %v, want ErrHandlerExists", err)
+	}
+	if err := RegisterTo(registry, "", "billing", fn); err == nil {
+		t.Error("RegisterTo() without topic succeeded")
+	}
+
+	if got := registry.Topics("billing"); len(got) != 2 || got[0] != "orders" || got[1] != "refunds" {
+		t.Errorf("Topics() = %v, want [orders refunds]", got)
+	}
+}
+
+// TestConsumeClaim tests that the messages of a partition are decoded and
+// processed in order, and marked whatever the result of the handler.
+func TestConsumeClaim(t *testing.T) {
+	registry := NewRegistry()
+	var ids []int32
+	err := RegisterTo(registry, "orders", "billing", func(ctx context.Context, msg *pkgproto.TestMessage, meta Meta) error {
+		if meta.Group != "billing" || meta.Topic != "orders" {
+			t.Errorf("meta = %+v, want group billing and topic orders", meta)
+		}
+		ids = append(ids, msg.GetId())
+		switch msg.GetId() {
+		case 2:
+			return errors.New("failed")
+		case 3:
+			panic("boom")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := newGroupRunner("billing", nil, registry.handlers["billing"], RunnerConfig{})
+	session := &testSession{ctx: context.Background()}
+	claim := newTestClaim("orders", testMessage(t, 1), testMessage(t, 2), testMessage(t, 3), []byte("invalid"), testMessage(t, 4))
+
+	if err := runner.ConsumeClaim(session, claim); err != nil {
+		t.Fatalf("ConsumeClaim() error = %v", err)
+	}
+	if fmt.Sprint(ids) != "[1 2 3 4]" {
+		t.Errorf("processed ids = %v, want [1 2 3 4]", ids)
+	}
+	if fmt.Sprint(session.marked) != "[0 1 2 3 4]" {
+		t.Errorf("marked offsets = %v, want [0 1 2 3 4]", session.marked)
+	}
+}
+
+// TestConsumeClaimSessionEnd tests that a message failing because the
+// session ended is not marked, so that it is redelivered.
+func TestConsumeClaimSessionEnd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	registry := NewRegistry()
+	err := RegisterTo(registry, "orders", "billing", func(ctx context.Context, msg *pkgproto.TestMessage, meta Meta) error {
+		cancel()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := newGroupRunner("billing", nil, registry.handlers["billing"], RunnerConfig{})
+	session := &testSession{ctx: ctx}
+	if err := runner.ConsumeClaim(session, newTestClaim("orders", testMessage(t, 1), testMessage(t, 2))); err != nil {
+		t.Fatalf("ConsumeClaim() error = %v", err)
+	}
+	if len(session.marked) != 0 {
+		t.Errorf("marked offsets = %v, want none", session.marked)
+	}
+}
+
+// TestConcurrency tests that the concurrency of a handler bounds the
+// messages processed at once across partitions.
+func TestConcurrency(t *testing.T) {
+	registry := NewRegistry()
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	err := RegisterTo(registry, "orders", "billing", func(ctx context.Context, msg *pkgproto.TestMessage, meta Meta) error {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil
+	}, WithConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := newGroupRunner("billing", nil, registry.handlers["billing"], RunnerConfig{Concurrency: 10})
+	session := &testSession{ctx: context.Background()}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		claim := newTestClaim("orders", testMessage(t, 1), testMessage(t, 2), testMessage(t, 3))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = runner.ConsumeClaim(session, claim)
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight != 2 {
+		t.Errorf("max messages in flight = %d, want 2", maxInFlight)
+	}
+	if len(session.marked) != 15 {
+		t.Errorf("marked %d messages, want 15", len(session.marked))
+	}
+}
+
+// testGroup is a consumer group whose sessions fail a number of times, then
+// last until the context is canceled.
+type testGroup struct {
+	sarama.ConsumerGroup
+
+	mu       sync.Mutex
+	failures int
+	consumes int
+	closed   bool
+}
+
+func (g *testGroup) Consume(ctx context.Context, _ []string, _ sarama.ConsumerGroupHandler) error {
+	g.mu.Lock()
+	g.consumes++
+	fail := g.consumes <= g.failures
+	g.mu.Unlock()
+
+	if fail {
+		return sarama.ErrOutOfBrokers
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (g *testGroup) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+	return nil
+}
+
+// TestStartStop tests that a failed session is restarted with a backoff and
+// that Stop closes the consumer groups.
+func TestStartStop(t *testing.T) {
+	registry := NewRegistry()
+	fn := func(ctx context.Context, msg *pkgproto.TestMessage, meta Meta) error { return nil }
+	if err := RegisterTo(registry, "orders", "billing", fn); err != nil {
+		t.Fatal(err)
+	}
+
+	group := &testGroup{failures: 3}
+	err := registry.Start(func(string) (sarama.ConsumerGroup, error) { return group, nil },
+		RunnerConfig{MinReconnectBackoff: time.Millisecond, MaxReconnectBackoff: 2 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := registry.Start(func(string) (sarama.ConsumerGroup, error) { return group, nil }, RunnerConfig{}); !errors.Is(err, ErrRegistryStarted) {
+		t.Errorf("second Start() error = %v, want ErrRegistryStarted", err)
+	}
+	if err := RegisterTo(registry, "refunds", "billing", fn); !errors.Is(err, ErrRegistryStarted) {
+		t.Errorf("RegisterTo() once started error = %v, want ErrRegistryStarted", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		group.mu.Lock()
+		consumes := group.consumes
+		group.mu.Unlock()
+		if consumes > 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Consume called %d times, want the session restarted after 3 failures", consumes)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := registry.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if !group.closed {
+		t.Error("Stop() did not close the consumer group")
+	}
+}
+
+// testProducer is a producer recording the sent messages, failing the first
+// ones.
+type testProducer struct {
+	sarama.SyncProducer
+
+	mu       sync.Mutex
+	failures int
+	sent     []*sarama.ProducerMessage
+}
+
+func (p *testProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failures > 0 {
+		p.failures--
+		return 0, 0, sarama.ErrOutOfBrokers
+	}
+	p.sent = append(p.sent, msg)
+	return 0, int64(len(p.sent) - 1), nil
+}
+
+// TestRetryPolicy tests that the failed messages are sent to the retry
+// topics, then to the dead letter topic, and that the retried messages are
+// processed again.
+func TestRetryPolicy(t *testing.T) {
+	registry := NewRegistry()
+	var attempts []int
+	err := RegisterTo(registry, "orders", "billing", func(ctx context.Context, msg *pkgproto.TestMessage, meta Meta) error {
+		attempts = append(attempts, meta.Attempt)
+		if msg.GetId() == 2 {
+			return resilience.Permanent(errors.New("invalid order"))
+		}
+		return errors.New("failed")
+	}, WithRetryPolicy(RetryPolicy{Delays: []time.Duration{time.Minute, 10 * time.Minute}, DeadLetter: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	producer := &testProducer{failures: 1}
+	runner := newGroupRunner("billing", nil, registry.handlers["billing"], RunnerConfig{
+		MinReconnectBackoff: time.Millisecond,
+		MaxReconnectBackoff: time.Millisecond,
+		Producer:            producer,
+	})
+	if fmt.Sprint(runner.topics) != "[orders orders.retry.1m orders.retry.10m]" {
+		t.Errorf("topics = %v, want the topic and its retry topics", runner.topics)
+	}
+
+	session := &testSession{ctx: context.Background()}
+	claim := newTestClaim("orders", testMessage(t, 1), testMessage(t, 2), []byte("invalid"))
+	if err := runner.ConsumeClaim(session, claim); err != nil {
+		t.Fatalf("ConsumeClaim() error = %v", err)
+	}
+
+	// The first message is retried, once the producer recovered, the
+	// permanent failure and the undecodable message are dead lettered
+	if len(producer.sent) != 3 || producer.sent[0].Topic != "orders.retry.1m" ||
+		producer.sent[1].Topic != "orders.dlq" || producer.sent[2].Topic != "orders.dlq" {
+		t.Fatalf("sent %d messages, want one to orders.retry.1m then two to orders.dlq", len(producer.sent))
+	}
+	if len(session.marked) != 3 {
+		t.Errorf("marked offsets = %v, want the 3 messages", session.marked)
+	}
+
+	// The retried message is processed again from the retry topics, then
+	// dead lettered
+	retry := producer.sent[0]
+	for _, topic := range []string{"orders.retry.1m", "orders.retry.10m"} {
+		// The retry is due now, after the retry of another group
+		retried := consumerMessage(topic, retry)
+		retried.Headers = replaceHeader(retried.Headers, HeaderRetryAt, strconv.FormatInt(time.Now().UnixMilli(), 10))
+
+		claim := &testClaim{topic: topic, messages: make(chan *sarama.ConsumerMessage, 2)}
+		other := consumerMessage(topic, producer.sent[0])
+		other.Headers = replaceHeader(other.Headers, HeaderConsumerGroup, "shipping")
+		claim.messages <- other
+		claim.messages <- retried
+		close(claim.messages)
+
+		if err := runner.ConsumeClaim(session, claim); err != nil {
+			t.Fatalf("ConsumeClaim() of %s error = %v", topic, err)
+		}
+		retry = producer.sent[len(producer.sent)-1]
+	}
+
+	if fmt.Sprint(attempts) != "[0 0 1 2]" {
+		t.Errorf("attempts = %v, want [0 0 1 2]", attempts)
+	}
+	last := producer.sent[len(producer.sent)-1]
+	if len(producer.sent) != 5 || producer.sent[3].Topic != "orders.retry.10m" || last.Topic != "orders.dlq" {
+		t.Fatalf("sent %d messages, want the retry to orders.retry.10m then orders.dlq", len(producer.sent))
+	}
+	headers := map[string]string{}
+	for _, h := range last.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	if headers[HeaderOriginalTopic] != "orders" || headers[HeaderOriginalOffset] != "0" ||
+		headers[HeaderAttempt] != "3" || headers[HeaderError] != "failed" || headers[HeaderRetryAt] != "" {
+		t.Errorf("dead letter headers = %v", headers)
+	}
+}
+
+// consumerMessage returns the message consumed from topic after pm was
+// sent.
+func consumerMessage(topic string, pm *sarama.ProducerMessage) *sarama.ConsumerMessage {
+	value, _ := pm.Value.Encode()
+	msg := &sarama.ConsumerMessage{Topic: topic, Value: value}
+	for _, h := range pm.Headers {
+		msg.Headers = append(msg.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	return msg
+}
+
+// replaceHeader replaces the value of a header of a consumed message.
+func replaceHeader(headers []*sarama.RecordHeader, key, value string) []*sarama.RecordHeader {
+	replaced := make([]*sarama.RecordHeader, 0, len(headers))
+	for _, h := range headers {
+		if string(h.Key) == key {
+			h = &sarama.RecordHeader{Key: h.Key, Value: []byte(value)}
+		}
+		replaced = append(replaced, h)
+	}
+	return replaced
+}
@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
+
+	"github.com/IBM/sarama"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeadersFromContext returns the Kafka record headers carrying the
+// correlation values found in ctx, such as the request ID.
+//
+// Parameters:
+//   - ctx: The context of the request producing the message.
+//
+// Returns:
+//   - []sarama.RecordHeader: The headers to attach to the produced message.
+func HeadersFromContext(ctx context.Context) []sarama.RecordHeader {
+	var headers []sarama.RecordHeader
+	if requestID := common.RequestIDFromContext(ctx); requestID != "" {
+		headers = append(headers, sarama.RecordHeader{
+			Key:   []byte(common.RequestIDHeader),
+			Value: []byte(requestID),
+		})
+	}
+	return headers
+}
+
+// ContextFromHeaders returns a copy of ctx carrying the request ID found in
+// the headers of a consumed message.
+//
+// A new request ID is generated when the message does not carry any, so that
+// every log line written while processing the message can be correlated.
+//
+// Parameters:
+//   - ctx: The parent context.
+//   - headers: The headers of the consumed message.
+//
+// Returns:
+//   - context.Context: The context carrying the request ID.
+func ContextFromHeaders(ctx context.Context, headers []*sarama.RecordHeader) context.Context {
+	for _, header := range headers {
+		if header != nil && string(header.Key) == common.RequestIDHeader && len(header.Value) > 0 {
+			return common.WithRequestID(ctx, string(header.Value))
+		}
+	}
+	ctx, _ = common.EnsureRequestID(ctx)
+	return ctx
+}
+
+// StartConsumerSpan restores the request ID and the trace context propagated
+// in the headers of msg and starts a consumer span for it.
+//
+// The caller ends the span with tracing.EndSpan once the message is
+// processed.
+//
+// Parameters:
+//   - ctx: The parent context of the consumer.
+//   - msg: The consumed message.
+//
+// Returns:
+//   - context.Context: The context carrying the request ID and the span.
+//   - trace.Span: The consumer span.
+func StartConsumerSpan(ctx context.Context, msg *sarama.ConsumerMessage) (context.Context, trace.Span) {
+	ctx = ContextFromHeaders(ctx, msg.Headers)
+	return tracing.StartConsumerSpan(ctx, tracing.ConsumerMessageCarrier{Message: msg},
+		tracing.SystemKafka, msg.Topic,
+		semconv.MessagingKafkaDestinationPartition(int(msg.Partition)),
+		semconv.MessagingKafkaMessageOffset(int(msg.Offset)),
+	)
+}
@@ -0,0 +1,282 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/IBM/sarama"
+)
+
+// ErrGroupNotFound is returned when a consumer group does not exist.
+var ErrGroupNotFound = errors.New("kafka consumer group not found")
+
+// consumerProtocol is the protocol type of the consumer groups, the other
+// groups being e.g. Kafka Connect workers.
+const consumerProtocol = "consumer"
+
+// TopicSummary is a topic of the cluster.
+type TopicSummary struct {
+	Name              string `json:"name"`
+	Internal          bool   `json:"internal"`
+	Partitions        int    `json:"partitions"`
+	ReplicationFactor int    `json:"replication_factor"`
+}
+
+// TopicDescription is a topic of the cluster with its partitions.
+type TopicDescription struct {
+	Name       string                 `json:"name"`
+	Internal   bool                   `json:"internal"`
+	Partitions []PartitionDescription `json:"partitions"`
+}
+
+// PartitionDescription is a partition of a topic, with the brokers holding
+// its replicas and the range of its offsets.
+type PartitionDescription struct {
+	Partition       int32   `json:"partition"`
+	Leader          int32   `json:"leader"`
+	Replicas        []int32 `json:"replicas"`
+	ISR             []int32 `json:"isr"`
+	OfflineReplicas []int32 `json:"offline_replicas,omitempty"`
+	OldestOffset    int64   `json:"oldest_offset"`
+	NewestOffset    int64   `json:"newest_offset"` // the high water mark
+}
+
+// GroupSummary is a consumer group of the cluster.
+type GroupSummary struct {
+	Group        string `json:"group"`
+	ProtocolType string `json:"protocol_type"`
+	State        string `json:"state"`
+	Members      int    `json:"members"`
+}
+
+// GroupDescription is a consumer group of the cluster with its members and
+// its lag.
+type GroupDescription struct {
+	Group        string              `json:"group"`
+	ProtocolType string              `json:"protocol_type"`
+	Protocol     string              `json:"protocol"` // the partition assignment strategy
+	State        string              `json:"state"`
+	Members      []MemberDescription `json:"members"`
+	Lag          GroupLag            `json:"lag"`
+}
+
+// MemberDescription is a member of a consumer group with the partitions
+// assigned to it.
+type MemberDescription struct {
+	MemberID   string             `json:"member_id"`
+	ClientID   string             `json:"client_id"`
+	Host       string             `json:"host"`
+	Assignment map[string][]int32 `json:"assignment"` // partitions by topic
+}
+
+// Inspector describes the topics and the consumer groups of a cluster with
+// the admin API, e.g. for the admin endpoints and the lag monitor.
+//
+// The inspector shares the client it is created from: it has no resource
+// of its own and must not be closed, closing the admin API of sarama
+// closing the client.
+type Inspector struct {
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+}
+
+// NewInspector creates an inspector of the cluster of a client.
+//
+// Parameters:
+//   - client: The Kafka client, e.g. resource.KafkaClient.
+//
+// Returns:
+//   - *Inspector: The inspector.
+//   - error: An error if the controller of the cluster cannot be found.
+func NewInspector(client sarama.Client) (*Inspector, error) {
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka cluster admin: %w", err)
+	}
+	return &Inspector{client: client, admin: admin}, nil
+}
+
+// Topics returns the topics of the cluster sorted by name.
+//
+// Returns:
+//   - []TopicSummary: The topics.
+//   - error: An error if the metadata of the cluster cannot be retrieved.
+func (i *Inspector) Topics() ([]TopicSummary, error) {
+	metadata, err := i.admin.DescribeTopics(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe kafka topics: %w", err)
+	}
+
+	topics := make([]TopicSummary, 0, len(metadata))
+	for _, m := range metadata {
+		if !errors.Is(m.Err, sarama.ErrNoError) {
+			continue
+		}
+		topic := TopicSummary{Name: m.Name, Internal: m.IsInternal, Partitions: len(m.Partitions)}
+		if len(m.Partitions) > 0 {
+			topic.ReplicationFactor = len(m.Partitions[0].Replicas)
+		}
+		topics = append(topics, topic)
+	}
+	sort.Slice(topics, func(a, b int) bool { return topics[a].Name < topics[b].Name })
+	return topics, nil
+}
+
+// Topic returns a topic of the cluster with its partitions.
+//
+// Parameters:
+//   - topic: The topic.
+//
+// Returns:
+//   - TopicDescription: The topic, its partitions in order.
+//   - error: sarama.ErrUnknownTopicOrPartition if the topic does not exist,
+//     or an error if the metadata or the offsets cannot be retrieved.
+func (i *Inspector) Topic(topic string) (TopicDescription, error) {
+	desc := TopicDescription{Name: topic}
+
+	metadata, err := i.admin.DescribeTopics([]string{topic})
+	if err != nil {
+		return desc, fmt.Errorf("failed to describe kafka topic %s: %w", topic, err)
+	}
+	if len(metadata) == 0 {
+		return desc, fmt.Errorf("failed to describe kafka topic %s: %w", topic, sarama.ErrUnknownTopicOrPartition)
+	}
+	if m := metadata[0]; !errors.Is(m.Err, sarama.ErrNoError) {
+		return desc, fmt.Errorf("failed to describe kafka topic %s: %w", topic, m.Err)
+	}
+
+	desc.Internal = metadata[0].IsInternal
+	for _, p := range metadata[0].Partitions {
+		partition := PartitionDescription{
+			Partition:       p.ID,
+			Leader:          p.Leader,
+			Replicas:        p.Replicas,
+			ISR:             p.Isr,
+			OfflineReplicas: p.OfflineReplicas,
+		}
+		if partition.OldestOffset, err = i.client.GetOffset(topic, p.ID, sarama.OffsetOldest); err != nil {
+			return desc, fmt.Errorf("failed to get the oldest offset of %s/%d: %w", topic, p.ID, err)
+		}
+		if partition.NewestOffset, err = i.client.GetOffset(topic, p.ID, sarama.OffsetNewest); err != nil {
+			return desc, fmt.Errorf("failed to get the newest offset of %s/%d: %w", topic, p.ID, err)
+		}
+		desc.Partitions = append(desc.Partitions, partition)
+	}
+	sort.Slice(desc.Partitions, func(a, b int) bool {
+		return desc.Partitions[a].Partition < desc.Partitions[b].Partition
+	})
+	return desc, nil
+}
+
+// Groups returns the consumer groups of the cluster sorted by name.
+//
+// Returns:
+//   - []GroupSummary: The consumer groups.
+//   - error: An error if the groups cannot be listed or described.
+func (i *Inspector) Groups() ([]GroupSummary, error) {
+	listed, err := i.admin.ListConsumerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kafka consumer groups: %w", err)
+	}
+	if len(listed) == 0 {
+		return []GroupSummary{}, nil
+	}
+
+	names := make([]string, 0, len(listed))
+	for name := range listed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	descriptions, err := i.admin.DescribeConsumerGroups(names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe kafka consumer groups: %w", err)
+	}
+	states := make(map[string]*sarama.GroupDescription, len(descriptions))
+	for _, d := range descriptions {
+		states[d.GroupId] = d
+	}
+
+	groups := make([]GroupSummary, 0, len(names))
+	for _, name := range names {
+		group := GroupSummary{Group: name, ProtocolType: listed[name]}
+		if d := states[name]; d != nil {
+			group.State, group.Members = d.State, len(d.Members)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// Group returns a consumer group of the cluster with its members, the
+// partitions assigned to them and its lag.
+//
+// Parameters:
+//   - group: The consumer group ID.
+//
+// Returns:
+//   - GroupDescription: The consumer group, its members sorted by ID.
+//   - error: ErrGroupNotFound if the group does not exist, or an error if
+//     the group or its lag cannot be retrieved.
+func (i *Inspector) Group(group string) (GroupDescription, error) {
+	desc := GroupDescription{Group: group, Members: []MemberDescription{}}
+
+	descriptions, err := i.admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return desc, fmt.Errorf("failed to describe kafka consumer group %s: %w", group, err)
+	}
+	if len(descriptions) == 0 {
+		return desc, fmt.Errorf("%w: %s", ErrGroupNotFound, group)
+	}
+	d := descriptions[0]
+	if !errors.Is(d.Err, sarama.ErrNoError) {
+		return desc, fmt.Errorf("failed to describe kafka consumer group %s: %w", group, d.Err)
+	}
+	if d.State == "Dead" {
+		// The coordinator describes the unknown groups as dead
+		return desc, fmt.Errorf("%w: %s", ErrGroupNotFound, group)
+	}
+
+	desc.ProtocolType, desc.Protocol, desc.State = d.ProtocolType, d.Protocol, d.State
+	for id, m := range d.Members {
+		member := MemberDescription{MemberID: id, ClientID: m.ClientId, Host: m.ClientHost, Assignment: map[string][]int32{}}
+		if d.ProtocolType == consumerProtocol {
+			assignment, err := m.GetMemberAssignment()
+			if err != nil {
+				return desc, fmt.Errorf("failed to decode the assignment of member %s of kafka consumer group %s: %w", id, group, err)
+			}
+			if assignment != nil {
+				for topic, partitions := range assignment.Topics {
+					partitions = append([]int32(nil), partitions...)
+					sort.Slice(partitions, func(a, b int) bool { return partitions[a] < partitions[b] })
+					member.Assignment[topic] = partitions
+				}
+			}
+		}
+		desc.Members = append(desc.Members, member)
+	}
+	sort.Slice(desc.Members, func(a, b int) bool { return desc.Members[a].MemberID < desc.Members[b].MemberID })
+
+	if desc.Lag, err = i.Lag(group); err != nil {
+		return desc, err
+	}
+	return desc, nil
+}
+
+// consumerGroups returns the consumer groups of the cluster, without the
+// groups of the other protocols.
+func (i *Inspector) consumerGroups() ([]string, error) {
+	listed, err := i.admin.ListConsumerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kafka consumer groups: %w", err)
+	}
+	groups := make([]string, 0, len(listed))
+	for group, protocolType := range listed {
+		if protocolType == consumerProtocol || protocolType == "" {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
@@ -0,0 +1,90 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/bootstrap/service"
+	"github.com/xiebingnote/go-gin-project/library/config"
+
+	"github.com/BurntSushi/toml"
+)
+
+// brokerDialTimeout is the time waited for a broker of the Kafka cluster of
+// the integration tests to accept a connection.
+const brokerDialTimeout = 2 * time.Second
+
+var (
+	// initKafkaOnce initializes the Kafka service of the integration tests
+	// once.
+	initKafkaOnce sync.Once
+
+	// kafkaUnavailable is the reason why the integration tests are skipped,
+	// empty once the Kafka service is initialized.
+	kafkaUnavailable string
+)
+
+// requireKafka skips the integration test when no broker of the Kafka
+// cluster of conf/service/kafka.toml is reachable, and initializes the Kafka
+// service otherwise.
+//
+// The Kafka service is initialized by loading the configuration from a TOML
+// file and decoding it into the KafkaConfig struct, then initializing the
+// Kafka service with a background context.
+//
+// If there is an error getting the current working directory, or the Kafka
+// configuration file cannot be decoded, the function will panic with an
+// error message.
+func requireKafka(t *testing.T) {
+	t.Helper()
+
+	initKafkaOnce.Do(func() {
+		// Retrieve the current working directory
+		rootDir, err := os.Getwd()
+		if err != nil {
+			// Panic if there is an error getting the working directory
+			panic(err)
+		}
+
+		// Extract the root directory path by splitting on "/pkg"
+		dir := strings.Split(rootDir, "/pkg")
+		rootDir = dir[0]
+
+		// Load Kafka configuration from the specified TOML file
+		if _, err := toml.DecodeFile(rootDir+"/conf/service/kafka.toml", &config.KafkaConfig); err != nil {
+			// Panic if the Kafka configuration file cannot be decoded
+			panic(fmt.Sprintf("Failed to load Kafka configuration file: %v", err))
+		}
+
+		if !reachable(config.KafkaConfig.Kafka.Brokers) {
+			kafkaUnavailable = fmt.Sprintf("Skipping integration test - no Kafka broker reachable at %v",
+				config.KafkaConfig.Kafka.Brokers)
+			return
+		}
+
+		// Initialize the Kafka service with a background context
+		service.InitKafka(context.Background())
+	})
+
+	if kafkaUnavailable != "" {
+		t.Skip(kafkaUnavailable)
+	}
+}
+
+// reachable reports whether one of the addresses accepts a TCP connection.
+func reachable(addrs []string) bool {
+	for _, addr := range addrs {
+		conn, err := net.DialTimeout("tcp", addr, brokerDialTimeout)
+		if err == nil {
+			_ = conn.Close()
+			return true
+		}
+	}
+	return false
+}
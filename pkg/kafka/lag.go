@@ -0,0 +1,381 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// DefaultLagInterval is the default interval of the collection of the lag.
+const DefaultLagInterval = 30 * time.Second
+
+var (
+	// 消费者组各分区的延迟消息数
+	consumerGroupLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_group_lag",
+			Help: "Number of messages of a partition not consumed by a consumer group, the high water mark minus the committed offset",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+
+	// 延迟采集失败次数
+	lagCollectFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_lag_monitor_failures_total",
+			Help: "Total number of consumer groups whose lag could not be collected",
+		},
+		[]string{"group"},
+	)
+)
+
+// GroupLag is the lag of a consumer group, the number of messages of its
+// topics not consumed yet.
+type GroupLag struct {
+	Group  string     `json:"group"`
+	Lag    int64      `json:"lag"`
+	Topics []TopicLag `json:"topics"`
+}
+
+// TopicLag is the lag of a consumer group on a topic.
+type TopicLag struct {
+	Topic      string         `json:"topic"`
+	Lag        int64          `json:"lag"`
+	Partitions []PartitionLag `json:"partitions"`
+}
+
+// PartitionLag is the lag of a consumer group on a partition.
+type PartitionLag struct {
+	Partition     int32 `json:"partition"`
+	Committed     int64 `json:"committed"`
+	HighWaterMark int64 `json:"high_water_mark"`
+	Lag           int64 `json:"lag"`
+}
+
+// Lag returns the lag of a consumer group on the partitions it committed
+// offsets for, the partitions without committed offset being skipped.
+//
+// The committed offsets of all the topics are fetched at once, which
+// requires Kafka 0.10.2 or later.
+//
+// Parameters:
+//   - group: The consumer group ID.
+//
+// Returns:
+//   - GroupLag: The lag, the topics and the partitions in order.
+//   - error: An error if the committed offsets or the high water marks
+//     cannot be retrieved.
+func (i *Inspector) Lag(group string) (GroupLag, error) {
+	lag := GroupLag{Group: group, Topics: []TopicLag{}}
+
+	resp, err := i.admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return lag, fmt.Errorf("failed to fetch the offsets of kafka consumer group %s: %w", group, err)
+	}
+	if !errors.Is(resp.Err, sarama.ErrNoError) {
+		return lag, fmt.Errorf("failed to fetch the offsets of kafka consumer group %s: %w", group, resp.Err)
+	}
+
+	for topic, blocks := range resp.Blocks {
+		topicLag := TopicLag{Topic: topic}
+		for partition, block := range blocks {
+			if !errors.Is(block.Err, sarama.ErrNoError) {
+				return lag, fmt.Errorf("failed to fetch the offset of %s/%d for kafka consumer group %s: %w",
+					topic, partition, group, block.Err)
+			}
+			if block.Offset < 0 {
+				continue
+			}
+
+			hwm, err := i.client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+				// The offsets of a deleted topic are kept until they expire
+				continue
+			}
+			if err != nil {
+				return lag, fmt.Errorf("failed to get the high water mark of %s/%d: %w", topic, partition, err)
+			}
+
+			p := PartitionLag{Partition: partition, Committed: block.Offset, HighWaterMark: hwm, Lag: max(hwm-block.Offset, 0)}
+			topicLag.Partitions = append(topicLag.Partitions, p)
+			topicLag.Lag += p.Lag
+		}
+		if len(topicLag.Partitions) == 0 {
+			continue
+		}
+		sort.Slice(topicLag.Partitions, func(a, b int) bool {
+			return topicLag.Partitions[a].Partition < topicLag.Partitions[b].Partition
+		})
+		lag.Topics = append(lag.Topics, topicLag)
+		lag.Lag += topicLag.Lag
+	}
+	sort.Slice(lag.Topics, func(a, b int) bool { return lag.Topics[a].Topic < lag.Topics[b].Topic })
+	return lag, nil
+}
+
+// LagMonitorConfig is the configuration of a LagMonitor.
+type LagMonitorConfig struct {
+	// Interval is the interval of the collection, DefaultLagInterval if
+	// zero.
+	Interval time.Duration
+	// Groups are the monitored consumer groups, all the consumer groups of
+	// the cluster if empty.
+	Groups []string
+	// WarnThreshold is the lag of a group on a topic above which a warning
+	// is logged, zero for no warning.
+	WarnThreshold int64
+	// CriticalThreshold is the lag of a group on a topic above which an
+	// error is logged, zero for no error.
+	CriticalThreshold int64
+}
+
+// lagLevel is the alert level of the lag of a group on a topic.
+type lagLevel int
+
+// Alert levels.
+const (
+	lagOK lagLevel = iota
+	lagWarn
+	lagCritical
+)
+
+// lagKey is a topic of a consumer group.
+type lagKey struct {
+	group string
+	topic string
+}
+
+// LagMonitor periodically collects the lag of consumer groups, exports it
+// as the kafka_consumer_group_lag gauge and logs an alert when the lag of a
+// group on a topic crosses a threshold.
+//
+// An alert is logged when the lag reaches a higher level, and an info when
+// it is back below the warning threshold, the gauge tracking the lag in
+// between.
+type LagMonitor struct {
+	inspector *Inspector
+	cfg       LagMonitorConfig
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	stateMu sync.Mutex
+	series  map[string][][]string // group -> label values of its gauges
+	levels  map[lagKey]lagLevel
+}
+
+// NewLagMonitor creates a lag monitor.
+//
+// The monitor shares the client, which it does not close.
+//
+// Parameters:
+//   - client: The Kafka client, e.g. resource.KafkaClient.
+//   - cfg: The monitor configuration.
+//
+// Returns:
+//   - *LagMonitor: The monitor, started by Start.
+//   - error: An error if the controller of the cluster cannot be found.
+func NewLagMonitor(client sarama.Client, cfg LagMonitorConfig) (*LagMonitor, error) {
+	inspector, err := NewInspector(client)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultLagInterval
+	}
+	return &LagMonitor{
+		inspector: inspector,
+		cfg:       cfg,
+		series:    make(map[string][][]string),
+		levels:    make(map[lagKey]lagLevel),
+	}, nil
+}
+
+// Start starts collecting the lag in the background until Stop is called.
+// It does nothing if the monitor is started.
+func (m *LagMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel, m.done = cancel, make(chan struct{})
+	go m.run(ctx, m.done)
+}
+
+// Stop stops the monitor, waiting for the collection in progress.
+func (m *LagMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	<-m.done
+	m.cancel, m.done = nil, nil
+}
+
+// run collects the lag until ctx is canceled.
+func (m *LagMonitor) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	for {
+		if _, err := m.CollectOnce(); err != nil {
+			m.logger().Error("Failed to collect kafka consumer group lag", zap.Error(err))
+		}
+		if !sleep(ctx, m.cfg.Interval) {
+			return
+		}
+	}
+}
+
+// CollectOnce collects the lag of the monitored groups, updates the gauges
+// and logs the alerts. The groups whose lag cannot be collected keep their
+// previous gauges.
+//
+// Returns:
+//   - []GroupLag: The lag of the groups collected, sorted by group.
+//   - error: An error if the groups cannot be listed, or joining the errors
+//     of the groups whose lag cannot be collected.
+func (m *LagMonitor) CollectOnce() ([]GroupLag, error) {
+	groups := m.cfg.Groups
+	if len(groups) == 0 {
+		var err error
+		if groups, err = m.inspector.consumerGroups(); err != nil {
+			return nil, err
+		}
+	}
+
+	lags := make([]GroupLag, 0, len(groups))
+	var errs []error
+	for _, group := range groups {
+		lag, err := m.inspector.Lag(group)
+		if err != nil {
+			lagCollectFailuresTotal.WithLabelValues(group).Inc()
+			errs = append(errs, err)
+			continue
+		}
+		lags = append(lags, lag)
+	}
+	sort.Slice(lags, func(a, b int) bool { return lags[a].Group < lags[b].Group })
+
+	m.record(groups, lags)
+	return lags, errors.Join(errs...)
+}
+
+// record updates the gauges and the alert levels with the collected lag,
+// removing the gauges of the partitions and the groups which are gone.
+func (m *LagMonitor) record(groups []string, lags []GroupLag) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+
+	monitored := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		monitored[group] = true
+	}
+	for group, series := range m.series {
+		if !monitored[group] {
+			for _, labels := range series {
+				consumerGroupLag.DeleteLabelValues(labels...)
+			}
+			delete(m.series, group)
+		}
+	}
+	for key := range m.levels {
+		if !monitored[key.group] {
+			delete(m.levels, key)
+		}
+	}
+
+	for _, lag := range lags {
+		var series [][]string
+		current := make(map[string]bool)
+		for _, topic := range lag.Topics {
+			for _, p := range topic.Partitions {
+				labels := []string{lag.Group, topic.Topic, strconv.Itoa(int(p.Partition))}
+				consumerGroupLag.WithLabelValues(labels...).Set(float64(p.Lag))
+				series = append(series, labels)
+				current[topic.Topic+"/"+labels[2]] = true
+			}
+			m.alert(lagKey{group: lag.Group, topic: topic.Topic}, topic.Lag)
+		}
+		for _, labels := range m.series[lag.Group] {
+			if !current[labels[1]+"/"+labels[2]] {
+				consumerGroupLag.DeleteLabelValues(labels...)
+			}
+		}
+		m.series[lag.Group] = series
+
+		// The topics the group no longer has offsets for are not lagging
+		for key := range m.levels {
+			if key.group == lag.Group && !lag.hasTopic(key.topic) {
+				delete(m.levels, key)
+			}
+		}
+	}
+}
+
+// alert logs the change of the alert level of the lag of a group on a
+// topic.
+func (m *LagMonitor) alert(key lagKey, lag int64) {
+	level := lagOK
+	switch {
+	case m.cfg.CriticalThreshold > 0 && lag >= m.cfg.CriticalThreshold:
+		level = lagCritical
+	case m.cfg.WarnThreshold > 0 && lag >= m.cfg.WarnThreshold:
+		level = lagWarn
+	}
+
+	previous := m.levels[key]
+	if level == lagOK {
+		delete(m.levels, key)
+	} else {
+		m.levels[key] = level
+	}
+
+	fields := []zap.Field{zap.String("group", key.group), zap.String("topic", key.topic), zap.Int64("lag", lag)}
+	switch {
+	case level == lagCritical && previous < lagCritical:
+		m.logger().Error("Kafka consumer group lag above the critical threshold",
+			append(fields, zap.Int64("threshold", m.cfg.CriticalThreshold))...)
+	case level == lagWarn && previous < lagWarn:
+		m.logger().Warn("Kafka consumer group lag above the warning threshold",
+			append(fields, zap.Int64("threshold", m.cfg.WarnThreshold))...)
+	case level == lagOK && previous > lagOK:
+		m.logger().Info("Kafka consumer group lag back below the thresholds", fields...)
+	}
+}
+
+// logger returns the Kafka logger, a no-op logger if it is not initialized.
+func (m *LagMonitor) logger() *zap.Logger {
+	if resource.KafkaLogger == nil {
+		return zap.NewNop()
+	}
+	return resource.KafkaLogger
+}
+
+// hasTopic reports whether the group has a lag on a topic.
+func (g GroupLag) hasTopic(topic string) bool {
+	for _, t := range g.Topics {
+		if t.Topic == topic {
+			return true
+		}
+	}
+	return false
+}
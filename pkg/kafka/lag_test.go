@@ -0,0 +1,189 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// memberAssignment encodes the assignment of the partitions of a topic to
+// a consumer group member.
+func memberAssignment(topic string, partitions ...int32) []byte {
+	b := binary.BigEndian.AppendUint16(nil, 0) // version
+	b = binary.BigEndian.AppendUint32(b, 1)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(topic)))
+	b = append(b, topic...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(partitions)))
+	for _, partition := range partitions {
+		b = binary.BigEndian.AppendUint32(b, uint32(partition))
+	}
+	return binary.BigEndian.AppendUint32(b, 0xffffffff) // no user data
+}
+
+// newLagTestBroker starts a mock broker controlling a cluster with the two
+// partitions of the orders topic, consumed by the billing group which
+// committed the offset 10 of partition 0, and a connect group.
+func newLagTestBroker(t *testing.T) (*sarama.MockBroker, *sarama.MockOffsetFetchResponse) {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	offsets := sarama.NewMockOffsetFetchResponse(t).
+		SetOffset("billing", "orders", 0, 10, "", sarama.ErrNoError).
+		SetOffset("billing", "orders", 1, -1, "", sarama.ErrNoError)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, sarama.OffsetOldest, 5).
+			SetOffset("orders", 0, sarama.OffsetNewest, 50).
+			SetOffset("orders", 1, sarama.OffsetOldest, 0).
+			SetOffset("orders", 1, sarama.OffsetNewest, 7),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "billing", broker).
+			SetCoordinator(sarama.CoordinatorGroup, "unknown", broker).
+			SetCoordinator(sarama.CoordinatorGroup, "connect-sink", broker),
+		"ListGroupsRequest": sarama.NewMockListGroupsResponse(t).
+			AddGroup("billing", "consumer").
+			AddGroup("connect-sink", "connect"),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("billing", &sarama.GroupDescription{
+				GroupId:      "billing",
+				State:        "Stable",
+				ProtocolType: "consumer",
+				Protocol:     "range",
+				Members: map[string]*sarama.GroupMemberDescription{
+					"billing-1": {
+						ClientId:         "billing",
+						ClientHost:       "/10.0.0.1",
+						MemberAssignment: memberAssignment("orders", 1, 0),
+					},
+				},
+			}),
+		"OffsetFetchRequest": offsets,
+	})
+	return broker, offsets
+}
+
+// TestInspector tests the description of the topics and the consumer
+// groups of a cluster.
+func TestInspector(t *testing.T) {
+	broker, _ := newLagTestBroker(t)
+	inspector, err := NewInspector(newTestClient(t, broker))
+	if err != nil {
+		t.Fatalf("NewInspector() error = %v", err)
+	}
+
+	topics, err := inspector.Topics()
+	if err != nil {
+		t.Fatalf("Topics() error = %v", err)
+	}
+	if fmt.Sprint(topics) != "[{orders false 2 1}]" {
+		t.Errorf("Topics() = %v, want the orders topic", topics)
+	}
+
+	topic, err := inspector.Topic("orders")
+	if err != nil {
+		t.Fatalf("Topic() error = %v", err)
+	}
+	if len(topic.Partitions) != 2 || topic.Partitions[0].OldestOffset != 5 || topic.Partitions[0].NewestOffset != 50 ||
+		topic.Partitions[1].Partition != 1 || topic.Partitions[1].NewestOffset != 7 {
+		t.Errorf("Topic() = %+v, want the offsets of the partitions", topic)
+	}
+	if _, err := inspector.Topic("missing"); !errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+		t.Errorf("Topic() error = %v, want %v", err, sarama.ErrUnknownTopicOrPartition)
+	}
+
+	groups, err := inspector.Groups()
+	if err != nil {
+		t.Fatalf("Groups() error = %v", err)
+	}
+	if fmt.Sprint(groups) != "[{billing consumer Stable 1} {connect-sink connect Dead 0}]" {
+		t.Errorf("Groups() = %v, want the two groups", groups)
+	}
+
+	group, err := inspector.Group("billing")
+	if err != nil {
+		t.Fatalf("Group() error = %v", err)
+	}
+	if len(group.Members) != 1 || fmt.Sprint(group.Members[0].Assignment) != "map[orders:[0 1]]" ||
+		group.Members[0].Host != "/10.0.0.1" || group.Protocol != "range" {
+		t.Errorf("Group() = %+v, want the member and its partitions", group)
+	}
+	if group.Lag.Lag != 40 || len(group.Lag.Topics) != 1 || len(group.Lag.Topics[0].Partitions) != 1 {
+		t.Errorf("Group() lag = %+v, want 40 on the committed partition", group.Lag)
+	}
+	if _, err := inspector.Group("unknown"); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("Group() error = %v, want %v", err, ErrGroupNotFound)
+	}
+}
+
+// TestLagMonitor tests the gauges of the collected lag and the alerts
+// logged when the lag crosses the thresholds.
+func TestLagMonitor(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	previous := resource.KafkaLogger
+	resource.KafkaLogger = zap.New(core)
+	t.Cleanup(func() {
+		resource.KafkaLogger = previous
+	})
+
+	broker, offsets := newLagTestBroker(t)
+	monitor, err := NewLagMonitor(newTestClient(t, broker), LagMonitorConfig{WarnThreshold: 10, CriticalThreshold: 100})
+	if err != nil {
+		t.Fatalf("NewLagMonitor() error = %v", err)
+	}
+
+	// The connect group is not monitored
+	lags, err := monitor.CollectOnce()
+	if err != nil {
+		t.Fatalf("CollectOnce() error = %v", err)
+	}
+	if len(lags) != 1 || lags[0].Group != "billing" || lags[0].Lag != 40 {
+		t.Errorf("CollectOnce() = %+v, want the lag of the billing group", lags)
+	}
+	if v := testutil.ToFloat64(consumerGroupLag.WithLabelValues("billing", "orders", "0")); v != 40 {
+		t.Errorf("kafka_consumer_group_lag = %v, want 40", v)
+	}
+	if n := logs.FilterLevelExact(zapcore.WarnLevel).Len(); n != 1 {
+		t.Errorf("%d warnings logged, want 1", n)
+	}
+
+	// The alert is logged once while the lag stays above the threshold
+	if _, err := monitor.CollectOnce(); err != nil {
+		t.Fatalf("CollectOnce() error = %v", err)
+	}
+	if n := logs.FilterLevelExact(zapcore.WarnLevel).Len(); n != 1 {
+		t.Errorf("%d warnings logged, want 1", n)
+	}
+
+	offsets.SetOffset("billing", "orders", 0, 45, "", sarama.ErrNoError)
+	if _, err := monitor.CollectOnce(); err != nil {
+		t.Fatalf("CollectOnce() error = %v", err)
+	}
+	if n := logs.FilterMessage("Kafka consumer group lag back below the thresholds").Len(); n != 1 {
+		t.Errorf("%d recoveries logged, want 1", n)
+	}
+	if v := testutil.ToFloat64(consumerGroupLag.WithLabelValues("billing", "orders", "0")); v != 5 {
+		t.Errorf("kafka_consumer_group_lag = %v, want 5", v)
+	}
+
+	// The gauges of the groups which are gone are removed
+	monitor.record(nil, nil)
+	if n := testutil.CollectAndCount(consumerGroupLag); n != 0 {
+		t.Errorf("%d kafka_consumer_group_lag series, want 0", n)
+	}
+}
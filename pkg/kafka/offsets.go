@@ -0,0 +1,304 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// StartPosition is where the consumption of a partition starts when its
+// consumer has no committed offset: the earliest or the latest offset, or
+// the first offset at or after a time.
+type StartPosition struct {
+	offset int64 // sarama.OffsetOldest or sarama.OffsetNewest, if at is zero
+	at     time.Time
+}
+
+// Start positions.
+var (
+	StartEarliest = StartPosition{offset: sarama.OffsetOldest}
+	StartLatest   = StartPosition{offset: sarama.OffsetNewest}
+)
+
+// ErrGroupActive is returned when the offsets of a consumer group having
+// members are reset.
+var ErrGroupActive = errors.New("kafka consumer group has active members")
+
+// StartAt returns the start position of the first offset at or after t.
+//
+// Parameters:
+//   - t: The time of the first message to consume.
+//
+// Returns:
+//   - StartPosition: The start position.
+func StartAt(t time.Time) StartPosition {
+	return StartPosition{offset: sarama.OffsetOldest, at: t}
+}
+
+// ParseStartPosition parses a start position: "earliest", "latest" or an
+// RFC 3339 time, e.g. "2025-01-02T15:04:05Z". The empty string is the
+// earliest offset.
+//
+// Parameters:
+//   - s: The start position.
+//
+// Returns:
+//   - StartPosition: The parsed start position.
+//   - error: An error if s is not a start position.
+func ParseStartPosition(s string) (StartPosition, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "earliest":
+		return StartEarliest, nil
+	case "latest":
+		return StartLatest, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	if err != nil {
+		return StartPosition{}, fmt.Errorf("invalid kafka start position %q, must be earliest, latest or an RFC 3339 time", s)
+	}
+	return StartAt(t), nil
+}
+
+// IsTime reports whether the start position is a time.
+func (p StartPosition) IsTime() bool {
+	return !p.at.IsZero()
+}
+
+// InitialOffset returns the sarama initial offset of the start position,
+// sarama.OffsetOldest for a time, which is resolved by Offset.
+func (p StartPosition) InitialOffset() int64 {
+	return p.offset
+}
+
+// String returns the start position as parsed by ParseStartPosition.
+func (p StartPosition) String() string {
+	switch {
+	case p.IsTime():
+		return p.at.Format(time.RFC3339)
+	case p.offset == sarama.OffsetNewest:
+		return "latest"
+	default:
+		return "earliest"
+	}
+}
+
+// Offset returns the offset of a partition the consumption starts from.
+//
+// Parameters:
+//   - client: The Kafka client.
+//   - topic: The topic of the partition.
+//   - partition: The partition.
+//
+// Returns:
+//   - int64: The offset of the first message to consume, the high water
+//     mark if no message is at or after the time of the position.
+//   - error: An error if the offset cannot be retrieved.
+func (p StartPosition) Offset(client sarama.Client, topic string, partition int32) (int64, error) {
+	at := p.offset
+	if p.IsTime() {
+		at = p.at.UnixMilli()
+	}
+
+	offset, err := client.GetOffset(topic, partition, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the %s offset of %s/%d: %w", p, topic, partition, err)
+	}
+	if offset < 0 {
+		// No message is at or after the time yet
+		offset, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get the latest offset of %s/%d: %w", topic, partition, err)
+		}
+	}
+	return offset, nil
+}
+
+// ResetResult is the result of the reset of the offsets of a consumer
+// group.
+type ResetResult struct {
+	Group    string          `json:"group"`
+	Topic    string          `json:"topic"`
+	Position string          `json:"position"`
+	Offsets  map[int32]int64 `json:"offsets"` // by partition
+}
+
+// ResetOffsets resets the offsets of a consumer group on a topic to a start
+// position, e.g. to process the messages again from a time after a bug was
+// fixed, or to skip a backlog.
+//
+// The offsets are committed to Kafka, and to the database of the handler of
+// the topic if it is exactly once, see WithExactlyOnce. The group must have
+// no active member, e.g. its consumers must be stopped, so that they do not
+// overwrite the offsets.
+//
+// The offsets of an exactly once handler are stored first, and the
+// idempotency keys of the messages consumed again, from the reset offset to
+// the stored one, are removed in the same transaction so that they are not
+// skipped as duplicates. Only the keys made of the topic, partition and
+// offset are removed: the messages carrying a HeaderIdempotencyKey or
+// HeaderOutboxID header are still skipped until their key is purged.
+//
+// Parameters:
+//   - ctx: The context of the reset.
+//   - client: The Kafka client.
+//   - group: The consumer group ID.
+//   - topic: The topic whose offsets are reset.
+//   - position: The start position the offsets are reset to.
+//
+// Returns:
+//   - ResetResult: The offsets committed by partition.
+//   - error: ErrGroupActive if the group has members,
+//     sarama.ErrUnknownTopicOrPartition if the topic does not exist, or an
+//     error if the offsets cannot be committed.
+func (r *Registry) ResetOffsets(ctx context.Context, client sarama.Client, group, topic string, position StartPosition) (ResetResult, error) {
+	result := ResetResult{Group: group, Topic: topic, Position: position.String(), Offsets: make(map[int32]int64)}
+
+	coordinator, err := client.Coordinator(group)
+	if err != nil {
+		return result, fmt.Errorf("failed to find the coordinator of consumer group %s: %w", group, err)
+	}
+	if err := checkGroupInactive(coordinator, group); err != nil {
+		return result, err
+	}
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return result, err
+	}
+	for _, partition := range partitions {
+		offset, err := position.Offset(client, topic, partition)
+		if err != nil {
+			return result, err
+		}
+		result.Offsets[partition] = offset
+	}
+
+	// The offsets of the database are the ones an exactly once handler
+	// resumes from, they are stored first so that a failure leaves both
+	// stores unchanged
+	r.mu.Lock()
+	h := r.handlers[group][topic]
+	r.mu.Unlock()
+	if h != nil && h.txDB != nil {
+		db := h.txDB()
+		if db == nil {
+			return result, fmt.Errorf("database of the exactly once kafka handler of topic %s, group %s is not initialized", topic, group)
+		}
+		if err := resetStoredOffsets(ctx, db, group, topic, result.Offsets); err != nil {
+			return result, err
+		}
+	}
+
+	if err := commitOffsets(coordinator, group, topic, result.Offsets); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// checkGroupInactive returns ErrGroupActive if a consumer group has members.
+func checkGroupInactive(coordinator *sarama.Broker, group string) error {
+	resp, err := coordinator.DescribeGroups(&sarama.DescribeGroupsRequest{Groups: []string{group}})
+	if err != nil {
+		return fmt.Errorf("failed to describe consumer group %s: %w", group, err)
+	}
+	for _, g := range resp.Groups {
+		if g.GroupId != group {
+			continue
+		}
+		if !errors.Is(g.Err, sarama.ErrNoError) {
+			return fmt.Errorf("failed to describe consumer group %s: %w", group, g.Err)
+		}
+		if g.State != "Empty" && g.State != "Dead" {
+			return fmt.Errorf("%w: group %s is %s", ErrGroupActive, group, g.State)
+		}
+	}
+	return nil
+}
+
+// commitOffsets commits the offsets of the partitions of a topic for a
+// consumer group without member.
+func commitOffsets(coordinator *sarama.Broker, group, topic string, offsets map[int32]int64) error {
+	req := &sarama.OffsetCommitRequest{
+		Version:                 2,
+		ConsumerGroup:           group,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+		RetentionTime:           -1,
+	}
+	for partition, offset := range offsets {
+		req.AddBlock(topic, partition, offset, 0, "")
+	}
+
+	resp, err := coordinator.CommitOffset(req)
+	if err != nil {
+		return fmt.Errorf("failed to commit the offsets of consumer group %s: %w", group, err)
+	}
+	for partition, kerr := range resp.Errors[topic] {
+		if !errors.Is(kerr, sarama.ErrNoError) {
+			return fmt.Errorf("failed to commit the offset of %s/%d for consumer group %s: %w", topic, partition, group, kerr)
+		}
+	}
+	return nil
+}
+
+// committedOffsets returns the offsets committed by a consumer group for
+// the claimed partitions, without the partitions having no committed offset.
+func committedOffsets(client sarama.Client, group string, claims map[string][]int32) (map[string]map[int32]int64, error) {
+	coordinator, err := client.Coordinator(group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the coordinator of consumer group %s: %w", group, err)
+	}
+
+	req := &sarama.OffsetFetchRequest{Version: 1, ConsumerGroup: group}
+	for topic, partitions := range claims {
+		for _, partition := range partitions {
+			req.AddPartition(topic, partition)
+		}
+	}
+	resp, err := coordinator.FetchOffset(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the offsets of consumer group %s: %w", group, err)
+	}
+
+	committed := make(map[string]map[int32]int64, len(claims))
+	for topic, partitions := range claims {
+		for _, partition := range partitions {
+			block := resp.GetBlock(topic, partition)
+			if block == nil || block.Offset < 0 {
+				continue
+			}
+			if !errors.Is(block.Err, sarama.ErrNoError) {
+				return nil, fmt.Errorf("failed to fetch the offset of %s/%d for consumer group %s: %w",
+					topic, partition, group, block.Err)
+			}
+			if committed[topic] == nil {
+				committed[topic] = make(map[int32]int64)
+			}
+			committed[topic][partition] = block.Offset
+		}
+	}
+	return committed, nil
+}
+
+// seek sets the offset a claimed partition is consumed from, before the
+// session consumes it.
+func seek(session sarama.ConsumerGroupSession, topic string, partition int32, offset int64) {
+	// MarkOffset only moves the offset forward and ResetOffset backward
+	session.MarkOffset(topic, partition, offset, "")
+	session.ResetOffset(topic, partition, offset, "")
+}
+
+// sortedPartitions returns the partitions of offsets in order.
+func sortedPartitions(offsets map[int32]int64) []int32 {
+	partitions := make([]int32, 0, len(offsets))
+	for partition := range offsets {
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	return partitions
+}
@@ -0,0 +1,478 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+
+	"github.com/IBM/sarama"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestParseStartPosition tests the parsing of the start positions.
+func TestParseStartPosition(t *testing.T) {
+	at := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want StartPosition
+		err  bool
+	}{
+		{in: "", want: StartEarliest},
+		{in: "earliest", want: StartEarliest},
+		{in: "Latest", want: StartLatest},
+		{in: "2025-01-02T15:04:05Z", want: StartAt(at)},
+		{in: "yesterday", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStartPosition(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseStartPosition(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !tt.err && (got.offset != tt.want.offset || !got.at.Equal(tt.want.at)) {
+			t.Errorf("ParseStartPosition(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	if StartLatest.InitialOffset() != sarama.OffsetNewest || StartAt(at).InitialOffset() != sarama.OffsetOldest {
+		t.Error("InitialOffset() does not match the position")
+	}
+	if s := StartAt(at).String(); s != "2025-01-02T15:04:05Z" {
+		t.Errorf("String() = %s, want the RFC 3339 time", s)
+	}
+}
+
+// newTestBroker starts a mock broker leading the two partitions of the
+// orders topic and coordinating the billing group, whose partition 1 has no
+// message after at.
+func newTestBroker(t *testing.T, at time.Time, state string) (*sarama.MockBroker, *sarama.MockOffsetCommitResponse) {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	commits := sarama.NewMockOffsetCommitResponse(t)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, at.UnixMilli(), 42).
+			SetOffset("orders", 0, sarama.OffsetNewest, 50).
+			SetOffset("orders", 1, at.UnixMilli(), -1).
+			SetOffset("orders", 1, sarama.OffsetNewest, 7),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "billing", broker),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("billing", &sarama.GroupDescription{GroupId: "billing", State: state}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("billing", "orders", 0, 10, "", sarama.ErrNoError).
+			SetOffset("billing", "orders", 1, -1, "", sarama.ErrNoError),
+		"OffsetCommitRequest": commits,
+	})
+	return broker, commits
+}
+
+// newTestClient creates a client of a mock broker.
+func newTestClient(t *testing.T, broker *sarama.MockBroker) sarama.Client {
+	t.Helper()
+
+	client, err := sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return client
+}
+
+// TestStartPositionOffset tests the resolution of a time start position,
+// the latest offset being used when no message is after the time.
+func TestStartPositionOffset(t *testing.T) {
+	at := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	broker, _ := newTestBroker(t, at, "Empty")
+	client := newTestClient(t, broker)
+
+	for partition, want := range map[int32]int64{0: 42, 1: 7} {
+		got, err := StartAt(at).Offset(client, "orders", partition)
+		if err != nil {
+			t.Fatalf("Offset(%d) error = %v", partition, err)
+		}
+		if got != want {
+			t.Errorf("Offset(%d) = %d, want %d", partition, got, want)
+		}
+	}
+}
+
+// TestResetOffsets tests the reset of the offsets of an inactive group, and
+// the rejection of the reset of an active group.
+func TestResetOffsets(t *testing.T) {
+	at := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	broker, commits := newTestBroker(t, at, "Empty")
+	client := newTestClient(t, broker)
+
+	result, err := NewRegistry().ResetOffsets(context.Background(), client, "billing", "orders", StartAt(at))
+	if err != nil {
+		t.Fatalf("ResetOffsets() error = %v", err)
+	}
+	if fmt.Sprint(result.Offsets) != "map[0:42 1:7]" || result.Position != "2025-01-02T15:04:05Z" {
+		t.Errorf("ResetOffsets() = %+v, want the offsets at the time", result)
+	}
+
+	commits.SetError("billing", "orders", 1, sarama.ErrOffsetMetadataTooLarge)
+	if _, err := NewRegistry().ResetOffsets(context.Background(), client, "billing", "orders", StartAt(at)); !errors.Is(err, sarama.ErrOffsetMetadataTooLarge) {
+		t.Errorf("ResetOffsets() error = %v, want the commit error", err)
+	}
+
+	broker, _ = newTestBroker(t, at, "Stable")
+	client = newTestClient(t, broker)
+	if _, err := NewRegistry().ResetOffsets(context.Background(), client, "billing", "orders", StartEarliest); !errors.Is(err, ErrGroupActive) {
+		t.Errorf("ResetOffsets() error = %v, want %v", err, ErrGroupActive)
+	}
+}
+
+// recordingConn is a database connection recording the executed
+// statements, whose queries of the consumer offset table return offsets.
+type recordingConn struct {
+	mu         sync.Mutex
+	statements []string
+	args       [][]driver.NamedValue
+	offsets    map[int32]int64 // stored next offsets of billing/orders
+	failOn     string          // statements containing it fail
+}
+
+func (c *recordingConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *recordingConn) Driver() driver.Driver                        { return nil }
+func (c *recordingConn) Prepare(string) (driver.Stmt, error)          { return nil, driver.ErrSkip }
+func (c *recordingConn) Close() error                                 { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+func (c *recordingConn) Commit() error   { c.record("COMMIT", nil); return nil }
+func (c *recordingConn) Rollback() error { c.record("ROLLBACK", nil); return nil }
+
+func (c *recordingConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.record("BEGIN", nil)
+	return c, nil
+}
+
+func (c *recordingConn) record(statement string, args []driver.NamedValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, statement)
+	c.args = append(c.args, args)
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query, args)
+	if c.failOn != "" && strings.Contains(query, c.failOn) {
+		return nil, errors.New("database failure")
+	}
+	return recordingResult{}, nil
+}
+
+// recordingResult is the result of a statement affecting one row.
+type recordingResult struct{}
+
+func (recordingResult) LastInsertId() (int64, error) { return 0, nil }
+func (recordingResult) RowsAffected() (int64, error) { return 1, nil }
+
+func (c *recordingConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query, args)
+	rows := &offsetRows{}
+	for partition, offset := range c.offsets {
+		rows.values = append(rows.values, []driver.Value{"billing", "orders", int64(partition), offset, time.Now()})
+	}
+	return rows, nil
+}
+
+// offsetRows are rows of the consumer offset table.
+type offsetRows struct {
+	values [][]driver.Value
+}
+
+func (r *offsetRows) Columns() []string {
+	return []string{"consumer_group", "topic", "partition_id", "next_offset", "updated_at"}
+}
+
+func (r *offsetRows) Close() error { return nil }
+
+func (r *offsetRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// newRecordingDB returns a MySQL database of a recording connection.
+func newRecordingDB(t *testing.T, conn *recordingConn) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(conn),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	return db
+}
+
+// TestResetOffsetsExactlyOnce tests that the reset of an exactly once
+// handler stores its offsets and removes the idempotency keys of the
+// messages consumed again before committing to Kafka, and that Kafka is
+// left unchanged when the database fails.
+func TestResetOffsetsExactlyOnce(t *testing.T) {
+	at := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	broker, _ := newTestBroker(t, at, "Empty")
+	client := newTestClient(t, broker)
+
+	// Partition 0 is reset from 45 back to 42, partition 1 forward to 7
+	conn := &recordingConn{offsets: map[int32]int64{0: 45, 1: 3}}
+	registry := NewRegistry()
+	if err := RegisterTo(registry, "orders", "billing", func(context.Context, *pkgproto.TestMessage, Meta) error {
+		return nil
+	}, WithExactlyOnce(func() *gorm.DB { return newRecordingDB(t, conn) })); err != nil {
+		t.Fatalf("RegisterTo() error = %v", err)
+	}
+
+	if _, err := registry.ResetOffsets(context.Background(), client, "billing", "orders", StartAt(at)); err != nil {
+		t.Fatalf("ResetOffsets() error = %v", err)
+	}
+
+	var deleted []string
+	for i, statement := range conn.statements {
+		if !strings.HasPrefix(statement, "DELETE FROM `tb_kafka_processed_message`") {
+			continue
+		}
+		for _, arg := range conn.args[i][1:] {
+			deleted = append(deleted, fmt.Sprint(arg.Value))
+		}
+	}
+	if fmt.Sprint(deleted) != "[orders/0/42 orders/0/43 orders/0/44]" {
+		t.Errorf("deleted idempotency keys = %v, want the keys of partition 0 from 42 to 44", deleted)
+	}
+	if last := conn.statements[len(conn.statements)-1]; last != "COMMIT" {
+		t.Errorf("last statement = %s, want the transaction committed", last)
+	}
+	if !committedToKafka(broker) {
+		t.Error("ResetOffsets() did not commit the offsets to Kafka")
+	}
+
+	// A database failure leaves the Kafka offsets unchanged
+	broker, _ = newTestBroker(t, at, "Empty")
+	client = newTestClient(t, broker)
+	conn.failOn, conn.statements, conn.args = "INSERT INTO `tb_kafka_consumer_offset`", nil, nil
+	if _, err := registry.ResetOffsets(context.Background(), client, "billing", "orders", StartAt(at)); err == nil {
+		t.Fatal("ResetOffsets() succeeded despite the database failure")
+	}
+	if last := conn.statements[len(conn.statements)-1]; last != "ROLLBACK" {
+		t.Errorf("last statement = %s, want the transaction rolled back", last)
+	}
+	if committedToKafka(broker) {
+		t.Error("ResetOffsets() committed the offsets to Kafka despite the database failure")
+	}
+}
+
+// TestDeleteProcessedBatches tests that the idempotency keys of a large
+// range are removed in batches.
+func TestDeleteProcessedBatches(t *testing.T) {
+	conn := &recordingConn{}
+	db := newRecordingDB(t, conn)
+
+	if err := deleteProcessed(db, "billing", "orders", 2, 10, 10+2*processedDeleteBatch+1); err != nil {
+		t.Fatalf("deleteProcessed() error = %v", err)
+	}
+
+	var sizes []int
+	var last any
+	for i, statement := range conn.statements {
+		if strings.HasPrefix(statement, "DELETE") {
+			sizes = append(sizes, len(conn.args[i])-1)
+			last = conn.args[i][len(conn.args[i])-1].Value
+		}
+	}
+	if want := fmt.Sprint([]int{processedDeleteBatch, processedDeleteBatch, 1}); fmt.Sprint(sizes) != want {
+		t.Errorf("keys per statement = %v, want %s", sizes, want)
+	}
+	if last != fmt.Sprintf("orders/2/%d", 10+2*processedDeleteBatch) {
+		t.Errorf("last key = %v, want the key before the end offset", last)
+	}
+}
+
+// committedToKafka reports whether the offsets were committed to a mock
+// broker.
+func committedToKafka(broker *sarama.MockBroker) bool {
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.OffsetCommitRequest); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// seekSession is a session tracking the offsets of its claims like the
+// offset manager of a consumer group, and counting its commits.
+type seekSession struct {
+	*testSession
+
+	claims  map[string][]int32
+	mu      sync.Mutex
+	offsets map[string]int64 // by topic/partition, -1 if not committed
+	commits int
+}
+
+func (s *seekSession) Claims() map[string][]int32 { return s.claims }
+
+func (s *seekSession) MarkOffset(topic string, partition int32, offset int64, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := fmt.Sprintf("%s/%d", topic, partition)
+	if offset > s.offsets[key] {
+		s.offsets[key] = offset
+	}
+}
+
+func (s *seekSession) ResetOffset(topic string, partition int32, offset int64, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := fmt.Sprintf("%s/%d", topic, partition)
+	if offset <= s.offsets[key] {
+		s.offsets[key] = offset
+	}
+}
+
+func (s *seekSession) Commit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commits++
+}
+
+// TestSetupStartPosition tests that the partitions without committed
+// offset are consumed from the time start position.
+func TestSetupStartPosition(t *testing.T) {
+	at := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	broker, _ := newTestBroker(t, at, "Empty")
+	client := newTestClient(t, broker)
+
+	registry := NewRegistry()
+	if err := RegisterTo(registry, "orders", "billing", func(context.Context, *pkgproto.TestMessage, Meta) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("RegisterTo() error = %v", err)
+	}
+	runner := newGroupRunner("billing", nil, registry.handlers["billing"], RunnerConfig{StartPosition: StartAt(at), Client: client})
+
+	session := &seekSession{
+		testSession: &testSession{ctx: context.Background()},
+		claims:      map[string][]int32{"orders": {0, 1}},
+		offsets:     map[string]int64{"orders/0": 10, "orders/1": -1},
+	}
+	if err := runner.Setup(session); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if got := fmt.Sprint(session.offsets); got != "map[orders/0:10 orders/1:7]" {
+		t.Errorf("offsets = %s, want the committed offset and the start position", got)
+	}
+}
+
+// TestManualCommit tests that the offset of each processed message is
+// committed.
+func TestManualCommit(t *testing.T) {
+	registry := NewRegistry()
+	if err := RegisterTo(registry, "orders", "billing", func(context.Context, *pkgproto.TestMessage, Meta) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("RegisterTo() error = %v", err)
+	}
+	runner := newGroupRunner("billing", nil, registry.handlers["billing"], RunnerConfig{ManualCommit: true})
+
+	session := &seekSession{testSession: &testSession{ctx: context.Background()}, offsets: map[string]int64{}}
+	if err := runner.ConsumeClaim(session, newTestClaim("orders", testMessage(t, 1), testMessage(t, 2))); err != nil {
+		t.Fatalf("ConsumeClaim() error = %v", err)
+	}
+	if session.commits != 2 || fmt.Sprint(session.marked) != "[0 1]" {
+		t.Errorf("commits = %d, marked = %v, want each message committed", session.commits, session.marked)
+	}
+}
+
+// TestExactlyOnceFailureOffset tests that the offset of a failed message of
+// an exactly once handler is stored once the message is retried, so that
+// the stored offset does not stay behind the marked one.
+func TestExactlyOnceFailureOffset(t *testing.T) {
+	registry := NewRegistry()
+	if err := RegisterTo(registry, "orders", "billing", func(context.Context, *pkgproto.TestMessage, Meta) error {
+		return errors.New("failed")
+	}, WithRetryPolicy(RetryPolicy{Delays: []time.Duration{time.Minute}})); err != nil {
+		t.Fatalf("RegisterTo() error = %v", err)
+	}
+
+	producer := &testProducer{}
+	runner := newGroupRunner("billing", nil, registry.handlers["billing"], RunnerConfig{Producer: producer})
+	conn := &recordingConn{}
+	for _, route := range runner.routes {
+		route.db = newRecordingDB(t, conn)
+	}
+
+	session := &testSession{ctx: context.Background()}
+	if err := runner.ConsumeClaim(session, newTestClaim("orders", testMessage(t, 1))); err != nil {
+		t.Fatalf("ConsumeClaim() error = %v", err)
+	}
+	if len(producer.sent) != 1 || fmt.Sprint(session.marked) != "[0]" {
+		t.Fatalf("sent %d messages, marked = %v, want the message retried and marked", len(producer.sent), session.marked)
+	}
+
+	// The processing is rolled back, then the next offset is stored
+	var stored []string
+	for i, statement := range conn.statements {
+		if strings.HasPrefix(statement, "INSERT INTO `tb_kafka_consumer_offset`") {
+			stored = append(stored, fmt.Sprint(conn.args[i][3].Value))
+		}
+	}
+	if fmt.Sprint(stored) != "[1]" {
+		t.Errorf("stored offsets = %v, want the next offset 1", stored)
+	}
+	if statements := strings.Join(conn.statements, "\n"); !strings.Contains(statements, "ROLLBACK") ||
+		!strings.HasSuffix(statements, "COMMIT") {
+		t.Errorf("statements = %s, want the processing rolled back and the offset committed", statements)
+	}
+}
+
+// TestIdempotencyKey tests that a retried message has the idempotency key
+// of the original message.
+func TestIdempotencyKey(t *testing.T) {
+	original := &sarama.ConsumerMessage{Topic: "orders", Partition: 3, Offset: 42}
+	if key := IdempotencyKey(original); key != "orders/3/42" {
+		t.Errorf("IdempotencyKey() = %s, want orders/3/42", key)
+	}
+
+	pm := failureMessage(RetryTopic("orders", time.Minute), "billing", original, 1, errors.New("failed"), time.Now())
+	retried := &sarama.ConsumerMessage{Topic: pm.Topic, Partition: 0, Offset: 7}
+	for _, h := range pm.Headers {
+		retried.Headers = append(retried.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	if key := IdempotencyKey(retried); key != "orders/3/42" {
+		t.Errorf("IdempotencyKey() of the retried message = %s, want orders/3/42", key)
+	}
+
+	outbox := &sarama.ConsumerMessage{Topic: "orders", Headers: []*sarama.RecordHeader{{Key: []byte(HeaderOutboxID), Value: []byte("9")}}}
+	if key := IdempotencyKey(outbox); key != "outbox:9" {
+		t.Errorf("IdempotencyKey() of the outbox message = %s, want outbox:9", key)
+	}
+}
@@ -0,0 +1,483 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HeaderOutboxID is the header carrying the ID of the outbox row of a
+// relayed message, which consumers may use as an idempotency key.
+const HeaderOutboxID = "x-outbox-id"
+
+// Default outbox relay configuration.
+const (
+	DefaultOutboxBatchSize   = 100
+	DefaultOutboxInterval    = time.Second
+	DefaultOutboxRetention   = 7 * 24 * time.Hour
+	DefaultOutboxMaxAttempts = 10
+)
+
+// ResultParked is the result of an outbox message which failed
+// OutboxRelayConfig.MaxAttempts times and is no longer relayed.
+const ResultParked = "parked"
+
+// outboxPurgeInterval is the interval of the removal of the relayed rows
+// older than the retention.
+const outboxPurgeInterval = time.Hour
+
+var (
+	// 转发的 outbox 消息数
+	outboxRelayedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_outbox_relayed_total",
+			Help: "Total number of outbox messages relayed to Kafka, by result",
+		},
+		[]string{"result"},
+	)
+
+	// 待转发的最早 outbox 消息的等待时间
+	outboxOldestPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kafka_outbox_oldest_pending_seconds",
+			Help: "Age of the oldest outbox message not relayed yet, 0 if there is none",
+		},
+	)
+)
+
+// OutboxMessage is a message stored in the outbox table in the transaction
+// of the database writes it describes, then relayed to Kafka by an
+// OutboxRelay.
+type OutboxMessage struct {
+	ID        uint64     `gorm:"column:id;primaryKey;autoIncrement"`                         // 自增ID，决定转发顺序
+	Topic     string     `gorm:"column:topic;type:varchar(255);not null"`                    // 主题
+	Key       []byte     `gorm:"column:msg_key"`                                             // 分区键
+	Value     []byte     `gorm:"column:msg_value;not null"`                                  // 消息内容
+	Headers   string     `gorm:"column:headers;type:text"`                                   // 消息头，JSON
+	CreatedAt time.Time  `gorm:"column:created_at;not null"`                                 // 创建时间
+	SentAt    *time.Time `gorm:"column:sent_at;index:idx_kafka_outbox_pending,priority:1"`   // 转发时间，为空表示待转发
+	ParkedAt  *time.Time `gorm:"column:parked_at;index:idx_kafka_outbox_pending,priority:2"` // 搁置时间，转发失败次数达到上限后不再转发
+	Attempts  int        `gorm:"column:attempts;not null;default:0"`                         // 转发失败次数
+	LastError string     `gorm:"column:last_error;type:text"`                                // 最后一次转发错误
+}
+
+// TableName returns the outbox table.
+func (OutboxMessage) TableName() string {
+	return "tb_kafka_outbox"
+}
+
+// outboxHeader is a header of an outbox message.
+type outboxHeader struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// MigrateOutbox creates the outbox table.
+//
+// Parameters:
+//   - ctx: The context of the migration.
+//   - db: The MySQL or PostgreSQL database.
+//
+// Returns:
+//   - error: An error if the migration fails.
+func MigrateOutbox(ctx context.Context, db *gorm.DB) error {
+	if err := db.WithContext(ctx).AutoMigrate(&OutboxMessage{}); err != nil {
+		return fmt.Errorf("failed to migrate kafka outbox table: %w", err)
+	}
+	return nil
+}
+
+// EnqueueOutbox stores a message in the outbox table within the transaction
+// of the database writes it describes, so that it is relayed to Kafka if and
+// only if the transaction commits.
+//
+// Parameters:
+//   - ctx: The context of the request producing the message.
+//   - tx: The database transaction, e.g. of gorm.DB.Transaction.
+//   - msg: The message, e.g. built with NewMessage to propagate the request
+//     ID and the trace context.
+//
+// Returns:
+//   - error: An error if the message cannot be encoded or stored.
+func EnqueueOutbox(ctx context.Context, tx *gorm.DB, msg *sarama.ProducerMessage) error {
+	row, err := newOutboxMessage(msg)
+	if err != nil {
+		return err
+	}
+	if err := tx.WithContext(ctx).Create(row).Error; err != nil {
+		return fmt.Errorf("failed to store kafka outbox message: %w", err)
+	}
+	return nil
+}
+
+// newOutboxMessage returns the outbox row of a message.
+func newOutboxMessage(msg *sarama.ProducerMessage) (*OutboxMessage, error) {
+	if msg.Topic == "" || msg.Value == nil {
+		return nil, errors.New("kafka outbox message must have a topic and a value")
+	}
+
+	row := &OutboxMessage{Topic: msg.Topic, CreatedAt: time.Now()}
+	var err error
+	if row.Value, err = msg.Value.Encode(); err != nil {
+		return nil, fmt.Errorf("failed to encode kafka outbox message value: %w", err)
+	}
+	if msg.Key != nil {
+		if row.Key, err = msg.Key.Encode(); err != nil {
+			return nil, fmt.Errorf("failed to encode kafka outbox message key: %w", err)
+		}
+	}
+	if len(msg.Headers) > 0 {
+		headers := make([]outboxHeader, 0, len(msg.Headers))
+		for _, h := range msg.Headers {
+			headers = append(headers, outboxHeader{Key: string(h.Key), Value: h.Value})
+		}
+		data, err := json.Marshal(headers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode kafka outbox message headers: %w", err)
+		}
+		row.Headers = string(data)
+	}
+	return row, nil
+}
+
+// producerMessage returns the message relaying an outbox row.
+func (m *OutboxMessage) producerMessage() (*sarama.ProducerMessage, error) {
+	var headers []outboxHeader
+	if m.Headers != "" {
+		if err := json.Unmarshal([]byte(m.Headers), &headers); err != nil {
+			return nil, fmt.Errorf("invalid headers of kafka outbox message %d: %w", m.ID, err)
+		}
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic:   m.Topic,
+		Value:   sarama.ByteEncoder(m.Value),
+		Headers: make([]sarama.RecordHeader, 0, len(headers)+1),
+	}
+	if m.Key != nil {
+		msg.Key = sarama.ByteEncoder(m.Key)
+	}
+	for _, h := range headers {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(h.Key), Value: h.Value})
+	}
+	msg.Headers = setHeader(msg.Headers, HeaderOutboxID, strconv.FormatUint(m.ID, 10))
+	return msg, nil
+}
+
+// OutboxRelayConfig is the configuration of an OutboxRelay.
+type OutboxRelayConfig struct {
+	// BatchSize is the number of messages relayed per transaction,
+	// DefaultOutboxBatchSize if zero.
+	BatchSize int
+	// Interval is the interval of the polling of the outbox table while it
+	// is empty, DefaultOutboxInterval if zero.
+	Interval time.Duration
+	// Retention is the time the relayed messages are kept for, e.g. to
+	// investigate, DefaultOutboxRetention if zero.
+	Retention time.Duration
+	// MaxAttempts is the number of failed attempts after which a message is
+	// parked, DefaultOutboxMaxAttempts if zero.
+	MaxAttempts int
+}
+
+// OutboxRelay relays the messages of the outbox table to Kafka.
+//
+// The messages are relayed at least once: a message is sent again if the
+// relay stops before it is marked as relayed, and consumers may use its
+// HeaderOutboxID header to discard the duplicates.
+//
+// A single relay sends the messages in the order they were stored, a batch
+// being sent at once. A message failing to be sent is relayed again by a
+// later batch, after the messages of its batch which were sent, so the
+// messages of a key may be reordered when sending fails. Several instances
+// may relay the same table, the rows being locked while relayed and skipped
+// by the other instances, but the batches of the instances are then sent
+// concurrently and the messages of a key may be reordered as well: the
+// relay should run on a single instance when the consumers rely on the
+// order.
+//
+// A message failing MaxAttempts times, e.g. too large for the topic, is
+// parked: its parked_at column is set and it is no longer relayed, so that
+// it does not block the messages stored after it. The parked messages are
+// not purged, and are relayed again once parked_at is cleared.
+type OutboxRelay struct {
+	db       *gorm.DB
+	producer sarama.SyncProducer
+	cfg      OutboxRelayConfig
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewOutboxRelay creates an outbox relay.
+//
+// Parameters:
+//   - db: The MySQL or PostgreSQL database of the outbox table.
+//   - producer: The producer relaying the messages.
+//   - cfg: The relay configuration.
+//
+// Returns:
+//   - *OutboxRelay: The relay, started by Start.
+func NewOutboxRelay(db *gorm.DB, producer sarama.SyncProducer, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultOutboxBatchSize
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultOutboxInterval
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultOutboxRetention
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultOutboxMaxAttempts
+	}
+	return &OutboxRelay{db: db, producer: producer, cfg: cfg}
+}
+
+// Start starts relaying the messages in the background until Stop is
+// called. It does nothing if the relay is started.
+func (r *OutboxRelay) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel, r.done = cancel, make(chan struct{})
+	go r.run(ctx, r.done)
+}
+
+// Stop stops the relay, waiting for the batch being relayed.
+func (r *OutboxRelay) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel, r.done = nil, nil
+}
+
+// run relays the messages until ctx is canceled.
+func (r *OutboxRelay) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	log := r.logger()
+
+	var lastPurge time.Time
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Failed to relay kafka outbox messages", zap.Int("relayed", n), zap.Error(err))
+		}
+
+		if time.Since(lastPurge) >= outboxPurgeInterval {
+			lastPurge = time.Now()
+			if err := r.purge(ctx); err != nil && ctx.Err() == nil {
+				log.Error("Failed to purge kafka outbox messages", zap.Error(err))
+			}
+		}
+
+		// A full batch is followed by the next one right away
+		if n == r.cfg.BatchSize && err == nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		if !sleep(ctx, r.cfg.Interval) {
+			return
+		}
+	}
+}
+
+// RelayOnce relays a batch of messages of the outbox table.
+//
+// The batch is sent with a single SendMessages call, so that the rows are
+// locked for one round trip rather than one flush per message. The sent
+// rows are marked as relayed and the failed ones are relayed again by a
+// later batch, unless they are parked. The relayed messages are counted
+// once the transaction is committed.
+//
+// Parameters:
+//   - ctx: The context of the relay.
+//
+// Returns:
+//   - int: The number of messages relayed.
+//   - error: An error if the table cannot be read or a message cannot be
+//     sent.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	relayed := 0
+	var sendErr error
+	results := make(map[string]int)
+	var parkedRows []parkedRow
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("sent_at IS NULL AND parked_at IS NULL").Order("id").Limit(r.cfg.BatchSize)
+		switch tx.Dialector.Name() {
+		case "mysql", "postgres":
+			// The rows relayed by another instance are skipped
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+
+		var rows []OutboxMessage
+		if err := query.Find(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read kafka outbox messages: %w", err)
+		}
+		if len(rows) == 0 {
+			outboxOldestPending.Set(0)
+			return nil
+		}
+		outboxOldestPending.Set(time.Since(rows[0].CreatedAt).Seconds())
+
+		// The metadata of a message is the index of its row
+		failed := make(map[int]error)
+		msgs := make([]*sarama.ProducerMessage, 0, len(rows))
+		for i := range rows {
+			msg, err := rows[i].producerMessage()
+			if err != nil {
+				failed[i] = err
+				continue
+			}
+			msg.Metadata = i
+			msgs = append(msgs, msg)
+		}
+		if len(msgs) > 0 {
+			for i, err := range sendErrors(msgs, r.producer.SendMessages(msgs)) {
+				failed[i] = err
+			}
+		}
+
+		now := time.Now()
+		sent := make([]uint64, 0, len(rows))
+		for i := range rows {
+			row := &rows[i]
+			err, ok := failed[i]
+			if !ok {
+				results[ResultSuccess]++
+				sent = append(sent, row.ID)
+				continue
+			}
+
+			failure := fmt.Errorf("failed to relay kafka outbox message %d: %w", row.ID, err)
+			updates, parked := r.failure(row, err, now)
+			if updateErr := tx.Model(row).Updates(updates).Error; updateErr != nil {
+				return errors.Join(failure, updateErr)
+			}
+			if !parked {
+				results[ResultError]++
+				if sendErr == nil {
+					sendErr = failure
+				}
+				continue
+			}
+
+			// The next messages are no longer blocked by the parked one
+			results[ResultParked]++
+			parkedRows = append(parkedRows, parkedRow{row: row, err: err})
+		}
+
+		if len(sent) > 0 {
+			if err := tx.Model(&OutboxMessage{}).Where("id IN ?", sent).Update("sent_at", now).Error; err != nil {
+				return fmt.Errorf("failed to mark kafka outbox messages as relayed: %w", err)
+			}
+		}
+		relayed = len(sent)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// The rows are relayed again if the transaction is rolled back, they are
+	// counted once it is committed
+	for result, n := range results {
+		outboxRelayedTotal.WithLabelValues(result).Add(float64(n))
+	}
+	for _, p := range parkedRows {
+		r.logger().Error("Parked kafka outbox message after too many failed attempts",
+			zap.Uint64("id", p.row.ID), zap.String("topic", p.row.Topic),
+			zap.Int("attempts", p.row.Attempts+1), zap.Error(p.err))
+	}
+	return relayed, sendErr
+}
+
+// parkedRow is an outbox row parked by a relay, logged once the relay is
+// committed.
+type parkedRow struct {
+	row *OutboxMessage
+	err error
+}
+
+// sendErrors returns the errors of the messages of a SendMessages call
+// which were not sent, keyed by their metadata.
+//
+// Every message is considered failed when err is not a
+// sarama.ProducerErrors, e.g. when the producer is closed.
+func sendErrors(msgs []*sarama.ProducerMessage, err error) map[int]error {
+	failed := make(map[int]error)
+	if err == nil {
+		return failed
+	}
+
+	var producerErrs sarama.ProducerErrors
+	if !errors.As(err, &producerErrs) {
+		for _, msg := range msgs {
+			failed[msg.Metadata.(int)] = err
+		}
+		return failed
+	}
+	for _, pe := range producerErrs {
+		if i, ok := pe.Msg.Metadata.(int); ok {
+			failed[i] = pe.Err
+		}
+	}
+	return failed
+}
+
+// failure returns the updates of an outbox row which failed to be relayed,
+// and whether it is parked, having failed MaxAttempts times.
+func (r *OutboxRelay) failure(row *OutboxMessage, err error, now time.Time) (map[string]any, bool) {
+	updates := map[string]any{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": err.Error(),
+	}
+	if row.Attempts+1 < r.cfg.MaxAttempts {
+		return updates, false
+	}
+	updates["parked_at"] = now
+	return updates, true
+}
+
+// logger returns the Kafka logger, a no-op logger if it is not initialized.
+func (r *OutboxRelay) logger() *zap.Logger {
+	if resource.KafkaLogger == nil {
+		return zap.NewNop()
+	}
+	return resource.KafkaLogger
+}
+
+// purge removes the relayed messages older than the retention.
+func (r *OutboxRelay) purge(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("sent_at IS NOT NULL AND sent_at < ?", time.Now().Add(-r.cfg.Retention)).
+		Delete(&OutboxMessage{}).Error
+}
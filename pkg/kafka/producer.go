@@ -0,0 +1,260 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
+
+	"github.com/IBM/sarama"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrProducerClosed is returned when a message is sent with a closed
+// producer.
+var ErrProducerClosed = errors.New("kafka producer closed")
+
+// keySeparator separates the parts of a composite key.
+const keySeparator = ":"
+
+// NewMessage returns the message of a protobuf value, carrying the request
+// ID and the trace context found in ctx as headers.
+//
+// The value is serialized in the wire format of the schema registry, with
+// the type URL and the schema version as headers, if the registry is
+// enabled, see resource.SchemaSerde, and as bare protobuf otherwise.
+//
+// Parameters:
+//   - ctx: The context of the request producing the message.
+//   - topic: The topic to send the message to.
+//   - key: The partitioning key, e.g. built with Key, nil for a random
+//     partition.
+//   - value: The message value.
+//
+// Returns:
+//   - *sarama.ProducerMessage: The message to send.
+//   - error: An error if the value cannot be serialized.
+func NewMessage(ctx context.Context, topic string, key []byte, value proto.Message) (*sarama.ProducerMessage, error) {
+	data, schemaHeaders, err := encodeValue(ctx, topic, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize kafka message: %w", err)
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(data),
+		Headers: append(HeadersFromContext(ctx), schemaHeaders...),
+	}
+	if key != nil {
+		msg.Key = sarama.ByteEncoder(key)
+	}
+	tracing.Inject(ctx, tracing.ProducerMessageCarrier{Message: msg})
+	return msg, nil
+}
+
+// Key returns the partitioning key of an entity from its identifying parts,
+// e.g. Key("tenant-1", "order-42"), so that the messages of the entity are
+// sent to the same partition and consumed in order.
+//
+// Parameters:
+//   - parts: The identifying parts, e.g. the tenant and the entity IDs.
+//
+// Returns:
+//   - []byte: The key.
+func Key(parts ...string) []byte {
+	return []byte(strings.Join(parts, keySeparator))
+}
+
+// PartitionForKey returns the partition of a key, as chosen by the default
+// hash partitioner of the producers.
+//
+// Parameters:
+//   - key: The partitioning key.
+//   - partitions: The number of partitions of the topic.
+//
+// Returns:
+//   - int32: The partition of the key.
+//   - error: An error if the topic has no partition.
+func PartitionForKey(key []byte, partitions int32) (int32, error) {
+	if partitions <= 0 {
+		return 0, fmt.Errorf("invalid number of partitions: %d", partitions)
+	}
+	return sarama.NewHashPartitioner("").Partition(&sarama.ProducerMessage{Key: sarama.ByteEncoder(key)}, partitions)
+}
+
+// Transact sends the messages of fn in a Kafka transaction, so that they are
+// all visible to the read-committed consumers or none is.
+//
+// The transaction is aborted if fn fails, or if its commit fails with an
+// abortable error.
+//
+// Parameters:
+//   - producer: A transactional producer, whose configuration has a
+//     transaction ID.
+//   - fn: The function sending the messages with producer.
+//
+// Returns:
+//   - error: The error of fn, or an error if the transaction cannot be
+//     committed.
+func Transact(producer sarama.SyncProducer, fn func() error) error {
+	if !producer.IsTransactional() {
+		return errors.New("kafka producer is not transactional")
+	}
+	if err := producer.BeginTxn(); err != nil {
+		return fmt.Errorf("failed to begin kafka transaction: %w", err)
+	}
+
+	if err := fn(); err != nil {
+		if abortErr := producer.AbortTxn(); abortErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to abort kafka transaction: %w", abortErr))
+		}
+		return err
+	}
+
+	if err := producer.CommitTxn(); err != nil {
+		err = fmt.Errorf("failed to commit kafka transaction: %w", err)
+		if producer.TxnStatus()&sarama.ProducerTxnFlagAbortableError != 0 {
+			if abortErr := producer.AbortTxn(); abortErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to abort kafka transaction: %w", abortErr))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// DeliveryFunc is called once a message sent with an AsyncProducer is
+// acknowledged by the brokers, with a nil error, or failed. It is called
+// from the goroutine of the producer and must not block.
+type DeliveryFunc func(msg *sarama.ProducerMessage, err error)
+
+// AsyncProducer sends messages in batches, calling a delivery callback per
+// message. The batches are flushed according to the Producer.Flush
+// settings of the sarama configuration.
+type AsyncProducer struct {
+	producer sarama.AsyncProducer
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// delivery is the metadata of a message sent with an AsyncProducer.
+type delivery struct {
+	callback DeliveryFunc
+	metadata any
+}
+
+// NewAsyncProducer wraps a sarama async producer, whose configuration must
+// return the successes and the errors.
+//
+// Parameters:
+//   - producer: The sarama async producer, closed by Close.
+//
+// Returns:
+//   - *AsyncProducer: The producer.
+func NewAsyncProducer(producer sarama.AsyncProducer) *AsyncProducer {
+	p := &AsyncProducer{producer: producer}
+
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		for msg := range producer.Successes() {
+			deliver(msg, nil)
+		}
+	}()
+	go func() {
+		defer p.wg.Done()
+		for perr := range producer.Errors() {
+			deliver(perr.Msg, perr.Err)
+		}
+	}()
+	return p
+}
+
+// Send queues a message, waiting while the input buffer of the producer is
+// full.
+//
+// The Metadata of msg is restored before callback is called.
+//
+// Parameters:
+//   - ctx: The context of the request producing the message.
+//   - msg: The message to send, e.g. built with NewMessage.
+//   - callback: The delivery callback, nil to ignore the result.
+//
+// Returns:
+//   - error: ErrProducerClosed if the producer is closed, or the error of
+//     ctx if it is done before the message is queued.
+func (p *AsyncProducer) Send(ctx context.Context, msg *sarama.ProducerMessage, callback DeliveryFunc) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	msg.Metadata = &delivery{callback: callback, metadata: msg.Metadata}
+	select {
+	case p.producer.Input() <- msg:
+		return nil
+	case <-ctx.Done():
+		msg.Metadata = msg.Metadata.(*delivery).metadata
+		return ctx.Err()
+	}
+}
+
+// Close flushes the queued messages, waits for their delivery callbacks and
+// closes the producer.
+func (p *AsyncProducer) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	p.producer.AsyncClose()
+	p.wg.Wait()
+}
+
+// DefaultAsyncProducer is the async producer of SendAsync, created with the
+// Kafka clients at boot.
+var DefaultAsyncProducer *AsyncProducer
+
+// SendAsync queues a message with DefaultAsyncProducer, see
+// AsyncProducer.Send.
+//
+// Parameters:
+//   - ctx: The context of the request producing the message.
+//   - msg: The message to send, e.g. built with NewMessage.
+//   - callback: The delivery callback, nil to ignore the result.
+//
+// Returns:
+//   - error: ErrProducerClosed if there is no async producer or it is
+//     closed, or the error of ctx if it is done before the message is queued.
+func SendAsync(ctx context.Context, msg *sarama.ProducerMessage, callback DeliveryFunc) error {
+	p := DefaultAsyncProducer
+	if p == nil {
+		return ErrProducerClosed
+	}
+	return p.Send(ctx, msg, callback)
+}
+
+// deliver calls the delivery callback of a message.
+func deliver(msg *sarama.ProducerMessage, err error) {
+	if msg == nil {
+		return
+	}
+	d, ok := msg.Metadata.(*delivery)
+	if !ok {
+		return
+	}
+	msg.Metadata = d.metadata
+	if d.callback != nil {
+		d.callback(msg, err)
+	}
+}
@@ -0,0 +1,269 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/schema"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+// TestPartitionForKey tests that the messages of an entity are sent to the
+// same partition.
+func TestPartitionForKey(t *testing.T) {
+	key := Key("tenant-1", "order-42")
+	if string(key) != "tenant-1:order-42" {
+		t.Errorf("Key() = %s, want tenant-1:order-42", key)
+	}
+
+	partition, err := PartitionForKey(key, 12)
+	if err != nil {
+		t.Fatalf("PartitionForKey() error = %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if got, _ := PartitionForKey(Key("tenant-1", "order-42"), 12); got != partition {
+			t.Fatalf("PartitionForKey() = %d, want %d", got, partition)
+		}
+	}
+	if partition < 0 || partition >= 12 {
+		t.Errorf("PartitionForKey() = %d, want a partition of the topic", partition)
+	}
+
+	if _, err := PartitionForKey(key, 0); err == nil {
+		t.Error("PartitionForKey() of a topic without partition succeeded")
+	}
+}
+
+// TestAsyncProducer tests that the delivery callbacks are called with the
+// result of each message and its original metadata.
+func TestAsyncProducer(t *testing.T) {
+	cfg := mocks.NewTestConfig()
+	cfg.Producer.Return.Successes = true
+	mock := mocks.NewAsyncProducer(t, cfg)
+	mock.ExpectInputAndSucceed()
+	mock.ExpectInputAndFail(sarama.ErrMessageSizeTooLarge)
+
+	producer := NewAsyncProducer(mock)
+
+	var mu sync.Mutex
+	results := make(map[string]error)
+	callback := func(msg *sarama.ProducerMessage, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[msg.Metadata.(string)] = err
+	}
+
+	for _, name := range []string{"first", "second"} {
+		msg := &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder(name), Metadata: name}
+		if err := producer.Send(context.Background(), msg, callback); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	producer.Close()
+
+	if err, ok := results["first"]; !ok || err != nil {
+		t.Errorf("first delivery = %v, %v, want a success", err, ok)
+	}
+	if err := results["second"]; !errors.Is(err, sarama.ErrMessageSizeTooLarge) {
+		t.Errorf("second delivery = %v, want %v", err, sarama.ErrMessageSizeTooLarge)
+	}
+
+	msg := &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder("third")}
+	if err := producer.Send(context.Background(), msg, nil); !errors.Is(err, ErrProducerClosed) {
+		t.Errorf("Send() after Close error = %v, want %v", err, ErrProducerClosed)
+	}
+}
+
+// txnProducer is a transactional producer recording the transaction calls.
+type txnProducer struct {
+	sarama.SyncProducer
+
+	commitErr error
+	status    sarama.ProducerTxnStatusFlag
+	calls     []string
+}
+
+func (p *txnProducer) IsTransactional() bool { return true }
+
+func (p *txnProducer) TxnStatus() sarama.ProducerTxnStatusFlag { return p.status }
+
+func (p *txnProducer) BeginTxn() error {
+	p.calls = append(p.calls, "begin")
+	return nil
+}
+
+func (p *txnProducer) CommitTxn() error {
+	p.calls = append(p.calls, "commit")
+	return p.commitErr
+}
+
+func (p *txnProducer) AbortTxn() error {
+	p.calls = append(p.calls, "abort")
+	return nil
+}
+
+// TestTransact tests that the transactions are committed, or aborted when
+// they fail.
+func TestTransact(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name      string
+		fnErr     error
+		commitErr error
+		status    sarama.ProducerTxnStatusFlag
+		want      string
+	}{
+		{name: "commit", want: "[begin commit]"},
+		{name: "failure", fnErr: errFailed, want: "[begin abort]"},
+		{name: "abortable commit", commitErr: errFailed, status: sarama.ProducerTxnFlagAbortableError, want: "[begin commit abort]"},
+		{name: "fatal commit", commitErr: errFailed, status: sarama.ProducerTxnFlagFatalError, want: "[begin commit]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &txnProducer{commitErr: tt.commitErr, status: tt.status}
+			err := Transact(producer, func() error { return tt.fnErr })
+			if (err != nil) != (tt.fnErr != nil || tt.commitErr != nil) {
+				t.Errorf("Transact() error = %v", err)
+			}
+			if got := fmt.Sprint(producer.calls); got != tt.want {
+				t.Errorf("calls = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOutboxMessage tests that the outbox rows relay the key, the value and
+// the headers of the stored messages, with the outbox ID.
+func TestOutboxMessage(t *testing.T) {
+	msg := &sarama.ProducerMessage{
+		Topic:   "orders",
+		Key:     sarama.ByteEncoder(Key("tenant-1", "order-42")),
+		Value:   sarama.StringEncoder("created"),
+		Headers: []sarama.RecordHeader{{Key: []byte("x-tenant"), Value: []byte("tenant-1")}},
+	}
+	row, err := newOutboxMessage(msg)
+	if err != nil {
+		t.Fatalf("newOutboxMessage() error = %v", err)
+	}
+	row.ID = 7
+
+	relayed, err := row.producerMessage()
+	if err != nil {
+		t.Fatalf("producerMessage() error = %v", err)
+	}
+	key, _ := relayed.Key.Encode()
+	value, _ := relayed.Value.Encode()
+	if relayed.Topic != "orders" || string(key) != "tenant-1:order-42" || string(value) != "created" {
+		t.Errorf("producerMessage() = %s %s %s, want the stored message", relayed.Topic, key, value)
+	}
+	headers := make(map[string]string)
+	for _, h := range relayed.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	if headers["x-tenant"] != "tenant-1" || headers[HeaderOutboxID] != "7" {
+		t.Errorf("headers = %v, want the stored headers and the outbox ID", headers)
+	}
+
+	if _, err := newOutboxMessage(&sarama.ProducerMessage{Topic: "orders"}); err == nil {
+		t.Error("newOutboxMessage() of a message without value succeeded")
+	}
+}
+
+// TestOutboxFailure tests that an outbox message is parked once it failed
+// the maximum number of attempts.
+func TestOutboxFailure(t *testing.T) {
+	relay := NewOutboxRelay(nil, nil, OutboxRelayConfig{MaxAttempts: 3})
+	now := time.Now()
+	failure := errors.New("message too large")
+
+	updates, parked := relay.failure(&OutboxMessage{Attempts: 1}, failure, now)
+	if parked || updates["parked_at"] != nil || updates["last_error"] != "message too large" {
+		t.Errorf("failure() = %v, %v, want the attempt recorded", updates, parked)
+	}
+
+	updates, parked = relay.failure(&OutboxMessage{Attempts: 2}, failure, now)
+	if !parked || updates["parked_at"] != now {
+		t.Errorf("failure() = %v, %v, want the message parked on the third attempt", updates, parked)
+	}
+
+	if relay := NewOutboxRelay(nil, nil, OutboxRelayConfig{}); relay.cfg.MaxAttempts != DefaultOutboxMaxAttempts {
+		t.Errorf("MaxAttempts = %d, want %d", relay.cfg.MaxAttempts, DefaultOutboxMaxAttempts)
+	}
+}
+
+// TestOutboxSendErrors tests that the failed messages of a batch are found
+// from the errors of SendMessages.
+func TestOutboxSendErrors(t *testing.T) {
+	msgs := []*sarama.ProducerMessage{{Metadata: 0}, {Metadata: 2}, {Metadata: 3}}
+	tooLarge := errors.New("message too large")
+
+	if failed := sendErrors(msgs, nil); len(failed) != 0 {
+		t.Errorf("sendErrors(nil) = %v, want no failure", failed)
+	}
+
+	failed := sendErrors(msgs, sarama.ProducerErrors{{Msg: msgs[1], Err: tooLarge}})
+	if len(failed) != 1 || failed[2] != tooLarge {
+		t.Errorf("sendErrors(ProducerErrors) = %v, want the message of row 2 failed", failed)
+	}
+
+	failed = sendErrors(msgs, sarama.ErrClosedClient)
+	if len(failed) != 3 || failed[0] != sarama.ErrClosedClient || failed[3] != sarama.ErrClosedClient {
+		t.Errorf("sendErrors(ErrClosedClient) = %v, want every message failed", failed)
+	}
+}
+
+// TestSchemaRegistryMessage tests that the messages are produced in the
+// wire format of the schema registry, and that the handlers decode both
+// framed and bare messages.
+func TestSchemaRegistryMessage(t *testing.T) {
+	types := schema.NewTypes()
+	types.Register(&pkgproto.TestMessage{})
+	resource.SchemaSerde = schema.NewSerde(schema.NewMemoryRegistry(schema.CompatBackward), schema.SerdeConfig{
+		Topics:       map[string]schema.Format{"orders": schema.FormatJSON},
+		AutoRegister: true,
+		Types:        types,
+	})
+	t.Cleanup(func() { resource.SchemaSerde = nil })
+
+	pm, err := NewMessage(context.Background(), "orders", nil, &pkgproto.TestMessage{Id: 1})
+	if err != nil {
+		t.Fatalf("NewMessage() error = %v", err)
+	}
+	value, _ := pm.Value.Encode()
+	if !schema.IsFramed(value) {
+		t.Errorf("NewMessage() value = %v, want the schema registry wire format", value)
+	}
+	headers := make(map[string]string)
+	for _, h := range pm.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	if headers[schema.HeaderTypeURL] != "type.googleapis.com/proto.TestMessage" || headers[schema.HeaderSchemaVersion] != "1" {
+		t.Errorf("NewMessage() headers = %v, want the schema headers", headers)
+	}
+
+	registry := NewRegistry()
+	var ids []int32
+	if err := RegisterTo(registry, "orders", "billing", func(_ context.Context, msg *pkgproto.TestMessage, _ Meta) error {
+		ids = append(ids, msg.GetId())
+		return nil
+	}); err != nil {
+		t.Fatalf("RegisterTo() error = %v", err)
+	}
+	runner := newGroupRunner("billing", nil, registry.handlers["billing"], RunnerConfig{})
+	session := &testSession{ctx: context.Background()}
+	if err := runner.ConsumeClaim(session, newTestClaim("orders", value, testMessage(t, 2))); err != nil {
+		t.Fatalf("ConsumeClaim() error = %v", err)
+	}
+	if fmt.Sprint(ids) != "[1 2]" {
+		t.Errorf("processed ids = %v, want [1 2]", ids)
+	}
+}
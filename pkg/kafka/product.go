@@ -1,12 +1,16 @@
 package kafka
 
 import (
+	"context"
+
 	"github.com/xiebingnote/go-gin-project/library/common"
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
 	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
 
 	"github.com/IBM/sarama"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
 // SendKafkaMessage sends a message to the specified Kafka topic.
@@ -15,19 +19,48 @@ import (
 //
 // It returns an error if the message cannot be sent.
 func SendKafkaMessage(topic string, producerMessage []byte) error {
+	return SendKafkaMessageWithContext(context.Background(), topic, producerMessage)
+}
+
+// SendKafkaMessageWithContext sends a message to the specified Kafka topic,
+// propagating the request ID found in ctx as the X-Request-ID header and the
+// trace context as the traceparent header.
+//
+// A producer span is created for the message.
+//
+// Parameters:
+//   - ctx: The context of the request producing the message.
+//   - topic: The topic to send the message to.
+//   - producerMessage: The serialized message.
+//
+// Returns:
+//   - An error if the message cannot be sent.
+func SendKafkaMessageWithContext(ctx context.Context, topic string, producerMessage []byte) error {
+	ctx, span := tracing.StartProducerSpan(ctx, tracing.SystemKafka, topic)
+
 	// Create a producer message
 	message := &sarama.ProducerMessage{
 		Topic: topic,
 		// The message value is the serialized message
 		Value: sarama.ByteEncoder(producerMessage),
+		// Correlation headers such as the request ID
+		Headers: HeadersFromContext(ctx),
 	}
+	tracing.Inject(ctx, tracing.ProducerMessageCarrier{Message: message})
 
 	// Send the message to the topic
-	_, _, err := resource.KafkaProducer.SendMessage(message)
+	partition, offset, err := resource.KafkaProducer.SendMessage(message)
 	if err != nil {
+		tracing.EndSpan(span, err)
 		return err
 	}
 
+	span.SetAttributes(
+		semconv.MessagingKafkaDestinationPartition(int(partition)),
+		semconv.MessagingKafkaMessageOffset(int(offset)),
+	)
+	tracing.EndSpan(span, nil)
+
 	return nil
 }
 
@@ -0,0 +1,215 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Headers of the messages sent to the retry and the dead letter topics, in
+// addition to the headers of the failed message.
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderConsumerGroup     = "x-consumer-group"
+	HeaderAttempt           = "x-attempt"
+	HeaderError             = "x-error"
+	HeaderRetryAt           = "x-retry-at"  // Unix time in milliseconds
+	HeaderFailedAt          = "x-failed-at" // Unix time in milliseconds
+)
+
+// maxErrorHeader is the longest error carried by a failed message, the
+// error of a panic holding its stack.
+const maxErrorHeader = 1024
+
+// failureHeaders are the headers describing a failure, added to the failed
+// messages.
+var failureHeaders = []string{
+	HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset, HeaderConsumerGroup,
+	HeaderAttempt, HeaderError, HeaderRetryAt, HeaderFailedAt,
+}
+
+// RetryPolicy is the policy of the messages whose handler fails.
+//
+// A failed message is sent to the first retry topic, e.g. "orders.retry.1m",
+// where it is processed again once its delay elapsed, then to the next retry
+// topic if it fails again, and so on. Once the retry topics are exhausted,
+// or if the message cannot be decoded or failed with a permanent error, see
+// resilience.Permanent, it is sent to the dead letter topic, e.g.
+// "orders.dlq", or dropped.
+//
+// The retry and the dead letter topics must exist, or be created by the
+// brokers on first use. The retry topics of a topic are shared by the
+// consumer groups of the topic, each group processing its own retries.
+type RetryPolicy struct {
+	// Delays are the delays of the retry topics, in order, e.g. 1m then 10m.
+	Delays []time.Duration
+	// DeadLetter sends the messages failing their last attempt to the dead
+	// letter topic, instead of dropping them.
+	DeadLetter bool
+}
+
+// WithRetryPolicy retries the failed messages through delayed retry topics
+// and sends those failing their last attempt to a dead letter topic. The
+// handler must be started with a producer, see RunnerConfig.
+//
+// Parameters:
+//   - policy: The retry policy.
+//
+// Returns:
+//   - HandlerOption: The option.
+func WithRetryPolicy(policy RetryPolicy) HandlerOption {
+	return func(o *handlerOptions) {
+		o.retry = &policy
+	}
+}
+
+// validate checks the delays of the policy of a topic.
+func (p *RetryPolicy) validate(topic string) error {
+	topics := make(map[string]bool, len(p.Delays))
+	for _, delay := range p.Delays {
+		if delay <= 0 {
+			return fmt.Errorf("invalid kafka retry delay %s of topic %s, must be positive", delay, topic)
+		}
+		retryTopic := RetryTopic(topic, delay)
+		if topics[retryTopic] {
+			return fmt.Errorf("duplicate kafka retry delay %s of topic %s", delay, topic)
+		}
+		topics[retryTopic] = true
+	}
+	return nil
+}
+
+// RetryTopic returns the retry topic of a topic for a delay, e.g.
+// "orders.retry.10m".
+//
+// Parameters:
+//   - topic: The topic of the handler.
+//   - delay: The delay of the retry topic.
+//
+// Returns:
+//   - string: The retry topic.
+func RetryTopic(topic string, delay time.Duration) string {
+	return topic + ".retry." + formatDelay(delay)
+}
+
+// DeadLetterTopic returns the dead letter topic of a topic, e.g.
+// "orders.dlq".
+//
+// Parameters:
+//   - topic: The topic of the handler.
+//
+// Returns:
+//   - string: The dead letter topic.
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// formatDelay formats a delay in its largest whole unit, e.g. "10m".
+func formatDelay(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	case d%time.Second == 0:
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	default:
+		return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+	}
+}
+
+// failureMessage returns the message sent to a retry or the dead letter
+// topic after msg failed, carrying its headers and the failure metadata.
+//
+// Parameters:
+//   - topic: The retry or the dead letter topic.
+//   - group: The consumer group of the handler.
+//   - msg: The failed message.
+//   - attempt: The number of failed attempts, including this one.
+//   - cause: The error of the attempt.
+//   - retryAt: The time of the next attempt, zero for the dead letter topic.
+//
+// Returns:
+//   - *sarama.ProducerMessage: The message to send.
+func failureMessage(topic, group string, msg *sarama.ConsumerMessage, attempt int, cause error, retryAt time.Time) *sarama.ProducerMessage {
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+len(failureHeaders))
+	for _, h := range msg.Headers {
+		if h != nil {
+			headers = append(headers, *h)
+		}
+	}
+
+	// The original position is kept from the first failure
+	if header(msg.Headers, HeaderOriginalTopic) == nil {
+		headers = setHeader(headers, HeaderOriginalTopic, msg.Topic)
+		headers = setHeader(headers, HeaderOriginalPartition, strconv.Itoa(int(msg.Partition)))
+		headers = setHeader(headers, HeaderOriginalOffset, strconv.FormatInt(msg.Offset, 10))
+	}
+	headers = setHeader(headers, HeaderConsumerGroup, group)
+	headers = setHeader(headers, HeaderAttempt, strconv.Itoa(attempt))
+	errText := cause.Error()
+	if len(errText) > maxErrorHeader {
+		errText = strings.ToValidUTF8(errText[:maxErrorHeader], "")
+	}
+	headers = setHeader(headers, HeaderError, errText)
+	if retryAt.IsZero() {
+		headers = removeHeaders(headers, HeaderRetryAt)
+		headers = setHeader(headers, HeaderFailedAt, strconv.FormatInt(time.Now().UnixMilli(), 10))
+	} else {
+		headers = setHeader(headers, HeaderRetryAt, strconv.FormatInt(retryAt.UnixMilli(), 10))
+	}
+
+	pm := &sarama.ProducerMessage{
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}
+	if msg.Key != nil {
+		pm.Key = sarama.ByteEncoder(msg.Key)
+	}
+	return pm
+}
+
+// retryAt returns the time of the next attempt of a message of a retry
+// topic, zero if it has none.
+func retryAt(msg *sarama.ConsumerMessage) time.Time {
+	ms, err := strconv.ParseInt(string(header(msg.Headers, HeaderRetryAt)), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// header returns the value of a header, nil if it is absent.
+func header(headers []*sarama.RecordHeader, key string) []byte {
+	return Meta{Headers: headers}.Header(key)
+}
+
+// setHeader sets the value of a header, replacing its previous values.
+func setHeader(headers []sarama.RecordHeader, key, value string) []sarama.RecordHeader {
+	headers = removeHeaders(headers, key)
+	return append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// removeHeaders removes the headers of the given keys.
+func removeHeaders(headers []sarama.RecordHeader, keys ...string) []sarama.RecordHeader {
+	kept := headers[:0]
+	for _, h := range headers {
+		remove := false
+		for _, key := range keys {
+			if string(h.Key) == key {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
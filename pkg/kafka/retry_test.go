@@ -0,0 +1,241 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+// TestRetryTopic tests the names of the retry topics.
+func TestRetryTopic(t *testing.T) {
+	tests := []struct {
+		delay time.Duration
+		want  string
+	}{
+		{delay: 500 * time.Millisecond, want: "orders.retry.500ms"},
+		{delay: 30 * time.Second, want: "orders.retry.30s"},
+		{delay: 90 * time.Second, want: "orders.retry.90s"},
+		{delay: 10 * time.Minute, want: "orders.retry.10m"},
+		{delay: 2 * time.Hour, want: "orders.retry.2h"},
+	}
+
+	for _, tt := range tests {
+		if got := RetryTopic("orders", tt.delay); got != tt.want {
+			t.Errorf("RetryTopic(%s) = %s, want %s", tt.delay, got, tt.want)
+		}
+	}
+}
+
+// TestRetryPolicyValidate tests the validation of the retry delays.
+func TestRetryPolicyValidate(t *testing.T) {
+	if err := (&RetryPolicy{Delays: []time.Duration{time.Minute, 10 * time.Minute}}).validate("orders"); err != nil {
+		t.Errorf("validate() error = %v", err)
+	}
+	if err := (&RetryPolicy{Delays: []time.Duration{0}}).validate("orders"); err == nil {
+		t.Error("validate() of a zero delay succeeded")
+	}
+	if err := (&RetryPolicy{Delays: []time.Duration{time.Minute, 60 * time.Second}}).validate("orders"); err == nil {
+		t.Error("validate() of a duplicate delay succeeded")
+	}
+}
+
+// TestFailureMessage tests that a failed message keeps its key and headers
+// and that a replayed dead letter drops the failure metadata.
+func TestFailureMessage(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Topic:     "orders",
+		Partition: 3,
+		Offset:    42,
+		Key:       []byte("order-1"),
+		Value:     []byte("value"),
+		Headers:   []*sarama.RecordHeader{{Key: []byte("X-Request-ID"), Value: []byte("req-1")}},
+	}
+
+	pm := failureMessage("orders.dlq", "billing", msg, 1, errors.New(strings.Repeat("e", 2*maxErrorHeader)), time.Time{})
+	headers := map[string]string{}
+	for _, h := range pm.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	if headers["X-Request-ID"] != "req-1" || headers[HeaderOriginalTopic] != "orders" ||
+		headers[HeaderOriginalPartition] != "3" || headers[HeaderOriginalOffset] != "42" ||
+		headers[HeaderConsumerGroup] != "billing" || headers[HeaderAttempt] != "1" || headers[HeaderFailedAt] == "" {
+		t.Errorf("headers = %v", headers)
+	}
+	if len(headers[HeaderError]) != maxErrorHeader {
+		t.Errorf("error header of %d bytes, want %d", len(headers[HeaderError]), maxErrorHeader)
+	}
+	if key, _ := pm.Key.Encode(); string(key) != "order-1" {
+		t.Errorf("key = %s, want order-1", key)
+	}
+
+	dead := &sarama.ConsumerMessage{Topic: "orders.dlq", Key: msg.Key, Value: msg.Value}
+	for _, h := range pm.Headers {
+		dead.Headers = append(dead.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	now := time.UnixMilli(1700000000000)
+	replayed := replayMessage("orders.retry.1m", dead, now)
+	headers = map[string]string{}
+	for _, h := range replayed.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	if replayed.Topic != "orders.retry.1m" || headers["X-Request-ID"] != "req-1" || headers[HeaderConsumerGroup] != "billing" ||
+		headers[HeaderRetryAt] != "1700000000000" || headers[HeaderError] != "" || headers[HeaderFailedAt] != "" {
+		t.Errorf("replayed message to %s with headers %v, want the retry topic of the group, due now", replayed.Topic, headers)
+	}
+
+	// The replayed message has the idempotency key of the original one
+	retried := &sarama.ConsumerMessage{Topic: replayed.Topic, Partition: 0, Offset: 7}
+	for _, h := range replayed.Headers {
+		retried.Headers = append(retried.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	if key := IdempotencyKey(retried); key != IdempotencyKey(msg) || key != "orders/3/42" {
+		t.Errorf("IdempotencyKey() = %s, want orders/3/42", key)
+	}
+}
+
+// TestReplayDeadLetters tests that the dead letters of a group are replayed
+// to its first retry topic, those of the other groups being skipped.
+func TestReplayDeadLetters(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	fetch := &sarama.FetchResponse{Version: 10}
+	for offset, group := range []string{"billing", "shipping", "billing"} {
+		fetch.AddRecord("orders.dlq", 0, nil, sarama.StringEncoder("value"), int64(offset))
+		records := fetch.GetBlock("orders.dlq", 0).RecordsSet[0].RecordBatch.Records
+		records[offset].Headers = []*sarama.RecordHeader{{Key: []byte(HeaderConsumerGroup), Value: []byte(group)}}
+	}
+	fetch.SetLastOffsetDelta("orders.dlq", 0, 2)
+	fetch.GetBlock("orders.dlq", 0).HighWaterMarkOffset = 3
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders.dlq", 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders.dlq", 0, sarama.OffsetOldest, 0).
+			SetOffset("orders.dlq", 0, sarama.OffsetNewest, 3),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, ReplayGroup+".billing", broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(ReplayGroup+".billing", "orders.dlq", 0, -1, "", sarama.ErrNoError),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+		"FetchRequest":        sarama.NewMockWrapper(fetch),
+	})
+	client := newTestClient(t, broker)
+
+	registry := NewRegistry()
+	fn := func(context.Context, *pkgproto.TestMessage, Meta) error { return nil }
+	if err := RegisterTo(registry, "orders", "billing", fn, WithRetryPolicy(RetryPolicy{Delays: []time.Duration{time.Minute}})); err != nil {
+		t.Fatalf("RegisterTo() error = %v", err)
+	}
+	if err := RegisterTo(registry, "orders", "shipping", fn, WithRetryPolicy(RetryPolicy{DeadLetter: true})); err != nil {
+		t.Fatalf("RegisterTo() error = %v", err)
+	}
+
+	producer := mocks.NewSyncProducer(t, nil)
+	t.Cleanup(func() {
+		_ = producer.Close()
+	})
+	toRetryTopic := func(msg *sarama.ProducerMessage) error {
+		if msg.Topic != "orders.retry.1m" {
+			return errors.New("replayed to " + msg.Topic)
+		}
+		return nil
+	}
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(toRetryTopic).
+		ExpectSendMessageWithMessageCheckerFunctionAndSucceed(toRetryTopic)
+
+	result, err := registry.ReplayDeadLetters(context.Background(), client, producer, "billing", "orders", 0)
+	if err != nil {
+		t.Fatalf("ReplayDeadLetters() error = %v", err)
+	}
+	if result.Replayed != 2 || result.Skipped != 1 || result.RetryTopic != "orders.retry.1m" {
+		t.Errorf("ReplayDeadLetters() = %+v, want 2 dead letters replayed to the retry topic", result)
+	}
+
+	if _, err := registry.ReplayDeadLetters(context.Background(), client, producer, "shipping", "orders", 0); !errors.Is(err, ErrNoRetryTopic) {
+		t.Errorf("ReplayDeadLetters() error = %v, want %v", err, ErrNoRetryTopic)
+	}
+	if _, err := registry.ReplayDeadLetters(context.Background(), client, producer, "audit", "orders", 0); !errors.Is(err, ErrHandlerNotFound) {
+		t.Errorf("ReplayDeadLetters() error = %v, want %v", err, ErrHandlerNotFound)
+	}
+}
+
+// batchProducer is a sync producer failing the messages of a batch whose
+// metadata is in failed.
+type batchProducer struct {
+	sarama.SyncProducer
+
+	failed map[int64]bool
+	sent   []*sarama.ProducerMessage
+}
+
+func (p *batchProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	var errs sarama.ProducerErrors
+	for _, msg := range msgs {
+		if p.failed[msg.Metadata.(int64)] {
+			errs = append(errs, &sarama.ProducerError{Msg: msg, Err: sarama.ErrMessageSizeTooLarge})
+			continue
+		}
+		p.sent = append(p.sent, msg)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// markingOffsetManager is a partition offset manager recording the marked
+// offset.
+type markingOffsetManager struct {
+	sarama.PartitionOffsetManager
+
+	marked int64
+}
+
+func (m *markingOffsetManager) MarkOffset(offset int64, _ string) {
+	m.marked = offset
+}
+
+// TestReplayBatch tests that a batch of dead letters is sent at once, and
+// that the offset of the first dead letter not sent is marked so that the
+// next replay resumes from it.
+func TestReplayBatch(t *testing.T) {
+	producer := &batchProducer{failed: map[int64]bool{}}
+	pom := &markingOffsetManager{}
+	result := &ReplayResult{}
+	batch := &replayBatch{producer: producer, pom: pom, result: result}
+
+	for _, offset := range []int64{10, 11, 13} {
+		batch.add(&sarama.ProducerMessage{Topic: "orders.retry.1m"}, offset)
+	}
+	batch.next = 14
+	if err := batch.flush(); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+	if result.Replayed != 3 || len(producer.sent) != 3 || pom.marked != 14 || len(batch.msgs) != 0 {
+		t.Errorf("flush() replayed %d, sent %d, marked %d, want 3 sent and offset 14 marked",
+			result.Replayed, len(producer.sent), pom.marked)
+	}
+
+	producer.failed[16] = true
+	producer.failed[18] = true
+	for _, offset := range []int64{15, 16, 17, 18} {
+		batch.add(&sarama.ProducerMessage{Topic: "orders.retry.1m"}, offset)
+	}
+	batch.next = 19
+	if err := batch.flush(); !errors.Is(err, sarama.ErrMessageSizeTooLarge) {
+		t.Errorf("flush() error = %v, want %v", err, sarama.ErrMessageSizeTooLarge)
+	}
+	if result.Replayed != 5 || pom.marked != 16 {
+		t.Errorf("flush() replayed %d, marked %d, want 5 replayed and offset 16 marked", result.Replayed, pom.marked)
+	}
+}
@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/schema"
+
+	"github.com/IBM/sarama"
+	"google.golang.org/protobuf/proto"
+)
+
+// encodeValue serializes the value of a message of a topic: in the wire
+// format of the schema registry with the headers describing its schema if
+// the registry is enabled, see resource.SchemaSerde, as bare protobuf
+// otherwise.
+func encodeValue(ctx context.Context, topic string, value proto.Message) ([]byte, []sarama.RecordHeader, error) {
+	serde := resource.SchemaSerde
+	if serde == nil {
+		data, err := common.SerializeData(value)
+		return data, nil, err
+	}
+
+	data, info, err := serde.Serialize(ctx, topic, value)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The headers are added in a fixed order
+	values := info.Headers()
+	headers := make([]sarama.RecordHeader, 0, len(values))
+	for _, key := range []string{schema.HeaderTypeURL, schema.HeaderSchemaID, schema.HeaderSchemaVersion} {
+		if v, ok := values[key]; ok {
+			headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(v)})
+		}
+	}
+	return data, headers, nil
+}
+
+// decodeValue decodes the value of a consumed message into msg: with the
+// schema registry serde if the value is in its wire format, as bare
+// protobuf otherwise, so that the messages produced before the registry was
+// enabled are still consumed.
+func decodeValue(ctx context.Context, value []byte, msg proto.Message) (proto.Message, error) {
+	if !schema.IsFramed(value) {
+		return common.DeSerializeData(value, msg)
+	}
+
+	serde := resource.SchemaSerde
+	if serde == nil {
+		return nil, fmt.Errorf("kafka message is in the schema registry wire format, but the schema registry is disabled")
+	}
+	if _, err := serde.DeserializeInto(ctx, value, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,391 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// UnixPrefix is the scheme prefix used for unix domain socket addresses,
+	// e.g. "unix:///var/run/app.sock".
+	UnixPrefix = "unix://"
+
+	// listenFdsStart is the first file descriptor passed by systemd or by a
+	// parent process during a graceful restart (0, 1, 2 are stdio).
+	listenFdsStart = 3
+
+	// envListenPID, envListenFDs and envListenFDNames are the environment
+	// variables defined by the systemd socket activation protocol.
+	envListenPID     = "LISTEN_PID"
+	envListenFDs     = "LISTEN_FDS"
+	envListenFDNames = "LISTEN_FDNAMES"
+
+	// envInheritFDNames is set by a parent process when it hands its listening
+	// sockets to a child process on SIGUSR2. It holds the colon separated names
+	// of the passed sockets in file descriptor order.
+	envInheritFDNames = "GRACEFUL_LISTEN_FDNAMES"
+
+	// envReadyFD is set by a parent process when it hands its listening
+	// sockets to a child process on SIGUSR2. It holds the file descriptor of
+	// the pipe on which the child reports that it is ready, see Ready.
+	envReadyFD = "GRACEFUL_READY_FD"
+)
+
+// Options holds the configuration for creating listeners.
+type Options struct {
+	// SocketMode is the file permission applied to unix domain sockets.
+	// Zero keeps the permission derived from the process umask.
+	SocketMode os.FileMode
+
+	// EnableSocketActivation allows listeners passed by systemd
+	// (LISTEN_FDS) to be used instead of binding new sockets.
+	EnableSocketActivation bool
+}
+
+var (
+	// mu protects the listener registry and the inherited listeners.
+	mu sync.Mutex
+
+	// active holds the listeners created by Listen, keyed by server name.
+	// They are handed to the child process on a graceful restart.
+	active = make(map[string]net.Listener)
+
+	// inherited holds the listeners passed by systemd or a parent process,
+	// keyed by socket name. systemd gives the same name to several sockets
+	// when FileDescriptorName is not set, they are kept in passing order.
+	inherited map[string][]net.Listener
+
+	// inheritOnce guarantees the environment is only parsed once.
+	inheritOnce sync.Once
+)
+
+// Listen returns a listener for the named server bound to addr.
+//
+// The address may be a TCP address ("0.0.0.0:8080") or a unix domain socket
+// address ("unix:///var/run/app.sock"). If a listener was inherited from a
+// parent process (graceful restart) or from systemd (socket activation) under
+// the same name or address, it is reused instead of binding a new socket.
+//
+// Parameters:
+//   - name: The name of the server, e.g. "main" or "admin".
+//   - addr: The listen address.
+//   - opts: Listener options.
+//
+// Returns:
+//   - net.Listener: The listener ready to be passed to http.Server.Serve.
+//   - error: An error if the listener cannot be created.
+func Listen(name, addr string, opts Options) (net.Listener, error) {
+	ln, err := takeInherited(name, addr, opts.EnableSocketActivation)
+	if err != nil {
+		return nil, err
+	}
+
+	if ln == nil {
+		network, address := ParseAddr(addr)
+		switch network {
+		case "unix":
+			ln, err = listenUnix(address, opts.SocketMode)
+		default:
+			ln, err = net.Listen(network, address)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+	}
+
+	mu.Lock()
+	active[name] = ln
+	mu.Unlock()
+
+	return ln, nil
+}
+
+// ParseAddr splits a listen address into its network and address parts.
+//
+// Addresses prefixed with "unix://" are unix domain sockets, everything else
+// is treated as a TCP address.
+func ParseAddr(addr string) (network, address string) {
+	if strings.HasPrefix(addr, UnixPrefix) {
+		return "unix", strings.TrimPrefix(addr, UnixPrefix)
+	}
+	return "tcp", addr
+}
+
+// ParseFileMode parses an octal permission string such as "0660".
+//
+// An empty string returns 0, which means the permission is left unchanged.
+func ParseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid socket mode %q: %w", mode, err)
+	}
+	return os.FileMode(v), nil
+}
+
+// listenUnix creates a unix domain socket listener and applies the file mode.
+//
+// A stale socket file left behind by a crashed process is removed before
+// binding, but only if nothing is accepting connections on it.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("unix socket path is empty")
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if conn, dialErr := net.Dial("unix", path); dialErr == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("unix socket %s is already in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale unix socket %s: %w", path, err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("failed to chmod unix socket %s: %w", path, err)
+		}
+	}
+
+	return ln, nil
+}
+
+// takeInherited returns the inherited listener matching the server name or
+// address and removes it from the inherited set, or nil if there is none.
+func takeInherited(name, addr string, allowSystemd bool) (net.Listener, error) {
+	var err error
+	inheritOnce.Do(func() {
+		inherited, err = loadInherited(allowSystemd)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if lns := inherited[name]; len(lns) > 0 {
+		takeAt(name, 0)
+		return lns[0], nil
+	}
+
+	network, address := ParseAddr(addr)
+	for key, lns := range inherited {
+		for i, ln := range lns {
+			if sameAddr(ln.Addr(), network, address) {
+				takeAt(key, i)
+				return ln, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// sameAddr reports whether a listener address is the listen address of a
+// server.
+//
+// TCP addresses are compared by port and IP, the unspecified addresses
+// being equal, so that a socket bound to "[::]:8080" matches "0.0.0.0:8080"
+// and ":8080".
+func sameAddr(la net.Addr, network, address string) bool {
+	if la.Network() != network {
+		return false
+	}
+	if network != "tcp" {
+		return la.String() == address
+	}
+
+	tcp, ok := la.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	want, err := net.ResolveTCPAddr("tcp", address)
+	if err != nil || want.Port != tcp.Port {
+		return false
+	}
+	if len(want.IP) == 0 || want.IP.IsUnspecified() {
+		return len(tcp.IP) == 0 || tcp.IP.IsUnspecified()
+	}
+	return want.IP.Equal(tcp.IP)
+}
+
+// takeAt removes the i-th inherited listener of a name. mu must be held.
+func takeAt(name string, i int) {
+	lns := append(inherited[name][:i:i], inherited[name][i+1:]...)
+	if len(lns) == 0 {
+		delete(inherited, name)
+		return
+	}
+	inherited[name] = lns
+}
+
+// CloseInherited closes the listeners passed by systemd or a parent process
+// which were not taken by Listen, e.g. once every server listens, so that
+// their sockets are not kept open.
+//
+// Returns:
+//   - int: The number of listeners closed.
+func CloseInherited() int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	closed := 0
+	for name, lns := range inherited {
+		for _, ln := range lns {
+			_ = ln.Close()
+			closed++
+		}
+		delete(inherited, name)
+	}
+	return closed
+}
+
+// loadInherited builds listeners from the file descriptors passed by a
+// parent process or by systemd.
+//
+// The environment variables are cleared afterward so that they are not
+// passed on to processes started later.
+func loadInherited(allowSystemd bool) (map[string][]net.Listener, error) {
+	var (
+		count int
+		names []string
+	)
+
+	if v := os.Getenv(envInheritFDNames); v != "" {
+		// Sockets handed over by a parent process during a graceful restart
+		names = strings.Split(v, ":")
+		count = len(names)
+		_ = os.Unsetenv(envInheritFDNames)
+	} else if allowSystemd && os.Getenv(envListenPID) == strconv.Itoa(os.Getpid()) {
+		// Sockets passed by systemd socket activation
+		n, err := strconv.Atoi(os.Getenv(envListenFDs))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid %s: %q", envListenFDs, os.Getenv(envListenFDs))
+		}
+		count = n
+		if v := os.Getenv(envListenFDNames); v != "" {
+			names = strings.Split(v, ":")
+		}
+		_ = os.Unsetenv(envListenPID)
+		_ = os.Unsetenv(envListenFDs)
+		_ = os.Unsetenv(envListenFDNames)
+	}
+
+	listeners := make(map[string][]net.Listener, count)
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("fd%d", listenFdsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(listenFdsStart+i), name)
+		ln, err := net.FileListener(f)
+		// FileListener duplicates the descriptor, the original can be closed
+		_ = f.Close()
+		if err != nil {
+			for _, lns := range listeners {
+				for _, ln := range lns {
+					_ = ln.Close()
+				}
+			}
+			return nil, fmt.Errorf("failed to use inherited socket %s: %w", name, err)
+		}
+		listeners[name] = append(listeners[name], ln)
+	}
+
+	return listeners, nil
+}
+
+// Ready tells the parent process of a graceful restart that the servers of
+// the current process are listening, so that the parent stops serving.
+//
+// It is a no-op when the process was not started by Upgrade, e.g. on a
+// normal start or with systemd socket activation.
+//
+// Returns:
+//   - error: An error if the parent process cannot be notified.
+func Ready() error {
+	v := os.Getenv(envReadyFD)
+	if v == "" {
+		return nil
+	}
+	_ = os.Unsetenv(envReadyFD)
+
+	fd, err := strconv.Atoi(v)
+	if err != nil || fd < listenFdsStart {
+		return fmt.Errorf("invalid %s: %q", envReadyFD, v)
+	}
+
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	if _, err := f.Write([]byte{1}); err != nil {
+		return fmt.Errorf("failed to notify the parent process: %w", err)
+	}
+	return nil
+}
+
+// Files returns the names and duplicated file descriptors of all active
+// listeners. The i-th name belongs to the i-th file, so the files can be
+// passed as exec.Cmd.ExtraFiles together with the joined names.
+//
+// The unix socket listeners still unlink their socket file on close, see
+// keepSocketFiles, so that the file is removed if the handover fails.
+func Files() ([]string, []*os.File, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := make([]string, 0, len(active))
+	files := make([]*os.File, 0, len(active))
+	for name, ln := range active {
+		var (
+			f   *os.File
+			err error
+		)
+		switch l := ln.(type) {
+		case *net.TCPListener:
+			f, err = l.File()
+		case *net.UnixListener:
+			f, err = l.File()
+		default:
+			err = fmt.Errorf("unsupported listener type %T", ln)
+		}
+		if err != nil {
+			for _, opened := range files {
+				_ = opened.Close()
+			}
+			return nil, nil, fmt.Errorf("failed to get file of listener %s: %w", name, err)
+		}
+		names = append(names, name)
+		files = append(files, f)
+	}
+
+	return names, files, nil
+}
+
+// keepSocketFiles switches the active unix socket listeners to not unlink
+// their socket file on close, once a child process serves on them after a
+// successful graceful restart and owns the file.
+func keepSocketFiles() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, ln := range active {
+		if l, ok := ln.(*net.UnixListener); ok {
+			l.SetUnlinkOnClose(false)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParseAddr tests that ParseAddr detects unix socket and TCP addresses.
+func TestParseAddr(t *testing.T) {
+	tests := []struct {
+		name        string
+		addr        string
+		wantNetwork string
+		wantAddress string
+	}{
+		{"tcp", "0.0.0.0:8080", "tcp", "0.0.0.0:8080"},
+		{"unix", "unix:///var/run/app.sock", "unix", "/var/run/app.sock"},
+		{"relative unix", "unix://app.sock", "unix", "app.sock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network, address := ParseAddr(tt.addr)
+			if network != tt.wantNetwork || address != tt.wantAddress {
+				t.Errorf("ParseAddr(%q) = (%q, %q), want (%q, %q)",
+					tt.addr, network, address, tt.wantNetwork, tt.wantAddress)
+			}
+		})
+	}
+}
+
+// TestParseFileMode tests parsing of octal socket permissions.
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"empty", "", 0, false},
+		{"0660", "0660", 0660, false},
+		{"0600", "600", 0600, false},
+		{"invalid", "0999", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFileMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFileMode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseFileMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestListenUnix tests that a unix socket listener is created with the
+// configured permission, accepts connections and replaces a stale socket.
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	// Leave a stale socket file behind
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	ln, err := Listen("test-unix", UnixPrefix+path, Options{SocketMode: 0600})
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat socket: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket permission = %o, want %o", perm, 0600)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("failed to dial socket: %v", err)
+	}
+	_ = conn.Close()
+
+	// A socket in use must not be removed
+	if _, err := Listen("test-unix-dup", UnixPrefix+path, Options{}); err == nil {
+		t.Errorf("Listen() on a socket in use should return an error")
+	}
+}
+
+// TestFiles tests that active listeners can be exported for a child process.
+func TestFiles(t *testing.T) {
+	// Drop listeners registered (and closed) by other tests
+	mu.Lock()
+	active = make(map[string]net.Listener)
+	mu.Unlock()
+
+	ln, err := Listen("test-tcp", "127.0.0.1:0", Options{})
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	names, files, err := Files()
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	if len(names) != len(files) {
+		t.Fatalf("Files() returned %d names and %d files", len(names), len(files))
+	}
+
+	found := false
+	for _, name := range names {
+		if name == "test-tcp" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Files() names = %v, want to contain %q", names, "test-tcp")
+	}
+}
+
+// TestSameAddr tests the matching of the address of an inherited listener
+// with the listen address of a server.
+func TestSameAddr(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    net.Addr
+		network string
+		address string
+		want    bool
+	}{
+		{"ipv6 unspecified and ipv4 unspecified", &net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "tcp", "0.0.0.0:8080", true},
+		{"ipv6 unspecified and empty host", &net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "tcp", ":8080", true},
+		{"ipv4 unspecified and ipv6 unspecified", &net.TCPAddr{IP: net.IPv4zero, Port: 8080}, "tcp", "[::]:8080", true},
+		{"same ip", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}, "tcp", "127.0.0.1:8080", true},
+		{"other port", &net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "tcp", "0.0.0.0:8081", false},
+		{"other ip", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}, "tcp", "10.0.0.1:8080", false},
+		{"specific and unspecified", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}, "tcp", ":8080", false},
+		{"invalid address", &net.TCPAddr{IP: net.IPv4zero, Port: 8080}, "tcp", "8080", false},
+		{"same unix path", &net.UnixAddr{Name: "/run/app.sock", Net: "unix"}, "unix", "/run/app.sock", true},
+		{"other network", &net.UnixAddr{Name: "/run/app.sock", Net: "unix"}, "tcp", "/run/app.sock", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameAddr(tt.addr, tt.network, tt.address); got != tt.want {
+				t.Errorf("sameAddr(%s, %q) = %v, want %v", tt.addr, tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestKeepSocketFiles tests that the socket file of a unix listener is only
+// left in place on close once it is handed over to a child process.
+func TestKeepSocketFiles(t *testing.T) {
+	mu.Lock()
+	active = make(map[string]net.Listener)
+	mu.Unlock()
+
+	dir := t.TempDir()
+	for _, handedOver := range []bool{false, true} {
+		path := filepath.Join(dir, fmt.Sprintf("handed-over-%t.sock", handedOver))
+		ln, err := Listen("test-keep", UnixPrefix+path, Options{})
+		if err != nil {
+			t.Fatalf("Listen() error = %v", err)
+		}
+
+		_, files, err := Files()
+		if err != nil {
+			t.Fatalf("Files() error = %v", err)
+		}
+		for _, f := range files {
+			_ = f.Close()
+		}
+		if handedOver {
+			keepSocketFiles()
+		}
+		_ = ln.Close()
+
+		_, err = os.Stat(path)
+		if exists := err == nil; exists != handedOver {
+			t.Errorf("handed over %t: socket file exists = %t, want %t", handedOver, exists, handedOver)
+		}
+	}
+}
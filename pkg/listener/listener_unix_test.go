@@ -0,0 +1,255 @@
+//go:build unix
+
+package listener
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// envHelper selects the behaviour of TestHelperProcess in a child process.
+	envHelper = "LISTENER_TEST_HELPER"
+
+	// envHelperListen holds the comma separated name=address pairs of the
+	// listeners of TestHelperProcess in "activation" mode.
+	envHelperListen = "LISTENER_TEST_LISTEN"
+)
+
+// TestHelperProcess is not a test, it is the child process started by the
+// tests below:
+//   - "activation": takes the listeners of LISTENER_TEST_LISTEN from the
+//     sockets passed in LISTEN_FDS.
+//   - "upgrade": takes the listener "test-upgrade" passed by Upgrade and
+//     reports that it is ready.
+//   - "exit": exits before it is ready.
+//   - "hang": never reports that it is ready.
+//
+// Each listener then answers one connection with its address.
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv(envHelper)
+	if mode == "" {
+		return
+	}
+
+	var lns []net.Listener
+	var err error
+	switch mode {
+	case "activation":
+		// systemd sets LISTEN_PID to the PID of the activated process
+		_ = os.Setenv(envListenPID, strconv.Itoa(os.Getpid()))
+		for _, pair := range strings.Split(os.Getenv(envHelperListen), ",") {
+			name, addr, _ := strings.Cut(pair, "=")
+			ln, err := Listen(name, addr, Options{EnableSocketActivation: true})
+			if err != nil {
+				os.Exit(2)
+			}
+			lns = append(lns, ln)
+		}
+		if CloseInherited() != 0 {
+			os.Exit(4)
+		}
+	case "upgrade":
+		var ln net.Listener
+		if ln, err = Listen("test-upgrade", "127.0.0.1:0", Options{}); err == nil {
+			lns = append(lns, ln)
+			err = Ready()
+		}
+	case "exit":
+		os.Exit(3)
+	case "hang":
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	for _, ln := range lns {
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Exit(2)
+		}
+		_, _ = conn.Write([]byte(ln.Addr().String() + "\n"))
+		_ = conn.Close()
+	}
+	os.Exit(0)
+}
+
+// helperArgs sets the arguments of the child processes started by Upgrade
+// so that they only run TestHelperProcess.
+func helperArgs(t *testing.T, mode string) {
+	t.Helper()
+
+	t.Setenv(envHelper, mode)
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestHelperProcess$"}
+	t.Cleanup(func() { os.Args = args })
+}
+
+// dialAddr connects to addr and returns the address of the listener which
+// accepted the connection, as written by TestHelperProcess.
+func dialAddr(t *testing.T, addr string) string {
+	t.Helper()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", addr, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read the answer of the child process: %v", err)
+	}
+	return line[:len(line)-1]
+}
+
+// TestSocketActivation tests that the listeners passed in LISTEN_FDS, as by
+// systemd, are reused by the servers of the same name or address instead of
+// binding new sockets, including several sockets of the same name.
+func TestSocketActivation(t *testing.T) {
+	tests := []struct {
+		name   string
+		names  []string // the LISTEN_FDNAMES of the passed sockets
+		listen func(addrs []string) string
+	}{
+		{"by name", []string{"main"}, func([]string) string { return "main=127.0.0.1:0" }},
+		{"by address", []string{"http"}, func(addrs []string) string { return "main=" + addrs[0] }},
+		{"same names", []string{"unknown", "unknown"}, func([]string) string {
+			return "unknown=127.0.0.1:0,unknown=127.0.0.1:0"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var addrs []string
+			var files []*os.File
+			for range tt.names {
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatalf("failed to listen: %v", err)
+				}
+				f, err := ln.(*net.TCPListener).File()
+				if err != nil {
+					t.Fatalf("failed to get the file of the listener: %v", err)
+				}
+				// Only the child process accepts the connections
+				_ = ln.Close()
+				addrs = append(addrs, ln.Addr().String())
+				files = append(files, f)
+			}
+
+			cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+			cmd.ExtraFiles = files
+			cmd.Env = append(os.Environ(),
+				envHelper+"=activation",
+				envHelperListen+"="+tt.listen(addrs),
+				envListenFDs+"="+strconv.Itoa(len(files)),
+				envListenFDNames+"="+strings.Join(tt.names, ":"),
+			)
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("failed to start the child process: %v", err)
+			}
+			for _, f := range files {
+				_ = f.Close()
+			}
+
+			for _, addr := range addrs {
+				if got := dialAddr(t, addr); got != addr {
+					t.Errorf("listener address = %s, want the passed listener %s", got, addr)
+				}
+			}
+			if err := cmd.Wait(); err != nil {
+				t.Errorf("child process error = %v", err)
+			}
+		})
+	}
+}
+
+// TestUpgrade tests that Upgrade hands the active listeners to the child
+// process and returns once the child is ready.
+func TestUpgrade(t *testing.T) {
+	mu.Lock()
+	active = make(map[string]net.Listener)
+	mu.Unlock()
+	helperArgs(t, "upgrade")
+
+	ln, err := Listen("test-upgrade", "127.0.0.1:0", Options{})
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	addr := ln.Addr().String()
+
+	pid, err := Upgrade(10 * time.Second)
+	if err != nil {
+		_ = ln.Close()
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	if pid <= 0 {
+		t.Errorf("Upgrade() pid = %d, want the pid of the child process", pid)
+	}
+	_ = ln.Close()
+
+	if got := dialAddr(t, addr); got != addr {
+		t.Errorf("listener address = %s, want the handed over listener %s", got, addr)
+	}
+}
+
+// TestUpgradeNotReady tests that Upgrade fails, so that the current process
+// keeps serving and removes its socket files on shutdown, when the child
+// process exits or times out before it is ready.
+func TestUpgradeNotReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		timeout time.Duration
+	}{
+		{"exit", "exit", 10 * time.Second},
+		{"timeout", "hang", 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			active = make(map[string]net.Listener)
+			mu.Unlock()
+			helperArgs(t, tt.mode)
+
+			ln, err := Listen("test-upgrade", "127.0.0.1:0", Options{})
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			defer ln.Close()
+
+			path := filepath.Join(t.TempDir(), "app.sock")
+			unixLn, err := Listen("test-upgrade-unix", UnixPrefix+path, Options{})
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+
+			start := time.Now()
+			if _, err := Upgrade(tt.timeout); !errors.Is(err, ErrChildNotReady) {
+				t.Errorf("Upgrade() error = %v, want %v", err, ErrChildNotReady)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Upgrade() took %s", elapsed)
+			}
+
+			// The current process keeps owning its socket file
+			_ = unixLn.Close()
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("socket file kept after a failed upgrade, stat error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !unix
+
+package listener
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// ErrUpgradeUnsupported is returned when graceful restart is not available
+// on the current platform.
+var ErrUpgradeUnsupported = errors.New("graceful restart is not supported on this platform")
+
+// UpgradeSignal is nil on platforms without SIGUSR2.
+var UpgradeSignal os.Signal
+
+// Upgrade is not supported on this platform.
+func Upgrade(timeout time.Duration) (int, error) {
+	return 0, ErrUpgradeUnsupported
+}
+
+// NotifyUpgrade is a no-op on this platform.
+func NotifyUpgrade(c chan<- os.Signal) {}
+
+// Terminate is not supported on this platform.
+func Terminate() error {
+	return ErrUpgradeUnsupported
+}
@@ -0,0 +1,124 @@
+//go:build unix
+
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// UpgradeSignal is the signal that triggers a graceful restart.
+var UpgradeSignal os.Signal = syscall.SIGUSR2
+
+// ErrChildNotReady is returned when the child process of a graceful restart
+// exits or times out before it is ready.
+var ErrChildNotReady = errors.New("child process not ready")
+
+// Upgrade starts a new instance of the current executable, hands it all
+// active listening sockets and waits until it is ready.
+//
+// The child process receives the sockets as extra file descriptors starting
+// at 3 and finds them by name through the GRACEFUL_LISTEN_FDNAMES variable.
+// It reports that it is ready by calling Ready once its servers listen,
+// which writes to a pipe whose descriptor is passed in GRACEFUL_READY_FD.
+// The unix socket files are then left in place when the current process
+// closes its listeners. If the child exits first, or is not ready in time
+// and is killed, an error is returned and the current process keeps
+// serving, still removing its socket files on shutdown. The caller remains
+// responsible for draining and stopping the current process once Upgrade
+// returns successfully.
+//
+// Parameters:
+//   - timeout: The maximum time waited for the child process to be ready.
+//
+// Returns:
+//   - int: The PID of the child process.
+//   - error: ErrChildNotReady if the child process exits or times out
+//     before it is ready, or an error if it cannot be started.
+func Upgrade(timeout time.Duration) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve executable: %w", err)
+	}
+
+	names, files, err := Files()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		// The child has its own copies of the descriptors
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the readiness pipe: %w", err)
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readyW)
+	cmd.Env = append(os.Environ(),
+		envInheritFDNames+"="+strings.Join(names, ":"),
+		envReadyFD+"="+strconv.Itoa(listenFdsStart+len(files)),
+	)
+
+	err = cmd.Start()
+	// Only the child may write, so that the read ends once it exits
+	_ = readyW.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start child process: %w", err)
+	}
+	pid := cmd.Process.Pid
+
+	// Reap the child process if it exits
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	ready := make(chan error, 1)
+	go func() {
+		_, err := readyR.Read(make([]byte, 1))
+		ready <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			_ = cmd.Process.Kill()
+			return 0, fmt.Errorf("%w: child process %d exited", ErrChildNotReady, pid)
+		}
+		// The socket files now belong to the child process
+		keepSocketFiles()
+		return pid, nil
+	case <-timer.C:
+		_ = cmd.Process.Kill()
+		return 0, fmt.Errorf("%w: child process %d not ready after %s", ErrChildNotReady, pid, timeout)
+	}
+}
+
+// NotifyUpgrade relays the graceful restart signal to the given channel.
+func NotifyUpgrade(c chan<- os.Signal) {
+	signal.Notify(c, UpgradeSignal)
+}
+
+// Terminate sends SIGTERM to the current process so that the registered
+// shutdown hook drains the servers after a successful upgrade.
+func Terminate() error {
+	return syscall.Kill(os.Getpid(), syscall.SIGTERM)
+}
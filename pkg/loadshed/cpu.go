@@ -0,0 +1,43 @@
+package loadshed
+
+import (
+	"runtime"
+	"time"
+)
+
+// processCPU measures the CPU usage of the process between two calls of
+// usage.
+type processCPU struct {
+	lastWall time.Time
+	lastCPU  time.Duration
+}
+
+// newProcessCPU creates a CPU usage sampler, starting the measure now.
+func newProcessCPU() *processCPU {
+	p := &processCPU{lastWall: time.Now()}
+	p.lastCPU, _ = cpuTime()
+	return p
+}
+
+// usage returns the CPU usage of the process since the last call, between
+// 0 and 1 of the CPUs available to the Go scheduler.
+//
+// Returns:
+//   - float64: The CPU usage.
+//   - bool: False if the CPU time of the process is not available.
+func (p *processCPU) usage() (float64, bool) {
+	cpu, ok := cpuTime()
+	if !ok {
+		return 0, false
+	}
+
+	now := time.Now()
+	wall := now.Sub(p.lastWall)
+	used := cpu - p.lastCPU
+	p.lastWall, p.lastCPU = now, cpu
+	if wall <= 0 {
+		return 0, false
+	}
+
+	return min(used.Seconds()/wall.Seconds()/float64(runtime.GOMAXPROCS(0)), 1), true
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package loadshed
+
+import "time"
+
+// cpuTime is not available on this platform, the limit is only adjusted
+// with the latency.
+func cpuTime() (time.Duration, bool) {
+	return 0, false
+}
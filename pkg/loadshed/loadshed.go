@@ -0,0 +1,316 @@
+// Package loadshed provides an adaptive concurrency limiter, protecting a
+// server from overload by shedding the requests beyond its capacity.
+//
+// The limit on the number of requests in flight is adjusted with the
+// gradient between the long-term and the recent latency of the requests:
+// when requests start queueing, the recent latency rises above the
+// long-term one and the limit is lowered, while it grows slowly as long as
+// the latency stays flat. A CPU usage above a threshold lowers the limit as
+// well, whatever the latency.
+//
+// Requests have a priority: low priority requests are shed first, and
+// critical requests, e.g. the login and the health checks, last.
+package loadshed
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Priority is the priority class of a request.
+type Priority int
+
+const (
+	// PriorityLow requests are shed first, once the requests in flight
+	// reach LowPriorityRatio of the limit.
+	PriorityLow Priority = iota
+	// PriorityNormal requests are shed once the requests in flight reach
+	// the limit.
+	PriorityNormal
+	// PriorityCritical requests are shed last, once the requests in flight
+	// reach CriticalPriorityRatio of the limit.
+	PriorityCritical
+)
+
+// Admission ratios of the limit by priority.
+const (
+	LowPriorityRatio      = 0.75
+	CriticalPriorityRatio = 1.5
+)
+
+// String returns the name of the priority, e.g. "critical".
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// Default limiter configuration.
+const (
+	DefaultInitialLimit = 100
+	DefaultMinLimit     = 10
+	DefaultMaxLimit     = 1000
+	DefaultWindow       = time.Second
+	DefaultMinSamples   = 10
+	DefaultSmoothing    = 0.2
+	DefaultTolerance    = 1.5
+)
+
+// longRTTWeight is the weight of a window in the long-term latency, so that
+// it follows a lasting change of the latency within about 20 windows.
+const longRTTWeight = 0.05
+
+// cpuBackoff is the decrease of the limit after a window above the CPU
+// threshold.
+const cpuBackoff = 0.9
+
+var (
+	// 并发限制
+	limitGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "load_shedding_limit",
+			Help: "Current adaptive limit of the requests in flight",
+		},
+	)
+
+	// 正在处理的请求数
+	inFlightGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "load_shedding_in_flight",
+			Help: "Number of requests in flight admitted by the load shedder",
+		},
+	)
+
+	// 被丢弃的请求数
+	shedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "load_shedding_shed_total",
+			Help: "Total number of requests shed because of overload, by priority",
+		},
+		[]string{"priority"},
+	)
+
+	// 进程 CPU 使用率
+	cpuUsageGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "load_shedding_cpu_usage_ratio",
+			Help: "CPU usage of the process over the last window, between 0 and 1 of the available CPUs",
+		},
+	)
+)
+
+// Config is the configuration of a Limiter.
+type Config struct {
+	// InitialLimit is the limit before any latency is measured,
+	// DefaultInitialLimit if zero.
+	InitialLimit int
+	// MinLimit is the lowest limit, DefaultMinLimit if zero.
+	MinLimit int
+	// MaxLimit is the highest limit, DefaultMaxLimit if zero.
+	MaxLimit int
+	// Window is the period over which the latency is averaged before the
+	// limit is adjusted, DefaultWindow if zero.
+	Window time.Duration
+	// MinSamples is the number of requests of a window required to adjust
+	// the limit, DefaultMinSamples if zero.
+	MinSamples int
+	// Smoothing is the weight of a new limit, between 0 and 1,
+	// DefaultSmoothing if zero.
+	Smoothing float64
+	// Tolerance is the ratio of the recent to the long-term latency
+	// tolerated before the limit is lowered, DefaultTolerance if zero.
+	Tolerance float64
+	// CPUThreshold is the CPU usage of the process, between 0 and 1 of the
+	// available CPUs, above which the limit is lowered. Zero disables it.
+	CPUThreshold float64
+	// CPUUsage returns the CPU usage of the process since its last call,
+	// the usage measured from the process CPU time if nil.
+	CPUUsage func() (float64, bool)
+}
+
+// Limiter is an adaptive concurrency limiter.
+type Limiter struct {
+	mu  sync.Mutex
+	cfg Config
+
+	limit       float64
+	inFlight    int
+	maxInFlight int     // 当前窗口内的最大并发数
+	longRTT     float64 // 长期平均延迟，单位：秒
+
+	windowStart time.Time
+	windowSum   float64
+	windowCount int
+}
+
+// NewLimiter creates an adaptive concurrency limiter.
+//
+// Parameters:
+//   - cfg: The configuration of the limiter.
+//
+// Returns:
+//   - *Limiter: The limiter.
+func NewLimiter(cfg Config) *Limiter {
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = DefaultMinLimit
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = DefaultMaxLimit
+	}
+	if cfg.MaxLimit < cfg.MinLimit {
+		cfg.MaxLimit = cfg.MinLimit
+	}
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = DefaultInitialLimit
+	}
+	cfg.InitialLimit = min(max(cfg.InitialLimit, cfg.MinLimit), cfg.MaxLimit)
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = DefaultMinSamples
+	}
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = DefaultSmoothing
+	}
+	if cfg.Tolerance < 1 {
+		cfg.Tolerance = DefaultTolerance
+	}
+	if cfg.CPUThreshold > 0 && cfg.CPUUsage == nil {
+		cfg.CPUUsage = newProcessCPU().usage
+	}
+
+	l := &Limiter{
+		cfg:         cfg,
+		limit:       float64(cfg.InitialLimit),
+		windowStart: time.Now(),
+	}
+	limitGauge.Set(l.limit)
+	return l
+}
+
+// Acquire admits a request if the requests in flight are below the limit
+// of its priority. An admitted request must call Release once handled.
+//
+// Parameters:
+//   - p: The priority of the request.
+//
+// Returns:
+//   - bool: False if the request is shed.
+func (l *Limiter) Acquire(p Priority) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if float64(l.inFlight) >= l.limit*priorityRatio(p) {
+		shedTotal.WithLabelValues(p.String()).Inc()
+		return false
+	}
+
+	l.inFlight++
+	l.maxInFlight = max(l.maxInFlight, l.inFlight)
+	inFlightGauge.Set(float64(l.inFlight))
+	return true
+}
+
+// Release releases an admitted request and records its latency.
+//
+// Parameters:
+//   - latency: The time taken to handle the request.
+func (l *Limiter) Release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	inFlightGauge.Set(float64(l.inFlight))
+
+	l.windowSum += latency.Seconds()
+	l.windowCount++
+	if now := time.Now(); now.Sub(l.windowStart) >= l.cfg.Window {
+		l.update()
+		l.windowStart = now
+	}
+}
+
+// Limit returns the current limit of the requests in flight.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// InFlight returns the number of requests in flight.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inFlight
+}
+
+// update adjusts the limit at the end of a window. The caller must hold the
+// lock.
+func (l *Limiter) update() {
+	defer func() {
+		l.windowSum, l.windowCount = 0, 0
+		l.maxInFlight = l.inFlight
+	}()
+
+	newLimit := l.limit
+	if l.windowCount >= l.cfg.MinSamples && l.windowSum > 0 {
+		shortRTT := l.windowSum / float64(l.windowCount)
+		if l.longRTT == 0 {
+			l.longRTT = shortRTT
+		} else {
+			l.longRTT = l.longRTT*(1-longRTTWeight) + shortRTT*longRTTWeight
+		}
+
+		// The limit shrinks as the recent latency grows beyond the tolerance,
+		// by half at most, and grows by the square root of the limit
+		gradient := min(max(l.cfg.Tolerance*l.longRTT/shortRTT, 0.5), 1)
+		newLimit = l.limit*gradient + math.Sqrt(l.limit)
+
+		// The limit does not grow while it is not reached, otherwise it
+		// would grow without bound under a light load
+		if float64(l.maxInFlight) < l.limit/2 {
+			newLimit = min(newLimit, l.limit)
+		}
+	}
+
+	prev := l.limit
+	l.limit = l.limit*(1-l.cfg.Smoothing) + newLimit*l.cfg.Smoothing
+
+	// The CPU backoff is not smoothed, so that a saturated CPU is relieved
+	// within a few windows
+	if l.cfg.CPUThreshold > 0 {
+		if usage, ok := l.cfg.CPUUsage(); ok {
+			cpuUsageGauge.Set(usage)
+			if usage > l.cfg.CPUThreshold {
+				l.limit = min(l.limit, prev*cpuBackoff)
+			}
+		}
+	}
+
+	l.limit = min(max(l.limit, float64(l.cfg.MinLimit)), float64(l.cfg.MaxLimit))
+	limitGauge.Set(l.limit)
+}
+
+// priorityRatio returns the admission ratio of the limit of a priority.
+func priorityRatio(p Priority) float64 {
+	switch p {
+	case PriorityLow:
+		return LowPriorityRatio
+	case PriorityCritical:
+		return CriticalPriorityRatio
+	default:
+		return 1
+	}
+}
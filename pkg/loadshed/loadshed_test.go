@@ -0,0 +1,95 @@
+package loadshed
+
+import (
+	"testing"
+	"time"
+)
+
+// window records a window of n requests of the given latency with the
+// given maximum of requests in flight, and adjusts the limit.
+func window(l *Limiter, n int, latency time.Duration, maxInFlight int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.windowSum = float64(n) * latency.Seconds()
+	l.windowCount = n
+	l.maxInFlight = maxInFlight
+	l.update()
+}
+
+// TestPriorities tests that the low priority requests are shed first and
+// the critical ones last.
+func TestPriorities(t *testing.T) {
+	l := NewLimiter(Config{InitialLimit: 20, MinLimit: 20, MaxLimit: 20})
+
+	admitted := map[Priority]int{}
+	for _, p := range []Priority{PriorityLow, PriorityNormal, PriorityCritical} {
+		for l.Acquire(p) {
+			admitted[p]++
+		}
+	}
+	// 15 low, then 5 normal up to the limit, then 10 critical up to 1.5 times the limit
+	if admitted[PriorityLow] != 15 || admitted[PriorityNormal] != 5 || admitted[PriorityCritical] != 10 {
+		t.Errorf("admitted = %v, want 15 low, 5 normal and 10 critical", admitted)
+	}
+
+	l.Release(time.Millisecond)
+	if !l.Acquire(PriorityCritical) || l.Acquire(PriorityNormal) {
+		t.Error("a released slot went to a normal request above the limit")
+	}
+}
+
+// TestLimitGradient tests that the limit shrinks when the latency rises and
+// grows while the latency is flat and the limit is reached.
+func TestLimitGradient(t *testing.T) {
+	l := NewLimiter(Config{InitialLimit: 100, MinLimit: 10, MaxLimit: 1000})
+
+	window(l, 100, 10*time.Millisecond, 100)
+	window(l, 100, 10*time.Millisecond, 100)
+	if got := l.Limit(); got <= 100 {
+		t.Errorf("Limit() = %d with a flat latency, want more than 100", got)
+	}
+
+	grown := l.Limit()
+	for i := 0; i < 5; i++ {
+		window(l, 100, 100*time.Millisecond, grown)
+	}
+	if got := l.Limit(); got >= grown {
+		t.Errorf("Limit() = %d after the latency rose, want less than %d", got, grown)
+	}
+}
+
+// TestLimitLightLoad tests that the limit does not grow while it is far from
+// reached.
+func TestLimitLightLoad(t *testing.T) {
+	l := NewLimiter(Config{InitialLimit: 100})
+
+	for i := 0; i < 10; i++ {
+		window(l, 100, 10*time.Millisecond, 10)
+	}
+	if got := l.Limit(); got != 100 {
+		t.Errorf("Limit() = %d under a light load, want 100", got)
+	}
+}
+
+// TestLimitCPU tests that a CPU usage above the threshold lowers the limit
+// whatever the latency.
+func TestLimitCPU(t *testing.T) {
+	usage := 0.95
+	l := NewLimiter(Config{
+		InitialLimit: 100,
+		CPUThreshold: 0.8,
+		CPUUsage:     func() (float64, bool) { return usage, true },
+	})
+
+	window(l, 100, 10*time.Millisecond, 100)
+	if got := l.Limit(); got != 90 {
+		t.Errorf("Limit() = %d above the CPU threshold, want 90", got)
+	}
+
+	usage = 0.5
+	window(l, 100, 10*time.Millisecond, 100)
+	if got := l.Limit(); got <= 90 {
+		t.Errorf("Limit() = %d below the CPU threshold, want more than 90", got)
+	}
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// AccessLogOptions configures the file and the rotation of the access log.
+type AccessLogOptions struct {
+	Filename   string // the path of the access log file
+	MaxSize    int    // the maximum size in megabytes before the file is rotated
+	MaxAge     int    // the maximum number of days to retain rotated files
+	MaxBackups int    // the maximum number of rotated files to retain
+	LocalTime  bool   // whether rotated files are named with the local time
+	Compress   bool   // whether rotated files are compressed
+}
+
+// NewAccessLogger creates a logger writing JSON access log entries to a
+// dedicated file with its own rotation.
+//
+// Entries are written at info level and above, the level is not adjustable
+// at runtime and the entries are not sampled nor rate limited, so that every
+// request is recorded. Of the logger options, WithFields, WithTimeLayout and
+// WithRedactor are applied.
+//
+// Parameters:
+//   - access: The file and rotation options.
+//   - opts: The logger options.
+//
+// Returns:
+//   - *zap.Logger: The access logger.
+//   - error: An error if the file name is empty or its directory cannot be created.
+func NewAccessLogger(access AccessLogOptions, opts ...Option) (*zap.Logger, error) {
+	if access.Filename == "" {
+		return nil, fmt.Errorf("access log file is not configured")
+	}
+
+	opt := &options{}
+	for _, f := range opts {
+		f(opt)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(access.Filename), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create access log directory: %w", err)
+	}
+
+	timeLayout := common.DefaultTimeLayout
+	if opt.timeLayout != "" {
+		timeLayout = opt.timeLayout
+	}
+
+	encoder := zapcore.NewJSONEncoder(createEncoderConfig(timeLayout))
+	if opt.redactor != nil {
+		encoder = newRedactEncoder(encoder, opt.redactor)
+	}
+
+	writer := &lumberjack.Logger{
+		Filename:   access.Filename,
+		MaxSize:    access.MaxSize,
+		MaxBackups: access.MaxBackups,
+		MaxAge:     access.MaxAge,
+		LocalTime:  access.LocalTime,
+		Compress:   access.Compress,
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.AddSync(writer), zapcore.InfoLevel)
+	accessLogger := zap.New(core)
+
+	if len(opt.fields) > 0 {
+		fields := make([]zapcore.Field, 0, len(opt.fields))
+		for k, v := range opt.fields {
+			fields = append(fields, zap.String(k, v))
+		}
+		accessLogger = accessLogger.With(fields...)
+	}
+
+	return accessLogger, nil
+}
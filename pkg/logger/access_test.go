@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestNewAccessLogger tests that access entries are written as redacted JSON
+// to the access log file only.
+func TestNewAccessLogger(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "access", "access.log")
+
+	l, err := NewAccessLogger(AccessLogOptions{Filename: filename, MaxSize: 1},
+		WithField("service", "test"), WithRedactor(newTestRedactor(t)))
+	if err != nil {
+		t.Fatalf("NewAccessLogger() error = %v", err)
+	}
+
+	l.Debug("dropped")
+	l.Info("access", zap.String("path", "/login"), zap.String("token", "abc"))
+	_ = l.Sync()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 entry, got %d: %s", len(lines), data)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("entry is not JSON: %v", err)
+	}
+	if entry["msg"] != "access" || entry["path"] != "/login" || entry["service"] != "test" || entry["token"] != DefaultMask {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+
+	if _, err := NewAccessLogger(AccessLogOptions{}); err == nil {
+		t.Error("expected an error without file name")
+	}
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+
+	"go.uber.org/zap"
+)
+
+// ContextFieldsFunc extracts log fields from a context.
+type ContextFieldsFunc func(ctx context.Context) []zap.Field
+
+var (
+	// contextFieldsMu protects contextFieldsFuncs.
+	contextFieldsMu sync.RWMutex
+
+	// contextFieldsFuncs are the extractors applied by WithContext.
+	// The request ID extractor is always registered.
+	contextFieldsFuncs = []ContextFieldsFunc{requestIDFields}
+)
+
+// RegisterContextFields registers an extractor whose fields are attached by
+// WithContext, e.g. trace and span IDs.
+//
+// Parameters:
+//   - fn: The extractor to register.
+func RegisterContextFields(fn ContextFieldsFunc) {
+	contextFieldsMu.Lock()
+	defer contextFieldsMu.Unlock()
+
+	contextFieldsFuncs = append(contextFieldsFuncs, fn)
+}
+
+// WithContext returns a child logger carrying the correlation fields found in
+// ctx, such as the request ID set by the request ID middleware.
+//
+// If l is nil, a no-op logger is returned so that callers do not need to
+// check whether the logger service is initialized.
+//
+// Parameters:
+//   - ctx: The context of the request or message being processed.
+//   - l: The parent logger.
+//
+// Returns:
+//   - *zap.Logger: The logger with the context fields attached.
+func WithContext(ctx context.Context, l *zap.Logger) *zap.Logger {
+	if l == nil {
+		return zap.NewNop()
+	}
+	if ctx == nil {
+		return l
+	}
+
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
+
+// ContextFields returns the correlation fields found in ctx.
+func ContextFields(ctx context.Context) []zap.Field {
+	contextFieldsMu.RLock()
+	defer contextFieldsMu.RUnlock()
+
+	var fields []zap.Field
+	for _, fn := range contextFieldsFuncs {
+		fields = append(fields, fn(ctx)...)
+	}
+	return fields
+}
+
+// requestIDFields extracts the request ID from ctx.
+func requestIDFields(ctx context.Context) []zap.Field {
+	if requestID := common.RequestIDFromContext(ctx); requestID != "" {
+		return []zap.Field{zap.String(common.RequestIDKey, requestID)}
+	}
+	return nil
+}
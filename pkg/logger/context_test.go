@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestWithContext tests that the request ID of the context is attached to
+// log entries.
+func TestWithContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := common.WithRequestID(context.Background(), "req-1")
+
+	WithContext(ctx, zap.New(core)).Info("hello")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if got := entries[0].ContextMap()[common.RequestIDKey]; got != "req-1" {
+		t.Errorf("request_id = %v, want %q", got, "req-1")
+	}
+}
+
+// TestWithContextNilLogger tests that a nil logger yields a usable logger.
+func TestWithContextNilLogger(t *testing.T) {
+	WithContext(context.Background(), nil).Info("dropped")
+}
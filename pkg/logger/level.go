@@ -0,0 +1,331 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Subsystems with their own named logger and adjustable level.
+const (
+	ModuleKafka = "kafka"
+	ModuleNSQ   = "nsq"
+	ModuleHTTP  = "http"
+	ModuleCron  = "cron"
+	ModuleAuth  = "auth"
+)
+
+// Modules lists the subsystems with their own named logger.
+var Modules = []string{ModuleKafka, ModuleNSQ, ModuleHTTP, ModuleCron, ModuleAuth}
+
+// DefaultLevels is the level manager used by NewJsonLogger unless another one
+// is given with WithLevelManager.
+var DefaultLevels = NewLevelManager(zapcore.InfoLevel)
+
+// LevelManager holds the adjustable levels of the root logger and of the
+// module loggers.
+//
+// Module loggers follow the root level until their level is set explicitly.
+// Any level can be changed temporarily, e.g. "debug for 10 minutes", after
+// which it reverts automatically to the level it had before.
+type LevelManager struct {
+	mu      sync.Mutex
+	root    *adjustableLevel
+	modules map[string]*adjustableLevel
+}
+
+// adjustableLevel is a zap.AtomicLevel with an optional temporary override.
+type adjustableLevel struct {
+	level    zap.AtomicLevel
+	explicit bool // set explicitly, no longer follows the root level
+
+	// Temporary override, reverted to previous/previousExplicit at until
+	timer            *time.Timer
+	until            time.Time
+	previous         zapcore.Level
+	previousExplicit bool
+}
+
+// LevelStatus describes the current level of a logger.
+type LevelStatus struct {
+	Level         string     `json:"level"`
+	Inherited     bool       `json:"inherited,omitempty"`     // follows the root level
+	OverrideUntil *time.Time `json:"overrideUntil,omitempty"` // end of the temporary override
+}
+
+// NewLevelManager creates a level manager with the given root level and the
+// default modules.
+//
+// Parameters:
+//   - level: The initial root level.
+//
+// Returns:
+//   - *LevelManager: The level manager.
+func NewLevelManager(level zapcore.Level) *LevelManager {
+	m := &LevelManager{
+		root:    &adjustableLevel{level: zap.NewAtomicLevelAt(level), explicit: true},
+		modules: make(map[string]*adjustableLevel, len(Modules)),
+	}
+	for _, name := range Modules {
+		m.modules[name] = &adjustableLevel{level: zap.NewAtomicLevelAt(level)}
+	}
+	return m
+}
+
+// Root returns the atomic level of the root logger.
+func (m *LevelManager) Root() zap.AtomicLevel {
+	return m.root.level
+}
+
+// Level returns the atomic level of a module, or of the root logger if module
+// is empty.
+//
+// Parameters:
+//   - module: The module name, e.g. ModuleKafka.
+//
+// Returns:
+//   - zap.AtomicLevel: The atomic level.
+//   - error: An error if the module is unknown.
+func (m *LevelManager) Level(module string) (zap.AtomicLevel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, err := m.lookup(module)
+	if err != nil {
+		return zap.AtomicLevel{}, err
+	}
+	return l.level, nil
+}
+
+// SetLevel sets the level of a module, or of the root logger if module is
+// empty, cancelling any temporary override.
+//
+// Setting the root level also updates the modules which follow it.
+//
+// Parameters:
+//   - module: The module name, empty for the root logger.
+//   - level: The new level.
+//
+// Returns:
+//   - error: An error if the module is unknown.
+func (m *LevelManager) SetLevel(module string, level zapcore.Level) error {
+	return m.SetLevelFor(module, level, 0)
+}
+
+// SetLevelFor sets the level of a module, or of the root logger if module is
+// empty, for the given duration.
+//
+// When the duration expires, the level reverts to the one it had before the
+// first override. A zero duration sets the level permanently.
+//
+// Parameters:
+//   - module: The module name, empty for the root logger.
+//   - level: The new level.
+//   - d: The duration of the override, zero for a permanent change.
+//
+// Returns:
+//   - error: An error if the module is unknown or the duration negative.
+func (m *LevelManager) SetLevelFor(module string, level zapcore.Level, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("invalid duration: %v, must be non-negative", d)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, err := m.lookup(module)
+	if err != nil {
+		return err
+	}
+
+	if d == 0 {
+		m.stopOverride(l)
+		m.apply(module, l, level, true)
+		return nil
+	}
+
+	// Keep the level from before the first override when overrides stack
+	if l.timer == nil {
+		l.previous = l.level.Level()
+		l.previousExplicit = l.explicit
+	} else {
+		l.timer.Stop()
+	}
+
+	l.until = time.Now().Add(d)
+	l.timer = time.AfterFunc(d, func() { m.revert(module, l) })
+	m.apply(module, l, level, true)
+	return nil
+}
+
+// Status returns the current level of the root logger and of every module.
+func (m *LevelManager) Status() (LevelStatus, map[string]LevelStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	modules := make(map[string]LevelStatus, len(m.modules))
+	for name, l := range m.modules {
+		modules[name] = l.status()
+	}
+	return m.root.status(), modules
+}
+
+// ModuleNames returns the sorted names of the known modules.
+func (m *LevelManager) ModuleNames() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.modules))
+	for name := range m.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Named returns a child logger of l named after module whose entries are
+// filtered by the level of the module instead of the root level.
+//
+// Parameters:
+//   - l: The root logger created by NewJsonLogger with this manager.
+//   - module: The module name, e.g. ModuleKafka.
+//
+// Returns:
+//   - *zap.Logger: The module logger, a no-op logger if l is nil.
+func (m *LevelManager) Named(l *zap.Logger, module string) *zap.Logger {
+	if l == nil {
+		return zap.NewNop()
+	}
+
+	m.mu.Lock()
+	ml, ok := m.modules[module]
+	if !ok {
+		// Unknown modules are registered and follow the root level
+		ml = &adjustableLevel{level: zap.NewAtomicLevelAt(m.root.level.Level())}
+		m.modules[module] = ml
+	}
+	m.mu.Unlock()
+
+	return l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return withLevel(core, ml.level)
+	})).Named(module)
+}
+
+// lookup returns the level of module, the root level if module is empty.
+// It must be called with m.mu held.
+func (m *LevelManager) lookup(module string) (*adjustableLevel, error) {
+	if module == "" {
+		return m.root, nil
+	}
+	l, ok := m.modules[module]
+	if !ok {
+		return nil, fmt.Errorf("unknown log module: %s", module)
+	}
+	return l, nil
+}
+
+// apply sets the level of l and propagates root changes to the modules that
+// follow the root level. It must be called with m.mu held.
+func (m *LevelManager) apply(module string, l *adjustableLevel, level zapcore.Level, explicit bool) {
+	l.level.SetLevel(level)
+	if module == "" {
+		for _, ml := range m.modules {
+			if !ml.explicit {
+				ml.level.SetLevel(level)
+			}
+		}
+		return
+	}
+	l.explicit = explicit
+}
+
+// revert restores the level of l at the end of a temporary override.
+func (m *LevelManager) revert(module string, l *adjustableLevel) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The override was cancelled or replaced in the meantime
+	if l.timer == nil || time.Now().Before(l.until) {
+		return
+	}
+	l.timer = nil
+	l.until = time.Time{}
+
+	level := l.previous
+	if module != "" && !l.previousExplicit {
+		level = m.root.level.Level()
+	}
+	m.apply(module, l, level, l.previousExplicit)
+}
+
+// stopOverride cancels the temporary override of l, if any. It must be
+// called with m.mu held.
+func (m *LevelManager) stopOverride(l *adjustableLevel) {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+		l.until = time.Time{}
+	}
+}
+
+// status returns the current status of l. It must be called with the
+// manager lock held.
+func (l *adjustableLevel) status() LevelStatus {
+	s := LevelStatus{Level: l.level.Level().String(), Inherited: !l.explicit}
+	if l.timer != nil {
+		until := l.until
+		s.OverrideUntil = &until
+	}
+	return s
+}
+
+// levelCore filters the entries of a core with an adjustable level.
+type levelCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+// withLevel returns core filtered by level. If core is already filtered, the
+// previous level is replaced, so that module loggers are not limited by the
+// root level.
+func withLevel(core zapcore.Core, level zapcore.LevelEnabler) zapcore.Core {
+	if lc, ok := core.(*levelCore); ok {
+		core = lc.Core
+	}
+	return &levelCore{Core: core, level: level}
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+// Level implements zapcore.LevelEnabler.
+func (c *levelCore) Level() zapcore.Level {
+	return zapcore.LevelOf(c.level)
+}
+
+// With implements zapcore.Core.
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+// Check implements zapcore.Core.
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// ParseLevel parses a level name, see ValidateLogLevel.
+func ParseLevel(level string) (zapcore.Level, error) {
+	if err := ValidateLogLevel(level); err != nil {
+		return zapcore.InfoLevel, err
+	}
+	return zapcore.ParseLevel(level)
+}
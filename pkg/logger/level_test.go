@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newObservedLogger returns a logger filtered by the root level of m and
+// the entries it writes.
+func newObservedLogger(m *LevelManager) (*zap.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return zap.New(withLevel(core, m.Root())), logs
+}
+
+// TestLevelManagerRoot tests that the root level can be changed at runtime
+// and that following modules are updated.
+func TestLevelManagerRoot(t *testing.T) {
+	m := NewLevelManager(zapcore.InfoLevel)
+	l, logs := newObservedLogger(m)
+
+	l.Debug("dropped")
+	if err := m.SetLevel("", zapcore.DebugLevel); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	l.Debug("written")
+
+	if logs.Len() != 1 || logs.All()[0].Message != "written" {
+		t.Errorf("unexpected entries: %v", logs.All())
+	}
+
+	kafka, _ := m.Level(ModuleKafka)
+	if kafka.Level() != zapcore.DebugLevel {
+		t.Errorf("kafka level = %v, want it to follow the root level", kafka.Level())
+	}
+}
+
+// TestLevelManagerModule tests that module loggers have independent levels.
+func TestLevelManagerModule(t *testing.T) {
+	m := NewLevelManager(zapcore.InfoLevel)
+	root, logs := newObservedLogger(m)
+	kafka := m.Named(root.With(zap.String("service", "test")), ModuleKafka)
+	nsq := m.Named(root, ModuleNSQ)
+
+	if err := m.SetLevel(ModuleKafka, zapcore.DebugLevel); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	kafka.Debug("kafka debug")
+	nsq.Debug("nsq debug")
+	root.Debug("root debug")
+
+	if logs.Len() != 1 {
+		t.Fatalf("expected 1 entry, got %d: %v", logs.Len(), logs.All())
+	}
+	entry := logs.All()[0]
+	if entry.LoggerName != ModuleKafka || entry.ContextMap()["service"] != "test" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	// An explicit module level no longer follows the root level
+	if err := m.SetLevel("", zapcore.ErrorLevel); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	if lvl, _ := m.Level(ModuleKafka); lvl.Level() != zapcore.DebugLevel {
+		t.Errorf("kafka level = %v, want debug", lvl.Level())
+	}
+
+	if err := m.SetLevel("unknown", zapcore.DebugLevel); err == nil {
+		t.Error("expected an error for an unknown module")
+	}
+}
+
+// TestLevelManagerOverride tests that temporary overrides revert.
+func TestLevelManagerOverride(t *testing.T) {
+	m := NewLevelManager(zapcore.InfoLevel)
+
+	if err := m.SetLevelFor(ModuleAuth, zapcore.DebugLevel, 20*time.Millisecond); err != nil {
+		t.Fatalf("SetLevelFor() error = %v", err)
+	}
+	_, modules := m.Status()
+	if modules[ModuleAuth].Level != "debug" || modules[ModuleAuth].OverrideUntil == nil {
+		t.Fatalf("unexpected status: %+v", modules[ModuleAuth])
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, modules = m.Status(); modules[ModuleAuth].OverrideUntil == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	status := modules[ModuleAuth]
+	if status.Level != "info" || !status.Inherited || status.OverrideUntil != nil {
+		t.Errorf("override not reverted: %+v", status)
+	}
+}
+
+// TestNewJsonLoggerLevelManager tests that NewJsonLogger is filtered by the
+// root level of its level manager.
+func TestNewJsonLoggerLevelManager(t *testing.T) {
+	setupTestConfig()
+	defer cleanupTestLogs()
+
+	m := NewLevelManager(zapcore.InfoLevel)
+	l, err := NewJsonLogger(WithLevelManager(m), WithDisableConsole(), WithWarnLevel())
+	if err != nil {
+		t.Fatalf("NewJsonLogger() error = %v", err)
+	}
+	defer Close(l)
+
+	if l.Core().Enabled(zapcore.InfoLevel) {
+		t.Error("info must be disabled at warn level")
+	}
+	_ = m.SetLevel("", zapcore.DebugLevel)
+	if !l.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug must be enabled after changing the root level")
+	}
+}
@@ -26,6 +26,24 @@ type options struct {
 	timeLayout     string
 	disableConsole bool
 	logDir         string
+	levels         *LevelManager
+	sinks          *SinkManager
+	sampling       *samplingOptions
+	rateLimit      *rateLimitOptions
+	redactor       *Redactor
+}
+
+// samplingOptions configures zap sampling.
+type samplingOptions struct {
+	tick       time.Duration
+	first      int
+	thereafter int
+}
+
+// rateLimitOptions configures per-message rate limiting.
+type rateLimitOptions struct {
+	window time.Duration
+	burst  int
 }
 
 // WithLevel returns an Option that sets the logging level
@@ -144,6 +162,75 @@ func WithLogDir(logDir string) Option {
 	}
 }
 
+// WithLevelManager returns an Option that sets the level manager holding the
+// adjustable levels of the logger and of its module loggers.
+//
+// The default is DefaultLevels. The root level of the manager is set to the
+// level of the logger.
+//
+// Parameters:
+//   - m: The level manager.
+func WithLevelManager(m *LevelManager) Option {
+	return func(opt *options) {
+		opt.levels = m
+	}
+}
+
+// WithSinkManager returns an Option that sets the manager of the remote
+// sinks the logger ships its entries to.
+//
+// The default is DefaultSinks. Sinks can be added to the manager after the
+// logger is created.
+//
+// Parameters:
+//   - m: The sink manager.
+func WithSinkManager(m *SinkManager) Option {
+	return func(opt *options) {
+		opt.sinks = m
+	}
+}
+
+// WithSampling returns an Option that enables zap sampling.
+//
+// Within each tick, the first entries with a given level and message are
+// logged, then only every thereafter-th one. Sampling bounds the CPU and I/O
+// cost of hot paths but, unlike WithRateLimit, does not report the number of
+// dropped entries.
+//
+// Parameters:
+//   - tick: The sampling interval.
+//   - first: The number of entries logged per tick before sampling.
+//   - thereafter: Log one entry out of thereafter after the first ones.
+func WithSampling(tick time.Duration, first, thereafter int) Option {
+	return func(opt *options) {
+		opt.sampling = &samplingOptions{tick: tick, first: first, thereafter: thereafter}
+	}
+}
+
+// WithRateLimit returns an Option that limits identical entries, same level,
+// logger name and message, to burst per window.
+//
+// The number of dropped entries is reported in the "suppressed" field of the
+// next entry written for the same message. Entries at DPanic level and
+// above are never dropped.
+//
+// Parameters:
+//   - window: The rate limiting window.
+//   - burst: The number of identical entries logged per window.
+func WithRateLimit(window time.Duration, burst int) Option {
+	return func(opt *options) {
+		opt.rateLimit = &rateLimitOptions{window: window, burst: burst}
+	}
+}
+
+// WithRedactor returns an Option that masks sensitive fields and patterns
+// in every entry before it is written, see NewRedactor.
+func WithRedactor(r *Redactor) Option {
+	return func(opt *options) {
+		opt.redactor = r
+	}
+}
+
 // NewJsonLogger creates a new JSON formatted logger with the given options.
 //
 // This function validates the log configuration and initializes default options
@@ -152,7 +239,9 @@ func WithLogDir(logDir string) Option {
 // logs to files in the specified log directory and can log to the console if enabled.
 //
 // It creates log cores for different log levels, ensuring the log directory exists,
-// and combines them into a single core. The logger includes caller information and
+// and combines them into a single core. The core is filtered by the root level
+// of the level manager, so the level can be changed at runtime, and module
+// loggers created with LevelManager.Named are filtered by their own level. The logger includes caller information and
 // stack traces for errors. Additional fields configured through options are added
 // to the logger.
 //
@@ -175,6 +264,8 @@ func NewJsonLogger(opts ...Option) (*zap.Logger, error) {
 		level:  GetDefaultLevel(),
 		fields: make(map[string]string),
 		logDir: config.LogConfig.Log.LogDir,
+		levels: DefaultLevels,
+		sinks:  DefaultSinks,
 	}
 
 	// Apply provided options
@@ -196,6 +287,9 @@ func NewJsonLogger(opts ...Option) (*zap.Logger, error) {
 	// Create encoder configuration
 	encoderConfig := createEncoderConfig(timeLayout)
 	jsonEncoder := zapcore.NewJSONEncoder(encoderConfig)
+	if opt.redactor != nil {
+		jsonEncoder = newRedactEncoder(jsonEncoder, opt.redactor)
+	}
 
 	// Ensure log directory exists
 	if err := os.MkdirAll(opt.logDir, 0755); err != nil {
@@ -208,15 +302,33 @@ func NewJsonLogger(opts ...Option) (*zap.Logger, error) {
 		return nil, fmt.Errorf("failed to create log cores: %w", err)
 	}
 
+	// Ship entries to the remote sinks added to the manager
+	cores = append(cores, opt.sinks.core(jsonEncoder))
+
 	// Combine all cores
 	core := zapcore.NewTee(cores...)
 
 	// Add console logging if enabled
 	if !opt.disableConsole {
-		consoleCores := createConsoleCores(jsonEncoder, opt.level)
+		consoleCores := createConsoleCores(jsonEncoder, zapcore.DebugLevel)
 		core = zapcore.NewTee(append(cores, consoleCores...)...)
 	}
 
+	// Drop repeated entries of hot paths
+	if opt.sampling != nil && opt.sampling.tick > 0 {
+		core = zapcore.NewSamplerWithOptions(core, opt.sampling.tick, opt.sampling.first, opt.sampling.thereafter)
+	}
+	if opt.rateLimit != nil && opt.rateLimit.window > 0 {
+		core = newRateLimitCore(core, opt.rateLimit.window, opt.rateLimit.burst)
+	}
+
+	// Filter all cores by the adjustable root level, it must stay the
+	// outermost core so that module loggers can replace it
+	if err := opt.levels.SetLevel("", opt.level); err != nil {
+		return nil, err
+	}
+	core = withLevel(core, opt.levels.Root())
+
 	// Create logger with options
 	loggerOptions := []zap.Option{
 		zap.AddCaller(),
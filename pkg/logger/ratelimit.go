@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SuppressedKey is the field reporting how many identical entries were
+// dropped by the rate limiter since the previous one was written.
+const SuppressedKey = "suppressed"
+
+// rateLimitKey identifies identical entries.
+type rateLimitKey struct {
+	level  zapcore.Level
+	logger string
+	msg    string
+}
+
+// rateLimiter counts identical entries per window.
+type rateLimiter struct {
+	mu          sync.Mutex
+	window      time.Duration
+	burst       int
+	windowStart time.Time
+	counts      map[rateLimitKey]int
+	suppressed  map[rateLimitKey]int
+	now         func() time.Time
+}
+
+// allow reports whether an entry may be written, and how many identical
+// entries were dropped before it.
+func (r *rateLimiter) allow(key rateLimitKey) (bool, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Start a new window, carrying over the number of dropped entries so
+	// that it is reported with the next written one
+	if now := r.now(); now.Sub(r.windowStart) >= r.window {
+		r.windowStart = now
+		r.counts = make(map[rateLimitKey]int, len(r.counts))
+	}
+
+	r.counts[key]++
+	if r.counts[key] > r.burst {
+		r.suppressed[key]++
+		return false, 0
+	}
+
+	suppressed := r.suppressed[key]
+	delete(r.suppressed, key)
+	return true, suppressed
+}
+
+// rateLimitCore drops identical entries, same level, logger name and
+// message, written more than burst times per window.
+//
+// Unlike zap sampling, which keeps a fraction of the entries per second, it
+// reports the number of dropped entries in the SuppressedKey field of the
+// next entry written for the same message. Entries at DPanic level and
+// above are never dropped.
+type rateLimitCore struct {
+	zapcore.Core
+	limiter *rateLimiter
+}
+
+// newRateLimitCore wraps core with per-message rate limiting.
+func newRateLimitCore(core zapcore.Core, window time.Duration, burst int) zapcore.Core {
+	return &rateLimitCore{
+		Core: core,
+		limiter: &rateLimiter{
+			window:     window,
+			burst:      burst,
+			counts:     make(map[rateLimitKey]int),
+			suppressed: make(map[rateLimitKey]int),
+			now:        time.Now,
+		},
+	}
+}
+
+// With implements zapcore.Core.
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), limiter: c.limiter}
+}
+
+// Check implements zapcore.Core.
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if ent.Level >= zapcore.DPanicLevel {
+		return c.Core.Check(ent, ce)
+	}
+
+	allowed, suppressed := c.limiter.allow(rateLimitKey{level: ent.Level, logger: ent.LoggerName, msg: ent.Message})
+	if !allowed {
+		return ce
+	}
+	if suppressed > 0 {
+		return c.Core.With([]zapcore.Field{zap.Int(SuppressedKey, suppressed)}).Check(ent, ce)
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestRateLimitCore tests that identical entries are limited per window and
+// that the number of dropped entries is reported with the next one.
+func TestRateLimitCore(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := newRateLimitCore(observed, time.Second, 2).(*rateLimitCore)
+
+	now := time.Unix(0, 0)
+	core.limiter.now = func() time.Time { return now }
+	l := zap.New(core)
+
+	for i := 0; i < 5; i++ {
+		l.Warn("rate limit exceeded")
+	}
+	l.Warn("other message")
+	if logs.Len() != 3 {
+		t.Fatalf("expected 3 entries, got %d: %v", logs.Len(), logs.All())
+	}
+
+	// The next window reports the 3 dropped entries
+	now = now.Add(time.Second)
+	l.Warn("rate limit exceeded")
+	entry := logs.All()[3]
+	if got := entry.ContextMap()[SuppressedKey]; got != int64(3) {
+		t.Errorf("suppressed = %v, want 3", got)
+	}
+
+	// The counter is reset once reported
+	l.Warn("rate limit exceeded")
+	if _, ok := logs.All()[4].ContextMap()[SuppressedKey]; ok {
+		t.Errorf("unexpected suppressed field: %v", logs.All()[4].ContextMap())
+	}
+}
+
+// TestRateLimitCoreDPanic tests that DPanic entries are never dropped.
+func TestRateLimitCoreDPanic(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(newRateLimitCore(observed, time.Minute, 1))
+
+	for i := 0; i < 3; i++ {
+		l.DPanic("broken invariant")
+	}
+	if logs.Len() != 3 {
+		t.Errorf("expected 3 entries, got %d", logs.Len())
+	}
+}
+
+// TestRateLimitCoreLoggerName tests that named loggers are limited
+// separately.
+func TestRateLimitCoreLoggerName(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(newRateLimitCore(observed, time.Minute, 1))
+
+	l.Named(ModuleKafka).Info("consumer error")
+	l.Named(ModuleNSQ).Info("consumer error")
+	l.Named(ModuleNSQ).Info("consumer error")
+	if logs.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", logs.Len())
+	}
+}
@@ -0,0 +1,492 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sink names.
+const (
+	SinkKafka         = "kafka"
+	SinkElasticsearch = "elasticsearch"
+	SinkSyslog        = "syslog"
+)
+
+// Reasons of dropped records.
+const (
+	DropReasonOverflow = "overflow"
+	DropReasonError    = "error"
+	DropReasonClosed   = "closed"
+)
+
+// Default sink options.
+const (
+	DefaultSinkBufferSize    = 10000
+	DefaultSinkBatchSize     = 500
+	DefaultSinkFlushInterval = time.Second
+)
+
+// ErrSinkExists is returned when a sink with the same name is already added.
+var ErrSinkExists = errors.New("log sink already exists")
+
+var (
+	// 远程日志投递成功条数
+	sinkWrittenTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_sink_written_total",
+			Help: "Total number of log records shipped by remote log sinks",
+		},
+		[]string{"sink"},
+	)
+
+	// 远程日志丢弃条数
+	sinkDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_sink_dropped_total",
+			Help: "Total number of log records dropped by remote log sinks",
+		},
+		[]string{"sink", "reason"},
+	)
+
+	// 远程日志缓冲区长度
+	sinkBufferLength = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "log_sink_buffer_length",
+			Help: "Number of log records waiting in the buffer of remote log sinks",
+		},
+		[]string{"sink"},
+	)
+)
+
+// Record is an encoded log entry handed to a Sink.
+type Record struct {
+	Level      zapcore.Level
+	Time       time.Time
+	LoggerName string
+	Message    string
+	Data       []byte // the JSON encoded entry, without the trailing line ending
+}
+
+// Sink ships batches of log records to a remote system.
+//
+// Write is only called from the goroutine of the sink, so implementations
+// do not need to be safe for concurrent use.
+type Sink interface {
+	// Name returns the name of the sink, used in metrics.
+	Name() string
+
+	// Write ships records, all of them are dropped if an error is returned.
+	Write(ctx context.Context, records []Record) error
+
+	// Close releases the resources owned by the sink.
+	Close() error
+}
+
+// SinkOptions configures the buffering of a Sink.
+type SinkOptions struct {
+	Level         zapcore.Level // the minimum level shipped to the sink
+	BufferSize    int           // the number of buffered records, further ones are dropped
+	BatchSize     int           // the maximum number of records per Write
+	FlushInterval time.Duration // the maximum delay before buffered records are written
+}
+
+// asyncSink buffers records and writes them to a Sink from its own
+// goroutine.
+type asyncSink struct {
+	sink    Sink
+	opts    SinkOptions
+	records chan Record
+	flushes chan chan struct{}
+	done    chan struct{}
+
+	// mu protects closed, the records channel is closed with mu held so
+	// that enqueue never sends on a closed channel
+	mu     sync.RWMutex
+	closed bool
+
+	// failing is only accessed by the sink goroutine
+	failing bool
+}
+
+// newAsyncSink starts the goroutine writing to sink.
+func newAsyncSink(sink Sink, opts SinkOptions) *asyncSink {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultSinkBufferSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultSinkBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultSinkFlushInterval
+	}
+
+	s := &asyncSink{
+		sink:    sink,
+		opts:    opts,
+		records: make(chan Record, opts.BufferSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// enqueue buffers a record without blocking, it is dropped if the buffer is
+// full.
+func (s *asyncSink) enqueue(rec Record) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		sinkDroppedTotal.WithLabelValues(s.sink.Name(), DropReasonClosed).Inc()
+		return
+	}
+
+	select {
+	case s.records <- rec:
+	default:
+		sinkDroppedTotal.WithLabelValues(s.sink.Name(), DropReasonOverflow).Inc()
+	}
+}
+
+// run writes the buffered records in batches until the sink is closed.
+func (s *asyncSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, s.opts.BatchSize)
+	for {
+		select {
+		case rec, ok := <-s.records:
+			if !ok {
+				s.write(batch)
+				return
+			}
+			batch = append(batch, rec)
+			if len(batch) >= s.opts.BatchSize {
+				batch = s.write(batch)
+			}
+		case <-ticker.C:
+			batch = s.write(batch)
+		case flushed := <-s.flushes:
+			batch = s.drain(batch)
+			batch = s.write(batch)
+			close(flushed)
+		}
+	}
+}
+
+// drain moves the records buffered so far into batch, writing full batches.
+func (s *asyncSink) drain(batch []Record) []Record {
+	for n := len(s.records); n > 0; n-- {
+		rec, ok := <-s.records
+		if !ok {
+			break
+		}
+		batch = append(batch, rec)
+		if len(batch) >= s.opts.BatchSize {
+			batch = s.write(batch)
+		}
+	}
+	return batch
+}
+
+// write ships batch and returns it emptied.
+//
+// Errors cannot be logged through the logger the sink belongs to, they are
+// reported on stderr when the sink starts and stops failing.
+func (s *asyncSink) write(batch []Record) []Record {
+	sinkBufferLength.WithLabelValues(s.sink.Name()).Set(float64(len(s.records)))
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err := s.sink.Write(ctx, batch)
+	cancel()
+
+	switch {
+	case err != nil:
+		sinkDroppedTotal.WithLabelValues(s.sink.Name(), DropReasonError).Add(float64(len(batch)))
+		if !s.failing {
+			s.failing = true
+			fmt.Fprintf(os.Stderr, "log sink %s: write failed, dropping records: %v\n", s.sink.Name(), err)
+		}
+	default:
+		sinkWrittenTotal.WithLabelValues(s.sink.Name()).Add(float64(len(batch)))
+		if s.failing {
+			s.failing = false
+			fmt.Fprintf(os.Stderr, "log sink %s: write recovered\n", s.sink.Name())
+		}
+	}
+
+	// Do not keep references to the shipped data
+	clear(batch)
+	return batch[:0]
+}
+
+// flush writes the buffered records, waiting until ctx is done.
+func (s *asyncSink) flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case s.flushes <- flushed:
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops accepting records, writes the buffered ones and closes the
+// sink.
+func (s *asyncSink) close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return fmt.Errorf("log sink %s: %w", s.sink.Name(), ctx.Err())
+	}
+	return s.sink.Close()
+}
+
+// SinkManager holds the remote sinks the logger ships its entries to.
+//
+// Sinks can be added after the logger is created, e.g. once the Kafka
+// producer they depend on is initialized. Entries are encoded once and
+// buffered per sink, a slow or failing sink never blocks the caller.
+type SinkManager struct {
+	mu    sync.RWMutex
+	sinks map[string]*asyncSink
+}
+
+// DefaultSinks is the sink manager used by NewJsonLogger unless
+// WithSinkManager is given.
+var DefaultSinks = NewSinkManager()
+
+// NewSinkManager creates a new SinkManager without sinks.
+func NewSinkManager() *SinkManager {
+	return &SinkManager{sinks: make(map[string]*asyncSink)}
+}
+
+// Add starts shipping entries to sink.
+//
+// Parameters:
+//   - sink: The sink, its name must be unique.
+//   - opts: The buffering options, zero values use the defaults.
+//
+// Returns:
+//   - error: ErrSinkExists if a sink with the same name is already added.
+func (m *SinkManager) Add(sink Sink, opts SinkOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sinks[sink.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrSinkExists, sink.Name())
+	}
+	m.sinks[sink.Name()] = newAsyncSink(sink, opts)
+	return nil
+}
+
+// Remove stops shipping entries to the named sink, writes its buffered
+// entries and closes it. It does nothing if the sink is not added.
+//
+// Sinks depending on a client must be removed before the client is closed.
+//
+// Parameters:
+//   - ctx: The context bounding the time spent writing buffered entries.
+//   - name: The name of the sink.
+//
+// Returns:
+//   - error: An error if ctx is done first or closing the sink fails.
+func (m *SinkManager) Remove(ctx context.Context, name string) error {
+	m.mu.Lock()
+	s, ok := m.sinks[name]
+	delete(m.sinks, name)
+	m.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return s.close(ctx)
+}
+
+// Names returns the sorted names of the sinks.
+func (m *SinkManager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.sinks))
+	for name := range m.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Flush writes the entries buffered by all sinks.
+//
+// Parameters:
+//   - ctx: The context bounding the time spent writing buffered entries.
+//
+// Returns:
+//   - error: An error if ctx is done first.
+func (m *SinkManager) Flush(ctx context.Context) error {
+	var errs []error
+	for _, s := range m.snapshot() {
+		if err := s.flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("log sink %s: %w", s.sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close removes all sinks, writing their buffered entries.
+//
+// Parameters:
+//   - ctx: The context bounding the time spent writing buffered entries.
+//
+// Returns:
+//   - error: The errors of the sinks that could not be flushed or closed.
+func (m *SinkManager) Close(ctx context.Context) error {
+	var errs []error
+	for _, name := range m.Names() {
+		if err := m.Remove(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// snapshot returns the current sinks.
+func (m *SinkManager) snapshot() []*asyncSink {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sinks := make([]*asyncSink, 0, len(m.sinks))
+	for _, s := range m.sinks {
+		sinks = append(sinks, s)
+	}
+	return sinks
+}
+
+// enabled reports whether a sink accepts entries at level.
+func (m *SinkManager) enabled(level zapcore.Level) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, s := range m.sinks {
+		if level >= s.opts.Level {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch hands rec to the sinks accepting its level.
+func (m *SinkManager) dispatch(rec Record) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, s := range m.sinks {
+		if rec.Level >= s.opts.Level {
+			s.enqueue(rec)
+		}
+	}
+}
+
+// core returns a core encoding entries with enc and dispatching them to the
+// sinks of m.
+func (m *SinkManager) core(enc zapcore.Encoder) zapcore.Core {
+	return &sinkCore{m: m, enc: enc}
+}
+
+// sinkCore is the zapcore.Core feeding a SinkManager.
+type sinkCore struct {
+	m   *SinkManager
+	enc zapcore.Encoder
+}
+
+// Enabled implements zapcore.Core.
+func (c *sinkCore) Enabled(level zapcore.Level) bool {
+	return c.m.enabled(level)
+}
+
+// With implements zapcore.Core.
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &sinkCore{m: c.m, enc: enc}
+}
+
+// Check implements zapcore.Core.
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	// The buffer is reused once freed, the sinks get a copy
+	data := make([]byte, len(buf.Bytes()))
+	copy(data, buf.Bytes())
+	if n := len(data); n > 0 && data[n-1] == '\n' {
+		data = data[:n-1]
+	}
+
+	c.m.dispatch(Record{
+		Level:      ent.Level,
+		Time:       ent.Time,
+		LoggerName: ent.LoggerName,
+		Message:    ent.Message,
+		Data:       data,
+	})
+
+	// Like zap's own cores, ship panic and fatal entries before the
+	// process may exit
+	if ent.Level > zapcore.ErrorLevel {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return c.m.Flush(ctx)
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core. Sinks are flushed by SinkManager.Flush,
+// Sync does not wait for remote systems.
+func (c *sinkCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// DefaultIndexDateLayout is the date suffix of the daily log indices.
+const DefaultIndexDateLayout = "2006.01.02"
+
+// ElasticsearchSink ships log records to Elasticsearch with the bulk API.
+//
+// Records are indexed into daily indices named after their time, e.g.
+// "app-logs-2025.01.02", so that old logs can be dropped by index.
+type ElasticsearchSink struct {
+	client      *elastic.Client
+	indexPrefix string
+	dateLayout  string
+}
+
+// NewElasticsearchSink creates a new ElasticsearchSink.
+//
+// The client is shared with the application and is not stopped by the
+// sink, the sink must be removed before the client is stopped.
+//
+// Parameters:
+//   - client: The Elasticsearch client, e.g. resource.ElasticSearchClient.
+//   - indexPrefix: The prefix of the index names.
+//   - dateLayout: The time layout of the index suffix, DefaultIndexDateLayout if empty.
+//
+// Returns:
+//   - *ElasticsearchSink: The sink.
+func NewElasticsearchSink(client *elastic.Client, indexPrefix, dateLayout string) *ElasticsearchSink {
+	if dateLayout == "" {
+		dateLayout = DefaultIndexDateLayout
+	}
+	return &ElasticsearchSink{client: client, indexPrefix: indexPrefix, dateLayout: dateLayout}
+}
+
+// Name implements Sink.
+func (s *ElasticsearchSink) Name() string {
+	return SinkElasticsearch
+}
+
+// Index returns the name of the index a record is written to.
+func (s *ElasticsearchSink) Index(rec Record) string {
+	return s.indexPrefix + "-" + rec.Time.Format(s.dateLayout)
+}
+
+// Write implements Sink.
+func (s *ElasticsearchSink) Write(ctx context.Context, records []Record) error {
+	bulk := s.client.Bulk()
+	for _, rec := range records {
+		bulk.Add(elastic.NewBulkIndexRequest().Index(s.Index(rec)).Doc(json.RawMessage(rec.Data)))
+	}
+
+	res, err := bulk.Do(ctx)
+	if err != nil {
+		return err
+	}
+	if failed := res.Failed(); len(failed) > 0 {
+		// Partially failed requests are reported with their first cause
+		if failed[0].Error != nil {
+			return fmt.Errorf("%d of %d records failed: %s", len(failed), len(records), failed[0].Error.Reason)
+		}
+		return fmt.Errorf("%d of %d records failed", len(failed), len(records))
+	}
+	return nil
+}
+
+// Close implements Sink. The shared client is left running.
+func (s *ElasticsearchSink) Close() error {
+	return nil
+}
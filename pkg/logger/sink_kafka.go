@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"context"
+	"errors"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaSink ships log records to a Kafka topic, one message per record
+// keyed by the logger name.
+type KafkaSink struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+// NewKafkaSink creates a new KafkaSink.
+//
+// The producer is shared with the application and is not closed by the
+// sink, the sink must be removed before the producer is closed.
+//
+// Parameters:
+//   - producer: The Kafka producer, e.g. resource.KafkaProducer.
+//   - topic: The topic the records are sent to.
+//
+// Returns:
+//   - *KafkaSink: The sink.
+func NewKafkaSink(producer sarama.SyncProducer, topic string) *KafkaSink {
+	return &KafkaSink{producer: producer, topic: topic}
+}
+
+// Name implements Sink.
+func (s *KafkaSink) Name() string {
+	return SinkKafka
+}
+
+// Write implements Sink.
+func (s *KafkaSink) Write(_ context.Context, records []Record) error {
+	msgs := make([]*sarama.ProducerMessage, 0, len(records))
+	for _, rec := range records {
+		msg := &sarama.ProducerMessage{
+			Topic:     s.topic,
+			Value:     sarama.ByteEncoder(rec.Data),
+			Timestamp: rec.Time,
+		}
+		if rec.LoggerName != "" {
+			msg.Key = sarama.StringEncoder(rec.LoggerName)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	err := s.producer.SendMessages(msgs)
+	var produceErrs sarama.ProducerErrors
+	if errors.As(err, &produceErrs) && len(produceErrs) > 0 {
+		// Report the first cause rather than the number of failures only
+		return produceErrs[0]
+	}
+	return err
+}
+
+// Close implements Sink. The shared producer is left open.
+func (s *KafkaSink) Close() error {
+	return nil
+}
@@ -0,0 +1,209 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// memorySink records the written batches, optionally blocking until
+// release is closed.
+type memorySink struct {
+	name    string
+	release chan struct{}
+
+	mu      sync.Mutex
+	records []Record
+	closed  bool
+}
+
+func (s *memorySink) Name() string { return s.name }
+
+func (s *memorySink) Write(_ context.Context, records []Record) error {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *memorySink) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var msgs []string
+	for _, rec := range s.records {
+		msgs = append(msgs, rec.Message)
+	}
+	return msgs
+}
+
+// newSinkLogger returns a logger writing only to the sinks of m.
+func newSinkLogger(m *SinkManager) *zap.Logger {
+	enc := zapcore.NewJSONEncoder(createEncoderConfig(time.RFC3339))
+	return zap.New(m.core(enc))
+}
+
+// TestSinkManagerClose tests that entries above the sink level are shipped
+// with their fields and that Close flushes the buffer.
+func TestSinkManagerClose(t *testing.T) {
+	m := NewSinkManager()
+	sink := &memorySink{name: "memory"}
+	if err := m.Add(sink, SinkOptions{Level: zapcore.InfoLevel, FlushInterval: time.Hour}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := m.Add(&memorySink{name: "memory"}, SinkOptions{}); err == nil {
+		t.Error("expected an error when adding a sink twice")
+	}
+
+	l := newSinkLogger(m).With(zap.String("service", "test"))
+	l.Debug("dropped")
+	l.Info("shipped", zap.Int("n", 1))
+
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if msgs := sink.messages(); len(msgs) != 1 || msgs[0] != "shipped" {
+		t.Fatalf("unexpected records: %v", msgs)
+	}
+	if !sink.closed {
+		t.Error("sink is not closed")
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(sink.records[0].Data, &doc); err != nil {
+		t.Fatalf("record is not JSON: %v", err)
+	}
+	if doc["service"] != "test" || doc["n"] != float64(1) {
+		t.Errorf("unexpected record: %v", doc)
+	}
+
+	// Entries logged after Close are dropped without blocking
+	l.Info("after close")
+	if len(m.Names()) != 0 || len(sink.messages()) != 1 {
+		t.Errorf("entry shipped after close: %v", sink.messages())
+	}
+}
+
+// TestSinkManagerOverflow tests that entries are dropped and counted when
+// the buffer of a slow sink is full.
+func TestSinkManagerOverflow(t *testing.T) {
+	m := NewSinkManager()
+	sink := &memorySink{name: "overflow", release: make(chan struct{})}
+	if err := m.Add(sink, SinkOptions{BufferSize: 2, BatchSize: 1, FlushInterval: time.Hour}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	dropped := sinkDroppedTotal.WithLabelValues("overflow", DropReasonOverflow)
+	before := testutil.ToFloat64(dropped)
+
+	// The first entry blocks the sink goroutine, 2 are buffered, the rest
+	// are dropped
+	l := newSinkLogger(m)
+	l.Info("first")
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		l.Info("next")
+	}
+
+	if got := testutil.ToFloat64(dropped) - before; got != 3 {
+		t.Errorf("dropped = %v, want 3", got)
+	}
+
+	close(sink.release)
+	if err := m.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := len(sink.messages()); got != 3 {
+		t.Errorf("shipped %d records, want 3", got)
+	}
+	_ = m.Close(context.Background())
+}
+
+// TestKafkaSink tests that records are sent to the configured topic.
+func TestKafkaSink(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		if msg.Topic != "app-logs" {
+			t.Errorf("topic = %s, want app-logs", msg.Topic)
+		}
+		return nil
+	})
+	producer.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+
+	sink := NewKafkaSink(producer, "app-logs")
+	rec := Record{Time: time.Now(), LoggerName: ModuleKafka, Data: []byte(`{"msg":"m"}`)}
+	if err := sink.Write(context.Background(), []Record{rec}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := sink.Write(context.Background(), []Record{rec}); err == nil {
+		t.Error("expected an error when the producer fails")
+	}
+	if err := producer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+// TestElasticsearchSinkIndex tests the daily index names.
+func TestElasticsearchSinkIndex(t *testing.T) {
+	sink := NewElasticsearchSink(nil, "app-logs", "")
+	rec := Record{Time: time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)}
+	if got := sink.Index(rec); got != "app-logs-2025.01.02" {
+		t.Errorf("Index() = %s, want app-logs-2025.01.02", got)
+	}
+}
+
+// TestSyslogSink tests that records are sent as RFC 5424 messages over UDP.
+func TestSyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), "app", DefaultSyslogFacility)
+	if err != nil {
+		t.Fatalf("NewSyslogSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	rec := Record{Level: zapcore.ErrorLevel, Time: time.Now(), Data: []byte(`{"msg":"failed"}`)}
+	if err := sink.Write(context.Background(), []Record{rec}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+
+	// local0 (16) * 8 + error (3)
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<131>1 ") || !strings.Contains(msg, " app ") || !strings.HasSuffix(msg, `{"msg":"failed"}`) {
+		t.Errorf("unexpected message: %s", msg)
+	}
+
+	if _, err := NewSyslogSink("unix", "/dev/log", "app", DefaultSyslogFacility); err == nil {
+		t.Error("expected an error for an unsupported network")
+	}
+}
@@ -1,14 +1,16 @@
 package nsq
 
 import (
+	"context"
 	"fmt"
 
-	"github.com/xiebingnote/go-gin-project/library/common"
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
-	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
 
 	"github.com/nsqio/go-nsq"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // Consumer initializes and runs the NSQ consumer.
@@ -35,19 +37,36 @@ func Consumer() error {
 
 // MessageHandler processes a message received from NSQ.
 //
-// It deserializes the message body into a TestMessage structure and performs
-// additional processing logic. If deserialization fails, it returns an error.
+// It decodes the message envelope to restore the request ID and the trace
+// propagated by the producer, deserializes the payload and performs
+// additional processing logic. A payload in the wire format of the schema
+// registry is deserialized into the type named by its schema, a bare
+// protobuf payload into a TestMessage. If decoding or deserialization
+// fails, it returns an error.
 //
 // Parameters:
 //   - message: The NSQ message to process.
 //
 // Returns:
 //   - An error if deserialization fails.
-func MessageHandler(message *nsq.Message) error {
-	// Deserialize the message body into a TestMessage structure
-	data, err := common.DeSerializeData(message.Body, &pkgproto.TestMessage{})
+func MessageHandler(message *nsq.Message) (err error) {
+	// Split the envelope headers from the payload
+	headers, payload, err := DecodeEnvelope(message.Body)
 	if err != nil {
-		// Return an error if deserialization fails
+		return err
+	}
+
+	// Restore the request ID and trace propagated by the producer
+	ctx := ContextFromHeaders(context.Background(), headers)
+	ctx, span := tracing.StartConsumerSpan(ctx, propagation.MapCarrier(headers), tracing.SystemNSQ, consumerTopic())
+	defer func() { tracing.EndSpan(span, err) }()
+	log := logger.WithContext(ctx, resource.NsqLogger)
+
+	// Deserialize the payload
+	data, err := decodeMessage(ctx, payload)
+	if err != nil {
+		// Log and return an error if deserialization fails
+		log.Error(fmt.Sprintf("failed to deserialize nsq message, err: %v", err))
 		return err
 	}
 
@@ -56,3 +75,12 @@ func MessageHandler(message *nsq.Message) error {
 
 	return nil
 }
+
+// consumerTopic returns the configured consumer topic, used to name the
+// consumer spans.
+func consumerTopic() string {
+	if config.NsqConfig == nil {
+		return ""
+	}
+	return config.NsqConfig.NSQ.Consumer.Topic
+}
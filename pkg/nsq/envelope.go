@@ -0,0 +1,116 @@
+package nsq
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// envelopeMagic prefixes message bodies carrying headers.
+//
+// NSQ has no message headers, so they are encoded in front of the payload.
+// The prefix starts with a zero byte, which never starts a valid protobuf
+// message (field number 0 is reserved), so bodies published without an
+// envelope are still decoded as plain payloads.
+var envelopeMagic = []byte{0x00, 'N', 'S', 'Q', 'H', 0x01}
+
+// ErrInvalidEnvelope is returned when a body starts with the envelope prefix
+// but cannot be decoded.
+var ErrInvalidEnvelope = errors.New("invalid nsq message envelope")
+
+// EncodeEnvelope wraps payload with the given headers.
+//
+// The layout is the magic prefix, the length of the JSON encoded headers as
+// a big endian uint32, the headers and the payload. The payload is returned
+// unchanged when there are no headers.
+//
+// Parameters:
+//   - headers: The headers to attach to the message.
+//   - payload: The message payload.
+//
+// Returns:
+//   - []byte: The message body to publish.
+//   - error: An error if the headers cannot be encoded.
+func EncodeEnvelope(headers map[string]string, payload []byte) ([]byte, error) {
+	if len(headers) == 0 {
+		return payload, nil
+	}
+
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, 0, len(envelopeMagic)+4+len(encoded)+len(payload))
+	body = append(body, envelopeMagic...)
+	body = binary.BigEndian.AppendUint32(body, uint32(len(encoded)))
+	body = append(body, encoded...)
+	body = append(body, payload...)
+	return body, nil
+}
+
+// DecodeEnvelope splits a message body into its headers and payload.
+//
+// Bodies without the envelope prefix are returned as the payload with no
+// headers.
+//
+// Parameters:
+//   - body: The message body received from NSQ.
+//
+// Returns:
+//   - map[string]string: The message headers, nil if there are none.
+//   - []byte: The message payload.
+//   - error: ErrInvalidEnvelope if the envelope is malformed.
+func DecodeEnvelope(body []byte) (map[string]string, []byte, error) {
+	if !bytes.HasPrefix(body, envelopeMagic) {
+		return nil, body, nil
+	}
+
+	rest := body[len(envelopeMagic):]
+	if len(rest) < 4 {
+		return nil, nil, ErrInvalidEnvelope
+	}
+	size := binary.BigEndian.Uint32(rest)
+	rest = rest[4:]
+	if uint64(size) > uint64(len(rest)) {
+		return nil, nil, ErrInvalidEnvelope
+	}
+
+	var headers map[string]string
+	if err := json.Unmarshal(rest[:size], &headers); err != nil {
+		return nil, nil, ErrInvalidEnvelope
+	}
+	return headers, rest[size:], nil
+}
+
+// HeadersFromContext returns the message headers carrying the correlation
+// values found in ctx, such as the request ID and the trace context.
+func HeadersFromContext(ctx context.Context) map[string]string {
+	headers := make(map[string]string)
+	if requestID := common.RequestIDFromContext(ctx); requestID != "" {
+		headers[common.RequestIDHeader] = requestID
+	}
+	tracing.Inject(ctx, propagation.MapCarrier(headers))
+
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// ContextFromHeaders returns a copy of ctx carrying the request ID found in
+// the message headers, generating a new one if there is none.
+func ContextFromHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if requestID := headers[common.RequestIDHeader]; requestID != "" {
+		return common.WithRequestID(ctx, requestID)
+	}
+	ctx, _ = common.EnsureRequestID(ctx)
+	return ctx
+}
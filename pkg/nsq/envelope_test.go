@@ -0,0 +1,71 @@
+package nsq
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+)
+
+// TestEnvelopeRoundTrip tests that headers and payload survive encoding.
+func TestEnvelopeRoundTrip(t *testing.T) {
+	payload := []byte{0x08, 0x01, 0x12, 0x03, 'a', 'b', 'c'}
+	headers := map[string]string{common.RequestIDHeader: "req-1"}
+
+	body, err := EncodeEnvelope(headers, payload)
+	if err != nil {
+		t.Fatalf("EncodeEnvelope() error = %v", err)
+	}
+
+	gotHeaders, gotPayload, err := DecodeEnvelope(body)
+	if err != nil {
+		t.Fatalf("DecodeEnvelope() error = %v", err)
+	}
+	if gotHeaders[common.RequestIDHeader] != "req-1" {
+		t.Errorf("request ID = %q, want %q", gotHeaders[common.RequestIDHeader], "req-1")
+	}
+	if !bytes.Equal(gotPayload, payload) {
+		t.Errorf("payload = %v, want %v", gotPayload, payload)
+	}
+}
+
+// TestEnvelopeWithoutHeaders tests that plain bodies are left unchanged.
+func TestEnvelopeWithoutHeaders(t *testing.T) {
+	payload := []byte{0x08, 0x01}
+
+	body, err := EncodeEnvelope(nil, payload)
+	if err != nil {
+		t.Fatalf("EncodeEnvelope() error = %v", err)
+	}
+	if !bytes.Equal(body, payload) {
+		t.Errorf("body = %v, want payload unchanged", body)
+	}
+
+	headers, got, err := DecodeEnvelope(payload)
+	if err != nil || headers != nil || !bytes.Equal(got, payload) {
+		t.Errorf("DecodeEnvelope() = %v, %v, %v, want plain payload", headers, got, err)
+	}
+}
+
+// TestDecodeEnvelopeInvalid tests that truncated envelopes are rejected.
+func TestDecodeEnvelopeInvalid(t *testing.T) {
+	body, _ := EncodeEnvelope(map[string]string{"k": "v"}, nil)
+
+	if _, _, err := DecodeEnvelope(body[:len(body)-2]); err != ErrInvalidEnvelope {
+		t.Errorf("DecodeEnvelope() error = %v, want %v", err, ErrInvalidEnvelope)
+	}
+}
+
+// TestContextFromHeaders tests that the request ID is restored or generated.
+func TestContextFromHeaders(t *testing.T) {
+	ctx := ContextFromHeaders(context.Background(), map[string]string{common.RequestIDHeader: "req-2"})
+	if got := common.RequestIDFromContext(ctx); got != "req-2" {
+		t.Errorf("request ID = %q, want %q", got, "req-2")
+	}
+
+	ctx = ContextFromHeaders(context.Background(), nil)
+	if common.RequestIDFromContext(ctx) == "" {
+		t.Error("expected a generated request ID")
+	}
+}
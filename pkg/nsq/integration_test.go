@@ -0,0 +1,114 @@
+package nsq
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/bootstrap/service"
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+)
+
+// daemonDialTimeout is the time waited for a daemon of the NSQ cluster of
+// the integration tests to accept a connection.
+const daemonDialTimeout = 2 * time.Second
+
+var (
+	// initNSQOnce initializes the NSQ service of the integration tests once.
+	initNSQOnce sync.Once
+
+	// nsqUnavailable is the reason why the integration tests are skipped,
+	// empty once the NSQ service is initialized.
+	nsqUnavailable string
+)
+
+// requireNSQ skips the integration test when no daemon of the NSQ cluster of
+// conf/service/nsq.toml is reachable, and initializes the NSQ service
+// otherwise.
+//
+// The NSQ service is initialized by loading the configuration from a TOML
+// file and decoding it into the NsqConfig struct, then initializing the
+// logger and the NSQ service with a background context.
+//
+// If there is an error getting the current working directory, or the NSQ
+// configuration file cannot be decoded, the panic is recovered and logged.
+func requireNSQ(t *testing.T) {
+	t.Helper()
+
+	initNSQOnce.Do(func() {
+		// Handle panics gracefully
+		defer func() {
+			if r := recover(); r != nil {
+				if resource.LoggerService != nil {
+					resource.LoggerService.Error("Recovered from panic",
+						zap.Any("panic", r),
+						zap.String("stack", string(debug.Stack())),
+					)
+				} else {
+					// Fallback to standard log if logger is not available
+					log.Printf("Test panic recovered: %v\n", r)
+					log.Printf("Stack trace: %s\n", string(debug.Stack()))
+				}
+			}
+		}()
+
+		// Retrieve the current working directory
+		rootDir, err := os.Getwd()
+		if err != nil {
+			// Panic if there is an error getting the working directory
+			panic(err)
+		}
+
+		// Extract the root directory path by splitting on "/pkg"
+		dir := strings.Split(rootDir, "/pkg")
+		rootDir = dir[0]
+
+		// Load configuration from the specified TOML file
+		if _, err = toml.DecodeFile(rootDir+"/conf/log/log.toml", &config.LogConfig); err != nil {
+			panic("Failed to load log configuration file: " + err.Error())
+		}
+		if _, err = toml.DecodeFile(rootDir+"/conf/server.toml", &config.ServerConfig); err != nil {
+			panic("Failed to load server configuration file: " + err.Error())
+		}
+		if _, err = toml.DecodeFile(rootDir+"/conf/service/nsq.toml", &config.NsqConfig); err != nil {
+			panic("Failed to load nsq configuration file: " + err.Error())
+		}
+
+		if !reachable(config.NsqConfig.NSQ.Address) {
+			nsqUnavailable = fmt.Sprintf("Skipping integration test - no NSQ daemon reachable at %v",
+				config.NsqConfig.NSQ.Address)
+			return
+		}
+
+		// Initialize the logger and Nsq service with a background context
+		service.InitLogger(context.Background())
+		service.InitNSQ(context.Background())
+	})
+
+	if nsqUnavailable != "" {
+		t.Skip(nsqUnavailable)
+	}
+}
+
+// reachable reports whether one of the addresses accepts a TCP connection.
+func reachable(addrs []string) bool {
+	for _, addr := range addrs {
+		conn, err := net.DialTimeout("tcp", addr, daemonDialTimeout)
+		if err == nil {
+			_ = conn.Close()
+			return true
+		}
+	}
+	return false
+}
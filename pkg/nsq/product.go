@@ -1,15 +1,19 @@
 package nsq
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 
-	"github.com/xiebingnote/go-gin-project/library/common"
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/clientmetrics"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
 	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/tracing"
 
 	"github.com/nsqio/go-nsq"
+	"google.golang.org/protobuf/proto"
 )
 
 // Producer sends a message to a randomly selected NSQ producer.
@@ -24,23 +28,17 @@ func Producer() error {
 		Name: "testName",
 	}
 
-	// Serialize the message into a byte slice
-	serializedData, err := common.SerializeData(message)
+	// Serialize the message into a byte slice, in the wire format of the
+	// schema registry if it is enabled
+	serializedData, _, err := encodeMessage(context.Background(), config.NsqConfig.NSQ.Consumer.Topic, message)
 	if err != nil {
 		return err
 	}
 
-	// Ensure the NSQ producer list is not nil
-	if resource.NsqProducer == nil {
-		return fmt.Errorf("nsq producer is nil")
-	}
-
 	// Select a random NSQ producer from the list
-	var producer *nsq.Producer
-	if len(resource.NsqProducer) == 1 {
-		producer = resource.NsqProducer[0]
-	} else {
-		producer = resource.NsqProducer[rand.Intn(len(resource.NsqProducer))]
+	producer, err := selectProducer()
+	if err != nil {
+		return err
 	}
 
 	// Publish the serialized message to the specified NSQ topic
@@ -54,3 +52,90 @@ func Producer() error {
 	producer.Stop()
 	return nil
 }
+
+// PublishWithContext publishes a message to the specified NSQ topic using a
+// randomly selected producer.
+//
+// The request ID and the trace context found in ctx are propagated in the
+// message envelope, see EncodeEnvelope. Messages published without any of
+// them are sent as is. A producer span is created for the message.
+//
+// Parameters:
+//   - ctx: The context of the request producing the message.
+//   - topic: The topic to publish the message to.
+//   - payload: The serialized message.
+//
+// Returns:
+//   - An error if no producer is available or the message fails to publish.
+func PublishWithContext(ctx context.Context, topic string, payload []byte) error {
+	return publish(ctx, topic, payload, nil)
+}
+
+// PublishMessage serializes a protobuf message and publishes it to the
+// specified NSQ topic, see PublishWithContext.
+//
+// The message is serialized in the wire format of the schema registry, with
+// the type URL and the schema version in the envelope headers, if the
+// registry is enabled, see resource.SchemaSerde, and as bare protobuf
+// otherwise.
+//
+// Parameters:
+//   - ctx: The context of the request producing the message.
+//   - topic: The topic to publish the message to.
+//   - msg: The message.
+//
+// Returns:
+//   - An error if the message cannot be serialized, no producer is
+//     available or the message fails to publish.
+func PublishMessage(ctx context.Context, topic string, msg proto.Message) error {
+	payload, headers, err := encodeMessage(ctx, topic, msg)
+	if err != nil {
+		return fmt.Errorf("failed to serialize nsq message: %w", err)
+	}
+	return publish(ctx, topic, payload, headers)
+}
+
+// publish publishes a payload with the headers of ctx and the given ones in
+// its envelope.
+func publish(ctx context.Context, topic string, payload []byte, headers map[string]string) (err error) {
+	ctx, span := tracing.StartProducerSpan(ctx, tracing.SystemNSQ, topic)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	envelope := HeadersFromContext(ctx)
+	if len(headers) > 0 && envelope == nil {
+		envelope = make(map[string]string, len(headers))
+	}
+	for k, v := range headers {
+		envelope[k] = v
+	}
+	body, err := EncodeEnvelope(envelope, payload)
+	if err != nil {
+		return err
+	}
+
+	producer, err := selectProducer()
+	if err != nil {
+		return err
+	}
+
+	err = producer.Publish(topic, body)
+	clientmetrics.ObserveNSQPublish(clientmetrics.ClientNSQProducer, producer.String(), topic, err)
+	if err != nil {
+		logger.WithContext(ctx, resource.NsqLogger).Error(fmt.Sprintf("failed to publish message to topic %s, err: %v", topic, err))
+		return err
+	}
+	return nil
+}
+
+// selectProducer returns a randomly selected NSQ producer.
+func selectProducer() (*nsq.Producer, error) {
+	// Ensure the NSQ producer list is not empty
+	if len(resource.NsqProducer) == 0 {
+		return nil, fmt.Errorf("nsq producer is nil")
+	}
+
+	if len(resource.NsqProducer) == 1 {
+		return resource.NsqProducer[0], nil
+	}
+	return resource.NsqProducer[rand.Intn(len(resource.NsqProducer))], nil
+}
@@ -0,0 +1,46 @@
+package nsq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xiebingnote/go-gin-project/library/common"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/schema"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// encodeMessage serializes a message of a topic: in the wire format of the
+// schema registry with the headers describing its schema if the registry is
+// enabled, see resource.SchemaSerde, as bare protobuf otherwise.
+func encodeMessage(ctx context.Context, topic string, msg proto.Message) ([]byte, map[string]string, error) {
+	serde := resource.SchemaSerde
+	if serde == nil {
+		data, err := common.SerializeData(msg)
+		return data, nil, err
+	}
+
+	data, info, err := serde.Serialize(ctx, topic, msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, info.Headers(), nil
+}
+
+// decodeMessage deserializes a payload: into the type named by its schema
+// if it is in the wire format of the schema registry, into a TestMessage if
+// it is bare protobuf.
+func decodeMessage(ctx context.Context, payload []byte) (proto.Message, error) {
+	if !schema.IsFramed(payload) {
+		return common.DeSerializeData(payload, &pkgproto.TestMessage{})
+	}
+
+	serde := resource.SchemaSerde
+	if serde == nil {
+		return nil, fmt.Errorf("nsq message is in the schema registry wire format, but the schema registry is disabled")
+	}
+	msg, _, err := serde.Deserialize(ctx, payload)
+	return msg, err
+}
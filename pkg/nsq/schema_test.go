@@ -0,0 +1,51 @@
+package nsq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+	"github.com/xiebingnote/go-gin-project/pkg/schema"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// TestSchemaRegistryMessage tests that the messages are serialized in the
+// wire format of the schema registry when it is enabled, and that both
+// framed and bare payloads are deserialized.
+func TestSchemaRegistryMessage(t *testing.T) {
+	serde := resource.SchemaSerde
+	t.Cleanup(func() { resource.SchemaSerde = serde })
+	resource.SchemaSerde = nil
+
+	msg := &pkgproto.TestMessage{Id: 1, Name: "bare"}
+	bare, headers, err := encodeMessage(context.Background(), "events", msg)
+	if err != nil || headers != nil || schema.IsFramed(bare) {
+		t.Fatalf("encodeMessage() = %v, %v, %v, want a bare message", bare, headers, err)
+	}
+
+	types := schema.NewTypes()
+	types.Register(&pkgproto.TestMessage{})
+	resource.SchemaSerde = schema.NewSerde(schema.NewMemoryRegistry(schema.CompatBackward), schema.SerdeConfig{
+		Format:       schema.FormatAvro,
+		AutoRegister: true,
+		Types:        types,
+	})
+	framed, headers, err := encodeMessage(context.Background(), "events", msg)
+	if err != nil || !schema.IsFramed(framed) || headers[schema.HeaderTypeURL] != schema.TypeURL(msg) {
+		t.Fatalf("encodeMessage() = %v, %v, %v, want a framed message", framed, headers, err)
+	}
+
+	for _, payload := range [][]byte{bare, framed} {
+		got, err := decodeMessage(context.Background(), payload)
+		if err != nil || !proto.Equal(got, msg) {
+			t.Errorf("decodeMessage() = %v, %v, want %v", got, err, msg)
+		}
+	}
+
+	resource.SchemaSerde = nil
+	if _, err := decodeMessage(context.Background(), framed); err == nil {
+		t.Error("decodeMessage() of a framed message without the schema registry error = nil")
+	}
+}
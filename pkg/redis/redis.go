@@ -6,19 +6,21 @@ import (
 	"fmt"
 
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
 
 	"github.com/redis/go-redis/v9"
 )
 
-var ctx = context.Background()
-
 // SetValue sets a key-value pair in Redis with no expiration time.
 //
 // It uses the Redis client to set the key "test" with the value "test".
 // If the operation fails, it returns the error.
-func SetValue() error {
-	// Set the key "test" to the value "test" with no expiration time.
-	err := resource.RedisClient.Set(ctx, "test", "test", 0).Err()
+func SetValue(ctx context.Context) error {
+	// Set the key "test" to the value "test" with no expiration time, under
+	// the Redis policy. SET is idempotent and may be retried.
+	err := resource.Resilience.Get("redis").Execute(ctx, func(ctx context.Context) error {
+		return resource.RedisClient.Set(ctx, "test", "test", 0).Err()
+	})
 	if err != nil {
 		// Return the error if the set operation fails.
 		return err
@@ -34,9 +36,12 @@ func SetValue() error {
 // does not exist, it returns redis.Nil. If there is an error during the get
 // operation, it returns the error. If the operation is successful, it prints
 // out the value.
-func GetValue() error {
-	// Get the value for the key "test".
-	val, err := resource.RedisClient.Get(ctx, "test").Result()
+func GetValue(ctx context.Context) error {
+	// Get the value for the key "test" under the Redis policy, redis.Nil
+	// being neither retried nor counted as a failure.
+	val, err := resilience.Do(ctx, resource.Resilience.Get("redis"), func(ctx context.Context) (string, error) {
+		return resource.RedisClient.Get(ctx, "test").Result()
+	})
 	if errors.Is(err, redis.Nil) {
 		// Log an error if the key does not exist.
 		resource.LoggerService.Error(fmt.Sprintf("redis get failed: %v", err))
@@ -59,7 +64,7 @@ func GetValue() error {
 // It performs a series of operations on a Redis list identified by the key
 // "test-list". It pushes elements to the list, pops an element from the list,
 // and retrieves the remaining elements. It returns an error if any operation fails.
-func ListValue() error {
+func ListValue(ctx context.Context) error {
 	listKey := "test-list"
 
 	// Push elements "test1", "test2", and "test3" to the end of the list.
@@ -97,7 +102,7 @@ func ListValue() error {
 // It performs a series of operations on a Redis hash identified by the key
 // "test-hash". It sets multiple field-value pairs, retrieves a specific field,
 // and retrieves all field-value pairs. It returns an error if any operation fails.
-func HashValue() error {
+func HashValue(ctx context.Context) error {
 	hashKey := "test-hash"
 
 	// Set field-value pairs "test1": 1 and "test2": 2 in the hash.
@@ -130,7 +135,7 @@ func HashValue() error {
 //
 // It publishes a message with the value "test" to the channel identified by
 // the key "test". It returns an error if the publish operation fails.
-func PublishValue() error {
+func PublishValue(ctx context.Context) error {
 	err := resource.RedisClient.Publish(ctx, "test", "test").Err()
 	if err != nil {
 		return err
@@ -144,7 +149,7 @@ func PublishValue() error {
 // It subscribes to the channel identified by the key "test" and prints all
 // messages it receives. It blocks until the context is canceled and then
 // returns.
-func SubscribeValue() error {
+func SubscribeValue(ctx context.Context) error {
 	// Subscribe to the channel identified by the key "test".
 	sub := resource.RedisClient.Subscribe(ctx, "test")
 	ch := sub.Channel()
@@ -164,7 +169,7 @@ func SubscribeValue() error {
 // If the key already exists, it prints a message indicating that the lock
 // cannot be acquired. Otherwise, it prints a message indicating that the
 // lock is acquired and then releases the lock by deleting the key.
-func SetNXValue() error {
+func SetNXValue(ctx context.Context) error {
 	lockKey := "test"
 	ok, err := resource.RedisClient.SetNX(ctx, lockKey, "test", 0).Result()
 	if err != nil {
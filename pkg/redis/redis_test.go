@@ -71,7 +71,7 @@ func init() {
 // occurs, it logs an error message with the test name and the error.
 // Otherwise, it logs a success message with the test name.
 func TestSetValue(t *testing.T) {
-	err := SetValue()
+	err := SetValue(context.Background())
 	if err != nil {
 		t.Errorf("%s: %v", "TestSetValue", err)
 	} else {
@@ -85,7 +85,7 @@ func TestSetValue(t *testing.T) {
 // occurs, it logs an error message with the test name and the error.
 // Otherwise, it logs a success message with the test name.
 func TestGetValue(t *testing.T) {
-	err := GetValue()
+	err := GetValue(context.Background())
 	if err != nil {
 		t.Errorf("%s: %v", "TestGetValue", err)
 	} else {
@@ -99,7 +99,7 @@ func TestGetValue(t *testing.T) {
 // occurs, it logs an error message with the test name and the error.
 // Otherwise, it logs a success message with the test name.
 func TestListValue(t *testing.T) {
-	err := ListValue()
+	err := ListValue(context.Background())
 	if err != nil {
 		t.Errorf("%s: %v", "TestListValue", err)
 	} else {
@@ -113,7 +113,7 @@ func TestListValue(t *testing.T) {
 // occurs, it logs an error message with the test name and the error.
 // Otherwise, it logs a success message with the test name.
 func TestHashValue(t *testing.T) {
-	err := HashValue()
+	err := HashValue(context.Background())
 	if err != nil {
 		t.Errorf("%s: %v", "TestHashValue", err)
 	} else {
@@ -0,0 +1,126 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBulkheadFull is returned when a call is rejected by a bulkhead, because
+// its queue is full or the queue timeout expired.
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// DefaultMaxConcurrent is the maximum number of concurrent calls of a
+// bulkhead whose configuration sets none.
+const DefaultMaxConcurrent = 100
+
+// BulkheadConfig is the configuration of a bulkhead.
+type BulkheadConfig struct {
+	// MaxConcurrent is the maximum number of concurrent calls,
+	// DefaultMaxConcurrent if zero.
+	MaxConcurrent int
+	// MaxQueue is the maximum number of calls waiting for a slot, zero for
+	// no limit other than the queue timeout.
+	MaxQueue int
+	// QueueTimeout is the longest time a call waits for a slot, zero to
+	// reject the calls right away when all the slots are taken.
+	QueueTimeout time.Duration
+}
+
+// Bulkhead limits the number of concurrent calls to a dependency, so that a
+// slow dependency cannot take all the goroutines and connections of the
+// service.
+type Bulkhead struct {
+	name   string
+	cfg    BulkheadConfig
+	slots  chan struct{}
+	queued atomic.Int64
+}
+
+// NewBulkhead creates a bulkhead.
+//
+// Parameters:
+//   - name: The name of the bulkhead, usually the name of the dependency.
+//   - cfg: The configuration of the bulkhead.
+//
+// Returns:
+//   - *Bulkhead: The bulkhead.
+func NewBulkhead(name string, cfg BulkheadConfig) *Bulkhead {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = DefaultMaxConcurrent
+	}
+	return &Bulkhead{
+		name:  name,
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// Execute implements Policy. The call is run once a slot is free, and
+// rejected with ErrBulkheadFull if the queue is full or if no slot is freed
+// within the queue timeout.
+func (b *Bulkhead) Execute(ctx context.Context, fn Func) error {
+	if err := b.acquire(ctx); err != nil {
+		return err
+	}
+	defer b.release()
+
+	return fn(ctx)
+}
+
+// InFlight returns the number of calls running within the bulkhead.
+func (b *Bulkhead) InFlight() int {
+	return len(b.slots)
+}
+
+// acquire takes a slot, waiting for one if the queue allows it.
+func (b *Bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		bulkheadInFlight.WithLabelValues(b.name).Inc()
+		bulkheadWait.WithLabelValues(b.name).Observe(0)
+		return nil
+	default:
+	}
+
+	if b.cfg.QueueTimeout <= 0 {
+		return b.reject()
+	}
+	if queued := b.queued.Add(1); b.cfg.MaxQueue > 0 && queued > int64(b.cfg.MaxQueue) {
+		b.queued.Add(-1)
+		return b.reject()
+	}
+	bulkheadQueued.WithLabelValues(b.name).Inc()
+	defer func() {
+		b.queued.Add(-1)
+		bulkheadQueued.WithLabelValues(b.name).Dec()
+	}()
+
+	start := time.Now()
+	timer := time.NewTimer(b.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case b.slots <- struct{}{}:
+		bulkheadInFlight.WithLabelValues(b.name).Inc()
+		bulkheadWait.WithLabelValues(b.name).Observe(time.Since(start).Seconds())
+		return nil
+	case <-timer.C:
+		return b.reject()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot.
+func (b *Bulkhead) release() {
+	<-b.slots
+	bulkheadInFlight.WithLabelValues(b.name).Dec()
+}
+
+// reject records a rejected call.
+func (b *Bulkhead) reject() error {
+	bulkheadRejected.WithLabelValues(b.name).Inc()
+	return ErrBulkheadFull
+}
@@ -0,0 +1,48 @@
+package resilience
+
+import (
+	"context"
+
+	"github.com/xiebingnote/go-gin-project/pkg/circuitbreaker"
+)
+
+// CircuitBreaker runs the calls through a circuit breaker of a
+// circuitbreaker.Manager.
+//
+// The breaker is looked up by name on every call, so that the breakers
+// replaced by a configuration reload are picked up. The calls are run as
+// they are while the manager has no breaker of this name.
+type CircuitBreaker struct {
+	manager *circuitbreaker.Manager
+	name    string
+}
+
+// NewCircuitBreaker creates a circuit breaker policy.
+//
+// Parameters:
+//   - manager: The circuit breaker manager, nil to run the calls as they are.
+//   - name: The name of the circuit breaker, e.g. a dependency declared in
+//     conf/service/circuitbreaker.toml.
+//
+// Returns:
+//   - *CircuitBreaker: The circuit breaker policy.
+func NewCircuitBreaker(manager *circuitbreaker.Manager, name string) *CircuitBreaker {
+	return &CircuitBreaker{manager: manager, name: name}
+}
+
+// Execute implements Policy. The rejected calls return
+// circuitbreaker.ErrOpenState or circuitbreaker.ErrTooManyRequests.
+func (b *CircuitBreaker) Execute(ctx context.Context, fn Func) error {
+	var cb *circuitbreaker.CircuitBreaker
+	if b.manager != nil {
+		cb = b.manager.GetBreaker(b.name)
+	}
+	if cb == nil {
+		return fn(ctx)
+	}
+
+	_, err := cb.ExecuteWithContext(ctx, func(ctx context.Context) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
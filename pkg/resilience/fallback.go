@@ -0,0 +1,75 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+)
+
+// Fallback runs a fallback function when a call fails, e.g. to serve a
+// cached value while MySQL is unavailable.
+//
+// The fallback of the calls returning a result is given to DoWithFallback
+// instead.
+type Fallback struct {
+	name     string
+	fallback func(ctx context.Context, err error) error
+}
+
+// NewFallback creates a fallback policy.
+//
+// Parameters:
+//   - name: The name of the policy, usually the name of the dependency.
+//   - fallback: The function run with the error of the failed call, unless
+//     the context was cancelled. Its error is returned instead.
+//
+// Returns:
+//   - *Fallback: The fallback policy.
+func NewFallback(name string, fallback func(ctx context.Context, err error) error) *Fallback {
+	return &Fallback{name: name, fallback: fallback}
+}
+
+// Execute implements Policy.
+func (f *Fallback) Execute(ctx context.Context, fn Func) error {
+	err := fn(ctx)
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	return recordFallback(f.name, f.fallback(ctx, err))
+}
+
+// DoWithFallback runs a call returning a result under a policy, and returns
+// the result of the fallback function if the call fails, e.g. a cached or
+// default value.
+//
+// Parameters:
+//   - ctx: The context of the call.
+//   - name: The name recorded in the fallback metrics, usually the name of
+//     the dependency.
+//   - p: The policy.
+//   - fn: The call.
+//   - fallback: The function run with the error of the failed call, unless
+//     the context was cancelled.
+//
+// Returns:
+//   - T: The result of the call or of the fallback.
+//   - error: The error of the fallback if the call failed.
+func DoWithFallback[T any](ctx context.Context, name string, p Policy, fn func(ctx context.Context) (T, error),
+	fallback func(ctx context.Context, err error) (T, error)) (T, error) {
+	v, err := Do(ctx, p, fn)
+	if err == nil || errors.Is(err, context.Canceled) {
+		return v, err
+	}
+
+	v, err = fallback(ctx, err)
+	return v, recordFallback(name, err)
+}
+
+// recordFallback records the result of a fallback and returns its error.
+func recordFallback(name string, err error) error {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFailure
+	}
+	fallbackCalls.WithLabelValues(name, result).Inc()
+	return err
+}
@@ -0,0 +1,107 @@
+package resilience
+
+import (
+	"context"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/pkg/circuitbreaker"
+)
+
+// Default hedge configuration.
+const (
+	DefaultHedgeDelay = 100 * time.Millisecond
+	DefaultMaxHedges  = 1
+)
+
+// HedgeConfig is the configuration of a hedge policy.
+type HedgeConfig struct {
+	// Delay is the time waited for an attempt before a hedged attempt is
+	// started, usually a high percentile of the latency of the dependency,
+	// DefaultHedgeDelay if zero.
+	Delay time.Duration
+	// MaxHedges is the maximum number of hedged attempts of a call, the
+	// first attempt excluded, DefaultMaxHedges if zero.
+	MaxHedges int
+}
+
+// Hedge sends hedged requests: when an attempt is slower than the delay, a
+// concurrent attempt is started and the first successful one wins, the
+// others being cancelled. It cuts the tail latency of reads at the cost of
+// extra load, and must only protect idempotent calls.
+//
+// A failed attempt does not start a new one, the call fails once all the
+// started attempts failed. Failed calls are retried by Retry. A not found
+// result of circuitbreaker.IsNotFound ends the call like a success, and the
+// writes run by DoWrite are never hedged.
+type Hedge struct {
+	name string
+	cfg  HedgeConfig
+}
+
+// NewHedge creates a hedge policy.
+//
+// Parameters:
+//   - name: The name of the policy, usually the name of the dependency.
+//   - cfg: The configuration of the policy.
+//
+// Returns:
+//   - *Hedge: The hedge policy.
+func NewHedge(name string, cfg HedgeConfig) *Hedge {
+	if cfg.Delay <= 0 {
+		cfg.Delay = DefaultHedgeDelay
+	}
+	if cfg.MaxHedges <= 0 {
+		cfg.MaxHedges = DefaultMaxHedges
+	}
+	return &Hedge{name: name, cfg: cfg}
+}
+
+// Execute implements Policy. The error of the last failed attempt is
+// returned if all the attempts failed.
+func (h *Hedge) Execute(ctx context.Context, fn Func) error {
+	if IsWrite(ctx) {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		err    error
+		hedged bool
+	}
+	// Buffered so that the attempts losing the race do not block
+	results := make(chan result, h.cfg.MaxHedges+1)
+	start := func(hedged bool) {
+		go func() { results <- result{err: fn(ctx), hedged: hedged} }()
+	}
+
+	start(false)
+	started, running := 1, 1
+	timer := time.NewTimer(h.cfg.Delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case r := <-results:
+			running--
+			if r.err == nil || circuitbreaker.IsNotFound(r.err) {
+				if r.hedged {
+					hedgeWins.WithLabelValues(h.name).Inc()
+				}
+				return r.err
+			}
+			if running == 0 {
+				return r.err
+			}
+		case <-timer.C:
+			if started <= h.cfg.MaxHedges {
+				start(true)
+				started++
+				running++
+				hedgeAttempts.WithLabelValues(h.name).Inc()
+				timer.Reset(h.cfg.Delay)
+			}
+		}
+	}
+}
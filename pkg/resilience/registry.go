@@ -0,0 +1,64 @@
+package resilience
+
+import (
+	"sort"
+	"sync"
+)
+
+// Registry holds the policies protecting the dependencies, by name.
+type Registry struct {
+	mu       sync.RWMutex
+	policies map[string]Policy
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{policies: make(map[string]Policy)}
+}
+
+// Set sets the policy of a dependency, replacing the previous one.
+//
+// Parameters:
+//   - name: The name of the dependency, e.g. "mysql".
+//   - p: The policy of the dependency.
+func (r *Registry) Set(name string, p Policy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.policies[name] = p
+}
+
+// Get returns the policy of a dependency.
+//
+// Parameters:
+//   - name: The name of the dependency, e.g. "mysql".
+//
+// Returns:
+//   - Policy: The policy of the dependency, NoPolicy if none is set, so that
+//     the calls are still made when the dependency is not configured.
+func (r *Registry) Get(name string) Policy {
+	if r == nil {
+		return NoPolicy
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if p, ok := r.policies[name]; ok {
+		return p
+	}
+	return NoPolicy
+}
+
+// Names returns the sorted names of the dependencies having a policy.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.policies))
+	for name := range r.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
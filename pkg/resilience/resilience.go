@@ -0,0 +1,279 @@
+// Package resilience provides composable policies protecting the calls to
+// the dependencies, e.g. MySQL, Redis or Elasticsearch: bulkhead, retry with
+// exponential backoff and jitter, hedged requests, fallback and the circuit
+// breakers of pkg/circuitbreaker.
+//
+// Policies are combined with Wrap, the first policy being the outermost:
+//
+//	p := resilience.Wrap(
+//		resilience.NewRetry("mysql", resilience.RetryConfig{MaxAttempts: 3}),
+//		resilience.NewCircuitBreaker(resource.CircuitBreakerManager, "mysql"),
+//		resilience.NewBulkhead("mysql", resilience.BulkheadConfig{MaxConcurrent: 50}),
+//	)
+//	user, err := resilience.Do(ctx, p, func(ctx context.Context) (*User, error) {
+//		return findUser(ctx, resource.MySQLClient.WithContext(ctx), id)
+//	})
+//
+// The non idempotent calls, e.g. an INSERT, are run with DoWrite, which
+// attempts them once. The not found results of the dependencies, e.g.
+// redis.Nil or gorm.ErrRecordNotFound, are successes: they are neither
+// retried nor counted as failures by the circuit breakers.
+//
+// Every policy records Prometheus metrics labeled with its name, usually the
+// name of the dependency.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// NameLabel is the label holding the name of the policy.
+const NameLabel = "name"
+
+var (
+	// 舱壁正在执行的调用数
+	bulkheadInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "resilience_bulkhead_in_flight",
+			Help: "Number of calls running within the bulkhead",
+		},
+		[]string{NameLabel},
+	)
+
+	// 舱壁排队等待的调用数
+	bulkheadQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "resilience_bulkhead_queued",
+			Help: "Number of calls waiting for a slot of the bulkhead",
+		},
+		[]string{NameLabel},
+	)
+
+	// 舱壁拒绝的调用数
+	bulkheadRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resilience_bulkhead_rejected_total",
+			Help: "Total number of calls rejected by the bulkhead, because its queue was full or the queue timeout expired",
+		},
+		[]string{NameLabel},
+	)
+
+	// 舱壁排队等待时间
+	bulkheadWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "resilience_bulkhead_wait_seconds",
+			Help:    "Time spent by the admitted calls waiting for a slot of the bulkhead",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		},
+		[]string{NameLabel},
+	)
+
+	// 重试次数
+	retryAttempts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resilience_retry_attempts_total",
+			Help: "Total number of retried attempts, the first attempt of a call excluded",
+		},
+		[]string{NameLabel},
+	)
+
+	// 重试后的调用结果
+	retryCalls = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resilience_retry_calls_total",
+			Help: "Total number of calls made by the retry policy, by result: success, exhausted when the attempts or the deadline ran out, or aborted for a non retryable error",
+		},
+		[]string{NameLabel, "result"},
+	)
+
+	// 对冲请求数
+	hedgeAttempts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resilience_hedge_attempts_total",
+			Help: "Total number of hedged attempts started, the first attempt of a call excluded",
+		},
+		[]string{NameLabel},
+	)
+
+	// 对冲请求成功次数
+	hedgeWins = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resilience_hedge_wins_total",
+			Help: "Total number of calls whose result came from a hedged attempt",
+		},
+		[]string{NameLabel},
+	)
+
+	// 降级次数
+	fallbackCalls = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resilience_fallback_calls_total",
+			Help: "Total number of fallbacks, by result: success or failure",
+		},
+		[]string{NameLabel, "result"},
+	)
+)
+
+// Results of the calls recorded in the metrics.
+const (
+	ResultSuccess   = "success"
+	ResultFailure   = "failure"
+	ResultExhausted = "exhausted"
+	ResultAborted   = "aborted"
+)
+
+// Func is a call protected by the policies. It must honour the
+// cancellation of its context, e.g. when a hedged attempt wins.
+type Func func(ctx context.Context) error
+
+// Policy protects the calls to a dependency.
+type Policy interface {
+	// Execute runs fn under the policy and returns its error, or the error
+	// of the policy if fn was not run, e.g. ErrBulkheadFull.
+	Execute(ctx context.Context, fn Func) error
+}
+
+// PolicyFunc is an adapter allowing a function to be used as a Policy.
+type PolicyFunc func(ctx context.Context, fn Func) error
+
+// Execute implements Policy.
+func (f PolicyFunc) Execute(ctx context.Context, fn Func) error {
+	return f(ctx, fn)
+}
+
+// NoPolicy runs the calls as they are.
+var NoPolicy Policy = PolicyFunc(func(ctx context.Context, fn Func) error { return fn(ctx) })
+
+// Wrap combines policies, the first one being the outermost, e.g. a retry
+// around a bulkhead retries the calls rejected by the bulkhead. Nil policies
+// are skipped.
+//
+// Parameters:
+//   - policies: The policies, from the outermost to the innermost.
+//
+// Returns:
+//   - Policy: The combined policy.
+func Wrap(policies ...Policy) Policy {
+	var ps []Policy
+	for _, p := range policies {
+		if p != nil {
+			ps = append(ps, p)
+		}
+	}
+	switch len(ps) {
+	case 0:
+		return NoPolicy
+	case 1:
+		return ps[0]
+	}
+
+	return PolicyFunc(func(ctx context.Context, fn Func) error {
+		next := fn
+		for i := len(ps) - 1; i > 0; i-- {
+			p, inner := ps[i], next
+			next = func(ctx context.Context) error { return p.Execute(ctx, inner) }
+		}
+		return ps[0].Execute(ctx, next)
+	})
+}
+
+// Do runs a call returning a result under a policy.
+//
+// The result of the first successful attempt is returned, so that the
+// result of a hedged attempt that lost the race is discarded.
+//
+// Parameters:
+//   - ctx: The context of the call.
+//   - p: The policy.
+//   - fn: The call.
+//
+// Returns:
+//   - T: The result of the successful attempt, the zero value on error.
+//   - error: The error of the call or of the policy.
+func Do[T any](ctx context.Context, p Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var (
+		mu     sync.Mutex
+		result T
+		done   bool
+	)
+	err := p.Execute(ctx, func(ctx context.Context) error {
+		v, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !done {
+			result, done = v, true
+		}
+		return nil
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil || !done {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// writeKey is the context key marking the non idempotent calls.
+type writeKey struct{}
+
+// DoWrite runs a non idempotent call returning a result under a policy,
+// e.g. an INSERT, see Do. The call is attempted once: it is not hedged, and
+// not retried unless the retry policy enables RetryConfig.RetryWrites.
+//
+// Parameters:
+//   - ctx: The context of the call.
+//   - p: The policy.
+//   - fn: The call.
+//
+// Returns:
+//   - T: The result of the call, the zero value on error.
+//   - error: The error of the call or of the policy.
+func DoWrite[T any](ctx context.Context, p Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	return Do(context.WithValue(ctx, writeKey{}, true), p, fn)
+}
+
+// IsWrite reports whether a call is a non idempotent call run by DoWrite.
+func IsWrite(ctx context.Context) bool {
+	write, _ := ctx.Value(writeKey{}).(bool)
+	return write
+}
+
+// permanentError marks an error that must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that must not be retried, e.g. a validation
+// error. The error is still matched by errors.Is and errors.As.
+//
+// Parameters:
+//   - err: The error.
+//
+// Returns:
+//   - error: The wrapped error, nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether an error was marked by Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
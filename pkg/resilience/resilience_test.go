@@ -0,0 +1,220 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TestWrapOrder tests that the first policy of Wrap is the outermost.
+func TestWrapOrder(t *testing.T) {
+	var calls []string
+	policy := func(name string) Policy {
+		return PolicyFunc(func(ctx context.Context, fn Func) error {
+			calls = append(calls, name)
+			return fn(ctx)
+		})
+	}
+
+	p := Wrap(policy("outer"), nil, policy("inner"))
+	if err := p.Execute(context.Background(), func(context.Context) error {
+		calls = append(calls, "call")
+		return nil
+	}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := strings.Join(calls, ","); got != "outer,inner,call" {
+		t.Errorf("calls = %s, want outer,inner,call", got)
+	}
+}
+
+// TestBulkhead tests that the calls beyond the concurrency limit wait in
+// the queue, and are rejected once the queue is full or timed out.
+func TestBulkhead(t *testing.T) {
+	b := NewBulkhead("test-bulkhead", BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 50 * time.Millisecond})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = b.Execute(context.Background(), func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// The queued call times out
+	if err := b.Execute(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, ErrBulkheadFull) {
+		t.Errorf("Execute() error = %v, want ErrBulkheadFull after the queue timeout", err)
+	}
+
+	// The queued call runs once the slot is released
+	done := make(chan error)
+	go func() { done <- b.Execute(context.Background(), func(context.Context) error { return nil }) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// The queue is full
+	if err := b.Execute(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, ErrBulkheadFull) {
+		t.Errorf("Execute() error = %v, want ErrBulkheadFull with a full queue", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Execute() error = %v for the queued call, want nil", err)
+	}
+	if n := b.InFlight(); n != 0 {
+		t.Errorf("InFlight() = %d, want 0", n)
+	}
+}
+
+// TestRetry tests that the failed calls are retried up to the maximum
+// attempts, and that permanent errors are not retried.
+func TestRetry(t *testing.T) {
+	r := NewRetry("test-retry", RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, Jitter: 0.5})
+
+	var attempts int
+	err := r.Execute(context.Background(), func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("failure")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Execute() = %v after %d attempts, want nil after 3", err, attempts)
+	}
+
+	attempts = 0
+	failure := errors.New("failure")
+	if err := r.Execute(context.Background(), func(context.Context) error {
+		attempts++
+		return failure
+	}); !errors.Is(err, failure) || attempts != 3 {
+		t.Errorf("Execute() = %v after %d attempts, want the failure after 3", err, attempts)
+	}
+
+	attempts = 0
+	if err := r.Execute(context.Background(), func(context.Context) error {
+		attempts++
+		return Permanent(failure)
+	}); !errors.Is(err, failure) || attempts != 1 {
+		t.Errorf("Execute() = %v after %d attempts, want the permanent failure after 1", err, attempts)
+	}
+}
+
+// TestRetryNotFoundAndWrites tests that the not found results are not
+// retried, and that the writes are attempted once unless RetryWrites is set.
+func TestRetryNotFoundAndWrites(t *testing.T) {
+	r := NewRetry("test-retry-writes", RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+	var attempts int
+	if err := r.Execute(context.Background(), func(context.Context) error {
+		attempts++
+		return fmt.Errorf("find user: %w", gorm.ErrRecordNotFound)
+	}); !errors.Is(err, gorm.ErrRecordNotFound) || attempts != 1 {
+		t.Errorf("Execute() = %v after %d attempts, want the not found result after 1", err, attempts)
+	}
+
+	failure := errors.New("failure")
+	write := func(ctx context.Context) (int, error) {
+		attempts++
+		return 0, failure
+	}
+
+	attempts = 0
+	if _, err := DoWrite(context.Background(), r, write); !errors.Is(err, failure) || attempts != 1 {
+		t.Errorf("DoWrite() = %v after %d attempts, want the failure after 1", err, attempts)
+	}
+
+	attempts = 0
+	r = NewRetry("test-retry-writes", RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryWrites: true})
+	if _, err := DoWrite(context.Background(), r, write); !errors.Is(err, failure) || attempts != 3 {
+		t.Errorf("DoWrite() = %v after %d attempts, want the failure after 3 with RetryWrites", err, attempts)
+	}
+}
+
+// TestRetryDeadline tests that no retry is made when the deadline of the
+// context would expire during the backoff.
+func TestRetryDeadline(t *testing.T) {
+	r := NewRetry("test-retry-deadline", RetryConfig{MaxAttempts: 5, InitialBackoff: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var attempts int
+	start := time.Now()
+	_ = r.Execute(ctx, func(context.Context) error {
+		attempts++
+		return errors.New("failure")
+	})
+	if attempts != 1 || time.Since(start) > 50*time.Millisecond {
+		t.Errorf("%d attempts in %v, want 1 without waiting", attempts, time.Since(start))
+	}
+}
+
+// TestHedge tests that a slow call is hedged and that the first successful
+// attempt wins, the others being cancelled.
+func TestHedge(t *testing.T) {
+	h := NewHedge("test-hedge", HedgeConfig{Delay: 10 * time.Millisecond, MaxHedges: 1})
+
+	var attempts atomic.Int32
+	cancelled := make(chan struct{})
+	v, err := Do(context.Background(), h, func(ctx context.Context) (int, error) {
+		n := attempts.Add(1)
+		if n == 1 {
+			// The first attempt hangs until it is cancelled
+			<-ctx.Done()
+			close(cancelled)
+			return 0, ctx.Err()
+		}
+		return int(n), nil
+	})
+	if err != nil || v != 2 {
+		t.Errorf("Do() = %d, %v, want the result of the hedged attempt", v, err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("the losing attempt was not cancelled")
+	}
+
+	// The writes are not hedged, a duplicate write not being harmless
+	attempts.Store(0)
+	if _, err := DoWrite(context.Background(), h, func(context.Context) (int, error) {
+		attempts.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		return 0, nil
+	}); err != nil || attempts.Load() != 1 {
+		t.Errorf("DoWrite() = %v after %d attempts, want 1 attempt", err, attempts.Load())
+	}
+}
+
+// TestFallback tests that the fallback replaces the error of a failed call.
+func TestFallback(t *testing.T) {
+	failure := errors.New("failure")
+
+	f := NewFallback("test-fallback", func(_ context.Context, err error) error {
+		if !errors.Is(err, failure) {
+			t.Errorf("fallback error = %v, want the failure of the call", err)
+		}
+		return nil
+	})
+	if err := f.Execute(context.Background(), func(context.Context) error { return failure }); err != nil {
+		t.Errorf("Execute() error = %v, want nil", err)
+	}
+
+	v, err := DoWithFallback(context.Background(), "test-fallback", NoPolicy,
+		func(context.Context) (string, error) { return "", failure },
+		func(context.Context, error) (string, error) { return "cached", nil })
+	if err != nil || v != "cached" {
+		t.Errorf("DoWithFallback() = %q, %v, want the fallback value", v, err)
+	}
+}
@@ -0,0 +1,146 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/pkg/circuitbreaker"
+)
+
+// Default retry configuration.
+const (
+	DefaultMaxAttempts    = 3
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+	DefaultMultiplier     = 2
+)
+
+// RetryConfig is the configuration of a retry policy.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of attempts, the first one included,
+	// DefaultMaxAttempts if zero.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry,
+	// DefaultInitialBackoff if zero.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts, DefaultMaxBackoff if
+	// zero.
+	MaxBackoff time.Duration
+	// Multiplier is the growth of the wait after every retry,
+	// DefaultMultiplier if zero.
+	Multiplier float64
+	// Jitter is the fraction of the wait that is randomized, between 0 and
+	// 1, so that the clients of a failed dependency do not retry in lockstep.
+	// Zero disables the jitter.
+	Jitter float64
+	// RetryIf reports whether an error is retried, Retryable if nil.
+	RetryIf func(err error) bool
+	// RetryWrites enables the retries of the non idempotent calls run by
+	// DoWrite, which are otherwise attempted once so that a write applied
+	// before its response was lost is not applied twice.
+	RetryWrites bool
+}
+
+// Retryable is the default RetryIf of the retry policies. Every error is
+// retried except the errors marked by Permanent, the not found results of
+// circuitbreaker.IsNotFound, the context errors and the rejections of an
+// open circuit breaker.
+func Retryable(err error) bool {
+	return !IsPermanent(err) &&
+		!circuitbreaker.IsNotFound(err) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, circuitbreaker.ErrOpenState) &&
+		!errors.Is(err, circuitbreaker.ErrTooManyRequests)
+}
+
+// Retry retries the failed calls with an exponential backoff.
+//
+// The policy honours the deadline of the context: no retry is made if the
+// deadline would expire during the wait, the last error being returned
+// right away.
+type Retry struct {
+	name string
+	cfg  RetryConfig
+}
+
+// NewRetry creates a retry policy.
+//
+// Parameters:
+//   - name: The name of the policy, usually the name of the dependency.
+//   - cfg: The configuration of the policy.
+//
+// Returns:
+//   - *Retry: The retry policy.
+func NewRetry(name string, cfg RetryConfig) *Retry {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.Multiplier < 1 {
+		cfg.Multiplier = DefaultMultiplier
+	}
+	cfg.Jitter = min(max(cfg.Jitter, 0), 1)
+	if cfg.RetryIf == nil {
+		cfg.RetryIf = Retryable
+	}
+	return &Retry{name: name, cfg: cfg}
+}
+
+// Execute implements Policy. The error of the last attempt is returned.
+// The writes run by DoWrite are attempted once unless RetryWrites is set.
+func (r *Retry) Execute(ctx context.Context, fn Func) error {
+	if IsWrite(ctx) && !r.cfg.RetryWrites {
+		return fn(ctx)
+	}
+
+	backoff := r.cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			retryCalls.WithLabelValues(r.name, ResultSuccess).Inc()
+			return nil
+		}
+		if ctx.Err() != nil || !r.cfg.RetryIf(err) {
+			retryCalls.WithLabelValues(r.name, ResultAborted).Inc()
+			return err
+		}
+		if attempt >= r.cfg.MaxAttempts {
+			retryCalls.WithLabelValues(r.name, ResultExhausted).Inc()
+			return err
+		}
+
+		wait := r.jitter(backoff)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			retryCalls.WithLabelValues(r.name, ResultExhausted).Inc()
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			retryCalls.WithLabelValues(r.name, ResultAborted).Inc()
+			return err
+		}
+
+		retryAttempts.WithLabelValues(r.name).Inc()
+		backoff = min(time.Duration(float64(backoff)*r.cfg.Multiplier), r.cfg.MaxBackoff)
+	}
+}
+
+// jitter randomizes the configured fraction of a wait.
+func (r *Retry) jitter(d time.Duration) time.Duration {
+	if r.cfg.Jitter == 0 {
+		return d
+	}
+	return d - time.Duration(r.cfg.Jitter*rand.Float64()*float64(d))
+}
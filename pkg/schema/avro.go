@@ -0,0 +1,822 @@
+package schema
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// avroSchema returns the Avro schema of a message type: a record named by
+// the full name of the type, whose fields are the protobuf fields in order.
+//
+// The 32 bits integers are ints, the other integers are longs, the message
+// fields and the fields with presence are unions with null, the repeated
+// fields are arrays and the maps are maps with string keys.
+func avroSchema(md protoreflect.MessageDescriptor) string {
+	data, _ := json.Marshal(avroRecord(md, make(map[protoreflect.FullName]bool)))
+	return string(data)
+}
+
+// avroRecord returns the Avro schema of a message type, its name if it is
+// already defined.
+func avroRecord(md protoreflect.MessageDescriptor, defined map[protoreflect.FullName]bool) any {
+	if defined[md.FullName()] {
+		return string(md.FullName())
+	}
+	defined[md.FullName()] = true
+
+	fields := make([]map[string]any, 0, md.Fields().Len())
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		field := map[string]any{"name": string(fd.Name())}
+		switch {
+		case fd.IsMap():
+			field["type"] = map[string]any{"type": "map", "values": avroValueType(fd.MapValue(), defined)}
+			field["default"] = map[string]any{}
+		case fd.IsList():
+			field["type"] = map[string]any{"type": "array", "items": avroValueType(fd, defined)}
+			field["default"] = []any{}
+		case fd.HasPresence():
+			field["type"] = []any{"null", avroValueType(fd, defined)}
+			field["default"] = nil
+		default:
+			field["type"] = avroValueType(fd, defined)
+			field["default"] = avroDefault(fd)
+		}
+		fields = append(fields, field)
+	}
+
+	record := map[string]any{"type": "record", "name": string(md.Name()), "fields": fields}
+	if ns := parentNameOf(string(md.FullName())); ns != "" {
+		record["namespace"] = ns
+	}
+	return record
+}
+
+// avroValueType returns the Avro type of a value of a field.
+func avroValueType(fd protoreflect.FieldDescriptor, defined map[protoreflect.FullName]bool) any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return "boolean"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "int"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "long"
+	case protoreflect.FloatKind:
+		return "float"
+	case protoreflect.DoubleKind:
+		return "double"
+	case protoreflect.StringKind:
+		return "string"
+	case protoreflect.BytesKind:
+		return "bytes"
+	case protoreflect.EnumKind:
+		ed := fd.Enum()
+		if defined[ed.FullName()] {
+			return string(ed.FullName())
+		}
+		defined[ed.FullName()] = true
+		symbols := make([]string, ed.Values().Len())
+		for i := range symbols {
+			symbols[i] = string(ed.Values().Get(i).Name())
+		}
+		enum := map[string]any{"type": "enum", "name": string(ed.Name()), "symbols": symbols, "default": symbols[0]}
+		if ns := parentNameOf(string(ed.FullName())); ns != "" {
+			enum["namespace"] = ns
+		}
+		return enum
+	}
+	return avroRecord(fd.Message(), defined)
+}
+
+// avroDefault returns the default of a field without presence, the zero
+// value of its type.
+func avroDefault(fd protoreflect.FieldDescriptor) any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return false
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return ""
+	case protoreflect.EnumKind:
+		return string(fd.Enum().Values().Get(0).Name())
+	}
+	return 0
+}
+
+// avroMarshal encodes a message in the Avro binary encoding of the schema of
+// its type, see avroSchema.
+func avroMarshal(m protoreflect.Message) ([]byte, error) {
+	return appendAvroRecord(nil, m)
+}
+
+// appendAvroRecord appends the encoding of a message.
+func appendAvroRecord(out []byte, m protoreflect.Message) ([]byte, error) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		var err error
+		switch {
+		case fd.IsMap():
+			mv := m.Get(fd).Map()
+			keys := make([]protoreflect.MapKey, 0, mv.Len())
+			mv.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, k)
+				return true
+			})
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			if len(keys) > 0 {
+				out = binary.AppendVarint(out, int64(len(keys)))
+			}
+			for _, k := range keys {
+				out = appendAvroString(out, k.String())
+				if out, err = appendAvroValue(out, fd.MapValue(), mv.Get(k)); err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, 0)
+		case fd.IsList():
+			list := m.Get(fd).List()
+			if list.Len() > 0 {
+				out = binary.AppendVarint(out, int64(list.Len()))
+			}
+			for j := 0; j < list.Len(); j++ {
+				if out, err = appendAvroValue(out, fd, list.Get(j)); err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, 0)
+		case fd.HasPresence():
+			if !m.Has(fd) {
+				out = binary.AppendVarint(out, 0)
+				continue
+			}
+			out = binary.AppendVarint(out, 1)
+			if out, err = appendAvroValue(out, fd, m.Get(fd)); err != nil {
+				return nil, err
+			}
+		default:
+			if out, err = appendAvroValue(out, fd, m.Get(fd)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// appendAvroValue appends the encoding of a value of a field.
+func appendAvroValue(out []byte, fd protoreflect.FieldDescriptor, v protoreflect.Value) ([]byte, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if v.Bool() {
+			return append(out, 1), nil
+		}
+		return append(out, 0), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return binary.AppendVarint(out, v.Int()), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return binary.AppendVarint(out, int64(v.Uint())), nil
+	case protoreflect.FloatKind:
+		return binary.LittleEndian.AppendUint32(out, math.Float32bits(float32(v.Float()))), nil
+	case protoreflect.DoubleKind:
+		return binary.LittleEndian.AppendUint64(out, math.Float64bits(v.Float())), nil
+	case protoreflect.StringKind:
+		return appendAvroString(out, v.String()), nil
+	case protoreflect.BytesKind:
+		out = binary.AppendVarint(out, int64(len(v.Bytes())))
+		return append(out, v.Bytes()...), nil
+	case protoreflect.EnumKind:
+		value := fd.Enum().Values().ByNumber(v.Enum())
+		if value == nil {
+			return nil, fmt.Errorf("enum value %d of field %s has no avro symbol", v.Enum(), fd.FullName())
+		}
+		return binary.AppendVarint(out, int64(value.Index())), nil
+	}
+	return appendAvroRecord(out, v.Message())
+}
+
+// appendAvroString appends the encoding of a string.
+func appendAvroString(out []byte, s string) []byte {
+	out = binary.AppendVarint(out, int64(len(s)))
+	return append(out, s...)
+}
+
+// avroType is a parsed Avro schema.
+type avroType struct {
+	kind     string // primitive type, record, enum, array, map, fixed or union
+	name     string // full name of a named type
+	fields   []avroField
+	symbols  []string
+	enumDef  string // default symbol of an enum, if any
+	items    *avroType
+	values   *avroType
+	branches []*avroType
+	size     int
+}
+
+// avroField is a field of a record.
+type avroField struct {
+	name       string
+	typ        *avroType
+	hasDefault bool
+}
+
+// avroPrimitives are the primitive types of Avro.
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// parseAvro parses an Avro schema.
+func parseAvro(definition string) (*avroType, error) {
+	var raw any
+	if err := json.Unmarshal([]byte(definition), &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return parseAvroType(raw, "", make(map[string]*avroType))
+}
+
+// parseAvroType parses the Avro type of a JSON value, in a namespace, with
+// the named types already defined.
+func parseAvroType(raw any, namespace string, named map[string]*avroType) (*avroType, error) {
+	switch v := raw.(type) {
+	case string:
+		if avroPrimitives[v] {
+			return &avroType{kind: v}, nil
+		}
+		if t := named[v]; t != nil {
+			return t, nil
+		}
+		if t := named[qualify(namespace, v)]; t != nil {
+			return t, nil
+		}
+		return nil, fmt.Errorf("%w: unknown avro type %q", ErrInvalidSchema, v)
+	case []any:
+		union := &avroType{kind: "union"}
+		for _, branch := range v {
+			t, err := parseAvroType(branch, namespace, named)
+			if err != nil {
+				return nil, err
+			}
+			union.branches = append(union.branches, t)
+		}
+		return union, nil
+	case map[string]any:
+		return parseAvroComplex(v, namespace, named)
+	}
+	return nil, fmt.Errorf("%w: invalid avro type %v", ErrInvalidSchema, raw)
+}
+
+// parseAvroComplex parses an Avro type defined by a JSON object.
+func parseAvroComplex(v map[string]any, namespace string, named map[string]*avroType) (*avroType, error) {
+	kind, _ := v["type"].(string)
+	t := &avroType{kind: kind}
+
+	switch kind {
+	case "record", "error", "enum", "fixed":
+		name, _ := v["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("%w: avro %s without name", ErrInvalidSchema, kind)
+		}
+		if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		if strings.Contains(name, ".") {
+			t.name = name
+			namespace = parentNameOf(name)
+		} else {
+			t.name = qualify(namespace, name)
+		}
+		named[t.name] = t
+	}
+
+	switch kind {
+	case "record", "error":
+		t.kind = "record"
+		fields, _ := v["fields"].([]any)
+		for _, f := range fields {
+			fm, ok := f.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: invalid field of avro record %s", ErrInvalidSchema, t.name)
+			}
+			name, _ := fm["name"].(string)
+			ft, err := parseAvroType(fm["type"], namespace, named)
+			if err != nil {
+				return nil, err
+			}
+			_, hasDefault := fm["default"]
+			t.fields = append(t.fields, avroField{name: name, typ: ft, hasDefault: hasDefault})
+		}
+	case "enum":
+		symbols, _ := v["symbols"].([]any)
+		for _, s := range symbols {
+			symbol, _ := s.(string)
+			t.symbols = append(t.symbols, symbol)
+		}
+		t.enumDef, _ = v["default"].(string)
+	case "fixed":
+		size, _ := v["size"].(float64)
+		t.size = int(size)
+	case "array":
+		items, err := parseAvroType(v["items"], namespace, named)
+		if err != nil {
+			return nil, err
+		}
+		t.items = items
+	case "map":
+		values, err := parseAvroType(v["values"], namespace, named)
+		if err != nil {
+			return nil, err
+		}
+		t.values = values
+	default:
+		// A primitive type as an object, e.g. with a logical type
+		if !avroPrimitives[kind] {
+			return nil, fmt.Errorf("%w: unknown avro type %q", ErrInvalidSchema, kind)
+		}
+	}
+	return t, nil
+}
+
+// parentNameOf returns the namespace of a full name, the full name of its
+// parent.
+func parentNameOf(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// errAvroShort is returned when an Avro payload ends before its schema.
+var errAvroShort = errors.New("avro payload is truncated")
+
+// avroReader reads the Avro binary encoding.
+type avroReader struct {
+	data []byte
+}
+
+func (r *avroReader) long() (int64, error) {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		return 0, errAvroShort
+	}
+	r.data = r.data[n:]
+	return v, nil
+}
+
+func (r *avroReader) bytes(n int64) ([]byte, error) {
+	if n < 0 || n > int64(len(r.data)) {
+		return nil, errAvroShort
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b, nil
+}
+
+// block returns the number of items of the next block of an array or a map.
+func (r *avroReader) block() (int64, error) {
+	n, err := r.long()
+	if err != nil || n >= 0 {
+		return n, err
+	}
+	// A negative count is followed by the size of the block
+	if _, err := r.long(); err != nil {
+		return 0, err
+	}
+	return -n, nil
+}
+
+// avroUnmarshal decodes a payload written with an Avro schema into a
+// message. The fields of the writer are matched with the fields of the
+// message by name: the fields unknown to the message are skipped, and the
+// fields unknown to the writer keep their zero value.
+func avroUnmarshal(writer *avroType, data []byte, m protoreflect.Message) error {
+	if writer.kind != "record" {
+		return fmt.Errorf("avro schema of %s is a %s, not a record", m.Descriptor().FullName(), writer.kind)
+	}
+	r := &avroReader{data: data}
+	return r.record(writer, m)
+}
+
+// record decodes a record into a message.
+func (r *avroReader) record(t *avroType, m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+	for _, f := range t.fields {
+		fd := fields.ByName(protoreflect.Name(f.name))
+		if fd == nil {
+			if err := r.skip(f.typ); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.field(f.typ, m, fd); err != nil {
+			return fmt.Errorf("failed to decode field %s: %w", fd.FullName(), err)
+		}
+	}
+	return nil
+}
+
+// field decodes the value of a field.
+func (r *avroReader) field(t *avroType, m protoreflect.Message, fd protoreflect.FieldDescriptor) error {
+	if t.kind == "union" {
+		i, err := r.long()
+		if err != nil {
+			return err
+		}
+		if i < 0 || i >= int64(len(t.branches)) {
+			return fmt.Errorf("invalid avro union branch %d", i)
+		}
+		if t = t.branches[i]; t.kind == "null" {
+			m.Clear(fd)
+			return nil
+		}
+	}
+
+	switch {
+	case fd.IsMap():
+		if t.kind != "map" {
+			return fmt.Errorf("cannot decode an avro %s into a map", t.kind)
+		}
+		mv := m.Mutable(fd).Map()
+		return r.blocks(func() error {
+			s, err := r.string()
+			if err != nil {
+				return err
+			}
+			key, err := mapKey(fd.MapKey(), s)
+			if err != nil {
+				return err
+			}
+			v, err := r.value(t.values, fd.MapValue(), mv.NewValue)
+			if err != nil {
+				return err
+			}
+			mv.Set(key, v)
+			return nil
+		})
+	case fd.IsList():
+		if t.kind != "array" {
+			return fmt.Errorf("cannot decode an avro %s into a list", t.kind)
+		}
+		list := m.Mutable(fd).List()
+		return r.blocks(func() error {
+			v, err := r.value(t.items, fd, list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+			return nil
+		})
+	}
+
+	v, err := r.value(t, fd, func() protoreflect.Value { return m.NewField(fd) })
+	if err != nil {
+		return err
+	}
+	m.Set(fd, v)
+	return nil
+}
+
+// blocks decodes the items of an array or a map.
+func (r *avroReader) blocks(item func() error) error {
+	for {
+		n, err := r.block()
+		if err != nil || n == 0 {
+			return err
+		}
+		for ; n > 0; n-- {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// value decodes a value of a field, with the Avro type promotions.
+func (r *avroReader) value(t *avroType, fd protoreflect.FieldDescriptor, newMessage func() protoreflect.Value) (protoreflect.Value, error) {
+	if t.kind == "union" {
+		i, err := r.long()
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if i < 0 || i >= int64(len(t.branches)) || t.branches[i].kind == "null" {
+			return protoreflect.Value{}, fmt.Errorf("invalid avro union branch %d for a value", i)
+		}
+		t = t.branches[i]
+	}
+
+	mismatch := fmt.Errorf("cannot decode an avro %s into a %s", t.kind, fd.Kind())
+	switch t.kind {
+	case "boolean":
+		b, err := r.bytes(1)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if fd.Kind() != protoreflect.BoolKind {
+			return protoreflect.Value{}, mismatch
+		}
+		return protoreflect.ValueOfBool(b[0] != 0), nil
+	case "int", "long":
+		n, err := r.long()
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return numberValue(fd, float64(n), n, true, mismatch)
+	case "float":
+		b, err := r.bytes(4)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return numberValue(fd, float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 0, false, mismatch)
+	case "double":
+		b, err := r.bytes(8)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return numberValue(fd, math.Float64frombits(binary.LittleEndian.Uint64(b)), 0, false, mismatch)
+	case "string", "bytes", "fixed":
+		size := int64(t.size)
+		if t.kind != "fixed" {
+			var err error
+			if size, err = r.long(); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
+		b, err := r.bytes(size)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		switch fd.Kind() {
+		case protoreflect.StringKind:
+			return protoreflect.ValueOfString(string(b)), nil
+		case protoreflect.BytesKind:
+			return protoreflect.ValueOfBytes(append([]byte(nil), b...)), nil
+		}
+		return protoreflect.Value{}, mismatch
+	case "enum":
+		i, err := r.long()
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if fd.Kind() != protoreflect.EnumKind || i < 0 || i >= int64(len(t.symbols)) {
+			return protoreflect.Value{}, mismatch
+		}
+		// A symbol unknown to the reader is its default, the first value
+		value := fd.Enum().Values().ByName(protoreflect.Name(t.symbols[i]))
+		if value == nil {
+			value = fd.Enum().Values().Get(0)
+		}
+		return protoreflect.ValueOfEnum(value.Number()), nil
+	case "record":
+		if fd.Message() == nil {
+			return protoreflect.Value{}, mismatch
+		}
+		v := newMessage()
+		return v, r.record(t, v.Message())
+	}
+	return protoreflect.Value{}, mismatch
+}
+
+// numberValue converts a decoded number to the kind of a field. Integers
+// are decoded into integer and floating point fields, floating point
+// numbers only into floating point fields.
+func numberValue(fd protoreflect.FieldDescriptor, f float64, n int64, isInt bool, mismatch error) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(f)), nil
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(f), nil
+	}
+	if !isInt {
+		return protoreflect.Value{}, mismatch
+	}
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(n)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(n), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(n)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(n)), nil
+	}
+	return protoreflect.Value{}, mismatch
+}
+
+// mapKey parses the string key of an Avro map into a protobuf map key.
+func mapKey(fd protoreflect.FieldDescriptor, s string) (protoreflect.MapKey, error) {
+	var v protoreflect.Value
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(s)
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return protoreflect.MapKey{}, err
+		}
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, err
+		}
+		if fd.Kind() == protoreflect.Uint64Kind || fd.Kind() == protoreflect.Fixed64Kind {
+			v = protoreflect.ValueOfUint64(n)
+		} else {
+			v = protoreflect.ValueOfUint32(uint32(n))
+		}
+	default:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, err
+		}
+		if fd.Kind() == protoreflect.Int64Kind || fd.Kind() == protoreflect.Sint64Kind || fd.Kind() == protoreflect.Sfixed64Kind {
+			v = protoreflect.ValueOfInt64(n)
+		} else {
+			v = protoreflect.ValueOfInt32(int32(n))
+		}
+	}
+	return v.MapKey(), nil
+}
+
+// string reads a string.
+func (r *avroReader) string() (string, error) {
+	n, err := r.long()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(n)
+	return string(b), err
+}
+
+// skip skips a value of a field unknown to the reader.
+func (r *avroReader) skip(t *avroType) error {
+	var err error
+	switch t.kind {
+	case "null":
+	case "boolean":
+		_, err = r.bytes(1)
+	case "int", "long", "enum":
+		_, err = r.long()
+	case "float":
+		_, err = r.bytes(4)
+	case "double":
+		_, err = r.bytes(8)
+	case "string", "bytes":
+		_, err = r.string()
+	case "fixed":
+		_, err = r.bytes(int64(t.size))
+	case "union":
+		var i int64
+		if i, err = r.long(); err == nil {
+			if i < 0 || i >= int64(len(t.branches)) {
+				return fmt.Errorf("invalid avro union branch %d", i)
+			}
+			err = r.skip(t.branches[i])
+		}
+	case "array":
+		err = r.blocks(func() error { return r.skip(t.items) })
+	case "map":
+		err = r.blocks(func() error {
+			if _, err := r.string(); err != nil {
+				return err
+			}
+			return r.skip(t.values)
+		})
+	case "record":
+		for _, f := range t.fields {
+			if err = r.skip(f.typ); err != nil {
+				break
+			}
+		}
+	}
+	return err
+}
+
+// checkAvro returns the incompatibilities of the data written with an Avro
+// schema when it is read with another one, following the schema resolution
+// of the Avro specification.
+func checkAvro(reader, writer *avroType) []string {
+	return avroMatch(reader, writer, "", make(map[[2]*avroType]bool))
+}
+
+// avroMatch returns the incompatibilities of a writer type read as a reader
+// type at a path of the schema.
+func avroMatch(reader, writer *avroType, path string, seen map[[2]*avroType]bool) []string {
+	if writer.kind == "union" {
+		var issues []string
+		for _, branch := range writer.branches {
+			issues = append(issues, avroMatch(reader, branch, path, seen)...)
+		}
+		return issues
+	}
+	if reader.kind == "union" {
+		for _, branch := range reader.branches {
+			if len(avroMatch(branch, writer, path, seen)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: %s is not in the reader union", avroPath(path), writer.kind)}
+	}
+
+	if !avroPromotable(writer.kind, reader.kind) {
+		return []string{fmt.Sprintf("%s: type changes from %s to %s", avroPath(path), writer.kind, reader.kind)}
+	}
+
+	switch reader.kind {
+	case "record", "enum", "fixed":
+		if reader.name != writer.name {
+			return []string{fmt.Sprintf("%s: type name changes from %s to %s", avroPath(path), writer.name, reader.name)}
+		}
+	}
+
+	switch reader.kind {
+	case "record":
+		pair := [2]*avroType{reader, writer}
+		if seen[pair] {
+			return nil
+		}
+		seen[pair] = true
+
+		var issues []string
+		for _, rf := range reader.fields {
+			fieldPath := qualify(path, rf.name)
+			wf := writer.field(rf.name)
+			if wf == nil {
+				if !rf.hasDefault {
+					issues = append(issues, fmt.Sprintf("%s: field without default is added", fieldPath))
+				}
+				continue
+			}
+			issues = append(issues, avroMatch(rf.typ, wf.typ, fieldPath, seen)...)
+		}
+		return issues
+	case "enum":
+		if reader.enumDef != "" {
+			return nil
+		}
+		for _, symbol := range writer.symbols {
+			if !slices.Contains(reader.symbols, symbol) {
+				return []string{fmt.Sprintf("%s: enum symbol %s is removed", avroPath(path), symbol)}
+			}
+		}
+	case "fixed":
+		if reader.size != writer.size {
+			return []string{fmt.Sprintf("%s: fixed size changes from %d to %d", avroPath(path), writer.size, reader.size)}
+		}
+	case "array":
+		return avroMatch(reader.items, writer.items, path+"[]", seen)
+	case "map":
+		return avroMatch(reader.values, writer.values, path+"{}", seen)
+	}
+	return nil
+}
+
+// field returns a field of a record by name, nil if it has none.
+func (t *avroType) field(name string) *avroField {
+	for i := range t.fields {
+		if t.fields[i].name == name {
+			return &t.fields[i]
+		}
+	}
+	return nil
+}
+
+// avroPromotable reports whether a value of the writer type can be read as
+// the reader type.
+func avroPromotable(writer, reader string) bool {
+	if writer == reader {
+		return true
+	}
+	switch writer {
+	case "int":
+		return reader == "long" || reader == "float" || reader == "double"
+	case "long":
+		return reader == "float" || reader == "double"
+	case "float":
+		return reader == "double"
+	case "string":
+		return reader == "bytes"
+	case "bytes":
+		return reader == "string"
+	}
+	return false
+}
+
+// avroPath returns the path of a type in the messages of the
+// incompatibilities.
+func avroPath(path string) string {
+	if path == "" {
+		return "record"
+	}
+	return path
+}
@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Compatibility is the compatibility level of the versions of a subject, as
+// named by the registry.
+type Compatibility string
+
+// Compatibility levels. A backward compatible schema reads the data written
+// with the previous versions, a forward compatible schema writes data read
+// by the previous versions, and a full compatible schema does both. The
+// transitive levels check all the previous versions instead of the latest.
+const (
+	CompatNone               Compatibility = "NONE"
+	CompatBackward           Compatibility = "BACKWARD"
+	CompatBackwardTransitive Compatibility = "BACKWARD_TRANSITIVE"
+	CompatForward            Compatibility = "FORWARD"
+	CompatForwardTransitive  Compatibility = "FORWARD_TRANSITIVE"
+	CompatFull               Compatibility = "FULL"
+	CompatFullTransitive     Compatibility = "FULL_TRANSITIVE"
+)
+
+// ParseCompatibility parses a compatibility level, case insensitively. The
+// empty string is CompatBackward, the default of the registry.
+//
+// Parameters:
+//   - s: The compatibility level.
+//
+// Returns:
+//   - Compatibility: The parsed compatibility level.
+//   - error: An error if s is not a compatibility level.
+func ParseCompatibility(s string) (Compatibility, error) {
+	switch c := Compatibility(strings.ToUpper(strings.TrimSpace(s))); c {
+	case "":
+		return CompatBackward, nil
+	case CompatNone, CompatBackward, CompatBackwardTransitive, CompatForward,
+		CompatForwardTransitive, CompatFull, CompatFullTransitive:
+		return c, nil
+	}
+	return "", fmt.Errorf("invalid schema compatibility %q", s)
+}
+
+// parsedSchema is a parsed schema of any format.
+type parsedSchema struct {
+	format Format
+	proto  *protoFile
+	avro   *avroType
+	json   *jsonNode
+}
+
+// parseSchema parses a schema according to its format.
+func parseSchema(s Schema) (*parsedSchema, error) {
+	p := &parsedSchema{format: s.format()}
+	var err error
+	switch p.format {
+	case FormatProtobuf:
+		p.proto, err = parseProto(s.Definition)
+	case FormatAvro:
+		p.avro, err = parseAvro(s.Definition)
+	case FormatJSON:
+		p.json, err = parseJSONSchema(s.Definition)
+	default:
+		err = fmt.Errorf("%w: unsupported schema type %s", ErrInvalidSchema, s.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// check returns the incompatibilities of the data written with a schema
+// when it is read with another one.
+func check(reader, writer *parsedSchema) []string {
+	if reader.format != writer.format {
+		return []string{fmt.Sprintf("schema type changes from %s to %s", writer.format, reader.format)}
+	}
+	switch reader.format {
+	case FormatProtobuf:
+		return checkProto(reader.proto, writer.proto)
+	case FormatAvro:
+		return checkAvro(reader.avro, writer.avro)
+	}
+	return checkJSON(reader.json, writer.json)
+}
+
+// CheckCompatibility checks that a schema is compatible with the previous
+// versions of a subject according to a compatibility level.
+//
+// Parameters:
+//   - level: The compatibility level.
+//   - schema: The new schema.
+//   - previous: The previous versions, the latest last.
+//
+// Returns:
+//   - error: An error wrapping ErrIncompatible with the incompatibilities,
+//     or wrapping ErrInvalidSchema if a schema cannot be parsed.
+func CheckCompatibility(level Compatibility, schema Schema, previous []Schema) error {
+	parsed, err := parseSchema(schema)
+	if err != nil {
+		return err
+	}
+	if level == CompatNone || len(previous) == 0 {
+		return nil
+	}
+	if !strings.HasSuffix(string(level), "_TRANSITIVE") {
+		previous = previous[len(previous)-1:]
+	}
+	level = Compatibility(strings.TrimSuffix(string(level), "_TRANSITIVE"))
+
+	var issues []string
+	for i := len(previous) - 1; i >= 0; i-- {
+		old, err := parseSchema(previous[i])
+		if err != nil {
+			return err
+		}
+		if level == CompatBackward || level == CompatFull {
+			issues = append(issues, check(parsed, old)...)
+		}
+		if level == CompatForward || level == CompatFull {
+			issues = append(issues, check(old, parsed)...)
+		}
+		if len(issues) > 0 {
+			break
+		}
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%w (%s): %s", ErrIncompatible, level, strings.Join(dedupe(issues), "; "))
+	}
+	return nil
+}
+
+// dedupe returns the distinct issues in order.
+func dedupe(issues []string) []string {
+	seen := make(map[string]bool, len(issues))
+	out := issues[:0]
+	for _, issue := range issues {
+		if !seen[issue] {
+			seen[issue] = true
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+// sortedMessages returns the message types of a protobuf schema sorted by
+// full name.
+func sortedMessages(f *protoFile) []*protoMessage {
+	messages := make([]*protoMessage, 0, len(f.all))
+	for _, name := range sortedKeys(f.all) {
+		messages = append(messages, f.all[name])
+	}
+	return messages
+}
+
+// sortedNumbers returns the field numbers of a message type in order.
+func sortedNumbers(fields map[int]protoField) []int {
+	numbers := make([]int, 0, len(fields))
+	for number := range fields {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
@@ -0,0 +1,280 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// jsonSchemaDraft is the JSON Schema version of the generated schemas.
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// jsonSchema returns the JSON Schema of the protobuf JSON mapping of a
+// message type, titled by the full name of the type. The nested message
+// types are definitions referenced by full name.
+func jsonSchema(md protoreflect.MessageDescriptor) string {
+	definitions := make(map[string]any)
+	root := jsonObject(md, md, definitions)
+	root["$schema"] = jsonSchemaDraft
+	root["title"] = string(md.FullName())
+	if len(definitions) > 0 {
+		root["definitions"] = definitions
+	}
+	data, _ := json.Marshal(root)
+	return string(data)
+}
+
+// jsonObject returns the JSON Schema of a message type, adding the message
+// types of its fields to the definitions.
+func jsonObject(md, root protoreflect.MessageDescriptor, definitions map[string]any) map[string]any {
+	properties := make(map[string]any, md.Fields().Len())
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		switch {
+		case fd.IsMap():
+			properties[fd.JSONName()] = map[string]any{
+				"type":                 "object",
+				"additionalProperties": jsonValue(fd.MapValue(), root, definitions),
+			}
+		case fd.IsList():
+			properties[fd.JSONName()] = map[string]any{"type": "array", "items": jsonValue(fd, root, definitions)}
+		default:
+			properties[fd.JSONName()] = jsonValue(fd, root, definitions)
+		}
+	}
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// jsonValue returns the JSON Schema of a value of a field.
+func jsonValue(fd protoreflect.FieldDescriptor, root protoreflect.MessageDescriptor, definitions map[string]any) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// The 64 bits integers are strings in the protobuf JSON mapping
+		return map[string]any{"type": []string{"integer", "string"}}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		// NaN and the infinities are strings
+		return map[string]any{"type": []string{"number", "string"}}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.EnumKind:
+		if fd.Enum().FullName() == "google.protobuf.NullValue" {
+			return map[string]any{"type": "null"}
+		}
+		symbols := make([]any, 0, fd.Enum().Values().Len()+1)
+		for i := 0; i < fd.Enum().Values().Len(); i++ {
+			symbols = append(symbols, string(fd.Enum().Values().Get(i).Name()))
+		}
+		return map[string]any{"type": []string{"string", "integer"}, "enum": symbols}
+	}
+
+	md := fd.Message()
+	if wkt := jsonWellKnown(md); wkt != nil {
+		return wkt
+	}
+	if md.FullName() == root.FullName() {
+		return map[string]any{"$ref": "#"}
+	}
+	name := string(md.FullName())
+	if _, ok := definitions[name]; !ok {
+		definitions[name] = nil // defined before its fields, which may reference it
+		definitions[name] = jsonObject(md, root, definitions)
+	}
+	return map[string]any{"$ref": "#/definitions/" + name}
+}
+
+// jsonWellKnown returns the JSON Schema of a well known type having a
+// special protobuf JSON mapping, nil for the other types.
+func jsonWellKnown(md protoreflect.MessageDescriptor) map[string]any {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp", "google.protobuf.Duration", "google.protobuf.FieldMask":
+		return map[string]any{"type": "string"}
+	case "google.protobuf.Struct":
+		return map[string]any{"type": "object"}
+	case "google.protobuf.ListValue":
+		return map[string]any{"type": "array"}
+	case "google.protobuf.Value", "google.protobuf.Any":
+		return map[string]any{}
+	case "google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value", "google.protobuf.Int64Value",
+		"google.protobuf.UInt64Value", "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return jsonValue(md.Fields().ByName("value"), md, nil)
+	}
+	return nil
+}
+
+// jsonNode is a parsed JSON Schema, with what the compatibility checks
+// need.
+type jsonNode struct {
+	title       string
+	types       []string // sorted, empty for any type
+	ref         string
+	properties  map[string]*jsonNode
+	required    []string
+	closed      bool // no additional property is allowed
+	items       *jsonNode
+	additional  *jsonNode // schema of the additional properties
+	definitions map[string]*jsonNode
+}
+
+// parseJSONSchema parses a JSON Schema.
+func parseJSONSchema(definition string) (*jsonNode, error) {
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(definition), &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	root := parseJSONNode(raw)
+	root.definitions = make(map[string]*jsonNode)
+	for _, key := range []string{"definitions", "$defs"} {
+		defs, _ := raw[key].(map[string]any)
+		for name, def := range defs {
+			if m, ok := def.(map[string]any); ok {
+				root.definitions["#/"+key+"/"+name] = parseJSONNode(m)
+			}
+		}
+	}
+	return root, nil
+}
+
+// parseJSONNode parses a schema of a JSON Schema.
+func parseJSONNode(raw map[string]any) *jsonNode {
+	n := &jsonNode{}
+	n.title, _ = raw["title"].(string)
+	n.ref, _ = raw["$ref"].(string)
+
+	switch t := raw["type"].(type) {
+	case string:
+		n.types = []string{t}
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				n.types = append(n.types, s)
+			}
+		}
+	}
+	sort.Strings(n.types)
+
+	if props, ok := raw["properties"].(map[string]any); ok {
+		n.properties = make(map[string]*jsonNode, len(props))
+		for name, prop := range props {
+			if m, ok := prop.(map[string]any); ok {
+				n.properties[name] = parseJSONNode(m)
+			}
+		}
+	}
+	if required, ok := raw["required"].([]any); ok {
+		for _, v := range required {
+			if s, ok := v.(string); ok {
+				n.required = append(n.required, s)
+			}
+		}
+	}
+	switch additional := raw["additionalProperties"].(type) {
+	case bool:
+		n.closed = !additional
+	case map[string]any:
+		n.additional = parseJSONNode(additional)
+	}
+	if items, ok := raw["items"].(map[string]any); ok {
+		n.items = parseJSONNode(items)
+	}
+	return n
+}
+
+// checkJSON returns the incompatibilities of the documents valid for a
+// writer JSON Schema when they are validated with a reader one: the types
+// of the properties must be accepted by the reader, which must neither
+// require a property the writer does not require, nor reject a property of
+// the writer if its content model is closed.
+func checkJSON(reader, writer *jsonNode) []string {
+	return jsonMatch(reader, writer, reader, writer, "", make(map[[2]*jsonNode]bool))
+}
+
+// jsonMatch returns the incompatibilities of a writer schema validated by a
+// reader schema at a path of the document.
+func jsonMatch(reader, writer, readerRoot, writerRoot *jsonNode, path string, seen map[[2]*jsonNode]bool) []string {
+	reader, writer = reader.resolve(readerRoot), writer.resolve(writerRoot)
+	if reader == nil || writer == nil {
+		return nil
+	}
+	pair := [2]*jsonNode{reader, writer}
+	if seen[pair] {
+		return nil
+	}
+	seen[pair] = true
+
+	where := path
+	if where == "" {
+		where = "document"
+	}
+	if len(reader.types) > 0 {
+		if len(writer.types) == 0 {
+			return []string{fmt.Sprintf("%s: type is restricted to %s", where, strings.Join(reader.types, ", "))}
+		}
+		for _, t := range writer.types {
+			if !slices.Contains(reader.types, t) && !(t == "integer" && slices.Contains(reader.types, "number")) {
+				return []string{fmt.Sprintf("%s: type %s is not accepted by %s", where, t, strings.Join(reader.types, ", "))}
+			}
+		}
+	}
+
+	var issues []string
+	for _, name := range reader.required {
+		if !slices.Contains(writer.required, name) {
+			issues = append(issues, fmt.Sprintf("%s: property %s is required", where, name))
+		}
+	}
+	for _, name := range sortedKeys(writer.properties) {
+		propPath := qualify(path, name)
+		rp, ok := reader.properties[name]
+		switch {
+		case ok:
+			issues = append(issues, jsonMatch(rp, writer.properties[name], readerRoot, writerRoot, propPath, seen)...)
+		case reader.additional != nil:
+			issues = append(issues, jsonMatch(reader.additional, writer.properties[name], readerRoot, writerRoot, propPath, seen)...)
+		case reader.closed:
+			issues = append(issues, fmt.Sprintf("%s: property is not allowed", propPath))
+		}
+	}
+	if reader.items != nil && writer.items != nil {
+		issues = append(issues, jsonMatch(reader.items, writer.items, readerRoot, writerRoot, path+"[]", seen)...)
+	}
+	if reader.additional != nil && writer.additional != nil {
+		issues = append(issues, jsonMatch(reader.additional, writer.additional, readerRoot, writerRoot, path+"{}", seen)...)
+	}
+	return issues
+}
+
+// resolve returns the schema referenced by a node, the node itself if it is
+// not a reference, nil if the reference is external.
+func (n *jsonNode) resolve(root *jsonNode) *jsonNode {
+	for i := 0; n != nil && n.ref != "" && i < 32; i++ {
+		if n.ref == "#" {
+			n = root
+			continue
+		}
+		n = root.definitions[n.ref]
+	}
+	return n
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,280 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// MemoryRegistry is an in-process registry, enforcing a compatibility level
+// on the versions of its subjects. It stands in for the registry in tests,
+// directly or served over HTTP with Handler.
+type MemoryRegistry struct {
+	mu       sync.Mutex
+	level    Compatibility
+	schemas  []Schema         // by ID - 1
+	subjects map[string][]int // subject -> IDs by version - 1
+}
+
+// NewMemoryRegistry creates an empty in-process registry.
+//
+// Parameters:
+//   - level: The compatibility level of the subjects.
+//
+// Returns:
+//   - *MemoryRegistry: The registry.
+func NewMemoryRegistry(level Compatibility) *MemoryRegistry {
+	return &MemoryRegistry{level: level, subjects: make(map[string][]int)}
+}
+
+// Register registers a schema under a subject, see Registry.
+func (r *MemoryRegistry) Register(_ context.Context, subject string, schema Schema) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if v, ok := r.version(subject, schema); ok {
+		return r.subjects[subject][v-1], nil
+	}
+	if err := CheckCompatibility(r.level, schema, r.versions(subject)); err != nil {
+		return 0, err
+	}
+
+	// A schema registered under several subjects has a single ID
+	id := 0
+	for i, s := range r.schemas {
+		if s.equal(schema) {
+			id = i + 1
+			break
+		}
+	}
+	if id == 0 {
+		r.schemas = append(r.schemas, schema)
+		id = len(r.schemas)
+	}
+	r.subjects[subject] = append(r.subjects[subject], id)
+	return id, nil
+}
+
+// Lookup returns the version of a subject having a schema, see Registry.
+func (r *MemoryRegistry) Lookup(_ context.Context, subject string, schema Schema) (RegisteredSchema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subjects[subject]; !ok {
+		return RegisteredSchema{}, fmt.Errorf("%w: %s", ErrSubjectNotFound, subject)
+	}
+	v, ok := r.version(subject, schema)
+	if !ok {
+		return RegisteredSchema{}, fmt.Errorf("%w: in subject %s", ErrSchemaNotFound, subject)
+	}
+	return r.registered(subject, v), nil
+}
+
+// SchemaByID returns the schema of an ID, see Registry.
+func (r *MemoryRegistry) SchemaByID(_ context.Context, id int) (Schema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id <= 0 || id > len(r.schemas) {
+		return Schema{}, fmt.Errorf("%w: id %d", ErrSchemaNotFound, id)
+	}
+	return r.schemas[id-1], nil
+}
+
+// Latest returns the latest version of a subject, see Registry.
+func (r *MemoryRegistry) Latest(_ context.Context, subject string) (RegisteredSchema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids, ok := r.subjects[subject]
+	if !ok {
+		return RegisteredSchema{}, fmt.Errorf("%w: %s", ErrSubjectNotFound, subject)
+	}
+	return r.registered(subject, len(ids)), nil
+}
+
+// Compatible reports whether a schema is compatible with the versions of a
+// subject, see Registry.
+func (r *MemoryRegistry) Compatible(_ context.Context, subject string, schema Schema) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := CheckCompatibility(r.level, schema, r.versions(subject))
+	if errors.Is(err, ErrIncompatible) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Subjects returns the sorted subjects of the registry.
+func (r *MemoryRegistry) Subjects() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortedKeys(r.subjects)
+}
+
+// version returns the version of a subject having a schema.
+func (r *MemoryRegistry) version(subject string, schema Schema) (int, bool) {
+	for i, id := range r.subjects[subject] {
+		if r.schemas[id-1].equal(schema) {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// versions returns the schemas of the versions of a subject, the latest
+// last.
+func (r *MemoryRegistry) versions(subject string) []Schema {
+	ids := r.subjects[subject]
+	schemas := make([]Schema, len(ids))
+	for i, id := range ids {
+		schemas[i] = r.schemas[id-1]
+	}
+	return schemas
+}
+
+// registered returns a version of a subject.
+func (r *MemoryRegistry) registered(subject string, version int) RegisteredSchema {
+	id := r.subjects[subject][version-1]
+	return RegisteredSchema{Subject: subject, ID: id, Version: version, Schema: r.schemas[id-1]}
+}
+
+// Handler returns an HTTP handler serving the registry with the subset of
+// the REST API of the registry used by Client, e.g. with httptest.NewServer
+// to test the HTTP client of a service.
+//
+// Returns:
+//   - http.Handler: The handler.
+func (r *MemoryRegistry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /subjects", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r.Subjects())
+	})
+	mux.HandleFunc("POST /subjects/{subject}/versions", func(w http.ResponseWriter, req *http.Request) {
+		schema, ok := readSchema(w, req)
+		if !ok {
+			return
+		}
+		id, err := r.Register(req.Context(), req.PathValue("subject"), schema)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"id": id})
+	})
+	mux.HandleFunc("POST /subjects/{subject}", func(w http.ResponseWriter, req *http.Request) {
+		schema, ok := readSchema(w, req)
+		if !ok {
+			return
+		}
+		registered, err := r.Lookup(req.Context(), req.PathValue("subject"), schema)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, registered)
+	})
+	mux.HandleFunc("GET /subjects/{subject}/versions", func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		ids, ok := r.subjects[req.PathValue("subject")]
+		r.mu.Unlock()
+		if !ok {
+			writeError(w, fmt.Errorf("%w: %s", ErrSubjectNotFound, req.PathValue("subject")))
+			return
+		}
+		versions := make([]int, len(ids))
+		for i := range versions {
+			versions[i] = i + 1
+		}
+		writeJSON(w, http.StatusOK, versions)
+	})
+	mux.HandleFunc("GET /subjects/{subject}/versions/{version}", func(w http.ResponseWriter, req *http.Request) {
+		subject, version := req.PathValue("subject"), req.PathValue("version")
+		registered, err := r.Latest(req.Context(), subject)
+		if err == nil && version != "latest" {
+			r.mu.Lock()
+			v, convErr := strconv.Atoi(version)
+			if convErr != nil || v <= 0 || v > len(r.subjects[subject]) {
+				err = fmt.Errorf("%w: version %s of subject %s", ErrSchemaNotFound, version, subject)
+			} else {
+				registered = r.registered(subject, v)
+			}
+			r.mu.Unlock()
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, registered)
+	})
+	mux.HandleFunc("GET /schemas/ids/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(req.PathValue("id"))
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: id %s", ErrSchemaNotFound, req.PathValue("id")))
+			return
+		}
+		schema, err := r.SchemaByID(req.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, schema)
+	})
+	mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/latest", func(w http.ResponseWriter, req *http.Request) {
+		schema, ok := readSchema(w, req)
+		if !ok {
+			return
+		}
+		if _, err := r.Latest(req.Context(), req.PathValue("subject")); err != nil {
+			writeError(w, err)
+			return
+		}
+		compatible, err := r.Compatible(req.Context(), req.PathValue("subject"), schema)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"is_compatible": compatible})
+	})
+	return mux
+}
+
+// readSchema decodes the schema of a request body, writing the error
+// response if it is invalid.
+func readSchema(w http.ResponseWriter, req *http.Request) (Schema, bool) {
+	var schema Schema
+	if err := json.NewDecoder(req.Body).Decode(&schema); err != nil {
+		writeError(w, fmt.Errorf("%w: %v", ErrInvalidSchema, err))
+		return schema, false
+	}
+	return schema, true
+}
+
+// writeError writes the error response of the registry API for an error.
+func writeError(w http.ResponseWriter, err error) {
+	e := apiError{Code: http.StatusInternalServerError, Message: err.Error()}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrSubjectNotFound):
+		e.Code, status = codeSubjectNotFound, http.StatusNotFound
+	case errors.Is(err, ErrSchemaNotFound):
+		e.Code, status = codeSchemaNotFound, http.StatusNotFound
+	case errors.Is(err, ErrIncompatible):
+		e.Code, status = codeIncompatible, http.StatusConflict
+	case errors.Is(err, ErrInvalidSchema):
+		e.Code, status = codeInvalidSchema, http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, e)
+}
+
+// writeJSON writes a JSON response of the registry API.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,501 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// protoSchema returns the protobuf schema of a message type, the definition
+// of its file, and the indexes of the type in the file. The map entries are
+// not printed and have no index.
+func protoSchema(md protoreflect.MessageDescriptor) (string, []int) {
+	var indexes []int
+	for d := md; d != nil; {
+		var siblings protoreflect.MessageDescriptors
+		parent, _ := d.Parent().(protoreflect.MessageDescriptor)
+		if parent != nil {
+			siblings = parent.Messages()
+		} else {
+			siblings = d.ParentFile().Messages()
+		}
+
+		index := 0
+		for i := 0; i < d.Index(); i++ {
+			if !siblings.Get(i).IsMapEntry() {
+				index++
+			}
+		}
+		indexes = append([]int{index}, indexes...)
+		d = parent
+	}
+	return printProto(md.ParentFile()), indexes
+}
+
+// printProto prints the definition of a protobuf file, without its options
+// and services, as registered in the schema registry.
+func printProto(fd protoreflect.FileDescriptor) string {
+	var b strings.Builder
+	if fd.Syntax() == protoreflect.Proto2 {
+		b.WriteString("syntax = \"proto2\";\n")
+	} else {
+		b.WriteString("syntax = \"proto3\";\n")
+	}
+	if fd.Package() != "" {
+		fmt.Fprintf(&b, "package %s;\n", fd.Package())
+	}
+	if fd.Imports().Len() > 0 {
+		b.WriteString("\n")
+	}
+	for i := 0; i < fd.Imports().Len(); i++ {
+		fmt.Fprintf(&b, "import %q;\n", fd.Imports().Get(i).Path())
+	}
+	for i := 0; i < fd.Enums().Len(); i++ {
+		b.WriteString("\n")
+		printProtoEnum(&b, fd.Enums().Get(i), "")
+	}
+	for i := 0; i < fd.Messages().Len(); i++ {
+		b.WriteString("\n")
+		printProtoMessage(&b, fd.Messages().Get(i), "")
+	}
+	return b.String()
+}
+
+// printProtoMessage prints the definition of a message type.
+func printProtoMessage(b *strings.Builder, md protoreflect.MessageDescriptor, indent string) {
+	fmt.Fprintf(b, "%smessage %s {\n", indent, md.Name())
+	inner := indent + "  "
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			// The fields of a oneof are printed in its block, at its first field
+			if oneof.Fields().Get(0) == fd {
+				fmt.Fprintf(b, "%soneof %s {\n", inner, oneof.Name())
+				for j := 0; j < oneof.Fields().Len(); j++ {
+					printProtoField(b, oneof.Fields().Get(j), inner+"  ", false)
+				}
+				fmt.Fprintf(b, "%s}\n", inner)
+			}
+			continue
+		}
+		printProtoField(b, fd, inner, true)
+	}
+	for i := 0; i < md.Enums().Len(); i++ {
+		printProtoEnum(b, md.Enums().Get(i), inner)
+	}
+	for i := 0; i < md.Messages().Len(); i++ {
+		if nested := md.Messages().Get(i); !nested.IsMapEntry() {
+			printProtoMessage(b, nested, inner)
+		}
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+// printProtoField prints the definition of a field, with its label unless it
+// is in a oneof.
+func printProtoField(b *strings.Builder, fd protoreflect.FieldDescriptor, indent string, label bool) {
+	var typ string
+	switch {
+	case fd.IsMap():
+		typ = fmt.Sprintf("map<%s, %s>", protoTypeName(fd.MapKey()), protoTypeName(fd.MapValue()))
+	case !label:
+		typ = protoTypeName(fd)
+	case fd.IsList():
+		typ = "repeated " + protoTypeName(fd)
+	case fd.Cardinality() == protoreflect.Required:
+		typ = "required " + protoTypeName(fd)
+	case fd.HasOptionalKeyword():
+		typ = "optional " + protoTypeName(fd)
+	default:
+		typ = protoTypeName(fd)
+	}
+	fmt.Fprintf(b, "%s%s %s = %d;\n", indent, typ, fd.Name(), fd.Number())
+}
+
+// protoTypeName returns the type of a field, the fully qualified name of its
+// message or enum type.
+func protoTypeName(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return "." + string(fd.Message().FullName())
+	case protoreflect.EnumKind:
+		return "." + string(fd.Enum().FullName())
+	}
+	return fd.Kind().String()
+}
+
+// printProtoEnum prints the definition of an enum type.
+func printProtoEnum(b *strings.Builder, ed protoreflect.EnumDescriptor, indent string) {
+	fmt.Fprintf(b, "%senum %s {\n", indent, ed.Name())
+	for i := 0; i < ed.Values().Len(); i++ {
+		v := ed.Values().Get(i)
+		fmt.Fprintf(b, "%s  %s = %d;\n", indent, v.Name(), v.Number())
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+// protoFile is a parsed protobuf schema, with what the compatibility checks
+// and the resolution of the message indexes need.
+type protoFile struct {
+	pkg      string
+	messages []*protoMessage // top level messages, in order
+	all      map[string]*protoMessage
+	enums    map[string]bool // full names
+}
+
+// protoMessage is a message type of a protobuf schema.
+type protoMessage struct {
+	name     string // full name
+	fields   map[int]protoField
+	messages []*protoMessage // nested messages, in order
+}
+
+// protoField is a field of a message type.
+type protoField struct {
+	name  string
+	label string // repeated or empty
+	typ   string // type as written, resolved by kind
+	scope string // full name of the message of the field
+}
+
+// messageName returns the full name of the message type of the given
+// indexes.
+func (f *protoFile) messageName(indexes []int) (string, error) {
+	messages := f.messages
+	var m *protoMessage
+	for _, i := range indexes {
+		if i >= len(messages) {
+			return "", fmt.Errorf("invalid protobuf message indexes %v", indexes)
+		}
+		m = messages[i]
+		messages = m.messages
+	}
+	if m == nil {
+		return "", fmt.Errorf("invalid protobuf message indexes %v", indexes)
+	}
+	return m.name, nil
+}
+
+// String returns the field type with its label, e.g. "repeated string".
+func (f protoField) String() string {
+	return strings.TrimSpace(f.label + " " + f.typ)
+}
+
+// kind returns the wire compatible kind of a field: the scalar types
+// encoded alike share a kind, and a message type is its full name.
+func (f *protoFile) kind(field protoField) string {
+	switch field.typ {
+	case "int32", "int64", "uint32", "uint64", "bool":
+		return "varint"
+	case "sint32", "sint64":
+		return "zigzag"
+	case "fixed32", "sfixed32":
+		return "fixed32"
+	case "fixed64", "sfixed64":
+		return "fixed64"
+	case "float", "double":
+		return field.typ
+	case "string", "bytes":
+		return "bytes"
+	}
+	if strings.HasPrefix(field.typ, "map<") {
+		return field.typ
+	}
+
+	name := f.resolve(field.typ, field.scope)
+	if f.enums[name] {
+		return "varint"
+	}
+	return "message " + name
+}
+
+// resolve returns the full name of a type name written in scope, following
+// the protobuf scoping rules for the types of the file.
+func (f *protoFile) resolve(name, scope string) string {
+	if strings.HasPrefix(name, ".") {
+		return name[1:]
+	}
+	for scope != "" {
+		full := scope + "." + name
+		if f.all[full] != nil || f.enums[full] {
+			return full
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+	return name
+}
+
+// parseProto parses a protobuf schema. The options, services and extensions
+// are skipped.
+func parseProto(text string) (*protoFile, error) {
+	p := &protoParser{tokens: tokenizeProto(text)}
+	f := &protoFile{all: make(map[string]*protoMessage), enums: make(map[string]bool)}
+
+	for !p.done() {
+		switch tok := p.next(); tok {
+		case "syntax", "edition", "option", "import":
+			p.skipStatement()
+		case "package":
+			f.pkg = p.next()
+			p.skipStatement()
+		case "message":
+			m, err := p.message(f, f.pkg)
+			if err != nil {
+				return nil, err
+			}
+			f.messages = append(f.messages, m)
+		case "enum":
+			if err := p.enum(f, f.pkg); err != nil {
+				return nil, err
+			}
+		case "service", "extend":
+			p.skipStatement()
+		case ";":
+		default:
+			return nil, fmt.Errorf("%w: unexpected protobuf token %q", ErrInvalidSchema, tok)
+		}
+	}
+	return f, nil
+}
+
+// protoParser is a parser of the tokens of a protobuf schema.
+type protoParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *protoParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *protoParser) next() string {
+	if p.done() {
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+func (p *protoParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// skipStatement skips the tokens up to the end of a statement or of a
+// block, included.
+func (p *protoParser) skipStatement() {
+	depth := 0
+	for !p.done() {
+		switch p.next() {
+		case "{":
+			depth++
+		case "}":
+			if depth--; depth <= 0 {
+				return
+			}
+		case ";":
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// expect consumes a token, or returns an error if it is another one.
+func (p *protoParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		return fmt.Errorf("%w: expected %q in protobuf schema, got %q", ErrInvalidSchema, tok, got)
+	}
+	return nil
+}
+
+// qualify returns the full name of a type declared in scope.
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// message parses a message definition, after the message keyword.
+func (p *protoParser) message(f *protoFile, scope string) (*protoMessage, error) {
+	m := &protoMessage{name: qualify(scope, p.next()), fields: make(map[int]protoField)}
+	f.all[m.name] = m
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for {
+		switch tok := p.peek(); tok {
+		case "":
+			return nil, fmt.Errorf("%w: unterminated protobuf message %s", ErrInvalidSchema, m.name)
+		case "}":
+			p.next()
+			return m, nil
+		case "message":
+			p.next()
+			nested, err := p.message(f, m.name)
+			if err != nil {
+				return nil, err
+			}
+			m.messages = append(m.messages, nested)
+		case "enum":
+			p.next()
+			if err := p.enum(f, m.name); err != nil {
+				return nil, err
+			}
+		case "oneof":
+			p.next()
+			p.next()
+			if err := p.expect("{"); err != nil {
+				return nil, err
+			}
+			for p.peek() != "}" && !p.done() {
+				if p.peek() == "option" {
+					p.skipStatement()
+					continue
+				}
+				if err := p.field(m); err != nil {
+					return nil, err
+				}
+			}
+			p.next()
+		case "option", "reserved", "extensions", "extend":
+			p.skipStatement()
+		case ";":
+			p.next()
+		default:
+			if err := p.field(m); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// field parses a field definition.
+func (p *protoParser) field(m *protoMessage) error {
+	field := protoField{scope: m.name}
+	switch tok := p.next(); tok {
+	case "repeated":
+		field.label, field.typ = tok, p.next()
+	case "optional", "required":
+		field.typ = p.next()
+	case "map":
+		// map < key , value >
+		parts := []string{p.next(), p.next(), p.next(), p.next(), p.next()}
+		if parts[0] != "<" || parts[2] != "," || parts[4] != ">" {
+			return fmt.Errorf("%w: invalid protobuf map field in %s", ErrInvalidSchema, m.name)
+		}
+		field.typ = "map<" + parts[1] + "," + parts[3] + ">"
+	default:
+		field.typ = tok
+	}
+	field.name = p.next()
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	number, err := strconv.Atoi(p.next())
+	if err != nil {
+		return fmt.Errorf("%w: invalid protobuf field number of %s.%s", ErrInvalidSchema, m.name, field.name)
+	}
+	m.fields[number] = field
+	p.skipStatement()
+	return nil
+}
+
+// enum parses an enum definition, after the enum keyword.
+func (p *protoParser) enum(f *protoFile, scope string) error {
+	f.enums[qualify(scope, p.next())] = true
+	if p.peek() != "{" {
+		return fmt.Errorf("%w: invalid protobuf enum in %s", ErrInvalidSchema, scope)
+	}
+	p.skipStatement()
+	return nil
+}
+
+// tokenizeProto splits a protobuf schema into identifiers, numbers, string
+// literals and symbols, without the comments.
+func tokenizeProto(text string) []string {
+	var tokens []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.HasPrefix(text[i:], "//"):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(text) && text[j] != c {
+				if text[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			tokens = append(tokens, text[i:min(j+1, len(text))])
+			i = j + 1
+		case isProtoIdent(c):
+			j := i
+			for j < len(text) && isProtoIdent(text[j]) {
+				j++
+			}
+			tokens = append(tokens, text[i:j])
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+// isProtoIdent reports whether c is a character of an identifier, a number
+// or a qualified name.
+func isProtoIdent(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '+' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// checkProto returns the incompatibilities of the data written with a
+// protobuf schema when it is read with another one: the message types of
+// the writer removed from the reader, and the fields whose number is reused
+// with another wire type or label.
+func checkProto(reader, writer *protoFile) []string {
+	var issues []string
+	for _, wm := range sortedMessages(writer) {
+		rm := reader.all[wm.name]
+		if rm == nil {
+			issues = append(issues, fmt.Sprintf("message %s is removed", wm.name))
+			continue
+		}
+		for _, number := range sortedNumbers(wm.fields) {
+			wf := wm.fields[number]
+			rf, ok := rm.fields[number]
+			if !ok {
+				continue
+			}
+			if writer.kind(wf) != reader.kind(rf) || wf.label != rf.label {
+				issues = append(issues, fmt.Sprintf("field %d of message %s changes from %s to %s",
+					number, wm.name, wf, rf))
+			}
+		}
+	}
+	return issues
+}
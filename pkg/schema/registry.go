@@ -0,0 +1,302 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the serialization format of the messages of a topic, named as
+// the schema types of the registry.
+type Format string
+
+// Formats.
+const (
+	FormatProtobuf Format = "PROTOBUF"
+	FormatJSON     Format = "JSON"
+	FormatAvro     Format = "AVRO"
+)
+
+// ParseFormat parses a format, case insensitively: "protobuf", "json" or
+// "avro". The empty string is the protobuf format.
+//
+// Parameters:
+//   - s: The format.
+//
+// Returns:
+//   - Format: The parsed format.
+//   - error: An error if s is not a format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToUpper(strings.TrimSpace(s))); f {
+	case "":
+		return FormatProtobuf, nil
+	case FormatProtobuf, FormatJSON, FormatAvro:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid schema format %q, must be protobuf, json or avro", s)
+}
+
+// Errors of the schema registry.
+var (
+	ErrSubjectNotFound = errors.New("schema registry subject not found")
+	ErrSchemaNotFound  = errors.New("schema not found")
+	ErrIncompatible    = errors.New("schema is incompatible with the previous versions")
+	ErrInvalidSchema   = errors.New("invalid schema")
+)
+
+// Schema is a schema as stored by the registry.
+type Schema struct {
+	Type       Format      `json:"schemaType,omitempty"` // empty for Avro
+	Definition string      `json:"schema"`
+	References []Reference `json:"references,omitempty"`
+}
+
+// Reference is a schema imported by another one, e.g. the protobuf file of
+// a field type.
+type Reference struct {
+	Name    string `json:"name"` // e.g. the import path of a protobuf file
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// format returns the format of the schema, Avro if the type is empty as in
+// the registry API.
+func (s Schema) format() Format {
+	if s.Type == "" {
+		return FormatAvro
+	}
+	return s.Type
+}
+
+// equal reports whether two schemas are the same.
+func (s Schema) equal(o Schema) bool {
+	if s.format() != o.format() || s.Definition != o.Definition || len(s.References) != len(o.References) {
+		return false
+	}
+	for i := range s.References {
+		if s.References[i] != o.References[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RegisteredSchema is a version of the schema of a subject.
+type RegisteredSchema struct {
+	Subject string `json:"subject"`
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	Schema
+}
+
+// Registry is a schema registry, e.g. a Confluent compatible schema registry
+// reached with Client, or MemoryRegistry in tests.
+type Registry interface {
+	// Register registers a schema under a subject, if it is compatible
+	// with the previous versions of the subject, and returns its ID. The
+	// ID of a schema already registered is returned as is.
+	Register(ctx context.Context, subject string, schema Schema) (int, error)
+
+	// Lookup returns the version of a subject having a schema, or
+	// ErrSchemaNotFound.
+	Lookup(ctx context.Context, subject string, schema Schema) (RegisteredSchema, error)
+
+	// SchemaByID returns the schema of an ID, or ErrSchemaNotFound.
+	SchemaByID(ctx context.Context, id int) (Schema, error)
+
+	// Latest returns the latest version of a subject, or
+	// ErrSubjectNotFound.
+	Latest(ctx context.Context, subject string) (RegisteredSchema, error)
+
+	// Compatible reports whether a schema is compatible with the versions
+	// of a subject, according to the compatibility level of the subject.
+	Compatible(ctx context.Context, subject string, schema Schema) (bool, error)
+}
+
+// contentType is the content type of the registry API.
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// ClientConfig is the configuration of a registry client.
+type ClientConfig struct {
+	URL      string        // e.g. http://localhost:8081
+	Username string        // basic authentication, if not empty
+	Password string        // basic authentication password
+	Timeout  time.Duration // zero for no timeout
+
+	// HTTPClient is the HTTP client of the calls, e.g. httpclient.NewClient
+	// to propagate the request ID and the trace of their context, a client
+	// with Timeout if nil.
+	HTTPClient *http.Client
+}
+
+// Client is a client of the REST API of a Confluent compatible schema
+// registry.
+type Client struct {
+	url      string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClient creates a registry client.
+//
+// Parameters:
+//   - cfg: The client configuration.
+//
+// Returns:
+//   - *Client: The client.
+//   - error: An error if the URL is invalid.
+func NewClient(cfg ClientConfig) (*Client, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid schema registry url %q", cfg.URL)
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &Client{
+		url:      strings.TrimSuffix(cfg.URL, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		http:     client,
+	}, nil
+}
+
+// Register registers a schema under a subject, see Registry.
+func (c *Client) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", schema, &resp); err != nil {
+		return 0, fmt.Errorf("failed to register the schema of subject %s: %w", subject, err)
+	}
+	return resp.ID, nil
+}
+
+// Lookup returns the version of a subject having a schema, see Registry.
+func (c *Client) Lookup(ctx context.Context, subject string, schema Schema) (RegisteredSchema, error) {
+	var resp RegisteredSchema
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject), schema, &resp); err != nil {
+		return resp, fmt.Errorf("failed to look up the schema of subject %s: %w", subject, err)
+	}
+	return resp, nil
+}
+
+// SchemaByID returns the schema of an ID, see Registry.
+func (c *Client) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	var resp Schema
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &resp); err != nil {
+		return resp, fmt.Errorf("failed to get schema %d: %w", id, err)
+	}
+	return resp, nil
+}
+
+// Latest returns the latest version of a subject, see Registry.
+func (c *Client) Latest(ctx context.Context, subject string) (RegisteredSchema, error) {
+	var resp RegisteredSchema
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, &resp); err != nil {
+		return resp, fmt.Errorf("failed to get the latest schema of subject %s: %w", subject, err)
+	}
+	return resp, nil
+}
+
+// Compatible reports whether a schema is compatible with the versions of a
+// subject, see Registry. A schema is compatible with a subject without
+// version.
+func (c *Client) Compatible(ctx context.Context, subject string, schema Schema) (bool, error) {
+	var resp struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	err := c.do(ctx, http.MethodPost, "/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest", schema, &resp)
+	if errors.Is(err, ErrSubjectNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check the compatibility of the schema of subject %s: %w", subject, err)
+	}
+	return resp.IsCompatible, nil
+}
+
+// apiError is the error body of the registry API.
+type apiError struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+// Error codes of the registry API.
+const (
+	codeSubjectNotFound = 40401
+	codeVersionNotFound = 40402
+	codeSchemaNotFound  = 40403
+	codeIncompatible    = 409
+	codeInvalidSchema   = 42201
+)
+
+// err returns the error of an error body, wrapping the matching error of
+// the package.
+func (e apiError) err() error {
+	switch e.Code {
+	case codeSubjectNotFound:
+		return fmt.Errorf("%w: %s", ErrSubjectNotFound, e.Message)
+	case codeVersionNotFound, codeSchemaNotFound:
+		return fmt.Errorf("%w: %s", ErrSchemaNotFound, e.Message)
+	case codeIncompatible:
+		return fmt.Errorf("%w: %s", ErrIncompatible, e.Message)
+	case codeInvalidSchema:
+		return fmt.Errorf("%w: %s", ErrInvalidSchema, e.Message)
+	}
+	return fmt.Errorf("schema registry error %d: %s", e.Code, e.Message)
+}
+
+// do calls the registry API, encoding in as the JSON request body if it is
+// not nil and decoding the JSON response body into out.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var e apiError
+		if json.Unmarshal(data, &e) != nil || e.Code == 0 {
+			e = apiError{Code: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		}
+		return e.err()
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,386 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pkgproto "github.com/xiebingnote/go-gin-project/pkg/proto"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// field returns the descriptor of a field.
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     typ.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+// repeated makes a field repeated.
+func repeated(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return f
+}
+
+// testOrder builds the Order type of the test/order.proto file, importing
+// the Address and Status types of test/common.proto. The second version
+// widens the ID, removes the name and adds a priority.
+func testOrder(t *testing.T, version int) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	common := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/common.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Address"),
+			Field: []*descriptorpb.FieldDescriptorProto{field("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")},
+		}},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Status"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("STATUS_UNKNOWN"), Number: proto.Int32(0)},
+				{Name: proto.String("STATUS_PAID"), Number: proto.Int32(1)},
+			},
+		}},
+	}
+
+	note := field("note", 7, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")
+	note.Proto3Optional, note.OneofIndex = proto.Bool(true), proto.Int32(0)
+	fields := []*descriptorpb.FieldDescriptorProto{
+		field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+		field("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+		repeated(field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")),
+		repeated(field("counts", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Order.CountsEntry")),
+		field("status", 5, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".test.Status"),
+		field("address", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Address"),
+		note,
+		repeated(field("lines", 8, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Order.Line")),
+	}
+	if version == 2 {
+		fields[0] = field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, "")
+		fields[1] = field("priority", 9, descriptorpb.FieldDescriptorProto_TYPE_INT64, "")
+	}
+
+	order := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/order.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"test/common.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:      proto.String("Order"),
+			Field:     fields,
+			OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("_note")}},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("CountsEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+						field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				},
+				{
+					Name:  proto.String("Line"),
+					Field: []*descriptorpb.FieldDescriptorProto{field("sku", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")},
+				},
+			},
+		}},
+	}
+
+	files := new(protoregistry.Files)
+	for _, fdp := range []*descriptorpb.FileDescriptorProto{common, order} {
+		fd, err := protodesc.NewFile(fdp, files)
+		if err != nil {
+			t.Fatalf("NewFile(%s) error = %v", fdp.GetName(), err)
+		}
+		if err := files.RegisterFile(fd); err != nil {
+			t.Fatalf("RegisterFile(%s) error = %v", fdp.GetName(), err)
+		}
+	}
+	desc, err := files.FindDescriptorByName("test.Order")
+	if err != nil {
+		t.Fatalf("FindDescriptorByName() error = %v", err)
+	}
+	return desc.(protoreflect.MessageDescriptor)
+}
+
+// newOrder returns an order of the first version, with all its fields set.
+func newOrder(t *testing.T) *dynamicpb.Message {
+	t.Helper()
+
+	md := testOrder(t, 1)
+	m := dynamicpb.NewMessage(md)
+	fields := md.Fields()
+	m.Set(fields.ByName("id"), protoreflect.ValueOfInt32(-42))
+	m.Set(fields.ByName("name"), protoreflect.ValueOfString("books"))
+	tags := m.Mutable(fields.ByName("tags")).List()
+	tags.Append(protoreflect.ValueOfString("a"))
+	tags.Append(protoreflect.ValueOfString("b"))
+	counts := m.Mutable(fields.ByName("counts")).Map()
+	counts.Set(protoreflect.ValueOfString("x").MapKey(), protoreflect.ValueOfInt64(1<<40))
+	m.Set(fields.ByName("status"), protoreflect.ValueOfEnum(1))
+	address := m.Mutable(fields.ByName("address")).Message()
+	address.Set(address.Descriptor().Fields().ByName("city"), protoreflect.ValueOfString("Paris"))
+	m.Set(fields.ByName("note"), protoreflect.ValueOfString(""))
+	lines := m.Mutable(fields.ByName("lines")).List()
+	line := lines.NewElement()
+	line.Message().Set(line.Message().Descriptor().Fields().ByName("sku"), protoreflect.ValueOfString("sku-1"))
+	lines.Append(line)
+	return m
+}
+
+// TestFrame tests the Confluent wire format framing.
+func TestFrame(t *testing.T) {
+	for _, indexes := range [][]int{nil, {0}, {1, 2}} {
+		data := frame(7, indexes, []byte("payload"))
+		id, rest, err := unframe(data)
+		if err != nil || id != 7 {
+			t.Fatalf("unframe() = %d, %v, want 7", id, err)
+		}
+		if indexes != nil {
+			got, payload, err := readIndexes(rest)
+			if err != nil || len(got) != len(indexes) || got[len(got)-1] != indexes[len(indexes)-1] {
+				t.Fatalf("readIndexes() = %v, %v, want %v", got, err, indexes)
+			}
+			rest = payload
+		}
+		if string(rest) != "payload" {
+			t.Errorf("payload = %q, want payload", rest)
+		}
+	}
+	if data := frame(1, []int{0}, nil); len(data) != headerSize+1 {
+		t.Errorf("frame() of the first message = %v, want a single zero index byte", data)
+	}
+
+	bare, _ := proto.Marshal(&pkgproto.TestMessage{Id: 1, Name: "bare"})
+	if IsFramed(bare) {
+		t.Error("IsFramed() of a bare protobuf message = true")
+	}
+	if _, _, err := unframe(bare); !errors.Is(err, ErrNotFramed) {
+		t.Errorf("unframe() error = %v, want %v", err, ErrNotFramed)
+	}
+}
+
+// TestSerde tests the serialization of a message in each format and its
+// deserialization into the type named by its schema.
+func TestSerde(t *testing.T) {
+	order := newOrder(t)
+	types := NewTypes()
+	types.Register(order, &pkgproto.TestMessage{})
+
+	registry := NewMemoryRegistry(CompatBackward)
+	serde := NewSerde(registry, SerdeConfig{
+		Topics:       map[string]Format{"orders-json": FormatJSON, "orders-avro": FormatAvro},
+		AutoRegister: true,
+		Types:        types,
+	})
+
+	for _, topic := range []string{"orders", "orders-json", "orders-avro"} {
+		data, info, err := serde.Serialize(context.Background(), topic, order)
+		if err != nil {
+			t.Fatalf("Serialize(%s) error = %v", topic, err)
+		}
+		if info.Subject != topic+"-value" || info.Version != 1 || info.TypeURL != "type.googleapis.com/test.Order" {
+			t.Errorf("Serialize(%s) info = %+v", topic, info)
+		}
+		if h := info.Headers(); h[HeaderTypeURL] != info.TypeURL || h[HeaderSchemaVersion] != "1" {
+			t.Errorf("Headers() = %v", h)
+		}
+
+		got, dinfo, err := serde.Deserialize(context.Background(), data)
+		if err != nil {
+			t.Fatalf("Deserialize(%s) error = %v", topic, err)
+		}
+		if !proto.Equal(got, order) || dinfo.ID != info.ID || dinfo.Format != serde.Format(topic) {
+			t.Errorf("Deserialize(%s) = %v, %+v, want %v", topic, got, dinfo, order)
+		}
+	}
+
+	// The protobuf schema references the schema of its import
+	if subjects := strings.Join(registry.Subjects(), ","); subjects != "orders-avro-value,orders-json-value,orders-value,test/common.proto" {
+		t.Errorf("Subjects() = %s", subjects)
+	}
+
+	// A nested type is found with its message indexes
+	line := order.Get(order.Descriptor().Fields().ByName("lines")).List().Get(0).Message().Interface()
+	types.Register(line)
+	data, _, err := serde.Serialize(context.Background(), "lines", line)
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	if got, _, err := serde.Deserialize(context.Background(), data); err != nil || !proto.Equal(got, line) {
+		t.Errorf("Deserialize() of the nested type = %v, %v", got, err)
+	}
+
+	// Without registration, the schemas must have been registered
+	lookup := NewSerde(registry, SerdeConfig{Types: types})
+	if _, _, err := lookup.Serialize(context.Background(), "unknown", order); !errors.Is(err, ErrSubjectNotFound) {
+		t.Errorf("Serialize() error = %v, want %v", err, ErrSubjectNotFound)
+	}
+	if _, _, err := lookup.Serialize(context.Background(), "orders", order); err != nil {
+		t.Errorf("Serialize() of a registered schema error = %v", err)
+	}
+}
+
+// TestAvroEvolution tests that the data written with a schema version is
+// read with the next one, the fields being matched by name.
+func TestAvroEvolution(t *testing.T) {
+	registry := NewMemoryRegistry(CompatFull)
+	serde := NewSerde(registry, SerdeConfig{Format: FormatAvro, AutoRegister: true, Types: NewTypes()})
+
+	data, _, err := serde.Serialize(context.Background(), "orders", newOrder(t))
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+
+	v2 := testOrder(t, 2)
+	next := dynamicpb.NewMessage(v2)
+	if _, err := serde.DeserializeInto(context.Background(), data, next); err != nil {
+		t.Fatalf("DeserializeInto() error = %v", err)
+	}
+	fields := v2.Fields()
+	if next.Get(fields.ByName("id")).Int() != -42 || next.Get(fields.ByName("counts")).Map().Len() != 1 ||
+		next.Get(fields.ByName("status")).Enum() != 1 || !next.Has(fields.ByName("note")) ||
+		next.Get(fields.ByName("priority")).Int() != 0 {
+		t.Errorf("DeserializeInto() = %v, want the fields of the first version", next)
+	}
+
+	// The new version only adds fields with defaults and widens a type,
+	// which is backward but not forward compatible
+	schema := Schema{Type: FormatAvro, Definition: avroSchema(v2)}
+	if _, err := registry.Register(context.Background(), "orders-value", schema); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Register() with FULL compatibility error = %v, want %v", err, ErrIncompatible)
+	}
+	if err := CheckCompatibility(CompatBackward, schema, []Schema{{Type: FormatAvro, Definition: avroSchema(testOrder(t, 1))}}); err != nil {
+		t.Errorf("CheckCompatibility(BACKWARD) error = %v", err)
+	}
+}
+
+// TestCompatibility tests the compatibility checks of each format.
+func TestCompatibility(t *testing.T) {
+	v1, v2 := testOrder(t, 1), testOrder(t, 2)
+	proto1, _ := protoSchema(v1)
+	proto2, _ := protoSchema(v2)
+
+	tests := []struct {
+		name     string
+		level    Compatibility
+		schema   Schema
+		previous []Schema
+		ok       bool
+	}{
+		{"protobuf added field", CompatFull, Schema{Type: FormatProtobuf, Definition: proto2}, []Schema{{Type: FormatProtobuf, Definition: proto1}}, true},
+		{"protobuf field type change", CompatBackward,
+			Schema{Type: FormatProtobuf, Definition: strings.Replace(proto1, "string name = 2;", "double name = 2;", 1)},
+			[]Schema{{Type: FormatProtobuf, Definition: proto1}}, false},
+		{"protobuf message removed", CompatBackward,
+			Schema{Type: FormatProtobuf, Definition: "syntax = \"proto3\";\npackage test;\nmessage Other {}\n"},
+			[]Schema{{Type: FormatProtobuf, Definition: proto1}}, false},
+		{"avro field without default", CompatBackward,
+			Schema{Type: FormatAvro, Definition: `{"type":"record","name":"A","fields":[{"name":"x","type":"int"}]}`},
+			[]Schema{{Type: FormatAvro, Definition: `{"type":"record","name":"A","fields":[]}`}}, false},
+		{"json added field", CompatBackward, Schema{Type: FormatJSON, Definition: jsonSchema(v2)}, []Schema{{Type: FormatJSON, Definition: jsonSchema(v1)}}, true},
+		{"json required field", CompatBackward,
+			Schema{Type: FormatJSON, Definition: `{"type":"object","properties":{"x":{"type":"integer"}},"required":["x"]}`},
+			[]Schema{{Type: FormatJSON, Definition: `{"type":"object","properties":{}}`}}, false},
+		{"json closed content model", CompatForward,
+			Schema{Type: FormatJSON, Definition: `{"type":"object","properties":{"x":{"type":"integer"}}}`},
+			[]Schema{{Type: FormatJSON, Definition: `{"type":"object","additionalProperties":false}`}}, false},
+		{"none", CompatNone, Schema{Type: FormatJSON, Definition: `{"type":"string"}`}, []Schema{{Type: FormatJSON, Definition: `{"type":"integer"}`}}, true},
+		{"transitive", CompatBackwardTransitive,
+			Schema{Type: FormatAvro, Definition: `{"type":"record","name":"A","fields":[{"name":"x","type":"string","default":""}]}`},
+			[]Schema{
+				{Type: FormatAvro, Definition: `{"type":"record","name":"A","fields":[{"name":"x","type":"int"}]}`},
+				{Type: FormatAvro, Definition: `{"type":"record","name":"A","fields":[]}`},
+			}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCompatibility(tt.level, tt.schema, tt.previous)
+			if (err == nil) != tt.ok {
+				t.Errorf("CheckCompatibility() error = %v, want compatible %v", err, tt.ok)
+			}
+			if err != nil && !errors.Is(err, ErrIncompatible) {
+				t.Errorf("CheckCompatibility() error = %v, want %v", err, ErrIncompatible)
+			}
+		})
+	}
+
+	if err := CheckCompatibility(CompatBackward, Schema{Type: FormatAvro, Definition: "{"}, nil); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("CheckCompatibility() of an invalid schema error = %v, want %v", err, ErrInvalidSchema)
+	}
+}
+
+// TestClient tests the registry client against the in-process registry
+// served over HTTP.
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(NewMemoryRegistry(CompatBackward).Handler())
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(ClientConfig{URL: server.URL + "/", Username: "user", Password: "secret"})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ctx := context.Background()
+
+	types := NewTypes()
+	types.Register(&pkgproto.TestMessage{})
+	serde := NewSerde(client, SerdeConfig{AutoRegister: true, SubjectStrategy: SubjectRecord, Types: types})
+	msg := &pkgproto.TestMessage{Id: 7, Name: "seven"}
+	data, info, err := serde.Serialize(ctx, "tests", msg)
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	if info.Subject != "proto.TestMessage" || info.Version != 1 {
+		t.Errorf("Serialize() info = %+v", info)
+	}
+	got, _, err := NewSerde(client, SerdeConfig{Types: types}).Deserialize(ctx, data)
+	if err != nil || !proto.Equal(got, msg) {
+		t.Errorf("Deserialize() = %v, %v, want %v", got, err, msg)
+	}
+
+	latest, err := client.Latest(ctx, "proto.TestMessage")
+	if err != nil || latest.ID != info.ID || latest.Type != FormatProtobuf {
+		t.Errorf("Latest() = %+v, %v", latest, err)
+	}
+
+	// A subject named by a path is escaped
+	avro := Schema{Type: FormatAvro, Definition: `{"type":"record","name":"A","fields":[]}`}
+	if _, err := client.Register(ctx, "test/a.avsc", avro); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	incompatible := Schema{Type: FormatAvro, Definition: `{"type":"record","name":"A","fields":[{"name":"x","type":"int"}]}`}
+	if ok, err := client.Compatible(ctx, "test/a.avsc", incompatible); ok || err != nil {
+		t.Errorf("Compatible() = %v, %v, want false", ok, err)
+	}
+	if _, err := client.Register(ctx, "test/a.avsc", incompatible); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Register() error = %v, want %v", err, ErrIncompatible)
+	}
+	if ok, err := client.Compatible(ctx, "new", incompatible); !ok || err != nil {
+		t.Errorf("Compatible() with a new subject = %v, %v, want true", ok, err)
+	}
+	if _, err := client.SchemaByID(ctx, 99); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("SchemaByID() error = %v, want %v", err, ErrSchemaNotFound)
+	}
+	if _, err := client.Latest(ctx, "unknown"); !errors.Is(err, ErrSubjectNotFound) {
+		t.Errorf("Latest() error = %v, want %v", err, ErrSubjectNotFound)
+	}
+}
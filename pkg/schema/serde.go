@@ -0,0 +1,461 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Headers describing the schema of a serialized message, for the consumers
+// routing messages without decoding them.
+const (
+	HeaderTypeURL       = "x-type-url"
+	HeaderSchemaID      = "x-schema-id"
+	HeaderSchemaVersion = "x-schema-version"
+)
+
+// SubjectStrategy names the subject of the schema of the messages of a
+// topic.
+type SubjectStrategy string
+
+// Subject strategies.
+const (
+	// SubjectTopic is the "<topic>-value" subject, a topic having a single
+	// message type.
+	SubjectTopic SubjectStrategy = "topic"
+	// SubjectRecord is the full name of the message type, a message type
+	// having the same schema in all the topics.
+	SubjectRecord SubjectStrategy = "record"
+	// SubjectTopicRecord is the "<topic>-<full name>" subject, a topic
+	// having several message types.
+	SubjectTopicRecord SubjectStrategy = "topic_record"
+)
+
+// ParseSubjectStrategy parses a subject strategy: "topic", "record" or
+// "topic_record". The empty string is SubjectTopic.
+//
+// Parameters:
+//   - s: The subject strategy.
+//
+// Returns:
+//   - SubjectStrategy: The parsed strategy.
+//   - error: An error if s is not a subject strategy.
+func ParseSubjectStrategy(s string) (SubjectStrategy, error) {
+	switch strategy := SubjectStrategy(strings.ToLower(strings.TrimSpace(s))); strategy {
+	case "":
+		return SubjectTopic, nil
+	case SubjectTopic, SubjectRecord, SubjectTopicRecord:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("invalid schema subject strategy %q, must be topic, record or topic_record", s)
+}
+
+// Subject returns the subject of the schema of a message type in a topic.
+//
+// Parameters:
+//   - topic: The topic.
+//   - name: The full name of the message type.
+//
+// Returns:
+//   - string: The subject.
+func (s SubjectStrategy) Subject(topic string, name protoreflect.FullName) string {
+	switch s {
+	case SubjectRecord:
+		return string(name)
+	case SubjectTopicRecord:
+		return topic + "-" + string(name)
+	}
+	return topic + "-value"
+}
+
+// SerdeConfig is the configuration of a serde.
+type SerdeConfig struct {
+	Format          Format            // default format of the topics, protobuf if empty
+	Topics          map[string]Format // formats by topic
+	SubjectStrategy SubjectStrategy   // SubjectTopic if empty
+	AutoRegister    bool              // register the schemas of the produced types
+	Types           *Types            // DefaultTypes if nil
+}
+
+// Info is the schema of a serialized message.
+type Info struct {
+	ID      int
+	Subject string // empty for a deserialized message
+	Version int    // zero for a deserialized message
+	Format  Format
+	TypeURL string
+}
+
+// Headers returns the headers describing the schema of a message, see
+// HeaderTypeURL.
+//
+// Returns:
+//   - map[string]string: The headers, without the unknown values.
+func (i Info) Headers() map[string]string {
+	headers := map[string]string{HeaderTypeURL: i.TypeURL, HeaderSchemaID: strconv.Itoa(i.ID)}
+	if i.Version > 0 {
+		headers[HeaderSchemaVersion] = strconv.Itoa(i.Version)
+	}
+	return headers
+}
+
+// Serde serializes the protobuf messages of the topics in the Confluent
+// wire format, in the format of their topic, with the ID of their schema in
+// a registry, and deserializes them into the registered type named by their
+// schema. The IDs and the schemas are cached.
+type Serde struct {
+	registry Registry
+	cfg      SerdeConfig
+
+	mu      sync.RWMutex
+	infos   map[infoKey]serdeInfo
+	schemas map[int]*parsedSchema
+}
+
+// infoKey is the key of the schema of a message type in a format and a
+// subject.
+type infoKey struct {
+	subject string
+	format  Format
+	name    protoreflect.FullName
+}
+
+// serdeInfo is the cached schema of a message type.
+type serdeInfo struct {
+	info    Info
+	indexes []int // protobuf message indexes
+}
+
+// NewSerde creates a serde.
+//
+// Parameters:
+//   - registry: The schema registry, e.g. a Client.
+//   - cfg: The serde configuration.
+//
+// Returns:
+//   - *Serde: The serde.
+func NewSerde(registry Registry, cfg SerdeConfig) *Serde {
+	if cfg.Format == "" {
+		cfg.Format = FormatProtobuf
+	}
+	if cfg.SubjectStrategy == "" {
+		cfg.SubjectStrategy = SubjectTopic
+	}
+	if cfg.Types == nil {
+		cfg.Types = DefaultTypes
+	}
+	return &Serde{
+		registry: registry,
+		cfg:      cfg,
+		infos:    make(map[infoKey]serdeInfo),
+		schemas:  make(map[int]*parsedSchema),
+	}
+}
+
+// Types returns the type registry of the serde.
+func (s *Serde) Types() *Types {
+	return s.cfg.Types
+}
+
+// Format returns the format of the messages of a topic.
+//
+// Parameters:
+//   - topic: The topic.
+//
+// Returns:
+//   - Format: The format of the topic, or the default format.
+func (s *Serde) Format(topic string) Format {
+	if f, ok := s.cfg.Topics[topic]; ok {
+		return f
+	}
+	return s.cfg.Format
+}
+
+// Serialize serializes a message of a topic in the Confluent wire format.
+//
+// The schema of the message type is registered under its subject if the
+// serde registers the schemas, or must have been registered otherwise.
+//
+// Parameters:
+//   - ctx: The context of the registry calls.
+//   - topic: The topic of the message.
+//   - msg: The message.
+//
+// Returns:
+//   - []byte: The serialized message.
+//   - Info: The schema of the message, e.g. for its headers.
+//   - error: An error if the schema cannot be registered or found, or if
+//     the message cannot be encoded.
+func (s *Serde) Serialize(ctx context.Context, topic string, msg proto.Message) ([]byte, Info, error) {
+	md := msg.ProtoReflect().Descriptor()
+	format := s.Format(topic)
+	si, err := s.info(ctx, s.cfg.SubjectStrategy.Subject(topic, md.FullName()), format, md)
+	if err != nil {
+		return nil, Info{}, err
+	}
+
+	var payload []byte
+	switch format {
+	case FormatProtobuf:
+		payload, err = proto.Marshal(msg)
+	case FormatJSON:
+		payload, err = protojson.Marshal(msg)
+	case FormatAvro:
+		payload, err = avroMarshal(msg.ProtoReflect())
+	}
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("failed to encode %s message %s: %w", format, md.FullName(), err)
+	}
+	return frame(si.info.ID, si.indexes, payload), si.info, nil
+}
+
+// Deserialize deserializes a message in the Confluent wire format into a new
+// message of the type named by its schema, which must be registered in the
+// type registry of the serde.
+//
+// Parameters:
+//   - ctx: The context of the registry calls.
+//   - data: The serialized message.
+//
+// Returns:
+//   - proto.Message: The message.
+//   - Info: The schema of the message.
+//   - error: ErrNotFramed if the message is not in the wire format, or an
+//     error if its schema or its type is unknown or it cannot be decoded.
+func (s *Serde) Deserialize(ctx context.Context, data []byte) (proto.Message, Info, error) {
+	id, payload, err := unframe(data)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	writer, err := s.writer(ctx, id)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	name, payload, err := writer.messageName(payload)
+	if err != nil {
+		return nil, Info{}, err
+	}
+
+	mt, err := s.cfg.Types.Find(name)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	msg := mt.New().Interface()
+	if err := writer.decode(payload, msg); err != nil {
+		return nil, Info{}, err
+	}
+	return msg, Info{ID: id, Format: writer.format, TypeURL: typeURLPrefix + name}, nil
+}
+
+// DeserializeInto deserializes a message in the Confluent wire format into
+// msg, whatever the type named by its schema. The Avro and JSON fields are
+// matched by name, the protobuf fields by number.
+//
+// Parameters:
+//   - ctx: The context of the registry calls.
+//   - data: The serialized message.
+//   - msg: The message to decode into.
+//
+// Returns:
+//   - Info: The schema of the message.
+//   - error: ErrNotFramed if the message is not in the wire format, or an
+//     error if its schema is unknown or it cannot be decoded.
+func (s *Serde) DeserializeInto(ctx context.Context, data []byte, msg proto.Message) (Info, error) {
+	id, payload, err := unframe(data)
+	if err != nil {
+		return Info{}, err
+	}
+	writer, err := s.writer(ctx, id)
+	if err != nil {
+		return Info{}, err
+	}
+	name, payload, err := writer.messageName(payload)
+	if err != nil {
+		return Info{}, err
+	}
+	if err := writer.decode(payload, msg); err != nil {
+		return Info{}, err
+	}
+	return Info{ID: id, Format: writer.format, TypeURL: typeURLPrefix + name}, nil
+}
+
+// Compatible reports whether the schema of a message type is compatible
+// with the versions of its subject in a topic, e.g. to check a new version
+// of a type before it is deployed.
+//
+// Parameters:
+//   - ctx: The context of the registry calls.
+//   - topic: The topic of the messages.
+//   - msg: A message of the type.
+//
+// Returns:
+//   - bool: True if the schema is compatible.
+//   - error: An error if the registry cannot be reached.
+func (s *Serde) Compatible(ctx context.Context, topic string, msg proto.Message) (bool, error) {
+	md := msg.ProtoReflect().Descriptor()
+	schema, _, err := s.schema(ctx, s.Format(topic), md)
+	if err != nil {
+		return false, err
+	}
+	return s.registry.Compatible(ctx, s.cfg.SubjectStrategy.Subject(topic, md.FullName()), schema)
+}
+
+// info returns the schema of a message type in a subject, registering it
+// if the serde registers the schemas.
+func (s *Serde) info(ctx context.Context, subject string, format Format, md protoreflect.MessageDescriptor) (serdeInfo, error) {
+	key := infoKey{subject: subject, format: format, name: md.FullName()}
+	s.mu.RLock()
+	si, ok := s.infos[key]
+	s.mu.RUnlock()
+	if ok {
+		return si, nil
+	}
+
+	schema, indexes, err := s.schema(ctx, format, md)
+	if err != nil {
+		return serdeInfo{}, err
+	}
+	registered, err := s.resolve(ctx, subject, schema)
+	if err != nil {
+		return serdeInfo{}, err
+	}
+
+	si = serdeInfo{
+		info: Info{
+			ID:      registered.ID,
+			Subject: subject,
+			Version: registered.Version,
+			Format:  format,
+			TypeURL: typeURLPrefix + string(md.FullName()),
+		},
+	}
+	if format == FormatProtobuf {
+		si.indexes = indexes
+	}
+	s.mu.Lock()
+	s.infos[key] = si
+	s.mu.Unlock()
+	return si, nil
+}
+
+// schema returns the schema of a message type in a format, and the indexes
+// of the type in its protobuf schema. The imports of a protobuf schema,
+// other than the well known types, are references to the subjects named by
+// their path.
+func (s *Serde) schema(ctx context.Context, format Format, md protoreflect.MessageDescriptor) (Schema, []int, error) {
+	switch format {
+	case FormatJSON:
+		return Schema{Type: FormatJSON, Definition: jsonSchema(md)}, nil, nil
+	case FormatAvro:
+		return Schema{Type: FormatAvro, Definition: avroSchema(md)}, nil, nil
+	}
+
+	definition, indexes := protoSchema(md)
+	schema := Schema{Type: FormatProtobuf, Definition: definition}
+	refs, err := s.references(ctx, md.ParentFile())
+	if err != nil {
+		return Schema{}, nil, err
+	}
+	schema.References = refs
+	return schema, indexes, nil
+}
+
+// references returns the references of the imports of a protobuf file,
+// registering them if the serde registers the schemas.
+func (s *Serde) references(ctx context.Context, fd protoreflect.FileDescriptor) ([]Reference, error) {
+	var refs []Reference
+	for i := 0; i < fd.Imports().Len(); i++ {
+		imp := fd.Imports().Get(i).FileDescriptor
+		if strings.HasPrefix(imp.Path(), "google/protobuf/") {
+			continue
+		}
+		impRefs, err := s.references(ctx, imp)
+		if err != nil {
+			return nil, err
+		}
+		registered, err := s.resolve(ctx, imp.Path(), Schema{Type: FormatProtobuf, Definition: printProto(imp), References: impRefs})
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, Reference{Name: imp.Path(), Subject: imp.Path(), Version: registered.Version})
+	}
+	return refs, nil
+}
+
+// resolve returns the version of a subject having a schema, registering the
+// schema first if the serde registers the schemas.
+func (s *Serde) resolve(ctx context.Context, subject string, schema Schema) (RegisteredSchema, error) {
+	if s.cfg.AutoRegister {
+		if _, err := s.registry.Register(ctx, subject, schema); err != nil {
+			return RegisteredSchema{}, err
+		}
+	}
+	return s.registry.Lookup(ctx, subject, schema)
+}
+
+// writer returns the parsed schema of an ID.
+func (s *Serde) writer(ctx context.Context, id int) (*parsedSchema, error) {
+	s.mu.RLock()
+	parsed, ok := s.schemas[id]
+	s.mu.RUnlock()
+	if ok {
+		return parsed, nil
+	}
+
+	schema, err := s.registry.SchemaByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if parsed, err = parseSchema(schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %d: %w", id, err)
+	}
+	s.mu.Lock()
+	s.schemas[id] = parsed
+	s.mu.Unlock()
+	return parsed, nil
+}
+
+// messageName returns the full name of the message type of a payload
+// written with the schema, and the payload without its protobuf message
+// indexes.
+func (p *parsedSchema) messageName(payload []byte) (string, []byte, error) {
+	switch p.format {
+	case FormatProtobuf:
+		indexes, payload, err := readIndexes(payload)
+		if err != nil {
+			return "", nil, err
+		}
+		name, err := p.proto.messageName(indexes)
+		return name, payload, err
+	case FormatAvro:
+		return p.avro.name, payload, nil
+	}
+	if p.json.title == "" {
+		return "", nil, errors.New("json schema has no title naming its message type")
+	}
+	return p.json.title, payload, nil
+}
+
+// decode decodes a payload written with the schema into a message.
+func (p *parsedSchema) decode(payload []byte, msg proto.Message) error {
+	var err error
+	switch p.format {
+	case FormatProtobuf:
+		err = proto.Unmarshal(payload, msg)
+	case FormatAvro:
+		err = avroUnmarshal(p.avro, payload, msg.ProtoReflect())
+	case FormatJSON:
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(payload, msg)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode %s message %s: %w", p.format, msg.ProtoReflect().Descriptor().FullName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// typeURLPrefix prefixes the full name of a message type in its type URL,
+// as in google.protobuf.Any.
+const typeURLPrefix = "type.googleapis.com/"
+
+// Types is a local registry of the protobuf message types a process
+// produces or consumes, by full name, so that a message is decoded into the
+// type named by its schema.
+type Types struct {
+	mu    sync.RWMutex
+	types map[protoreflect.FullName]protoreflect.MessageType
+}
+
+// NewTypes creates an empty type registry.
+func NewTypes() *Types {
+	return &Types{types: make(map[protoreflect.FullName]protoreflect.MessageType)}
+}
+
+// DefaultTypes is the type registry of RegisterType, used by the serdes
+// created without type registry.
+var DefaultTypes = NewTypes()
+
+// RegisterType registers the types of messages in DefaultTypes, e.g.
+// RegisterType(&pkgproto.TestMessage{}).
+//
+// Parameters:
+//   - msgs: Messages of the types to register.
+func RegisterType(msgs ...proto.Message) {
+	DefaultTypes.Register(msgs...)
+}
+
+// Register registers the types of messages. Registering a type twice is a
+// no-op.
+//
+// Parameters:
+//   - msgs: Messages of the types to register.
+func (t *Types) Register(msgs ...proto.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, msg := range msgs {
+		mt := msg.ProtoReflect().Type()
+		t.types[mt.Descriptor().FullName()] = mt
+	}
+}
+
+// Find returns a registered message type by full name, e.g. "proto.TestMessage",
+// or by type URL, e.g. "type.googleapis.com/proto.TestMessage".
+//
+// Parameters:
+//   - name: The full name or the type URL.
+//
+// Returns:
+//   - protoreflect.MessageType: The message type.
+//   - error: An error if the type is not registered.
+func (t *Types) Find(name string) (protoreflect.MessageType, error) {
+	full := protoreflect.FullName(name[strings.LastIndex(name, "/")+1:])
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	mt, ok := t.types[full]
+	if !ok {
+		return nil, fmt.Errorf("protobuf message type %s is not registered", full)
+	}
+	return mt, nil
+}
+
+// Names returns the sorted full names of the registered types.
+func (t *Types) Names() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	names := make([]string, 0, len(t.types))
+	for name := range t.types {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TypeURL returns the type URL of a message, e.g.
+// "type.googleapis.com/proto.TestMessage".
+//
+// Parameters:
+//   - msg: The message.
+//
+// Returns:
+//   - string: The type URL.
+func TypeURL(msg proto.Message) string {
+	return typeURLPrefix + string(msg.ProtoReflect().Descriptor().FullName())
+}
@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// magicByte starts the messages of the Confluent wire format.
+const magicByte = 0x00
+
+// headerSize is the size of the magic byte and the schema ID.
+const headerSize = 5
+
+// ErrNotFramed is returned when a message does not start with the Confluent
+// wire format header, e.g. a bare protobuf message produced without a
+// registry.
+var ErrNotFramed = errors.New("message is not in the schema registry wire format")
+
+// IsFramed reports whether data starts with the Confluent wire format
+// header: the zero magic byte and a 4 bytes schema ID.
+//
+// A bare protobuf message never starts with a zero byte, the field number 0
+// being reserved, so framed and bare messages can be consumed from the same
+// topic during a migration.
+//
+// Parameters:
+//   - data: The message.
+//
+// Returns:
+//   - bool: True if the message is framed.
+func IsFramed(data []byte) bool {
+	return len(data) >= headerSize && data[0] == magicByte
+}
+
+// frame returns the message of a payload in the Confluent wire format: the
+// magic byte, the schema ID as a big endian uint32, the message indexes for
+// the protobuf format, and the payload.
+func frame(id int, indexes []int, payload []byte) []byte {
+	out := make([]byte, headerSize, headerSize+len(payload)+8)
+	out[0] = magicByte
+	binary.BigEndian.PutUint32(out[1:], uint32(id))
+	if indexes != nil {
+		out = appendIndexes(out, indexes)
+	}
+	return append(out, payload...)
+}
+
+// unframe splits a message in the Confluent wire format into its schema ID
+// and its payload, still prefixed by the message indexes for the protobuf
+// format.
+func unframe(data []byte) (int, []byte, error) {
+	if !IsFramed(data) {
+		return 0, nil, ErrNotFramed
+	}
+	return int(binary.BigEndian.Uint32(data[1:headerSize])), data[headerSize:], nil
+}
+
+// appendIndexes appends the indexes of a message type in its protobuf
+// schema, the path of the type through the nested message definitions, as
+// zigzag varints prefixed by their count. The first type, [0], is encoded as
+// a single zero byte.
+func appendIndexes(out []byte, indexes []int) []byte {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append(out, 0)
+	}
+	out = binary.AppendVarint(out, int64(len(indexes)))
+	for _, i := range indexes {
+		out = binary.AppendVarint(out, int64(i))
+	}
+	return out
+}
+
+// readIndexes reads the message indexes in front of a protobuf payload.
+func readIndexes(data []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 || count > int64(len(data)) {
+		return nil, nil, fmt.Errorf("invalid protobuf message indexes")
+	}
+	data = data[n:]
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(data)
+		if n <= 0 || index < 0 {
+			return nil, nil, fmt.Errorf("invalid protobuf message indexes")
+		}
+		indexes[i], data = int(index), data[n:]
+	}
+	return indexes, data, nil
+}
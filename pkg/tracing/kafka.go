@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"github.com/IBM/sarama"
+)
+
+// ProducerMessageCarrier adapts the headers of a sarama producer message to
+// propagation.TextMapCarrier.
+type ProducerMessageCarrier struct {
+	Message *sarama.ProducerMessage
+}
+
+// Get returns the value of the header key.
+func (c ProducerMessageCarrier) Get(key string) string {
+	for _, h := range c.Message.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set sets the header key, replacing any existing value.
+func (c ProducerMessageCarrier) Set(key, value string) {
+	for i, h := range c.Message.Headers {
+		if string(h.Key) == key {
+			c.Message.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.Message.Headers = append(c.Message.Headers, sarama.RecordHeader{
+		Key:   []byte(key),
+		Value: []byte(value),
+	})
+}
+
+// Keys returns the header keys.
+func (c ProducerMessageCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.Message.Headers))
+	for _, h := range c.Message.Headers {
+		keys = append(keys, string(h.Key))
+	}
+	return keys
+}
+
+// ConsumerMessageCarrier adapts the headers of a sarama consumer message to
+// propagation.TextMapCarrier.
+type ConsumerMessageCarrier struct {
+	Message *sarama.ConsumerMessage
+}
+
+// Get returns the value of the header key.
+func (c ConsumerMessageCarrier) Get(key string) string {
+	for _, h := range c.Message.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set sets the header key, replacing any existing value.
+func (c ConsumerMessageCarrier) Set(key, value string) {
+	for _, h := range c.Message.Headers {
+		if h != nil && string(h.Key) == key {
+			h.Value = []byte(value)
+			return
+		}
+	}
+	c.Message.Headers = append(c.Message.Headers, &sarama.RecordHeader{
+		Key:   []byte(key),
+		Value: []byte(value),
+	})
+}
+
+// Keys returns the header keys.
+func (c ConsumerMessageCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.Message.Headers))
+	for _, h := range c.Message.Headers {
+		if h != nil {
+			keys = append(keys, string(h.Key))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Messaging systems
+const (
+	SystemKafka = "kafka"
+	SystemNSQ   = "nsq"
+)
+
+// StartProducerSpan starts a producer span for a message published to topic.
+//
+// The caller injects the returned context into the message headers, see
+// Inject, and ends the span once the message is sent.
+//
+// Parameters:
+//   - ctx: The context of the request producing the message.
+//   - system: The messaging system, e.g. SystemKafka.
+//   - topic: The destination topic.
+//   - attrs: Additional span attributes.
+//
+// Returns:
+//   - context.Context: The context carrying the producer span.
+//   - trace.Span: The producer span.
+func StartProducerSpan(ctx context.Context, system, topic string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append([]attribute.KeyValue{
+		semconv.MessagingSystemKey.String(system),
+		semconv.MessagingDestinationName(topic),
+		semconv.MessagingOperationPublish,
+	}, attrs...)
+
+	return Tracer().Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// StartConsumerSpan starts a consumer span for a message received from topic,
+// continuing the trace found in carrier.
+//
+// Parameters:
+//   - ctx: The parent context of the consumer.
+//   - carrier: The message headers carrying the producer trace context.
+//   - system: The messaging system, e.g. SystemKafka.
+//   - topic: The source topic.
+//   - attrs: Additional span attributes.
+//
+// Returns:
+//   - context.Context: The context carrying the consumer span.
+//   - trace.Span: The consumer span.
+func StartConsumerSpan(ctx context.Context, carrier propagation.TextMapCarrier, system, topic string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx = Extract(ctx, carrier)
+
+	attrs = append([]attribute.KeyValue{
+		semconv.MessagingSystemKey.String(system),
+		semconv.MessagingDestinationName(topic),
+		semconv.MessagingOperationReceive,
+	}, attrs...)
+
+	return Tracer().Start(ctx, topic+" receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// Inject writes the trace context of ctx into carrier.
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// Extract returns a copy of ctx carrying the trace context found in carrier.
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// EndSpan records err on span, if any, and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mongoSpanKey identifies a command across its started and finished events.
+type mongoSpanKey struct {
+	connectionID string
+	requestID    int64
+}
+
+// NewMongoMonitor returns a command monitor creating a client span for each
+// MongoDB command.
+//
+// Set it with options.Client().SetMonitor(tracing.NewMongoMonitor()). The
+// command document is not recorded, as it may contain user data.
+//
+// Returns:
+//   - *event.CommandMonitor: The command monitor.
+func NewMongoMonitor() *event.CommandMonitor {
+	var spans sync.Map
+
+	end := func(evt event.CommandFinishedEvent, err error) {
+		key := mongoSpanKey{connectionID: evt.ConnectionID, requestID: evt.RequestID}
+		if value, ok := spans.LoadAndDelete(key); ok {
+			EndSpan(value.(trace.Span), err)
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			attrs := []attribute.KeyValue{
+				semconv.DBSystemMongoDB,
+				semconv.DBName(evt.DatabaseName),
+				semconv.DBOperation(evt.CommandName),
+			}
+			// The first element of the command holds the collection name
+			if elem, err := evt.Command.IndexErr(0); err == nil {
+				if collection, ok := elem.Value().StringValueOK(); ok {
+					attrs = append(attrs, semconv.DBMongoDBCollection(collection))
+				}
+			}
+
+			_, span := Tracer().Start(ctx, fmt.Sprintf("mongodb.%s", evt.CommandName),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attrs...),
+			)
+			spans.Store(mongoSpanKey{connectionID: evt.ConnectionID, requestID: evt.RequestID}, span)
+		},
+		Succeeded: func(_ context.Context, evt *event.CommandSucceededEvent) {
+			end(evt.CommandFinishedEvent, nil)
+		},
+		Failed: func(_ context.Context, evt *event.CommandFailedEvent) {
+			end(evt.CommandFinishedEvent, errors.New(evt.Failure))
+		},
+	}
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedisHook is a go-redis hook creating a client span for each command and
+// pipeline.
+//
+// Register it with client.AddHook(tracing.NewRedisHook(addr)).
+type RedisHook struct {
+	addr string
+}
+
+// NewRedisHook creates a new RedisHook.
+//
+// Parameters:
+//   - addr: The Redis server address, recorded on the spans.
+//
+// Returns:
+//   - *RedisHook: The hook.
+func NewRedisHook(addr string) *RedisHook {
+	return &RedisHook{addr: addr}
+}
+
+// DialHook creates a span for each new connection.
+func (h *RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := h.start(ctx, "redis.dial")
+		conn, err := next(ctx, network, addr)
+		EndSpan(span, err)
+		return conn, err
+	}
+}
+
+// ProcessHook creates a span for each command.
+func (h *RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := h.start(ctx, "redis "+cmd.Name(), semconv.DBOperation(cmd.Name()))
+		err := next(ctx, cmd)
+		EndSpan(span, redisError(err))
+		return err
+	}
+}
+
+// ProcessPipelineHook creates a span for each pipeline.
+func (h *RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name())
+		}
+
+		ctx, span := h.start(ctx, "redis pipeline", semconv.DBOperation(strings.Join(names, " ")))
+		err := next(ctx, cmds)
+		EndSpan(span, redisError(err))
+		return err
+	}
+}
+
+// start starts a Redis client span.
+func (h *RedisHook) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemRedis, semconv.ServerAddress(h.addr))
+	return Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// redisError ignores redis.Nil, which reports a missing key rather than a
+// failure.
+func redisError(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
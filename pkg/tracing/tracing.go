@@ -0,0 +1,291 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// InstrumentationName is the name of the tracer used by the instrumentation
+// of this package.
+const InstrumentationName = "github.com/xiebingnote/go-gin-project/pkg/tracing"
+
+// Supported exporters
+const (
+	ExporterOTLP   = "otlp"   // OTLP over HTTP, e.g. to a collector or Jaeger
+	ExporterStdout = "stdout" // spans written to stdout, for local development
+	ExporterMemory = "memory" // spans kept in memory, for tests
+	ExporterNone   = "none"   // spans are created but not exported
+)
+
+// Supported samplers
+const (
+	SamplerAlwaysOn  = "always_on"
+	SamplerAlwaysOff = "always_off"
+	SamplerRatio     = "ratio"
+)
+
+// Options configures the tracer provider.
+type Options struct {
+	ServiceName    string // service.name resource attribute
+	ServiceVersion string // service.version resource attribute
+	Environment    string // deployment.environment resource attribute
+
+	Exporter    string            // one of the Exporter* constants
+	Endpoint    string            // OTLP endpoint, host:port
+	URLPath     string            // OTLP URL path, "/v1/traces" if empty
+	Insecure    bool              // use HTTP instead of HTTPS for OTLP
+	Headers     map[string]string // headers sent with OTLP requests
+	Timeout     time.Duration     // export timeout
+	PrettyPrint bool              // indent the stdout exporter output
+
+	Sampler     string  // one of the Sampler* constants
+	SampleRatio float64 // sampled fraction for SamplerRatio
+	ParentBased bool    // follow the sampling decision of the parent span
+
+	MaxQueueSize       int           // batch span processor queue size
+	MaxExportBatchSize int           // maximum spans per export
+	BatchTimeout       time.Duration // maximum delay between exports
+}
+
+// Provider wraps the SDK tracer provider installed by Init.
+type Provider struct {
+	tp     *sdktrace.TracerProvider
+	memory *tracetest.InMemoryExporter
+}
+
+// registerLogFieldsOnce ensures the log correlation extractor is registered
+// only once, even if Init is called several times (e.g. in tests).
+var registerLogFieldsOnce sync.Once
+
+// Init creates a tracer provider from opts and installs it, together with
+// the W3C trace context and baggage propagators, as the global provider.
+//
+// It also registers the trace and span IDs as context fields of the
+// context-aware logger, so logs written with logger.WithContext can be
+// correlated with traces.
+//
+// Parameters:
+//   - ctx: Context for the operation, used when creating the exporter.
+//   - opts: The tracing options.
+//
+// Returns:
+//   - *Provider: The installed provider, to be shut down on exit.
+//   - error: An error if the exporter or sampler is invalid.
+func Init(ctx context.Context, opts Options) (*Provider, error) {
+	sampler, err := NewSampler(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := newResource(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	p := &Provider{}
+	tpOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(res),
+	}
+
+	switch opts.Exporter {
+	case ExporterMemory:
+		// Export synchronously so spans are visible as soon as they end
+		p.memory = tracetest.NewInMemoryExporter()
+		tpOpts = append(tpOpts, sdktrace.WithSyncer(p.memory))
+	case ExporterNone, "":
+		// Spans still carry IDs for propagation and log correlation
+	default:
+		exporter, err := newExporter(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tpOpts = append(tpOpts, sdktrace.WithBatcher(exporter, batchOptions(opts)...))
+	}
+
+	p.tp = sdktrace.NewTracerProvider(tpOpts...)
+
+	otel.SetTracerProvider(p.tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	registerLogFieldsOnce.Do(func() {
+		logger.RegisterContextFields(traceFields)
+	})
+
+	return p, nil
+}
+
+// Shutdown flushes the pending spans and stops the provider.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil || p.tp == nil {
+		return nil
+	}
+	return p.tp.Shutdown(ctx)
+}
+
+// ForceFlush exports all pending spans.
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	if p == nil || p.tp == nil {
+		return nil
+	}
+	return p.tp.ForceFlush(ctx)
+}
+
+// TracerProvider returns the underlying SDK tracer provider.
+func (p *Provider) TracerProvider() *sdktrace.TracerProvider {
+	if p == nil {
+		return nil
+	}
+	return p.tp
+}
+
+// MemoryExporter returns the in-memory exporter when the provider was
+// created with ExporterMemory, nil otherwise.
+func (p *Provider) MemoryExporter() *tracetest.InMemoryExporter {
+	if p == nil {
+		return nil
+	}
+	return p.memory
+}
+
+// Tracer returns the tracer used by the instrumentation of this package.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// NewSampler creates the sampler described by opts.
+//
+// Parameters:
+//   - opts: The tracing options.
+//
+// Returns:
+//   - sdktrace.Sampler: The sampler, wrapped in a parent based sampler if
+//     opts.ParentBased is set.
+//   - error: An error if the sampler type or ratio is invalid.
+func NewSampler(opts Options) (sdktrace.Sampler, error) {
+	var sampler sdktrace.Sampler
+	switch opts.Sampler {
+	case SamplerAlwaysOn, "":
+		sampler = sdktrace.AlwaysSample()
+	case SamplerAlwaysOff:
+		sampler = sdktrace.NeverSample()
+	case SamplerRatio:
+		if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+			return nil, fmt.Errorf("invalid sample ratio: %v, must be between 0 and 1", opts.SampleRatio)
+		}
+		sampler = sdktrace.TraceIDRatioBased(opts.SampleRatio)
+	default:
+		return nil, fmt.Errorf("unsupported sampler: %q", opts.Sampler)
+	}
+
+	if opts.ParentBased {
+		sampler = sdktrace.ParentBased(sampler)
+	}
+	return sampler, nil
+}
+
+// newExporter creates the span exporter described by opts.
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, error) {
+	switch opts.Exporter {
+	case ExporterOTLP:
+		if opts.Endpoint == "" {
+			return nil, errors.New("otlp exporter endpoint is not configured")
+		}
+
+		otlpOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint)}
+		if opts.URLPath != "" {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithURLPath(opts.URLPath))
+		}
+		if opts.Insecure {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithInsecure())
+		}
+		if len(opts.Headers) > 0 {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithHeaders(opts.Headers))
+		}
+		if opts.Timeout > 0 {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithTimeout(opts.Timeout))
+		}
+		return otlptracehttp.New(ctx, otlpOpts...)
+	case ExporterStdout:
+		stdoutOpts := []stdouttrace.Option{stdouttrace.WithWriter(os.Stdout)}
+		if opts.PrettyPrint {
+			stdoutOpts = append(stdoutOpts, stdouttrace.WithPrettyPrint())
+		}
+		return stdouttrace.New(stdoutOpts...)
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter: %q", opts.Exporter)
+	}
+}
+
+// batchOptions returns the batch span processor options of opts.
+func batchOptions(opts Options) []sdktrace.BatchSpanProcessorOption {
+	var batchOpts []sdktrace.BatchSpanProcessorOption
+	if opts.MaxQueueSize > 0 {
+		batchOpts = append(batchOpts, sdktrace.WithMaxQueueSize(opts.MaxQueueSize))
+	}
+	if opts.MaxExportBatchSize > 0 {
+		batchOpts = append(batchOpts, sdktrace.WithMaxExportBatchSize(opts.MaxExportBatchSize))
+	}
+	if opts.BatchTimeout > 0 {
+		batchOpts = append(batchOpts, sdktrace.WithBatchTimeout(opts.BatchTimeout))
+	}
+	if opts.Timeout > 0 {
+		batchOpts = append(batchOpts, sdktrace.WithExportTimeout(opts.Timeout))
+	}
+	return batchOpts
+}
+
+// newResource describes the service emitting the spans.
+func newResource(opts Options) (*sdkresource.Resource, error) {
+	serviceName := opts.ServiceName
+	if serviceName == "" {
+		serviceName = "go-gin-project"
+	}
+
+	attrs := []attribute.KeyValue{semconv.ServiceName(serviceName)}
+	if opts.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(opts.ServiceVersion))
+	}
+	if opts.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(opts.Environment))
+	}
+
+	return sdkresource.Merge(
+		sdkresource.Default(),
+		sdkresource.NewWithAttributes(semconv.SchemaURL, attrs...),
+	)
+}
+
+// traceFields extracts the trace and span IDs of the span in ctx for log
+// correlation.
+func traceFields(ctx context.Context) []zap.Field {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", spanCtx.TraceID().String()),
+		zap.String("span_id", spanCtx.SpanID().String()),
+	}
+}
@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"github.com/IBM/sarama"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// setupMemory installs a tracer provider recording spans in memory.
+func setupMemory(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	p, err := Init(context.Background(), Options{Exporter: ExporterMemory})
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+	return p.MemoryExporter()
+}
+
+// TestNewSampler tests the sampler configuration.
+func TestNewSampler(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"default", Options{}, false},
+		{"always on", Options{Sampler: SamplerAlwaysOn}, false},
+		{"always off", Options{Sampler: SamplerAlwaysOff}, false},
+		{"ratio", Options{Sampler: SamplerRatio, SampleRatio: 0.5, ParentBased: true}, false},
+		{"invalid ratio", Options{Sampler: SamplerRatio, SampleRatio: 2}, true},
+		{"unknown", Options{Sampler: "sometimes"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSampler(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSampler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestInitInvalidExporter tests that unknown exporters are rejected.
+func TestInitInvalidExporter(t *testing.T) {
+	if _, err := Init(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Error("expected an error for an unsupported exporter")
+	}
+}
+
+// TestTransport tests that outgoing requests get a client span and carry the
+// trace context.
+func TestTransport(t *testing.T) {
+	exporter := setupMemory(t)
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	res, err := (&http.Client{Transport: NewTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	res.Body.Close()
+	parent.End()
+
+	if traceparent == "" {
+		t.Fatal("expected the traceparent header to be sent")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("client span is not a child of the parent span")
+	}
+}
+
+// TestKafkaPropagation tests that the trace survives a round trip through
+// Kafka record headers.
+func TestKafkaPropagation(t *testing.T) {
+	exporter := setupMemory(t)
+
+	ctx, producerSpan := StartProducerSpan(context.Background(), SystemKafka, "topic")
+	msg := &sarama.ProducerMessage{Topic: "topic"}
+	Inject(ctx, ProducerMessageCarrier{Message: msg})
+	producerSpan.End()
+
+	consumed := &sarama.ConsumerMessage{Topic: "topic"}
+	for i := range msg.Headers {
+		consumed.Headers = append(consumed.Headers, &msg.Headers[i])
+	}
+
+	_, consumerSpan := StartConsumerSpan(context.Background(), ConsumerMessageCarrier{Message: consumed}, SystemKafka, "topic")
+	consumerSpan.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[1].SpanContext.TraceID() != spans[0].SpanContext.TraceID() {
+		t.Error("consumer span does not continue the producer trace")
+	}
+	if spans[1].SpanKind != trace.SpanKindConsumer {
+		t.Errorf("span kind = %v, want consumer", spans[1].SpanKind)
+	}
+}
+
+// TestMapPropagation tests that the trace survives a round trip through a
+// map carrier, as used by the NSQ envelope.
+func TestMapPropagation(t *testing.T) {
+	setupMemory(t)
+
+	ctx, span := Tracer().Start(context.Background(), "parent")
+	defer span.End()
+
+	headers := map[string]string{}
+	Inject(ctx, propagation.MapCarrier(headers))
+
+	got := trace.SpanContextFromContext(Extract(context.Background(), propagation.MapCarrier(headers)))
+	if got.TraceID() != span.SpanContext().TraceID() {
+		t.Error("extracted trace ID does not match")
+	}
+}
+
+// TestRedisHook tests that commands get a span and redis.Nil is not an error.
+func TestRedisHook(t *testing.T) {
+	exporter := setupMemory(t)
+
+	hook := NewRedisHook("127.0.0.1:6379")
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return redis.Nil
+	})
+
+	cmd := redis.NewStringCmd(context.Background(), "get", "missing")
+	if err := process(context.Background(), cmd); err != redis.Nil {
+		t.Fatalf("process() error = %v, want redis.Nil", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "redis get" {
+		t.Fatalf("unexpected spans: %v", spans)
+	}
+	if len(spans[0].Events) != 0 {
+		t.Error("redis.Nil must not be recorded as an error")
+	}
+}
+
+// TestMongoMonitor tests that commands get a span.
+func TestMongoMonitor(t *testing.T) {
+	exporter := setupMemory(t)
+
+	monitor := NewMongoMonitor()
+	command, _ := bson.Marshal(bson.D{{Key: "find", Value: "users"}})
+	monitor.Started(context.Background(), &event.CommandStartedEvent{
+		Command:      command,
+		DatabaseName: "test",
+		CommandName:  "find",
+		RequestID:    1,
+		ConnectionID: "conn",
+	})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, ConnectionID: "conn"},
+		Failure:              "boom",
+	})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "mongodb.find" {
+		t.Fatalf("unexpected spans: %v", spans)
+	}
+	if spans[0].Status.Description != "boom" {
+		t.Errorf("status = %q, want %q", spans[0].Status.Description, "boom")
+	}
+}
+
+// TestLogCorrelation tests that logs written in a span carry its IDs.
+func TestLogCorrelation(t *testing.T) {
+	setupMemory(t)
+
+	core, logs := observer.New(zap.InfoLevel)
+	ctx, span := Tracer().Start(context.Background(), "parent")
+	logger.WithContext(ctx, zap.New(core)).Info("hello")
+	span.End()
+
+	fields := logs.All()[0].ContextMap()
+	if fields["trace_id"] != span.SpanContext().TraceID().String() {
+		t.Errorf("trace_id = %v, want %s", fields["trace_id"], span.SpanContext().TraceID())
+	}
+}
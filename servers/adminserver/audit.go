@@ -0,0 +1,206 @@
+package adminserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/audit"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultAuditPerPage is the page size of GET /admin/audit when none is given.
+	defaultAuditPerPage = 20
+	// defaultAuditQueryMaxLimit is the largest page size when none is configured.
+	defaultAuditQueryMaxLimit = 500
+	// defaultAuditExportMaxRows is the largest export when none is configured.
+	defaultAuditExportMaxRows = 100000
+)
+
+// AuditQueryRequest is the query of GET /admin/audit and GET /admin/audit/export.
+type AuditQueryRequest struct {
+	Actor     string `form:"actor"`      // exact actor
+	Action    string `form:"action"`     // exact action, e.g. auth.login
+	Outcome   string `form:"outcome"`    // success or failure
+	Target    string `form:"target"`     // exact target
+	RequestID string `form:"request_id"` // exact request ID
+	From      string `form:"from"`       // RFC 3339 time, events at or after
+	To        string `form:"to"`         // RFC 3339 time, events before
+	Page      int    `form:"page"`       // page number, starting at 1
+	PerPage   int    `form:"perPage"`    // page size, ignored by the export
+}
+
+// AuditQueryResponse is the body returned by GET /admin/audit.
+type AuditQueryResponse struct {
+	Total   int64         `json:"total"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"perPage"`
+	Events  []audit.Event `json:"events"`
+}
+
+// QueryAudit returns a page of the audit trail, the most recent events
+// first, e.g. GET /admin/audit?action=auth.login&outcome=failure&from=2024-01-01T00:00:00Z.
+func QueryAudit(c *gin.Context) {
+	store := auditStore(c)
+	if store == nil {
+		return
+	}
+
+	filter, req, ok := bindAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	maxLimit := defaultAuditQueryMaxLimit
+	if config.AuditConfig != nil && config.AuditConfig.Audit.QueryMaxLimit > 0 {
+		maxLimit = config.AuditConfig.Audit.QueryMaxLimit
+	}
+	if req.PerPage <= 0 {
+		req.PerPage = defaultAuditPerPage
+	}
+	if req.PerPage > maxLimit {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "perPage"}), resp.RequestID(c))
+		return
+	}
+	filter.Offset = (req.Page - 1) * req.PerPage
+	filter.Limit = req.PerPage
+
+	events, total, err := store.Query(c.Request.Context(), filter)
+	if err != nil {
+		logger.WithContext(c.Request.Context(), resource.LoggerService).Error("Failed to query audit trail", zap.Error(err))
+		resp.AbortWithAppError(c, resp.ErrDependencyFailure.Wrap(err), resp.RequestID(c))
+		return
+	}
+	if events == nil {
+		events = []audit.Event{}
+	}
+
+	resp.NewOKResp(c, AuditQueryResponse{
+		Total:   total,
+		Page:    req.Page,
+		PerPage: req.PerPage,
+		Events:  events,
+	}, resp.RequestID(c))
+}
+
+// ExportAudit returns the events matching the filters of QueryAudit as a
+// CSV file, the most recent events first.
+//
+// The export is rejected if more events than the configured ExportMaxRows
+// match, so that the filters are narrowed instead of returning a partial file.
+func ExportAudit(c *gin.Context) {
+	store := auditStore(c)
+	if store == nil {
+		return
+	}
+
+	filter, _, ok := bindAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	maxRows := defaultAuditExportMaxRows
+	if config.AuditConfig != nil && config.AuditConfig.Audit.ExportMaxRows > 0 {
+		maxRows = config.AuditConfig.Audit.ExportMaxRows
+	}
+	// Read one more event to detect an export that is too large
+	filter.Limit = maxRows + 1
+
+	events, _, err := store.Query(c.Request.Context(), filter)
+	if err != nil {
+		logger.WithContext(c.Request.Context(), resource.LoggerService).Error("Failed to export audit trail", zap.Error(err))
+		resp.AbortWithAppError(c, resp.ErrDependencyFailure.Wrap(err), resp.RequestID(c))
+		return
+	}
+	if len(events) > maxRows {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(map[string]int{"max_rows": maxRows}), resp.RequestID(c))
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.csv", time.Now().UTC().Format("20060102T150405Z"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	if err := audit.WriteCSV(c.Writer, events); err != nil {
+		// The response is already started, only log the failure
+		logger.WithContext(c.Request.Context(), resource.LoggerService).Error("Failed to write audit export", zap.Error(err))
+	}
+}
+
+// VerifyAudit checks the hash chain of the whole audit trail and returns
+// the first invalid event, if any.
+func VerifyAudit(c *gin.Context) {
+	store := auditStore(c)
+	if store == nil {
+		return
+	}
+
+	result, err := store.Verify(c.Request.Context(), 0)
+	if err != nil {
+		logger.WithContext(c.Request.Context(), resource.LoggerService).Error("Failed to verify audit trail", zap.Error(err))
+		resp.AbortWithAppError(c, resp.ErrDependencyFailure.Wrap(err), resp.RequestID(c))
+		return
+	}
+
+	if !result.Valid {
+		logger.WithContext(c.Request.Context(), resource.LoggerService).Error("Audit trail hash chain is broken",
+			zap.Uint64("id", result.Broken.ID),
+			zap.String("reason", result.Broken.Reason),
+		)
+	}
+	resp.NewOKResp(c, result, resp.RequestID(c))
+}
+
+// auditStore returns the audit store, or aborts the request with a 503
+// response and returns nil if the audit trail is not initialized.
+func auditStore(c *gin.Context) *audit.Store {
+	store := resource.AuditStore
+	if store == nil {
+		resp.AbortWithAppError(c, resp.ErrServiceUnavailable, resp.RequestID(c))
+	}
+	return store
+}
+
+// bindAuditFilter binds the audit query of the request, or aborts the
+// request with a 400 response and returns false if it is invalid.
+func bindAuditFilter(c *gin.Context) (audit.Filter, AuditQueryRequest, bool) {
+	var req AuditQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.Wrap(err), resp.RequestID(c))
+		return audit.Filter{}, req, false
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+
+	filter := audit.Filter{
+		Actor:     req.Actor,
+		Action:    req.Action,
+		Outcome:   req.Outcome,
+		Target:    req.Target,
+		RequestID: req.RequestID,
+	}
+
+	var err error
+	if req.From != "" {
+		if filter.From, err = time.Parse(time.RFC3339, req.From); err != nil {
+			resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "from"}).Wrap(err), resp.RequestID(c))
+			return audit.Filter{}, req, false
+		}
+	}
+	if req.To != "" {
+		if filter.To, err = time.Parse(time.RFC3339, req.To); err != nil {
+			resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "to"}).Wrap(err), resp.RequestID(c))
+			return audit.Filter{}, req, false
+		}
+	}
+	return filter, req, true
+}
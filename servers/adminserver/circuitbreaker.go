@@ -0,0 +1,216 @@
+package adminserver
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/circuitbreaker"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxForcedState is the longest forced state accepted with a duration. A
+// breaker can still be forced until it is reset by omitting the duration.
+const maxForcedState = 24 * time.Hour
+
+// eventHeartbeat is the interval of the heartbeat events of the state change
+// stream, which keep idle proxies from closing it.
+const eventHeartbeat = 15 * time.Second
+
+// ForceCircuitBreakerRequest is the optional body of
+// POST /admin/circuitbreakers/open and /close.
+type ForceCircuitBreakerRequest struct {
+	Duration string `json:"duration"` // e.g. "30m", empty to force the state until reset
+}
+
+// ListCircuitBreakers returns the status of every circuit breaker, sorted by
+// name.
+func ListCircuitBreakers(c *gin.Context) {
+	manager, ok := circuitBreakerManager(c)
+	if !ok {
+		return
+	}
+
+	breakers := manager.ListBreakers()
+	statuses := make([]circuitbreaker.Status, 0, len(breakers))
+	for _, cb := range breakers {
+		statuses = append(statuses, cb.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+
+	resp.NewOKResp(c, statuses, resp.RequestID(c))
+}
+
+// GetCircuitBreaker returns the status of the circuit breaker named by the
+// "name" query parameter.
+func GetCircuitBreaker(c *gin.Context) {
+	cb, ok := circuitBreaker(c)
+	if !ok {
+		return
+	}
+	resp.NewOKResp(c, cb.Status(), resp.RequestID(c))
+}
+
+// OpenCircuitBreaker forces the circuit breaker named by the "name" query
+// parameter open, e.g. during the planned maintenance of a dependency.
+//
+// When a duration is given, the breaker half-opens once it expires and
+// probes the dependency, e.g. {"duration": "30m"}. Otherwise it stays open
+// until it is reset.
+func OpenCircuitBreaker(c *gin.Context) {
+	forceCircuitBreaker(c, circuitbreaker.StateOpen)
+}
+
+// CloseCircuitBreaker forces the circuit breaker named by the "name" query
+// parameter closed, letting every request through regardless of the
+// failures.
+//
+// When a duration is given, the breaker resumes tripping once it expires,
+// e.g. {"duration": "10m"}. Otherwise it stays closed until it is reset.
+func CloseCircuitBreaker(c *gin.Context) {
+	forceCircuitBreaker(c, circuitbreaker.StateClosed)
+}
+
+// ResetCircuitBreaker releases the forced state of the circuit breaker named
+// by the "name" query parameter and closes it with cleared counts.
+func ResetCircuitBreaker(c *gin.Context) {
+	cb, ok := circuitBreaker(c)
+	if !ok {
+		return
+	}
+
+	cb.Reset()
+
+	logger.WithContext(c.Request.Context(), resource.LoggerService).Warn("Circuit breaker reset",
+		zap.String("name", cb.Name()),
+		zap.String("client_ip", c.ClientIP()),
+	)
+
+	resp.NewOKResp(c, cb.Status(), resp.RequestID(c))
+}
+
+// StreamCircuitBreakerEvents streams the state changes of the circuit
+// breakers as server-sent events until the client disconnects.
+//
+// Every state change is sent as a "state_change" event whose data is a JSON
+// circuitbreaker.StateChangeEvent. A "heartbeat" event is sent every 15
+// seconds. Events are dropped while the client is too slow to read them.
+func StreamCircuitBreakerEvents(c *gin.Context) {
+	manager, ok := circuitBreakerManager(c)
+	if !ok {
+		return
+	}
+
+	events, unsubscribe := manager.Subscribe()
+	defer unsubscribe()
+
+	// The stream outlives the write timeout of the admin server
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent("state_change", event)
+			return true
+		case now := <-heartbeat.C:
+			c.SSEvent("heartbeat", now.Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// forceCircuitBreaker forces the state of the circuit breaker of the request,
+// for the duration of the optional body.
+func forceCircuitBreaker(c *gin.Context, state circuitbreaker.State) {
+	cb, ok := circuitBreaker(c)
+	if !ok {
+		return
+	}
+
+	var req ForceCircuitBreakerRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			resp.AbortWithAppError(c, resp.ErrInvalidParams.Wrap(err), resp.RequestID(c))
+			return
+		}
+	}
+
+	var until time.Time
+	var d time.Duration
+	if req.Duration != "" {
+		var err error
+		d, err = time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 || d > maxForcedState {
+			resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "duration"}), resp.RequestID(c))
+			return
+		}
+		until = time.Now().Add(d)
+	}
+
+	if err := cb.Force(state, until); err != nil {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.Wrap(err), resp.RequestID(c))
+		return
+	}
+
+	logger.WithContext(c.Request.Context(), resource.LoggerService).Warn("Circuit breaker state forced",
+		zap.String("name", cb.Name()),
+		zap.String("state", state.String()),
+		zap.Duration("duration", d),
+		zap.String("client_ip", c.ClientIP()),
+	)
+
+	resp.NewOKResp(c, cb.Status(), resp.RequestID(c))
+}
+
+// circuitBreaker returns the circuit breaker named by the "name" query
+// parameter, aborting the request if there is none.
+//
+// The name is not a path parameter since the names of the route breakers
+// contain slashes, e.g. "GET:/api/v1/users/:id".
+func circuitBreaker(c *gin.Context) (*circuitbreaker.CircuitBreaker, bool) {
+	manager, ok := circuitBreakerManager(c)
+	if !ok {
+		return nil, false
+	}
+
+	name := c.Query("name")
+	if name == "" {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "name"}), resp.RequestID(c))
+		return nil, false
+	}
+	cb := manager.GetBreaker(name)
+	if cb == nil {
+		resp.AbortWithAppError(c, resp.ErrNotFound.WithDetails(resp.FieldError{Field: "name"}).
+			Wrap(errors.New("unknown circuit breaker "+name)), resp.RequestID(c))
+		return nil, false
+	}
+	return cb, true
+}
+
+// circuitBreakerManager returns the circuit breaker manager, aborting the
+// request if the circuit breakers are disabled.
+func circuitBreakerManager(c *gin.Context) (*circuitbreaker.Manager, bool) {
+	manager := resource.CircuitBreakerManager
+	if manager == nil {
+		resp.AbortWithAppError(c, resp.ErrServiceUnavailable.Wrap(errors.New("circuit breakers are disabled")), resp.RequestID(c))
+		return nil, false
+	}
+	return manager, true
+}
@@ -0,0 +1,136 @@
+package adminserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	"github.com/xiebingnote/go-gin-project/pkg/circuitbreaker"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCircuitBreakerRouter returns the admin router with a circuit breaker
+// manager holding the route breakers "GET:/web/api/x", created by the
+// default route policy, and "*:/api/v1/*/export", declared by a route rule.
+func newCircuitBreakerRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	policy := circuitbreaker.Policy{MaxRequests: 1, Interval: time.Minute, Timeout: time.Minute, ConsecutiveFailures: 5}
+	manager := circuitbreaker.NewManager(nil)
+	manager.Configure(nil, []circuitbreaker.RouteRule{
+		{Method: "*", Pattern: "/api/v1/*/export", Policy: policy},
+	}, &policy)
+	if _, _, ok := manager.RouteBreaker(http.MethodGet, "/web/api/x"); !ok {
+		t.Fatal("RouteBreaker() did not create the default route breaker")
+	}
+
+	previous := resource.CircuitBreakerManager
+	resource.CircuitBreakerManager = manager
+	t.Cleanup(func() { resource.CircuitBreakerManager = previous })
+
+	router := gin.New()
+	Router(router.Group("/admin"))
+	return router
+}
+
+// breakerStatus is the status of a circuit breaker in a response.
+type breakerStatus struct {
+	Name        string     `json:"name"`
+	State       string     `json:"state"`
+	Forced      bool       `json:"forced"`
+	ForcedUntil *time.Time `json:"forced_until"`
+}
+
+// serveCircuitBreaker sends a request for the circuit breaker name to the
+// admin router and returns the response status and the breaker status.
+func serveCircuitBreaker(t *testing.T, router *gin.Engine, method, action, name, body string) (int, breakerStatus) {
+	t.Helper()
+
+	target := "/admin/circuitbreakers/" + action
+	if name != "" {
+		target += "?name=" + url.QueryEscape(name)
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var res struct {
+		Result breakerStatus `json:"result"`
+	}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("failed to decode the response %s: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code, res.Result
+}
+
+// TestCircuitBreakerRouteNames tests that the route breakers, whose names
+// contain slashes, can be read, opened, closed and reset.
+func TestCircuitBreakerRouteNames(t *testing.T) {
+	for _, name := range []string{"GET:/web/api/x", "*:/api/v1/*/export"} {
+		t.Run(name, func(t *testing.T) {
+			router := newCircuitBreakerRouter(t)
+
+			code, status := serveCircuitBreaker(t, router, http.MethodGet, "status", name, "")
+			if code != http.StatusOK || status.Name != name || status.State != circuitbreaker.StateClosed.String() {
+				t.Fatalf("status: code = %d, status = %+v, want a closed %s", code, status, name)
+			}
+
+			code, status = serveCircuitBreaker(t, router, http.MethodPost, "open", name, `{"duration": "30m"}`)
+			if code != http.StatusOK || status.State != circuitbreaker.StateOpen.String() || !status.Forced || status.ForcedUntil == nil {
+				t.Errorf("open: code = %d, status = %+v, want forced open until a time", code, status)
+			}
+
+			code, status = serveCircuitBreaker(t, router, http.MethodPost, "close", name, "")
+			if code != http.StatusOK || status.State != circuitbreaker.StateClosed.String() || !status.Forced || status.ForcedUntil != nil {
+				t.Errorf("close: code = %d, status = %+v, want forced closed until reset", code, status)
+			}
+
+			code, status = serveCircuitBreaker(t, router, http.MethodPost, "reset", name, "")
+			if code != http.StatusOK || status.State != circuitbreaker.StateClosed.String() || status.Forced {
+				t.Errorf("reset: code = %d, status = %+v, want closed and not forced", code, status)
+			}
+		})
+	}
+}
+
+// TestCircuitBreakerInvalidName tests the responses to a missing or unknown
+// circuit breaker name, and to an invalid forced duration.
+func TestCircuitBreakerInvalidName(t *testing.T) {
+	router := newCircuitBreakerRouter(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		action     string
+		breaker    string
+		body       string
+		wantStatus int
+	}{
+		{"missing name", http.MethodGet, "status", "", "", http.StatusBadRequest},
+		{"unknown name", http.MethodPost, "open", "GET:/web/api/unknown", "", http.StatusNotFound},
+		{"path only", http.MethodPost, "reset", "/web/api/x", "", http.StatusNotFound},
+		{"invalid duration", http.MethodPost, "open", "GET:/web/api/x", `{"duration": "forever"}`, http.StatusBadRequest},
+		{"duration too long", http.MethodPost, "close", "GET:/web/api/x", `{"duration": "48h"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := serveCircuitBreaker(t, router, tt.method, tt.action, tt.breaker, tt.body)
+			if code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", code, tt.wantStatus)
+			}
+		})
+	}
+}
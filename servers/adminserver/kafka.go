@@ -0,0 +1,257 @@
+package adminserver
+
+import (
+	"errors"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/kafka"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"github.com/IBM/sarama"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Limits of the dead letters replayed by a request.
+const (
+	defaultReplayLimit = 1000
+	maxReplayLimit     = 10000
+)
+
+// ReplayDeadLettersRequest is the body of
+// POST /admin/kafka/deadletters/:topic/replay.
+type ReplayDeadLettersRequest struct {
+	Group string `json:"group" binding:"required"` // the consumer group whose dead letters are replayed
+	Limit int    `json:"limit"`                    // the maximum number of dead letters replayed, 1000 by default
+}
+
+// ResetOffsetsRequest is the body of
+// POST /admin/kafka/groups/:group/offsets/reset.
+type ResetOffsetsRequest struct {
+	Topic    string `json:"topic" binding:"required"`    // the topic whose offsets are reset
+	Position string `json:"position" binding:"required"` // earliest, latest or an RFC 3339 time
+}
+
+// ReplayDeadLetters sends the dead letters of a consumer group on a topic
+// not replayed yet to the first retry topic of its handler, e.g.
+// {"group": "billing", "limit": 100}, and returns how many were replayed.
+//
+// The dead letters are not sent back to the source topic: all the groups of
+// the topic would process them again, including the groups which processed
+// them successfully. They are sent to the first retry topic instead, which
+// only the failing group consumes. A handler with a dead letter topic but no
+// retry delay has no retry topic, and its replay is rejected with a 400:
+// configure a retry delay to replay its dead letters. The replay resumes
+// where the previous one of the group stopped, so that a dead letter is
+// replayed once.
+func ReplayDeadLetters(c *gin.Context) {
+	if resource.KafkaClient == nil || resource.KafkaProducer == nil {
+		resp.AbortWithAppError(c, resp.ErrServiceUnavailable.Wrap(errors.New("kafka is not initialized")), resp.RequestID(c))
+		return
+	}
+
+	req := ReplayDeadLettersRequest{Limit: defaultReplayLimit}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.Wrap(err), resp.RequestID(c))
+		return
+	}
+	if req.Limit <= 0 || req.Limit > maxReplayLimit {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "limit"}), resp.RequestID(c))
+		return
+	}
+
+	topic := c.Param("topic")
+	result, err := kafka.DefaultRegistry.ReplayDeadLetters(c.Request.Context(), resource.KafkaClient, resource.KafkaProducer,
+		req.Group, topic, req.Limit)
+
+	log := logger.WithContext(c.Request.Context(), resource.LoggerService)
+	switch {
+	case errors.Is(err, sarama.ErrUnknownTopicOrPartition):
+		resp.AbortWithAppError(c, resp.ErrNotFound.WithDetails(resp.FieldError{Field: "topic"}).Wrap(err), resp.RequestID(c))
+		return
+	case errors.Is(err, kafka.ErrHandlerNotFound):
+		resp.AbortWithAppError(c, resp.ErrNotFound.WithDetails(resp.FieldError{Field: "group"}).Wrap(err), resp.RequestID(c))
+		return
+	case errors.Is(err, kafka.ErrNoRetryTopic):
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "group"}).Wrap(err), resp.RequestID(c))
+		return
+	case err != nil:
+		log.Error("Kafka dead letter replay failed",
+			zap.String("group", req.Group),
+			zap.String("topic", topic),
+			zap.Int("replayed", result.Replayed),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Error(err),
+		)
+		resp.AbortWithAppError(c, resp.ErrDependencyFailure.WithDetails(result).Wrap(err), resp.RequestID(c))
+		return
+	}
+
+	log.Warn("Kafka dead letters replayed",
+		zap.String("group", req.Group),
+		zap.String("topic", topic),
+		zap.String("retry_topic", result.RetryTopic),
+		zap.Int("replayed", result.Replayed),
+		zap.String("client_ip", c.ClientIP()),
+	)
+
+	resp.NewOKResp(c, result, resp.RequestID(c))
+}
+
+// ResetOffsets resets the offsets of a consumer group on a topic to a time,
+// e.g. {"topic": "orders", "position": "2025-01-02T15:04:05Z"}, or to the
+// earliest or the latest offsets, and returns the committed offsets.
+//
+// The consumers of the group must be stopped, the reset of an active group
+// is rejected with a conflict. The messages consumed again by an exactly
+// once handler are processed again, see kafka.Registry.ResetOffsets.
+func ResetOffsets(c *gin.Context) {
+	if resource.KafkaClient == nil {
+		resp.AbortWithAppError(c, resp.ErrServiceUnavailable.Wrap(errors.New("kafka is not initialized")), resp.RequestID(c))
+		return
+	}
+
+	var req ResetOffsetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.Wrap(err), resp.RequestID(c))
+		return
+	}
+	position, err := kafka.ParseStartPosition(req.Position)
+	if err != nil {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "position"}).Wrap(err), resp.RequestID(c))
+		return
+	}
+
+	group := c.Param("group")
+	result, err := kafka.DefaultRegistry.ResetOffsets(c.Request.Context(), resource.KafkaClient, group, req.Topic, position)
+
+	log := logger.WithContext(c.Request.Context(), resource.LoggerService)
+	switch {
+	case errors.Is(err, kafka.ErrGroupActive):
+		resp.AbortWithAppError(c, resp.ErrConflict.Wrap(err), resp.RequestID(c))
+		return
+	case errors.Is(err, sarama.ErrUnknownTopicOrPartition):
+		resp.AbortWithAppError(c, resp.ErrNotFound.WithDetails(resp.FieldError{Field: "topic"}).Wrap(err), resp.RequestID(c))
+		return
+	case err != nil:
+		log.Error("Kafka consumer group offsets reset failed",
+			zap.String("group", group),
+			zap.String("topic", req.Topic),
+			zap.String("position", position.String()),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Error(err),
+		)
+		resp.AbortWithAppError(c, resp.ErrDependencyFailure.Wrap(err), resp.RequestID(c))
+		return
+	}
+
+	log.Warn("Kafka consumer group offsets reset",
+		zap.String("group", group),
+		zap.String("topic", req.Topic),
+		zap.String("position", position.String()),
+		zap.Any("offsets", result.Offsets),
+		zap.String("client_ip", c.ClientIP()),
+	)
+
+	resp.NewOKResp(c, result, resp.RequestID(c))
+}
+
+// ListTopics returns the topics of the Kafka cluster sorted by name, with
+// their number of partitions and replication factor.
+func ListTopics(c *gin.Context) {
+	inspector, ok := kafkaInspector(c)
+	if !ok {
+		return
+	}
+
+	topics, err := inspector.Topics()
+	if err != nil {
+		abortKafkaInspection(c, "Kafka topics listing failed", err)
+		return
+	}
+	resp.NewOKResp(c, topics, resp.RequestID(c))
+}
+
+// GetTopic returns a topic of the Kafka cluster with its partitions: their
+// leader, replicas, in-sync replicas and oldest and newest offsets.
+func GetTopic(c *gin.Context) {
+	inspector, ok := kafkaInspector(c)
+	if !ok {
+		return
+	}
+
+	topic, err := inspector.Topic(c.Param("topic"))
+	if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+		resp.AbortWithAppError(c, resp.ErrNotFound.WithDetails(resp.FieldError{Field: "topic"}).Wrap(err), resp.RequestID(c))
+		return
+	}
+	if err != nil {
+		abortKafkaInspection(c, "Kafka topic description failed", err)
+		return
+	}
+	resp.NewOKResp(c, topic, resp.RequestID(c))
+}
+
+// ListConsumerGroups returns the consumer groups of the Kafka cluster
+// sorted by name, with their state and number of members.
+func ListConsumerGroups(c *gin.Context) {
+	inspector, ok := kafkaInspector(c)
+	if !ok {
+		return
+	}
+
+	groups, err := inspector.Groups()
+	if err != nil {
+		abortKafkaInspection(c, "Kafka consumer groups listing failed", err)
+		return
+	}
+	resp.NewOKResp(c, groups, resp.RequestID(c))
+}
+
+// GetConsumerGroup returns a consumer group of the Kafka cluster with its
+// members, the partitions assigned to them, and its lag by topic and
+// partition.
+func GetConsumerGroup(c *gin.Context) {
+	inspector, ok := kafkaInspector(c)
+	if !ok {
+		return
+	}
+
+	group, err := inspector.Group(c.Param("group"))
+	if errors.Is(err, kafka.ErrGroupNotFound) {
+		resp.AbortWithAppError(c, resp.ErrNotFound.WithDetails(resp.FieldError{Field: "group"}).Wrap(err), resp.RequestID(c))
+		return
+	}
+	if err != nil {
+		abortKafkaInspection(c, "Kafka consumer group description failed", err)
+		return
+	}
+	resp.NewOKResp(c, group, resp.RequestID(c))
+}
+
+// kafkaInspector returns the inspector of the Kafka cluster sharing the
+// client of the admin operations, aborting the request if Kafka is not
+// initialized or its controller cannot be found.
+func kafkaInspector(c *gin.Context) (*kafka.Inspector, bool) {
+	if resource.KafkaClient == nil {
+		resp.AbortWithAppError(c, resp.ErrServiceUnavailable.Wrap(errors.New("kafka is not initialized")), resp.RequestID(c))
+		return nil, false
+	}
+	inspector, err := kafka.NewInspector(resource.KafkaClient)
+	if err != nil {
+		abortKafkaInspection(c, "Kafka cluster admin creation failed", err)
+		return nil, false
+	}
+	return inspector, true
+}
+
+// abortKafkaInspection logs the failure of the inspection of the Kafka
+// cluster and aborts the request with a dependency failure.
+func abortKafkaInspection(c *gin.Context, msg string, err error) {
+	logger.WithContext(c.Request.Context(), resource.LoggerService).Error(msg,
+		zap.String("client_ip", c.ClientIP()),
+		zap.Error(err),
+	)
+	resp.AbortWithAppError(c, resp.ErrDependencyFailure.Wrap(err), resp.RequestID(c))
+}
@@ -0,0 +1,82 @@
+package adminserver
+
+import (
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/resource"
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxLevelOverride is the longest temporary level override accepted, so that
+// a forgotten "debug" level cannot flood the disks.
+const maxLevelOverride = 24 * time.Hour
+
+// SetLogLevelRequest is the body of PUT /admin/log/level.
+type SetLogLevelRequest struct {
+	Module   string `json:"module"`                   // module name, empty for the root logger
+	Level    string `json:"level" binding:"required"` // debug, info, warn or error
+	Duration string `json:"duration"`                 // e.g. "10m", empty for a permanent change
+}
+
+// LogLevelResponse is the body returned by the log level endpoints.
+type LogLevelResponse struct {
+	Root    logger.LevelStatus            `json:"root"`
+	Modules map[string]logger.LevelStatus `json:"modules"`
+}
+
+// GetLogLevel returns the current level of the root logger and of every
+// module logger.
+func GetLogLevel(c *gin.Context) {
+	resp.NewOKResp(c, logLevelResponse(), resp.RequestID(c))
+}
+
+// SetLogLevel changes the level of the root logger or of a module logger.
+//
+// When a duration is given, the level reverts automatically once it
+// expires, e.g. {"module": "kafka", "level": "debug", "duration": "10m"}.
+func SetLogLevel(c *gin.Context) {
+	var req SetLogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.Wrap(err), resp.RequestID(c))
+		return
+	}
+
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "level"}).Wrap(err), resp.RequestID(c))
+		return
+	}
+
+	var d time.Duration
+	if req.Duration != "" {
+		d, err = time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 || d > maxLevelOverride {
+			resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "duration"}), resp.RequestID(c))
+			return
+		}
+	}
+
+	if err := logger.DefaultLevels.SetLevelFor(req.Module, level, d); err != nil {
+		resp.AbortWithAppError(c, resp.ErrInvalidParams.WithDetails(resp.FieldError{Field: "module"}).Wrap(err), resp.RequestID(c))
+		return
+	}
+
+	logger.WithContext(c.Request.Context(), resource.LoggerService).Warn("Log level changed",
+		zap.String("module", req.Module),
+		zap.String("level", level.String()),
+		zap.Duration("duration", d),
+		zap.String("client_ip", c.ClientIP()),
+	)
+
+	resp.NewOKResp(c, logLevelResponse(), resp.RequestID(c))
+}
+
+// logLevelResponse returns the current log levels.
+func logLevelResponse() LogLevelResponse {
+	root, modules := logger.DefaultLevels.Status()
+	return LogLevelResponse{Root: root, Modules: modules}
+}
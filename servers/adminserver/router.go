@@ -0,0 +1,52 @@
+package adminserver
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Router registers the admin endpoints under r, e.g. the "/admin" group of
+// the admin server.
+//
+// The following endpoints are registered:
+//   - GET /log/level: the current root and module log levels.
+//   - PUT /log/level: changes a log level, optionally for a limited time.
+//   - GET /audit: a page of the audit trail, filtered by actor, action, outcome, target, request ID and time.
+//   - GET /audit/export: the filtered audit trail as a CSV file.
+//   - GET /audit/verify: checks the hash chain of the audit trail.
+//   - GET /circuitbreakers: the status of every circuit breaker.
+//   - GET /circuitbreakers/events: the state changes of the circuit breakers as server-sent events.
+//   - GET /circuitbreakers/status?name=: the status of a circuit breaker.
+//   - POST /circuitbreakers/open?name=: forces a circuit breaker open, optionally for a limited time.
+//   - POST /circuitbreakers/close?name=: forces a circuit breaker closed, optionally for a limited time.
+//   - POST /circuitbreakers/reset?name=: releases the forced state of a circuit breaker and clears its counts.
+//   - GET /kafka/topics: the topics of the Kafka cluster.
+//   - GET /kafka/topics/:topic: the partitions of a topic, with their replicas and offsets.
+//   - GET /kafka/groups: the consumer groups of the Kafka cluster.
+//   - GET /kafka/groups/:group: the members of a consumer group, their partitions and the lag of the group.
+//   - POST /kafka/deadletters/:topic/replay: sends the dead letters of a consumer group on a topic to its first retry topic, not to the topic itself.
+//   - POST /kafka/groups/:group/offsets/reset: resets the offsets of a stopped consumer group to a time.
+//
+// The circuit breakers are named by a query parameter since the names of the
+// route breakers contain slashes, e.g. "GET:/api/v1/users/:id".
+func Router(r *gin.RouterGroup) {
+	r.GET("/log/level", GetLogLevel)
+	r.PUT("/log/level", SetLogLevel)
+
+	r.GET("/audit", QueryAudit)
+	r.GET("/audit/export", ExportAudit)
+	r.GET("/audit/verify", VerifyAudit)
+
+	r.GET("/circuitbreakers", ListCircuitBreakers)
+	r.GET("/circuitbreakers/events", StreamCircuitBreakerEvents)
+	r.GET("/circuitbreakers/status", GetCircuitBreaker)
+	r.POST("/circuitbreakers/open", OpenCircuitBreaker)
+	r.POST("/circuitbreakers/close", CloseCircuitBreaker)
+	r.POST("/circuitbreakers/reset", ResetCircuitBreaker)
+
+	r.GET("/kafka/topics", ListTopics)
+	r.GET("/kafka/topics/:topic", GetTopic)
+	r.GET("/kafka/groups", ListConsumerGroups)
+	r.GET("/kafka/groups/:group", GetConsumerGroup)
+	r.POST("/kafka/deadletters/:topic/replay", ReplayDeadLetters)
+	r.POST("/kafka/groups/:group/offsets/reset", ResetOffsets)
+}
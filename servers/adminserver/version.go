@@ -0,0 +1,28 @@
+package adminserver
+
+import (
+	"time"
+
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/buildinfo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionResponse is the body returned by GET /version.
+type VersionResponse struct {
+	buildinfo.Info
+	StartTime     string  `json:"start_time"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// GetVersion returns the build information of the running binary and its
+// uptime, e.g. to check which version a deployment runs.
+func GetVersion(c *gin.Context) {
+	start := buildinfo.StartTime()
+	resp.NewOKResp(c, VersionResponse{
+		Info:          buildinfo.Get(),
+		StartTime:     start.Format(time.RFC3339),
+		UptimeSeconds: time.Since(start).Seconds(),
+	}, resp.RequestID(c))
+}
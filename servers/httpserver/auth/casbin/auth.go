@@ -1,19 +1,61 @@
 package casbin
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
-	"net/http"
+	"strings"
 
 	"github.com/xiebingnote/go-gin-project/library/middleware"
 	"github.com/xiebingnote/go-gin-project/library/resource"
 	resp "github.com/xiebingnote/go-gin-project/library/response"
 	"github.com/xiebingnote/go-gin-project/model/types"
+	"github.com/xiebingnote/go-gin-project/pkg/audit"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+// requiredFieldError returns the error for a missing username or password.
+//
+// Parameters:
+//   - username: The username from the request, used to tell which field is missing.
+//
+// Returns:
+//   - *resp.AppError: The error with the missing field in its details.
+func requiredFieldError(username string) *resp.AppError {
+	if username == "" {
+		return resp.ErrUsernameRequired.WithDetails(resp.FieldError{Field: "username"})
+	}
+	return resp.ErrPasswordRequired.WithDetails(resp.FieldError{Field: "password"})
+}
+
+// recordAuthEvent records a registration or a login in the audit trail.
+//
+// Parameters:
+//   - c: The Gin context of the request.
+//   - action: The audit action, audit.ActionRegister or audit.ActionLogin.
+//   - username: The username of the user.
+//   - reason: Why the event failed, empty if it succeeded.
+//   - details: The details of the event, may be nil.
+func recordAuthEvent(c *gin.Context, action, username, reason string, details interface{}) {
+	e := audit.Event{
+		Actor:   username,
+		Action:  action,
+		Target:  username,
+		Outcome: audit.OutcomeSuccess,
+		Reason:  reason,
+	}
+	if reason != "" {
+		e.Outcome = audit.OutcomeFailure
+	}
+	_ = e.SetDetails(details)
+	middleware.RecordAudit(c, e)
+}
+
 // Register handles user registration by accepting a JSON request with a username, password, and role,
 // hashing the password, and storing the user data in the database. It validates the incoming request
 // and ensures the username is unique. If any step fails, it returns an appropriate error response.
@@ -31,7 +73,7 @@ import (
 //   - Aborts with a 409 Conflict if the username already exists.
 //   - Responds with a 201 Created and a success message upon successful registration.
 func Register(c *gin.Context) {
-	reqID := uuid.NewString()
+	reqID := resp.RequestID(c)
 	var req struct {
 		Username string `json:"username"` // Username chosen by the user
 		Password string `json:"password"` // Password chosen by the user
@@ -40,22 +82,26 @@ func Register(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// Return an error response if the request body is invalid
-		resource.LoggerService.Error(fmt.Sprintf("Invalid request: %v", err))
-		resp.NewErrResp(c, http.StatusBadRequest, "Invalid request", reqID)
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error(fmt.Sprintf("Invalid request: %v", err))
+		recordAuthEvent(c, audit.ActionRegister, "", "invalid request", nil)
+		resp.NewAppErrResp(c, resp.ErrBadRequest, reqID)
 		return
 	}
 
 	if req.Username == "" || req.Password == "" {
 		// Return an error response if the username or password is empty
-		resource.LoggerService.Error(fmt.Sprintf("Registration failed: Username and password are required"))
-		resp.NewErrResp(c, http.StatusBadRequest, "Registration failed: Username and password are required", reqID)
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error("Registration failed: Username and password are required")
+		recordAuthEvent(c, audit.ActionRegister, req.Username, "username and password are required", nil)
+		resp.NewAppErrResp(c, requiredFieldError(req.Username), reqID)
+		return
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		// Return an error response if password hashing fails
-		resource.LoggerService.Error(fmt.Sprintf("Registration failed: Unable to hash password"))
-		resp.NewErrResp(c, http.StatusInternalServerError, "Registration failed: Unable to hash password", reqID)
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error("Registration failed: Unable to hash password")
+		recordAuthEvent(c, audit.ActionRegister, req.Username, "unable to hash password", nil)
+		resp.NewAppErrResp(c, resp.ErrInternal, reqID)
 		return
 	}
 
@@ -65,16 +111,36 @@ func Register(c *gin.Context) {
 		Role:     req.Role,
 	}
 
-	// Insert the user into the database
-	if result := resource.MySQLClient.Table("tb_user").Create(&user); result.Error != nil {
+	// Insert the user into the database, once: the insert is not idempotent.
+	// A duplicate username is an answer of a healthy database, not a failure
+	// counted by the circuit breaker.
+	exists, err := resilience.DoWrite(c.Request.Context(), resource.Resilience.Get("mysql"), func(ctx context.Context) (bool, error) {
+		err := resource.MySQLClient.WithContext(ctx).Table("tb_user").Create(&user).Error
+		if err != nil && strings.Contains(err.Error(), "Duplicate entry") {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		// Return an error response if the database is unavailable
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error(fmt.Sprintf("Registration failed: %v", err))
+		recordAuthEvent(c, audit.ActionRegister, req.Username, "database error", nil)
+		resp.NewAppErrResp(c, resp.ErrDependencyFailure.Wrap(err), reqID)
+		return
+	}
+	if exists {
 		// Return an error response if the username already exists
-		resource.LoggerService.Error(fmt.Sprintf("Registration failed: Username already exists"))
-		resp.NewErrResp(c, http.StatusConflict, "Registration failed: Username already exists", reqID)
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error("Registration failed: Username already exists")
+		recordAuthEvent(c, audit.ActionRegister, req.Username, "username already exists", nil)
+		resp.NewAppErrResp(c, resp.ErrUserExists, reqID)
 		return
 	}
 
+	// The role is granted by the registration
+	recordAuthEvent(c, audit.ActionRegister, req.Username, "", map[string]string{"role": req.Role})
+
 	// Return a success response
-	resp.NewOKResp(c, "User created", reqID)
+	resp.NewOKResp(c, resp.LocalizeContext(c, "auth.register_success"), reqID)
 }
 
 // Login authenticates a user by validating the provided username and password,
@@ -93,7 +159,7 @@ func Register(c *gin.Context) {
 //   - Returns a 401 Unauthorized if the username or password is incorrect.
 //   - Returns a 500 Internal Server Error if token generation fails.
 func Login(c *gin.Context) {
-	reqID := uuid.NewString()
+	reqID := resp.RequestID(c)
 
 	// Define a struct to bind the incoming JSON request
 	var req struct {
@@ -104,32 +170,47 @@ func Login(c *gin.Context) {
 	// Bind the incoming JSON request to the struct and validate it
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// Return an error response if the request body is invalid
-		resource.LoggerService.Error(fmt.Sprintf("Invalid request: %v", err))
-		resp.NewErrResp(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), reqID)
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error(fmt.Sprintf("Invalid request: %v", err))
+		recordAuthEvent(c, audit.ActionLogin, "", "invalid request", nil)
+		resp.NewAppErrResp(c, resp.ErrBadRequest, reqID)
 		return
 	}
 
 	if req.Username == "" || req.Password == "" {
 		// Return an error response if the username or password is empty
-		resource.LoggerService.Error(fmt.Sprintf("Login failed: Username and password are required"))
-		resp.NewErrResp(c, http.StatusBadRequest, "Login failed: Username and password are required", reqID)
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error("Login failed: Username and password are required")
+		recordAuthEvent(c, audit.ActionLogin, req.Username, "username and password are required", nil)
+		resp.NewAppErrResp(c, requiredFieldError(req.Username), reqID)
 		return
 	}
 
 	// Query the user from the database
-	var user types.TbUser
-	if result := resource.MySQLClient.Table("tb_user").Where("username = ?", req.Username).First(&user); result.Error != nil {
+	user, err := resilience.Do(c.Request.Context(), resource.Resilience.Get("mysql"), func(ctx context.Context) (types.TbUser, error) {
+		var user types.TbUser
+		err := resource.MySQLClient.WithContext(ctx).Table("tb_user").Where("username = ?", req.Username).First(&user).Error
+		return user, err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		// Return an error response if the user does not exist
-		resource.LoggerService.Error(fmt.Sprintf("Login failed: Invalid credentials"))
-		resp.NewErrResp(c, http.StatusUnauthorized, "Invalid credentials", reqID)
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error("Login failed: Invalid credentials")
+		recordAuthEvent(c, audit.ActionLogin, req.Username, "user not found", nil)
+		resp.NewAppErrResp(c, resp.ErrInvalidCredentials, reqID)
+		return
+	}
+	if err != nil {
+		// Return an error response if the database is unavailable
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error(fmt.Sprintf("Login failed: %v", err))
+		recordAuthEvent(c, audit.ActionLogin, req.Username, "database error", nil)
+		resp.NewAppErrResp(c, resp.ErrDependencyFailure.Wrap(err), reqID)
 		return
 	}
 
 	// Verify the password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		// Return an error response if the password is incorrect
-		resource.LoggerService.Error(fmt.Sprintf("Login failed: Invalid credentials"))
-		resp.NewErrResp(c, http.StatusUnauthorized, "Invalid credentials", reqID)
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error("Login failed: Invalid credentials")
+		recordAuthEvent(c, audit.ActionLogin, req.Username, "wrong password", nil)
+		resp.NewAppErrResp(c, resp.ErrInvalidCredentials, reqID)
 		return
 	}
 
@@ -137,11 +218,14 @@ func Login(c *gin.Context) {
 	token, err := middleware.GenerateTokenCasbin(user.ID, user.Role)
 	if err != nil {
 		// Return an error response if JWT token generation fails
-		resource.LoggerService.Error(fmt.Sprintf("Login failed: %v", err))
-		resp.NewErrResp(c, http.StatusInternalServerError, err.Error(), reqID)
+		logger.WithContext(c.Request.Context(), resource.AuthLogger).Error(fmt.Sprintf("Login failed: %v", err))
+		recordAuthEvent(c, audit.ActionLogin, req.Username, "unable to generate token", nil)
+		resp.NewAppErrResp(c, resp.ErrInternal, reqID)
 		return
 	}
 
+	recordAuthEvent(c, audit.ActionLogin, req.Username, "", map[string]string{"role": user.Role})
+
 	// Return the JWT token as a success response
 	resp.NewOKResp(c, token, reqID)
 }
@@ -1,9 +1,9 @@
 package jwt
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"net/http"
 	"regexp"
 	"strings"
 	"time"
@@ -13,10 +13,13 @@ import (
 	"github.com/xiebingnote/go-gin-project/library/resource"
 	resp "github.com/xiebingnote/go-gin-project/library/response"
 	"github.com/xiebingnote/go-gin-project/model/types"
+	"github.com/xiebingnote/go-gin-project/pkg/audit"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
+	"github.com/xiebingnote/go-gin-project/pkg/resilience"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 const (
@@ -25,20 +28,6 @@ const (
 
 	MinPasswordLength = 8
 	MaxPasswordLength = 128
-
-	ErrUsernameRequired   = "用户名不能为空"
-	ErrPasswordRequired   = "密码不能为空"
-	ErrUsernameLength     = "用户名长度必须在3-20个字符之间"
-	ErrUsernameFormat     = "用户名只能包含字母、数字和下划线"
-	ErrPasswordLength     = "密码长度至少8个字符"
-	ErrPasswordUppercase  = "密码必须包含至少一个大写字母"
-	ErrPasswordLowercase  = "密码必须包含至少一个小写字母"
-	ErrPasswordDigit      = "密码必须包含至少一个数字"
-	ErrPasswordSpecial    = "密码必须包含至少一个特殊字符"
-	ErrInvalidCredentials = "用户名或密码错误"
-	ErrUserExists         = "用户名已存在"
-	ErrInternalError      = "服务器内部错误"
-	ErrInvalidRequest     = "请求格式错误"
 )
 
 var (
@@ -46,15 +35,10 @@ var (
 	usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
 )
 
-// ValidationError represents a validation error with a field and a message.
-type ValidationError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
-}
-
-// ValidationError implements the error interface.
-func (e ValidationError) Error() string {
-	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+// fieldError returns a copy of appErr carrying the name of the invalid field
+// in its details.
+func fieldError(appErr *resp.AppError, field string) *resp.AppError {
+	return appErr.WithDetails(resp.FieldError{Field: field})
 }
 
 // validateUsername checks if the provided username meets the requirements.
@@ -71,18 +55,12 @@ func (e ValidationError) Error() string {
 func validateUsername(username string) error {
 	// Check if the length is within the required range
 	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
-		return ValidationError{
-			Field:   "username",
-			Message: ErrUsernameLength,
-		}
+		return fieldError(resp.ErrUsernameLength.WithArgs(MinUsernameLength, MaxUsernameLength), "username")
 	}
 
 	// Check if the username matches the allowed format
 	if !usernameRegex.MatchString(username) {
-		return ValidationError{
-			Field:   "username",
-			Message: ErrUsernameFormat,
-		}
+		return fieldError(resp.ErrUsernameFormat, "username")
 	}
 
 	return nil
@@ -105,17 +83,11 @@ func validateUsername(username string) error {
 func validatePassword(password string) error {
 	// Check the password length
 	if len(password) < MinPasswordLength {
-		return ValidationError{
-			Field:   "password",
-			Message: ErrPasswordLength,
-		}
+		return fieldError(resp.ErrPasswordLength.WithArgs(MinPasswordLength), "password")
 	}
 
 	if len(password) > MaxPasswordLength {
-		return ValidationError{
-			Field:   "password",
-			Message: fmt.Sprintf("密码长度不能超过%d个字符", MaxPasswordLength),
-		}
+		return fieldError(resp.ErrPasswordTooLong.WithArgs(MaxPasswordLength), "password")
 	}
 
 	var (
@@ -141,31 +113,19 @@ func validatePassword(password string) error {
 
 	// Check for character type requirements
 	if !hasUpper {
-		return ValidationError{
-			Field:   "password",
-			Message: ErrPasswordUppercase,
-		}
+		return fieldError(resp.ErrPasswordUppercase, "password")
 	}
 
 	if !hasLower {
-		return ValidationError{
-			Field:   "password",
-			Message: ErrPasswordLowercase,
-		}
+		return fieldError(resp.ErrPasswordLowercase, "password")
 	}
 
 	if !hasDigit {
-		return ValidationError{
-			Field:   "password",
-			Message: ErrPasswordDigit,
-		}
+		return fieldError(resp.ErrPasswordDigit, "password")
 	}
 
 	if !hasSpecial {
-		return ValidationError{
-			Field:   "password",
-			Message: ErrPasswordSpecial,
-		}
+		return fieldError(resp.ErrPasswordSpecial, "password")
 	}
 
 	return nil
@@ -199,17 +159,11 @@ type LoginRequest struct {
 func validateRequest(username, password string) error {
 	// Check for non-empty username and password
 	if strings.TrimSpace(username) == "" {
-		return ValidationError{
-			Field:   "username",
-			Message: ErrUsernameRequired,
-		}
+		return fieldError(resp.ErrUsernameRequired, "username")
 	}
 
 	if strings.TrimSpace(password) == "" {
-		return ValidationError{
-			Field:   "password",
-			Message: ErrPasswordRequired,
-		}
+		return fieldError(resp.ErrPasswordRequired, "password")
 	}
 
 	// Validate username and password
@@ -224,45 +178,63 @@ func validateRequest(username, password string) error {
 	return nil
 }
 
-// logAuthEvent 记录认证事件日志
+// logAuthEvent 记录认证事件日志和审计日志
 //
 // Parameters:
-//   - reqID: The request ID.
-//   - event: The event name (e.g. "register", "login").
+//   - c: The Gin context of the request, carrying the request ID.
+//   - event: The audit action of the event (e.g. audit.ActionRegister, audit.ActionLogin).
 //   - username: The username of the user.
 //   - success: Whether the event was successful or not.
 //   - err: The error that occurred (if any).
 //
 // Logs the event with the appropriate log level (INFO for success, ERROR for failure).
-// The log message will include the request ID, event name, username, and success indicator.
-// If an error occurred, the log message will also include the error details.
-func logAuthEvent(reqID, event, username string, success bool, err error) {
-	logMsg := fmt.Sprintf("[%s] %s - ", reqID, event)
+// The log entry carries the request ID of the request, and the message includes the event name,
+// username, and success indicator. If an error occurred, the log message will also
+// include the error details. The event is also recorded in the audit trail with the
+// client IP and user agent of the request.
+func logAuthEvent(c *gin.Context, event, username string, success bool, err error) {
+	logMsg := fmt.Sprintf("%s - ", event)
 	logMsg += fmt.Sprintf("用户: %s, ", username)
 	logMsg += fmt.Sprintf("成功: %t", success)
 	if err != nil {
 		logMsg += fmt.Sprintf(", 错误: %v", err)
 	}
 
+	log := logger.WithContext(c.Request.Context(), resource.AuthLogger)
 	if success {
-		resource.LoggerService.Info(logMsg)
+		log.Info(logMsg)
 	} else {
-		resource.LoggerService.Error(logMsg)
+		log.Error(logMsg)
 	}
+
+	e := audit.Event{
+		Actor:   username,
+		Action:  event,
+		Target:  username,
+		Outcome: audit.OutcomeSuccess,
+	}
+	if !success {
+		e.Outcome = audit.OutcomeFailure
+	}
+	if err != nil {
+		e.Reason = err.Error()
+	}
+	middleware.RecordAudit(c, e)
 }
 
 // handleValidationError handles a validation error by logging the error and returning a 400 Bad Request response.
 //
-// If the error is a ValidationError, it logs the error with the appropriate message and returns a 400 Bad Request response with the error message.
-// If the error is not a ValidationError, it logs the error with the appropriate message and returns a 400 Bad Request response with the error message.
+// If the error is an AppError, the response carries its code, the localised message and the invalid field.
+// Any other error is returned as a generic invalid parameters error.
 func handleValidationError(c *gin.Context, reqID string, err error) {
-	// If the error is a ValidationError, log the error with the appropriate message
-	var validationErr ValidationError
-	if errors.As(err, &validationErr) {
-		resource.LoggerService.Error(fmt.Sprintf("[%s] 验证失败: %s", reqID, validationErr.Message))
-		// Return a 400 Bad Request response with the error message
-		resp.NewErrResp(c, http.StatusBadRequest, validationErr.Message, reqID)
+	var appErr *resp.AppError
+	if !errors.As(err, &appErr) {
+		appErr = resp.ErrInvalidParams.Wrap(err)
 	}
+
+	logger.WithContext(c.Request.Context(), resource.AuthLogger).Error(fmt.Sprintf("验证失败: %v", appErr))
+	// Return a 400 Bad Request response with the localised error message
+	resp.NewAppErrResp(c, appErr, reqID)
 }
 
 // Register handles user registration by validating the incoming request data,
@@ -287,20 +259,20 @@ func handleValidationError(c *gin.Context, reqID string, err error) {
 //   - Aborts with a 409 Conflict if the username already exists.
 //   - Responds with a 201 Created and the user ID upon successful registration.
 func Register(c *gin.Context) {
-	reqID := uuid.NewString()
+	reqID := resp.RequestID(c)
 	startTime := time.Now()
 
 	// Extract and validate the request data
 	var req RegisterRequest
 	if err := c.ShouldBind(&req); err != nil {
-		logAuthEvent(reqID, "register", "", false, err)
-		resp.NewErrResp(c, http.StatusBadRequest, ErrInvalidRequest, reqID)
+		logAuthEvent(c, audit.ActionRegister, "", false, err)
+		resp.NewAppErrResp(c, resp.ErrBadRequest, reqID)
 		return
 	}
 
 	// Validate the request data
 	if err := validateRequest(req.Username, req.Password); err != nil {
-		logAuthEvent(reqID, "register", req.Username, false, err)
+		logAuthEvent(c, audit.ActionRegister, req.Username, false, err)
 		handleValidationError(c, reqID, err)
 		return
 	}
@@ -308,8 +280,8 @@ func Register(c *gin.Context) {
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		logAuthEvent(reqID, "register", req.Username, false, err)
-		resp.NewErrResp(c, http.StatusInternalServerError, ErrInternalError, reqID)
+		logAuthEvent(c, audit.ActionRegister, req.Username, false, err)
+		resp.NewAppErrResp(c, resp.ErrInternal, reqID)
 		return
 	}
 
@@ -319,28 +291,36 @@ func Register(c *gin.Context) {
 		Password: string(hashedPassword),
 	}
 
-	// Insert the user into the database
-	if result := resource.MySQLClient.Table("tb_user").Create(&user); result.Error != nil {
-		// Check for duplicate entry error
-		if strings.Contains(result.Error.Error(), "Duplicate entry") {
-			logAuthEvent(reqID, "register", req.Username, false, fmt.Errorf("用户名已存在"))
-			resp.NewErrResp(c, http.StatusConflict, ErrUserExists, reqID)
-			return
+	// Insert the user into the database, once: the insert is not idempotent.
+	// A duplicate username is an answer of a healthy database, not a failure
+	// counted by the circuit breaker.
+	exists, err := resilience.DoWrite(c.Request.Context(), resource.Resilience.Get("mysql"), func(ctx context.Context) (bool, error) {
+		err := resource.MySQLClient.WithContext(ctx).Table("tb_user").Create(&user).Error
+		if err != nil && strings.Contains(err.Error(), "Duplicate entry") {
+			return true, nil
 		}
-		// Handle other database errors
-		logAuthEvent(reqID, "register", req.Username, false, result.Error)
-		resp.NewErrResp(c, http.StatusInternalServerError, ErrInternalError, reqID)
+		return false, err
+	})
+	if err != nil {
+		// Handle the database errors and the rejections of the policy
+		logAuthEvent(c, audit.ActionRegister, req.Username, false, err)
+		resp.NewAppErrResp(c, resp.ErrDependencyFailure.Wrap(err), reqID)
+		return
+	}
+	if exists {
+		logAuthEvent(c, audit.ActionRegister, req.Username, false, fmt.Errorf("用户名已存在"))
+		resp.NewAppErrResp(c, resp.ErrUserExists, reqID)
 		return
 	}
 
 	// Log successful registration
 	duration := time.Since(startTime)
-	logAuthEvent(reqID, "register", req.Username, true, nil)
-	resource.LoggerService.Info(fmt.Sprintf("[%s] 用户注册成功，耗时: %v", reqID, duration))
+	logAuthEvent(c, audit.ActionRegister, req.Username, true, nil)
+	logger.WithContext(c.Request.Context(), resource.AuthLogger).Info(fmt.Sprintf("用户注册成功，耗时: %v", duration))
 
 	// Return successful response
 	resp.NewOKResp(c, gin.H{
-		"message": "用户创建成功",
+		"message": resp.LocalizeContext(c, "auth.register_success"),
 		"user_id": user.ID,
 	}, reqID)
 }
@@ -361,63 +341,73 @@ func Register(c *gin.Context) {
 //   - Returns a 401 Unauthorized if the username or password is incorrect.
 //   - Returns a 500 Internal Server Error if token generation fails.
 func Login(c *gin.Context) {
-	reqID := uuid.NewString()
+	reqID := resp.RequestID(c)
 	startTime := time.Now()
 
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logAuthEvent(reqID, "登录", "", false, err)
-		resp.NewErrResp(c, http.StatusBadRequest, ErrInvalidRequest, reqID)
+		logAuthEvent(c, audit.ActionLogin, "", false, err)
+		resp.NewAppErrResp(c, resp.ErrBadRequest, reqID)
 		return
 	}
 
 	// Basic validation (excluding password complexity check for login)
 	if strings.TrimSpace(req.Username) == "" {
-		logAuthEvent(reqID, "登录", "", false, fmt.Errorf("用户名为空"))
-		resp.NewErrResp(c, http.StatusBadRequest, ErrUsernameRequired, reqID)
+		logAuthEvent(c, audit.ActionLogin, "", false, fmt.Errorf("用户名为空"))
+		resp.NewAppErrResp(c, fieldError(resp.ErrUsernameRequired, "username"), reqID)
 		return
 	}
 
 	if strings.TrimSpace(req.Password) == "" {
-		logAuthEvent(reqID, "登录", req.Username, false, fmt.Errorf("密码为空"))
-		resp.NewErrResp(c, http.StatusBadRequest, ErrPasswordRequired, reqID)
+		logAuthEvent(c, audit.ActionLogin, req.Username, false, fmt.Errorf("密码为空"))
+		resp.NewAppErrResp(c, fieldError(resp.ErrPasswordRequired, "password"), reqID)
 		return
 	}
 
 	// Query the user from the database
-	var user types.TbUser
-	if result := resource.MySQLClient.Table("tb_user").Where("username = ?", req.Username).First(&user); result.Error != nil {
+	user, err := resilience.Do(c.Request.Context(), resource.Resilience.Get("mysql"), func(ctx context.Context) (types.TbUser, error) {
+		var user types.TbUser
+		err := resource.MySQLClient.WithContext(ctx).Table("tb_user").Where("username = ?", req.Username).First(&user).Error
+		return user, err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		// Return a uniform error message to prevent username enumeration attacks
-		logAuthEvent(reqID, "登录", req.Username, false, fmt.Errorf("用户不存在"))
-		resp.NewErrResp(c, http.StatusUnauthorized, ErrInvalidCredentials, reqID)
+		logAuthEvent(c, audit.ActionLogin, req.Username, false, fmt.Errorf("用户不存在"))
+		resp.NewAppErrResp(c, resp.ErrInvalidCredentials, reqID)
+		return
+	}
+	if err != nil {
+		// An unavailable database is not a wrong password
+		logAuthEvent(c, audit.ActionLogin, req.Username, false, err)
+		resp.NewAppErrResp(c, resp.ErrDependencyFailure.Wrap(err), reqID)
 		return
 	}
 
 	// Verify the password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		logAuthEvent(reqID, "登录", req.Username, false, fmt.Errorf("密码错误"))
-		resp.NewErrResp(c, http.StatusUnauthorized, ErrInvalidCredentials, reqID)
+		logAuthEvent(c, audit.ActionLogin, req.Username, false, fmt.Errorf("密码错误"))
+		resp.NewAppErrResp(c, resp.ErrInvalidCredentials, reqID)
 		return
 	}
 
 	// Generate a JWT token for the authenticated user
 	token, err := middleware.GenerateTokenJWT(user.ID)
 	if err != nil {
-		logAuthEvent(reqID, "登录", req.Username, false, err)
-		resp.NewErrResp(c, http.StatusInternalServerError, ErrInternalError, reqID)
+		logAuthEvent(c, audit.ActionLogin, req.Username, false, err)
+		resp.NewAppErrResp(c, resp.ErrInternal, reqID)
 		return
 	}
 
 	// Log successful login
 	duration := time.Since(startTime)
-	logAuthEvent(reqID, "登录", req.Username, true, nil)
-	resource.LoggerService.Info(fmt.Sprintf("[%s] 用户登录成功，耗时: %v", reqID, duration))
+	logAuthEvent(c, audit.ActionLogin, req.Username, true, nil)
+	logger.WithContext(c.Request.Context(), resource.AuthLogger).Info(fmt.Sprintf("用户登录成功，耗时: %v", duration))
 
 	// Return a successful response containing the JWT token
 	resp.NewOKResp(c, gin.H{
 		"token":    token,
 		"user_id":  user.ID,
 		"username": user.Username,
-		"message":  "登录成功",
+		"message":  resp.LocalizeContext(c, "auth.login_success"),
 	}, reqID)
 }
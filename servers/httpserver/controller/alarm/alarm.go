@@ -5,16 +5,16 @@ import (
 
 	"github.com/xiebingnote/go-gin-project/library/resource"
 	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 func List(c *gin.Context) {
-	reqID := uuid.NewString()
+	reqID := resp.RequestID(c)
 	//todo
 
-	resource.LoggerService.Info("Application started", zap.Int("pid", os.Getpid()))
+	logger.WithContext(c.Request.Context(), resource.LoggerService).Info("Application started", zap.Int("pid", os.Getpid()))
 	resp.NewOKResp(c, "test", reqID)
 }
@@ -1,12 +1,18 @@
 package httpserver
 
 import (
+	"context"
 	"fmt"
-	"net/http"
+	"strings"
+	"time"
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/middleware"
 	"github.com/xiebingnote/go-gin-project/library/resource"
+	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/audit"
+	"github.com/xiebingnote/go-gin-project/pkg/loadshed"
+	"github.com/xiebingnote/go-gin-project/pkg/logger"
 	authcasbin "github.com/xiebingnote/go-gin-project/servers/httpserver/auth/casbin"
 	"github.com/xiebingnote/go-gin-project/servers/httpserver/auth/jwt"
 
@@ -42,6 +48,7 @@ func DefaultServerOptions() *ServerOptions {
 		WriteTimeout:    config.ServerConfig.Options.WriteTimeout,
 		IdleTimeout:     config.ServerConfig.Options.IdleTimeout,
 		ShutdownTimeout: config.ServerConfig.Options.ShutdownTimeout,
+		RequestTimeout:  config.ServerConfig.Options.RequestTimeout,
 		RateLimitConfig: &ServerRateLimitConfig{
 			EnableRedis:  config.ServerConfig.Options.RateLimitConfig.EnableRedis,
 			EnableMemory: config.ServerConfig.Options.RateLimitConfig.EnableMemory,
@@ -103,14 +110,40 @@ func NewServerWithOptions(opts *ServerOptions) *gin.Engine {
 	// Add base middleware
 	setupBaseMiddleware(router)
 
+	// Add monitoring middleware if metrics are enabled
+	//
+	// It comes before the load shedding middleware, so that the shed
+	// requests are counted and spend the error budget of the SLO.
+	if opts.EnableMetrics {
+		router.Use(middleware.PrometheusMiddleware())
+		if slo, ok := sloOptions(); ok {
+			router.Use(middleware.SLOMiddleware(slo))
+		}
+	}
+
+	// Shed the requests beyond the adaptive concurrency limit, before the
+	// other middleware spends time on them
+	if shedding, ok := loadSheddingOptions(); ok {
+		router.Use(middleware.LoadSheddingMiddleware(shedding))
+	}
+
 	// Add security middleware if enabled
 	if opts.EnableSecurity {
 		setupSecurityMiddleware(router, opts)
 	}
 
-	// Add monitoring middleware if metrics are enabled
-	if opts.EnableMetrics {
-		router.Use(middleware.PrometheusMiddleware())
+	// Add the circuit breaker middleware if enabled in the circuit breaker configuration
+	if manager, ok := circuitBreakerManager(); ok {
+		router.Use(middleware.CircuitBreakerMiddleware(manager))
+	}
+
+	// Add the request timeout middleware if configured
+	//
+	// It comes after the security, metrics, SLO and circuit breaker
+	// middleware, so that the 504 it writes carries their headers and is
+	// the status they record.
+	if opts.RequestTimeout > 0 {
+		router.Use(middleware.TimeoutMiddleware(opts.RequestTimeout * time.Second))
 	}
 
 	// Set up authentication routes
@@ -173,48 +206,155 @@ func Validate(opts *ServerOptions) error {
 		return fmt.Errorf("shutdown timeout must be positive")
 	}
 
+	// Ensure the request timeout does not exceed the write timeout of the
+	// main server, see newMainServer, otherwise the connection is closed
+	// before the timeout response can be written. Both are in seconds.
+	if opts.RequestTimeout < 0 {
+		return fmt.Errorf("request timeout must not be negative")
+	}
+	if writeTimeout := config.ServerConfig.HTTPServer.WriteTimeout; writeTimeout > 0 && opts.RequestTimeout > writeTimeout {
+		return fmt.Errorf("request timeout %ds must not exceed the HTTPServer write timeout %ds",
+			int64(opts.RequestTimeout), int64(writeTimeout))
+	}
+
 	return nil
 }
 
+// accessLogOptions returns the access log options configured in the
+// [Access] section of the log configuration.
+//
+// Returns:
+//   - middleware.AccessLogOptions: The access log options.
+func accessLogOptions() middleware.AccessLogOptions {
+	cfg := config.LogConfig
+
+	// The redaction patterns are validated when the logger is created
+	redactor, err := logger.RedactorFromConfig(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("invalid log redaction config: %v", err))
+	}
+
+	return middleware.AccessLogOptions{
+		Logger:        resource.AccessLogger,
+		Fields:        cfg.Access.Fields,
+		SlowThreshold: time.Duration(cfg.Access.SlowThreshold) * time.Millisecond,
+		SkipPaths:     cfg.Access.SkipPaths,
+		CaptureBody:   cfg.Access.Body.Enable,
+		MaxBodySize:   cfg.Access.Body.MaxSize,
+		Redactor:      redactor,
+	}
+}
+
+// sloOptions returns the SLO middleware options of the metrics
+// configuration.
+//
+// Returns:
+//   - middleware.SLOOptions: The SLO options.
+//   - bool: False if the service level objectives are not enabled.
+func sloOptions() (middleware.SLOOptions, bool) {
+	if config.ServerConfig == nil || !config.ServerConfig.Metrics.SLO.Enable {
+		return middleware.SLOOptions{}, false
+	}
+
+	cfg := config.ServerConfig.Metrics.SLO
+	return middleware.SLOOptions{
+		AvailabilityObjective: cfg.AvailabilityObjective,
+		LatencyObjective:      cfg.LatencyObjective,
+		LatencyThreshold:      time.Duration(cfg.LatencyThreshold) * time.Millisecond,
+		SkipPaths:             cfg.SkipPaths,
+	}, true
+}
+
+// loadSheddingOptions returns the load shedding middleware options of the
+// server configuration, with a new adaptive concurrency limiter.
+//
+// Returns:
+//   - middleware.LoadSheddingOptions: The load shedding options.
+//   - bool: False if load shedding is not enabled.
+func loadSheddingOptions() (middleware.LoadSheddingOptions, bool) {
+	if config.ServerConfig == nil || !config.ServerConfig.LoadShedding.Enable {
+		return middleware.LoadSheddingOptions{}, false
+	}
+
+	cfg := config.ServerConfig.LoadShedding
+	limiter := loadshed.NewLimiter(loadshed.Config{
+		InitialLimit: cfg.InitialLimit,
+		MinLimit:     cfg.MinLimit,
+		MaxLimit:     cfg.MaxLimit,
+		Window:       time.Duration(cfg.Window) * time.Millisecond,
+		Tolerance:    cfg.Tolerance,
+		CPUThreshold: cfg.CPUThreshold,
+	})
+	return middleware.LoadSheddingOptions{
+		Limiter:          limiter,
+		RetryAfter:       time.Duration(cfg.RetryAfter) * time.Second,
+		CriticalPaths:    cfg.CriticalPaths,
+		LowPriorityPaths: cfg.LowPriorityPaths,
+	}, true
+}
+
+// circuitBreakerManager returns the circuit breaker manager protecting the
+// routes of the main server.
+//
+// Returns:
+//   - *middleware.CircuitBreakerManager: The manager created at boot.
+//   - bool: False if the circuit breakers or their middleware are disabled.
+func circuitBreakerManager() (*middleware.CircuitBreakerManager, bool) {
+	cfg := config.CircuitBreakerConfig
+	if cfg == nil || !cfg.CircuitBreaker.Enable || !cfg.CircuitBreaker.EnableMiddleware {
+		return nil, false
+	}
+	return resource.CircuitBreakerManager, resource.CircuitBreakerManager != nil
+}
+
 // setupBaseMiddleware sets up the base middleware for the gin server.
 //
-// The base middleware includes a custom logger, a recovery middleware, and a
-// request ID middleware.
+// The base middleware includes the access log, a recovery middleware, a
+// request ID middleware, the tracing middleware and the error rendering
+// middleware.
 func setupBaseMiddleware(router *gin.Engine) {
-	// Custom logger middleware
-	router.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return fmt.Sprintf("[%s] %s %s %d %s %s\n",
-			param.TimeStamp.Format("2006-01-02 15:04:05"),
-			param.Method,
-			param.Path,
-			param.StatusCode,
-			param.Latency,
-			param.ClientIP,
-		)
-	}))
+	// Access log middleware
+	//
+	// This middleware writes one JSON entry per request to the access log. It
+	// comes first so that the latency and status include the other middleware.
+	if resource.AccessLogger != nil {
+		router.Use(middleware.AccessLogMiddleware(accessLogOptions()))
+	}
 
 	// Recovery middleware
 	//
 	// This middleware recovers from panic and logs the error. It also returns
 	// a JSON response with a 500 status code.
 	router.Use(gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		if resource.LoggerService != nil {
-			resource.LoggerService.Error("Panic recovered",
-				zap.Any("error", recovered),
-				zap.String("path", c.Request.URL.Path),
-				zap.String("method", c.Request.Method),
-			)
-		}
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"error": "Internal Server Error",
-			"code":  "INTERNAL_ERROR",
-		})
+		logger.WithContext(c.Request.Context(), resource.HTTPLogger).Error("Panic recovered",
+			zap.Any("error", recovered),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("method", c.Request.Method),
+		)
+		resp.AbortWithAppError(c, resp.ErrInternal, resp.RequestID(c))
 	}))
 
 	// Request ID middleware
 	//
 	// This middleware sets a request ID for each request.
 	router.Use(middleware.RequestIDMiddleware())
+
+	// Tracing middleware
+	//
+	// This middleware creates a span for each request. It is a no-op until
+	// a tracer provider is installed by the tracing service.
+	router.Use(middleware.TracingMiddleware())
+
+	// Error middleware
+	//
+	// This middleware renders errors attached with c.Error into the standard
+	// response envelope, so every error path returns the same shape.
+	router.Use(middleware.ErrorMiddleware())
+
+	// Unknown routes and methods also use the standard response envelope
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(middleware.NotFoundHandler)
+	router.NoMethod(middleware.MethodNotAllowedHandler)
 }
 
 // setupSecurityMiddleware sets up the security middleware for the router.
@@ -318,7 +458,8 @@ func setupAPIMiddleware(api *gin.RouterGroup, opts *ServerOptions) {
 //
 // This function configures the default access control policies for different roles
 // and adds specific users to role groups. If the Casbin enforcer is not initialized,
-// the function exits early.
+// the function exits early. Policies and grouping policies actually added are
+// recorded in the audit trail.
 func setupCasbinPolicies() {
 	// Return early if the Casbin enforcer is not initialized
 	if resource.Enforcer == nil {
@@ -337,7 +478,8 @@ func setupCasbinPolicies() {
 
 	// Add each policy to the Casbin enforcer
 	for _, policy := range policies {
-		if _, err := resource.Enforcer.AddPolicy(policy); err != nil {
+		added, err := resource.Enforcer.AddPolicy(policy)
+		if err != nil {
 			// Log an error if adding the policy fails
 			if resource.LoggerService != nil {
 				resource.LoggerService.Error("Failed to add Casbin policy",
@@ -346,15 +488,42 @@ func setupCasbinPolicies() {
 				)
 			}
 		}
+		if added || err != nil {
+			recordPolicyChange(audit.ActionPolicyAdd, policy, err)
+		}
 	}
 
 	// Add grouping policy to associate users with roles
-	if _, err := resource.Enforcer.AddGroupingPolicy("alice", "admin"); err != nil {
+	added, err := resource.Enforcer.AddGroupingPolicy("alice", "admin")
+	if err != nil {
 		// Log an error if adding the grouping policy fails
 		if resource.LoggerService != nil {
 			resource.LoggerService.Error("Failed to add Casbin grouping policy", zap.Error(err))
 		}
 	}
+	if added || err != nil {
+		recordPolicyChange(audit.ActionRoleChange, []string{"alice", "admin"}, err)
+	}
+}
+
+// recordPolicyChange records a change of the Casbin policies made at startup
+// in the audit trail.
+//
+// Parameters:
+//   - action: The audit action, e.g. audit.ActionPolicyAdd.
+//   - rule: The policy or grouping policy.
+//   - err: The error of the change, nil if it succeeded.
+func recordPolicyChange(action string, rule []string, err error) {
+	e := audit.Event{
+		Actor:   "system",
+		Action:  action,
+		Target:  strings.Join(rule, ", "),
+		Outcome: audit.Outcome(err),
+	}
+	if err != nil {
+		e.Reason = err.Error()
+	}
+	middleware.RecordAuditContext(context.Background(), e)
 }
 
 // NewServerCasbin creates an HTTP server with Casbin authorization enabled.
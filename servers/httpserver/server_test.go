@@ -0,0 +1,50 @@
+package httpserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xiebingnote/go-gin-project/library/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestValidateRequestTimeout tests that the request timeout is checked
+// against the write timeout of the main server, [HTTPServer] WriteTimeout.
+func TestValidateRequestTimeout(t *testing.T) {
+	previous := config.ServerConfig
+	config.ServerConfig = &config.ServerConfigEntry{}
+	t.Cleanup(func() { config.ServerConfig = previous })
+
+	tests := []struct {
+		name           string
+		writeTimeout   int // [HTTPServer] WriteTimeout, in seconds
+		requestTimeout int
+		wantErr        bool
+	}{
+		{"disabled", 30, 0, false},
+		{"below write timeout", 30, 10, false},
+		{"equal to write timeout", 30, 30, false},
+		{"above write timeout", 30, 60, true},
+		{"no write timeout", 0, 60, false},
+		{"negative", 30, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.ServerConfig.HTTPServer.WriteTimeout = time.Duration(tt.writeTimeout)
+			opts := &ServerOptions{
+				Mode:            gin.TestMode,
+				AuthType:        "jwt",
+				ReadTimeout:     30,
+				WriteTimeout:    120, // [Options] WriteTimeout, not used by the server
+				ShutdownTimeout: 30,
+				RequestTimeout:  time.Duration(tt.requestTimeout),
+			}
+
+			if err := Validate(opts); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,21 +3,31 @@ package servers
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/xiebingnote/go-gin-project/library/config"
 	"github.com/xiebingnote/go-gin-project/library/middleware"
 	"github.com/xiebingnote/go-gin-project/library/resource"
 	resp "github.com/xiebingnote/go-gin-project/library/response"
+	"github.com/xiebingnote/go-gin-project/pkg/listener"
+	"github.com/xiebingnote/go-gin-project/servers/adminserver"
 	"github.com/xiebingnote/go-gin-project/servers/httpserver"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 )
 
+// defaultGracefulRestartTimeout is the time waited for the new process of a
+// graceful restart to be ready when [Listener] GracefulRestartTimeout is not
+// set.
+const defaultGracefulRestartTimeout = 60 * time.Second
+
 // Start initializes and starts both the main and admin HTTP servers.
 //
 // It creates a buffered error channel to capture any errors that occur
@@ -35,11 +45,15 @@ func Start() (mainSrv *http.Server, adminSrv *http.Server, errChan chan error) {
 	// Create an error channel with a buffer size of 2 to capture errors from both servers.
 	errChan = make(chan error, 2)
 
+	// Count the servers not listening yet.
+	var listening sync.WaitGroup
+	listening.Add(2)
+
 	// Start the main server with the provided configuration and handler.
 	mainSrv = newMainServer(config.ServerConfig, httpserver.NewServer())
 	go func() {
 		// Run the main server and send any errors to the error channel.
-		if err := runServer(mainSrv, "main"); err != nil {
+		if err := runServer(mainSrv, "main", &listening); err != nil {
 			errChan <- err
 		}
 	}()
@@ -48,11 +62,30 @@ func Start() (mainSrv *http.Server, adminSrv *http.Server, errChan chan error) {
 	adminSrv = newAdminServer(config.ServerConfig, newAdminHandler())
 	go func() {
 		// Run the admin server and send any errors to the error channel.
-		if err := runServer(adminSrv, "admin"); err != nil {
+		if err := runServer(adminSrv, "admin", &listening); err != nil {
 			errChan <- err
 		}
 	}()
 
+	// Close the inherited sockets no server took, then tell the parent process
+	// of a graceful restart, if any, that both servers are listening. If a
+	// server fails to listen, the process exits instead, and the parent keeps
+	// serving.
+	go func() {
+		listening.Wait()
+		if closed := listener.CloseInherited(); closed > 0 {
+			resource.LoggerService.Warn("Closed the inherited sockets matching no server", zap.Int("count", closed))
+		}
+		if err := listener.Ready(); err != nil {
+			resource.LoggerService.Error("Failed to notify the parent process of the graceful restart", zap.Error(err))
+		}
+	}()
+
+	// Hand the listening sockets to a new process on SIGUSR2 if enabled.
+	if config.ServerConfig.Listener.EnableGracefulRestart {
+		watchUpgradeSignal()
+	}
+
 	// Return the initialized servers and the error channel.
 	return mainSrv, adminSrv, errChan
 }
@@ -104,7 +137,12 @@ func newAdminServer(cfg *config.ServerConfigEntry, handler http.Handler) *http.S
 	}
 }
 
-// runServer starts the HTTP server and listens for incoming requests.
+// runServer creates the listener for the server and serves incoming requests.
+//
+// The listener is obtained from pkg/listener, so the listen address may be a
+// TCP address or a unix domain socket ("unix:///path.sock"), and sockets
+// inherited from systemd or from a parent process during a graceful restart
+// are reused.
 //
 // If the server fails to start or encounters an error (other than a closed server error),
 // it returns an error with a formatted message indicating the server name.
@@ -112,12 +150,21 @@ func newAdminServer(cfg *config.ServerConfigEntry, handler http.Handler) *http.S
 // Parameters:
 //   - srv: The HTTP server to run.
 //   - name: The name of the server to format in the error message.
+//   - listening: The wait group marked done once the server listens.
 //
 // Returns:
 //   - An error indicating the reason for the server failure.
-func runServer(srv *http.Server, name string) error {
-	// Attempt to start the server and listen for incoming requests.
-	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+func runServer(srv *http.Server, name string, listening *sync.WaitGroup) error {
+	// Create the listener for the configured address.
+	ln, err := newListener(name, srv.Addr)
+	if err != nil {
+		resource.LoggerService.Error(fmt.Sprintf("%s server failed: %v", name, err))
+		return fmt.Errorf("%s server failed: %w", name, err)
+	}
+	listening.Done()
+
+	// Serve incoming requests on the listener.
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		// Log and return a formatted error message if the server fails to start.
 		errMsg := fmt.Sprintf("%s server failed: %v", name, err)
 		resource.LoggerService.Error(errMsg)
@@ -127,20 +174,89 @@ func runServer(srv *http.Server, name string) error {
 	return nil
 }
 
+// newListener creates the listener for the named server according to the
+// [Listener] section of the server configuration.
+//
+// Parameters:
+//   - name: The name of the server, used to match inherited sockets.
+//   - addr: The listen address of the server.
+//
+// Returns:
+//   - net.Listener: The listener for the server.
+//   - error: An error if the socket mode is invalid or listening fails.
+func newListener(name, addr string) (net.Listener, error) {
+	cfg := config.ServerConfig.Listener
+
+	mode, err := listener.ParseFileMode(cfg.SocketMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return listener.Listen(name, addr, listener.Options{
+		SocketMode:             mode,
+		EnableSocketActivation: cfg.EnableSocketActivation,
+	})
+}
+
+// watchUpgradeSignal performs a graceful restart when SIGUSR2 is received.
+//
+// A new process is started with the current listening sockets. Once it
+// reports that its servers are listening, SIGTERM is sent to the current
+// process so that the shutdown hook drains in-flight requests and releases
+// resources as on a normal stop. If the new process exits or is not ready
+// within [Listener] GracefulRestartTimeout, the current process keeps
+// serving.
+func watchUpgradeSignal() {
+	sigChan := make(chan os.Signal, 1)
+	listener.NotifyUpgrade(sigChan)
+
+	timeout := config.ServerConfig.Listener.GracefulRestartTimeout * time.Second
+	if timeout <= 0 {
+		timeout = defaultGracefulRestartTimeout
+	}
+
+	go func() {
+		for range sigChan {
+			pid, err := listener.Upgrade(timeout)
+			if err != nil {
+				resource.LoggerService.Error("Graceful restart failed, keep serving", zap.Error(err))
+				continue
+			}
+
+			resource.LoggerService.Info("🔄 Graceful restart done, the new process is ready",
+				zap.Int("child_pid", pid))
+
+			if err := listener.Terminate(); err != nil {
+				resource.LoggerService.Error("Failed to stop current process after restart", zap.Error(err))
+			}
+			return
+		}
+	}()
+}
+
 // newAdminHandler returns a new HTTP handler for the admin interface.
 //
 // The returned handler registers the following endpoints:
 //   - /debug/pprof/ (via gin.WrapH(http.DefaultServeMux)): the pprof debug endpoints.
 //   - /metrics: Prometheus metrics endpoint.
+//   - /version: the build information and uptime of the binary.
+//   - /admin/log/level: the runtime log level control, see adminserver.Router.
+//   - /admin/audit: the audit trail query, export and verification, see adminserver.Router.
+//   - /admin/circuitbreakers: the circuit breaker status, controls and state change stream, see adminserver.Router.
+//   - /admin/kafka/deadletters: the replay of the Kafka dead letters, see adminserver.Router.
+//   - /admin/kafka/groups: the reset of the offsets of the Kafka consumer groups, see adminserver.Router.
 //   - /test: a test endpoint that returns a 200 OK response with a UUID.
 //
 // The handler also uses the Gin recovery middleware to recover from panics and return a 500 Internal Server Error response.
 // The middleware.PrometheusMiddleware is used to register the Prometheus metrics endpoint.
+// The /admin endpoints require a bearer token of [AdminServer.Auth], see middleware.AdminAuthMiddleware.
+// Every call of the /admin endpoints, including the rejected ones, is recorded in the audit trail with the
+// name of the token as actor by middleware.AuditMiddleware.
 func newAdminHandler() http.Handler {
 	// Create a new Gin router for handling admin routes.
 	router := gin.New()
 	// Use the Gin recovery middleware to recover from panics and return a 500 Internal Server Error response.
-	router.Use(gin.Recovery(), middleware.PrometheusMiddleware())
+	router.Use(gin.Recovery(), middleware.RequestIDMiddleware(), middleware.PrometheusMiddleware())
 
 	// Register the pprof debug endpoints using the default HTTP ServeMux.
 	// The pprof package provides the http.DefaultServeMux handler, which serves the pprof debug endpoints.
@@ -152,11 +268,20 @@ func newAdminHandler() http.Handler {
 	// Register the Prometheus metrics endpoint.
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// Register the build information endpoint.
+	router.GET("/version", adminserver.GetVersion)
+
+	// Register the admin endpoints, e.g. the runtime log level control, authenticate and audit their calls.
+	adminserver.Router(router.Group("/admin",
+		middleware.AuditMiddleware(),
+		middleware.AdminAuthMiddleware(config.ServerConfig.AdminServer.Auth),
+	))
+
 	// Register a test endpoint that returns a 200 OK response with a UUID.
 	// This endpoint can be used to test the admin server.
 	router.GET("/test", func(c *gin.Context) {
 		// Respond with a 200-OK status and a message.
-		resp.NewOKResp(c, "Metrics endpoint test", uuid.NewString())
+		resp.NewOKResp(c, "Metrics endpoint test", resp.RequestID(c))
 	})
 
 	// Return the configured Gin router as the admin HTTP handler.